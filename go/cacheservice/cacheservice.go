@@ -26,6 +26,9 @@ var DefaultCacheService string
 type Config struct {
 	Address string
 	Timeout time.Duration
+	// Capacity is the memory limit in bytes of the cache services
+	// that keep their data in-process. It's ignored by the others.
+	Capacity int64
 }
 
 // Result gives the cached data.
@@ -96,3 +99,15 @@ func Connect(config Config) (CacheService, error) {
 	}
 	return fn(config)
 }
+
+// ConnectTo returns a CacheService of the given registered service,
+// regardless of DefaultCacheService.
+func ConnectTo(name string, config Config) (CacheService, error) {
+	mu.Lock()
+	fn, ok := services[name]
+	mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("cache service %s is not registered", name)
+	}
+	return fn(config)
+}
@@ -0,0 +1,327 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package lrucacheservice provides an in-process implementation of
+// cacheservice.CacheService, on top of a cache.LRUCache. It lets the
+// rowcache run without a memcached process.
+//
+// The connections to the same address share their cache, which lives
+// as long as it has open connections. Expiration times are ignored.
+package lrucacheservice
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/youtube/vitess/go/cache"
+	cs "github.com/youtube/vitess/go/cacheservice"
+)
+
+// DefaultCapacity is the capacity in bytes of the caches whose
+// config doesn't specify one. It's the default of memcached.
+const DefaultCapacity = 64 * 1024 * 1024
+
+// itemOverhead approximates the memory used by an item besides its
+// key and value, so that small items are not considered free.
+const itemOverhead = 48
+
+var (
+	mu     sync.Mutex
+	caches = make(map[string]*Cache)
+)
+
+// item is a value stored in a Cache. Items are never modified
+// once they're in the cache: updates store a new item.
+type item struct {
+	key   string
+	value []byte
+	flags uint16
+	cas   uint64
+}
+
+// Size is part of the cache.Value interface.
+func (it *item) Size() int {
+	return len(it.key) + len(it.value) + itemOverhead
+}
+
+// Cache is the LRU cache shared by the connections to an address.
+type Cache struct {
+	// mu serializes the operations that read and then write an item.
+	mu      sync.Mutex
+	lru     *cache.LRUCache
+	lastCas uint64
+	conns   int
+	started time.Time
+
+	// stats use the names of the memcached stats.
+	stats map[string]int64
+}
+
+func newCache(capacity int64) *Cache {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Cache{
+		lru:     cache.NewLRUCache(capacity),
+		started: time.Now(),
+		stats:   make(map[string]int64),
+	}
+}
+
+func (c *Cache) get(key string) *item {
+	v, ok := c.lru.Get(key)
+	if !ok {
+		return nil
+	}
+	return v.(*item)
+}
+
+// store stores value for key with a new cas identifier.
+func (c *Cache) store(key string, flags uint16, value []byte) {
+	c.lastCas++
+	c.lru.Set(key, &item{key: key, value: value, flags: flags, cas: c.lastCas})
+	c.stats["cmd_set"]++
+}
+
+// Connection is a connection to the cache of an address.
+type Connection struct {
+	address string
+	cache   *Cache
+}
+
+// Connect returns a connection to the cache of config.Address, and
+// creates the cache with config.Capacity if it doesn't exist.
+func Connect(config cs.Config) (cs.CacheService, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	c, ok := caches[config.Address]
+	if !ok {
+		c = newCache(config.Capacity)
+		caches[config.Address] = c
+	}
+	c.mu.Lock()
+	c.conns++
+	c.mu.Unlock()
+	return &Connection{address: config.Address, cache: c}, nil
+}
+
+func (conn *Connection) lock() (*Cache, error) {
+	if conn.cache == nil {
+		return nil, fmt.Errorf("connection to %s is closed", conn.address)
+	}
+	conn.cache.mu.Lock()
+	return conn.cache, nil
+}
+
+// Get returns cached data for given keys.
+func (conn *Connection) Get(keys ...string) ([]cs.Result, error) {
+	results, err := conn.Gets(keys...)
+	for i := range results {
+		results[i].Cas = 0
+	}
+	return results, err
+}
+
+// Gets returns cached data for given keys, it is an alternative Get api
+// for using with CAS. Gets returns a CAS identifier with the item. If
+// the item's CAS value has changed since you Gets'ed it, it will not be stored.
+func (conn *Connection) Gets(keys ...string) ([]cs.Result, error) {
+	c, err := conn.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer c.mu.Unlock()
+	results := make([]cs.Result, 0, len(keys))
+	for _, key := range keys {
+		c.stats["cmd_get"]++
+		it := c.get(key)
+		if it == nil {
+			c.stats["get_misses"]++
+			continue
+		}
+		c.stats["get_hits"]++
+		results = append(results, cs.Result{
+			Key:   key,
+			Value: it.value,
+			Flags: it.flags,
+			Cas:   it.cas,
+		})
+	}
+	return results, nil
+}
+
+// Set set the value with specified cache key.
+func (conn *Connection) Set(key string, flags uint16, timeout uint64, value []byte) (bool, error) {
+	c, err := conn.lock()
+	if err != nil {
+		return false, err
+	}
+	defer c.mu.Unlock()
+	c.store(key, flags, value)
+	return true, nil
+}
+
+// Add store the value only if it does not already exist.
+func (conn *Connection) Add(key string, flags uint16, timeout uint64, value []byte) (bool, error) {
+	c, err := conn.lock()
+	if err != nil {
+		return false, err
+	}
+	defer c.mu.Unlock()
+	if c.get(key) != nil {
+		return false, nil
+	}
+	c.store(key, flags, value)
+	return true, nil
+}
+
+// Replace replaces the value, only if the value already exists,
+// for the specified cache key.
+func (conn *Connection) Replace(key string, flags uint16, timeout uint64, value []byte) (bool, error) {
+	c, err := conn.lock()
+	if err != nil {
+		return false, err
+	}
+	defer c.mu.Unlock()
+	if c.get(key) == nil {
+		return false, nil
+	}
+	c.store(key, flags, value)
+	return true, nil
+}
+
+// Append appends the value after the last bytes in an existing item.
+// Like memcached, it ignores flags.
+func (conn *Connection) Append(key string, flags uint16, timeout uint64, value []byte) (bool, error) {
+	c, err := conn.lock()
+	if err != nil {
+		return false, err
+	}
+	defer c.mu.Unlock()
+	it := c.get(key)
+	if it == nil {
+		return false, nil
+	}
+	c.store(key, it.flags, concat(it.value, value))
+	return true, nil
+}
+
+// Prepend prepends the value before existing value.
+// Like memcached, it ignores flags.
+func (conn *Connection) Prepend(key string, flags uint16, timeout uint64, value []byte) (bool, error) {
+	c, err := conn.lock()
+	if err != nil {
+		return false, err
+	}
+	defer c.mu.Unlock()
+	it := c.get(key)
+	if it == nil {
+		return false, nil
+	}
+	c.store(key, it.flags, concat(value, it.value))
+	return true, nil
+}
+
+// concat returns a new slice, because the values of the items
+// are shared with the results of Get.
+func concat(a, b []byte) []byte {
+	value := make([]byte, 0, len(a)+len(b))
+	return append(append(value, a...), b...)
+}
+
+// Cas stores the value only if no one else has updated the data since you read it last.
+func (conn *Connection) Cas(key string, flags uint16, timeout uint64, value []byte, cas uint64) (bool, error) {
+	c, err := conn.lock()
+	if err != nil {
+		return false, err
+	}
+	defer c.mu.Unlock()
+	it := c.get(key)
+	switch {
+	case it == nil:
+		c.stats["cas_misses"]++
+		return false, nil
+	case it.cas != cas:
+		c.stats["cas_badval"]++
+		return false, nil
+	}
+	c.stats["cas_hits"]++
+	c.store(key, flags, value)
+	return true, nil
+}
+
+// Delete delete the value for the specified cache key.
+func (conn *Connection) Delete(key string) (bool, error) {
+	c, err := conn.lock()
+	if err != nil {
+		return false, err
+	}
+	defer c.mu.Unlock()
+	if !c.lru.Delete(key) {
+		c.stats["delete_misses"]++
+		return false, nil
+	}
+	c.stats["delete_hits"]++
+	return true, nil
+}
+
+// FlushAll purges the entire cache.
+func (conn *Connection) FlushAll() error {
+	c, err := conn.lock()
+	if err != nil {
+		return err
+	}
+	defer c.mu.Unlock()
+	c.lru.Clear()
+	c.stats["cmd_flush"]++
+	return nil
+}
+
+// Stats returns a list of basic stats, in the format of memcached.
+// Only the general stats are supported: the other arguments return
+// no stats.
+func (conn *Connection) Stats(argument string) ([]byte, error) {
+	c, err := conn.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer c.mu.Unlock()
+	if argument != "" {
+		return nil, nil
+	}
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "STAT uptime %d\n", int64(time.Now().Sub(c.started).Seconds()))
+	fmt.Fprintf(buf, "STAT curr_connections %d\n", c.conns)
+	fmt.Fprintf(buf, "STAT curr_items %d\n", c.lru.Length())
+	fmt.Fprintf(buf, "STAT bytes %d\n", c.lru.Size())
+	fmt.Fprintf(buf, "STAT limit_maxbytes %d\n", c.lru.Capacity())
+	for _, name := range []string{"cmd_get", "cmd_set", "cmd_flush", "get_hits", "get_misses", "delete_hits", "delete_misses", "cas_hits", "cas_misses", "cas_badval"} {
+		fmt.Fprintf(buf, "STAT %s %d\n", name, c.stats[name])
+	}
+	return buf.Bytes(), nil
+}
+
+// Close closes the connection. The cache of the address is released
+// with its last connection.
+func (conn *Connection) Close() {
+	if conn.cache == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	c := conn.cache
+	conn.cache = nil
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conns--
+	if c.conns == 0 && caches[conn.address] == c {
+		delete(caches, conn.address)
+	}
+}
+
+func init() {
+	cs.Register("lru", Connect)
+}
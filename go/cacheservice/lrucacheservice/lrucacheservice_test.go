@@ -0,0 +1,142 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lrucacheservice
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	cs "github.com/youtube/vitess/go/cacheservice"
+)
+
+func connect(t *testing.T, address string, capacity int64) cs.CacheService {
+	conn, err := cs.ConnectTo("lru", cs.Config{Address: address, Capacity: capacity})
+	if err != nil {
+		t.Fatalf("ConnectTo(lru): %v", err)
+	}
+	return conn
+}
+
+func TestStore(t *testing.T) {
+	conn := connect(t, "TestStore", 0)
+	defer conn.Close()
+
+	if stored, err := conn.Replace("k1", 0, 0, []byte("v0")); stored || err != nil {
+		t.Errorf("Replace of a missing key: %v, %v, want false", stored, err)
+	}
+	if stored, err := conn.Add("k1", 1, 0, []byte("v1")); !stored || err != nil {
+		t.Errorf("Add: %v, %v, want true", stored, err)
+	}
+	if stored, _ := conn.Add("k1", 0, 0, []byte("v2")); stored {
+		t.Errorf("Add of an existing key must fail")
+	}
+	if stored, _ := conn.Append("k1", 0, 0, []byte("b")); !stored {
+		t.Errorf("Append must succeed")
+	}
+	if stored, _ := conn.Prepend("k1", 0, 0, []byte("a")); !stored {
+		t.Errorf("Prepend must succeed")
+	}
+	results, err := conn.Get("k1", "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []cs.Result{{Key: "k1", Value: []byte("av1b"), Flags: 1}}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Get: %v, want %v", results, want)
+	}
+
+	if deleted, _ := conn.Delete("k1"); !deleted {
+		t.Errorf("Delete must succeed")
+	}
+	if deleted, _ := conn.Delete("k1"); deleted {
+		t.Errorf("Delete of a missing key must fail")
+	}
+	conn.Set("k1", 0, 0, []byte("v3"))
+	conn.Set("k2", 0, 0, []byte("v4"))
+	if err := conn.FlushAll(); err != nil {
+		t.Fatal(err)
+	}
+	if results, _ := conn.Get("k1", "k2"); len(results) != 0 {
+		t.Errorf("Get after FlushAll: %v, want none", results)
+	}
+}
+
+func TestCas(t *testing.T) {
+	conn := connect(t, "TestCas", 0)
+	defer conn.Close()
+
+	if stored, _ := conn.Cas("k1", 0, 0, []byte("v1"), 1); stored {
+		t.Errorf("Cas of a missing key must fail")
+	}
+	conn.Set("k1", 1, 0, nil)
+	results, _ := conn.Gets("k1")
+	if len(results) != 1 || results[0].Cas == 0 {
+		t.Fatalf("Gets: %v, want a cas identifier", results)
+	}
+	cas := results[0].Cas
+	if stored, _ := conn.Cas("k1", 0, 0, []byte("v2"), cas+1); stored {
+		t.Errorf("Cas with another identifier must fail")
+	}
+	if stored, _ := conn.Cas("k1", 0, 0, []byte("v2"), cas); !stored {
+		t.Errorf("Cas must succeed")
+	}
+	if stored, _ := conn.Cas("k1", 0, 0, []byte("v3"), cas); stored {
+		t.Errorf("Cas after an update must fail")
+	}
+
+	stats, err := conn.Stats("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"STAT cas_hits 1\n", "STAT cas_misses 1\n", "STAT cas_badval 2\n", "STAT curr_items 1\n"} {
+		if !strings.Contains(string(stats), want) {
+			t.Errorf("Stats: %s, must contain %s", stats, want)
+		}
+	}
+}
+
+func TestEviction(t *testing.T) {
+	conn := connect(t, "TestEviction", 2*(itemOverhead+10))
+	defer conn.Close()
+
+	for _, key := range []string{"key1", "key2", "key3"} {
+		conn.Set(key, 0, 0, []byte("value1"))
+	}
+	results, _ := conn.Get("key1", "key2", "key3")
+	if len(results) != 2 || results[0].Key != "key2" || results[1].Key != "key3" {
+		t.Errorf("Get: %v, want key2 and key3", results)
+	}
+}
+
+func TestSharedCache(t *testing.T) {
+	conn1 := connect(t, "TestSharedCache", 0)
+	conn2 := connect(t, "TestSharedCache", 0)
+	conn1.Set("k1", 0, 0, []byte("v1"))
+	if results, _ := conn2.Get("k1"); len(results) != 1 {
+		t.Errorf("connections to the same address must share their cache")
+	}
+	other := connect(t, "TestSharedCacheOther", 0)
+	if results, _ := other.Get("k1"); len(results) != 0 {
+		t.Errorf("connections to another address must not share the cache")
+	}
+	other.Close()
+
+	conn1.Close()
+	conn1.Close()
+	if _, err := conn1.Get("k1"); err == nil {
+		t.Errorf("Get on a closed connection must fail")
+	}
+	if results, _ := conn2.Get("k1"); len(results) != 1 {
+		t.Errorf("the cache must live as long as it has connections")
+	}
+	conn2.Close()
+
+	conn3 := connect(t, "TestSharedCache", 0)
+	defer conn3.Close()
+	if results, _ := conn3.Get("k1"); len(results) != 0 {
+		t.Errorf("the cache must be released with its last connection")
+	}
+}
@@ -23,7 +23,14 @@ function vindexInfo() {
           "Type": "functional",
           "Unique": true,
           "Params": [
-              "Table", "Column"
+              "Table", "Column", "Sequence"
+          ]
+      },
+      "region": {
+          "Type": "functional",
+          "Unique": true,
+          "Params": [
+              "RegionBytes"
           ]
       },
       "lookup_hash": {
@@ -44,14 +51,14 @@ function vindexInfo() {
           "Type": "lookup",
           "Unique": false,
           "Params": [
-              "Table", "From", "To"
+              "Table", "From", "To", "Sequence"
           ]
       },
       "lookup_hash_unique_autoinc": {
           "Type": "lookup",
           "Unique": true,
           "Params": [
-              "Table", "From", "To"
+              "Table", "From", "To", "Sequence"
           ]
       }
   };
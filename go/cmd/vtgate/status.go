@@ -72,6 +72,11 @@ var (
   {{end}}
 </table>
 <small>This is just a cache, so some data may not be visible here yet.</small>
+`
+
+	debugTemplate = `
+<a href="/debug/query_plans">Query&nbsp;Plans</a></br>
+<a href="/debug/schema">VSchema</a></br>
 `
 
 	statsTemplate = `
@@ -206,6 +211,9 @@ func init() {
 		servenv.AddStatusPart("Stats", statsTemplate, func() interface{} {
 			return nil
 		})
+		servenv.AddStatusPart("Debug", debugTemplate, func() interface{} {
+			return nil
+		})
 		if onStatusRegistered != nil {
 			onStatusRegistered()
 		}
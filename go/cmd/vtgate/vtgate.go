@@ -77,6 +77,7 @@ func main() {
 
 startServer:
 	resilientSrvTopoServer = vtgate.NewResilientSrvTopoServer(ts, "ResilientSrvTopoServer")
+	servenv.OnClose(resilientSrvTopoServer.Close)
 
 	// For the initial phase vtgate is exposing
 	// topoReader api. This will be subsumed by
@@ -85,5 +86,10 @@ startServer:
 	servenv.Register("toporeader", topoReader)
 
 	vtgate.Init(resilientSrvTopoServer, schema, *cell, *retryDelay, *retryCount, *connTimeoutTotal, *connTimeoutPerConn, *connLife, *maxInFlight)
+	if store, ok := ts.(topo.VTGateQuotaStore); ok {
+		vtgate.InitQuotas(store)
+	} else {
+		log.Infof("Skipping the per-caller quotas: topo does not support the VTGateQuotaStore interface")
+	}
 	servenv.RunDefault()
 }
@@ -0,0 +1,18 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// Imports and register the in-process LRU cache service, for
+// -rowcache-backend lru
+
+import (
+	"github.com/youtube/vitess/go/cacheservice"
+	_ "github.com/youtube/vitess/go/cacheservice/lrucacheservice"
+)
+
+func init() {
+	// memcache stays the cache service of the default rowcache backend.
+	cacheservice.DefaultCacheService = "memcache"
+}
@@ -131,6 +131,16 @@ func (f *fakeVTGateService) SplitQuery(ctx context.Context, req *proto.SplitQuer
 	return nil
 }
 
+// SplitQueryV2 is part of the VTGateService interface
+func (f *fakeVTGateService) SplitQueryV2(ctx context.Context, req *proto.SplitQueryV2Request, reply *proto.SplitQueryResult) error {
+	return nil
+}
+
+// ExplainQuery is part of the VTGateService interface
+func (f *fakeVTGateService) ExplainQuery(ctx context.Context, query *proto.Query, reply *proto.ExplainQueryResult) error {
+	return nil
+}
+
 // HandlePanic is part of the VTGateService interface
 func (f *fakeVTGateService) HandlePanic(err *error) {
 	if x := recover(); x != nil {
@@ -72,6 +72,20 @@ func TestWatchEndPoints(t *testing.T) {
 	test.CheckWatchEndPoints(ctx, t, ts)
 }
 
+func TestWatchSrvKeyspace(t *testing.T) {
+	ctx := context.Background()
+	ts := newTestServer(t, []string{"test"})
+	defer ts.Close()
+	test.CheckWatchSrvKeyspace(ctx, t, ts)
+}
+
+func TestWatchSrvShard(t *testing.T) {
+	ctx := context.Background()
+	ts := newTestServer(t, []string{"test"})
+	defer ts.Close()
+	test.CheckWatchSrvShard(ctx, t, ts)
+}
+
 func TestKeyspaceLock(t *testing.T) {
 	ctx := context.Background()
 	ts := newTestServer(t, []string{"test"})
@@ -111,3 +125,10 @@ func TestVSchema(t *testing.T) {
 	defer ts.Close()
 	test.CheckVSchema(ctx, t, ts)
 }
+
+func TestVTGateQuotas(t *testing.T) {
+	ctx := context.Background()
+	ts := newTestServer(t, []string{"test"})
+	defer ts.Close()
+	test.CheckVTGateQuotas(ctx, t, ts)
+}
@@ -257,66 +257,136 @@ func (s *Server) WatchEndPoints(ctx context.Context, cellName, keyspace, shard s
 
 	notifications := make(chan *topo.EndPoints, 10)
 	stopWatching := make(chan struct{})
+	go func() {
+		watchNode(cell, filePath, stopWatching, func(node *etcd.Node) {
+			var ep *topo.EndPoints
+			if node != nil && node.Value != "" {
+				ep = &topo.EndPoints{}
+				if err := json.Unmarshal([]byte(node.Value), ep); err != nil {
+					log.Errorf("failed to Unmarshal EndPoints for %v: %v", filePath, err)
+					return
+				}
+			}
+			notifications <- ep
+		})
+		close(notifications)
+	}()
+	return notifications, stopWatching, nil
+}
+
+// WatchSrvKeyspace is part of the topo.Server interface
+func (s *Server) WatchSrvKeyspace(ctx context.Context, cellName, keyspace string) (<-chan *topo.SrvKeyspace, chan<- struct{}, error) {
+	cell, err := s.getCell(cellName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("WatchSrvKeyspace cannot get cell: %v", err)
+	}
+	filePath := srvKeyspaceFilePath(keyspace)
+
+	notifications := make(chan *topo.SrvKeyspace, 10)
+	stopWatching := make(chan struct{})
+	go func() {
+		watchNode(cell, filePath, stopWatching, func(node *etcd.Node) {
+			var srvKeyspace *topo.SrvKeyspace
+			if node != nil && node.Value != "" {
+				srvKeyspace = topo.NewSrvKeyspace(int64(node.ModifiedIndex))
+				if err := json.Unmarshal([]byte(node.Value), srvKeyspace); err != nil {
+					log.Errorf("failed to Unmarshal SrvKeyspace for %v: %v", filePath, err)
+					return
+				}
+			}
+			notifications <- srvKeyspace
+		})
+		close(notifications)
+	}()
+	return notifications, stopWatching, nil
+}
+
+// WatchSrvShard is part of the topo.Server interface
+func (s *Server) WatchSrvShard(ctx context.Context, cellName, keyspace, shard string) (<-chan *topo.SrvShard, chan<- struct{}, error) {
+	cell, err := s.getCell(cellName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("WatchSrvShard cannot get cell: %v", err)
+	}
+	filePath := srvShardFilePath(keyspace, shard)
+
+	notifications := make(chan *topo.SrvShard, 10)
+	stopWatching := make(chan struct{})
+	go func() {
+		watchNode(cell, filePath, stopWatching, func(node *etcd.Node) {
+			var srvShard *topo.SrvShard
+			if node != nil && node.Value != "" {
+				srvShard = topo.NewSrvShard(int64(node.ModifiedIndex))
+				if err := json.Unmarshal([]byte(node.Value), srvShard); err != nil {
+					log.Errorf("failed to Unmarshal SrvShard for %v: %v", filePath, err)
+					return
+				}
+			}
+			notifications <- srvShard
+		})
+		close(notifications)
+	}()
+	return notifications, stopWatching, nil
+}
 
+// watchNode calls notify with the current version of filePath, and
+// then with every new version of it, until stopWatching is closed.
+// The node passed to notify is nil if the file doesn't exist, and
+// has an empty value if it was deleted.
+func watchNode(cell *cellClient, filePath string, stopWatching <-chan struct{}, notify func(*etcd.Node)) {
 	// The watch go routine will stop if the 'stop' channel is closed.
-	// Otherwise it will try to watch everything in a loop, and send events
-	// to the 'watch' channel.
+	// Otherwise it will send the current version of the file, and then
+	// try to watch everything in a loop, and send events to the
+	// 'watch' channel.
 	watch := make(chan *etcd.Response)
 	stop := make(chan bool)
 	go func() {
-		// get the current version of the file
-		ep, modifiedVersion, err := s.GetEndPoints(ctx, cellName, keyspace, shard, tabletType)
-		if err != nil {
+		// get the current version of the file, and the index to
+		// watch from so we don't miss any change after it
+		var node *etcd.Node
+		var waitIndex uint64
+		resp, err := cell.Get(filePath, false /* sort */, false /* recursive */)
+		if err == nil {
+			node = resp.Node
+			waitIndex = resp.EtcdIndex + 1
+		} else if etcdErr, ok := err.(*etcd.EtcdError); ok {
 			// node doesn't exist
-			modifiedVersion = 0
-			ep = nil
+			waitIndex = etcdErr.Index + 1
 		}
 
-		// re-check for stop here to be safe, in case the
-		// getEndPoints took a long time
 		select {
 		case <-stop:
 			return
-		case notifications <- ep:
+		case watch <- &etcd.Response{Node: node}:
 		}
 
 		for {
-			if _, err := cell.Client.Watch(filePath, uint64(modifiedVersion), false /* recursive */, watch, stop); err != nil {
-				log.Errorf("Watch on %v failed, waiting for %v to retry: %v", filePath, WatchSleepDuration, err)
-				timer := time.After(WatchSleepDuration)
-				select {
-				case <-stop:
-					return
-				case <-timer:
-				}
+			_, err := cell.Client.Watch(filePath, waitIndex, false /* recursive */, watch, stop)
+			select {
+			case <-stop:
+				return
+			default:
 			}
-		}
-	}()
-
-	// This go routine is the main event handling routine:
-	// - it will stop if stopWatching is closed.
-	// - if it receives a notification from the watch, it will forward it
-	// to the notifications channel.
-	go func() {
-		for {
+			log.Errorf("Watch on %v failed, waiting for %v to retry: %v", filePath, WatchSleepDuration, err)
+			timer := time.After(WatchSleepDuration)
 			select {
-			case resp := <-watch:
-				var ep *topo.EndPoints
-				if resp.Node != nil && resp.Node.Value != "" {
-					ep = &topo.EndPoints{}
-					if err := json.Unmarshal([]byte(resp.Node.Value), ep); err != nil {
-						log.Errorf("failed to Unmarshal EndPoints for %v: %v", filePath, err)
-						continue
-					}
-				}
-				notifications <- ep
-			case <-stopWatching:
-				close(stop)
-				close(notifications)
+			case <-stop:
 				return
+			case <-timer:
 			}
 		}
 	}()
 
-	return notifications, stopWatching, nil
+	// This is the main event handling loop:
+	// - it will stop if stopWatching is closed.
+	// - if it receives a notification from the watch, it will forward it
+	// to notify.
+	for {
+		select {
+		case resp := <-watch:
+			notify(resp.Node)
+		case <-stopWatching:
+			close(stop)
+			return
+		}
+	}
 }
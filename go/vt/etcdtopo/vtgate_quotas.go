@@ -0,0 +1,46 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package etcdtopo
+
+import (
+	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/vtgate/quota"
+	"golang.org/x/net/context"
+)
+
+/*
+This file contains the vtgate quotas management code for etcdtopo.Server
+*/
+
+var _ topo.VTGateQuotaStore = (*Server)(nil) // compile-time interface check
+
+// SaveVTGateQuotas saves the JSON vtgate quotas into the topo.
+func (s *Server) SaveVTGateQuotas(ctx context.Context, quotas string) error {
+	if _, err := quota.NewConfig([]byte(quotas)); err != nil {
+		return err
+	}
+
+	_, err := s.getGlobal().Set(vtgateQuotasPath, quotas, 0 /* ttl */)
+	if err != nil {
+		return convertError(err)
+	}
+	return nil
+}
+
+// GetVTGateQuotas fetches the JSON vtgate quotas from the topo.
+func (s *Server) GetVTGateQuotas(ctx context.Context) (string, error) {
+	resp, err := s.getGlobal().Get(vtgateQuotasPath, false /* sort */, false /* recursive */)
+	if err != nil {
+		err = convertError(err)
+		if err == topo.ErrNoNode {
+			return "{}", nil
+		}
+		return "", err
+	}
+	if resp.Node == nil {
+		return "", ErrBadResponse
+	}
+	return resp.Node.Value, nil
+}
@@ -869,6 +869,9 @@ type RealtimeStats struct {
 	SecondsBehindMaster uint32 `protobuf:"varint,2,opt,name=seconds_behind_master" json:"seconds_behind_master,omitempty"`
 	// cpu_usage is used for load-based balancing
 	CpuUsage float64 `protobuf:"fixed64,3,opt,name=cpu_usage" json:"cpu_usage,omitempty"`
+	// qps is the average number of queries per second the server
+	// executed recently. It's used for load-based balancing.
+	Qps float64 `protobuf:"fixed64,4,opt,name=qps" json:"qps,omitempty"`
 }
 
 func (m *RealtimeStats) Reset()         { *m = RealtimeStats{} }
@@ -46,6 +46,8 @@ It has these top-level messages:
 	ApplySchemaResponse
 	ExecuteFetchAsDbaRequest
 	ExecuteFetchAsDbaResponse
+	ExecuteTransactionAsDbaRequest
+	ExecuteTransactionAsDbaResponse
 	ExecuteFetchAsAppRequest
 	ExecuteFetchAsAppResponse
 	SlaveStatusRequest
@@ -570,6 +572,22 @@ func (m *ExecuteFetchAsDbaResponse) GetResult() *query.QueryResult {
 	return nil
 }
 
+type ExecuteTransactionAsDbaRequest struct {
+	Queries []string `protobuf:"bytes,1,rep,name=queries" json:"queries,omitempty"`
+	DbName  string   `protobuf:"bytes,2,opt,name=db_name" json:"db_name,omitempty"`
+}
+
+func (m *ExecuteTransactionAsDbaRequest) Reset()         { *m = ExecuteTransactionAsDbaRequest{} }
+func (m *ExecuteTransactionAsDbaRequest) String() string { return proto.CompactTextString(m) }
+func (*ExecuteTransactionAsDbaRequest) ProtoMessage()    {}
+
+type ExecuteTransactionAsDbaResponse struct {
+}
+
+func (m *ExecuteTransactionAsDbaResponse) Reset()         { *m = ExecuteTransactionAsDbaResponse{} }
+func (m *ExecuteTransactionAsDbaResponse) String() string { return proto.CompactTextString(m) }
+func (*ExecuteTransactionAsDbaResponse) ProtoMessage()    {}
+
 type ExecuteFetchAsAppRequest struct {
 	Query      string `protobuf:"bytes,1,opt,name=query" json:"query,omitempty"`
 	MaxRows    uint64 `protobuf:"varint,2,opt,name=max_rows" json:"max_rows,omitempty"`
@@ -51,6 +51,7 @@ type TabletManagerClient interface {
 	PreflightSchema(ctx context.Context, in *tabletmanagerdata.PreflightSchemaRequest, opts ...grpc.CallOption) (*tabletmanagerdata.PreflightSchemaResponse, error)
 	ApplySchema(ctx context.Context, in *tabletmanagerdata.ApplySchemaRequest, opts ...grpc.CallOption) (*tabletmanagerdata.ApplySchemaResponse, error)
 	ExecuteFetchAsDba(ctx context.Context, in *tabletmanagerdata.ExecuteFetchAsDbaRequest, opts ...grpc.CallOption) (*tabletmanagerdata.ExecuteFetchAsDbaResponse, error)
+	ExecuteTransactionAsDba(ctx context.Context, in *tabletmanagerdata.ExecuteTransactionAsDbaRequest, opts ...grpc.CallOption) (*tabletmanagerdata.ExecuteTransactionAsDbaResponse, error)
 	ExecuteFetchAsApp(ctx context.Context, in *tabletmanagerdata.ExecuteFetchAsAppRequest, opts ...grpc.CallOption) (*tabletmanagerdata.ExecuteFetchAsAppResponse, error)
 	// SlaveStatus returns the current slave status.
 	SlaveStatus(ctx context.Context, in *tabletmanagerdata.SlaveStatusRequest, opts ...grpc.CallOption) (*tabletmanagerdata.SlaveStatusResponse, error)
@@ -272,6 +273,15 @@ func (c *tabletManagerClient) ExecuteFetchAsDba(ctx context.Context, in *tabletm
 	return out, nil
 }
 
+func (c *tabletManagerClient) ExecuteTransactionAsDba(ctx context.Context, in *tabletmanagerdata.ExecuteTransactionAsDbaRequest, opts ...grpc.CallOption) (*tabletmanagerdata.ExecuteTransactionAsDbaResponse, error) {
+	out := new(tabletmanagerdata.ExecuteTransactionAsDbaResponse)
+	err := grpc.Invoke(ctx, "/tabletmanagerservice.TabletManager/ExecuteTransactionAsDba", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *tabletManagerClient) ExecuteFetchAsApp(ctx context.Context, in *tabletmanagerdata.ExecuteFetchAsAppRequest, opts ...grpc.CallOption) (*tabletmanagerdata.ExecuteFetchAsAppResponse, error) {
 	out := new(tabletmanagerdata.ExecuteFetchAsAppResponse)
 	err := grpc.Invoke(ctx, "/tabletmanagerservice.TabletManager/ExecuteFetchAsApp", in, out, c.cc, opts...)
@@ -544,6 +554,7 @@ type TabletManagerServer interface {
 	PreflightSchema(context.Context, *tabletmanagerdata.PreflightSchemaRequest) (*tabletmanagerdata.PreflightSchemaResponse, error)
 	ApplySchema(context.Context, *tabletmanagerdata.ApplySchemaRequest) (*tabletmanagerdata.ApplySchemaResponse, error)
 	ExecuteFetchAsDba(context.Context, *tabletmanagerdata.ExecuteFetchAsDbaRequest) (*tabletmanagerdata.ExecuteFetchAsDbaResponse, error)
+	ExecuteTransactionAsDba(context.Context, *tabletmanagerdata.ExecuteTransactionAsDbaRequest) (*tabletmanagerdata.ExecuteTransactionAsDbaResponse, error)
 	ExecuteFetchAsApp(context.Context, *tabletmanagerdata.ExecuteFetchAsAppRequest) (*tabletmanagerdata.ExecuteFetchAsAppResponse, error)
 	// SlaveStatus returns the current slave status.
 	SlaveStatus(context.Context, *tabletmanagerdata.SlaveStatusRequest) (*tabletmanagerdata.SlaveStatusResponse, error)
@@ -806,6 +817,18 @@ func _TabletManager_ExecuteFetchAsDba_Handler(srv interface{}, ctx context.Conte
 	return out, nil
 }
 
+func _TabletManager_ExecuteTransactionAsDba_Handler(srv interface{}, ctx context.Context, codec grpc.Codec, buf []byte) (interface{}, error) {
+	in := new(tabletmanagerdata.ExecuteTransactionAsDbaRequest)
+	if err := codec.Unmarshal(buf, in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(TabletManagerServer).ExecuteTransactionAsDba(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func _TabletManager_ExecuteFetchAsApp_Handler(srv interface{}, ctx context.Context, codec grpc.Codec, buf []byte) (interface{}, error) {
 	in := new(tabletmanagerdata.ExecuteFetchAsAppRequest)
 	if err := codec.Unmarshal(buf, in); err != nil {
@@ -1179,6 +1202,10 @@ var _TabletManager_serviceDesc = grpc.ServiceDesc{
 			MethodName: "ExecuteFetchAsDba",
 			Handler:    _TabletManager_ExecuteFetchAsDba_Handler,
 		},
+		{
+			MethodName: "ExecuteTransactionAsDba",
+			Handler:    _TabletManager_ExecuteTransactionAsDba_Handler,
+		},
 		{
 			MethodName: "ExecuteFetchAsApp",
 			Handler:    _TabletManager_ExecuteFetchAsApp_Handler,
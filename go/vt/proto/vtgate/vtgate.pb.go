@@ -42,6 +42,10 @@ It has these top-level messages:
 	RollbackResponse
 	SplitQueryRequest
 	SplitQueryResponse
+	SplitQueryV2Request
+	ExplainQueryRequest
+	QueryPlan
+	ExplainQueryResponse
 */
 package vtgate
 
@@ -88,6 +92,16 @@ func (x ExecuteEntityIdsRequest_EntityId_Type) String() string {
 type Session struct {
 	InTransaction bool                    `protobuf:"varint,1,opt,name=in_transaction" json:"in_transaction,omitempty"`
 	ShardSessions []*Session_ShardSession `protobuf:"bytes,2,rep,name=shard_sessions" json:"shard_sessions,omitempty"`
+	// allow_scatter_dml allows updates and deletes to be
+	// sent to all the shards of a keyspace.
+	AllowScatterDml bool `protobuf:"varint,3,opt,name=allow_scatter_dml" json:"allow_scatter_dml,omitempty"`
+	// read_your_writes makes the replica and rdonly reads outside of
+	// transactions see the transactions committed by the session.
+	ReadYourWrites bool                     `protobuf:"varint,4,opt,name=read_your_writes" json:"read_your_writes,omitempty"`
+	ShardPositions []*Session_ShardPosition `protobuf:"bytes,5,rep,name=shard_positions" json:"shard_positions,omitempty"`
+	// max_staleness_seconds, if not zero, is the maximum replication
+	// lag of the tablets serving the replica and rdonly reads.
+	MaxStalenessSeconds int64 `protobuf:"varint,6,opt,name=max_staleness_seconds" json:"max_staleness_seconds,omitempty"`
 }
 
 func (m *Session) Reset()         { *m = Session{} }
@@ -101,6 +115,13 @@ func (m *Session) GetShardSessions() []*Session_ShardSession {
 	return nil
 }
 
+func (m *Session) GetShardPositions() []*Session_ShardPosition {
+	if m != nil {
+		return m.ShardPositions
+	}
+	return nil
+}
+
 type Session_ShardSession struct {
 	Target        *query.Target `protobuf:"bytes,1,opt,name=target" json:"target,omitempty"`
 	TransactionId int64         `protobuf:"varint,2,opt,name=transaction_id" json:"transaction_id,omitempty"`
@@ -117,6 +138,18 @@ func (m *Session_ShardSession) GetTarget() *query.Target {
 	return nil
 }
 
+// ShardPosition is the replication position of the master of a
+// shard after the last commit of the session.
+type Session_ShardPosition struct {
+	Keyspace string `protobuf:"bytes,1,opt,name=keyspace" json:"keyspace,omitempty"`
+	Shard    string `protobuf:"bytes,2,opt,name=shard" json:"shard,omitempty"`
+	Position string `protobuf:"bytes,3,opt,name=position" json:"position,omitempty"`
+}
+
+func (m *Session_ShardPosition) Reset()         { *m = Session_ShardPosition{} }
+func (m *Session_ShardPosition) String() string { return proto.CompactTextString(m) }
+func (*Session_ShardPosition) ProtoMessage()    {}
+
 // ExecuteRequest is the payload to Execute
 type ExecuteRequest struct {
 	CallerId         *vtrpc.CallerID     `protobuf:"bytes,1,opt,name=caller_id" json:"caller_id,omitempty"`
@@ -931,6 +964,8 @@ func (m *CommitRequest) GetSession() *Session {
 // CommitResponse is the returned value from Commit
 type CommitResponse struct {
 	Error *vtrpc.RPCError `protobuf:"bytes,1,opt,name=error" json:"error,omitempty"`
+	// session is only returned for the sessions with read_your_writes.
+	Session *Session `protobuf:"bytes,2,opt,name=session" json:"session,omitempty"`
 }
 
 func (m *CommitResponse) Reset()         { *m = CommitResponse{} }
@@ -944,6 +979,13 @@ func (m *CommitResponse) GetError() *vtrpc.RPCError {
 	return nil
 }
 
+func (m *CommitResponse) GetSession() *Session {
+	if m != nil {
+		return m.Session
+	}
+	return nil
+}
+
 // RollbackRequest is the payload to Rollback
 type RollbackRequest struct {
 	CallerId *vtrpc.CallerID `protobuf:"bytes,1,opt,name=caller_id" json:"caller_id,omitempty"`
@@ -1046,6 +1088,8 @@ func (m *SplitQueryResponse_KeyRangePart) GetKeyRanges() []*topodata.KeyRange {
 type SplitQueryResponse_ShardPart struct {
 	Keyspace string   `protobuf:"bytes,1,opt,name=keyspace" json:"keyspace,omitempty"`
 	Shards   []string `protobuf:"bytes,2,rep,name=shards" json:"shards,omitempty"`
+	// tablet_type is only set by SplitQueryV2.
+	TabletType topodata.TabletType `protobuf:"varint,3,opt,name=tablet_type,enum=topodata.TabletType" json:"tablet_type,omitempty"`
 }
 
 func (m *SplitQueryResponse_ShardPart) Reset()         { *m = SplitQueryResponse_ShardPart{} }
@@ -1084,6 +1128,128 @@ func (m *SplitQueryResponse_Part) GetShardPart() *SplitQueryResponse_ShardPart {
 	return nil
 }
 
+// SplitQueryV2Request is the payload to SplitQueryV2. The query is
+// split on ranges of split_columns, which must be a prefix of the
+// primary key of its table, or empty to use the whole primary key.
+// Each part has about num_rows_per_query_part rows. If it's 0, there
+// are about split_count parts. The parts are sent to tablet_type
+// tablets, rdonly if unknown.
+type SplitQueryV2Request struct {
+	CallerId            *vtrpc.CallerID     `protobuf:"bytes,1,opt,name=caller_id" json:"caller_id,omitempty"`
+	Keyspace            string              `protobuf:"bytes,2,opt,name=keyspace" json:"keyspace,omitempty"`
+	Query               *query.BoundQuery   `protobuf:"bytes,3,opt,name=query" json:"query,omitempty"`
+	SplitColumns        []string            `protobuf:"bytes,4,rep,name=split_columns" json:"split_columns,omitempty"`
+	SplitCount          int64               `protobuf:"varint,5,opt,name=split_count" json:"split_count,omitempty"`
+	NumRowsPerQueryPart int64               `protobuf:"varint,6,opt,name=num_rows_per_query_part" json:"num_rows_per_query_part,omitempty"`
+	TabletType          topodata.TabletType `protobuf:"varint,7,opt,name=tablet_type,enum=topodata.TabletType" json:"tablet_type,omitempty"`
+}
+
+func (m *SplitQueryV2Request) Reset()         { *m = SplitQueryV2Request{} }
+func (m *SplitQueryV2Request) String() string { return proto.CompactTextString(m) }
+func (*SplitQueryV2Request) ProtoMessage()    {}
+
+func (m *SplitQueryV2Request) GetCallerId() *vtrpc.CallerID {
+	if m != nil {
+		return m.CallerId
+	}
+	return nil
+}
+
+func (m *SplitQueryV2Request) GetQuery() *query.BoundQuery {
+	if m != nil {
+		return m.Query
+	}
+	return nil
+}
+
+// ExplainQueryRequest is the payload to ExplainQuery
+type ExplainQueryRequest struct {
+	CallerId   *vtrpc.CallerID     `protobuf:"bytes,1,opt,name=caller_id" json:"caller_id,omitempty"`
+	Query      *query.BoundQuery   `protobuf:"bytes,2,opt,name=query" json:"query,omitempty"`
+	TabletType topodata.TabletType `protobuf:"varint,3,opt,name=tablet_type,enum=topodata.TabletType" json:"tablet_type,omitempty"`
+}
+
+func (m *ExplainQueryRequest) Reset()         { *m = ExplainQueryRequest{} }
+func (m *ExplainQueryRequest) String() string { return proto.CompactTextString(m) }
+func (*ExplainQueryRequest) ProtoMessage()    {}
+
+func (m *ExplainQueryRequest) GetCallerId() *vtrpc.CallerID {
+	if m != nil {
+		return m.CallerId
+	}
+	return nil
+}
+
+func (m *ExplainQueryRequest) GetQuery() *query.BoundQuery {
+	if m != nil {
+		return m.Query
+	}
+	return nil
+}
+
+// QueryPlan describes how vtgate routes a query.
+type QueryPlan struct {
+	// plan_id is the name of the plan, like SelectEqual.
+	PlanId string `protobuf:"bytes,1,opt,name=plan_id" json:"plan_id,omitempty"`
+	// reason is set if vtgate cannot route the query.
+	Reason    string `protobuf:"bytes,2,opt,name=reason" json:"reason,omitempty"`
+	Table     string `protobuf:"bytes,3,opt,name=table" json:"table,omitempty"`
+	Col       string `protobuf:"bytes,4,opt,name=col" json:"col,omitempty"`
+	Vindex    string `protobuf:"bytes,5,opt,name=vindex" json:"vindex,omitempty"`
+	Rewritten string `protobuf:"bytes,6,opt,name=rewritten" json:"rewritten,omitempty"`
+	Keyspace  string `protobuf:"bytes,7,opt,name=keyspace" json:"keyspace,omitempty"`
+	// shards are the shards the query is sent to for the
+	// bind variables of the request.
+	Shards []string `protobuf:"bytes,8,rep,name=shards" json:"shards,omitempty"`
+	// left and right are the plans of the two sides of a join.
+	// The shards of right depend on the rows returned by left,
+	// and are not set.
+	Left  *QueryPlan `protobuf:"bytes,9,opt,name=left" json:"left,omitempty"`
+	Right *QueryPlan `protobuf:"bytes,10,opt,name=right" json:"right,omitempty"`
+}
+
+func (m *QueryPlan) Reset()         { *m = QueryPlan{} }
+func (m *QueryPlan) String() string { return proto.CompactTextString(m) }
+func (*QueryPlan) ProtoMessage()    {}
+
+func (m *QueryPlan) GetLeft() *QueryPlan {
+	if m != nil {
+		return m.Left
+	}
+	return nil
+}
+
+func (m *QueryPlan) GetRight() *QueryPlan {
+	if m != nil {
+		return m.Right
+	}
+	return nil
+}
+
+// ExplainQueryResponse is the returned value from ExplainQuery
+type ExplainQueryResponse struct {
+	Error *vtrpc.RPCError `protobuf:"bytes,1,opt,name=error" json:"error,omitempty"`
+	Plan  *QueryPlan      `protobuf:"bytes,2,opt,name=plan" json:"plan,omitempty"`
+}
+
+func (m *ExplainQueryResponse) Reset()         { *m = ExplainQueryResponse{} }
+func (m *ExplainQueryResponse) String() string { return proto.CompactTextString(m) }
+func (*ExplainQueryResponse) ProtoMessage()    {}
+
+func (m *ExplainQueryResponse) GetError() *vtrpc.RPCError {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+func (m *ExplainQueryResponse) GetPlan() *QueryPlan {
+	if m != nil {
+		return m.Plan
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterEnum("vtgate.ExecuteEntityIdsRequest_EntityId_Type", ExecuteEntityIdsRequest_EntityId_Type_name, ExecuteEntityIdsRequest_EntityId_Type_value)
 }
@@ -65,6 +65,11 @@ type VitessClient interface {
 	Rollback(ctx context.Context, in *vtgate.RollbackRequest, opts ...grpc.CallOption) (*vtgate.RollbackResponse, error)
 	// Split a query into non-overlapping sub queries
 	SplitQuery(ctx context.Context, in *vtgate.SplitQueryRequest, opts ...grpc.CallOption) (*vtgate.SplitQueryResponse, error)
+	// Split a query into non-overlapping sub queries on ranges of
+	// its primary key, with a keyspace and shard per sub query
+	SplitQueryV2(ctx context.Context, in *vtgate.SplitQueryV2Request, opts ...grpc.CallOption) (*vtgate.SplitQueryResponse, error)
+	// ExplainQuery returns how a query would be routed, without executing it.
+	ExplainQuery(ctx context.Context, in *vtgate.ExplainQueryRequest, opts ...grpc.CallOption) (*vtgate.ExplainQueryResponse, error)
 }
 
 type vitessClient struct {
@@ -302,6 +307,24 @@ func (c *vitessClient) SplitQuery(ctx context.Context, in *vtgate.SplitQueryRequ
 	return out, nil
 }
 
+func (c *vitessClient) SplitQueryV2(ctx context.Context, in *vtgate.SplitQueryV2Request, opts ...grpc.CallOption) (*vtgate.SplitQueryResponse, error) {
+	out := new(vtgate.SplitQueryResponse)
+	err := grpc.Invoke(ctx, "/vtgateservice.Vitess/SplitQueryV2", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vitessClient) ExplainQuery(ctx context.Context, in *vtgate.ExplainQueryRequest, opts ...grpc.CallOption) (*vtgate.ExplainQueryResponse, error) {
+	out := new(vtgate.ExplainQueryResponse)
+	err := grpc.Invoke(ctx, "/vtgateservice.Vitess/ExplainQuery", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Vitess service
 
 type VitessServer interface {
@@ -340,6 +363,11 @@ type VitessServer interface {
 	Rollback(context.Context, *vtgate.RollbackRequest) (*vtgate.RollbackResponse, error)
 	// Split a query into non-overlapping sub queries
 	SplitQuery(context.Context, *vtgate.SplitQueryRequest) (*vtgate.SplitQueryResponse, error)
+	// Split a query into non-overlapping sub queries on ranges of
+	// its primary key, with a keyspace and shard per sub query
+	SplitQueryV2(context.Context, *vtgate.SplitQueryV2Request) (*vtgate.SplitQueryResponse, error)
+	// ExplainQuery returns how a query would be routed, without executing it.
+	ExplainQuery(context.Context, *vtgate.ExplainQueryRequest) (*vtgate.ExplainQueryResponse, error)
 }
 
 func RegisterVitessServer(s *grpc.Server, srv VitessServer) {
@@ -562,6 +590,30 @@ func _Vitess_SplitQuery_Handler(srv interface{}, ctx context.Context, codec grpc
 	return out, nil
 }
 
+func _Vitess_SplitQueryV2_Handler(srv interface{}, ctx context.Context, codec grpc.Codec, buf []byte) (interface{}, error) {
+	in := new(vtgate.SplitQueryV2Request)
+	if err := codec.Unmarshal(buf, in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(VitessServer).SplitQueryV2(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func _Vitess_ExplainQuery_Handler(srv interface{}, ctx context.Context, codec grpc.Codec, buf []byte) (interface{}, error) {
+	in := new(vtgate.ExplainQueryRequest)
+	if err := codec.Unmarshal(buf, in); err != nil {
+		return nil, err
+	}
+	out, err := srv.(VitessServer).ExplainQuery(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 var _Vitess_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "vtgateservice.Vitess",
 	HandlerType: (*VitessServer)(nil),
@@ -610,6 +662,14 @@ var _Vitess_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SplitQuery",
 			Handler:    _Vitess_SplitQuery_Handler,
 		},
+		{
+			MethodName: "SplitQueryV2",
+			Handler:    _Vitess_SplitQueryV2_Handler,
+		},
+		{
+			MethodName: "ExplainQuery",
+			Handler:    _Vitess_ExplainQuery_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
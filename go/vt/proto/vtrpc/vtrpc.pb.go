@@ -34,6 +34,13 @@ const (
 	// VtgateError is the base VTGate error code. All VTGate errors
 	// should be 4 digits, starting with 2.
 	ErrorCode_VtgateError ErrorCode = 2000
+	// QuotaExceeded is returned by VTGate when a query is rejected
+	// because its caller is over one of its quotas.
+	ErrorCode_QuotaExceeded ErrorCode = 2001
+	// ResourceExhausted is returned by VTGate when a query is aborted
+	// because it's over one of the limits of the resources it can
+	// use, like the rows a stream can return.
+	ErrorCode_ResourceExhausted ErrorCode = 2002
 	// UnknownVtgateError is the code for an unknown error that came from VTGate.
 	ErrorCode_UnknownVtgateError ErrorCode = 2999
 )
@@ -43,6 +50,8 @@ var ErrorCode_name = map[int32]string{
 	1000: "TabletError",
 	1999: "UnknownTabletError",
 	2000: "VtgateError",
+	2001: "QuotaExceeded",
+	2002: "ResourceExhausted",
 	2999: "UnknownVtgateError",
 }
 var ErrorCode_value = map[string]int32{
@@ -50,6 +59,8 @@ var ErrorCode_value = map[string]int32{
 	"TabletError":        1000,
 	"UnknownTabletError": 1999,
 	"VtgateError":        2000,
+	"QuotaExceeded":      2001,
+	"ResourceExhausted":  2002,
 	"UnknownVtgateError": 2999,
 }
 
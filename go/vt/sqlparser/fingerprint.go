@@ -0,0 +1,50 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlparser
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// NormalizedString returns the query of node without its literals:
+// strings, numbers and bind variables are replaced by '?', lists of
+// values by '(?)', and comments are removed. Queries that only differ
+// by their values have the same normalized string.
+func NormalizedString(node SQLNode) string {
+	buf := NewTrackedBuffer(formatNormalized)
+	buf.Myprintf("%v", node)
+	return buf.String()
+}
+
+func formatNormalized(buf *TrackedBuffer, node SQLNode) {
+	switch node := node.(type) {
+	case StrVal, NumVal, ValArg, ListArg:
+		buf.WriteString("?")
+	case ValTuple:
+		for _, expr := range node {
+			if !IsValue(expr) {
+				node.Format(buf)
+				return
+			}
+		}
+		buf.WriteString("(?)")
+	case Comments:
+	default:
+		node.Format(buf)
+	}
+}
+
+// Fingerprint returns the fingerprint of sql: a hash of the normalized
+// string of its statement (see NormalizedString).
+func Fingerprint(sql string) (string, error) {
+	statement, err := Parse(sql)
+	if err != nil {
+		return "", err
+	}
+	h := fnv.New64a()
+	h.Write([]byte(NormalizedString(statement)))
+	return fmt.Sprintf("%016x", h.Sum64()), nil
+}
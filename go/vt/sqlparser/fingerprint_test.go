@@ -0,0 +1,60 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlparser
+
+import "testing"
+
+func TestNormalizedString(t *testing.T) {
+	testcases := []struct {
+		in  string
+		out string
+	}{
+		{"select /* comment */ a from t where b = 1 and c = 'x'", "select a from t where b = ? and c = ?"},
+		{"select a from t where b = :b limit 10, 20", "select a from t where b = ? limit ?, ?"},
+		{"select a from t where b in (1, 2, 3)", "select a from t where b in (?)"},
+		{"select a from t where b in ::list", "select a from t where b in ?"},
+		{"select a from t where (b, c) in ((1, 2))", "select a from t where (b, c) in ((?))"},
+		{"select a from t where b is null", "select a from t where b is null"},
+		{"insert into t(a, b) values (1, 'x')", "insert into t(a, b) values (?)"},
+		{"update t set a = a + 1 where id = 3", "update t set a = a+? where id = ?"},
+	}
+	for _, tc := range testcases {
+		tree, err := Parse(tc.in)
+		if err != nil {
+			t.Errorf("Parse(%s): %v", tc.in, err)
+			continue
+		}
+		if got := NormalizedString(tree); got != tc.out {
+			t.Errorf("NormalizedString(%s): %s, want %s", tc.in, got, tc.out)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	fp1, err := Fingerprint("select a from t where b = 1 and c in (1, 2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fp1) != 16 {
+		t.Errorf("Fingerprint: %s, want 16 hex digits", fp1)
+	}
+	fp2, err := Fingerprint("select /* other */ a from t where b = :b and c in (3)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp1 != fp2 {
+		t.Errorf("Fingerprint of the same query shape: %s != %s", fp1, fp2)
+	}
+	fp3, err := Fingerprint("select a from t where b = 1 or c in (1, 2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp1 == fp3 {
+		t.Errorf("Fingerprint of different queries: %s == %s", fp1, fp3)
+	}
+	if _, err := Fingerprint("select from"); err == nil {
+		t.Errorf("Fingerprint of an invalid query should fail")
+	}
+}
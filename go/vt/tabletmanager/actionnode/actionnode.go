@@ -152,6 +152,10 @@ const (
 	// TabletActionExecuteFetchAsDba uses the DBA connection to run queries.
 	TabletActionExecuteFetchAsDba = "ExecuteFetchAsDba"
 
+	// TabletActionExecuteTransactionAsDba uses the DBA connection to run
+	// a transaction.
+	TabletActionExecuteTransactionAsDba = "ExecuteTransactionAsDba"
+
 	// TabletActionExecuteFetchAsApp uses the App connection to run queries.
 	TabletActionExecuteFetchAsApp = "ExecuteFetchAsApp"
 
@@ -168,6 +168,10 @@ func NewActionAgent(
 	agent.BinlogPlayerMap = NewBinlogPlayerMap(topoServer, &dbcfgs.Filtered, mysqld)
 	RegisterBinlogPlayerMap(agent.BinlogPlayerMap)
 
+	// The query service resolves the abandoned distributed
+	// transactions through the masters of their participants.
+	tabletserver.RegisterTwoPCClient(newTwoPCClient(topoServer, tabletAlias.Cell))
+
 	// try to figure out the mysql port
 	mysqlPort := mycnf.MysqlPort
 	if mysqlPort == 0 {
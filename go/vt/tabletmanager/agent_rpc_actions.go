@@ -60,6 +60,8 @@ type RPCAgent interface {
 
 	ExecuteFetchAsDba(ctx context.Context, query string, dbName string, maxrows int, wantFields, disableBinlogs bool, reloadSchema bool) (*proto.QueryResult, error)
 
+	ExecuteTransactionAsDba(ctx context.Context, queries []string, dbName string) error
+
 	ExecuteFetchAsApp(ctx context.Context, query string, maxrows int, wantFields bool) (*proto.QueryResult, error)
 
 	// Replication related methods
@@ -271,6 +273,38 @@ func (agent *ActionAgent) ExecuteFetchAsDba(ctx context.Context, query string, d
 	return qr, err
 }
 
+// ExecuteTransactionAsDba will execute the given queries in one transaction,
+// one by one on the same connection. If a query fails, the transaction is
+// rolled back.
+// Should be called under RPCWrap.
+func (agent *ActionAgent) ExecuteTransactionAsDba(ctx context.Context, queries []string, dbName string) error {
+	// get a connection
+	conn, err := agent.MysqlDaemon.GetDbaConnection()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if dbName != "" {
+		if _, err := conn.ExecuteFetch("USE "+dbName, 1, false); err != nil {
+			return err
+		}
+	}
+	if _, err := conn.ExecuteFetch("BEGIN", 0, false); err != nil {
+		return err
+	}
+	for _, query := range queries {
+		if _, err := conn.ExecuteFetch(query, 0, false); err != nil {
+			// A failed rollback is ignored: closing the
+			// connection rolls the transaction back too.
+			conn.ExecuteFetch("ROLLBACK", 0, false)
+			return err
+		}
+	}
+	_, err = conn.ExecuteFetch("COMMIT", 0, false)
+	return err
+}
+
 // ExecuteFetchAsApp will execute the given query, possibly disabling binlogs.
 // Should be called under RPCWrap.
 func (agent *ActionAgent) ExecuteFetchAsApp(ctx context.Context, query string, maxrows int, wantFields bool) (*proto.QueryResult, error) {
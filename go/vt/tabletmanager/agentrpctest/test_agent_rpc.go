@@ -524,6 +524,16 @@ func (fra *fakeRPCAgent) ExecuteFetchAsDba(ctx context.Context, query string, db
 	return testExecuteFetchResult, nil
 }
 
+var testExecuteTransactionQueries = []string{"begin this", "fetch this"}
+
+func (fra *fakeRPCAgent) ExecuteTransactionAsDba(ctx context.Context, queries []string, dbName string) error {
+	if fra.panics {
+		panic(fmt.Errorf("test-triggered panic"))
+	}
+	compare(fra.t, "ExecuteTransactionAsDba queries", queries, testExecuteTransactionQueries)
+	return nil
+}
+
 func (fra *fakeRPCAgent) ExecuteFetchAsApp(ctx context.Context, query string, maxrows int, wantFields bool) (*mproto.QueryResult, error) {
 	if fra.panics {
 		panic(fmt.Errorf("test-triggered panic"))
@@ -537,6 +547,10 @@ func (fra *fakeRPCAgent) ExecuteFetchAsApp(ctx context.Context, query string, ma
 func agentRPCTestExecuteFetch(ctx context.Context, t *testing.T, client tmclient.TabletManagerClient, ti *topo.TabletInfo) {
 	qr, err := client.ExecuteFetchAsDba(ctx, ti, testExecuteFetchQuery, testExecuteFetchMaxRows, true, true, true)
 	compareError(t, "ExecuteFetchAsDba", err, qr, testExecuteFetchResult)
+	err = client.ExecuteTransactionAsDba(ctx, ti, testExecuteTransactionQueries)
+	if err != nil {
+		t.Errorf("ExecuteTransactionAsDba failed: %v", err)
+	}
 	qr, err = client.ExecuteFetchAsApp(ctx, ti, testExecuteFetchQuery, testExecuteFetchMaxRows, true)
 	compareError(t, "ExecuteFetchAsApp", err, qr, testExecuteFetchResult)
 }
@@ -545,6 +559,9 @@ func agentRPCTestExecuteFetchPanic(ctx context.Context, t *testing.T, client tmc
 	_, err := client.ExecuteFetchAsDba(ctx, ti, testExecuteFetchQuery, testExecuteFetchMaxRows, true, true, false)
 	expectRPCWrapPanic(t, err)
 
+	err = client.ExecuteTransactionAsDba(ctx, ti, testExecuteTransactionQueries)
+	expectRPCWrapPanic(t, err)
+
 	_, err = client.ExecuteFetchAsApp(ctx, ti, testExecuteFetchQuery, testExecuteFetchMaxRows, true)
 	expectRPCWrapPanic(t, err)
 }
@@ -139,6 +139,11 @@ func (client *FakeTabletManagerClient) ExecuteFetchAsDba(ctx context.Context, ta
 	return &qr, nil
 }
 
+// ExecuteTransactionAsDba is part of the tmclient.TabletManagerClient interface
+func (client *FakeTabletManagerClient) ExecuteTransactionAsDba(ctx context.Context, tablet *topo.TabletInfo, queries []string) error {
+	return nil
+}
+
 // ExecuteFetchAsApp is part of the tmclient.TabletManagerClient interface
 func (client *FakeTabletManagerClient) ExecuteFetchAsApp(ctx context.Context, tablet *topo.TabletInfo, query string, maxRows int, wantFields bool) (*mproto.QueryResult, error) {
 	var qr mproto.QueryResult
@@ -92,6 +92,12 @@ type ExecuteFetchArgs struct {
 	ReloadSchema   bool
 }
 
+// ExecuteTransactionArgs has arguments for ExecuteTransactionAsDba
+type ExecuteTransactionArgs struct {
+	Queries []string
+	DbName  string
+}
+
 // BackupArgs has arguments for Backup
 type BackupArgs struct {
 	Concurrency int
@@ -190,6 +190,14 @@ func (client *GoRPCTabletManagerClient) ExecuteFetchAsDba(ctx context.Context, t
 	return &qr, nil
 }
 
+// ExecuteTransactionAsDba is part of the tmclient.TabletManagerClient interface
+func (client *GoRPCTabletManagerClient) ExecuteTransactionAsDba(ctx context.Context, tablet *topo.TabletInfo, queries []string) error {
+	return client.rpcCallTablet(ctx, tablet, actionnode.TabletActionExecuteTransactionAsDba, &gorpcproto.ExecuteTransactionArgs{
+		Queries: queries,
+		DbName:  tablet.DbName(),
+	}, &rpc.Unused{})
+}
+
 // ExecuteFetchAsApp is part of the tmclient.TabletManagerClient interface
 func (client *GoRPCTabletManagerClient) ExecuteFetchAsApp(ctx context.Context, tablet *topo.TabletInfo, query string, maxRows int, wantFields bool) (*mproto.QueryResult, error) {
 	var qr mproto.QueryResult
@@ -183,6 +183,14 @@ func (tm *TabletManager) ExecuteFetchAsDba(ctx context.Context, args *gorpcproto
 	})
 }
 
+// ExecuteTransactionAsDba wraps RPCAgent.ExecuteTransactionAsDba
+func (tm *TabletManager) ExecuteTransactionAsDba(ctx context.Context, args *gorpcproto.ExecuteTransactionArgs, reply *rpc.Unused) error {
+	ctx = callinfo.RPCWrapCallInfo(ctx)
+	return tm.agent.RPCWrap(ctx, actionnode.TabletActionExecuteTransactionAsDba, args, reply, func() error {
+		return tm.agent.ExecuteTransactionAsDba(ctx, args.Queries, args.DbName)
+	})
+}
+
 // ExecuteFetchAsApp wraps RPCAgent.ExecuteFetchAsApp
 func (tm *TabletManager) ExecuteFetchAsApp(ctx context.Context, args *gorpcproto.ExecuteFetchArgs, reply *mproto.QueryResult) error {
 	ctx = callinfo.RPCWrapCallInfo(ctx)
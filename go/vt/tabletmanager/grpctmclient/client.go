@@ -303,6 +303,20 @@ func (client *Client) ExecuteFetchAsDba(ctx context.Context, tablet *topo.Tablet
 	return mproto.Proto3ToQueryResult(response.Result), nil
 }
 
+// ExecuteTransactionAsDba is part of the tmclient.TabletManagerClient interface
+func (client *Client) ExecuteTransactionAsDba(ctx context.Context, tablet *topo.TabletInfo, queries []string) error {
+	cc, c, err := client.dial(ctx, tablet)
+	if err != nil {
+		return err
+	}
+	defer cc.Close()
+	_, err = c.ExecuteTransactionAsDba(ctx, &pb.ExecuteTransactionAsDbaRequest{
+		Queries: queries,
+		DbName:  tablet.DbName(),
+	})
+	return err
+}
+
 // ExecuteFetchAsApp is part of the tmclient.TabletManagerClient interface
 func (client *Client) ExecuteFetchAsApp(ctx context.Context, tablet *topo.TabletInfo, query string, maxRows int, wantFields bool) (*mproto.QueryResult, error) {
 	cc, c, err := client.dial(ctx, tablet)
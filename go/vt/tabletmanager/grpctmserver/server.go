@@ -198,6 +198,14 @@ func (s *server) ExecuteFetchAsDba(ctx context.Context, request *pb.ExecuteFetch
 	})
 }
 
+func (s *server) ExecuteTransactionAsDba(ctx context.Context, request *pb.ExecuteTransactionAsDbaRequest) (*pb.ExecuteTransactionAsDbaResponse, error) {
+	ctx = callinfo.GRPCCallInfo(ctx)
+	response := &pb.ExecuteTransactionAsDbaResponse{}
+	return response, s.agent.RPCWrap(ctx, actionnode.TabletActionExecuteTransactionAsDba, request, response, func() error {
+		return s.agent.ExecuteTransactionAsDba(ctx, request.Queries, request.DbName)
+	})
+}
+
 func (s *server) ExecuteFetchAsApp(ctx context.Context, request *pb.ExecuteFetchAsAppRequest) (*pb.ExecuteFetchAsAppResponse, error) {
 	ctx = callinfo.GRPCCallInfo(ctx)
 	response := &pb.ExecuteFetchAsAppResponse{}
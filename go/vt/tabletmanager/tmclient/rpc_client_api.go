@@ -81,6 +81,10 @@ type TabletManagerClient interface {
 	// ExecuteFetchAsDba executes a query remotely using the DBA pool
 	ExecuteFetchAsDba(ctx context.Context, tablet *topo.TabletInfo, query string, maxRows int, wantFields, disableBinlogs, reloadSchema bool) (*mproto.QueryResult, error)
 
+	// ExecuteTransactionAsDba executes queries remotely in one
+	// transaction, on a single connection of the DBA pool
+	ExecuteTransactionAsDba(ctx context.Context, tablet *topo.TabletInfo, queries []string) error
+
 	// ExecuteFetchAsApp executes a query remotely using the App pool
 	ExecuteFetchAsApp(ctx context.Context, tablet *topo.TabletInfo, query string, maxRows int, wantFields bool) (*mproto.QueryResult, error)
 
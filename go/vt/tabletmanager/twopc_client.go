@@ -0,0 +1,61 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tabletmanager
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	tproto "github.com/youtube/vitess/go/vt/tabletserver/proto"
+	"github.com/youtube/vitess/go/vt/tabletserver/tabletconn"
+	"github.com/youtube/vitess/go/vt/topo"
+	"golang.org/x/net/context"
+)
+
+var twoPCConnectTimeout = flag.Duration("twopc_connect_timeout", 30*time.Second, "connect timeout to the participants of the distributed transactions that the tablet resolves")
+
+// twoPCClient implements tabletserver.TwoPCClient: it resolves
+// the prepared transactions of a participant on its master,
+// which it finds in the serving graph of the cell of the tablet.
+type twoPCClient struct {
+	ts   topo.Server
+	cell string
+}
+
+func newTwoPCClient(ts topo.Server, cell string) *twoPCClient {
+	return &twoPCClient{ts: ts, cell: cell}
+}
+
+func (client *twoPCClient) dial(ctx context.Context, participant tproto.DTParticipant) (tabletconn.TabletConn, error) {
+	addrs, _, err := client.ts.GetEndPoints(ctx, client.cell, participant.Keyspace, participant.Shard, topo.TYPE_MASTER)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs.Entries) == 0 {
+		return nil, fmt.Errorf("no master for %s/%s in cell %s", participant.Keyspace, participant.Shard, client.cell)
+	}
+	return tabletconn.GetDialer()(ctx, addrs.Entries[0], participant.Keyspace, participant.Shard, *twoPCConnectTimeout)
+}
+
+// CommitPrepared is part of the tabletserver.TwoPCClient interface.
+func (client *twoPCClient) CommitPrepared(ctx context.Context, participant tproto.DTParticipant, dtid string) error {
+	conn, err := client.dial(ctx, participant)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.CommitPrepared(ctx, dtid)
+}
+
+// RollbackPrepared is part of the tabletserver.TwoPCClient interface.
+func (client *twoPCClient) RollbackPrepared(ctx context.Context, participant tproto.DTParticipant, dtid string) error {
+	conn, err := client.dial(ctx, participant)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.RollbackPrepared(ctx, dtid)
+}
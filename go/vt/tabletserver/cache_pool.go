@@ -5,6 +5,7 @@
 package tabletserver
 
 import (
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -28,6 +29,7 @@ type CachePool struct {
 	pool              *pools.ResourcePool
 	maxPrefix         sync2.AtomicInt64
 	cmd               *exec.Cmd
+	cacheConn         cacheservice.CacheService
 	rowCacheConfig    RowCacheConfig
 	capacity          int
 	socket            string
@@ -79,7 +81,7 @@ func NewCachePool(
 	}
 	http.Handle(statsURL, cp)
 
-	if rowCacheConfig.Binary == "" {
+	if !rowCacheConfig.Enabled() {
 		return cp
 	}
 	cp.rowCacheConfig = rowCacheConfig
@@ -95,24 +97,27 @@ func NewCachePool(
 	return cp
 }
 
-// Open opens the pool. It launches memcache and waits till it's up.
+// Open opens the pool. It launches memcache and waits till it's up,
+// or creates the cache of an in-process backend.
 func (cp *CachePool) Open() {
 	cp.mu.Lock()
 	defer cp.mu.Unlock()
 	if cp.pool != nil {
 		panic(NewTabletError(ErrFatal, "rowcache is already open"))
 	}
-	if cp.rowCacheConfig.Binary == "" {
-		panic(NewTabletError(ErrFatal, "rowcache binary not specified"))
+	if cp.rowCacheConfig.InProcess() {
+		cp.socket = fmt.Sprintf("%s%d", cp.name, inProcessCaches.Add(1))
+		cp.startInProcessCache()
+	} else {
+		if cp.rowCacheConfig.Binary == "" {
+			panic(NewTabletError(ErrFatal, "rowcache binary not specified"))
+		}
+		cp.socket = generateFilename(cp.rowCacheConfig.Socket)
+		cp.startCacheService()
 	}
-	cp.socket = generateFilename(cp.rowCacheConfig.Socket)
-	cp.startCacheService()
 	log.Infof("rowcache is enabled")
 	f := func() (pools.Resource, error) {
-		return cacheservice.Connect(cacheservice.Config{
-			Address: cp.socket,
-			Timeout: 10 * time.Second,
-		})
+		return cp.connect(10 * time.Second)
 	}
 	cp.pool = pools.NewResourcePool(f, cp.capacity, cp.capacity, cp.idleTimeout)
 	if cp.memcacheStats != nil {
@@ -142,6 +147,38 @@ func generateFilename(hint string) string {
 	return name
 }
 
+// inProcessCaches numbers the caches of the in-process backends, so
+// that every Open gets a new cache.
+var inProcessCaches sync2.AtomicInt64
+
+// connect returns a connection to the cache service of the pool.
+func (cp *CachePool) connect(timeout time.Duration) (cacheservice.CacheService, error) {
+	config := cacheservice.Config{
+		Address:  cp.socket,
+		Timeout:  timeout,
+		Capacity: int64(cp.rowCacheConfig.Memory),
+	}
+	if cp.rowCacheConfig.Backend == "" {
+		return cacheservice.Connect(config)
+	}
+	return cacheservice.ConnectTo(cp.rowCacheConfig.Backend, config)
+}
+
+// startInProcessCache creates the cache of an in-process backend. The
+// connection to it keeps it alive while the pool is open, like the
+// memcached process.
+func (cp *CachePool) startInProcessCache() {
+	c, err := cp.connect(10 * time.Second)
+	if err != nil {
+		panic(NewTabletError(ErrFatal, "can't start rowcache backend %s: %v", cp.rowCacheConfig.Backend, err))
+	}
+	if _, err = c.Set("health", 0, 0, []byte("ok")); err != nil {
+		c.Close()
+		panic(NewTabletError(ErrFatal, "can't communicate with cache service: %v", err))
+	}
+	cp.cacheConn = c
+}
+
 func (cp *CachePool) startCacheService() {
 	commandLine := cp.rowCacheConfig.GetSubprocessFlags(cp.socket)
 	cp.cmd = exec.Command(commandLine[0], commandLine[1:]...)
@@ -150,10 +187,7 @@ func (cp *CachePool) startCacheService() {
 	}
 	attempts := 0
 	for {
-		c, err := cacheservice.Connect(cacheservice.Config{
-			Address: cp.socket,
-			Timeout: 30 * time.Millisecond,
-		})
+		c, err := cp.connect(30 * time.Millisecond)
 
 		if err != nil {
 			attempts++
@@ -198,10 +232,15 @@ func (cp *CachePool) Close() {
 	if cp.memcacheStats != nil {
 		cp.memcacheStats.Close()
 	}
-	cp.cmd.Process.Kill()
-	// Avoid zombies
-	go cp.cmd.Wait()
-	_ = os.Remove(cp.socket)
+	if cp.cacheConn != nil {
+		cp.cacheConn.Close()
+		cp.cacheConn = nil
+	} else {
+		cp.cmd.Process.Kill()
+		// Avoid zombies
+		go cp.cmd.Wait()
+		_ = os.Remove(cp.socket)
+	}
 	cp.socket = ""
 	cp.pool = nil
 }
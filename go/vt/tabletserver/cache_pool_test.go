@@ -14,6 +14,7 @@ import (
 	"testing"
 	"time"
 
+	_ "github.com/youtube/vitess/go/cacheservice/lrucacheservice"
 	"github.com/youtube/vitess/go/vt/tabletserver/fakecacheservice"
 	"github.com/youtube/vitess/go/vt/vttest/fakesqldb"
 	"golang.org/x/net/context"
@@ -257,6 +258,43 @@ func TestCachePoolFailToStartBecauseCacheServiceWasDown(t *testing.T) {
 	cachePool.Open()
 }
 
+func TestCachePoolInProcess(t *testing.T) {
+	fakecacheservice.Register()
+	fakesqldb.Register()
+	rowCacheConfig := RowCacheConfig{
+		Backend:     "lru",
+		Memory:      1024 * 1024,
+		Connections: 100,
+	}
+	cachePool := newTestCachePool(rowCacheConfig, false)
+	cachePool.Open()
+	if cachePool.IsClosed() {
+		t.Fatalf("cache pool is closed")
+	}
+	ctx := context.Background()
+	conn := cachePool.Get(ctx)
+	conn.Set("key", 0, 0, []byte("value"))
+	cachePool.Put(conn)
+	conn = cachePool.Get(ctx)
+	if results, _ := conn.Get("key"); len(results) != 1 {
+		t.Errorf("in-process cache lost its value: %v", results)
+	}
+	cachePool.Put(conn)
+	cachePool.Close()
+	if !cachePool.IsClosed() {
+		t.Fatalf("cache pool is not closed")
+	}
+
+	// Like memcached, the cache starts empty after a restart.
+	cachePool.Open()
+	defer cachePool.Close()
+	conn = cachePool.Get(ctx)
+	defer cachePool.Put(conn)
+	if results, _ := conn.Get("key"); len(results) != 0 {
+		t.Errorf("reopened cache must be empty: %v", results)
+	}
+}
+
 func newTestCachePool(rowcacheConfig RowCacheConfig, enablePublishStats bool) *CachePool {
 	randID := rand.Int63()
 	name := fmt.Sprintf("TestCachePool-%d-", randID)
@@ -11,6 +11,7 @@ import (
 	"github.com/youtube/vitess/go/pools"
 	"github.com/youtube/vitess/go/sqldb"
 	"github.com/youtube/vitess/go/stats"
+	"github.com/youtube/vitess/go/sync2"
 	"github.com/youtube/vitess/go/vt/dbconnpool"
 	"golang.org/x/net/context"
 )
@@ -21,6 +22,8 @@ import (
 // also trigger a CheckMySQL call if we fail to connect to MySQL.
 // Other than the connection type, ConnPool maintains an additional
 // pool of dba connections that are used to kill connections.
+// It can also limit the number of connections each effective caller
+// holds at a time, so that a single caller can't use up the pool.
 type ConnPool struct {
 	mu                sync.Mutex
 	connections       *pools.ResourcePool
@@ -28,6 +31,13 @@ type ConnPool struct {
 	idleTimeout       time.Duration
 	dbaPool           *dbconnpool.ConnectionPool
 	queryServiceStats *QueryServiceStats
+
+	// callerQuota is the max number of connections per caller.
+	// 0 means unlimited.
+	callerQuota     sync2.AtomicInt64
+	callerMu        sync.Mutex
+	callerConns     map[string]int64
+	quotaRejections *stats.Counters
 }
 
 // NewConnPool creates a new ConnPool. The name is used
@@ -43,11 +53,18 @@ func NewConnPool(
 		idleTimeout:       idleTimeout,
 		dbaPool:           dbconnpool.NewConnectionPool("", 1, idleTimeout),
 		queryServiceStats: queryServiceStats,
+		callerConns:       make(map[string]int64),
+	}
+	rejectionsName := ""
+	if name != "" && enablePublishStats {
+		rejectionsName = name + "CallerQuotaRejections"
 	}
+	cp.quotaRejections = stats.NewCounters(rejectionsName)
 	if name == "" {
 		return cp
 	}
 	if enablePublishStats {
+		stats.Publish(name+"CallerQuota", stats.IntFunc(cp.callerQuota.Get))
 		stats.Publish(name+"Capacity", stats.IntFunc(cp.Capacity))
 		stats.Publish(name+"Available", stats.IntFunc(cp.Available))
 		stats.Publish(name+"MaxCap", stats.IntFunc(cp.MaxCap))
@@ -95,16 +112,61 @@ func (cp *ConnPool) Close() {
 
 // Get returns a connection.
 // You must call Recycle on DBConn once done.
+// It returns ErrCallerQuota if the effective caller of ctx
+// already holds as many connections as the caller quota.
 func (cp *ConnPool) Get(ctx context.Context) (*DBConn, error) {
 	p := cp.pool()
 	if p == nil {
 		return nil, ErrConnPoolClosed
 	}
+	caller, err := cp.acquireQuota(ctx)
+	if err != nil {
+		return nil, err
+	}
 	r, err := p.Get(ctx)
 	if err != nil {
+		cp.releaseQuota(caller)
 		return nil, err
 	}
-	return r.(*DBConn), nil
+	conn := r.(*DBConn)
+	conn.quotaCaller = caller
+	return conn, nil
+}
+
+// acquireQuota counts a connection against the quota of the effective
+// caller of ctx, and returns the caller to release it to. The callers
+// without an effective caller id are not limited.
+func (cp *ConnPool) acquireQuota(ctx context.Context) (string, error) {
+	quota := cp.callerQuota.Get()
+	if quota <= 0 {
+		return "", nil
+	}
+	caller := callerName(ctx)
+	if caller == "" {
+		return "", nil
+	}
+	cp.callerMu.Lock()
+	defer cp.callerMu.Unlock()
+	if cp.callerConns[caller] >= quota {
+		cp.quotaRejections.Add(caller, 1)
+		return "", ErrCallerQuota
+	}
+	cp.callerConns[caller]++
+	return caller, nil
+}
+
+// releaseQuota releases a connection acquired by acquireQuota.
+func (cp *ConnPool) releaseQuota(caller string) {
+	if caller == "" {
+		return
+	}
+	cp.callerMu.Lock()
+	defer cp.callerMu.Unlock()
+	if cp.callerConns[caller] <= 1 {
+		delete(cp.callerConns, caller)
+		return
+	}
+	cp.callerConns[caller]--
 }
 
 // Put puts a connection into the pool.
@@ -134,6 +196,13 @@ func (cp *ConnPool) SetCapacity(capacity int) (err error) {
 	return nil
 }
 
+// SetCallerQuota sets the max number of connections each effective
+// caller can hold at a time. 0 means unlimited. The connections
+// already held are not affected.
+func (cp *ConnPool) SetCallerQuota(quota int) {
+	cp.callerQuota.Set(int64(quota))
+}
+
 // SetIdleTimeout sets the idleTimeout on the pool.
 func (cp *ConnPool) SetIdleTimeout(idleTimeout time.Duration) {
 	cp.mu.Lock()
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/youtube/vitess/go/sqldb"
+	"github.com/youtube/vitess/go/vt/callerid"
 	"github.com/youtube/vitess/go/vt/vttest/fakesqldb"
 	"golang.org/x/net/context"
 )
@@ -31,6 +32,45 @@ func TestConnPoolGet(t *testing.T) {
 	dbConn.Recycle()
 }
 
+func TestConnPoolCallerQuota(t *testing.T) {
+	fakesqldb.Register()
+	testUtils := newTestUtils()
+	appParams := &sqldb.ConnParams{}
+	dbaParams := &sqldb.ConnParams{}
+	connPool := testUtils.newConnPool()
+	connPool.SetCallerQuota(1)
+	connPool.Open(appParams, dbaParams)
+	defer connPool.Close()
+
+	ctx := callerid.NewContext(context.Background(), callerid.NewEffectiveCallerID("batch", "", ""), nil)
+	dbConn, err := connPool.Get(ctx)
+	if err != nil {
+		t.Fatalf("first Get of the caller: %v, want nil", err)
+	}
+	if _, err := connPool.Get(ctx); err != ErrCallerQuota {
+		t.Fatalf("second Get of the caller: %v, want %v", err, ErrCallerQuota)
+	}
+	if got := connPool.quotaRejections.Counts()["batch"]; got != 1 {
+		t.Errorf("quota rejections: %d, want 1", got)
+	}
+	// Other callers, and the queries without a caller, are not affected.
+	otherCtx := callerid.NewContext(context.Background(), callerid.NewEffectiveCallerID("web", "", ""), nil)
+	for _, ctx := range []context.Context{otherCtx, context.Background()} {
+		conn, err := connPool.Get(ctx)
+		if err != nil {
+			t.Fatalf("Get of another caller: %v, want nil", err)
+		}
+		conn.Recycle()
+	}
+	// Recycle releases the quota.
+	dbConn.Recycle()
+	dbConn, err = connPool.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get after Recycle: %v, want nil", err)
+	}
+	dbConn.Recycle()
+}
+
 func TestConnPoolPutWhilePoolIsClosed(t *testing.T) {
 	fakesqldb.Register()
 	testUtils := newTestUtils()
@@ -0,0 +1,226 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package etcdcustomrule implements a custom rule source that watches
+// a key in etcd.
+package etcdcustomrule
+
+import (
+	"flag"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd"
+	log "github.com/golang/glog"
+	"github.com/youtube/vitess/go/flagutil"
+	"github.com/youtube/vitess/go/vt/servenv"
+	"github.com/youtube/vitess/go/vt/tabletserver"
+)
+
+var (
+	// Actual EtcdCustomRule object in charge of rule updates, created
+	// by ActivateEtcdCustomRules.
+	etcdCustomRule *EtcdCustomRule
+	// Commandline flags to specify the rule key and the etcd cluster
+	etcdRulePath  = flag.String("etcdcustomrules", "", "etcd based custom rule key")
+	etcdRuleAddrs flagutil.StringListValue
+)
+
+func init() {
+	flag.Var(&etcdRuleAddrs, "etcdcustomrules_addrs", "comma-separated list of addresses (http://host:port) for the etcd cluster of -etcdcustomrules")
+}
+
+// InvalidQueryRulesVersion is used to mark invalid query rules
+const InvalidQueryRulesVersion int64 = -1
+
+// EtcdCustomRuleSource is the name of the etcd based custom rule source
+const EtcdCustomRuleSource string = "ETCD_CUSTOM_RULE"
+
+// sleepDuringEtcdFailure is how long to wait before watching again
+// after an etcd error.
+var sleepDuringEtcdFailure = 30 * time.Second
+
+// Client contains the parts of etcd.Client that are needed.
+type Client interface {
+	Get(key string, sort, recursive bool) (*etcd.Response, error)
+	Watch(prefix string, waitIndex uint64, recursive bool,
+		receiver chan *etcd.Response, stop chan bool) (*etcd.Response, error)
+}
+
+// EtcdCustomRule is the etcd backed implementation of CustomRuleManager
+type EtcdCustomRule struct {
+	mu                    sync.Mutex
+	path                  string
+	client                Client
+	waitIndex             uint64 // etcd index to watch from
+	currentRuleSet        *tabletserver.QueryRules
+	currentRuleSetVersion int64 // implemented with the etcd modified index
+	lastUpdate            time.Time
+	retryDelay            time.Duration // wait before retrying after an etcd error
+	stop                  chan bool
+}
+
+// NewEtcdCustomRule creates a new EtcdCustomRule structure
+func NewEtcdCustomRule(client Client) *EtcdCustomRule {
+	return &EtcdCustomRule{
+		client:                client,
+		currentRuleSet:        tabletserver.NewQueryRules(),
+		currentRuleSetVersion: InvalidQueryRulesVersion,
+		retryDelay:            sleepDuringEtcdFailure,
+		stop:                  make(chan bool),
+	}
+}
+
+// Open gets the initial QueryRules and starts watching the key for
+// changes. If the rules can't be read, it returns the error, but
+// keeps retrying in the background until Close is called.
+func (ecr *EtcdCustomRule) Open(qsc tabletserver.QueryServiceControl, rulePath string) error {
+	ecr.path = rulePath
+	err := ecr.refreshData(qsc)
+	go ecr.poll(qsc, err == nil)
+	return err
+}
+
+// refreshData gets the query rules from etcd, and the index to watch
+// them from.
+func (ecr *EtcdCustomRule) refreshData(qsc tabletserver.QueryServiceControl) error {
+	resp, err := ecr.client.Get(ecr.path, false /* sort */, false /* recursive */)
+	if err != nil {
+		if etcdErr, ok := err.(*etcd.EtcdError); ok && etcdErr.ErrorCode == etcdErrorKeyNotFound {
+			// no rules yet
+			ecr.waitIndex = etcdErr.Index + 1
+			ecr.applyRules(qsc, nil)
+			return nil
+		}
+		log.Warningf("Error encountered when trying to get custom rules from etcd: %v", err)
+		return err
+	}
+	ecr.waitIndex = resp.EtcdIndex + 1
+	ecr.applyRules(qsc, resp.Node)
+	return nil
+}
+
+// etcdErrorKeyNotFound is the etcd error code of a missing key.
+const etcdErrorKeyNotFound = 100
+
+// applyRules rebuilds the query rules from node, and pushes them to
+// the query service if they changed. A nil node, or a node without
+// a value, means there are no rules.
+func (ecr *EtcdCustomRule) applyRules(qsc tabletserver.QueryServiceControl, node *etcd.Node) {
+	qrs := tabletserver.NewQueryRules()
+	version := InvalidQueryRulesVersion
+	if node != nil {
+		version = int64(node.ModifiedIndex)
+		if node.Value != "" {
+			if err := qrs.UnmarshalJSON([]byte(node.Value)); err != nil {
+				log.Warningf("Error unmarshaling query rules %v, original data '%s'", err, node.Value)
+				return
+			}
+		}
+	}
+	ecr.mu.Lock()
+	defer ecr.mu.Unlock()
+	ecr.currentRuleSetVersion = version
+	ecr.lastUpdate = time.Now()
+	if !reflect.DeepEqual(ecr.currentRuleSet, qrs) {
+		ecr.currentRuleSet = qrs.Copy()
+		qsc.SetQueryRules(EtcdCustomRuleSource, qrs.Copy())
+		log.Infof("Custom rule version %v fetched from etcd and applied to vttablet", version)
+	}
+}
+
+// poll watches the key for changes until Close is called. After an
+// error, it waits and gets the rules again, since the changes since
+// the last index may not be available anymore. refreshed is false if
+// the rules must be read again before watching.
+func (ecr *EtcdCustomRule) poll(qsc tabletserver.QueryServiceControl, refreshed bool) {
+	for {
+		if !refreshed {
+			select {
+			case <-ecr.stop:
+				return
+			case <-time.After(ecr.retryDelay):
+			}
+			refreshed = ecr.refreshData(qsc) == nil
+			continue
+		}
+		resp, err := ecr.client.Watch(ecr.path, ecr.waitIndex, false /* recursive */, nil, ecr.stop)
+		select {
+		case <-ecr.stop:
+			return
+		default:
+		}
+		if err != nil {
+			log.Warningf("Watch on %v failed, waiting for %v to retry: %v", ecr.path, ecr.retryDelay, err)
+			refreshed = false
+			continue
+		}
+		ecr.waitIndex = resp.Node.ModifiedIndex + 1
+		if resp.Action == "delete" || resp.Action == "expire" {
+			ecr.applyRules(qsc, nil)
+		} else {
+			ecr.applyRules(qsc, resp.Node)
+		}
+	}
+}
+
+// Close stops watching etcd
+func (ecr *EtcdCustomRule) Close() {
+	close(ecr.stop)
+}
+
+// GetRules retrieves cached rules
+func (ecr *EtcdCustomRule) GetRules() (qrs *tabletserver.QueryRules, version int64, err error) {
+	ecr.mu.Lock()
+	defer ecr.mu.Unlock()
+	return ecr.currentRuleSet.Copy(), ecr.currentRuleSetVersion, nil
+}
+
+// etcdCustomRuleStatus is the data of the status page part.
+type etcdCustomRuleStatus struct {
+	Path       string
+	Version    int64
+	LastUpdate time.Time
+}
+
+const etcdCustomRuleStatusHTML = `
+<table>
+  <tr><td>Key:</td><td>{{.Path}}</td></tr>
+  <tr><td>Active rule version:</td><td>{{if eq .Version -1}}none{{else}}{{.Version}}{{end}}</td></tr>
+  <tr><td>Last update:</td><td>{{.LastUpdate}}</td></tr>
+</table>
+`
+
+// status returns the status of the rules, for the status page.
+func (ecr *EtcdCustomRule) status() interface{} {
+	ecr.mu.Lock()
+	defer ecr.mu.Unlock()
+	return etcdCustomRuleStatus{
+		Path:       ecr.path,
+		Version:    ecr.currentRuleSetVersion,
+		LastUpdate: ecr.lastUpdate,
+	}
+}
+
+// ActivateEtcdCustomRules activates etcd dynamic custom rule mechanism
+func ActivateEtcdCustomRules(qsc tabletserver.QueryServiceControl) {
+	if *etcdRulePath != "" {
+		tabletserver.QueryRuleSources.RegisterQueryRuleSource(EtcdCustomRuleSource)
+		etcdCustomRule = NewEtcdCustomRule(etcd.NewClient(etcdRuleAddrs))
+		if err := etcdCustomRule.Open(qsc, *etcdRulePath); err != nil {
+			log.Warningf("Cannot get the custom rules from etcd, retrying every %v: %v", sleepDuringEtcdFailure, err)
+		}
+		servenv.AddStatusPart("Etcd Custom Rules", etcdCustomRuleStatusHTML, etcdCustomRule.status)
+	}
+}
+
+func init() {
+	tabletserver.QueryServiceControlRegisterFunctions = append(tabletserver.QueryServiceControlRegisterFunctions, ActivateEtcdCustomRules)
+	servenv.OnTerm(func() {
+		if etcdCustomRule != nil {
+			etcdCustomRule.Close()
+		}
+	})
+}
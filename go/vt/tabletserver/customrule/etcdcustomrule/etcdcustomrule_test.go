@@ -0,0 +1,204 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package etcdcustomrule
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd"
+	"github.com/youtube/vitess/go/vt/tabletserver"
+)
+
+var customRule1 = `[
+				{
+					"Name": "r1",
+					"Description": "disallow bindvar 'asdfg'",
+					"BindVarConds":[{
+						"Name": "asdfg",
+						"OnAbsent": false,
+						"Operator": "NOOP"
+					}]
+				}
+			]`
+
+var customRule2 = `[
+				{
+					"Name": "r2",
+					"Description": "disallow insert on table test",
+					"TableNames" : ["test"],
+					"Query" : "(insert)|(INSERT)"
+				}
+			]`
+
+// fakeClient is an in-memory etcd with a single key.
+type fakeClient struct {
+	mu      sync.Mutex
+	index   uint64
+	node    *etcd.Node // nil if the key doesn't exist
+	last    *etcd.Response
+	changed chan struct{} // closed on every change
+	// getErrors is the number of Get calls that fail as if etcd
+	// was unreachable.
+	getErrors int
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{index: 1, changed: make(chan struct{})}
+}
+
+func (fc *fakeClient) update(action, value string) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.index++
+	node := &etcd.Node{Key: "/rules", Value: value, ModifiedIndex: fc.index}
+	fc.last = &etcd.Response{Action: action, Node: node}
+	if action == "delete" {
+		fc.node = nil
+	} else {
+		fc.node = node
+	}
+	close(fc.changed)
+	fc.changed = make(chan struct{})
+}
+
+func (fc *fakeClient) Get(key string, sort, recursive bool) (*etcd.Response, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.getErrors > 0 {
+		fc.getErrors--
+		return nil, &etcd.EtcdError{ErrorCode: etcd.ErrCodeEtcdNotReachable, Message: "etcd unreachable"}
+	}
+	if fc.node == nil {
+		return nil, &etcd.EtcdError{ErrorCode: etcdErrorKeyNotFound, Index: fc.index}
+	}
+	return &etcd.Response{Action: "get", Node: fc.node, EtcdIndex: fc.index}, nil
+}
+
+func (fc *fakeClient) Watch(prefix string, waitIndex uint64, recursive bool, receiver chan *etcd.Response, stop chan bool) (*etcd.Response, error) {
+	for {
+		fc.mu.Lock()
+		last, changed := fc.last, fc.changed
+		fc.mu.Unlock()
+		if last != nil && last.Node.ModifiedIndex >= waitIndex {
+			return last, nil
+		}
+		select {
+		case <-changed:
+		case <-stop:
+			return nil, etcd.ErrWatchStoppedByUser
+		}
+	}
+}
+
+// waitForVersion waits until the rules of ecr have version.
+func waitForVersion(t *testing.T, ecr *EtcdCustomRule, version int64) *tabletserver.QueryRules {
+	for i := 0; i < 100; i++ {
+		qrs, v, err := ecr.GetRules()
+		if err != nil {
+			t.Fatalf("GetRules of EtcdCustomRule should always return nil error, but we receive %v", err)
+		}
+		if v == version {
+			return qrs
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for rule version %v", version)
+	return nil
+}
+
+func TestEtcdCustomRule(t *testing.T) {
+	tqsc := tabletserver.NewTestQueryServiceControl()
+	client := newFakeClient()
+	client.update("set", customRule1)
+
+	ecr := NewEtcdCustomRule(client)
+	if err := ecr.Open(tqsc, "/rules"); err != nil {
+		t.Fatalf("Cannot open etcd custom rule service, err=%v", err)
+	}
+	defer ecr.Close()
+
+	// Test if we can successfully fetch the original rule (test GetRules)
+	qrs := waitForVersion(t, ecr, 2)
+	if qrs.Find("r1") == nil {
+		t.Fatalf("Expect custom rule r1 to be found, but got nothing, qrs=%v", qrs)
+	}
+
+	// Test updating rules
+	client.update("set", customRule2)
+	qrs = waitForVersion(t, ecr, 3)
+	if qrs.Find("r2") == nil {
+		t.Fatalf("Expect custom rule r2 to be found, but got nothing, qrs=%v", qrs)
+	}
+	if qrs.Find("r1") != nil {
+		t.Fatalf("Custom rule r1 should not be found after r2 is set")
+	}
+
+	// Test invalid rules: the previous rules are kept
+	client.update("set", "invalid")
+	client.update("set", customRule1)
+	qrs = waitForVersion(t, ecr, 5)
+	if qrs.Find("r1") == nil {
+		t.Fatalf("Expect custom rule r1 to be found, but got nothing, qrs=%v", qrs)
+	}
+
+	// Test key removal
+	client.update("delete", "")
+	qrs = waitForVersion(t, ecr, InvalidQueryRulesVersion)
+	if qrs.Find("r1") != nil {
+		t.Fatalf("Expect empty rules after the key is deleted, got %v", qrs)
+	}
+
+	// Test status
+	status := ecr.status().(etcdCustomRuleStatus)
+	if status.Path != "/rules" || status.Version != InvalidQueryRulesVersion {
+		t.Errorf("wrong status: %+v", status)
+	}
+}
+
+func TestEtcdCustomRuleNoKey(t *testing.T) {
+	tqsc := tabletserver.NewTestQueryServiceControl()
+	client := newFakeClient()
+
+	ecr := NewEtcdCustomRule(client)
+	if err := ecr.Open(tqsc, "/rules"); err != nil {
+		t.Fatalf("Cannot open etcd custom rule service, err=%v", err)
+	}
+	defer ecr.Close()
+	waitForVersion(t, ecr, InvalidQueryRulesVersion)
+
+	client.update("set", customRule2)
+	qrs := waitForVersion(t, ecr, 2)
+	if qrs.Find("r2") == nil {
+		t.Fatalf("Expect custom rule r2 to be found, but got nothing, qrs=%v", qrs)
+	}
+}
+
+func TestEtcdCustomRuleUnreachable(t *testing.T) {
+	tqsc := tabletserver.NewTestQueryServiceControl()
+	client := newFakeClient()
+	client.update("set", customRule1)
+	client.getErrors = 2
+
+	// Open fails, but keeps retrying until etcd is reachable.
+	ecr := NewEtcdCustomRule(client)
+	ecr.retryDelay = 10 * time.Millisecond
+	if err := ecr.Open(tqsc, "/rules"); err == nil {
+		t.Fatalf("Open should fail while etcd is unreachable")
+	}
+	defer ecr.Close()
+	qrs := waitForVersion(t, ecr, 2)
+	if qrs.Find("r1") == nil {
+		t.Fatalf("Expect custom rule r1 to be found, but got nothing, qrs=%v", qrs)
+	}
+
+	// It watches the key once it got the rules.
+	client.update("set", customRule2)
+	qrs = waitForVersion(t, ecr, 3)
+	if qrs.Find("r2") == nil {
+		t.Fatalf("Expect custom rule r2 to be found, but got nothing, qrs=%v", qrs)
+	}
+}
@@ -28,6 +28,10 @@ type DBConn struct {
 	pool              *ConnPool
 	queryServiceStats *QueryServiceStats
 	current           sync2.AtomicString
+
+	// quotaCaller is the caller whose quota the connection
+	// counts against, if any.
+	quotaCaller string
 }
 
 // NewDBConn creates a new DBConn. It triggers a CheckMySQL if creation fails.
@@ -118,6 +122,8 @@ func (dbc *DBConn) IsClosed() bool {
 
 // Recycle returns the DBConn to the pool.
 func (dbc *DBConn) Recycle() {
+	dbc.pool.releaseQuota(dbc.quotaCaller)
+	dbc.quotaCaller = ""
 	if dbc.conn.IsClosed() {
 		dbc.pool.Put(nil)
 	} else {
@@ -137,6 +137,54 @@ func (sq *SqlQuery) Rollback2(ctx context.Context, rollbackRequest *proto.Rollba
 	return tErr
 }
 
+// Prepare is exposing tabletserver.SqlQuery.Prepare
+func (sq *SqlQuery) Prepare(ctx context.Context, dt *proto.DistributedTransaction, noOutput *rpc.Unused) (err error) {
+	defer sq.server.HandlePanic(&err)
+	return sq.server.Prepare(callinfo.RPCWrapCallInfo(ctx), dt)
+}
+
+// CommitPrepared is exposing tabletserver.SqlQuery.CommitPrepared
+func (sq *SqlQuery) CommitPrepared(ctx context.Context, dt *proto.DistributedTransaction, noOutput *rpc.Unused) (err error) {
+	defer sq.server.HandlePanic(&err)
+	return sq.server.CommitPrepared(callinfo.RPCWrapCallInfo(ctx), dt)
+}
+
+// RollbackPrepared is exposing tabletserver.SqlQuery.RollbackPrepared
+func (sq *SqlQuery) RollbackPrepared(ctx context.Context, dt *proto.DistributedTransaction, noOutput *rpc.Unused) (err error) {
+	defer sq.server.HandlePanic(&err)
+	return sq.server.RollbackPrepared(callinfo.RPCWrapCallInfo(ctx), dt)
+}
+
+// CreateTransaction is exposing tabletserver.SqlQuery.CreateTransaction
+func (sq *SqlQuery) CreateTransaction(ctx context.Context, dt *proto.DistributedTransaction, noOutput *rpc.Unused) (err error) {
+	defer sq.server.HandlePanic(&err)
+	return sq.server.CreateTransaction(callinfo.RPCWrapCallInfo(ctx), dt)
+}
+
+// StartCommit is exposing tabletserver.SqlQuery.StartCommit
+func (sq *SqlQuery) StartCommit(ctx context.Context, dt *proto.DistributedTransaction, noOutput *rpc.Unused) (err error) {
+	defer sq.server.HandlePanic(&err)
+	return sq.server.StartCommit(callinfo.RPCWrapCallInfo(ctx), dt)
+}
+
+// ConcludeTransaction is exposing tabletserver.SqlQuery.ConcludeTransaction
+func (sq *SqlQuery) ConcludeTransaction(ctx context.Context, dt *proto.DistributedTransaction, noOutput *rpc.Unused) (err error) {
+	defer sq.server.HandlePanic(&err)
+	return sq.server.ConcludeTransaction(callinfo.RPCWrapCallInfo(ctx), dt)
+}
+
+// MasterPosition is exposing tabletserver.SqlQuery.MasterPosition
+func (sq *SqlQuery) MasterPosition(ctx context.Context, req *proto.ReplicationPositionRequest, reply *proto.ReplicationPosition) (err error) {
+	defer sq.server.HandlePanic(&err)
+	return sq.server.MasterPosition(callinfo.RPCWrapCallInfo(ctx), req, reply)
+}
+
+// WaitMasterPos is exposing tabletserver.SqlQuery.WaitMasterPos
+func (sq *SqlQuery) WaitMasterPos(ctx context.Context, req *proto.ReplicationPositionRequest, noOutput *rpc.Unused) (err error) {
+	defer sq.server.HandlePanic(&err)
+	return sq.server.WaitMasterPos(callinfo.RPCWrapCallInfo(ctx), req)
+}
+
 // Execute is exposing tabletserver.SqlQuery.Execute
 func (sq *SqlQuery) Execute(ctx context.Context, query *proto.Query, reply *mproto.QueryResult) (err error) {
 	defer sq.server.HandlePanic(&err)
@@ -251,6 +299,21 @@ func (sq *SqlQuery) SplitQuery(ctx context.Context, req *proto.SplitQueryRequest
 	return tErr
 }
 
+// SplitQueryV2 is exposing tabletserver.SqlQuery.SplitQueryV2
+func (sq *SqlQuery) SplitQueryV2(ctx context.Context, req *proto.SplitQueryV2Request, reply *proto.SplitQueryResult) (err error) {
+	defer sq.server.HandlePanic(&err)
+	ctx = callerid.NewContext(ctx,
+		callerid.GoRPCEffectiveCallerID(req.EffectiveCallerID),
+		callerid.GoRPCImmediateCallerID(req.ImmediateCallerID),
+	)
+	tErr := sq.server.SplitQueryV2(callinfo.RPCWrapCallInfo(ctx), req, reply)
+	tabletserver.AddTabletErrorToSplitQueryResult(tErr, reply)
+	if *tabletserver.RPCErrorOnlyInReply {
+		return nil
+	}
+	return tErr
+}
+
 // StreamHealth is exposing tabletserver.SqlQuery.StreamHealthRegister and
 // tabletserver.SqlQuery.StreamHealthUnregister
 func (sq *SqlQuery) StreamHealth(ctx context.Context, query *rpc.Unused, sendReply func(reply interface{}) error) (err error) {
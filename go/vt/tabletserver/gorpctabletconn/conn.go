@@ -477,6 +477,107 @@ func (conn *TabletBson) Rollback2(ctx context.Context, transactionID int64) erro
 	return tabletError(err)
 }
 
+// Prepare is the stub for SqlQuery.Prepare RPC
+func (conn *TabletBson) Prepare(ctx context.Context, transactionID int64, dtid string) error {
+	return conn.twoPC(ctx, "SqlQuery.Prepare", &tproto.DistributedTransaction{
+		TransactionId: transactionID,
+		Dtid:          dtid,
+	})
+}
+
+// CommitPrepared is the stub for SqlQuery.CommitPrepared RPC
+func (conn *TabletBson) CommitPrepared(ctx context.Context, dtid string) error {
+	return conn.twoPC(ctx, "SqlQuery.CommitPrepared", &tproto.DistributedTransaction{
+		Dtid: dtid,
+	})
+}
+
+// RollbackPrepared is the stub for SqlQuery.RollbackPrepared RPC
+func (conn *TabletBson) RollbackPrepared(ctx context.Context, dtid string) error {
+	return conn.twoPC(ctx, "SqlQuery.RollbackPrepared", &tproto.DistributedTransaction{
+		Dtid: dtid,
+	})
+}
+
+// CreateTransaction is the stub for SqlQuery.CreateTransaction RPC
+func (conn *TabletBson) CreateTransaction(ctx context.Context, dtid string, participants []tproto.DTParticipant) error {
+	return conn.twoPC(ctx, "SqlQuery.CreateTransaction", &tproto.DistributedTransaction{
+		Dtid:         dtid,
+		Participants: participants,
+	})
+}
+
+// StartCommit is the stub for SqlQuery.StartCommit RPC
+func (conn *TabletBson) StartCommit(ctx context.Context, transactionID int64, dtid string) error {
+	return conn.twoPC(ctx, "SqlQuery.StartCommit", &tproto.DistributedTransaction{
+		TransactionId: transactionID,
+		Dtid:          dtid,
+	})
+}
+
+// ConcludeTransaction is the stub for SqlQuery.ConcludeTransaction RPC
+func (conn *TabletBson) ConcludeTransaction(ctx context.Context, dtid string) error {
+	return conn.twoPC(ctx, "SqlQuery.ConcludeTransaction", &tproto.DistributedTransaction{
+		Dtid: dtid,
+	})
+}
+
+func (conn *TabletBson) twoPC(ctx context.Context, method string, req *tproto.DistributedTransaction) error {
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+	if conn.rpcClient == nil {
+		return tabletconn.ConnClosed
+	}
+
+	req.SessionId = conn.sessionID
+	action := func() error {
+		return conn.rpcClient.Call(ctx, method, req, &rpc.Unused{})
+	}
+	err := conn.withTimeout(ctx, action)
+	return tabletError(err)
+}
+
+// MasterPosition is the stub for SqlQuery.MasterPosition RPC
+func (conn *TabletBson) MasterPosition(ctx context.Context) (string, error) {
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+	if conn.rpcClient == nil {
+		return "", tabletconn.ConnClosed
+	}
+
+	req := &tproto.ReplicationPositionRequest{
+		SessionId: conn.sessionID,
+	}
+	reply := new(tproto.ReplicationPosition)
+	action := func() error {
+		return conn.rpcClient.Call(ctx, "SqlQuery.MasterPosition", req, reply)
+	}
+	if err := conn.withTimeout(ctx, action); err != nil {
+		return "", tabletError(err)
+	}
+	return reply.Position, nil
+}
+
+// WaitMasterPos is the stub for SqlQuery.WaitMasterPos RPC
+func (conn *TabletBson) WaitMasterPos(ctx context.Context, position string, waitTimeout time.Duration) error {
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+	if conn.rpcClient == nil {
+		return tabletconn.ConnClosed
+	}
+
+	req := &tproto.ReplicationPositionRequest{
+		SessionId:   conn.sessionID,
+		Position:    position,
+		WaitTimeout: waitTimeout,
+	}
+	action := func() error {
+		return conn.rpcClient.Call(ctx, "SqlQuery.WaitMasterPos", req, &rpc.Unused{})
+	}
+	err := conn.withTimeout(ctx, action)
+	return tabletError(err)
+}
+
 // SplitQuery is the stub for SqlQuery.SplitQuery RPC
 func (conn *TabletBson) SplitQuery(ctx context.Context, query tproto.BoundQuery, splitColumn string, splitCount int) (queries []tproto.QuerySplit, err error) {
 	conn.mu.RLock()
@@ -506,6 +607,36 @@ func (conn *TabletBson) SplitQuery(ctx context.Context, query tproto.BoundQuery,
 	return reply.Queries, nil
 }
 
+// SplitQueryV2 is the stub for SqlQuery.SplitQueryV2 RPC
+func (conn *TabletBson) SplitQueryV2(ctx context.Context, query tproto.BoundQuery, splitColumns []string, splitCount int, numRowsPerQueryPart int64) (queries []tproto.QuerySplit, err error) {
+	conn.mu.RLock()
+	defer conn.mu.RUnlock()
+	if conn.rpcClient == nil {
+		err = tabletconn.ConnClosed
+		return
+	}
+	req := &tproto.SplitQueryV2Request{
+		Query:               query,
+		SplitColumns:        splitColumns,
+		SplitCount:          splitCount,
+		NumRowsPerQueryPart: numRowsPerQueryPart,
+		SessionID:           conn.sessionID,
+	}
+	reply := new(tproto.SplitQueryResult)
+	action := func() error {
+		err := conn.rpcClient.Call(ctx, "SqlQuery.SplitQueryV2", req, reply)
+		if err != nil {
+			return err
+		}
+		// SqlQuery.SplitQueryV2 might return an application error inside the SplitQueryResult
+		return vterrors.FromRPCError(reply.Err)
+	}
+	if err := conn.withTimeout(ctx, action); err != nil {
+		return nil, tabletError(err)
+	}
+	return reply.Queries, nil
+}
+
 // StreamHealth is the stub for SqlQuery.StreamHealth RPC
 func (conn *TabletBson) StreamHealth(ctx context.Context) (<-chan *pb.StreamHealthResponse, tabletconn.ErrFunc, error) {
 	conn.mu.RLock()
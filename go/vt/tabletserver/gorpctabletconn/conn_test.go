@@ -60,6 +60,9 @@ func testGoRPCTabletConn(t *testing.T, rpcOnlyInReply bool) {
 
 	// run the test suite
 	tabletconntest.TestSuite(t, client, service)
+	tabletconntest.TestTwoPCSuite(t, client, service)
+	tabletconntest.TestReplicationPositionSuite(t, client, service)
+	tabletconntest.TestSplitQueryV2Suite(t, client, service)
 
 	// and clean up
 	client.Close()
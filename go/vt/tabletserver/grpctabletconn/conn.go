@@ -257,6 +257,54 @@ func (conn *gRPCQueryClient) Rollback2(ctx context.Context, transactionID int64)
 	return conn.Rollback(ctx, transactionID)
 }
 
+// errTwoPCNotSupported is returned by the two-phase commit calls:
+// they're not part of the gRPC query service yet.
+var errTwoPCNotSupported = tabletconn.OperationalError("vttablet: two-phase commit is not supported over gRPC")
+
+// Prepare is not supported over gRPC yet
+func (conn *gRPCQueryClient) Prepare(ctx context.Context, transactionID int64, dtid string) error {
+	return errTwoPCNotSupported
+}
+
+// CommitPrepared is not supported over gRPC yet
+func (conn *gRPCQueryClient) CommitPrepared(ctx context.Context, dtid string) error {
+	return errTwoPCNotSupported
+}
+
+// RollbackPrepared is not supported over gRPC yet
+func (conn *gRPCQueryClient) RollbackPrepared(ctx context.Context, dtid string) error {
+	return errTwoPCNotSupported
+}
+
+// CreateTransaction is not supported over gRPC yet
+func (conn *gRPCQueryClient) CreateTransaction(ctx context.Context, dtid string, participants []tproto.DTParticipant) error {
+	return errTwoPCNotSupported
+}
+
+// StartCommit is not supported over gRPC yet
+func (conn *gRPCQueryClient) StartCommit(ctx context.Context, transactionID int64, dtid string) error {
+	return errTwoPCNotSupported
+}
+
+// ConcludeTransaction is not supported over gRPC yet
+func (conn *gRPCQueryClient) ConcludeTransaction(ctx context.Context, dtid string) error {
+	return errTwoPCNotSupported
+}
+
+// errReplicationPositionNotSupported is returned by the replication
+// position calls: they're not part of the gRPC query service yet.
+var errReplicationPositionNotSupported = tabletconn.OperationalError("vttablet: replication positions are not supported over gRPC")
+
+// MasterPosition is not supported over gRPC yet
+func (conn *gRPCQueryClient) MasterPosition(ctx context.Context) (string, error) {
+	return "", errReplicationPositionNotSupported
+}
+
+// WaitMasterPos is not supported over gRPC yet
+func (conn *gRPCQueryClient) WaitMasterPos(ctx context.Context, position string, waitTimeout time.Duration) error {
+	return errReplicationPositionNotSupported
+}
+
 // SplitQuery is the stub for SqlQuery.SplitQuery RPC
 func (conn *gRPCQueryClient) SplitQuery(ctx context.Context, query tproto.BoundQuery, splitColumn string, splitCount int) (queries []tproto.QuerySplit, err error) {
 	conn.mu.RLock()
@@ -282,6 +330,11 @@ func (conn *gRPCQueryClient) SplitQuery(ctx context.Context, query tproto.BoundQ
 	return tproto.Proto3ToQuerySplits(sqr.Queries), nil
 }
 
+// SplitQueryV2 is not supported over gRPC yet
+func (conn *gRPCQueryClient) SplitQueryV2(ctx context.Context, query tproto.BoundQuery, splitColumns []string, splitCount int, numRowsPerQueryPart int64) ([]tproto.QuerySplit, error) {
+	return nil, tabletconn.OperationalError("vttablet: SplitQueryV2 is not supported over gRPC")
+}
+
 // StreamHealth is the stub for SqlQuery.StreamHealth RPC
 func (conn *gRPCQueryClient) StreamHealth(ctx context.Context) (<-chan *pb.StreamHealthResponse, tabletconn.ErrFunc, error) {
 	conn.mu.RLock()
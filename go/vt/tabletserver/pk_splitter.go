@@ -0,0 +1,268 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tabletserver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/sqltypes"
+	"github.com/youtube/vitess/go/vt/sqlparser"
+	"github.com/youtube/vitess/go/vt/tabletserver/proto"
+)
+
+// The bind variables of the boundaries of the parts are these
+// prefixes followed by the name of their split column.
+const (
+	splitStartPrefix = "_splitquery_start_"
+	splitEndPrefix   = "_splitquery_end_"
+)
+
+// PKSplitter splits a BoundQuery into queries that each scan a range
+// of the primary key of its table, for SplitQueryV2. Unlike
+// QuerySplitter, it supports primary keys of any type, and composite
+// primary keys: the boundaries of the ranges are sampled from the
+// table, every numRowsPerQueryPart rows in primary key order.
+//
+// The query can be any select on a single table whose rows can be
+// split: it can't have a GROUP BY, HAVING, DISTINCT, LIMIT or
+// aggregate function.
+type PKSplitter struct {
+	query        *proto.BoundQuery
+	schemaInfo   *SchemaInfo
+	sel          *sqlparser.Select
+	tableName    string
+	splitColumns []string
+}
+
+// NewPKSplitter creates a new PKSplitter. splitColumns must be a
+// prefix of the primary key of the table of query. If it's empty,
+// the whole primary key is used.
+func NewPKSplitter(query *proto.BoundQuery, splitColumns []string, schemaInfo *SchemaInfo) *PKSplitter {
+	return &PKSplitter{
+		query:        query,
+		schemaInfo:   schemaInfo,
+		splitColumns: splitColumns,
+	}
+}
+
+// validateQuery makes sure the query can be split, and finds its
+// table and split columns.
+func (ps *PKSplitter) validateQuery() error {
+	statement, err := sqlparser.Parse(ps.query.Sql)
+	if err != nil {
+		return err
+	}
+	var ok bool
+	ps.sel, ok = statement.(*sqlparser.Select)
+	if !ok {
+		return fmt.Errorf("not a select statement")
+	}
+	if ps.sel.Distinct != "" || ps.sel.GroupBy != nil ||
+		ps.sel.Having != nil || len(ps.sel.From) != 1 ||
+		ps.sel.Limit != nil || ps.sel.Lock != "" {
+		return fmt.Errorf("unsupported query")
+	}
+	for _, expr := range ps.sel.SelectExprs {
+		nonStar, ok := expr.(*sqlparser.NonStarExpr)
+		if !ok {
+			continue
+		}
+		if f, ok := nonStar.Expr.(*sqlparser.FuncExpr); ok && sqlparser.Aggregates[strings.ToLower(string(f.Name))] {
+			return fmt.Errorf("unsupported query: aggregate function %s", f.Name)
+		}
+	}
+	node, ok := ps.sel.From[0].(*sqlparser.AliasedTableExpr)
+	if !ok {
+		return fmt.Errorf("unsupported query")
+	}
+	ps.tableName = sqlparser.GetTableName(node.Expr)
+	if ps.tableName == "" {
+		return fmt.Errorf("not a simple table expression")
+	}
+	tableInfo, ok := ps.schemaInfo.tables[ps.tableName]
+	if !ok {
+		return fmt.Errorf("can't find table in schema")
+	}
+	if len(tableInfo.PKColumns) == 0 {
+		return fmt.Errorf("no primary keys")
+	}
+	if len(ps.splitColumns) == 0 {
+		for i := range tableInfo.PKColumns {
+			ps.splitColumns = append(ps.splitColumns, tableInfo.GetPKColumn(i).Name)
+		}
+		return nil
+	}
+	if len(ps.splitColumns) > len(tableInfo.PKColumns) {
+		return fmt.Errorf("split columns %v are not a prefix of the primary key of %s", ps.splitColumns, ps.tableName)
+	}
+	for i, column := range ps.splitColumns {
+		if tableInfo.GetPKColumn(i).Name != column {
+			return fmt.Errorf("split columns %v are not a prefix of the primary key of %s", ps.splitColumns, ps.tableName)
+		}
+	}
+	return nil
+}
+
+// tableRowsQuery returns the query that estimates the number of rows
+// of the table.
+func (ps *PKSplitter) tableRowsQuery() string {
+	return fmt.Sprintf("select table_rows from information_schema.tables where table_schema = database() and table_name = '%s'", ps.tableName)
+}
+
+// rowsPerPart returns the number of rows of each part for splitCount
+// parts, from the result of tableRowsQuery. It returns 0 if the query
+// doesn't need to be split.
+func (ps *PKSplitter) rowsPerPart(tableRows *mproto.QueryResult, splitCount int) (int64, error) {
+	if splitCount <= 1 || len(tableRows.Rows) != 1 || tableRows.Rows[0][0].IsNull() {
+		return 0, nil
+	}
+	rows, err := tableRows.Rows[0][0].ParseInt64()
+	if err != nil {
+		return 0, err
+	}
+	return (rows + int64(splitCount) - 1) / int64(splitCount), nil
+}
+
+// boundaryQuery returns the query that finds the boundary that
+// follows prev: the split columns of the row numRowsPerQueryPart
+// rows after it. If prev is nil, it finds the first boundary.
+func (ps *PKSplitter) boundaryQuery(prev []sqltypes.Value, numRowsPerQueryPart int64) (*sqlparser.ParsedQuery, map[string]interface{}) {
+	sel := &sqlparser.Select{
+		From:  ps.sel.From,
+		Limit: &sqlparser.Limit{Rowcount: sqlparser.NumVal("1")},
+	}
+	bindVars := make(map[string]interface{})
+	offset := numRowsPerQueryPart
+	if prev != nil {
+		// prev itself is the first row of the part.
+		offset--
+		sel.Where = &sqlparser.Where{
+			Type: sqlparser.AST_WHERE,
+			Expr: compareColumns(ps.splitColumns, splitStartPrefix, sqlparser.AST_GT, sqlparser.AST_GT),
+		}
+		ps.setBindVars(bindVars, splitStartPrefix, prev)
+	}
+	if offset != 0 {
+		sel.Limit.Offset = sqlparser.NumVal(strconv.FormatInt(offset, 10))
+	}
+	for _, column := range ps.splitColumns {
+		col := &sqlparser.ColName{Name: []byte(column)}
+		sel.SelectExprs = append(sel.SelectExprs, &sqlparser.NonStarExpr{Expr: col})
+		sel.OrderBy = append(sel.OrderBy, &sqlparser.Order{Expr: col, Direction: sqlparser.AST_ASC})
+	}
+	buf := sqlparser.NewTrackedBuffer(nil)
+	buf.Myprintf("%v", sel)
+	return buf.ParsedQuery(), bindVars
+}
+
+// split returns the parts of the query for the boundaries found by
+// the boundary queries. numRowsPerQueryPart is the estimated size
+// of each part.
+func (ps *PKSplitter) split(boundaries [][]sqltypes.Value, numRowsPerQueryPart int64) []proto.QuerySplit {
+	if len(boundaries) == 0 {
+		return []proto.QuerySplit{{
+			Query:    *ps.query,
+			RowCount: numRowsPerQueryPart,
+		}}
+	}
+	splits := make([]proto.QuerySplit, 0, len(boundaries)+1)
+	var start []sqltypes.Value
+	for i := 0; i <= len(boundaries); i++ {
+		var end []sqltypes.Value
+		if i < len(boundaries) {
+			end = boundaries[i]
+		}
+		splits = append(splits, ps.part(start, end, numRowsPerQueryPart))
+		start = end
+	}
+	return splits
+}
+
+// part returns the part of the query whose split columns are within
+// [start, end). A nil start or end means no bound.
+func (ps *PKSplitter) part(start, end []sqltypes.Value, rowCount int64) proto.QuerySplit {
+	bindVars := make(map[string]interface{}, len(ps.query.BindVariables)+2*len(ps.splitColumns))
+	for k, v := range ps.query.BindVariables {
+		bindVars[k] = v
+	}
+	var clauses sqlparser.BoolExpr
+	if start != nil {
+		clauses = compareColumns(ps.splitColumns, splitStartPrefix, sqlparser.AST_GT, sqlparser.AST_GE)
+		ps.setBindVars(bindVars, splitStartPrefix, start)
+	}
+	if end != nil {
+		endClause := compareColumns(ps.splitColumns, splitEndPrefix, sqlparser.AST_LT, sqlparser.AST_LT)
+		if clauses == nil {
+			clauses = endClause
+		} else {
+			clauses = &sqlparser.AndExpr{Left: clauses, Right: endClause}
+		}
+		ps.setBindVars(bindVars, splitEndPrefix, end)
+	}
+	if ps.sel.Where != nil {
+		clauses = &sqlparser.AndExpr{
+			Left:  &sqlparser.ParenBoolExpr{Expr: ps.sel.Where.Expr},
+			Right: clauses,
+		}
+	}
+	sel := *ps.sel
+	sel.Where = &sqlparser.Where{
+		Type: sqlparser.AST_WHERE,
+		Expr: clauses,
+	}
+	return proto.QuerySplit{
+		Query: proto.BoundQuery{
+			Sql:           sqlparser.String(&sel),
+			BindVariables: bindVars,
+		},
+		RowCount: rowCount,
+	}
+}
+
+// setBindVars sets the bind variables of the split columns for values.
+func (ps *PKSplitter) setBindVars(bindVars map[string]interface{}, prefix string, values []sqltypes.Value) {
+	for i, column := range ps.splitColumns {
+		bindVars[prefix+column] = bindValue(values[i])
+	}
+}
+
+// bindValue returns the bind variable value of a value returned by MySQL.
+func bindValue(v sqltypes.Value) interface{} {
+	if v.IsNumeric() {
+		if i, err := v.ParseInt64(); err == nil {
+			return i
+		}
+		if u, err := v.ParseUint64(); err == nil {
+			return u
+		}
+	}
+	return v.Raw()
+}
+
+// compareColumns returns the expression that compares the columns to
+// their bind variables in lexicographic order. op is the comparison
+// of the leading columns, lastOp the one of the last column: for
+// instance, (a, b) >= (:a, :b) is a > :a or (a = :a and b >= :b).
+func compareColumns(columns []string, prefix, op, lastOp string) sqlparser.BoolExpr {
+	col := &sqlparser.ColName{Name: []byte(columns[0])}
+	arg := sqlparser.ValArg(":" + prefix + columns[0])
+	if len(columns) == 1 {
+		return &sqlparser.ComparisonExpr{Operator: lastOp, Left: col, Right: arg}
+	}
+	return &sqlparser.ParenBoolExpr{
+		Expr: &sqlparser.OrExpr{
+			Left: &sqlparser.ComparisonExpr{Operator: op, Left: col, Right: arg},
+			Right: &sqlparser.ParenBoolExpr{
+				Expr: &sqlparser.AndExpr{
+					Left:  &sqlparser.ComparisonExpr{Operator: sqlparser.AST_EQ, Left: col, Right: arg},
+					Right: compareColumns(columns[1:], prefix, op, lastOp),
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tabletserver
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/sqltypes"
+	"github.com/youtube/vitess/go/vt/schema"
+	"github.com/youtube/vitess/go/vt/tabletserver/proto"
+)
+
+// getPKSplitterSchemaInfo adds a table with a composite primary key
+// to the tables of getSchemaInfo.
+func getPKSplitterSchemaInfo() *SchemaInfo {
+	schemaInfo := getSchemaInfo()
+	table := &schema.Table{
+		Name: "test_table_composite",
+	}
+	zero, _ := sqltypes.BuildValue(0)
+	table.AddColumn("user_id", "int", zero, "")
+	table.AddColumn("name", "varchar(64)", sqltypes.Value{}, "")
+	table.AddColumn("count", "int", zero, "")
+	table.PKColumns = []int{0, 1}
+	primaryIndex := table.AddIndex("PRIMARY")
+	primaryIndex.AddColumn("user_id", 12345)
+	primaryIndex.AddColumn("name", 12345)
+	schemaInfo.tables["test_table_composite"] = &TableInfo{Table: table}
+	return schemaInfo
+}
+
+func TestPKSplitterValidateQuery(t *testing.T) {
+	schemaInfo := getPKSplitterSchemaInfo()
+	testcases := []struct {
+		sql          string
+		splitColumns []string
+		err          string
+	}{
+		{"delete from test_table", nil, "not a select statement"},
+		{"select * from test_table limit 10", nil, "unsupported query"},
+		{"select id from test_table group by id", nil, "unsupported query"},
+		{"select A.* from test_table A join test_table B", nil, "unsupported query"},
+		{"select count(*) from test_table", nil, "unsupported query: aggregate function count"},
+		{"select * from test_table_no_pk", nil, "no primary keys"},
+		{"select * from test_table_composite", []string{"name"}, "split columns [name] are not a prefix of the primary key of test_table_composite"},
+		{"select * from test_table_composite", []string{"user_id", "name", "count"}, "split columns [user_id name count] are not a prefix of the primary key of test_table_composite"},
+	}
+	for _, tc := range testcases {
+		splitter := NewPKSplitter(&proto.BoundQuery{Sql: tc.sql}, tc.splitColumns, schemaInfo)
+		if err := splitter.validateQuery(); err == nil || err.Error() != tc.err {
+			t.Errorf("validateQuery(%s, %v): %v, want %s", tc.sql, tc.splitColumns, err, tc.err)
+		}
+	}
+
+	splitter := NewPKSplitter(&proto.BoundQuery{Sql: "select * from test_table_composite where count > :count order by name"}, nil, schemaInfo)
+	if err := splitter.validateQuery(); err != nil {
+		t.Fatalf("validateQuery: %v", err)
+	}
+	if want := []string{"user_id", "name"}; !reflect.DeepEqual(splitter.splitColumns, want) {
+		t.Errorf("splitColumns: %v, want %v", splitter.splitColumns, want)
+	}
+
+	splitter = NewPKSplitter(&proto.BoundQuery{Sql: "select * from test_table_composite"}, []string{"user_id"}, schemaInfo)
+	if err := splitter.validateQuery(); err != nil {
+		t.Errorf("validateQuery with a prefix of the primary key: %v", err)
+	}
+}
+
+func TestPKSplitterRowsPerPart(t *testing.T) {
+	splitter := NewPKSplitter(&proto.BoundQuery{Sql: "select * from test_table"}, nil, getPKSplitterSchemaInfo())
+	if err := splitter.validateQuery(); err != nil {
+		t.Fatalf("validateQuery: %v", err)
+	}
+	if got, want := splitter.tableRowsQuery(), "select table_rows from information_schema.tables where table_schema = database() and table_name = 'test_table'"; got != want {
+		t.Errorf("tableRowsQuery: %s, want %s", got, want)
+	}
+	tableRows := &mproto.QueryResult{
+		Rows: [][]sqltypes.Value{{sqltypes.MakeNumeric([]byte("1001"))}},
+	}
+	testcases := []struct {
+		splitCount int
+		want       int64
+	}{
+		{0, 0},
+		{1, 0},
+		{10, 101},
+		{2000, 1},
+	}
+	for _, tc := range testcases {
+		got, err := splitter.rowsPerPart(tableRows, tc.splitCount)
+		if err != nil || got != tc.want {
+			t.Errorf("rowsPerPart(%d): %v, %v, want %v", tc.splitCount, got, err, tc.want)
+		}
+	}
+	if got, err := splitter.rowsPerPart(&mproto.QueryResult{}, 10); err != nil || got != 0 {
+		t.Errorf("rowsPerPart without table rows: %v, %v, want 0", got, err)
+	}
+}
+
+func TestPKSplitterBoundaryQuery(t *testing.T) {
+	splitter := NewPKSplitter(&proto.BoundQuery{Sql: "select * from test_table_composite where count > :count"}, nil, getPKSplitterSchemaInfo())
+	if err := splitter.validateQuery(); err != nil {
+		t.Fatalf("validateQuery: %v", err)
+	}
+
+	parsedQuery, bindVars := splitter.boundaryQuery(nil, 1000)
+	want := "select user_id, name from test_table_composite order by user_id asc, name asc limit 1000, 1"
+	if parsedQuery.Query != want {
+		t.Errorf("first boundaryQuery: %s, want %s", parsedQuery.Query, want)
+	}
+	if len(bindVars) != 0 {
+		t.Errorf("first boundaryQuery bind variables: %v, want none", bindVars)
+	}
+
+	prev := []sqltypes.Value{sqltypes.MakeNumeric([]byte("12")), sqltypes.MakeString([]byte("foo"))}
+	parsedQuery, bindVars = splitter.boundaryQuery(prev, 1000)
+	want = "select user_id, name from test_table_composite where (user_id > :_splitquery_start_user_id or (user_id = :_splitquery_start_user_id and name > :_splitquery_start_name)) order by user_id asc, name asc limit 999, 1"
+	if parsedQuery.Query != want {
+		t.Errorf("next boundaryQuery: %s, want %s", parsedQuery.Query, want)
+	}
+	wantBindVars := map[string]interface{}{
+		"_splitquery_start_user_id": int64(12),
+		"_splitquery_start_name":    []byte("foo"),
+	}
+	if !reflect.DeepEqual(bindVars, wantBindVars) {
+		t.Errorf("next boundaryQuery bind variables: %v, want %v", bindVars, wantBindVars)
+	}
+}
+
+func TestPKSplitterSplit(t *testing.T) {
+	query := &proto.BoundQuery{
+		Sql:           "select * from test_table_composite where count > :count or count is null",
+		BindVariables: map[string]interface{}{"count": 10},
+	}
+	splitter := NewPKSplitter(query, nil, getPKSplitterSchemaInfo())
+	if err := splitter.validateQuery(); err != nil {
+		t.Fatalf("validateQuery: %v", err)
+	}
+
+	// Without boundaries, the query isn't split.
+	splits := splitter.split(nil, 0)
+	if want := []proto.QuerySplit{{Query: *query}}; !reflect.DeepEqual(splits, want) {
+		t.Errorf("split without boundaries: %v, want %v", splits, want)
+	}
+
+	boundaries := [][]sqltypes.Value{
+		{sqltypes.MakeNumeric([]byte("12")), sqltypes.MakeString([]byte("foo"))},
+		{sqltypes.MakeNumeric([]byte("20")), sqltypes.MakeString([]byte("bar"))},
+	}
+	splits = splitter.split(boundaries, 100)
+	start := "(user_id > :_splitquery_start_user_id or (user_id = :_splitquery_start_user_id and name >= :_splitquery_start_name))"
+	end := "(user_id < :_splitquery_end_user_id or (user_id = :_splitquery_end_user_id and name < :_splitquery_end_name))"
+	where := "select * from test_table_composite where (count > :count or count is null) and "
+	want := []proto.QuerySplit{{
+		Query: proto.BoundQuery{
+			Sql: where + end,
+			BindVariables: map[string]interface{}{
+				"count":                   10,
+				"_splitquery_end_user_id": int64(12),
+				"_splitquery_end_name":    []byte("foo"),
+			},
+		},
+		RowCount: 100,
+	}, {
+		Query: proto.BoundQuery{
+			Sql: where + start + " and " + end,
+			BindVariables: map[string]interface{}{
+				"count":                     10,
+				"_splitquery_start_user_id": int64(12),
+				"_splitquery_start_name":    []byte("foo"),
+				"_splitquery_end_user_id":   int64(20),
+				"_splitquery_end_name":      []byte("bar"),
+			},
+		},
+		RowCount: 100,
+	}, {
+		Query: proto.BoundQuery{
+			Sql: where + start,
+			BindVariables: map[string]interface{}{
+				"count":                     10,
+				"_splitquery_start_user_id": int64(20),
+				"_splitquery_start_name":    []byte("bar"),
+			},
+		},
+		RowCount: 100,
+	}}
+	if len(splits) != len(want) {
+		t.Fatalf("split: got %d parts, want %d", len(splits), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(splits[i], want[i]) {
+			t.Errorf("split[%d]:\n%v, want\n%v", i, splits[i], want[i])
+		}
+	}
+	// The original query is left untouched.
+	if !strings.HasSuffix(query.Sql, "count is null") || len(query.BindVariables) != 1 {
+		t.Errorf("query was modified: %v", query)
+	}
+}
@@ -6,6 +6,7 @@ package proto
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/youtube/vitess/go/bytes2"
 	mproto "github.com/youtube/vitess/go/mysql/proto"
@@ -133,6 +134,38 @@ type TransactionInfo struct {
 
 //go:generate bsongen -file $GOFILE -type TransactionInfo -o transaction_info_bson.go
 
+// DTParticipant is a shard that takes part in a distributed transaction.
+type DTParticipant struct {
+	Keyspace string
+	Shard    string
+}
+
+// DistributedTransaction is passed to the two-phase commit calls.
+// Dtid identifies the transaction across all its shards. TransactionId
+// is only used by Prepare and StartCommit, and Participants only by
+// CreateTransaction.
+type DistributedTransaction struct {
+	SessionId     int64
+	TransactionId int64
+	Dtid          string
+	Participants  []DTParticipant
+}
+
+// ReplicationPositionRequest is passed to MasterPosition and
+// WaitMasterPos. Position and WaitTimeout are only used by
+// WaitMasterPos.
+type ReplicationPositionRequest struct {
+	SessionId   int64
+	Position    string
+	WaitTimeout time.Duration
+}
+
+// ReplicationPosition is returned by MasterPosition. Position is an
+// encoded myproto.ReplicationPosition.
+type ReplicationPosition struct {
+	Position string
+}
+
 // SplitQueryRequest represents a request to split a Query into queries that
 // each return a subset of the original query.
 // SplitColumn: preferred column to split. Server will pick a random PK column
@@ -147,6 +180,21 @@ type SplitQueryRequest struct {
 	ImmediateCallerID *VTGateCallerID
 }
 
+// SplitQueryV2Request is the payload to SplitQueryV2. Query is split
+// on ranges of SplitColumns, which must be a prefix of the primary key
+// of its table, or empty to use the whole primary key. Each part has
+// about NumRowsPerQueryPart rows. If it's 0, it's estimated from
+// SplitCount and the number of rows of the table.
+type SplitQueryV2Request struct {
+	Query               BoundQuery
+	SplitColumns        []string
+	SplitCount          int
+	NumRowsPerQueryPart int64
+	SessionID           int64
+	EffectiveCallerID   *CallerID
+	ImmediateCallerID   *VTGateCallerID
+}
+
 // QuerySplit represents a split of SplitQueryRequest.Query. RowCount is only
 // approximate.
 type QuerySplit struct {
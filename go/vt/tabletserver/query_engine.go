@@ -49,11 +49,14 @@ type QueryEngine struct {
 	streamConnPool *ConnPool
 
 	// Services
-	txPool       *TxPool
-	consolidator *sync2.Consolidator
-	invalidator  *RowcacheInvalidator
-	streamQList  *QueryList
-	tasks        sync.WaitGroup
+	txPool        *TxPool
+	txResolver    *TxResolver
+	consolidator  *sync2.Consolidator
+	invalidator   *RowcacheInvalidator
+	warmer        *RowcacheWarmer
+	resourceUsage *ResourceUsage
+	streamQList   *QueryList
+	tasks         sync.WaitGroup
 
 	// Vars
 	queryTimeout     sync2.AtomicDuration
@@ -64,6 +67,7 @@ type QueryEngine struct {
 	streamBufferSize sync2.AtomicInt64
 	strictTableAcl   bool
 	enableAutoCommit bool
+	enableTwoPC      bool
 
 	// Loggers
 	accessCheckerLogger *logutil.ThrottledLogger
@@ -101,7 +105,10 @@ func getOrPanic(ctx context.Context, pool *ConnPool) *DBConn {
 // This is a singleton class.
 // You must call this only once.
 func NewQueryEngine(config Config) *QueryEngine {
-	qe := &QueryEngine{enableAutoCommit: config.EnableAutoCommit}
+	qe := &QueryEngine{
+		enableAutoCommit: config.EnableAutoCommit,
+		enableTwoPC:      config.EnableTwoPC,
+	}
 	qe.queryServiceStats = NewQueryServiceStats(config.StatsPrefix, config.EnablePublishStats)
 	qe.schemaInfo = NewSchemaInfo(
 		config.QueryCacheSize,
@@ -141,6 +148,8 @@ func NewQueryEngine(config Config) *QueryEngine {
 		config.EnablePublishStats,
 		qe.queryServiceStats,
 	)
+	qe.connPool.SetCallerQuota(config.PoolCallerQuota)
+	qe.streamConnPool.SetCallerQuota(config.PoolCallerQuota)
 
 	// Services
 	qe.txPool = NewTxPool(
@@ -153,9 +162,20 @@ func NewQueryEngine(config Config) *QueryEngine {
 		config.EnablePublishStats,
 		qe.queryServiceStats,
 	)
+	qe.txPool.pool.SetCallerQuota(config.TransactionCallerQuota)
+	qe.resourceUsage = NewResourceUsage(config.StatsPrefix, config.EnablePublishStats)
+	qe.txPool.resourceUsage = qe.resourceUsage
+	http.Handle(config.DebugURLPrefix+"/resource_usage", qe.resourceUsage)
+	qe.txResolver = NewTxResolver(
+		qe.txPool,
+		time.Duration(config.TwoPCAbandonAge*1e9),
+		qe.queryServiceStats,
+	)
 	qe.consolidator = sync2.NewConsolidator()
 	http.Handle(config.DebugURLPrefix+"/consolidations", qe.consolidator)
 	qe.invalidator = NewRowcacheInvalidator(config.StatsPrefix, qe, config.EnablePublishStats)
+	qe.warmer = NewRowcacheWarmer(config.StatsPrefix, qe, config.RowCache.WarmupKeys, config.EnablePublishStats)
+	qe.schemaInfo.warmer = qe.warmer
 	qe.streamQList = NewQueryList()
 
 	// Vars
@@ -229,6 +249,41 @@ func (qe *QueryEngine) Open(dbconfigs *dbconfigs.DBConfigs, schemaOverrides []Sc
 	qe.connPool.Open(&appParams, &dbaParams)
 	qe.streamConnPool.Open(&appParams, &dbaParams)
 	qe.txPool.Open(&appParams, &dbaParams)
+
+	// The rowcache starts cold: read the hottest rows back.
+	if dbconfigs.App.EnableRowcache {
+		qe.warmer.Open()
+		qe.warmer.Warm()
+	}
+}
+
+// OpenTwoPC prepares a master for two-phase commits: it creates the
+// sidecar tables, restores the prepared transactions of the redo log,
+// and starts the resolver of abandoned transactions. It's a no-op if
+// two-phase commits are not enabled.
+func (qe *QueryEngine) OpenTwoPC(ctx context.Context) {
+	if !qe.enableTwoPC {
+		return
+	}
+	dbaParams := qe.dbconfigs.App.ConnParams
+	if qe.dbconfigs.Dba.Uname != "" {
+		dbaParams.Uname = qe.dbconfigs.Dba.Uname
+		dbaParams.Pass = qe.dbconfigs.Dba.Pass
+	}
+	conn, err := dbconnpool.NewDBConnection(&dbaParams, qe.queryServiceStats.MySQLStats)
+	if err != nil {
+		panic(NewTabletErrorSql(ErrFatal, err))
+	}
+	defer conn.Close()
+	for _, query := range twoPCTables {
+		if _, err := conn.ExecuteFetch(query, 1, false); err != nil {
+			panic(NewTabletErrorSql(ErrFatal, err))
+		}
+	}
+	if err := qe.txPool.RestorePrepared(ctx); err != nil {
+		panic(err)
+	}
+	qe.txResolver.Open()
 }
 
 // Launch launches the specified function inside a goroutine.
@@ -275,8 +330,10 @@ func (qe *QueryEngine) WaitForTxEmpty() {
 // You must ensure that no more queries will be sent
 // before calling Close.
 func (qe *QueryEngine) Close() {
+	qe.warmer.Close()
 	qe.tasks.Wait()
 	// Close in reverse order of Open.
+	qe.txResolver.Close()
 	qe.txPool.Close()
 	qe.streamConnPool.Close()
 	qe.connPool.Close()
@@ -289,6 +346,22 @@ func (qe *QueryEngine) Close() {
 // Commit commits the specified transaction.
 func (qe *QueryEngine) Commit(ctx context.Context, logStats *SQLQueryStats, transactionID int64) {
 	dirtyTables, err := qe.txPool.SafeCommit(ctx, transactionID)
+	qe.invalidateRows(logStats, dirtyTables)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// CommitPrepared commits the prepared transaction dtid.
+func (qe *QueryEngine) CommitPrepared(ctx context.Context, logStats *SQLQueryStats, dtid string) {
+	dirtyTables, err := qe.txPool.SafeCommitPrepared(ctx, dtid)
+	qe.invalidateRows(logStats, dirtyTables)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (qe *QueryEngine) invalidateRows(logStats *SQLQueryStats, dirtyTables map[string]DirtyKeys) {
 	for tableName, invalidList := range dirtyTables {
 		tableInfo := qe.schemaInfo.GetTable(tableName)
 		if tableInfo == nil {
@@ -304,7 +377,4 @@ func (qe *QueryEngine) Commit(ctx context.Context, logStats *SQLQueryStats, tran
 		logStats.CacheInvalidations += invalidations
 		tableInfo.invalidations.Add(invalidations)
 	}
-	if err != nil {
-		panic(err)
-	}
 }
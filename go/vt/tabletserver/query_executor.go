@@ -47,17 +47,21 @@ func (qre *QueryExecutor) Execute() (reply *mproto.QueryResult, err error) {
 		qre.qe.queryServiceStats.QueryStats.Add(planName, duration)
 		if reply == nil {
 			qre.plan.AddStats(1, duration, 0, 1)
+			qre.qe.resourceUsage.RecordQuery(qre.plan.TableName, qre.logStats, 0)
 			return
 		}
 		qre.plan.AddStats(1, duration, int64(reply.RowsAffected), 0)
+		qre.qe.resourceUsage.RecordQuery(qre.plan.TableName, qre.logStats, len(reply.Rows))
 		qre.logStats.RowsAffected = int(reply.RowsAffected)
 		qre.logStats.Rows = reply.Rows
 		qre.qe.queryServiceStats.ResultStats.Add(int64(len(reply.Rows)))
 	}(time.Now())
 
-	if err := qre.checkPermissions(); err != nil {
+	release, err := qre.checkPermissions()
+	if err != nil {
 		return nil, err
 	}
+	defer release()
 
 	if qre.plan.PlanId == planbuilder.PLAN_DDL {
 		return qre.execDDL()
@@ -77,7 +81,7 @@ func (qre *QueryExecutor) Execute() (reply *mproto.QueryResult, err error) {
 			if qre.qe.strictMode.Get() != 0 {
 				return nil, NewTabletError(ErrFail, "DML too complex")
 			}
-			reply, err = qre.directFetch(conn, qre.plan.FullQuery, qre.bindVars, nil)
+			reply, err = qre.dmlFetch(conn, qre.plan.FullQuery, qre.bindVars, nil)
 		case planbuilder.PLAN_INSERT_PK:
 			reply, err = qre.execInsertPK(conn)
 		case planbuilder.PLAN_INSERT_SUBQUERY:
@@ -126,10 +130,16 @@ func (qre *QueryExecutor) Stream(sendReply func(*mproto.QueryResult) error) erro
 	qre.logStats.OriginalSql = qre.query
 	qre.logStats.PlanType = qre.plan.PlanId.String()
 	defer qre.qe.queryServiceStats.QueryStats.Record(qre.plan.PlanId.String(), time.Now())
+	rowsRead := 0
+	defer func() {
+		qre.qe.resourceUsage.RecordQuery(qre.plan.TableName, qre.logStats, rowsRead)
+	}()
 
-	if err := qre.checkPermissions(); err != nil {
+	release, err := qre.checkPermissions()
+	if err != nil {
 		return err
 	}
+	defer release()
 
 	conn, err := qre.getConn(qre.qe.streamConnPool)
 	if err != nil {
@@ -141,7 +151,10 @@ func (qre *QueryExecutor) Stream(sendReply func(*mproto.QueryResult) error) erro
 	qre.qe.streamQList.Add(qd)
 	defer qre.qe.streamQList.Remove(qd)
 
-	return qre.fullStreamFetch(conn, qre.plan.FullQuery, qre.bindVars, nil, sendReply)
+	return qre.fullStreamFetch(conn, qre.plan.FullQuery, qre.bindVars, nil, func(reply *mproto.QueryResult) error {
+		rowsRead += len(reply.Rows)
+		return sendReply(reply)
+	})
 }
 
 func (qre *QueryExecutor) execDmlAutoCommit() (reply *mproto.QueryResult, err error) {
@@ -171,7 +184,7 @@ func (qre *QueryExecutor) execDmlAutoCommit() (reply *mproto.QueryResult, err er
 		if qre.qe.strictMode.Get() != 0 {
 			return nil, NewTabletError(ErrFail, "DML too complex")
 		}
-		reply, err = qre.directFetch(conn, qre.plan.FullQuery, qre.bindVars, nil)
+		reply, err = qre.dmlFetch(conn, qre.plan.FullQuery, qre.bindVars, nil)
 	case planbuilder.PLAN_INSERT_PK:
 		reply, err = qre.execInsertPK(conn)
 	case planbuilder.PLAN_INSERT_SUBQUERY:
@@ -186,10 +199,12 @@ func (qre *QueryExecutor) execDmlAutoCommit() (reply *mproto.QueryResult, err er
 	return reply, nil
 }
 
-func (qre *QueryExecutor) checkPermissions() error {
+// checkPermissions applies the query rules and the table ACLs to the
+// query. If it's allowed, release must be called once it's done.
+func (qre *QueryExecutor) checkPermissions() (release func(), err error) {
 	// Skip permissions check if we have a background context.
 	if qre.ctx == context.Background() {
-		return nil
+		return func() {}, nil
 	}
 
 	// Blacklist
@@ -200,12 +215,9 @@ func (qre *QueryExecutor) checkPermissions() error {
 		remoteAddr = ci.RemoteAddr()
 		username = ci.Username()
 	}
-	action, desc := qre.plan.Rules.getAction(remoteAddr, username, qre.bindVars)
-	switch action {
-	case QR_FAIL:
-		return NewTabletError(ErrFail, "Query disallowed due to rule: %s", desc)
-	case QR_FAIL_RETRY:
-		return NewTabletError(ErrRetry, "Query disallowed due to rule: %s", desc)
+	release, err = qre.plan.Rules.apply(qre.ctx, remoteAddr, username, qre.query, qre.bindVars)
+	if err != nil {
+		return nil, err
 	}
 
 	// Perform table ACL check if it is enabled
@@ -213,11 +225,12 @@ func (qre *QueryExecutor) checkPermissions() error {
 		errStr := fmt.Sprintf("table acl error: %q cannot run %v on table %q", username, qre.plan.PlanId, qre.plan.TableName)
 		// Raise error if in strictTableAcl mode, else just log an error
 		if qre.qe.strictTableAcl {
-			return NewTabletError(ErrFail, "%s", errStr)
+			release()
+			return nil, NewTabletError(ErrFail, "%s", errStr)
 		}
 		qre.qe.accessCheckerLogger.Errorf("%s", errStr)
 	}
-	return nil
+	return release, nil
 }
 
 func (qre *QueryExecutor) execDDL() (*mproto.QueryResult, error) {
@@ -282,6 +295,8 @@ func (qre *QueryExecutor) fetchMulti(pkRows [][]sqltypes.Value, limit int64) (*m
 	rcresults := tableInfo.Cache.Get(qre.ctx, keys)
 	rows := make([][]sqltypes.Value, 0, len(pkRows))
 	missingRows := make([][]sqltypes.Value, 0, len(pkRows))
+	// foundPKs are recorded for the RowcacheWarmer.
+	foundPKs := make([][]sqltypes.Value, 0, len(pkRows))
 	var hits, absent, misses int64
 	for i, pk := range pkRows {
 		rcresult := rcresults[keys[i]]
@@ -293,6 +308,7 @@ func (qre *QueryExecutor) fetchMulti(pkRows [][]sqltypes.Value, limit int64) (*m
 				}
 			}
 			rows = append(rows, applyFilter(qre.plan.ColumnNumbers, rcresult.Row))
+			foundPKs = append(foundPKs, pk)
 			hits++
 		} else {
 			missingRows = append(missingRows, pk)
@@ -313,7 +329,9 @@ func (qre *QueryExecutor) fetchMulti(pkRows [][]sqltypes.Value, limit int64) (*m
 		absent = int64(len(pkRows)) - hits - misses
 		for _, row := range resultFromdb.Rows {
 			rows = append(rows, applyFilter(qre.plan.ColumnNumbers, row))
-			key := buildKey(applyFilter(qre.plan.TableInfo.PKColumns, row))
+			pk := applyFilter(qre.plan.TableInfo.PKColumns, row)
+			foundPKs = append(foundPKs, pk)
+			key := buildKey(pk)
 			tableInfo.Cache.Set(qre.ctx, key, row, rcresults[key].Cas)
 		}
 	}
@@ -323,6 +341,8 @@ func (qre *QueryExecutor) fetchMulti(pkRows [][]sqltypes.Value, limit int64) (*m
 	qre.logStats.CacheMisses = misses
 
 	qre.logStats.QuerySources |= QuerySourceRowcache
+	qre.logStats.rowcacheTable = tableInfo.Name
+	qre.logStats.rowcachePKs = foundPKs
 
 	tableInfo.hits.Add(hits)
 	tableInfo.absent.Add(absent)
@@ -448,7 +468,7 @@ func (qre *QueryExecutor) execInsertPKRows(conn poolConn, pkRows [][]sqltypes.Va
 		return nil, err
 	}
 	bsc := buildStreamComment(qre.plan.TableInfo, pkRows, secondaryList)
-	return qre.directFetch(conn, qre.plan.OuterQuery, qre.bindVars, bsc)
+	return qre.dmlFetch(conn, qre.plan.OuterQuery, qre.bindVars, bsc)
 }
 
 func (qre *QueryExecutor) execDMLPK(conn poolConn, invalidator CacheInvalidator) (*mproto.QueryResult, error) {
@@ -493,7 +513,7 @@ func (qre *QueryExecutor) execDMLPKRows(conn poolConn, pkRows [][]sqltypes.Value
 			Columns: qre.plan.TableInfo.Indexes[0].Columns,
 			Rows:    pkRows,
 		}
-		r, err := qre.directFetch(conn, qre.plan.OuterQuery, qre.bindVars, bsc)
+		r, err := qre.dmlFetch(conn, qre.plan.OuterQuery, qre.bindVars, bsc)
 		if err != nil {
 			return nil, err
 		}
@@ -668,7 +688,7 @@ func (qre *QueryExecutor) getConn(pool *ConnPool) (*DBConn, error) {
 	case nil:
 		qre.logStats.WaitingForConnection += time.Now().Sub(start)
 		return conn, nil
-	case ErrConnPoolClosed:
+	case ErrConnPoolClosed, ErrCallerQuota:
 		return nil, err
 	}
 	return nil, NewTabletErrorSql(ErrFatal, err)
@@ -685,9 +705,12 @@ func (qre *QueryExecutor) qFetch(logStats *SQLQueryStats, parsedQuery *sqlparser
 		waitingForConnectionStart := time.Now()
 		conn, err := qre.qe.connPool.Get(qre.ctx)
 		logStats.WaitingForConnection += time.Now().Sub(waitingForConnectionStart)
-		if err != nil {
+		switch {
+		case err == ErrCallerQuota:
+			q.Err = err
+		case err != nil:
 			q.Err = NewTabletErrorSql(ErrFatal, err)
-		} else {
+		default:
 			defer conn.Recycle()
 			q.Result, q.Err = qre.execSQL(conn, sql, false)
 		}
@@ -711,6 +734,23 @@ func (qre *QueryExecutor) directFetch(conn poolConn, parsedQuery *sqlparser.Pars
 	return qre.execSQL(conn, sql, false)
 }
 
+// dmlFetch is like directFetch, but it also records the final DML
+// in the transaction, in case it's prepared for a two-phase commit.
+func (qre *QueryExecutor) dmlFetch(conn poolConn, parsedQuery *sqlparser.ParsedQuery, bindVars map[string]interface{}, buildStreamComment []byte) (*mproto.QueryResult, error) {
+	sql, err := qre.generateFinalSQL(parsedQuery, bindVars, buildStreamComment)
+	if err != nil {
+		return nil, err
+	}
+	result, err := qre.execSQL(conn, sql, false)
+	if err != nil {
+		return nil, err
+	}
+	if txc, ok := conn.(*TxConnection); ok {
+		txc.RecordRedo(sql)
+	}
+	return result, nil
+}
+
 // fullFetch also fetches field info
 func (qre *QueryExecutor) fullFetch(conn poolConn, parsedQuery *sqlparser.ParsedQuery, bindVars map[string]interface{}, buildStreamComment []byte) (*mproto.QueryResult, error) {
 	sql, err := qre.generateFinalSQL(parsedQuery, bindVars, buildStreamComment)
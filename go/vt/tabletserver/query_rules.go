@@ -7,13 +7,27 @@ package tabletserver
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"regexp"
 	"strconv"
+	"time"
 
+	"github.com/youtube/vitess/go/ratelimiter"
+	"github.com/youtube/vitess/go/streamlog"
+	"github.com/youtube/vitess/go/sync2"
+	"github.com/youtube/vitess/go/vt/callerid"
 	"github.com/youtube/vitess/go/vt/key"
+	"github.com/youtube/vitess/go/vt/sqlparser"
 	"github.com/youtube/vitess/go/vt/tabletserver/planbuilder"
+	"golang.org/x/net/context"
+
+	vtpb "github.com/youtube/vitess/go/vt/proto/vtrpc"
 )
 
+// QueryRuleLogger receives the queries matched by QR_LOG rules.
+// Call QueryRuleLogger.ServeLogs in your main program to see them.
+var QueryRuleLogger = streamlog.New("QueryRuleLog", 50)
+
 //-----------------------------------------------
 
 // QueryRules is used to store and execute rules for the tabletserver.
@@ -107,13 +121,60 @@ func (qrs *QueryRules) filterByPlan(query string, planid planbuilder.PlanType, t
 	return &QueryRules{newrules}
 }
 
-func (qrs *QueryRules) getAction(ip, user string, bindVars map[string]interface{}) (action Action, desc string) {
+// apply performs the actions of the rules that match the request, in
+// order. It stops at the first rule that rejects the query, and
+// returns its error. Otherwise, the query can proceed, and release
+// must be called once it's done: it gives back the slots taken by
+// the QR_MAX_CONCURRENCY rules.
+func (qrs *QueryRules) apply(ctx context.Context, ip, user, query string, bindVars map[string]interface{}) (release func(), err error) {
+	callerID := callerid.EffectiveCallerIDFromContext(ctx)
+	var acquired []*QueryRule
+	release = func() {
+		for _, qr := range acquired {
+			qr.concurrency.Release()
+		}
+	}
 	for _, qr := range qrs.rules {
-		if act := qr.getAction(ip, user, bindVars); act != QR_CONTINUE {
-			return act, qr.Description
+		switch qr.getAction(ip, user, callerID, bindVars) {
+		case QR_CONTINUE:
+		case QR_FAIL:
+			err = NewTabletError(ErrFail, "Query disallowed due to rule: %s", qr.Description)
+		case QR_FAIL_RETRY:
+			err = NewTabletError(ErrRetry, "Query disallowed due to rule: %s", qr.Description)
+		case QR_THROTTLE:
+			if !qr.throttler.Allow() {
+				err = NewTabletError(ErrFail, "Query throttled due to rule: %s", qr.Description)
+			}
+		case QR_LOG:
+			QueryRuleLogger.Send(&QueryRuleMatch{
+				Time:          time.Now(),
+				Rule:          qr.Name,
+				RemoteAddr:    ip,
+				Username:      user,
+				Query:         query,
+				BindVariables: bindVars,
+			})
+		case QR_DELAY:
+			timer := time.NewTimer(qr.delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				err = NewTabletError(ErrFail, "Query delayed by rule %s: %v", qr.Description, ctx.Err())
+			}
+		case QR_MAX_CONCURRENCY:
+			if !qr.concurrency.TryAcquire() {
+				err = NewTabletError(ErrFail, "Query exceeds the max concurrency of rule: %s", qr.Description)
+				break
+			}
+			acquired = append(acquired, qr)
+		}
+		if err != nil {
+			release()
+			return nil, err
 		}
 	}
-	return QR_CONTINUE, ""
+	return release, nil
 }
 
 //-----------------------------------------------
@@ -135,6 +196,14 @@ type QueryRule struct {
 	// Regexp conditions. nil conditions are ignored (TRUE).
 	requestIP, user, query *regexp.Regexp
 
+	// Regexp conditions on the effective caller id. nil conditions
+	// are ignored (TRUE).
+	principal, component, subcomponent *regexp.Regexp
+
+	// Fingerprint of the query (see sqlparser.Fingerprint). An empty
+	// fingerprint is ignored (TRUE).
+	fingerprint string
+
 	// Any matched plan will make this condition true (OR)
 	plans []planbuilder.PlanType
 
@@ -146,23 +215,37 @@ type QueryRule struct {
 
 	// Action to be performed on trigger
 	act Action
+
+	// Parameters and state of the action. They are shared by the
+	// copies of the rule, so they apply across all the query plans.
+	throttler   *ratelimiter.RateLimiter
+	delay       time.Duration
+	concurrency *sync2.Semaphore
 }
 
-// NewQueryRule creates a new QueryRule.
+// NewQueryRule creates a new QueryRule. The actions that take
+// parameters must be set with SetThrottle, SetDelay or
+// SetMaxConcurrency instead.
 func NewQueryRule(description, name string, act Action) (qr *QueryRule) {
-	// We ignore act because there's only one action right now
 	return &QueryRule{Description: description, Name: name, act: act}
 }
 
 // Copy performs a deep copy of a QueryRule.
 func (qr *QueryRule) Copy() (newqr *QueryRule) {
 	newqr = &QueryRule{
-		Description: qr.Description,
-		Name:        qr.Name,
-		requestIP:   qr.requestIP,
-		user:        qr.user,
-		query:       qr.query,
-		act:         qr.act,
+		Description:  qr.Description,
+		Name:         qr.Name,
+		requestIP:    qr.requestIP,
+		user:         qr.user,
+		query:        qr.query,
+		principal:    qr.principal,
+		component:    qr.component,
+		subcomponent: qr.subcomponent,
+		fingerprint:  qr.fingerprint,
+		act:          qr.act,
+		throttler:    qr.throttler,
+		delay:        qr.delay,
+		concurrency:  qr.concurrency,
 	}
 	if qr.plans != nil {
 		newqr.plans = make([]planbuilder.PlanType, len(qr.plans))
@@ -179,6 +262,43 @@ func (qr *QueryRule) Copy() (newqr *QueryRule) {
 	return newqr
 }
 
+// SetThrottle makes the rule a QR_THROTTLE rule: at most qps of
+// the matching queries are allowed per second, the others fail. The
+// error can't be retried: vtgate would send the queries to the other
+// tablets of the shard, and multiply the load the rule limits.
+func (qr *QueryRule) SetThrottle(qps int) error {
+	if qps <= 0 {
+		return NewTabletError(ErrFail, "invalid QPS %d for THROTTLE", qps)
+	}
+	qr.act = QR_THROTTLE
+	qr.throttler = ratelimiter.NewRateLimiter(qps, time.Second)
+	return nil
+}
+
+// SetDelay makes the rule a QR_DELAY rule: the matching queries
+// wait for delay before they are executed.
+func (qr *QueryRule) SetDelay(delay time.Duration) error {
+	if delay <= 0 {
+		return NewTabletError(ErrFail, "invalid Delay %v for DELAY", delay)
+	}
+	qr.act = QR_DELAY
+	qr.delay = delay
+	return nil
+}
+
+// SetMaxConcurrency makes the rule a QR_MAX_CONCURRENCY rule: at most
+// maxConcurrency of the matching queries can execute at the same
+// time, the others fail. Like for QR_THROTTLE, the error can't be
+// retried.
+func (qr *QueryRule) SetMaxConcurrency(maxConcurrency int) error {
+	if maxConcurrency <= 0 {
+		return NewTabletError(ErrFail, "invalid MaxConcurrency %d for MAX_CONCURRENCY", maxConcurrency)
+	}
+	qr.act = QR_MAX_CONCURRENCY
+	qr.concurrency = sync2.NewSemaphore(maxConcurrency, 0)
+	return nil
+}
+
 // SetIPCond adds a regular expression condition for the client IP.
 // It has to be a full match (not substring).
 func (qr *QueryRule) SetIPCond(pattern string) (err error) {
@@ -193,6 +313,34 @@ func (qr *QueryRule) SetUserCond(pattern string) (err error) {
 	return
 }
 
+// SetPrincipalCond adds a regular expression condition for the
+// principal of the effective caller id.
+func (qr *QueryRule) SetPrincipalCond(pattern string) (err error) {
+	qr.principal, err = regexp.Compile(makeExact(pattern))
+	return
+}
+
+// SetComponentCond adds a regular expression condition for the
+// component of the effective caller id.
+func (qr *QueryRule) SetComponentCond(pattern string) (err error) {
+	qr.component, err = regexp.Compile(makeExact(pattern))
+	return
+}
+
+// SetSubcomponentCond adds a regular expression condition for the
+// subcomponent of the effective caller id.
+func (qr *QueryRule) SetSubcomponentCond(pattern string) (err error) {
+	qr.subcomponent, err = regexp.Compile(makeExact(pattern))
+	return
+}
+
+// SetFingerprintCond adds a condition on the fingerprint of the query,
+// as returned by sqlparser.Fingerprint: it matches all the queries
+// that only differ by their values.
+func (qr *QueryRule) SetFingerprintCond(fingerprint string) {
+	qr.fingerprint = fingerprint
+}
+
 // AddPlanCond adds to the list of plans that can be matched for
 // the rule to fire.
 // This function acts as an OR: Any plan id match is considered a match.
@@ -296,20 +444,33 @@ func (qr *QueryRule) filterByPlan(query string, planid planbuilder.PlanType, tab
 	if !tableMatch(qr.tableNames, tableName) {
 		return nil
 	}
+	if !fingerprintMatch(qr.fingerprint, query) {
+		return nil
+	}
 	newqr = qr.Copy()
 	newqr.query = nil
 	newqr.plans = nil
 	newqr.tableNames = nil
+	newqr.fingerprint = ""
 	return newqr
 }
 
-func (qr *QueryRule) getAction(ip, user string, bindVars map[string]interface{}) Action {
+func (qr *QueryRule) getAction(ip, user string, callerID *vtpb.CallerID, bindVars map[string]interface{}) Action {
 	if !reMatch(qr.requestIP, ip) {
 		return QR_CONTINUE
 	}
 	if !reMatch(qr.user, user) {
 		return QR_CONTINUE
 	}
+	if !reMatch(qr.principal, callerid.GetPrincipal(callerID)) {
+		return QR_CONTINUE
+	}
+	if !reMatch(qr.component, callerid.GetComponent(callerID)) {
+		return QR_CONTINUE
+	}
+	if !reMatch(qr.subcomponent, callerid.GetSubcomponent(callerID)) {
+		return QR_CONTINUE
+	}
 	for _, bvcond := range qr.bindVarConds {
 		if !bvMatch(bvcond, bindVars) {
 			return QR_CONTINUE
@@ -322,6 +483,16 @@ func reMatch(re *regexp.Regexp, val string) bool {
 	return re == nil || re.MatchString(val)
 }
 
+// fingerprintMatch returns true if query has the fingerprint. The
+// queries that can't be parsed have no fingerprint.
+func fingerprintMatch(fingerprint, query string) bool {
+	if fingerprint == "" {
+		return true
+	}
+	queryFingerprint, err := sqlparser.Fingerprint(query)
+	return err == nil && queryFingerprint == fingerprint
+}
+
 func planMatch(plans []planbuilder.PlanType, plan planbuilder.PlanType) bool {
 	if plans == nil {
 		return true
@@ -368,8 +539,40 @@ const (
 	QR_CONTINUE = Action(iota)
 	QR_FAIL
 	QR_FAIL_RETRY
+	// QR_THROTTLE fails the queries beyond a rate (see SetThrottle).
+	QR_THROTTLE
+	// QR_LOG sends the queries to QueryRuleLogger, and lets them through.
+	QR_LOG
+	// QR_DELAY delays the queries (see SetDelay).
+	QR_DELAY
+	// QR_MAX_CONCURRENCY fails the queries beyond a number of
+	// concurrent queries (see SetMaxConcurrency).
+	QR_MAX_CONCURRENCY
 )
 
+// QueryRuleMatch is the record of a query matched by a QR_LOG rule.
+type QueryRuleMatch struct {
+	Time          time.Time
+	Rule          string
+	RemoteAddr    string
+	Username      string
+	Query         string
+	BindVariables map[string]interface{}
+}
+
+// Format returns a printable version of the match.
+func (qrm *QueryRuleMatch) Format(params url.Values) string {
+	return fmt.Sprintf(
+		"%v\t%v\t%v\t%v\t%q\t%v\t\n",
+		qrm.Time.Format(time.StampMicro),
+		qrm.Rule,
+		qrm.RemoteAddr,
+		qrm.Username,
+		qrm.Query,
+		qrm.BindVariables,
+	)
+}
+
 // BindVarCond represents a bind var condition.
 type BindVarCond struct {
 	name       string
@@ -698,12 +901,18 @@ func MapStrOperator(strop string) (op Operator, err error) {
 
 func BuildQueryRule(ruleInfo map[string]interface{}) (qr *QueryRule, err error) {
 	qr = NewQueryRule("", "", QR_FAIL)
+	// The parameters of the actions are only known once all the keys
+	// have been read.
+	var qps, maxConcurrency int
+	var delay time.Duration
 	for k, v := range ruleInfo {
 		var sv string
 		var lv []interface{}
+		var nv float64
 		var ok bool
 		switch k {
-		case "Name", "Description", "RequestIP", "User", "Query", "Action":
+		case "Name", "Description", "RequestIP", "User", "Query", "Action", "Delay",
+			"Principal", "Component", "Subcomponent", "Fingerprint":
 			sv, ok = v.(string)
 			if !ok {
 				return nil, NewTabletError(ErrFail, "want string for %s", k)
@@ -713,6 +922,11 @@ func BuildQueryRule(ruleInfo map[string]interface{}) (qr *QueryRule, err error)
 			if !ok {
 				return nil, NewTabletError(ErrFail, "want list for %s", k)
 			}
+		case "QPS", "MaxConcurrency":
+			nv, ok = v.(float64)
+			if !ok || nv != float64(int(nv)) {
+				return nil, NewTabletError(ErrFail, "want integer for %s", k)
+			}
 		default:
 			return nil, NewTabletError(ErrFail, "unrecognized tag %s", k)
 		}
@@ -736,6 +950,23 @@ func BuildQueryRule(ruleInfo map[string]interface{}) (qr *QueryRule, err error)
 			if err != nil {
 				return nil, NewTabletError(ErrFail, "could not set Query condition: %v", sv)
 			}
+		case "Principal":
+			err = qr.SetPrincipalCond(sv)
+			if err != nil {
+				return nil, NewTabletError(ErrFail, "could not set Principal condition: %v", sv)
+			}
+		case "Component":
+			err = qr.SetComponentCond(sv)
+			if err != nil {
+				return nil, NewTabletError(ErrFail, "could not set Component condition: %v", sv)
+			}
+		case "Subcomponent":
+			err = qr.SetSubcomponentCond(sv)
+			if err != nil {
+				return nil, NewTabletError(ErrFail, "could not set Subcomponent condition: %v", sv)
+			}
+		case "Fingerprint":
+			qr.SetFingerprintCond(sv)
 		case "Plans":
 			for _, p := range lv {
 				pv, ok := p.(string)
@@ -774,14 +1005,58 @@ func BuildQueryRule(ruleInfo map[string]interface{}) (qr *QueryRule, err error)
 				qr.act = QR_FAIL
 			case "FAIL_RETRY":
 				qr.act = QR_FAIL_RETRY
+			case "THROTTLE":
+				qr.act = QR_THROTTLE
+			case "LOG":
+				qr.act = QR_LOG
+			case "DELAY":
+				qr.act = QR_DELAY
+			case "MAX_CONCURRENCY":
+				qr.act = QR_MAX_CONCURRENCY
 			default:
 				return nil, NewTabletError(ErrFail, "invalid Action %s", sv)
 			}
+		case "QPS":
+			qps = int(nv)
+		case "MaxConcurrency":
+			maxConcurrency = int(nv)
+		case "Delay":
+			delay, err = time.ParseDuration(sv)
+			if err != nil {
+				return nil, NewTabletError(ErrFail, "want duration for Delay: %s", sv)
+			}
 		}
 	}
+	if err := buildActionParams(qr, qps, maxConcurrency, delay); err != nil {
+		return nil, err
+	}
 	return qr, nil
 }
 
+// buildActionParams sets the parameters of the action of qr. Each
+// action with parameters requires them, and the other actions don't
+// accept any.
+func buildActionParams(qr *QueryRule, qps, maxConcurrency int, delay time.Duration) error {
+	if qr.act != QR_THROTTLE && qps != 0 {
+		return NewTabletError(ErrFail, "QPS is only valid for THROTTLE")
+	}
+	if qr.act != QR_MAX_CONCURRENCY && maxConcurrency != 0 {
+		return NewTabletError(ErrFail, "MaxConcurrency is only valid for MAX_CONCURRENCY")
+	}
+	if qr.act != QR_DELAY && delay != 0 {
+		return NewTabletError(ErrFail, "Delay is only valid for DELAY")
+	}
+	switch qr.act {
+	case QR_THROTTLE:
+		return qr.SetThrottle(qps)
+	case QR_MAX_CONCURRENCY:
+		return qr.SetMaxConcurrency(maxConcurrency)
+	case QR_DELAY:
+		return qr.SetDelay(delay)
+	}
+	return nil
+}
+
 func buildBindVarCondition(bvc interface{}) (name string, onAbsent, onMismatch bool, op Operator, value interface{}, err error) {
 	bvcinfo, ok := bvc.(map[string]interface{})
 	if !ok {
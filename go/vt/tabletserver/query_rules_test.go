@@ -9,9 +9,14 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/youtube/vitess/go/vt/callerid"
 	"github.com/youtube/vitess/go/vt/key"
+	"github.com/youtube/vitess/go/vt/sqlparser"
 	"github.com/youtube/vitess/go/vt/tabletserver/planbuilder"
+	"github.com/youtube/vitess/go/vt/vterrors"
+	"golang.org/x/net/context"
 )
 
 func TestQueryRules(t *testing.T) {
@@ -507,31 +512,245 @@ func TestAction(t *testing.T) {
 
 	bv := make(map[string]interface{})
 	bv["a"] = uint64(0)
-	action, desc := qrs.getAction("123", "user1", bv)
-	if action != QR_FAIL {
-		t.Errorf("want fail")
+	_, err := qrs.apply(context.Background(), "123", "user1", "select 1", bv)
+	checkApplyError(t, err, ErrFail, "Query disallowed due to rule: rule 1")
+	_, err = qrs.apply(context.Background(), "1234", "user", "select 1", bv)
+	checkApplyError(t, err, ErrRetry, "Query disallowed due to rule: rule 2")
+	if _, err := qrs.apply(context.Background(), "1234", "user1", "select 1", bv); err != nil {
+		t.Errorf("apply: %v, want continue", err)
 	}
-	if desc != "rule 1" {
-		t.Errorf("want rule 1, got %s", desc)
+	bv["a"] = uint64(1)
+	_, err = qrs.apply(context.Background(), "1234", "user1", "select 1", bv)
+	checkApplyError(t, err, ErrFail, "Query disallowed due to rule: rule 3")
+}
+
+func TestCallerIDConditions(t *testing.T) {
+	qr := NewQueryRule("rule 1", "r1", QR_FAIL)
+	qr.SetPrincipalCond("batch.*")
+	qr.SetComponentCond("reports")
+	qr.SetSubcomponentCond("daily|weekly")
+	qrs := NewQueryRules()
+	qrs.Add(qr)
+
+	testcases := []struct {
+		principal, component, subcomponent string
+		want                               Action
+	}{
+		{"batch_user", "reports", "daily", QR_FAIL},
+		{"batch", "reports", "weekly", QR_FAIL},
+		{"user", "reports", "daily", QR_CONTINUE},
+		{"batch", "other", "daily", QR_CONTINUE},
+		{"batch", "reports", "monthly", QR_CONTINUE},
+	}
+	for _, tc := range testcases {
+		callerID := callerid.NewEffectiveCallerID(tc.principal, tc.component, tc.subcomponent)
+		ctx := callerid.NewContext(context.Background(), callerID, nil)
+		_, err := qrs.apply(ctx, "", "", "select 1", nil)
+		if tc.want == QR_CONTINUE {
+			if err != nil {
+				t.Errorf("apply(%v): %v, want continue", callerID, err)
+			}
+			continue
+		}
+		checkApplyError(t, err, ErrFail, "Query disallowed due to rule: rule 1")
 	}
-	action, desc = qrs.getAction("1234", "user", bv)
-	if action != QR_FAIL_RETRY {
-		t.Errorf("want fail_retry")
+	if _, err := qrs.apply(context.Background(), "", "", "select 1", nil); err != nil {
+		t.Errorf("apply without caller id: %v, want continue", err)
 	}
-	if desc != "rule 2" {
-		t.Errorf("want rule 2, got %s", desc)
+}
+
+func TestFingerprintCond(t *testing.T) {
+	fingerprint, err := sqlparser.Fingerprint("select * from a where id = 1")
+	if err != nil {
+		t.Fatal(err)
 	}
-	action, desc = qrs.getAction("1234", "user1", bv)
-	if action != QR_CONTINUE {
-		t.Errorf("want continue")
+	qr := NewQueryRule("rule 1", "r1", QR_FAIL)
+	qr.SetFingerprintCond(fingerprint)
+	qrs := NewQueryRules()
+	qrs.Add(qr)
+
+	testcases := []struct {
+		query string
+		match bool
+	}{
+		{"select * from a where id = 2", true},
+		{"select /* comment */ * from a where id = :id", true},
+		{"select * from a where id = 2 limit 1", false},
+		{"select * from b where id = 2", false},
+		{"invalid query", false},
+	}
+	for _, tc := range testcases {
+		rules := qrs.filterByPlan(tc.query, planbuilder.PLAN_PASS_SELECT, "a")
+		if match := len(rules.rules) == 1; match != tc.match {
+			t.Errorf("filterByPlan(%s): match %v, want %v", tc.query, match, tc.match)
+			continue
+		}
+		if tc.match && rules.rules[0].fingerprint != "" {
+			t.Errorf("filterByPlan(%s): fingerprint %s, want none", tc.query, rules.rules[0].fingerprint)
+		}
 	}
-	bv["a"] = uint64(1)
-	action, desc = qrs.getAction("1234", "user1", bv)
-	if action != QR_FAIL {
-		t.Errorf("want fail")
+
+	if err := qrs.UnmarshalJSON([]byte(`[{"Name": "r2", "Fingerprint": "` + fingerprint + `", "Principal": "batch", "Component": "c", "Subcomponent": "s"}]`)); err != nil {
+		t.Fatalf("UnmarshalJSON: %v", err)
+	}
+	qr = qrs.Find("r2")
+	if qr.fingerprint != fingerprint || qr.principal == nil || qr.component == nil || qr.subcomponent == nil {
+		t.Errorf("json rule: %+v", qr)
+	}
+}
+
+// checkApplyError makes sure err is a TabletError of errorType
+// starting with prefix.
+func checkApplyError(t *testing.T, err error, errorType int, prefix string) {
+	terr, ok := err.(*TabletError)
+	if !ok {
+		t.Fatalf("apply: %v, want a TabletError", err)
+	}
+	if terr.ErrorType != errorType || !strings.HasPrefix(terr.Message, prefix) {
+		t.Errorf("apply: %v, want %s with type %v", err, prefix, errorType)
+	}
+}
+
+func TestApplyThrottle(t *testing.T) {
+	qrs := NewQueryRules()
+	qr := NewQueryRule("throttle user", "r1", QR_CONTINUE)
+	if err := qr.SetThrottle(1); err != nil {
+		t.Fatal(err)
+	}
+	qr.SetUserCond("user")
+	qrs.Add(qr)
+	// The rate is shared by the copies of the rule.
+	qrs = qrs.Copy()
+
+	release, err := qrs.apply(context.Background(), "", "user", "select 1", nil)
+	if err != nil {
+		t.Fatalf("first query: %v", err)
+	}
+	release()
+	_, err = qrs.apply(context.Background(), "", "user", "select 1", nil)
+	checkApplyError(t, err, ErrFail, "Query throttled due to rule: throttle user")
+	// vtgate doesn't retry the throttled queries on the other tablets.
+	if code := rpcErrFromTabletError(err).Code; code != vterrors.TabletError+ErrFail {
+		t.Errorf("throttled query: code %d, want %d", code, vterrors.TabletError+ErrFail)
+	}
+	if _, err := qrs.apply(context.Background(), "", "other", "select 1", nil); err != nil {
+		t.Errorf("other user: %v", err)
+	}
+	if err := qr.SetThrottle(0); err == nil {
+		t.Errorf("SetThrottle(0) should fail")
+	}
+}
+
+func TestApplyLog(t *testing.T) {
+	ch := QueryRuleLogger.Subscribe("TestApplyLog")
+	defer QueryRuleLogger.Unsubscribe(ch)
+
+	qrs := NewQueryRules()
+	qrs.Add(NewQueryRule("log all", "log", QR_LOG))
+	qr := NewQueryRule("fail a", "fail", QR_FAIL)
+	qr.AddBindVarCond("a", false, false, QR_NOOP, nil)
+	qrs.Add(qr)
+
+	bindVars := map[string]interface{}{"b": 1}
+	release, err := qrs.apply(context.Background(), "1.2.3.4", "user", "select 1", bindVars)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	release()
+	match := (<-ch).(*QueryRuleMatch)
+	if match.Rule != "log" || match.RemoteAddr != "1.2.3.4" || match.Username != "user" || match.Query != "select 1" || match.BindVariables["b"] != 1 {
+		t.Errorf("wrong match: %+v", match)
+	}
+
+	// The rules after a LOG rule still apply.
+	_, err = qrs.apply(context.Background(), "", "", "select 1", map[string]interface{}{"a": 1})
+	checkApplyError(t, err, ErrFail, "Query disallowed due to rule: fail a")
+	<-ch
+}
+
+func TestApplyDelay(t *testing.T) {
+	qrs := NewQueryRules()
+	qr := NewQueryRule("delay all", "r1", QR_CONTINUE)
+	if err := qr.SetDelay(10 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	qrs.Add(qr)
+
+	start := time.Now()
+	if _, err := qrs.apply(context.Background(), "", "", "select 1", nil); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if d := time.Now().Sub(start); d < 10*time.Millisecond {
+		t.Errorf("apply took %v, want at least 10ms", d)
+	}
+
+	// The delay stops as soon as the context is done.
+	if err := qr.SetDelay(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err := qrs.apply(ctx, "", "", "select 1", nil)
+	checkApplyError(t, err, ErrFail, "Query delayed by rule delay all")
+	if d := time.Now().Sub(start); d > time.Second {
+		t.Errorf("apply took %v, want it to stop with the context", d)
+	}
+}
+
+func TestApplyMaxConcurrency(t *testing.T) {
+	qrs := NewQueryRules()
+	qr := NewQueryRule("max 1", "r1", QR_CONTINUE)
+	if err := qr.SetMaxConcurrency(1); err != nil {
+		t.Fatal(err)
+	}
+	qrs.Add(qr)
+	qrs.Add(NewQueryRule("fail all", "r2", QR_FAIL))
+
+	// A query rejected by a later rule gives its slot back.
+	_, err := qrs.apply(context.Background(), "", "", "select 1", nil)
+	checkApplyError(t, err, ErrFail, "Query disallowed due to rule: fail all")
+	qrs.Delete("r2")
+
+	release, err := qrs.apply(context.Background(), "", "", "select 1", nil)
+	if err != nil {
+		t.Fatalf("first query: %v", err)
+	}
+	_, err = qrs.apply(context.Background(), "", "", "select 1", nil)
+	checkApplyError(t, err, ErrFail, "Query exceeds the max concurrency of rule: max 1")
+	if code := rpcErrFromTabletError(err).Code; code != vterrors.TabletError+ErrFail {
+		t.Errorf("query over the max concurrency: code %d, want %d", code, vterrors.TabletError+ErrFail)
+	}
+	release()
+	release, err = qrs.apply(context.Background(), "", "", "select 1", nil)
+	if err != nil {
+		t.Fatalf("after release: %v", err)
+	}
+	release()
+}
+
+func TestBuildQueryRuleActions(t *testing.T) {
+	var qrs = NewQueryRules()
+	err := qrs.UnmarshalJSON([]byte(`[
+		{"Name": "throttle", "Action": "THROTTLE", "QPS": 100},
+		{"Name": "log", "Action": "LOG"},
+		{"Name": "delay", "Action": "DELAY", "Delay": "50ms"},
+		{"Name": "max_concurrency", "Action": "MAX_CONCURRENCY", "MaxConcurrency": 5}
+	]`))
+	if err != nil {
+		t.Fatalf("UnmarshalJSON: %v", err)
+	}
+	if qr := qrs.Find("throttle"); qr.act != QR_THROTTLE || qr.throttler == nil {
+		t.Errorf("throttle: %+v", qr)
+	}
+	if qr := qrs.Find("log"); qr.act != QR_LOG {
+		t.Errorf("log: %+v", qr)
+	}
+	if qr := qrs.Find("delay"); qr.act != QR_DELAY || qr.delay != 50*time.Millisecond {
+		t.Errorf("delay: %+v", qr)
 	}
-	if desc != "rule 3" {
-		t.Errorf("want rule 2, got %s", desc)
+	if qr := qrs.Find("max_concurrency"); qr.act != QR_MAX_CONCURRENCY || qr.concurrency == nil {
+		t.Errorf("max_concurrency: %+v", qr)
 	}
 }
 
@@ -789,7 +1008,17 @@ var invalidjsons = []InvalidJSONCase{
 	{`[{"BindVarConds": [{"Name": "a", "OnAbsent": true, "OnMismatch": true, "Operator": "MATCH", "Value": "["}]}]`, "processing [: error parsing regexp: missing closing ]: `[$`"},
 	{`[{"BindVarConds": [{"Name": "a", "OnAbsent": true, "OnMismatch": true, "Operator": "NOMATCH", "Value": "["}]}]`, "processing [: error parsing regexp: missing closing ]: `[$`"},
 	{`[{"Action": 1 }]`, "want string for Action"},
+	{`[{"Principal": "[" }]`, "could not set Principal condition: ["},
+	{`[{"Fingerprint": 1 }]`, "want string for Fingerprint"},
 	{`[{"Action": "foo" }]`, "invalid Action foo"},
+	{`[{"Action": "THROTTLE" }]`, "invalid QPS 0 for THROTTLE"},
+	{`[{"Action": "THROTTLE", "QPS": "10" }]`, "want integer for QPS"},
+	{`[{"Action": "FAIL", "QPS": 10 }]`, "QPS is only valid for THROTTLE"},
+	{`[{"Action": "DELAY" }]`, "invalid Delay 0s for DELAY"},
+	{`[{"Action": "DELAY", "Delay": "soon" }]`, "want duration for Delay: soon"},
+	{`[{"Action": "LOG", "Delay": "1s" }]`, "Delay is only valid for DELAY"},
+	{`[{"Action": "MAX_CONCURRENCY", "MaxConcurrency": 1.5 }]`, "want integer for MaxConcurrency"},
+	{`[{"MaxConcurrency": 2 }]`, "MaxConcurrency is only valid for MAX_CONCURRENCY"},
 }
 
 func TestInvalidJSON(t *testing.T) {
@@ -29,6 +29,7 @@ import (
 var (
 	queryLogHandler = flag.String("query-log-stream-handler", "/debug/querylog", "URL handler for streaming queries log")
 	txLogHandler    = flag.String("transaction-log-stream-handler", "/debug/txlog", "URL handler for streaming transactions log")
+	ruleLogHandler  = flag.String("query-rule-log-stream-handler", "/debug/queryrulelog", "URL handler for streaming the queries matched by LOG query rules")
 
 	checkMySLQThrottler = sync2.NewSemaphore(1, 0)
 )
@@ -37,6 +38,8 @@ func init() {
 	flag.IntVar(&qsConfig.PoolSize, "queryserver-config-pool-size", DefaultQsConfig.PoolSize, "query server connection pool size, connection pool is used by regular queries (non streaming, not in a transaction)")
 	flag.IntVar(&qsConfig.StreamPoolSize, "queryserver-config-stream-pool-size", DefaultQsConfig.StreamPoolSize, "query server stream pool size, stream pool is used by stream queries: queries that return results to client in a streaming fashion")
 	flag.IntVar(&qsConfig.TransactionCap, "queryserver-config-transaction-cap", DefaultQsConfig.TransactionCap, "query server transaction cap is the maximum number of transactions allowed to happen at any given point of a time for a single vttablet. E.g. by setting transaction cap to 100, there are at most 100 transactions will be processed by a vttablet and the 101th transaction will be blocked (and fail if it cannot get connection within specified timeout)")
+	flag.IntVar(&qsConfig.PoolCallerQuota, "queryserver-config-pool-caller-quota", DefaultQsConfig.PoolCallerQuota, "query server pool caller quota, the maximum number of connections of the connection pool and of the stream pool that a single effective caller can hold at a time. Queries of a caller that exceeds it fail. 0 means unlimited.")
+	flag.IntVar(&qsConfig.TransactionCallerQuota, "queryserver-config-transaction-caller-quota", DefaultQsConfig.TransactionCallerQuota, "query server transaction caller quota, the maximum number of transactions that a single effective caller can have open at a time. 0 means unlimited.")
 	flag.Float64Var(&qsConfig.TransactionTimeout, "queryserver-config-transaction-timeout", DefaultQsConfig.TransactionTimeout, "query server transaction timeout (in seconds), a transaction will be killed if it takes longer than this value")
 	flag.IntVar(&qsConfig.MaxResultSize, "queryserver-config-max-result-size", DefaultQsConfig.MaxResultSize, "query server max result size, maximum number of rows allowed to return from vttablet for non-streaming queries.")
 	flag.IntVar(&qsConfig.MaxDMLRows, "queryserver-config-max-dml-rows", DefaultQsConfig.MaxDMLRows, "query server max dml rows per statement, maximum number of rows allowed to return at a time for an upadte or delete with either 1) an equality where clauses on primary keys, or 2) a subselect statement. For update and delete statements in above two categories, vttablet will split the original query into multiple small queries based on this configuration value. ")
@@ -58,10 +61,14 @@ func init() {
 	flag.IntVar(&qsConfig.RowCache.Threads, "rowcache-threads", DefaultQsConfig.RowCache.Threads, "rowcache number of threads")
 	flag.BoolVar(&qsConfig.RowCache.LockPaged, "rowcache-lock-paged", DefaultQsConfig.RowCache.LockPaged, "whether rowcache locks down paged memory")
 	flag.StringVar(&qsConfig.RowCache.StatsPrefix, "rowcache-stats-prefix", DefaultQsConfig.RowCache.StatsPrefix, "rowcache stats prefix, rowcache will export various metrics and this config specifies the metric prefix")
+	flag.StringVar(&qsConfig.RowCache.Backend, "rowcache-backend", DefaultQsConfig.RowCache.Backend, "rowcache backend, the name of the cache service of the rowcache. By default, vttablet launches the memcached of rowcache-bin. An in-process backend like lru keeps the rows in vttablet, up to rowcache-memory bytes, and doesn't need rowcache-bin.")
+	flag.IntVar(&qsConfig.RowCache.WarmupKeys, "rowcache-warmup-keys", DefaultQsConfig.RowCache.WarmupKeys, "rowcache warmup keys, the number of hottest primary keys that are read back into the rowcache after it starts, or after a schema reload changes their table. 0 disables the warmup.")
 	flag.StringVar(&qsConfig.StatsPrefix, "stats-prefix", DefaultQsConfig.StatsPrefix, "prefix for variable names exported via expvar")
 	flag.StringVar(&qsConfig.DebugURLPrefix, "debug-url-prefix", DefaultQsConfig.DebugURLPrefix, "debug url prefix, vttablet will report various system debug pages and this config controls the prefix of these debug urls")
 	flag.StringVar(&qsConfig.PoolNamePrefix, "pool-name-prefix", DefaultQsConfig.PoolNamePrefix, "pool name prefix, vttablet has several pools and each of them has a name. This config specifies the prefix of these pool names")
 	flag.BoolVar(&qsConfig.EnableAutoCommit, "enable-autocommit", DefaultQsConfig.EnableAutoCommit, "if the flag is on, a DML outsides a transaction will be auto committed.")
+	flag.BoolVar(&qsConfig.EnableTwoPC, "enable-twopc", DefaultQsConfig.EnableTwoPC, "if the flag is on, the master supports two-phase commits: it keeps a redo log of its prepared transactions, and resolves the distributed transactions abandoned by vtgate.")
+	flag.Float64Var(&qsConfig.TwoPCAbandonAge, "twopc-abandon-age", DefaultQsConfig.TwoPCAbandonAge, "time in seconds after which a distributed transaction is considered abandoned by vtgate, and is resolved by its coordinator. It must be well above the transaction timeout.")
 }
 
 // RowCacheConfig encapsulates the configuration for RowCache
@@ -73,6 +80,23 @@ type RowCacheConfig struct {
 	Threads     int
 	LockPaged   bool
 	StatsPrefix string
+	Backend     string
+	WarmupKeys  int
+}
+
+// memcacheBackend is the name of the cache service of memcached.
+const memcacheBackend = "memcache"
+
+// Enabled returns true if the rowcache can be opened: it needs either
+// a memcached binary, or an in-process backend.
+func (c *RowCacheConfig) Enabled() bool {
+	return c.Binary != "" || c.InProcess()
+}
+
+// InProcess returns true if the backend of the rowcache keeps the rows
+// in-process, instead of in the memcached launched from Binary.
+func (c *RowCacheConfig) InProcess() bool {
+	return c.Backend != "" && c.Backend != memcacheBackend
 }
 
 // GetSubprocessFlags returns the flags to use to call memcached
@@ -101,28 +125,32 @@ func (c *RowCacheConfig) GetSubprocessFlags(socket string) []string {
 
 // Config contains all the configuration for query service
 type Config struct {
-	PoolSize           int
-	StreamPoolSize     int
-	TransactionCap     int
-	TransactionTimeout float64
-	MaxResultSize      int
-	MaxDMLRows         int
-	StreamBufferSize   int
-	QueryCacheSize     int
-	SchemaReloadTime   float64
-	QueryTimeout       float64
-	TxPoolTimeout      float64
-	IdleTimeout        float64
-	RowCache           RowCacheConfig
-	SpotCheckRatio     float64
-	StrictMode         bool
-	StrictTableAcl     bool
-	TerseErrors        bool
-	EnablePublishStats bool
-	EnableAutoCommit   bool
-	StatsPrefix        string
-	DebugURLPrefix     string
-	PoolNamePrefix     string
+	PoolSize               int
+	StreamPoolSize         int
+	TransactionCap         int
+	PoolCallerQuota        int
+	TransactionCallerQuota int
+	TransactionTimeout     float64
+	MaxResultSize          int
+	MaxDMLRows             int
+	StreamBufferSize       int
+	QueryCacheSize         int
+	SchemaReloadTime       float64
+	QueryTimeout           float64
+	TxPoolTimeout          float64
+	IdleTimeout            float64
+	RowCache               RowCacheConfig
+	SpotCheckRatio         float64
+	StrictMode             bool
+	StrictTableAcl         bool
+	TerseErrors            bool
+	EnablePublishStats     bool
+	EnableAutoCommit       bool
+	EnableTwoPC            bool
+	TwoPCAbandonAge        float64
+	StatsPrefix            string
+	DebugURLPrefix         string
+	PoolNamePrefix         string
 }
 
 // DefaultQSConfig is the default value for the query service config.
@@ -134,28 +162,32 @@ type Config struct {
 // great (the overhead makes the final packets on the wire about twice
 // bigger than this).
 var DefaultQsConfig = Config{
-	PoolSize:           16,
-	StreamPoolSize:     750,
-	TransactionCap:     20,
-	TransactionTimeout: 30,
-	MaxResultSize:      10000,
-	MaxDMLRows:         500,
-	QueryCacheSize:     5000,
-	SchemaReloadTime:   30 * 60,
-	QueryTimeout:       0,
-	TxPoolTimeout:      1,
-	IdleTimeout:        30 * 60,
-	StreamBufferSize:   32 * 1024,
-	RowCache:           RowCacheConfig{Memory: -1, Connections: -1, Threads: -1},
-	SpotCheckRatio:     0,
-	StrictMode:         true,
-	StrictTableAcl:     false,
-	TerseErrors:        false,
-	EnablePublishStats: true,
-	EnableAutoCommit:   false,
-	StatsPrefix:        "",
-	DebugURLPrefix:     "/debug",
-	PoolNamePrefix:     "",
+	PoolSize:               16,
+	StreamPoolSize:         750,
+	TransactionCap:         20,
+	PoolCallerQuota:        0,
+	TransactionCallerQuota: 0,
+	TransactionTimeout:     30,
+	MaxResultSize:          10000,
+	MaxDMLRows:             500,
+	QueryCacheSize:         5000,
+	SchemaReloadTime:       30 * 60,
+	QueryTimeout:           0,
+	TxPoolTimeout:          1,
+	IdleTimeout:            30 * 60,
+	StreamBufferSize:       32 * 1024,
+	RowCache:               RowCacheConfig{Memory: -1, Connections: -1, Threads: -1},
+	SpotCheckRatio:         0,
+	StrictMode:             true,
+	StrictTableAcl:         false,
+	TerseErrors:            false,
+	EnablePublishStats:     true,
+	EnableAutoCommit:       false,
+	EnableTwoPC:            false,
+	TwoPCAbandonAge:        5 * 60,
+	StatsPrefix:            "",
+	DebugURLPrefix:         "/debug",
+	PoolNamePrefix:         "",
 }
 
 var qsConfig Config
@@ -442,5 +474,6 @@ func buildFmter(logger *streamlog.StreamLogger) func(url.Values, interface{}) st
 func InitQueryService(qsc QueryServiceControl) {
 	SqlQueryLogger.ServeLogs(*queryLogHandler, buildFmter(SqlQueryLogger))
 	TxLogger.ServeLogs(*txLogHandler, buildFmter(TxLogger))
+	QueryRuleLogger.ServeLogs(*ruleLogHandler, buildFmter(QueryRuleLogger))
 	qsc.Register()
 }
@@ -26,6 +26,21 @@ type QueryService interface {
 	Commit(ctx context.Context, session *proto.Session) error
 	Rollback(ctx context.Context, session *proto.Session) error
 
+	// Two-phase commit support. The coordinator of a distributed
+	// transaction serves CreateTransaction, StartCommit and
+	// ConcludeTransaction, and its participants serve the others.
+	Prepare(ctx context.Context, dt *proto.DistributedTransaction) error
+	CommitPrepared(ctx context.Context, dt *proto.DistributedTransaction) error
+	RollbackPrepared(ctx context.Context, dt *proto.DistributedTransaction) error
+	CreateTransaction(ctx context.Context, dt *proto.DistributedTransaction) error
+	StartCommit(ctx context.Context, dt *proto.DistributedTransaction) error
+	ConcludeTransaction(ctx context.Context, dt *proto.DistributedTransaction) error
+
+	// Replication positions, for the reads that must see
+	// the writes committed before them.
+	MasterPosition(ctx context.Context, req *proto.ReplicationPositionRequest, reply *proto.ReplicationPosition) error
+	WaitMasterPos(ctx context.Context, req *proto.ReplicationPositionRequest) error
+
 	// Query execution
 	Execute(ctx context.Context, query *proto.Query, reply *mproto.QueryResult) error
 	StreamExecute(ctx context.Context, query *proto.Query, sendReply func(*mproto.QueryResult) error) error
@@ -33,6 +48,7 @@ type QueryService interface {
 
 	// Map reduce helper
 	SplitQuery(ctx context.Context, req *proto.SplitQueryRequest, reply *proto.SplitQueryResult) error
+	SplitQueryV2(ctx context.Context, req *proto.SplitQueryV2Request, reply *proto.SplitQueryResult) error
 
 	// StreamHealthRegister registers a listener for StreamHealth
 	StreamHealthRegister(chan<- *pb.StreamHealthResponse) (int, error)
@@ -71,6 +87,46 @@ func (e *ErrorQueryService) Rollback(ctx context.Context, session *proto.Session
 	return fmt.Errorf("ErrorQueryService does not implement any method")
 }
 
+// Prepare is part of QueryService interface
+func (e *ErrorQueryService) Prepare(ctx context.Context, dt *proto.DistributedTransaction) error {
+	return fmt.Errorf("ErrorQueryService does not implement any method")
+}
+
+// CommitPrepared is part of QueryService interface
+func (e *ErrorQueryService) CommitPrepared(ctx context.Context, dt *proto.DistributedTransaction) error {
+	return fmt.Errorf("ErrorQueryService does not implement any method")
+}
+
+// RollbackPrepared is part of QueryService interface
+func (e *ErrorQueryService) RollbackPrepared(ctx context.Context, dt *proto.DistributedTransaction) error {
+	return fmt.Errorf("ErrorQueryService does not implement any method")
+}
+
+// CreateTransaction is part of QueryService interface
+func (e *ErrorQueryService) CreateTransaction(ctx context.Context, dt *proto.DistributedTransaction) error {
+	return fmt.Errorf("ErrorQueryService does not implement any method")
+}
+
+// StartCommit is part of QueryService interface
+func (e *ErrorQueryService) StartCommit(ctx context.Context, dt *proto.DistributedTransaction) error {
+	return fmt.Errorf("ErrorQueryService does not implement any method")
+}
+
+// ConcludeTransaction is part of QueryService interface
+func (e *ErrorQueryService) ConcludeTransaction(ctx context.Context, dt *proto.DistributedTransaction) error {
+	return fmt.Errorf("ErrorQueryService does not implement any method")
+}
+
+// MasterPosition is part of QueryService interface
+func (e *ErrorQueryService) MasterPosition(ctx context.Context, req *proto.ReplicationPositionRequest, reply *proto.ReplicationPosition) error {
+	return fmt.Errorf("ErrorQueryService does not implement any method")
+}
+
+// WaitMasterPos is part of QueryService interface
+func (e *ErrorQueryService) WaitMasterPos(ctx context.Context, req *proto.ReplicationPositionRequest) error {
+	return fmt.Errorf("ErrorQueryService does not implement any method")
+}
+
 // Execute is part of QueryService interface
 func (e *ErrorQueryService) Execute(ctx context.Context, query *proto.Query, reply *mproto.QueryResult) error {
 	return fmt.Errorf("ErrorQueryService does not implement any method")
@@ -91,6 +147,11 @@ func (e *ErrorQueryService) SplitQuery(ctx context.Context, req *proto.SplitQuer
 	return fmt.Errorf("ErrorQueryService does not implement any method")
 }
 
+// SplitQueryV2 is part of QueryService interface
+func (e *ErrorQueryService) SplitQueryV2(ctx context.Context, req *proto.SplitQueryV2Request, reply *proto.SplitQueryResult) error {
+	return fmt.Errorf("ErrorQueryService does not implement any method")
+}
+
 // StreamHealthRegister is part of QueryService interface
 func (e *ErrorQueryService) StreamHealthRegister(chan<- *pb.StreamHealthResponse) (int, error) {
 	return 0, fmt.Errorf("ErrorQueryService does not implement any method")
@@ -0,0 +1,200 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tabletserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/youtube/vitess/go/acl"
+	"github.com/youtube/vitess/go/stats"
+	"github.com/youtube/vitess/go/vt/callerid"
+	"golang.org/x/net/context"
+)
+
+// unknownCaller is the caller the resources of the queries without
+// an effective caller id are attributed to.
+const unknownCaller = "unknown"
+
+// callerName returns the principal of the effective caller of ctx,
+// or "" if it has none.
+func callerName(ctx context.Context) string {
+	if ef := callerid.EffectiveCallerIDFromContext(ctx); ef != nil {
+		return ef.Principal
+	}
+	return ""
+}
+
+// accountedCaller returns the caller the resources used for ctx are
+// attributed to.
+func accountedCaller(ctx context.Context) string {
+	if name := callerName(ctx); name != "" {
+		return name
+	}
+	return unknownCaller
+}
+
+// ResourceUsage attributes the resources used by the queries to their
+// table and their effective caller: the time they waited for a
+// connection, the time MySQL took to execute them, and the rows they
+// read. Transactions can span several tables, so their time is only
+// attributed to their caller, and the wait for their connection at
+// Begin has no table.
+type ResourceUsage struct {
+	WaitTime        *stats.MultiTimings
+	ExecTime        *stats.MultiTimings
+	RowsRead        *stats.MultiCounters
+	TransactionTime *stats.MultiTimings
+}
+
+// NewResourceUsage creates a new ResourceUsage.
+func NewResourceUsage(statsPrefix string, enablePublishStats bool) *ResourceUsage {
+	name := func(name string) string {
+		if enablePublishStats {
+			return statsPrefix + name
+		}
+		return ""
+	}
+	tableCaller := []string{"Table", "Caller"}
+	return &ResourceUsage{
+		WaitTime:        stats.NewMultiTimings(name("ResourceWaitTime"), tableCaller),
+		ExecTime:        stats.NewMultiTimings(name("ResourceExecTime"), tableCaller),
+		RowsRead:        stats.NewMultiCounters(name("ResourceRowsRead"), tableCaller),
+		TransactionTime: stats.NewMultiTimings(name("ResourceTransactionTime"), []string{"Caller"}),
+	}
+}
+
+// RecordQuery attributes the resources used by a query of tableName,
+// from its logStats, to the table and the caller of the query.
+func (ru *ResourceUsage) RecordQuery(tableName string, logStats *SQLQueryStats, rowsRead int) {
+	if ru == nil {
+		return
+	}
+	names := []string{tableName, accountedCaller(logStats.ctx)}
+	ru.WaitTime.Add(names, logStats.WaitingForConnection)
+	ru.ExecTime.Add(names, logStats.MysqlResponseTime)
+	if rowsRead > 0 {
+		ru.RowsRead.Add(names, int64(rowsRead))
+	}
+}
+
+// RecordBegin attributes the time a transaction of caller waited for
+// its connection.
+func (ru *ResourceUsage) RecordBegin(caller string, wait time.Duration) {
+	if ru == nil {
+		return
+	}
+	ru.WaitTime.Add([]string{"", caller}, wait)
+}
+
+// RecordTransaction attributes the time of a transaction to its caller.
+func (ru *ResourceUsage) RecordTransaction(caller string, duration time.Duration) {
+	if ru == nil {
+		return
+	}
+	ru.TransactionTime.Add([]string{caller}, duration)
+}
+
+type queryResourceUsage struct {
+	Table    string
+	Caller   string
+	Queries  int64
+	WaitTime int64
+	ExecTime int64
+	RowsRead int64
+}
+
+type transactionResourceUsage struct {
+	Caller       string
+	Transactions int64
+	Time         int64
+}
+
+type byTableCaller []*queryResourceUsage
+
+func (u byTableCaller) Len() int      { return len(u) }
+func (u byTableCaller) Swap(i, j int) { u[i], u[j] = u[j], u[i] }
+func (u byTableCaller) Less(i, j int) bool {
+	if u[i].Table != u[j].Table {
+		return u[i].Table < u[j].Table
+	}
+	return u[i].Caller < u[j].Caller
+}
+
+type byCaller []*transactionResourceUsage
+
+func (u byCaller) Len() int           { return len(u) }
+func (u byCaller) Swap(i, j int)      { u[i], u[j] = u[j], u[i] }
+func (u byCaller) Less(i, j int) bool { return u[i].Caller < u[j].Caller }
+
+// queryUsage returns the resources used by the queries, by table and
+// caller. The waits at Begin are counted as queries without a table.
+func (ru *ResourceUsage) queryUsage() []*queryResourceUsage {
+	usage := make(map[string]*queryResourceUsage)
+	get := func(key string) *queryResourceUsage {
+		u, ok := usage[key]
+		if !ok {
+			// Table names don't contain dots, caller names may.
+			names := strings.SplitN(key, ".", 2)
+			u = &queryResourceUsage{Table: names[0], Caller: names[1]}
+			usage[key] = u
+		}
+		return u
+	}
+	for key, h := range ru.WaitTime.Histograms() {
+		u := get(key)
+		u.Queries = h.Count()
+		u.WaitTime = h.Total()
+	}
+	for key, h := range ru.ExecTime.Histograms() {
+		get(key).ExecTime = h.Total()
+	}
+	for key, rows := range ru.RowsRead.Counts() {
+		get(key).RowsRead = rows
+	}
+	result := make([]*queryResourceUsage, 0, len(usage))
+	for _, u := range usage {
+		result = append(result, u)
+	}
+	sort.Sort(byTableCaller(result))
+	return result
+}
+
+// transactionUsage returns the time of the transactions by caller.
+func (ru *ResourceUsage) transactionUsage() []*transactionResourceUsage {
+	histograms := ru.TransactionTime.Histograms()
+	result := make([]*transactionResourceUsage, 0, len(histograms))
+	for caller, h := range histograms {
+		result = append(result, &transactionResourceUsage{
+			Caller:       caller,
+			Transactions: h.Count(),
+			Time:         h.Total(),
+		})
+	}
+	sort.Sort(byCaller(result))
+	return result
+}
+
+// ServeHTTP serves the resource usage as JSON. The times are in
+// nanoseconds.
+func (ru *ResourceUsage) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if err := acl.CheckAccessHTTP(request, acl.DEBUGGING); err != nil {
+		acl.SendError(response, err)
+		return
+	}
+	response.Header().Set("Content-Type", "application/json; charset=utf-8")
+	usage := struct {
+		Queries      []*queryResourceUsage
+		Transactions []*transactionResourceUsage
+	}{ru.queryUsage(), ru.transactionUsage()}
+	if b, err := json.MarshalIndent(usage, "", "  "); err != nil {
+		response.Write([]byte(err.Error()))
+	} else {
+		response.Write(b)
+	}
+}
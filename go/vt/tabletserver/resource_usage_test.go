@@ -0,0 +1,90 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tabletserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/youtube/vitess/go/vt/callerid"
+	"golang.org/x/net/context"
+)
+
+func TestResourceUsageRecordQuery(t *testing.T) {
+	ru := NewResourceUsage("", false)
+	ctx := callerid.NewContext(context.Background(), callerid.NewEffectiveCallerID("batch.reports", "", ""), nil)
+	logStats := newSqlQueryStats("Execute", ctx)
+	logStats.WaitingForConnection = 2 * time.Millisecond
+	logStats.MysqlResponseTime = 5 * time.Millisecond
+	ru.RecordQuery("test_table", logStats, 3)
+	ru.RecordQuery("test_table", logStats, 1)
+	ru.RecordQuery("test_table", newSqlQueryStats("Execute", context.Background()), 0)
+	ru.RecordBegin("batch.reports", time.Millisecond)
+	ru.RecordTransaction("batch.reports", 10*time.Millisecond)
+
+	want := []*queryResourceUsage{{
+		Caller:   "batch.reports",
+		Queries:  1,
+		WaitTime: int64(time.Millisecond),
+	}, {
+		Table:    "test_table",
+		Caller:   "batch.reports",
+		Queries:  2,
+		WaitTime: int64(4 * time.Millisecond),
+		ExecTime: int64(10 * time.Millisecond),
+		RowsRead: 4,
+	}, {
+		Table:   "test_table",
+		Caller:  unknownCaller,
+		Queries: 1,
+	}}
+	if got := ru.queryUsage(); !reflect.DeepEqual(got, want) {
+		t.Errorf("queryUsage: %s, want %s", jsonString(got), jsonString(want))
+	}
+	wantTx := []*transactionResourceUsage{{
+		Caller:       "batch.reports",
+		Transactions: 1,
+		Time:         int64(10 * time.Millisecond),
+	}}
+	if got := ru.transactionUsage(); !reflect.DeepEqual(got, wantTx) {
+		t.Errorf("transactionUsage: %s, want %s", jsonString(got), jsonString(wantTx))
+	}
+
+	// A nil ResourceUsage records nothing.
+	var nilUsage *ResourceUsage
+	nilUsage.RecordQuery("test_table", logStats, 1)
+	nilUsage.RecordBegin("batch", time.Millisecond)
+	nilUsage.RecordTransaction("batch", time.Millisecond)
+}
+
+func TestResourceUsageServeHTTP(t *testing.T) {
+	ru := NewResourceUsage("", false)
+	ru.RecordQuery("test_table", newSqlQueryStats("Execute", context.Background()), 2)
+	request, _ := http.NewRequest("GET", "/debug/resource_usage", nil)
+	response := httptest.NewRecorder()
+	ru.ServeHTTP(response, request)
+	var usage struct {
+		Queries      []*queryResourceUsage
+		Transactions []*transactionResourceUsage
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &usage); err != nil {
+		t.Fatalf("could not unmarshal %s: %v", response.Body.String(), err)
+	}
+	if len(usage.Queries) != 1 || usage.Queries[0].Table != "test_table" || usage.Queries[0].RowsRead != 2 {
+		t.Errorf("queries: %s, want 2 rows read from test_table", jsonString(usage.Queries))
+	}
+	if len(usage.Transactions) != 0 {
+		t.Errorf("transactions: %s, want none", jsonString(usage.Transactions))
+	}
+}
+
+func jsonString(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,247 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tabletserver
+
+import (
+	"sort"
+	"sync"
+
+	log "github.com/golang/glog"
+	"github.com/youtube/vitess/go/cache"
+	"github.com/youtube/vitess/go/sqltypes"
+	"github.com/youtube/vitess/go/stats"
+	"github.com/youtube/vitess/go/sync2"
+	"github.com/youtube/vitess/go/vt/schema"
+	"github.com/youtube/vitess/go/vt/sqlparser"
+	"github.com/youtube/vitess/go/vt/tabletserver/planbuilder"
+	"golang.org/x/net/context"
+)
+
+const (
+	// hotKeyCandidates is how many times more keys than it warms the
+	// RowcacheWarmer tracks, to find the hottest ones among them.
+	hotKeyCandidates = 4
+
+	// warmupBatchSize is the max number of rows read by each query
+	// of a warmup.
+	warmupBatchSize = 100
+)
+
+// RowcacheWarmer reads the hottest rows back into the rowcache when
+// it starts cold: after the rowcache is opened, and after a schema
+// reload gives a table a new rowcache. The hottest rows are the ones
+// whose primary keys show up the most in the SQLQueryStats of the
+// recent queries that went through the rowcache.
+//
+// The keys are kept across Close and Open, so that a QueryEngine
+// that is reopened warms its new rowcache with the keys of the
+// previous one.
+type RowcacheWarmer struct {
+	qe      *QueryEngine
+	maxKeys int
+
+	// keys tracks the recent primary keys by table and key.
+	// It's nil if the warmup is disabled.
+	keys       *cache.LRUCache
+	warmedRows *stats.Counters
+
+	mu   sync.Mutex
+	done chan struct{}
+}
+
+// hotKey is a primary key tracked by the RowcacheWarmer.
+type hotKey struct {
+	table string
+	pk    []sqltypes.Value
+	hits  sync2.AtomicInt64
+}
+
+// Size is part of the cache.Value interface.
+func (hk *hotKey) Size() int {
+	return 1
+}
+
+type byHits []*hotKey
+
+func (keys byHits) Len() int           { return len(keys) }
+func (keys byHits) Swap(i, j int)      { keys[i], keys[j] = keys[j], keys[i] }
+func (keys byHits) Less(i, j int) bool { return keys[i].hits.Get() > keys[j].hits.Get() }
+
+// NewRowcacheWarmer creates a new RowcacheWarmer that warms up to
+// maxKeys rows. If maxKeys is 0, the warmup is disabled.
+func NewRowcacheWarmer(statsPrefix string, qe *QueryEngine, maxKeys int, enablePublishStats bool) *RowcacheWarmer {
+	rw := &RowcacheWarmer{qe: qe, maxKeys: maxKeys}
+	if maxKeys > 0 {
+		rw.keys = cache.NewLRUCache(int64(maxKeys * hotKeyCandidates))
+	}
+	name := ""
+	if enablePublishStats {
+		name = statsPrefix + "RowcacheWarmupRows"
+	}
+	rw.warmedRows = stats.NewCounters(name)
+	return rw
+}
+
+// Open starts tracking the primary keys read through the rowcache.
+func (rw *RowcacheWarmer) Open() {
+	if rw == nil || rw.keys == nil {
+		return
+	}
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if rw.done != nil {
+		return
+	}
+	done := make(chan struct{})
+	rw.done = done
+	ch := SqlQueryLogger.Subscribe("RowcacheWarmer")
+	go func() {
+		defer SqlQueryLogger.Unsubscribe(ch)
+		for {
+			select {
+			case <-done:
+				return
+			case message := <-ch:
+				rw.record(message.(*SQLQueryStats))
+			}
+		}
+	}()
+}
+
+// Close stops tracking the primary keys. It keeps the keys that were
+// tracked so far.
+func (rw *RowcacheWarmer) Close() {
+	if rw == nil {
+		return
+	}
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if rw.done != nil {
+		close(rw.done)
+		rw.done = nil
+	}
+}
+
+// record tracks the primary keys read by a query.
+func (rw *RowcacheWarmer) record(logStats *SQLQueryStats) {
+	for _, pk := range logStats.rowcachePKs {
+		key := buildKey(pk)
+		if key == "" {
+			continue
+		}
+		key = logStats.rowcacheTable + "." + key
+		if v, ok := rw.keys.Get(key); ok {
+			v.(*hotKey).hits.Add(1)
+			continue
+		}
+		rw.keys.Set(key, &hotKey{table: logStats.rowcacheTable, pk: pk, hits: 1})
+	}
+}
+
+// hottest returns the hottest primary keys of tables, or of all the
+// tables if tables is nil.
+func (rw *RowcacheWarmer) hottest(tables map[string]bool) []*hotKey {
+	var keys []*hotKey
+	for _, item := range rw.keys.Items() {
+		hk := item.Value.(*hotKey)
+		if tables == nil || tables[hk.table] {
+			keys = append(keys, hk)
+		}
+	}
+	sort.Stable(byHits(keys))
+	if len(keys) > rw.maxKeys {
+		keys = keys[:rw.maxKeys]
+	}
+	return keys
+}
+
+// Warm reads the hottest rows of tableNames back into the rowcache in
+// the background. Without tableNames, it warms all the tables.
+func (rw *RowcacheWarmer) Warm(tableNames ...string) {
+	if rw == nil || rw.keys == nil {
+		return
+	}
+	var tables map[string]bool
+	if len(tableNames) != 0 {
+		tables = make(map[string]bool, len(tableNames))
+		for _, name := range tableNames {
+			tables[name] = true
+		}
+	}
+	rw.qe.Launch(func() { rw.warm(tables) })
+}
+
+func (rw *RowcacheWarmer) warm(tables map[string]bool) {
+	if rw.qe.cachePool.IsClosed() {
+		return
+	}
+	pks := make(map[string][][]sqltypes.Value)
+	for _, hk := range rw.hottest(tables) {
+		pks[hk.table] = append(pks[hk.table], hk.pk)
+	}
+	ctx := context.Background()
+	for tableName, tablePKs := range pks {
+		tableInfo := rw.qe.schemaInfo.GetTable(tableName)
+		if tableInfo == nil || tableInfo.CacheType != schema.CACHE_RW || tableInfo.Cache == nil {
+			continue
+		}
+		for len(tablePKs) > 0 {
+			batch := tablePKs
+			if len(batch) > warmupBatchSize {
+				batch = batch[:warmupBatchSize]
+			}
+			tablePKs = tablePKs[len(batch):]
+			if err := rw.warmRows(ctx, tableInfo, batch); err != nil {
+				log.Warningf("Could not warm the rowcache of %s: %v", tableName, err)
+				rw.qe.queryServiceStats.InternalErrors.Add("RowcacheWarmup", 1)
+				break
+			}
+		}
+	}
+}
+
+// warmRows reads the rows of pks from MySQL, and adds them to the
+// rowcache of their table. Like the reads of the queries, it doesn't
+// overwrite the rows that are already cached or were invalidated.
+func (rw *RowcacheWarmer) warmRows(ctx context.Context, tableInfo *TableInfo, pks [][]sqltypes.Value) error {
+	var rows [][]sqltypes.Value
+	for _, pk := range pks {
+		// The primary key may have changed since the key was read.
+		if len(pk) == len(tableInfo.PKColumns) {
+			rows = append(rows, pk)
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	sel := &sqlparser.Select{
+		From: sqlparser.TableExprs{&sqlparser.AliasedTableExpr{
+			Expr: &sqlparser.TableName{Name: []byte(tableInfo.Name)},
+		}},
+	}
+	sql, err := planbuilder.GenerateSelectOuterQuery(sel, tableInfo.Table).GenerateQuery(map[string]interface{}{
+		"#pk": sqlparser.TupleEqualityList{
+			Columns: tableInfo.Indexes[0].Columns,
+			Rows:    rows,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	conn, err := rw.qe.connPool.Get(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Recycle()
+	result, err := conn.Exec(ctx, string(sql), len(rows), false)
+	if err != nil {
+		return err
+	}
+	for _, row := range result.Rows {
+		tableInfo.Cache.Set(ctx, buildKey(applyFilter(tableInfo.PKColumns, row)), row, 0)
+	}
+	rw.warmedRows.Add(tableInfo.Name, int64(len(result.Rows)))
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tabletserver
+
+import (
+	"reflect"
+	"testing"
+
+	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/sqltypes"
+	"golang.org/x/net/context"
+)
+
+func numericPK(v string) []sqltypes.Value {
+	return []sqltypes.Value{sqltypes.MakeNumeric([]byte(v))}
+}
+
+func TestRowcacheWarmerHottest(t *testing.T) {
+	rw := NewRowcacheWarmer("", nil, 2, false)
+	rw.record(&SQLQueryStats{rowcacheTable: "t1", rowcachePKs: [][]sqltypes.Value{numericPK("1"), numericPK("2")}})
+	rw.record(&SQLQueryStats{rowcacheTable: "t1", rowcachePKs: [][]sqltypes.Value{numericPK("2"), numericPK("3")}})
+	rw.record(&SQLQueryStats{rowcacheTable: "t2", rowcachePKs: [][]sqltypes.Value{numericPK("1"), numericPK("1"), {sqltypes.NULL}}})
+
+	var got []string
+	for _, hk := range rw.hottest(nil) {
+		got = append(got, hk.table+"."+buildKey(hk.pk))
+	}
+	if want := []string{"t2.1", "t1.2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("hottest: %v, want %v", got, want)
+	}
+
+	got = nil
+	for _, hk := range rw.hottest(map[string]bool{"t1": true}) {
+		got = append(got, hk.table+"."+buildKey(hk.pk))
+	}
+	if len(got) != 2 || got[0] != "t1.2" {
+		t.Errorf("hottest of t1: %v, want t1.2 first", got)
+	}
+}
+
+func TestRowcacheWarmerDisabled(t *testing.T) {
+	rw := NewRowcacheWarmer("", nil, 0, false)
+	// These are no-ops without keys.
+	rw.Open()
+	rw.Warm()
+	rw.Close()
+
+	var nilWarmer *RowcacheWarmer
+	nilWarmer.Warm("test_table")
+}
+
+func TestRowcacheWarmerWarm(t *testing.T) {
+	db := setUpQueryExecutorTest()
+	query := "select * from test_table where pk in (1, 2) limit 1000"
+	row := []sqltypes.Value{
+		sqltypes.MakeNumeric([]byte("1")),
+		sqltypes.MakeNumeric([]byte("20")),
+		sqltypes.MakeNumeric([]byte("30")),
+	}
+	result := &mproto.QueryResult{
+		Fields:       getTestTableFields(),
+		RowsAffected: 1,
+		Rows:         [][]sqltypes.Value{row},
+	}
+	db.AddQuery(query, result)
+	db.AddQuery("select pk, name, addr from test_table where pk in (1, 2)", result)
+	db.AddQuery("select pk, name, addr from test_table where pk in (1)", result)
+	db.AddQuery("select * from test_table where 1 != 1", &mproto.QueryResult{
+		Fields: getTestTableFields(),
+	})
+	qre, sqlQuery := newTestQueryExecutor(
+		query, context.Background(), enableRowCache|enableStrict|enableSchemaOverrides)
+	defer sqlQuery.disallowQueries()
+	if _, err := qre.Execute(); err != nil {
+		t.Fatalf("qre.Execute() = %v, want nil", err)
+	}
+	// Only the rows that exist are recorded.
+	if want := [][]sqltypes.Value{numericPK("1")}; qre.logStats.rowcacheTable != "test_table" || !reflect.DeepEqual(qre.logStats.rowcachePKs, want) {
+		t.Fatalf("recorded %s %v, want test_table %v", qre.logStats.rowcacheTable, qre.logStats.rowcachePKs, want)
+	}
+
+	qe := sqlQuery.qe
+	rw := NewRowcacheWarmer("", qe, 10, false)
+	rw.record(qre.logStats)
+
+	// Simulate a restart of the rowcache.
+	conn := qe.cachePool.Get(context.Background())
+	conn.FlushAll()
+	qe.cachePool.Put(conn)
+	tableInfo := qe.schemaInfo.GetTable("test_table")
+	if rcresult := tableInfo.Cache.Get(context.Background(), []string{"1"})["1"]; rcresult.Row != nil {
+		t.Fatalf("rowcache must be empty after FlushAll, got %v", rcresult.Row)
+	}
+
+	rw.warm(nil)
+	rcresult := tableInfo.Cache.Get(context.Background(), []string{"1"})["1"]
+	if !reflect.DeepEqual(rcresult.Row, row) {
+		t.Errorf("warmed row: %v, want %v", rcresult.Row, row)
+	}
+	if got := rw.warmedRows.Counts()["test_table"]; got != 1 {
+		t.Errorf("warmed rows: %d, want 1", got)
+	}
+}
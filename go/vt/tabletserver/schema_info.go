@@ -21,6 +21,7 @@ import (
 	"github.com/youtube/vitess/go/stats"
 	"github.com/youtube/vitess/go/timer"
 	"github.com/youtube/vitess/go/vt/schema"
+	"github.com/youtube/vitess/go/vt/sqlparser"
 	"github.com/youtube/vitess/go/vt/tableacl"
 	tacl "github.com/youtube/vitess/go/vt/tableacl/acl"
 	"github.com/youtube/vitess/go/vt/tabletserver/planbuilder"
@@ -105,6 +106,7 @@ type SchemaInfo struct {
 	queries           *cache.LRUCache
 	connPool          *ConnPool
 	cachePool         *CachePool
+	warmer            *RowcacheWarmer
 	lastChange        time.Time
 	ticks             *timer.Timer
 	reloadTime        time.Duration
@@ -340,6 +342,10 @@ func (si *SchemaInfo) CreateOrUpdateTable(ctx context.Context, tableName string)
 		// Otherwise, the query plans may not be in sync with the schema.
 		si.queries.Clear()
 		log.Infof("Updating table %s", tableName)
+		// The new table has a new rowcache.
+		if tableInfo.Cache != nil {
+			si.warmer.Warm(tableName)
+		}
 	}
 	si.tables[tableName] = tableInfo
 
@@ -572,13 +578,14 @@ func (si *SchemaInfo) getQueryStats(f queryStatsFunc) map[string]int64 {
 }
 
 type perQueryStats struct {
-	Query      string
-	Table      string
-	Plan       planbuilder.PlanType
-	QueryCount int64
-	Time       time.Duration
-	RowCount   int64
-	ErrorCount int64
+	Query       string
+	Fingerprint string
+	Table       string
+	Plan        planbuilder.PlanType
+	QueryCount  int64
+	Time        time.Duration
+	RowCount    int64
+	ErrorCount  int64
 }
 
 func (si *SchemaInfo) ServeHTTP(response http.ResponseWriter, request *http.Request) {
@@ -624,6 +631,8 @@ func (si *SchemaInfo) handleHTTPQueryStats(response http.ResponseWriter, request
 		if plan := si.getQuery(v); plan != nil {
 			var pqstats perQueryStats
 			pqstats.Query = unicoded(v)
+			// The fingerprint can be used in query rules.
+			pqstats.Fingerprint, _ = sqlparser.Fingerprint(v)
 			pqstats.Table = plan.TableName
 			pqstats.Plan = plan.PlanId
 			pqstats.QueryCount, pqstats.Time, pqstats.RowCount, pqstats.ErrorCount = plan.Stats()
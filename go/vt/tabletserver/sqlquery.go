@@ -16,14 +16,17 @@ import (
 	"github.com/youtube/vitess/go/mysql"
 	mproto "github.com/youtube/vitess/go/mysql/proto"
 	"github.com/youtube/vitess/go/stats"
+	"github.com/youtube/vitess/go/sqltypes"
 	"github.com/youtube/vitess/go/tb"
 	"github.com/youtube/vitess/go/vt/dbconfigs"
 	"github.com/youtube/vitess/go/vt/dbconnpool"
 	"github.com/youtube/vitess/go/vt/mysqlctl"
+	myproto "github.com/youtube/vitess/go/vt/mysqlctl/proto"
 	"github.com/youtube/vitess/go/vt/tabletserver/proto"
 	"golang.org/x/net/context"
 
 	pb "github.com/youtube/vitess/go/vt/proto/query"
+	pbt "github.com/youtube/vitess/go/vt/proto/topodata"
 )
 
 // Allowed state transitions:
@@ -91,6 +94,7 @@ type SqlQuery struct {
 	sessionID int64
 	dbconfig  *dbconfigs.DBConfig
 	target    *pb.Target
+	mysqld    mysqlctl.MysqlDaemon
 
 	// streamHealthMutex protects all the following fields
 	streamHealthMutex        sync.Mutex
@@ -181,8 +185,12 @@ func (sq *SqlQuery) allowQueries(target *pb.Target, dbconfigs *dbconfigs.DBConfi
 	}()
 
 	sq.qe.Open(dbconfigs, schemaOverrides, mysqld)
+	if target != nil && target.TabletType == pbt.TabletType_MASTER {
+		sq.qe.OpenTwoPC(context.Background())
+	}
 	sq.dbconfig = &dbconfigs.App
 	sq.target = target
+	sq.mysqld = mysqld
 	sq.sessionID = Rand()
 	log.Infof("Session id: %d", sq.sessionID)
 	return nil
@@ -344,6 +352,142 @@ func (sq *SqlQuery) Rollback(ctx context.Context, session *proto.Session) (err e
 	return nil
 }
 
+// Prepare prepares the specified transaction for a two-phase commit.
+func (sq *SqlQuery) Prepare(ctx context.Context, dt *proto.DistributedTransaction) (err error) {
+	return sq.execTwoPC(ctx, "Prepare", "PREPARE", dt, func(ctx context.Context, logStats *SQLQueryStats) {
+		logStats.TransactionID = dt.TransactionId
+		sq.qe.txPool.Prepare(ctx, dt.TransactionId, dt.Dtid)
+	})
+}
+
+// CommitPrepared commits the prepared transaction.
+func (sq *SqlQuery) CommitPrepared(ctx context.Context, dt *proto.DistributedTransaction) (err error) {
+	return sq.execTwoPC(ctx, "CommitPrepared", "COMMIT_PREPARED", dt, func(ctx context.Context, logStats *SQLQueryStats) {
+		sq.qe.CommitPrepared(ctx, logStats, dt.Dtid)
+	})
+}
+
+// RollbackPrepared rolls back the prepared transaction.
+func (sq *SqlQuery) RollbackPrepared(ctx context.Context, dt *proto.DistributedTransaction) (err error) {
+	return sq.execTwoPC(ctx, "RollbackPrepared", "ROLLBACK_PREPARED", dt, func(ctx context.Context, logStats *SQLQueryStats) {
+		sq.qe.txPool.RollbackPrepared(ctx, dt.Dtid)
+	})
+}
+
+// CreateTransaction records a distributed transaction
+// in the coordinator, along with its participants.
+func (sq *SqlQuery) CreateTransaction(ctx context.Context, dt *proto.DistributedTransaction) (err error) {
+	return sq.execTwoPC(ctx, "CreateTransaction", "CREATE_TRANSACTION", dt, func(ctx context.Context, logStats *SQLQueryStats) {
+		if err := sq.qe.txPool.CreateTransaction(ctx, dt.Dtid, dt.Participants); err != nil {
+			panic(err)
+		}
+	})
+}
+
+// StartCommit records the commit decision of a distributed transaction
+// in the coordinator, and commits the transaction of the coordinator.
+func (sq *SqlQuery) StartCommit(ctx context.Context, dt *proto.DistributedTransaction) (err error) {
+	return sq.execTwoPC(ctx, "StartCommit", "START_COMMIT", dt, func(ctx context.Context, logStats *SQLQueryStats) {
+		logStats.TransactionID = dt.TransactionId
+		sq.qe.txPool.StartCommit(ctx, dt.TransactionId, dt.Dtid)
+		sq.qe.Commit(ctx, logStats, dt.TransactionId)
+	})
+}
+
+// ConcludeTransaction forgets a distributed transaction in the
+// coordinator, once all its participants are resolved.
+func (sq *SqlQuery) ConcludeTransaction(ctx context.Context, dt *proto.DistributedTransaction) (err error) {
+	return sq.execTwoPC(ctx, "ConcludeTransaction", "CONCLUDE_TRANSACTION", dt, func(ctx context.Context, logStats *SQLQueryStats) {
+		if err := sq.qe.txPool.ConcludeTransaction(ctx, dt.Dtid); err != nil {
+			panic(err)
+		}
+	})
+}
+
+// execTwoPC executes a step of a two-phase commit. Like Commit, it's
+// allowed while the transactions are shutting down.
+func (sq *SqlQuery) execTwoPC(ctx context.Context, method, statsName string, dt *proto.DistributedTransaction, f func(context.Context, *SQLQueryStats)) (err error) {
+	logStats := newSqlQueryStats(method, ctx)
+	logStats.OriginalSql = strings.ToLower(statsName)
+	defer handleError(&err, logStats, sq.qe.queryServiceStats)
+
+	if !sq.qe.enableTwoPC {
+		return NewTabletError(ErrFail, "%s: two-phase commits are not enabled", method)
+	}
+	if err = sq.startRequest(nil, dt.SessionId, false, true); err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, sq.qe.queryTimeout.Get())
+	defer func() {
+		sq.qe.queryServiceStats.QueryStats.Record(statsName, time.Now())
+		cancel()
+		sq.endRequest()
+	}()
+
+	f(ctx, logStats)
+	return nil
+}
+
+// MasterPosition returns the current replication position of the
+// tablet. vtgate reads it from the master after a commit, so that
+// the next reads can wait for it on a replica.
+func (sq *SqlQuery) MasterPosition(ctx context.Context, req *proto.ReplicationPositionRequest, reply *proto.ReplicationPosition) (err error) {
+	return sq.execReplication(ctx, "MasterPosition", "MASTER_POSITION", req, func(mysqld mysqlctl.MysqlDaemon) {
+		pos, err := mysqld.MasterPosition()
+		if err != nil {
+			panic(NewTabletError(ErrFail, "MasterPosition: %v", err))
+		}
+		reply.Position = myproto.EncodeReplicationPosition(pos)
+	})
+}
+
+// WaitMasterPos waits until the tablet has replicated req.Position,
+// or fails after req.WaitTimeout. The wait is done by MySQL, which
+// counts the timeout in whole seconds: it's rounded up to one second.
+func (sq *SqlQuery) WaitMasterPos(ctx context.Context, req *proto.ReplicationPositionRequest) (err error) {
+	return sq.execReplication(ctx, "WaitMasterPos", "WAIT_MASTER_POS", req, func(mysqld mysqlctl.MysqlDaemon) {
+		pos, err := myproto.DecodeReplicationPosition(req.Position)
+		if err != nil {
+			panic(NewTabletError(ErrFail, "WaitMasterPos: invalid position %v: %v", req.Position, err))
+		}
+		// Most of the time the position is already replicated,
+		// and there is no need to ask MySQL to wait for it.
+		if status, err := mysqld.SlaveStatus(); err == nil && status.Position.AtLeast(pos) {
+			return
+		}
+		waitTimeout := req.WaitTimeout
+		if waitTimeout < time.Second {
+			// A timeout of zero would wait forever.
+			waitTimeout = time.Second
+		}
+		if err := mysqld.WaitMasterPos(pos, waitTimeout); err != nil {
+			panic(NewTabletError(ErrFail, "WaitMasterPos: %v", err))
+		}
+	})
+}
+
+// execReplication executes a replication position request with the
+// MysqlDaemon of the tablet.
+func (sq *SqlQuery) execReplication(ctx context.Context, method, statsName string, req *proto.ReplicationPositionRequest, f func(mysqlctl.MysqlDaemon)) (err error) {
+	logStats := newSqlQueryStats(method, ctx)
+	logStats.OriginalSql = strings.ToLower(statsName)
+	defer handleError(&err, logStats, sq.qe.queryServiceStats)
+
+	if err = sq.startRequest(nil, req.SessionId, false, false); err != nil {
+		return err
+	}
+	defer func() {
+		sq.qe.queryServiceStats.QueryStats.Record(statsName, time.Now())
+		sq.endRequest()
+	}()
+
+	if sq.mysqld == nil {
+		return NewTabletError(ErrFail, "%s: the replication position is not available", method)
+	}
+	f(sq.mysqld)
+	return nil
+}
+
 // handleExecError handles panics during query execution and sets
 // the supplied error return value.
 func (sq *SqlQuery) handleExecError(query *proto.Query, err *error, logStats *SQLQueryStats) {
@@ -559,6 +703,73 @@ func (sq *SqlQuery) SplitQuery(ctx context.Context, req *proto.SplitQueryRequest
 	return nil
 }
 
+// SplitQueryV2 splits a BoundQuery into queries that each scan a
+// range of the primary key of its table. The boundaries of the ranges
+// are found one after the other, by keyset queries that each skip the
+// rows of one range in the primary key index, see PKSplitter.
+func (sq *SqlQuery) SplitQueryV2(ctx context.Context, req *proto.SplitQueryV2Request, reply *proto.SplitQueryResult) (err error) {
+	logStats := newSqlQueryStats("SplitQueryV2", ctx)
+	defer handleError(&err, logStats, sq.qe.queryServiceStats)
+	if err = sq.startRequest(nil, req.SessionID, false, false); err != nil {
+		return err
+	}
+	ctx, cancel := withTimeout(ctx, sq.qe.queryTimeout.Get())
+	defer func() {
+		cancel()
+		sq.endRequest()
+	}()
+
+	splitter := NewPKSplitter(&(req.Query), req.SplitColumns, sq.qe.schemaInfo)
+	err = splitter.validateQuery()
+	if err != nil {
+		return NewTabletError(ErrFail, "splitQueryV2: query validation error: %s, request: %#v", err, req)
+	}
+
+	qre := &QueryExecutor{
+		ctx:      ctx,
+		logStats: logStats,
+		qe:       sq.qe,
+	}
+	conn, err := qre.getConn(sq.qe.connPool)
+	if err != nil {
+		return err
+	}
+	defer conn.Recycle()
+	numRows := req.NumRowsPerQueryPart
+	if numRows == 0 {
+		tableRows, err := qre.execSQL(conn, splitter.tableRowsQuery(), true)
+		if err != nil {
+			return err
+		}
+		numRows, err = splitter.rowsPerPart(tableRows, req.SplitCount)
+		if err != nil {
+			return NewTabletError(ErrFail, "splitQueryV2: cannot estimate the rows of the table: %s, request: %#v", err, req)
+		}
+	}
+	var boundaries [][]sqltypes.Value
+	for numRows > 0 {
+		var prev []sqltypes.Value
+		if len(boundaries) != 0 {
+			prev = boundaries[len(boundaries)-1]
+		}
+		parsedQuery, bindVars := splitter.boundaryQuery(prev, numRows)
+		sql, err := qre.generateFinalSQL(parsedQuery, bindVars, nil)
+		if err != nil {
+			return err
+		}
+		qr, err := qre.execSQL(conn, sql, false)
+		if err != nil {
+			return err
+		}
+		if len(qr.Rows) == 0 {
+			break
+		}
+		boundaries = append(boundaries, qr.Rows[0])
+	}
+	reply.Queries = splitter.split(boundaries, numRows)
+	return nil
+}
+
 // StreamHealthRegister is part of queryservice.QueryService interface
 func (sq *SqlQuery) StreamHealthRegister(c chan<- *pb.StreamHealthResponse) (int, error) {
 	sq.streamHealthMutex.Lock()
@@ -589,8 +800,14 @@ func (sq *SqlQuery) HandlePanic(err *error) {
 	}
 }
 
-// BroadcastHealth will broadcast the current health to all listeners
+// BroadcastHealth will broadcast the current health to all listeners.
+// The qps of stats is filled in from the query service.
 func (sq *SqlQuery) BroadcastHealth(terTimestamp int64, stats *pb.RealtimeStats) {
+	if stats != nil {
+		statsCopy := *stats
+		statsCopy.Qps = sq.currentQPS()
+		stats = &statsCopy
+	}
 	shr := &pb.StreamHealthResponse{
 		Target: sq.target,
 		TabletExternallyReparentedTimestamp: terTimestamp,
@@ -609,6 +826,15 @@ func (sq *SqlQuery) BroadcastHealth(terTimestamp int64, stats *pb.RealtimeStats)
 	sq.lastStreamHealthResponse = shr
 }
 
+// currentQPS returns the most recent qps of all the queries.
+func (sq *SqlQuery) currentQPS() float64 {
+	qps, ok := sq.qe.queryServiceStats.QPSRates.Get()["All"]
+	if !ok || len(qps) == 0 {
+		return 0
+	}
+	return qps[len(qps)-1]
+}
+
 // startRequest validates the current state and sessionID and registers
 // the request (a waitgroup) as started. Every startRequest requires one
 // and only one corresponding endRequest. When the service shuts down,
@@ -38,6 +38,8 @@ type SQLQueryStats struct {
 	OriginalSql          string
 	BindVariables        map[string]interface{}
 	rewrittenSqls        []string
+	rowcacheTable        string
+	rowcachePKs          [][]sqltypes.Value
 	RowsAffected         int
 	NumberOfQueries      int
 	StartTime            time.Time
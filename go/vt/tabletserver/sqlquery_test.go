@@ -13,6 +13,8 @@ import (
 
 	mproto "github.com/youtube/vitess/go/mysql/proto"
 	"github.com/youtube/vitess/go/sqltypes"
+	"github.com/youtube/vitess/go/vt/mysqlctl"
+	myproto "github.com/youtube/vitess/go/vt/mysqlctl/proto"
 	"github.com/youtube/vitess/go/vt/tabletserver/proto"
 	"github.com/youtube/vitess/go/vt/vttest/fakesqldb"
 	"golang.org/x/net/context"
@@ -375,6 +377,54 @@ func TestSqlQueryRollback(t *testing.T) {
 	}
 }
 
+func TestSqlQueryReplicationPosition(t *testing.T) {
+	setUpSqlQueryTest()
+	testUtils := newTestUtils()
+	config := testUtils.newQueryServiceConfig()
+	sqlQuery := NewSqlQuery(config)
+	dbconfigs := testUtils.newDBConfigs()
+	mysqld := mysqlctl.NewFakeMysqlDaemon()
+	current, err := myproto.DecodeReplicationPosition("MariaDB/0-1-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mysqld.CurrentMasterPosition = current
+	err = sqlQuery.allowQueries(nil, &dbconfigs, []SchemaOverride{}, mysqld)
+	if err != nil {
+		t.Fatalf("allowQueries failed: %v", err)
+	}
+	defer sqlQuery.disallowQueries()
+	ctx := context.Background()
+
+	reply := proto.ReplicationPosition{}
+	req := proto.ReplicationPositionRequest{SessionId: sqlQuery.sessionID}
+	if err := sqlQuery.MasterPosition(ctx, &req, &reply); err != nil {
+		t.Fatalf("MasterPosition failed: %v", err)
+	}
+	if reply.Position != "MariaDB/0-1-5" {
+		t.Errorf("MasterPosition: %v, want MariaDB/0-1-5", reply.Position)
+	}
+
+	// Replicated already: MySQL doesn't wait.
+	req = proto.ReplicationPositionRequest{
+		SessionId:   sqlQuery.sessionID,
+		Position:    "MariaDB/0-1-4",
+		WaitTimeout: time.Second,
+	}
+	if err := sqlQuery.WaitMasterPos(ctx, &req); err != nil {
+		t.Errorf("WaitMasterPos(%s) failed: %v", req.Position, err)
+	}
+	// Not replicated yet: MySQL waits, and fails.
+	req.Position = "MariaDB/0-1-6"
+	if err := sqlQuery.WaitMasterPos(ctx, &req); err == nil {
+		t.Errorf("WaitMasterPos(%s) should fail", req.Position)
+	}
+	req.Position = "bad position"
+	if err := sqlQuery.WaitMasterPos(ctx, &req); err == nil {
+		t.Errorf("WaitMasterPos(%s) should fail", req.Position)
+	}
+}
+
 func TestSqlQueryStreamExecute(t *testing.T) {
 	db := setUpSqlQueryTest()
 	testUtils := newTestUtils()
@@ -45,6 +45,10 @@ const (
 // ErrConnPoolClosed is returned / panicked when the connection pool is closed.
 var ErrConnPoolClosed = NewTabletError(ErrFatal, "connection pool is closed")
 
+// ErrCallerQuota is returned when the effective caller already holds
+// as many connections of a pool as its quota allows.
+var ErrCallerQuota = NewTabletError(ErrFail, "caller exceeds its connection pool quota")
+
 var logTxPoolFull = logutil.NewThrottledLogger("TxPoolFull", 1*time.Minute)
 
 // TabletError is the error type we use in this library
@@ -82,6 +82,21 @@ type TabletConn interface {
 	Commit(ctx context.Context, transactionId int64) error
 	Rollback(ctx context.Context, transactionId int64) error
 
+	// Two-phase commit support. The coordinator of a distributed
+	// transaction serves CreateTransaction, StartCommit and
+	// ConcludeTransaction, and its participants serve the others.
+	Prepare(ctx context.Context, transactionId int64, dtid string) error
+	CommitPrepared(ctx context.Context, dtid string) error
+	RollbackPrepared(ctx context.Context, dtid string) error
+	CreateTransaction(ctx context.Context, dtid string, participants []tproto.DTParticipant) error
+	StartCommit(ctx context.Context, transactionId int64, dtid string) error
+	ConcludeTransaction(ctx context.Context, dtid string) error
+
+	// Replication positions, for the reads that must see the writes
+	// committed before them. Positions are encoded replication positions.
+	MasterPosition(ctx context.Context) (position string, err error)
+	WaitMasterPos(ctx context.Context, position string, waitTimeout time.Duration) error
+
 	// These should not be used for anything except tests for now; they will eventually
 	// replace the existing methods.
 	Execute2(ctx context.Context, query string, bindVars map[string]interface{}, transactionId int64) (*mproto.QueryResult, error)
@@ -101,6 +116,11 @@ type TabletConn interface {
 	// appending primary key range clauses to the original query
 	SplitQuery(ctx context.Context, query tproto.BoundQuery, splitColumn string, splitCount int) ([]tproto.QuerySplit, error)
 
+	// SplitQueryV2 splits a query into queries that scan ranges of
+	// the primary key, whose boundaries are sampled from the table.
+	// It supports primary keys of any type, and composite ones.
+	SplitQueryV2(ctx context.Context, query tproto.BoundQuery, splitColumns []string, splitCount int, numRowsPerQueryPart int64) ([]tproto.QuerySplit, error)
+
 	// StreamHealth streams StreamHealthResponse to the client
 	StreamHealth(ctx context.Context) (<-chan *pb.StreamHealthResponse, ErrFunc, error)
 }
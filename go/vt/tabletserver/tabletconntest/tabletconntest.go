@@ -11,6 +11,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	mproto "github.com/youtube/vitess/go/mysql/proto"
 	"github.com/youtube/vitess/go/sqltypes"
@@ -290,6 +291,192 @@ func testRollback2Panics(t *testing.T, conn tabletconn.TabletConn) {
 	}
 }
 
+// Prepare is part of the queryservice.QueryService interface
+func (f *FakeQueryService) Prepare(ctx context.Context, dt *proto.DistributedTransaction) error {
+	return f.checkTwoPC("Prepare", dt, twoPCTransactionID, nil)
+}
+
+// CommitPrepared is part of the queryservice.QueryService interface
+func (f *FakeQueryService) CommitPrepared(ctx context.Context, dt *proto.DistributedTransaction) error {
+	return f.checkTwoPC("CommitPrepared", dt, 0, nil)
+}
+
+// RollbackPrepared is part of the queryservice.QueryService interface
+func (f *FakeQueryService) RollbackPrepared(ctx context.Context, dt *proto.DistributedTransaction) error {
+	return f.checkTwoPC("RollbackPrepared", dt, 0, nil)
+}
+
+// CreateTransaction is part of the queryservice.QueryService interface
+func (f *FakeQueryService) CreateTransaction(ctx context.Context, dt *proto.DistributedTransaction) error {
+	return f.checkTwoPC("CreateTransaction", dt, 0, testParticipants)
+}
+
+// StartCommit is part of the queryservice.QueryService interface
+func (f *FakeQueryService) StartCommit(ctx context.Context, dt *proto.DistributedTransaction) error {
+	return f.checkTwoPC("StartCommit", dt, twoPCTransactionID, nil)
+}
+
+// ConcludeTransaction is part of the queryservice.QueryService interface
+func (f *FakeQueryService) ConcludeTransaction(ctx context.Context, dt *proto.DistributedTransaction) error {
+	return f.checkTwoPC("ConcludeTransaction", dt, 0, nil)
+}
+
+func (f *FakeQueryService) checkTwoPC(method string, dt *proto.DistributedTransaction, transactionID int64, participants []proto.DTParticipant) error {
+	if f.hasError {
+		return testTabletError
+	}
+	if f.panics {
+		panic(fmt.Errorf("test-triggered panic"))
+	}
+	if dt.SessionId != testSessionID {
+		f.t.Errorf("%s: invalid SessionId: got %v expected %v", method, dt.SessionId, testSessionID)
+	}
+	if dt.TransactionId != transactionID {
+		f.t.Errorf("%s: invalid TransactionId: got %v expected %v", method, dt.TransactionId, transactionID)
+	}
+	if dt.Dtid != testDtid {
+		f.t.Errorf("%s: invalid Dtid: got %v expected %v", method, dt.Dtid, testDtid)
+	}
+	if !sameParticipants(dt.Participants, participants) {
+		f.t.Errorf("%s: invalid Participants: got %v expected %v", method, dt.Participants, participants)
+	}
+	return nil
+}
+
+// sameParticipants compares the participants element by element:
+// the RPC layers may turn an empty list into nil, or the reverse.
+func sameParticipants(got, want []proto.DTParticipant) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+const twoPCTransactionID int64 = 999055
+
+const testDtid = "test_keyspace:0:999055"
+
+var testParticipants = []proto.DTParticipant{{
+	Keyspace: TestKeyspace,
+	Shard:    "1",
+}, {
+	Keyspace: TestKeyspace,
+	Shard:    "2",
+}}
+
+// twoPCCalls makes all the two-phase commit calls,
+// and returns the error of each one.
+func twoPCCalls(conn tabletconn.TabletConn) map[string]error {
+	ctx := context.Background()
+	return map[string]error{
+		"Prepare":             conn.Prepare(ctx, twoPCTransactionID, testDtid),
+		"CommitPrepared":      conn.CommitPrepared(ctx, testDtid),
+		"RollbackPrepared":    conn.RollbackPrepared(ctx, testDtid),
+		"CreateTransaction":   conn.CreateTransaction(ctx, testDtid, testParticipants),
+		"StartCommit":         conn.StartCommit(ctx, twoPCTransactionID, testDtid),
+		"ConcludeTransaction": conn.ConcludeTransaction(ctx, testDtid),
+	}
+}
+
+func testTwoPC(t *testing.T, conn tabletconn.TabletConn) {
+	t.Log("testTwoPC")
+	for method, err := range twoPCCalls(conn) {
+		if err != nil {
+			t.Errorf("%s failed: %v", method, err)
+		}
+	}
+}
+
+func testTwoPCError(t *testing.T, conn tabletconn.TabletConn) {
+	t.Log("testTwoPCError")
+	for method, err := range twoPCCalls(conn) {
+		verifyError(t, err, method)
+	}
+}
+
+func testTwoPCPanics(t *testing.T, conn tabletconn.TabletConn) {
+	t.Log("testTwoPCPanics")
+	for method, err := range twoPCCalls(conn) {
+		if err == nil || !strings.Contains(err.Error(), "caught test panic") {
+			t.Errorf("%s: unexpected panic error: %v", method, err)
+		}
+	}
+}
+
+// MasterPosition is part of the queryservice.QueryService interface
+func (f *FakeQueryService) MasterPosition(ctx context.Context, req *proto.ReplicationPositionRequest, reply *proto.ReplicationPosition) error {
+	if err := f.checkReplicationPosition("MasterPosition", req, "", 0); err != nil {
+		return err
+	}
+	reply.Position = testPosition
+	return nil
+}
+
+// WaitMasterPos is part of the queryservice.QueryService interface
+func (f *FakeQueryService) WaitMasterPos(ctx context.Context, req *proto.ReplicationPositionRequest) error {
+	return f.checkReplicationPosition("WaitMasterPos", req, testPosition, testWaitTimeout)
+}
+
+func (f *FakeQueryService) checkReplicationPosition(method string, req *proto.ReplicationPositionRequest, position string, waitTimeout time.Duration) error {
+	if f.hasError {
+		return testTabletError
+	}
+	if f.panics {
+		panic(fmt.Errorf("test-triggered panic"))
+	}
+	if req.SessionId != testSessionID {
+		f.t.Errorf("%s: invalid SessionId: got %v expected %v", method, req.SessionId, testSessionID)
+	}
+	if req.Position != position {
+		f.t.Errorf("%s: invalid Position: got %v expected %v", method, req.Position, position)
+	}
+	if req.WaitTimeout != waitTimeout {
+		f.t.Errorf("%s: invalid WaitTimeout: got %v expected %v", method, req.WaitTimeout, waitTimeout)
+	}
+	return nil
+}
+
+const testPosition = "MySQL56/00010203-0405-0607-0809-0a0b0c0d0e0f:1-42"
+
+const testWaitTimeout = 3 * time.Second
+
+func testMasterPosition(t *testing.T, conn tabletconn.TabletConn) {
+	t.Log("testMasterPosition")
+	position, err := conn.MasterPosition(context.Background())
+	if err != nil {
+		t.Fatalf("MasterPosition failed: %v", err)
+	}
+	if position != testPosition {
+		t.Errorf("Unexpected position from MasterPosition: got %v wanted %v", position, testPosition)
+	}
+	if err := conn.WaitMasterPos(context.Background(), testPosition, testWaitTimeout); err != nil {
+		t.Errorf("WaitMasterPos failed: %v", err)
+	}
+}
+
+func testMasterPositionError(t *testing.T, conn tabletconn.TabletConn) {
+	t.Log("testMasterPositionError")
+	_, err := conn.MasterPosition(context.Background())
+	verifyError(t, err, "MasterPosition")
+	err = conn.WaitMasterPos(context.Background(), testPosition, testWaitTimeout)
+	verifyError(t, err, "WaitMasterPos")
+}
+
+func testMasterPositionPanics(t *testing.T, conn tabletconn.TabletConn) {
+	t.Log("testMasterPositionPanics")
+	if _, err := conn.MasterPosition(context.Background()); err == nil || !strings.Contains(err.Error(), "caught test panic") {
+		t.Errorf("MasterPosition: unexpected panic error: %v", err)
+	}
+	if err := conn.WaitMasterPos(context.Background(), testPosition, testWaitTimeout); err == nil || !strings.Contains(err.Error(), "caught test panic") {
+		t.Errorf("WaitMasterPos: unexpected panic error: %v", err)
+	}
+}
+
 // Execute is part of the queryservice.QueryService interface
 func (f *FakeQueryService) Execute(ctx context.Context, query *proto.Query, reply *mproto.QueryResult) error {
 	if f.hasError {
@@ -909,6 +1096,61 @@ func testSplitQueryPanics(t *testing.T, conn tabletconn.TabletConn) {
 	}
 }
 
+// SplitQueryV2 is part of the queryservice.QueryService interface
+func (f *FakeQueryService) SplitQueryV2(ctx context.Context, req *proto.SplitQueryV2Request, reply *proto.SplitQueryResult) error {
+	if f.hasError {
+		return testTabletError
+	}
+	if f.panics {
+		panic(fmt.Errorf("test-triggered panic"))
+	}
+	if !reflect.DeepEqual(req.Query, splitQueryBoundQuery) {
+		f.t.Errorf("invalid SplitQueryV2.SplitQueryV2Request.Query: got %v expected %v", req.Query, splitQueryBoundQuery)
+	}
+	if !reflect.DeepEqual(req.SplitColumns, splitQueryV2SplitColumns) {
+		f.t.Errorf("invalid SplitQueryV2.SplitQueryV2Request.SplitColumns: got %v expected %v", req.SplitColumns, splitQueryV2SplitColumns)
+	}
+	if req.SplitCount != splitQuerySplitCount {
+		f.t.Errorf("invalid SplitQueryV2.SplitQueryV2Request.SplitCount: got %v expected %v", req.SplitCount, splitQuerySplitCount)
+	}
+	if req.NumRowsPerQueryPart != splitQueryV2NumRowsPerQueryPart {
+		f.t.Errorf("invalid SplitQueryV2.SplitQueryV2Request.NumRowsPerQueryPart: got %v expected %v", req.NumRowsPerQueryPart, splitQueryV2NumRowsPerQueryPart)
+	}
+	reply.Queries = splitQueryQuerySplitList
+	return nil
+}
+
+var splitQueryV2SplitColumns = []string{"nice_column_to_split", "other_column"}
+
+const splitQueryV2NumRowsPerQueryPart = 1234
+
+func testSplitQueryV2(t *testing.T, conn tabletconn.TabletConn) {
+	t.Log("testSplitQueryV2")
+	ctx := context.Background()
+	qsl, err := conn.SplitQueryV2(ctx, splitQueryBoundQuery, splitQueryV2SplitColumns, splitQuerySplitCount, splitQueryV2NumRowsPerQueryPart)
+	if err != nil {
+		t.Fatalf("SplitQueryV2 failed: %v", err)
+	}
+	if !reflect.DeepEqual(qsl, splitQueryQuerySplitList) {
+		t.Errorf("Unexpected result from SplitQueryV2: got %v wanted %v", qsl, splitQueryQuerySplitList)
+	}
+}
+
+func testSplitQueryV2Error(t *testing.T, conn tabletconn.TabletConn) {
+	t.Log("testSplitQueryV2Error")
+	ctx := context.Background()
+	_, err := conn.SplitQueryV2(ctx, splitQueryBoundQuery, splitQueryV2SplitColumns, splitQuerySplitCount, splitQueryV2NumRowsPerQueryPart)
+	verifyError(t, err, "SplitQueryV2")
+}
+
+func testSplitQueryV2Panics(t *testing.T, conn tabletconn.TabletConn) {
+	t.Log("testSplitQueryV2Panics")
+	ctx := context.Background()
+	if _, err := conn.SplitQueryV2(ctx, splitQueryBoundQuery, splitQueryV2SplitColumns, splitQuerySplitCount, splitQueryV2NumRowsPerQueryPart); err == nil || !strings.Contains(err.Error(), "caught test panic") {
+		t.Fatalf("unexpected panic error: %v", err)
+	}
+}
+
 // this test is a bit of a hack: we write something on the channel
 // upon registration, and we also return an error, so the streaming query
 // ends right there. Otherwise we have no real way to trigger a real
@@ -1064,3 +1306,45 @@ func TestSuite(t *testing.T, conn tabletconn.TabletConn, fake *FakeQueryService)
 	testStreamHealthPanics(t, conn)
 	fake.panics = false
 }
+
+// TestTwoPCSuite runs the tests of the two-phase commit calls, for the
+// implementations that support them.
+func TestTwoPCSuite(t *testing.T, conn tabletconn.TabletConn, fake *FakeQueryService) {
+	testTwoPC(t, conn)
+
+	fake.hasError = true
+	testTwoPCError(t, conn)
+	fake.hasError = false
+
+	fake.panics = true
+	testTwoPCPanics(t, conn)
+	fake.panics = false
+}
+
+// TestReplicationPositionSuite runs the tests of the replication
+// position calls, for the implementations that support them.
+func TestReplicationPositionSuite(t *testing.T, conn tabletconn.TabletConn, fake *FakeQueryService) {
+	testMasterPosition(t, conn)
+
+	fake.hasError = true
+	testMasterPositionError(t, conn)
+	fake.hasError = false
+
+	fake.panics = true
+	testMasterPositionPanics(t, conn)
+	fake.panics = false
+}
+
+// TestSplitQueryV2Suite runs the tests of SplitQueryV2, for the
+// implementations that support it.
+func TestSplitQueryV2Suite(t *testing.T, conn tabletconn.TabletConn, fake *FakeQueryService) {
+	testSplitQueryV2(t, conn)
+
+	fake.hasError = true
+	testSplitQueryV2Error(t, conn)
+	fake.hasError = false
+
+	fake.panics = true
+	testSplitQueryV2Panics(t, conn)
+	fake.panics = false
+}
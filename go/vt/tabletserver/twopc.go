@@ -0,0 +1,387 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tabletserver
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/golang/glog"
+	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/sqltypes"
+	"github.com/youtube/vitess/go/timer"
+	"github.com/youtube/vitess/go/vt/tabletserver/proto"
+	"golang.org/x/net/context"
+)
+
+// The two-phase commit of a distributed transaction goes through
+// these steps, driven by vtgate:
+//
+// CreateTransaction: the coordinator, which is one of the shards of
+// the transaction, records the dtid and the other shards (the
+// participants) with the PREPARE state.
+//
+// Prepare: every participant writes the statements of its transaction
+// to its redo log, and keeps the transaction open.
+//
+// StartCommit: the coordinator changes the state to COMMIT as part of
+// committing its own transaction. This is the commit decision.
+//
+// CommitPrepared: every participant commits its transaction, and
+// deletes it from the redo log.
+//
+// ConcludeTransaction: the coordinator forgets the transaction.
+//
+// If vtgate fails midway, the TxResolver of the coordinator finishes
+// the transaction. The prepared transactions of a participant survive
+// restarts, because they're restored from its redo log.
+
+// These are the states of a distributed transaction in the coordinator.
+const (
+	DTStatePrepare  = "PREPARE"
+	DTStateCommit   = "COMMIT"
+	DTStateRollback = "ROLLBACK"
+)
+
+// twoPCTables are the sidecar tables of the two-phase commits.
+var twoPCTables = []string{
+	"create database if not exists _vt",
+	`create table if not exists _vt.redo_log_transaction(
+  dtid varbinary(512) not null,
+  time_created bigint not null,
+  primary key(dtid)
+) engine=InnoDB`,
+	`create table if not exists _vt.redo_log_statement(
+  dtid varbinary(512) not null,
+  id bigint not null,
+  statement mediumblob not null,
+  primary key(dtid, id)
+) engine=InnoDB`,
+	`create table if not exists _vt.dt_state(
+  dtid varbinary(512) not null,
+  state varbinary(16) not null,
+  time_created bigint not null,
+  primary key(dtid)
+) engine=InnoDB`,
+	`create table if not exists _vt.dt_participant(
+  dtid varbinary(512) not null,
+  id bigint not null,
+  keyspace varchar(256) not null,
+  shard varchar(256) not null,
+  primary key(dtid, id)
+) engine=InnoDB`,
+}
+
+// TwoPCClient is used by TxResolver to resolve the prepared transactions
+// of the participants. vttablet registers an implementation that finds
+// the master of the participant in the topology.
+type TwoPCClient interface {
+	CommitPrepared(ctx context.Context, participant proto.DTParticipant, dtid string) error
+	RollbackPrepared(ctx context.Context, participant proto.DTParticipant, dtid string) error
+}
+
+var (
+	twoPCClientMu sync.Mutex
+	twoPCClient   TwoPCClient
+)
+
+// RegisterTwoPCClient registers the TwoPCClient used by TxResolver.
+func RegisterTwoPCClient(client TwoPCClient) {
+	twoPCClientMu.Lock()
+	defer twoPCClientMu.Unlock()
+	twoPCClient = client
+}
+
+func getTwoPCClient() TwoPCClient {
+	twoPCClientMu.Lock()
+	defer twoPCClientMu.Unlock()
+	return twoPCClient
+}
+
+// DistributedTx is a distributed transaction as seen by its coordinator.
+type DistributedTx struct {
+	Dtid         string
+	State        string
+	Participants []proto.DTParticipant
+}
+
+func encodeString(in string) string {
+	buf := &bytes.Buffer{}
+	sqltypes.MakeString([]byte(in)).EncodeSql(buf)
+	return buf.String()
+}
+
+// execInNewTx executes the queries in a transaction of its own, which
+// is committed right away. It doesn't use the active transactions.
+func (axp *TxPool) execInNewTx(ctx context.Context, queries []string) (results []*mproto.QueryResult, err error) {
+	conn, err := axp.pool.Get(ctx)
+	if err != nil {
+		return nil, NewTabletErrorSql(ErrFatal, err)
+	}
+	defer conn.Recycle()
+	if _, err := conn.ExecOnce(ctx, "begin", 1, false); err != nil {
+		return nil, NewTabletErrorSql(ErrFail, err)
+	}
+	for _, query := range queries {
+		qr, err := conn.ExecOnce(ctx, query, 10000, false)
+		if err != nil {
+			conn.ExecOnce(ctx, "rollback", 1, false)
+			return nil, NewTabletErrorSql(ErrFail, err)
+		}
+		results = append(results, qr)
+	}
+	if _, err := conn.ExecOnce(ctx, "commit", 1, false); err != nil {
+		return nil, NewTabletErrorSql(ErrFail, err)
+	}
+	return results, nil
+}
+
+func (axp *TxPool) writeRedoLog(ctx context.Context, dtid string, statements []string) error {
+	queries := []string{fmt.Sprintf(
+		"insert into _vt.redo_log_transaction(dtid, time_created) values (%s, unix_timestamp())",
+		encodeString(dtid),
+	)}
+	if len(statements) != 0 {
+		values := make([]string, 0, len(statements))
+		for i, statement := range statements {
+			values = append(values, fmt.Sprintf("(%s, %d, %s)", encodeString(dtid), i+1, encodeString(statement)))
+		}
+		queries = append(queries, "insert into _vt.redo_log_statement(dtid, id, statement) values "+strings.Join(values, ", "))
+	}
+	_, err := axp.execInNewTx(ctx, queries)
+	return err
+}
+
+func readRedoLogQuery(dtid string) string {
+	return fmt.Sprintf("select dtid from _vt.redo_log_transaction where dtid = %s", encodeString(dtid))
+}
+
+func deleteRedoLogQueries(dtid string) []string {
+	return []string{
+		fmt.Sprintf("delete from _vt.redo_log_statement where dtid = %s", encodeString(dtid)),
+		fmt.Sprintf("delete from _vt.redo_log_transaction where dtid = %s", encodeString(dtid)),
+	}
+}
+
+// RestorePrepared restores the prepared transactions of the redo log
+// by replaying their statements in new transactions. It must be called
+// on a master before it serves queries. A transaction that can't be
+// replayed is left in the redo log, and needs manual intervention.
+func (axp *TxPool) RestorePrepared(ctx context.Context) error {
+	results, err := axp.execInNewTx(ctx, []string{
+		"select dtid from _vt.redo_log_transaction",
+		"select dtid, statement from _vt.redo_log_statement order by dtid, id",
+	})
+	if err != nil {
+		return err
+	}
+	statements := make(map[string][]string)
+	for _, row := range results[1].Rows {
+		dtid := row[0].String()
+		statements[dtid] = append(statements[dtid], row[1].String())
+	}
+	for _, row := range results[0].Rows {
+		dtid := row[0].String()
+		if err := axp.replay(ctx, dtid, statements[dtid]); err != nil {
+			log.Errorf("could not restore prepared transaction %s: %v", dtid, err)
+			axp.queryServiceStats.InternalErrors.Add("TwoPCRestore", 1)
+			continue
+		}
+		log.Infof("restored prepared transaction %s", dtid)
+	}
+	return nil
+}
+
+func (axp *TxPool) replay(ctx context.Context, dtid string, statements []string) (err error) {
+	defer handleError(&err, nil, axp.queryServiceStats)
+	transactionID := axp.Begin(ctx)
+	conn := axp.Get(transactionID)
+	for _, statement := range statements {
+		if _, err := conn.Exec(ctx, statement, 1, false); err != nil {
+			conn.Recycle()
+			axp.Rollback(ctx, transactionID)
+			return err
+		}
+		conn.RecordRedo(statement)
+	}
+	axp.activePool.Unregister(transactionID)
+	axp.putPrepared(dtid, conn)
+	return nil
+}
+
+// CreateTransaction records the distributed transaction dtid
+// in the PREPARE state, along with its participants.
+func (axp *TxPool) CreateTransaction(ctx context.Context, dtid string, participants []proto.DTParticipant) error {
+	queries := []string{fmt.Sprintf(
+		"insert into _vt.dt_state(dtid, state, time_created) values (%s, %s, unix_timestamp())",
+		encodeString(dtid),
+		encodeString(DTStatePrepare),
+	)}
+	if len(participants) != 0 {
+		values := make([]string, 0, len(participants))
+		for i, participant := range participants {
+			values = append(values, fmt.Sprintf("(%s, %d, %s, %s)", encodeString(dtid), i+1, encodeString(participant.Keyspace), encodeString(participant.Shard)))
+		}
+		queries = append(queries, "insert into _vt.dt_participant(dtid, id, keyspace, shard) values "+strings.Join(values, ", "))
+	}
+	_, err := axp.execInNewTx(ctx, queries)
+	return err
+}
+
+// StartCommit changes the state of dtid to COMMIT as part of the
+// specified transaction, which must be committed next. It fails if
+// the state is not PREPARE any more, because the resolver decided
+// to roll back the transaction. The transaction is rolled back if
+// StartCommit fails.
+func (axp *TxPool) StartCommit(ctx context.Context, transactionID int64, dtid string) {
+	conn := axp.Get(transactionID)
+	qr, err := conn.Exec(ctx, fmt.Sprintf(
+		"update _vt.dt_state set state = %s where dtid = %s and state = %s",
+		encodeString(DTStateCommit),
+		encodeString(dtid),
+		encodeString(DTStatePrepare),
+	), 1, false)
+	conn.Recycle()
+	if err == nil && qr.RowsAffected != 1 {
+		err = NewTabletError(ErrFail, "could not commit dtid %s: it's not in the %s state", dtid, DTStatePrepare)
+	}
+	if err != nil {
+		axp.Rollback(ctx, transactionID)
+		panic(err)
+	}
+}
+
+// ConcludeTransaction forgets the distributed transaction dtid,
+// once all its participants are resolved.
+func (axp *TxPool) ConcludeTransaction(ctx context.Context, dtid string) error {
+	_, err := axp.execInNewTx(ctx, []string{
+		fmt.Sprintf("delete from _vt.dt_participant where dtid = %s", encodeString(dtid)),
+		fmt.Sprintf("delete from _vt.dt_state where dtid = %s", encodeString(dtid)),
+	})
+	return err
+}
+
+// ReadAbandoned returns the distributed transactions older
+// than abandonAge. The age is measured by the clock of MySQL.
+func (axp *TxPool) ReadAbandoned(ctx context.Context, abandonAge time.Duration) ([]*DistributedTx, error) {
+	results, err := axp.execInNewTx(ctx, []string{fmt.Sprintf(
+		"select s.dtid, s.state, p.keyspace, p.shard from _vt.dt_state s join _vt.dt_participant p on s.dtid = p.dtid where s.time_created < unix_timestamp() - %d order by s.dtid, p.id",
+		int64(abandonAge.Seconds()),
+	)})
+	if err != nil {
+		return nil, err
+	}
+	var dts []*DistributedTx
+	for _, row := range results[0].Rows {
+		dtid := row[0].String()
+		if len(dts) == 0 || dts[len(dts)-1].Dtid != dtid {
+			dts = append(dts, &DistributedTx{Dtid: dtid, State: row[1].String()})
+		}
+		dt := dts[len(dts)-1]
+		dt.Participants = append(dt.Participants, proto.DTParticipant{
+			Keyspace: row[2].String(),
+			Shard:    row[3].String(),
+		})
+	}
+	return dts, nil
+}
+
+// setRollback changes the state of dtid from PREPARE to ROLLBACK.
+// It returns false if the state was not PREPARE any more.
+func (axp *TxPool) setRollback(ctx context.Context, dtid string) (bool, error) {
+	results, err := axp.execInNewTx(ctx, []string{fmt.Sprintf(
+		"update _vt.dt_state set state = %s where dtid = %s and state = %s",
+		encodeString(DTStateRollback),
+		encodeString(dtid),
+		encodeString(DTStatePrepare),
+	)})
+	if err != nil {
+		return false, err
+	}
+	return results[0].RowsAffected == 1, nil
+}
+
+// TxResolver finishes the distributed transactions that vtgate
+// abandoned in the coordinator. A transaction is abandoned once it's
+// older than abandonAge, which must be well above the transaction
+// timeout. If its decision is not made yet, it's rolled back.
+type TxResolver struct {
+	txPool            *TxPool
+	abandonAge        time.Duration
+	ticks             *timer.Timer
+	queryServiceStats *QueryServiceStats
+}
+
+// NewTxResolver creates a new TxResolver. It's not running until it's Open'd.
+func NewTxResolver(txPool *TxPool, abandonAge time.Duration, qStats *QueryServiceStats) *TxResolver {
+	return &TxResolver{
+		txPool:            txPool,
+		abandonAge:        abandonAge,
+		ticks:             timer.NewTimer(abandonAge / 2),
+		queryServiceStats: qStats,
+	}
+}
+
+// Open starts resolving the abandoned transactions.
+func (txr *TxResolver) Open() {
+	txr.ticks.Start(func() { txr.resolveAbandoned() })
+}
+
+// Close stops the resolver.
+func (txr *TxResolver) Close() {
+	txr.ticks.Stop()
+}
+
+func (txr *TxResolver) resolveAbandoned() {
+	defer logError(txr.queryServiceStats)
+	ctx, cancel := context.WithTimeout(context.Background(), txr.abandonAge/2)
+	defer cancel()
+	dts, err := txr.txPool.ReadAbandoned(ctx, txr.abandonAge)
+	if err != nil {
+		log.Errorf("could not read abandoned transactions: %v", err)
+		txr.queryServiceStats.InternalErrors.Add("TwoPCResolve", 1)
+		return
+	}
+	for _, dt := range dts {
+		if err := txr.resolve(ctx, dt); err != nil {
+			log.Errorf("could not resolve transaction %s: %v", dt.Dtid, err)
+			txr.queryServiceStats.InternalErrors.Add("TwoPCResolve", 1)
+		}
+	}
+}
+
+func (txr *TxResolver) resolve(ctx context.Context, dt *DistributedTx) error {
+	if dt.State == DTStatePrepare {
+		ok, err := txr.txPool.setRollback(ctx, dt.Dtid)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// The commit started meanwhile: resolve it next time.
+			return nil
+		}
+		dt.State = DTStateRollback
+	}
+	client := getTwoPCClient()
+	if client == nil {
+		return fmt.Errorf("no TwoPCClient is registered")
+	}
+	for _, participant := range dt.Participants {
+		var err error
+		if dt.State == DTStateCommit {
+			err = client.CommitPrepared(ctx, participant, dt.Dtid)
+		} else {
+			err = client.RollbackPrepared(ctx, participant, dt.Dtid)
+		}
+		if err != nil {
+			return fmt.Errorf("%s/%s: %v", participant.Keyspace, participant.Shard, err)
+		}
+	}
+	log.Infof("resolved abandoned transaction %s: %s", dt.Dtid, dt.State)
+	return txr.txPool.ConcludeTransaction(ctx, dt.Dtid)
+}
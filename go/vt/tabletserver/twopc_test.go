@@ -0,0 +1,288 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tabletserver
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/sqldb"
+	"github.com/youtube/vitess/go/sqltypes"
+	"github.com/youtube/vitess/go/vt/tabletserver/proto"
+	"github.com/youtube/vitess/go/vt/vttest/fakesqldb"
+	"golang.org/x/net/context"
+)
+
+const (
+	testRedoInsert    = "insert into _vt.redo_log_transaction(dtid, time_created) values ('ks:0:1', unix_timestamp())"
+	testRedoStatement = "insert into _vt.redo_log_statement(dtid, id, statement) values ('ks:0:1', 1, 'update a set b = 1 where c = 2')"
+	testRedoDelete1   = "delete from _vt.redo_log_statement where dtid = 'ks:0:1'"
+	testRedoDelete2   = "delete from _vt.redo_log_transaction where dtid = 'ks:0:1'"
+	testRedoRead      = "select dtid from _vt.redo_log_transaction where dtid = 'ks:0:1'"
+)
+
+func newTwoPCDB() *fakesqldb.DB {
+	db := fakesqldb.Register()
+	for _, query := range []string{
+		"begin",
+		"commit",
+		"rollback",
+		"update a set b = 1 where c = 2",
+		testRedoInsert,
+		testRedoStatement,
+		testRedoDelete1,
+		testRedoDelete2,
+		testRedoRead,
+	} {
+		db.AddQuery(query, &mproto.QueryResult{})
+	}
+	return db
+}
+
+func openTxPool() *TxPool {
+	txPool := newTxPool(false)
+	appParams := sqldb.ConnParams{}
+	dbaParams := sqldb.ConnParams{}
+	txPool.Open(&appParams, &dbaParams)
+	return txPool
+}
+
+func beginWithRedo(ctx context.Context, t *testing.T, txPool *TxPool) int64 {
+	transactionID := txPool.Begin(ctx)
+	txConn := txPool.Get(transactionID)
+	defer txConn.Recycle()
+	if _, err := txConn.Exec(ctx, "update a set b = 1 where c = 2", 1, false); err != nil {
+		t.Fatal(err)
+	}
+	txConn.RecordRedo("update a set b = 1 where c = 2")
+	return transactionID
+}
+
+func TestTxPoolPrepareCommitPrepared(t *testing.T) {
+	db := newTwoPCDB()
+	txPool := openTxPool()
+	defer txPool.Close()
+	ctx := context.Background()
+
+	transactionID := beginWithRedo(ctx, t, txPool)
+	txPool.Prepare(ctx, transactionID, "ks:0:1")
+	if db.GetQueryCalledNum(testRedoStatement) != 1 {
+		t.Errorf("redo statements were not written")
+	}
+	if txPool.PreparedCount() != 1 {
+		t.Errorf("PreparedCount: %d, want 1", txPool.PreparedCount())
+	}
+	// A prepared transaction can't be used any more.
+	if _, err := txPool.activePool.Get(transactionID, "for test"); err == nil {
+		t.Errorf("prepared transaction is still active")
+	}
+
+	if _, err := txPool.SafeCommitPrepared(ctx, "ks:0:1"); err != nil {
+		t.Fatal(err)
+	}
+	if db.GetQueryCalledNum(testRedoDelete2) != 1 {
+		t.Errorf("redo log was not deleted")
+	}
+	if txPool.PreparedCount() != 0 {
+		t.Errorf("PreparedCount: %d, want 0", txPool.PreparedCount())
+	}
+	// Committing again is a no-op.
+	if _, err := txPool.SafeCommitPrepared(ctx, "ks:0:1"); err != nil {
+		t.Fatal(err)
+	}
+	if db.GetQueryCalledNum(testRedoDelete2) != 1 {
+		t.Errorf("redo log was deleted again")
+	}
+	if db.GetQueryCalledNum(testRedoRead) != 1 {
+		t.Errorf("redo log was not checked")
+	}
+}
+
+func TestTxPoolPrepareFail(t *testing.T) {
+	db := newTwoPCDB()
+	db.AddRejectedQuery(testRedoStatement)
+	txPool := openTxPool()
+	defer txPool.Close()
+	ctx := context.Background()
+
+	transactionID := beginWithRedo(ctx, t, txPool)
+	func() {
+		defer func() {
+			if x := recover(); x == nil {
+				t.Errorf("Prepare did not fail")
+			}
+		}()
+		txPool.Prepare(ctx, transactionID, "ks:0:1")
+	}()
+	if txPool.PreparedCount() != 0 {
+		t.Errorf("PreparedCount: %d, want 0", txPool.PreparedCount())
+	}
+	// The transaction was rolled back.
+	if _, err := txPool.activePool.Get(transactionID, "for test"); err == nil {
+		t.Errorf("transaction is still active")
+	}
+}
+
+func TestTxPoolRollbackPrepared(t *testing.T) {
+	db := newTwoPCDB()
+	txPool := openTxPool()
+	defer txPool.Close()
+	ctx := context.Background()
+
+	transactionID := beginWithRedo(ctx, t, txPool)
+	txPool.Prepare(ctx, transactionID, "ks:0:1")
+	txPool.RollbackPrepared(ctx, "ks:0:1")
+	if txPool.PreparedCount() != 0 {
+		t.Errorf("PreparedCount: %d, want 0", txPool.PreparedCount())
+	}
+	// The redo log is deleted even if the transaction is not prepared.
+	txPool.RollbackPrepared(ctx, "ks:0:1")
+	if db.GetQueryCalledNum(testRedoDelete2) != 2 {
+		t.Errorf("redo log deletes: %d, want 2", db.GetQueryCalledNum(testRedoDelete2))
+	}
+}
+
+func TestTxPoolRestorePrepared(t *testing.T) {
+	db := newTwoPCDB()
+	db.AddQuery("select dtid from _vt.redo_log_transaction", &mproto.QueryResult{
+		RowsAffected: 2,
+		Rows: [][]sqltypes.Value{
+			{sqltypes.MakeString([]byte("ks:0:1"))},
+			{sqltypes.MakeString([]byte("ks:0:2"))},
+		},
+	})
+	db.AddQuery("select dtid, statement from _vt.redo_log_statement order by dtid, id", &mproto.QueryResult{
+		RowsAffected: 2,
+		Rows: [][]sqltypes.Value{
+			{sqltypes.MakeString([]byte("ks:0:1")), sqltypes.MakeString([]byte("update a set b = 1 where c = 2"))},
+			{sqltypes.MakeString([]byte("ks:0:2")), sqltypes.MakeString([]byte("bad statement"))},
+		},
+	})
+	db.AddRejectedQuery("bad statement")
+	db.AddQuery("select dtid from _vt.redo_log_transaction where dtid = 'ks:0:2'", &mproto.QueryResult{
+		RowsAffected: 1,
+		Rows: [][]sqltypes.Value{
+			{sqltypes.MakeString([]byte("ks:0:2"))},
+		},
+	})
+	txPool := openTxPool()
+	defer txPool.Close()
+	ctx := context.Background()
+
+	if err := txPool.RestorePrepared(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// ks:0:2 can't be replayed, and remains in the redo log.
+	if txPool.PreparedCount() != 1 {
+		t.Errorf("PreparedCount: %d, want 1", txPool.PreparedCount())
+	}
+	if _, err := txPool.SafeCommitPrepared(ctx, "ks:0:1"); err != nil {
+		t.Fatal(err)
+	}
+	if db.GetQueryCalledNum(testRedoDelete2) != 1 {
+		t.Errorf("redo log was not deleted")
+	}
+	_, err := txPool.SafeCommitPrepared(ctx, "ks:0:2")
+	want := "prepared transaction ks:0:2 was not restored from the redo log, it needs manual intervention"
+	if terr, ok := err.(*TabletError); !ok || terr.Message != want {
+		t.Errorf("SafeCommitPrepared: %v, want %s", err, want)
+	}
+}
+
+func TestTxPoolStartCommitFail(t *testing.T) {
+	db := newTwoPCDB()
+	// The resolver rolled back ks:0:1 meanwhile.
+	db.AddQuery("update _vt.dt_state set state = 'COMMIT' where dtid = 'ks:0:1' and state = 'PREPARE'", &mproto.QueryResult{RowsAffected: 0})
+	txPool := openTxPool()
+	defer txPool.Close()
+	ctx := context.Background()
+
+	transactionID := txPool.Begin(ctx)
+	func() {
+		defer func() {
+			x := recover()
+			want := "could not commit dtid ks:0:1: it's not in the PREPARE state"
+			if err, ok := x.(*TabletError); !ok || err.Message != want {
+				t.Errorf("StartCommit: %v, want %s", x, want)
+			}
+		}()
+		txPool.StartCommit(ctx, transactionID, "ks:0:1")
+	}()
+	if _, err := txPool.activePool.Get(transactionID, "for test"); err == nil {
+		t.Errorf("transaction is still active")
+	}
+}
+
+type fakeTwoPCClient struct {
+	calls []string
+	err   error
+}
+
+func (client *fakeTwoPCClient) CommitPrepared(ctx context.Context, participant proto.DTParticipant, dtid string) error {
+	client.calls = append(client.calls, fmt.Sprintf("CommitPrepared %s/%s %s", participant.Keyspace, participant.Shard, dtid))
+	return client.err
+}
+
+func (client *fakeTwoPCClient) RollbackPrepared(ctx context.Context, participant proto.DTParticipant, dtid string) error {
+	client.calls = append(client.calls, fmt.Sprintf("RollbackPrepared %s/%s %s", participant.Keyspace, participant.Shard, dtid))
+	return client.err
+}
+
+func TestTxResolver(t *testing.T) {
+	db := newTwoPCDB()
+	db.AddQuery("select s.dtid, s.state, p.keyspace, p.shard from _vt.dt_state s join _vt.dt_participant p on s.dtid = p.dtid where s.time_created < unix_timestamp() - 60 order by s.dtid, p.id", &mproto.QueryResult{
+		RowsAffected: 4,
+		Rows: [][]sqltypes.Value{
+			{sqltypes.MakeString([]byte("ks:0:1")), sqltypes.MakeString([]byte("COMMIT")), sqltypes.MakeString([]byte("ks")), sqltypes.MakeString([]byte("1"))},
+			{sqltypes.MakeString([]byte("ks:0:1")), sqltypes.MakeString([]byte("COMMIT")), sqltypes.MakeString([]byte("ks")), sqltypes.MakeString([]byte("2"))},
+			{sqltypes.MakeString([]byte("ks:0:2")), sqltypes.MakeString([]byte("PREPARE")), sqltypes.MakeString([]byte("ks")), sqltypes.MakeString([]byte("1"))},
+			{sqltypes.MakeString([]byte("ks:0:3")), sqltypes.MakeString([]byte("PREPARE")), sqltypes.MakeString([]byte("ks")), sqltypes.MakeString([]byte("1"))},
+		},
+	})
+	// fakesqldb returns as many rows as RowsAffected, so the
+	// updated row is given as an empty one.
+	db.AddQuery("update _vt.dt_state set state = 'ROLLBACK' where dtid = 'ks:0:2' and state = 'PREPARE'", &mproto.QueryResult{
+		RowsAffected: 1,
+		Rows:         [][]sqltypes.Value{{}},
+	})
+	// ks:0:3 started its commit meanwhile.
+	db.AddQuery("update _vt.dt_state set state = 'ROLLBACK' where dtid = 'ks:0:3' and state = 'PREPARE'", &mproto.QueryResult{RowsAffected: 0})
+	for _, dtid := range []string{"ks:0:1", "ks:0:2"} {
+		db.AddQuery(fmt.Sprintf("delete from _vt.dt_participant where dtid = '%s'", dtid), &mproto.QueryResult{})
+		db.AddQuery(fmt.Sprintf("delete from _vt.dt_state where dtid = '%s'", dtid), &mproto.QueryResult{})
+	}
+	client := &fakeTwoPCClient{}
+	RegisterTwoPCClient(client)
+	defer RegisterTwoPCClient(nil)
+	txPool := openTxPool()
+	defer txPool.Close()
+
+	txr := NewTxResolver(txPool, 60*time.Second, txPool.queryServiceStats)
+	txr.resolveAbandoned()
+	want := []string{
+		"CommitPrepared ks/1 ks:0:1",
+		"CommitPrepared ks/2 ks:0:1",
+		"RollbackPrepared ks/1 ks:0:2",
+	}
+	if !reflect.DeepEqual(client.calls, want) {
+		t.Errorf("calls: %v, want %v", client.calls, want)
+	}
+	for _, dtid := range []string{"ks:0:1", "ks:0:2"} {
+		if db.GetQueryCalledNum(fmt.Sprintf("delete from _vt.dt_state where dtid = '%s'", dtid)) != 1 {
+			t.Errorf("%s was not concluded", dtid)
+		}
+	}
+
+	// A failed participant prevents the conclusion.
+	client.calls = nil
+	client.err = fmt.Errorf("participant failed")
+	txr.resolveAbandoned()
+	if db.GetQueryCalledNum("delete from _vt.dt_state where dtid = 'ks:0:1'") != 1 {
+		t.Errorf("ks:0:1 was concluded again")
+	}
+}
@@ -53,9 +53,16 @@ type TxPool struct {
 	ticks             *timer.Timer
 	txStats           *stats.Timings
 	queryServiceStats *QueryServiceStats
+	// resourceUsage is set by the QueryEngine. It can be nil.
+	resourceUsage *ResourceUsage
 	// Tracking culprits that cause tx pool full errors.
 	logMu   sync.Mutex
 	lastLog time.Time
+	// prepared holds the transactions prepared for a two-phase
+	// commit, by dtid. They're not in activePool, so they can't
+	// be killed.
+	preparedMu sync.Mutex
+	prepared   map[string]*TxConnection
 }
 
 // NewTxPool creates a new TxPool. It's not operational until it's Open'd.
@@ -83,6 +90,7 @@ func NewTxPool(
 		ticks:             timer.NewTimer(timeout / 10),
 		txStats:           stats.NewTimings(txStatsName),
 		queryServiceStats: qStats,
+		prepared:          make(map[string]*TxConnection),
 	}
 	// Careful: pool also exports name+"xxx" vars,
 	// but we know it doesn't export Timeout.
@@ -111,6 +119,17 @@ func (axp *TxPool) Close() {
 		conn.Close()
 		conn.discard(TxClose)
 	}
+	// The prepared transactions are rolled back by MySQL when their
+	// connections are closed. They remain in the redo log, and are
+	// restored by RestorePrepared.
+	axp.preparedMu.Lock()
+	for dtid, conn := range axp.prepared {
+		log.Infof("closing prepared transaction %s for shutdown", dtid)
+		conn.Close()
+		conn.discard(TxClose)
+		delete(axp.prepared, dtid)
+	}
+	axp.preparedMu.Unlock()
 	axp.pool.Close()
 }
 
@@ -139,6 +158,8 @@ func (axp *TxPool) Begin(ctx context.Context) int64 {
 		poolCtx, cancel = context.WithDeadline(ctx, deadline.Add(-10*time.Millisecond))
 		defer cancel()
 	}
+	caller := accountedCaller(ctx)
+	waitStart := time.Now()
 	conn, err := axp.pool.Get(poolCtx)
 	if err != nil {
 		switch err {
@@ -147,15 +168,20 @@ func (axp *TxPool) Begin(ctx context.Context) int64 {
 		case pools.ErrTimeout:
 			axp.LogActive()
 			panic(NewTabletError(ErrTxPoolFull, "Transaction pool connection limit exceeded"))
+		case ErrCallerQuota:
+			panic(NewTabletError(ErrTxPoolFull, "Transaction pool quota of the caller exceeded"))
 		}
 		panic(NewTabletErrorSql(ErrFatal, err))
 	}
+	axp.resourceUsage.RecordBegin(caller, time.Now().Sub(waitStart))
 	if _, err := conn.Exec(ctx, "begin", 1, false); err != nil {
 		conn.Recycle()
 		panic(NewTabletErrorSql(ErrFail, err))
 	}
 	transactionID := axp.lastID.Add(1)
-	axp.activePool.Register(transactionID, newTxConnection(conn, transactionID, axp))
+	txc := newTxConnection(conn, transactionID, axp)
+	txc.caller = caller
+	axp.activePool.Register(transactionID, txc)
 	return transactionID
 }
 
@@ -188,6 +214,108 @@ func (axp *TxPool) Rollback(ctx context.Context, transactionID int64) {
 	}
 }
 
+// Prepare prepares the specified transaction for a two-phase commit
+// as dtid. Its statements are written to the redo log, and it's
+// moved out of the active transactions: it can't be killed any more,
+// and must be resolved by CommitPrepared or RollbackPrepared.
+// The transaction is rolled back if Prepare fails.
+func (axp *TxPool) Prepare(ctx context.Context, transactionID int64, dtid string) {
+	conn := axp.Get(transactionID)
+	// A dtid that's already prepared fails on the primary
+	// key of the redo log.
+	if err := axp.writeRedoLog(ctx, dtid, conn.RedoStatements); err != nil {
+		axp.txStats.Add("Aborted", time.Now().Sub(conn.StartTime))
+		if _, rbErr := conn.Exec(ctx, "rollback", 1, false); rbErr != nil {
+			conn.Close()
+		}
+		conn.discard(TxRollback)
+		panic(err)
+	}
+	axp.activePool.Unregister(transactionID)
+	axp.putPrepared(dtid, conn)
+}
+
+// SafeCommitPrepared commits the prepared transaction dtid, and deletes
+// it from the redo log as part of the same transaction. It's a no-op
+// if dtid is neither prepared nor in the redo log, because it was
+// already committed. It fails if dtid is only in the redo log: its
+// transaction could not be restored. Like SafeCommit, it returns an
+// error on failure instead of panic.
+func (axp *TxPool) SafeCommitPrepared(ctx context.Context, dtid string) (invalidList map[string]DirtyKeys, err error) {
+	defer handleError(&err, nil, axp.queryServiceStats)
+
+	conn := axp.takePrepared(dtid)
+	if conn == nil {
+		results, err := axp.execInNewTx(ctx, []string{readRedoLogQuery(dtid)})
+		if err != nil {
+			return nil, err
+		}
+		if len(results[0].Rows) != 0 {
+			return nil, NewTabletError(ErrFail, "prepared transaction %s was not restored from the redo log, it needs manual intervention", dtid)
+		}
+		return nil, nil
+	}
+	for _, query := range deleteRedoLogQueries(dtid) {
+		if _, err := conn.Exec(ctx, query, 1, false); err != nil {
+			// The transaction is still intact: keep it prepared,
+			// so the commit can be retried.
+			axp.putPrepared(dtid, conn)
+			return nil, err
+		}
+	}
+	defer conn.discard(TxCommit)
+	invalidList = conn.dirtyTables
+	axp.txStats.Add("Completed", time.Now().Sub(conn.StartTime))
+	if _, fetchErr := conn.Exec(ctx, "commit", 1, false); fetchErr != nil {
+		// The transaction is lost with the connection, but not its
+		// redo log: it will be restored when the pool is reopened.
+		conn.Close()
+		axp.queryServiceStats.InternalErrors.Add("TwoPCCommit", 1)
+		err = NewTabletErrorSql(ErrFail, fetchErr)
+	}
+	return
+}
+
+// RollbackPrepared rolls back the prepared transaction dtid, and deletes
+// it from the redo log. It's a no-op for the transaction if dtid is not
+// prepared, because it was already rolled back.
+func (axp *TxPool) RollbackPrepared(ctx context.Context, dtid string) {
+	if conn := axp.takePrepared(dtid); conn != nil {
+		axp.txStats.Add("Aborted", time.Now().Sub(conn.StartTime))
+		if _, err := conn.Exec(ctx, "rollback", 1, false); err != nil {
+			conn.Close()
+		}
+		conn.discard(TxRollback)
+	}
+	if _, err := axp.execInNewTx(ctx, deleteRedoLogQueries(dtid)); err != nil {
+		panic(err)
+	}
+}
+
+func (axp *TxPool) takePrepared(dtid string) *TxConnection {
+	axp.preparedMu.Lock()
+	defer axp.preparedMu.Unlock()
+	conn, ok := axp.prepared[dtid]
+	if !ok {
+		return nil
+	}
+	delete(axp.prepared, dtid)
+	return conn
+}
+
+func (axp *TxPool) putPrepared(dtid string, conn *TxConnection) {
+	axp.preparedMu.Lock()
+	defer axp.preparedMu.Unlock()
+	axp.prepared[dtid] = conn
+}
+
+// PreparedCount returns the number of prepared transactions.
+func (axp *TxPool) PreparedCount() int {
+	axp.preparedMu.Lock()
+	defer axp.preparedMu.Unlock()
+	return len(axp.prepared)
+}
+
 // Get fetches the connection associated to the transactionID.
 // You must call Recycle on TxConnection once done.
 func (axp *TxPool) Get(transactionID int64) (conn *TxConnection) {
@@ -248,8 +376,14 @@ type TxConnection struct {
 	EndTime       time.Time
 	dirtyTables   map[string]DirtyKeys
 	Queries       []string
-	Conclusion    string
-	LogToFile     sync2.AtomicInt32
+	// RedoStatements are the final DMLs of the transaction, which
+	// are written to the redo log if it's prepared.
+	RedoStatements []string
+	Conclusion     string
+	LogToFile      sync2.AtomicInt32
+	// caller is the effective caller the transaction is
+	// accounted to.
+	caller string
 }
 
 func newTxConnection(conn *DBConn, transactionID int64, pool *TxPool) *TxConnection {
@@ -302,10 +436,17 @@ func (txc *TxConnection) RecordQuery(query string) {
 	txc.Queries = append(txc.Queries, query)
 }
 
+// RecordRedo records a DML executed by this transaction, in case
+// it's prepared for a two-phase commit.
+func (txc *TxConnection) RecordRedo(query string) {
+	txc.RedoStatements = append(txc.RedoStatements, query)
+}
+
 func (txc *TxConnection) discard(conclusion string) {
 	txc.Conclusion = conclusion
 	txc.EndTime = time.Now()
 	txc.pool.activePool.Unregister(txc.TransactionID)
+	txc.pool.resourceUsage.RecordTransaction(txc.caller, txc.EndTime.Sub(txc.StartTime))
 	txc.DBConn.Recycle()
 	// Ensure PoolConnection won't be accessed after Recycle.
 	txc.DBConn = nil
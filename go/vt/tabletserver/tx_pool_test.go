@@ -12,6 +12,7 @@ import (
 
 	"github.com/youtube/vitess/go/mysql/proto"
 	"github.com/youtube/vitess/go/sqldb"
+	"github.com/youtube/vitess/go/vt/callerid"
 	"github.com/youtube/vitess/go/vt/vttest/fakesqldb"
 	"golang.org/x/net/context"
 )
@@ -159,6 +160,34 @@ func TestTxPoolBeginWithShortDeadline(t *testing.T) {
 	txPool.Begin(ctx)
 }
 
+func TestTxPoolBeginWithCallerQuota(t *testing.T) {
+	db := fakesqldb.Register()
+	db.AddQuery("begin", &proto.QueryResult{})
+	db.AddQuery("rollback", &proto.QueryResult{})
+
+	txPool := newTxPool(false)
+	txPool.resourceUsage = NewResourceUsage("", false)
+	appParams := sqldb.ConnParams{}
+	dbaParams := sqldb.ConnParams{}
+	txPool.Open(&appParams, &dbaParams)
+	txPool.pool.SetCallerQuota(1)
+	defer txPool.Close()
+
+	ctx := callerid.NewContext(context.Background(), callerid.NewEffectiveCallerID("batch", "", ""), nil)
+	transactionID := txPool.Begin(ctx)
+	func() {
+		defer handleAndVerifyTabletError(t, "expect to get an error", ErrTxPoolFull)
+		txPool.Begin(ctx)
+	}()
+	txPool.Rollback(ctx, transactionID)
+	// The quota is released with the transaction.
+	txPool.Rollback(ctx, txPool.Begin(ctx))
+
+	if got := txPool.resourceUsage.TransactionTime.Counts()["batch"]; got != 2 {
+		t.Errorf("transactions of batch: %d, want 2", got)
+	}
+}
+
 func TestTxPoolBeginWithPoolConnectionError(t *testing.T) {
 	db := fakesqldb.Register()
 	db.EnableConnFail()
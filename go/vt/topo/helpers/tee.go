@@ -696,6 +696,18 @@ func (tee *Tee) WatchEndPoints(ctx context.Context, cell, keyspace, shard string
 	return tee.primary.WatchEndPoints(ctx, cell, keyspace, shard, tabletType)
 }
 
+// WatchSrvKeyspace is part of the topo.Server interface.
+// We only watch for changes on the primary.
+func (tee *Tee) WatchSrvKeyspace(ctx context.Context, cell, keyspace string) (<-chan *topo.SrvKeyspace, chan<- struct{}, error) {
+	return tee.primary.WatchSrvKeyspace(ctx, cell, keyspace)
+}
+
+// WatchSrvShard is part of the topo.Server interface.
+// We only watch for changes on the primary.
+func (tee *Tee) WatchSrvShard(ctx context.Context, cell, keyspace, shard string) (<-chan *topo.SrvShard, chan<- struct{}, error) {
+	return tee.primary.WatchSrvShard(ctx, cell, keyspace, shard)
+}
+
 //
 // Keyspace and Shard locks for actions, global.
 //
@@ -78,6 +78,18 @@ func TestWatchEndPoints(t *testing.T) {
 	test.CheckWatchEndPoints(context.Background(), t, ts)
 }
 
+func TestWatchSrvKeyspace(t *testing.T) {
+	zktopo.WatchSleepDuration = 2 * time.Millisecond
+	ts := newFakeTeeServer(t)
+	test.CheckWatchSrvKeyspace(context.Background(), t, ts)
+}
+
+func TestWatchSrvShard(t *testing.T) {
+	zktopo.WatchSleepDuration = 2 * time.Millisecond
+	ts := newFakeTeeServer(t)
+	test.CheckWatchSrvShard(context.Background(), t, ts)
+}
+
 func TestShardReplication(t *testing.T) {
 	ctx := context.Background()
 	ts := newFakeTeeServer(t)
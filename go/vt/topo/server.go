@@ -278,6 +278,18 @@ type Server interface {
 	// in this cell. They shall be sorted.
 	GetSrvKeyspaceNames(ctx context.Context, cell string) ([]string, error)
 
+	// WatchSrvKeyspace returns a channel that receives notifications
+	// every time the SrvKeyspace for the given keyspace / cell changes.
+	// It has the same semantics as WatchEndPoints: a value of nil
+	// means the SrvKeyspace object doesn't exist, and the watch is
+	// stopped by closing the stopWatching channel.
+	WatchSrvKeyspace(ctx context.Context, cell, keyspace string) (notifications <-chan *SrvKeyspace, stopWatching chan<- struct{}, err error)
+
+	// WatchSrvShard returns a channel that receives notifications
+	// every time the SrvShard for the given keyspace / shard / cell
+	// changes. It has the same semantics as WatchEndPoints.
+	WatchSrvShard(ctx context.Context, cell, keyspace, shard string) (notifications <-chan *SrvShard, stopWatching chan<- struct{}, err error)
+
 	//
 	// Keyspace and Shard locks for actions, global.
 	//
@@ -312,6 +324,18 @@ type Schemafier interface {
 	GetVSchema(ctx context.Context) (string, error)
 }
 
+// VTGateQuotaStore is an optional interface for the Server
+// implementations that store the per-caller vtgate quotas, a JSON
+// document described in the vtgate/quota package.
+type VTGateQuotaStore interface {
+	// SaveVTGateQuotas validates and saves the vtgate quotas.
+	SaveVTGateQuotas(ctx context.Context, quotas string) error
+
+	// GetVTGateQuotas returns the vtgate quotas, or "{}" if
+	// they were never saved.
+	GetVTGateQuotas(ctx context.Context) (string, error)
+}
+
 // Registry for Server implementations.
 var serverImpls = make(map[string]Server)
 
@@ -207,6 +207,16 @@ func (ft FakeTopo) DeleteSrvKeyspace(ctx context.Context, cell, keyspace string)
 	return errNotImplemented
 }
 
+// WatchSrvKeyspace implements topo.Server.
+func (ft FakeTopo) WatchSrvKeyspace(ctx context.Context, cell, keyspace string) (<-chan *topo.SrvKeyspace, chan<- struct{}, error) {
+	return nil, nil, errNotImplemented
+}
+
+// WatchSrvShard implements topo.Server.
+func (ft FakeTopo) WatchSrvShard(ctx context.Context, cell, keyspace, shard string) (<-chan *topo.SrvShard, chan<- struct{}, error) {
+	return nil, nil, errNotImplemented
+}
+
 // LockKeyspaceForAction implements topo.Server.
 func (ft FakeTopo) LockKeyspaceForAction(ctx context.Context, keyspace, contents string) (string, error) {
 	return "", errNotImplemented
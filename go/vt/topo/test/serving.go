@@ -306,3 +306,191 @@ func CheckWatchEndPoints(ctx context.Context, t *testing.T, ts topo.Server) {
 		}
 	}
 }
+
+// CheckWatchSrvKeyspace makes sure WatchSrvKeyspace works as expected
+func CheckWatchSrvKeyspace(ctx context.Context, t *testing.T, ts topo.Server) {
+	cell := getLocalCell(ctx, t, ts)
+	keyspace := "test_keyspace"
+
+	// start watching, should get nil first
+	notifications, stopWatching, err := ts.WatchSrvKeyspace(ctx, cell, keyspace)
+	if err != nil {
+		t.Fatalf("WatchSrvKeyspace failed: %v", err)
+	}
+	sk, ok := <-notifications
+	if !ok || sk != nil {
+		t.Fatalf("first value is wrong: %v %v", sk, ok)
+	}
+
+	// update the SrvKeyspace, should get a notification
+	srvKeyspace := &topo.SrvKeyspace{
+		ShardingColumnName: "video_id",
+	}
+	if err := ts.UpdateSrvKeyspace(ctx, cell, keyspace, srvKeyspace); err != nil {
+		t.Fatalf("UpdateSrvKeyspace failed: %v", err)
+	}
+	for {
+		sk, ok := <-notifications
+		if !ok {
+			t.Fatalf("watch channel is closed???")
+		}
+		if sk == nil {
+			// duplicate notification of the first value, that's OK
+			continue
+		}
+		// non-empty value, that one should be ours
+		if sk.ShardingColumnName != "video_id" {
+			t.Fatalf("first value is wrong: %v %v", sk, ok)
+		}
+		break
+	}
+
+	// delete the SrvKeyspace, should get a notification
+	if err := ts.DeleteSrvKeyspace(ctx, cell, keyspace); err != nil {
+		t.Fatalf("DeleteSrvKeyspace failed: %v", err)
+	}
+	for {
+		sk, ok := <-notifications
+		if !ok {
+			t.Fatalf("watch channel is closed???")
+		}
+		if sk == nil {
+			break
+		}
+
+		// duplicate notification of the first value, that's OK,
+		// but value better be good.
+		if sk.ShardingColumnName != "video_id" {
+			t.Fatalf("duplicate notification value is bad: %v", sk)
+		}
+	}
+
+	// re-create the value, a bit different, should get a notification
+	srvKeyspace.ShardingColumnName = "user_id"
+	if err := ts.UpdateSrvKeyspace(ctx, cell, keyspace, srvKeyspace); err != nil {
+		t.Fatalf("UpdateSrvKeyspace failed: %v", err)
+	}
+	for {
+		sk, ok := <-notifications
+		if !ok {
+			t.Fatalf("watch channel is closed???")
+		}
+		if sk == nil {
+			// duplicate notification of the closed value, that's OK
+			continue
+		}
+		// non-empty value, that one should be ours
+		if sk.ShardingColumnName != "user_id" {
+			t.Fatalf("value after delete / re-create is wrong: %v %v", sk, ok)
+		}
+		break
+	}
+
+	// close the stopWatching channel, should eventually get a closed
+	// notifications channel too
+	close(stopWatching)
+	for {
+		sk, ok := <-notifications
+		if !ok {
+			break
+		}
+		if sk == nil || sk.ShardingColumnName != "user_id" {
+			t.Fatalf("duplicate notification value is bad: %v", sk)
+		}
+	}
+}
+
+// CheckWatchSrvShard makes sure WatchSrvShard works as expected
+func CheckWatchSrvShard(ctx context.Context, t *testing.T, ts topo.Server) {
+	cell := getLocalCell(ctx, t, ts)
+	keyspace := "test_keyspace"
+	shard := "-10"
+
+	// start watching, should get nil first
+	notifications, stopWatching, err := ts.WatchSrvShard(ctx, cell, keyspace, shard)
+	if err != nil {
+		t.Fatalf("WatchSrvShard failed: %v", err)
+	}
+	ss, ok := <-notifications
+	if !ok || ss != nil {
+		t.Fatalf("first value is wrong: %v %v", ss, ok)
+	}
+
+	// update the SrvShard, should get a notification
+	srvShard := &topo.SrvShard{
+		Name:       shard,
+		MasterCell: "test_cell",
+	}
+	if err := ts.UpdateSrvShard(ctx, cell, keyspace, shard, srvShard); err != nil {
+		t.Fatalf("UpdateSrvShard failed: %v", err)
+	}
+	for {
+		ss, ok := <-notifications
+		if !ok {
+			t.Fatalf("watch channel is closed???")
+		}
+		if ss == nil {
+			// duplicate notification of the first value, that's OK
+			continue
+		}
+		// non-empty value, that one should be ours
+		if ss.Name != shard || ss.MasterCell != "test_cell" {
+			t.Fatalf("first value is wrong: %v %v", ss, ok)
+		}
+		break
+	}
+
+	// delete the SrvShard, should get a notification
+	if err := ts.DeleteSrvShard(ctx, cell, keyspace, shard); err != nil {
+		t.Fatalf("DeleteSrvShard failed: %v", err)
+	}
+	for {
+		ss, ok := <-notifications
+		if !ok {
+			t.Fatalf("watch channel is closed???")
+		}
+		if ss == nil {
+			break
+		}
+
+		// duplicate notification of the first value, that's OK,
+		// but value better be good.
+		if ss.MasterCell != "test_cell" {
+			t.Fatalf("duplicate notification value is bad: %v", ss)
+		}
+	}
+
+	// re-create the value, a bit different, should get a notification
+	srvShard.MasterCell = "other_cell"
+	if err := ts.UpdateSrvShard(ctx, cell, keyspace, shard, srvShard); err != nil {
+		t.Fatalf("UpdateSrvShard failed: %v", err)
+	}
+	for {
+		ss, ok := <-notifications
+		if !ok {
+			t.Fatalf("watch channel is closed???")
+		}
+		if ss == nil {
+			// duplicate notification of the closed value, that's OK
+			continue
+		}
+		// non-empty value, that one should be ours
+		if ss.MasterCell != "other_cell" {
+			t.Fatalf("value after delete / re-create is wrong: %v %v", ss, ok)
+		}
+		break
+	}
+
+	// close the stopWatching channel, should eventually get a closed
+	// notifications channel too
+	close(stopWatching)
+	for {
+		ss, ok := <-notifications
+		if !ok {
+			break
+		}
+		if ss == nil || ss.MasterCell != "other_cell" {
+			t.Fatalf("duplicate notification value is bad: %v", ss)
+		}
+	}
+}
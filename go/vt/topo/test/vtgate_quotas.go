@@ -0,0 +1,50 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/youtube/vitess/go/vt/topo"
+	"golang.org/x/net/context"
+)
+
+// CheckVTGateQuotas runs the tests on the vtgate quotas part of the API
+func CheckVTGateQuotas(ctx context.Context, t *testing.T, ts topo.Server) {
+	store, ok := ts.(topo.VTGateQuotaStore)
+	if !ok {
+		t.Errorf("%T is not a VTGateQuotaStore", ts)
+		return
+	}
+	got, err := store.GetVTGateQuotas(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	want := "{}"
+	if got != want {
+		t.Errorf("GetVTGateQuotas: %s, want %s", got, want)
+	}
+
+	quotas := `{"Default": {"QPS": 100}, "Principals": {"batch": {"MaxConcurrent": 2}}}`
+	err = store.SaveVTGateQuotas(ctx, quotas)
+	if err != nil {
+		t.Error(err)
+	}
+
+	got, err = store.GetVTGateQuotas(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	if got != quotas {
+		t.Errorf("GetVTGateQuotas: %s, want %s", got, quotas)
+	}
+
+	err = store.SaveVTGateQuotas(ctx, "invalid")
+	want = "Unmarshal failed:"
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("SaveVTGateQuotas: %v, must start with %s", err, want)
+	}
+}
@@ -344,6 +344,12 @@ var commands = []commandGroup{
 			command{"ApplyVSchema", commandApplyVSchema,
 				"{-vschema=<vschema> || -vschema_file=<vschema file>}",
 				"Applies the VTGate routing schema."},
+			command{"GetVTGateQuotas", commandGetVTGateQuotas,
+				"",
+				"Displays the per-caller VTGate query quotas."},
+			command{"ApplyVTGateQuotas", commandApplyVTGateQuotas,
+				"{-quotas=<quotas> || -quotas_file=<quotas file>}",
+				"Applies the per-caller VTGate query quotas."},
 		},
 	},
 	commandGroup{
@@ -1951,6 +1957,51 @@ func commandApplyVSchema(ctx context.Context, wr *wrangler.Wrangler, subFlags *f
 	return schemafier.SaveVSchema(ctx, s)
 }
 
+func commandGetVTGateQuotas(ctx context.Context, wr *wrangler.Wrangler, subFlags *flag.FlagSet, args []string) error {
+	if err := subFlags.Parse(args); err != nil {
+		return err
+	}
+	if subFlags.NArg() != 0 {
+		return fmt.Errorf("The GetVTGateQuotas command does not support any arguments.")
+	}
+	ts := wr.TopoServer()
+	store, ok := ts.(topo.VTGateQuotaStore)
+	if !ok {
+		return fmt.Errorf("%T does not support the vtgate quotas operations", ts)
+	}
+	quotas, err := store.GetVTGateQuotas(ctx)
+	if err != nil {
+		return err
+	}
+	wr.Logger().Printf("%s\n", quotas)
+	return nil
+}
+
+func commandApplyVTGateQuotas(ctx context.Context, wr *wrangler.Wrangler, subFlags *flag.FlagSet, args []string) error {
+	quotas := subFlags.String("quotas", "", "Identifies the VTGate quotas")
+	quotasFile := subFlags.String("quotas_file", "", "Identifies the VTGate quotas file")
+	if err := subFlags.Parse(args); err != nil {
+		return err
+	}
+	if (*quotas == "") == (*quotasFile == "") {
+		return fmt.Errorf("Either the quotas or quotas_file flag must be specified when calling the ApplyVTGateQuotas command.")
+	}
+	ts := wr.TopoServer()
+	store, ok := ts.(topo.VTGateQuotaStore)
+	if !ok {
+		return fmt.Errorf("%T does not support the vtgate quotas operations", ts)
+	}
+	q := *quotas
+	if *quotasFile != "" {
+		data, err := ioutil.ReadFile(*quotasFile)
+		if err != nil {
+			return err
+		}
+		q = string(data)
+	}
+	return store.SaveVTGateQuotas(ctx, q)
+}
+
 func commandGetSrvKeyspace(ctx context.Context, wr *wrangler.Wrangler, subFlags *flag.FlagSet, args []string) error {
 	if err := subFlags.Parse(args); err != nil {
 		return err
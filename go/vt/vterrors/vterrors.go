@@ -20,6 +20,12 @@ const (
 	UnknownTabletError = 1999
 	// VtgateError is the base VTGate error code. All VTGate errors should be 4 digits, starting with 2.
 	VtgateError = 2000
+	// QuotaExceeded is the code of the queries VTGate rejects because their
+	// caller is over one of its quotas.
+	QuotaExceeded = 2001
+	// ResourceExhausted is the code of the queries VTGate aborts because
+	// they're over one of the limits of the resources they can use.
+	ResourceExhausted = 2002
 	// UnknownVtgateError is the code for an unknown error that came from VTGate.
 	UnknownVtgateError = 2999
 )
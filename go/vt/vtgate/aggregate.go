@@ -0,0 +1,339 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vtgate
+
+// This is a V3 file. Do not intermix with V2.
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+
+	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/sqltypes"
+	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
+)
+
+// combineAggregates combines the partial results returned by the shards
+// for an Aggregate plan. Rows that have the same GROUP BY key are combined
+// into one row. The result is then sorted, and the LIMIT of the plan is applied.
+// Like MySQL, the result is sorted by the GROUP BY columns if there's no ORDER BY.
+func combineAggregates(qr *mproto.QueryResult, plan *planbuilder.Plan, bindVars map[string]interface{}) (*mproto.QueryResult, error) {
+	if len(qr.Fields) == 0 {
+		return qr, nil
+	}
+	if err := checkGroupBy(plan.Aggregates, qr.Fields); err != nil {
+		return nil, err
+	}
+	var groups [][]sqltypes.Value
+	keys := make(map[string]int)
+	for _, row := range qr.Rows {
+		key := groupKey(plan.Aggregates, row)
+		i, ok := keys[key]
+		if !ok {
+			keys[key] = len(groups)
+			groups = append(groups, row)
+			continue
+		}
+		combined, err := combineRows(plan.Aggregates, qr.Fields, groups[i], row)
+		if err != nil {
+			return nil, err
+		}
+		groups[i] = combined
+	}
+
+	result := &mproto.QueryResult{
+		Fields: aggregateFields(plan.Aggregates, qr.Fields),
+	}
+	for _, group := range groups {
+		row, err := finalizeRow(plan.Aggregates, qr.Fields, group)
+		if err != nil {
+			return nil, err
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	orderBy := plan.OrderBy
+	if orderBy == nil {
+		for i, aggr := range plan.Aggregates {
+			if aggr.Func == planbuilder.AggrGroupBy {
+				orderBy = append(orderBy, planbuilder.OrderByCol{Index: i})
+			}
+		}
+	}
+	if err := sortRows(result, orderBy); err != nil {
+		return nil, err
+	}
+	offset, rowcount, err := resolveLimits(plan, bindVars)
+	if err != nil {
+		return nil, err
+	}
+	if offset >= int64(len(result.Rows)) {
+		result.Rows = nil
+	} else {
+		result.Rows = result.Rows[offset:]
+	}
+	if rowcount != -1 && rowcount < int64(len(result.Rows)) {
+		result.Rows = result.Rows[:rowcount]
+	}
+	result.RowsAffected = uint64(len(result.Rows))
+	return result, nil
+}
+
+// checkGroupBy makes sure that vtgate can tell which GROUP BY values
+// are equal. Like for ordering, this depends on the collation of the
+// non-binary strings, see hasExactOrder: 'A' and 'a' can be the same
+// group.
+func checkGroupBy(aggregates []planbuilder.AggregateCol, fields []mproto.Field) error {
+	for _, aggr := range aggregates {
+		if aggr.Func != planbuilder.AggrGroupBy {
+			continue
+		}
+		if !hasExactOrder(fields[aggr.Index]) {
+			return fmt.Errorf("cannot group by column %s: its values depend on its collation", fields[aggr.Index].Name)
+		}
+	}
+	return nil
+}
+
+// groupKey returns a string that uniquely identifies
+// the GROUP BY values of a shard row. The values are compared
+// byte-wise, see checkGroupBy.
+func groupKey(aggregates []planbuilder.AggregateCol, row []sqltypes.Value) string {
+	var buf bytes.Buffer
+	for _, aggr := range aggregates {
+		if aggr.Func != planbuilder.AggrGroupBy {
+			continue
+		}
+		val := row[aggr.Index]
+		if val.IsNull() {
+			buf.WriteByte('n')
+			continue
+		}
+		fmt.Fprintf(&buf, "%d:", len(val.Raw()))
+		buf.Write(val.Raw())
+	}
+	return buf.String()
+}
+
+// combineRows combines two shard rows that have the same GROUP BY key.
+func combineRows(aggregates []planbuilder.AggregateCol, fields []mproto.Field, left, right []sqltypes.Value) ([]sqltypes.Value, error) {
+	combined := make([]sqltypes.Value, len(left))
+	copy(combined, left)
+	var err error
+	for _, aggr := range aggregates {
+		i := aggr.Index
+		switch aggr.Func {
+		case planbuilder.AggrCount:
+			combined[i], err = addCounts(left[i], right[i])
+		case planbuilder.AggrSum:
+			combined[i], err = addSums(fields[i], left[i], right[i])
+		case planbuilder.AggrAvg:
+			if combined[i], err = addSums(fields[i], left[i], right[i]); err != nil {
+				break
+			}
+			combined[i+1], err = addCounts(left[i+1], right[i+1])
+		case planbuilder.AggrMin, planbuilder.AggrMax:
+			combined[i], err = minMax(aggr.Func, fields[i], left[i], right[i])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot combine %s values of column %s: %v", aggr.Func, fields[i].Name, err)
+		}
+	}
+	return combined, nil
+}
+
+// aggregateFields returns the fields of the final
+// result computed from the fields of the shard results.
+func aggregateFields(aggregates []planbuilder.AggregateCol, fields []mproto.Field) []mproto.Field {
+	result := make([]mproto.Field, 0, len(aggregates))
+	for _, aggr := range aggregates {
+		if aggr.Func != planbuilder.AggrAvg {
+			result = append(result, fields[aggr.Index])
+			continue
+		}
+		field := mproto.Field{Name: aggr.Name, Type: mproto.VT_NEWDECIMAL}
+		if isFloat(fields[aggr.Index]) {
+			field.Type = mproto.VT_DOUBLE
+		}
+		result = append(result, field)
+	}
+	return result
+}
+
+// finalizeRow converts a combined shard row into a result row.
+func finalizeRow(aggregates []planbuilder.AggregateCol, fields []mproto.Field, row []sqltypes.Value) ([]sqltypes.Value, error) {
+	result := make([]sqltypes.Value, 0, len(aggregates))
+	for _, aggr := range aggregates {
+		if aggr.Func != planbuilder.AggrAvg {
+			result = append(result, row[aggr.Index])
+			continue
+		}
+		val, err := average(fields[aggr.Index], row[aggr.Index], row[aggr.Index+1])
+		if err != nil {
+			return nil, fmt.Errorf("cannot compute %s: %v", aggr.Name, err)
+		}
+		result = append(result, val)
+	}
+	return result, nil
+}
+
+func addCounts(left, right sqltypes.Value) (sqltypes.Value, error) {
+	l, err := strconv.ParseInt(left.String(), 10, 64)
+	if err != nil {
+		return sqltypes.Value{}, err
+	}
+	r, err := strconv.ParseInt(right.String(), 10, 64)
+	if err != nil {
+		return sqltypes.Value{}, err
+	}
+	return sqltypes.MakeNumeric(strconv.AppendInt(nil, l+r, 10)), nil
+}
+
+// addSums adds two partial sums. Like in MySQL, the sum of float
+// values is a float, and all other sums are exact decimals.
+// NULL values are ignored.
+func addSums(field mproto.Field, left, right sqltypes.Value) (sqltypes.Value, error) {
+	switch {
+	case left.IsNull():
+		return right, nil
+	case right.IsNull():
+		return left, nil
+	}
+	if isFloat(field) {
+		l, err := strconv.ParseFloat(left.String(), 64)
+		if err != nil {
+			return sqltypes.Value{}, err
+		}
+		r, err := strconv.ParseFloat(right.String(), 64)
+		if err != nil {
+			return sqltypes.Value{}, err
+		}
+		return sqltypes.MakeFractional(strconv.AppendFloat(nil, l+r, 'g', -1, 64)), nil
+	}
+	l, err := parseDecimal(left)
+	if err != nil {
+		return sqltypes.Value{}, err
+	}
+	r, err := parseDecimal(right)
+	if err != nil {
+		return sqltypes.Value{}, err
+	}
+	scale := decimalScale(left)
+	if s := decimalScale(right); s > scale {
+		scale = s
+	}
+	return sqltypes.MakeFractional([]byte(l.Add(l, r).FloatString(scale))), nil
+}
+
+// minMax returns the smaller or larger of two values
+// depending on fun. NULL values are ignored.
+func minMax(fun string, field mproto.Field, left, right sqltypes.Value) (sqltypes.Value, error) {
+	switch {
+	case left.IsNull():
+		return right, nil
+	case right.IsNull():
+		return left, nil
+	}
+	cmp, err := compareValues(field, left, right)
+	if err != nil {
+		return sqltypes.Value{}, err
+	}
+	if (fun == planbuilder.AggrMin) == (cmp <= 0) {
+		return left, nil
+	}
+	return right, nil
+}
+
+// average computes an avg from its sum and count. Like MySQL,
+// the average of exact values has 4 more decimals than its sum.
+func average(field mproto.Field, sum, count sqltypes.Value) (sqltypes.Value, error) {
+	n, err := strconv.ParseInt(count.String(), 10, 64)
+	if err != nil {
+		return sqltypes.Value{}, err
+	}
+	if sum.IsNull() || n == 0 {
+		return sqltypes.NULL, nil
+	}
+	if isFloat(field) {
+		s, err := strconv.ParseFloat(sum.String(), 64)
+		if err != nil {
+			return sqltypes.Value{}, err
+		}
+		return sqltypes.MakeFractional(strconv.AppendFloat(nil, s/float64(n), 'g', -1, 64)), nil
+	}
+	s, err := parseDecimal(sum)
+	if err != nil {
+		return sqltypes.Value{}, err
+	}
+	avg := s.Quo(s, big.NewRat(n, 1))
+	return sqltypes.MakeFractional([]byte(avg.FloatString(decimalScale(sum) + 4))), nil
+}
+
+func isFloat(field mproto.Field) bool {
+	return field.Type == mproto.VT_FLOAT || field.Type == mproto.VT_DOUBLE
+}
+
+func isDecimal(field mproto.Field) bool {
+	return field.Type == mproto.VT_DECIMAL || field.Type == mproto.VT_NEWDECIMAL
+}
+
+func parseDecimal(val sqltypes.Value) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(val.String())
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal value: %s", val.String())
+	}
+	return r, nil
+}
+
+// decimalScale returns the number of digits after the decimal point.
+func decimalScale(val sqltypes.Value) int {
+	raw := val.Raw()
+	if i := bytes.IndexByte(raw, '.'); i != -1 {
+		return len(raw) - i - 1
+	}
+	return 0
+}
+
+// sortRows sorts the rows of qr by orderBy.
+func sortRows(qr *mproto.QueryResult, orderBy []planbuilder.OrderByCol) error {
+	if len(orderBy) == 0 {
+		return nil
+	}
+	cols, err := resolveOrderBy(orderBy, qr.Fields)
+	if err != nil {
+		return err
+	}
+	sorter := &rowSorter{cols: cols, rows: qr.Rows}
+	sort.Stable(sorter)
+	return sorter.err
+}
+
+// rowSorter sorts rows by the sort columns. It satisfies
+// sort.Interface. Comparison errors are saved in err.
+type rowSorter struct {
+	cols []sortColumn
+	rows [][]sqltypes.Value
+	err  error
+}
+
+func (rs *rowSorter) Len() int {
+	return len(rs.rows)
+}
+
+func (rs *rowSorter) Less(i, j int) bool {
+	cmp, err := compareRows(rs.cols, rs.rows[i], rs.rows[j])
+	if err != nil {
+		rs.err = err
+		return false
+	}
+	return cmp < 0
+}
+
+func (rs *rowSorter) Swap(i, j int) {
+	rs.rows[i], rs.rows[j] = rs.rows[j], rs.rows[i]
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	log "github.com/golang/glog"
+	pb "github.com/youtube/vitess/go/vt/proto/query"
 	"github.com/youtube/vitess/go/vt/topo"
 )
 
@@ -31,6 +32,11 @@ type Balancer struct {
 	getEndPoints       GetEndPointsFunc
 	retryDelay         time.Duration
 	resetDownConnDelay time.Duration
+
+	// healthCache is only set for the replica and rdonly
+	// tablets, if their health stream is enabled.
+	healthCache       *HealthCache
+	maxReplicationLag time.Duration
 }
 
 type addressStatus struct {
@@ -52,6 +58,17 @@ func NewBalancer(getEndPoints GetEndPointsFunc, retryDelay time.Duration) *Balan
 	return blc
 }
 
+// NewHealthBalancer creates a Balancer like NewBalancer, which also
+// uses the health of the tablets from healthCache. Get excludes the
+// unhealthy tablets, and the ones lagging more than maxReplicationLag,
+// unless no other tablet is available.
+func NewHealthBalancer(getEndPoints GetEndPointsFunc, retryDelay time.Duration, healthCache *HealthCache, maxReplicationLag time.Duration) *Balancer {
+	blc := NewBalancer(getEndPoints, retryDelay)
+	blc.healthCache = healthCache
+	blc.maxReplicationLag = maxReplicationLag
+	return blc
+}
+
 // Get returns a single endpoint that was not recently marked down.
 // If it finds an address that was down for longer than retryDelay,
 // it refreshes the list of addresses and returns the next available
@@ -84,10 +101,125 @@ func (blc *Balancer) Get() (endPoints []topo.EndPoint, err error) {
 		}
 		break
 	}
+	if blc.healthCache != nil {
+		validEndPoints = blc.filterByHealth(validEndPoints)
+	}
 
 	return validEndPoints, nil
 }
 
+// filterByHealth returns the endpoints that are healthy and lag less
+// than maxReplicationLag, or the ones with an unknown health. They're
+// shuffled so that the tablets serving less qps are more likely first.
+// If there are none, it returns the lagging endpoints, from the least
+// lagging. If all are unhealthy, it returns endPoints unchanged, in
+// case the health check is wrong.
+func (blc *Balancer) filterByHealth(endPoints []topo.EndPoint) []topo.EndPoint {
+	var fresh, lagged []topo.EndPoint
+	var freshStats, laggedStats []*pb.RealtimeStats
+	for _, endPoint := range endPoints {
+		stats := blc.healthCache.Get(endPoint)
+		switch {
+		case stats == nil:
+			fresh = append(fresh, endPoint)
+			freshStats = append(freshStats, nil)
+		case stats.HealthError != "":
+		case time.Duration(stats.SecondsBehindMaster)*time.Second > blc.maxReplicationLag:
+			lagged = append(lagged, endPoint)
+			laggedStats = append(laggedStats, stats)
+		default:
+			fresh = append(fresh, endPoint)
+			freshStats = append(freshStats, stats)
+		}
+	}
+	if len(fresh) != 0 {
+		shuffleByQPS(fresh, freshStats)
+		return fresh
+	}
+	if len(lagged) != 0 {
+		sort.Sort(byReplicationLag{lagged, laggedStats})
+		return lagged
+	}
+	return endPoints
+}
+
+// shuffleByQPS shuffles endPoints, so that the ones serving less qps
+// are more likely first. The weight of an endpoint is 1/(qps+avg+1),
+// where avg is the average qps: the damping by avg prevents an idle
+// tablet from getting all the new traffic at once. The endpoints with
+// unknown stats count as serving the average qps.
+func shuffleByQPS(endPoints []topo.EndPoint, stats []*pb.RealtimeStats) {
+	var total float64
+	var known int
+	for _, s := range stats {
+		if s != nil {
+			total += s.Qps
+			known++
+		}
+	}
+	var avg float64
+	if known != 0 {
+		avg = total / float64(known)
+	}
+	weights := make([]float64, len(endPoints))
+	for i, s := range stats {
+		qps := avg
+		if s != nil {
+			qps = s.Qps
+		}
+		weights[i] = 1 / (qps + avg + 1)
+	}
+	for i := 0; i < len(endPoints)-1; i++ {
+		var sum float64
+		for _, w := range weights[i:] {
+			sum += w
+		}
+		pick := len(endPoints) - 1
+		r := rand.Float64() * sum
+		for j := i; j < len(endPoints); j++ {
+			r -= weights[j]
+			if r < 0 {
+				pick = j
+				break
+			}
+		}
+		endPoints[i], endPoints[pick] = endPoints[pick], endPoints[i]
+		weights[i], weights[pick] = weights[pick], weights[i]
+	}
+}
+
+// byReplicationLag sorts endpoints from the least lagging.
+type byReplicationLag struct {
+	endPoints []topo.EndPoint
+	stats     []*pb.RealtimeStats
+}
+
+func (bl byReplicationLag) Len() int {
+	return len(bl.endPoints)
+}
+
+func (bl byReplicationLag) Swap(i, j int) {
+	bl.endPoints[i], bl.endPoints[j] = bl.endPoints[j], bl.endPoints[i]
+	bl.stats[i], bl.stats[j] = bl.stats[j], bl.stats[i]
+}
+
+func (bl byReplicationLag) Less(i, j int) bool {
+	return bl.stats[i].SecondsBehindMaster < bl.stats[j].SecondsBehindMaster
+}
+
+// Close stops watching the health of the endpoints.
+func (blc *Balancer) Close() {
+	blc.mu.Lock()
+	defer blc.mu.Unlock()
+	if blc.healthCache == nil {
+		return
+	}
+	for _, addrNode := range blc.addressNodes {
+		blc.healthCache.Unwatch(addrNode.endPoint)
+	}
+	blc.addressNodes = nil
+}
+
 // MarkDown marks the specified address down. Such addresses
 // will not be used by Balancer for the duration of retryDelay.
 func (blc *Balancer) MarkDown(uid uint32, reason string) {
@@ -113,7 +245,12 @@ func (blc *Balancer) refresh() error {
 					balancer: blc,
 				}
 				blc.addressNodes = append(blc.addressNodes, addrNode)
+				blc.watch(endPoint)
 			} else {
+				if endPointKey(blc.addressNodes[index].endPoint) != endPointKey(endPoint) {
+					blc.unwatch(blc.addressNodes[index].endPoint)
+					blc.watch(endPoint)
+				}
 				blc.addressNodes[index].endPoint = endPoint
 			}
 		}
@@ -122,6 +259,7 @@ func (blc *Balancer) refresh() error {
 	i := 0
 	for i < len(blc.addressNodes) {
 		if index := findAddress(endPoints, blc.addressNodes[i].endPoint.Uid); index == -1 {
+			blc.unwatch(blc.addressNodes[i].endPoint)
 			blc.addressNodes = delAddrNode(blc.addressNodes, i)
 			continue
 		}
@@ -137,6 +275,18 @@ func (blc *Balancer) refresh() error {
 	return nil
 }
 
+func (blc *Balancer) watch(endPoint topo.EndPoint) {
+	if blc.healthCache != nil {
+		blc.healthCache.Watch(endPoint)
+	}
+}
+
+func (blc *Balancer) unwatch(endPoint topo.EndPoint) {
+	if blc.healthCache != nil {
+		blc.healthCache.Unwatch(endPoint)
+	}
+}
+
 // AddressList is the slice of addressStatus.
 type AddressList []*addressStatus
 
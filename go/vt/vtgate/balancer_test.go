@@ -9,7 +9,10 @@ import (
 	"testing"
 	"time"
 
+	pb "github.com/youtube/vitess/go/vt/proto/query"
+	"github.com/youtube/vitess/go/vt/tabletserver/tabletconn"
 	"github.com/youtube/vitess/go/vt/topo"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -246,3 +249,75 @@ func TestRefresh(t *testing.T) {
 		t.Errorf("want 12, got %v", portNew)
 	}
 }
+
+// blockingDial never connects, so that the tests control the
+// content of the HealthCache.
+func blockingDial(ctx context.Context, endPoint topo.EndPoint, keyspace, shard string, timeout time.Duration) (tabletconn.TabletConn, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestHealthBalancer(t *testing.T) {
+	hc := NewHealthCache(blockingDial, time.Hour, time.Hour)
+	b := NewHealthBalancer(endPoints3, RetryDelay, hc, 10*time.Second)
+	defer b.Close()
+
+	// The health of all the tablets is unknown.
+	endPoints, err := b.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(endPoints) != 3 {
+		t.Errorf("want 3, got %d", len(endPoints))
+	}
+
+	updateHealth(hc, "0/0", &pb.RealtimeStats{SecondsBehindMaster: 20})
+	updateHealth(hc, "1/1", &pb.RealtimeStats{SecondsBehindMaster: 5})
+	updateHealth(hc, "2/2", &pb.RealtimeStats{HealthError: "not healthy"})
+	endPoints, _ = b.Get()
+	if len(endPoints) != 1 || endPoints[0].Uid != 1 {
+		t.Errorf("want [1], got %+v", endPoints)
+	}
+
+	// Lagging tablets are used if no other tablet is available,
+	// from the least lagging.
+	updateHealth(hc, "1/1", &pb.RealtimeStats{SecondsBehindMaster: 30})
+	endPoints, _ = b.Get()
+	if len(endPoints) != 2 || endPoints[0].Uid != 0 || endPoints[1].Uid != 1 {
+		t.Errorf("want [0 1], got %+v", endPoints)
+	}
+
+	// All the tablets are returned if all are unhealthy.
+	updateHealth(hc, "0/0", &pb.RealtimeStats{HealthError: "not healthy"})
+	updateHealth(hc, "1/1", &pb.RealtimeStats{HealthError: "not healthy"})
+	endPoints, _ = b.Get()
+	if len(endPoints) != 3 {
+		t.Errorf("want 3, got %d", len(endPoints))
+	}
+
+	b.Close()
+	if len(hc.tablets) != 0 {
+		t.Errorf("tablets are still watched: %v", hc.tablets)
+	}
+}
+
+func TestHealthBalancerQPS(t *testing.T) {
+	hc := NewHealthCache(blockingDial, time.Hour, time.Hour)
+	b := NewHealthBalancer(endPoints3, RetryDelay, hc, 10*time.Second)
+	defer b.Close()
+	b.Get()
+
+	updateHealth(hc, "0/0", &pb.RealtimeStats{Qps: 1000})
+	updateHealth(hc, "1/1", &pb.RealtimeStats{Qps: 1000})
+	updateHealth(hc, "2/2", &pb.RealtimeStats{Qps: 0})
+	firsts := make(map[uint32]int)
+	for i := 0; i < 1000; i++ {
+		endPoints, _ := b.Get()
+		firsts[endPoints[0].Uid]++
+	}
+	// The weight of 2 is 2.5 times the weight of the others,
+	// so it should be first more than half of the time.
+	if firsts[2] < 350 || firsts[2] > 650 {
+		t.Errorf("firsts: %v, want about 500 for uid 2", firsts)
+	}
+}
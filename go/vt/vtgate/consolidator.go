@@ -0,0 +1,97 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vtgate
+
+// This is a V3 file. Do not intermix with V2.
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+
+	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/stats"
+	"github.com/youtube/vitess/go/vt/callerid"
+	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
+	"github.com/youtube/vitess/go/vt/vtgate/proto"
+)
+
+var (
+	consolidateReads = flag.Bool("vtgate_consolidate_reads", false, "if true, concurrent identical reads outside of a transaction share one execution and its result in the V3 router")
+
+	consolidationWaits = stats.NewTimings("VtgateConsolidationWaits")
+)
+
+// isRead returns true if the plan only reads rows. The sub-plans
+// of a join are reads too.
+func isRead(plan *planbuilder.Plan) bool {
+	return plan.ID >= planbuilder.SelectUnsharded && plan.ID <= planbuilder.SelectLeftJoin
+}
+
+// canConsolidate returns true if the query may share the result of
+// an identical query. Reads in a transaction or in a read-your-writes
+// session depend on the session state, and are never shared.
+func canConsolidate(query *proto.Query) bool {
+	return query.Session == nil || !(query.Session.InTransaction || query.Session.ReadYourWrites)
+}
+
+// consolidationKey identifies the queries that can share their
+// results: they have the same plan, bind variables, tablet type,
+// keyspace, staleness bound and caller.
+func consolidationKey(vcursor *requestContext, plan *planbuilder.Plan) string {
+	keyspace := ""
+	if plan.Table != nil {
+		keyspace = plan.Table.Keyspace.Name
+	}
+	var maxStaleness int64
+	if vcursor.query.Session != nil {
+		maxStaleness = vcursor.query.Session.MaxStalenessSeconds
+	}
+	caller := callerid.GetPrincipal(callerid.EffectiveCallerIDFromContext(vcursor.ctx))
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "%q %d %s %s %s {", caller, maxStaleness, keyspace, vcursor.query.TabletType, plan.Original)
+	names := make([]string, 0, len(vcursor.query.BindVariables))
+	for name := range vcursor.query.BindVariables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(buf, "%s: %#v, ", name, vcursor.query.BindVariables[name])
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
+
+// executeConsolidated executes plan like execute, but if an identical
+// read is already executing, it waits for it and returns its result
+// instead. The result is shared, and must not be modified. A waiter
+// whose context is done stops waiting and returns the context error.
+func (rtr *Router) executeConsolidated(vcursor *requestContext, plan *planbuilder.Plan) (*mproto.QueryResult, error) {
+	q, created := rtr.consolidator.Create(consolidationKey(vcursor, plan))
+	if created {
+		defer q.Broadcast()
+		q.Result, q.Err = rtr.execute(vcursor, plan)
+	} else {
+		startTime := time.Now()
+		done := make(chan struct{})
+		go func() {
+			q.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-vcursor.ctx.Done():
+			return nil, vcursor.ctx.Err()
+		}
+		consolidationWaits.Record("Execute", startTime)
+	}
+	if q.Err != nil {
+		return nil, q.Err
+	}
+	qr, _ := q.Result.(*mproto.QueryResult)
+	return qr, nil
+}
@@ -0,0 +1,165 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vtgate
+
+// This is a V3 file. Do not intermix with V2.
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/youtube/vitess/go/vt/key"
+	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
+	"github.com/youtube/vitess/go/vt/vtgate/proto"
+	"golang.org/x/net/context"
+)
+
+// Explain returns how a query is routed for its bind variables.
+// The query is not executed, but vindexes may read their lookup
+// tables to compute the target shards.
+func (rtr *Router) Explain(ctx context.Context, query *proto.Query) (*proto.QueryPlan, error) {
+	if query.BindVariables == nil {
+		query.BindVariables = make(map[string]interface{})
+	}
+	vcursor := newRequestContext(ctx, query, rtr)
+	plan := rtr.planner.GetPlan(string(query.Sql))
+	return rtr.explain(vcursor, plan)
+}
+
+// explain describes a plan, including the shards it targets.
+// For a join, only the shards of the left side can be computed.
+func (rtr *Router) explain(vcursor *requestContext, plan *planbuilder.Plan) (*proto.QueryPlan, error) {
+	qp := newQueryPlan(plan)
+	var err error
+	var params *scatterParams
+	switch plan.ID {
+	case planbuilder.SelectJoin, planbuilder.SelectLeftJoin:
+		if qp.Left, err = rtr.explain(vcursor, plan.Left); err != nil {
+			return nil, err
+		}
+		return qp, nil
+	case planbuilder.SelectUnsharded, planbuilder.UpdateUnsharded,
+		planbuilder.DeleteUnsharded, planbuilder.InsertUnsharded:
+		params, err = rtr.paramsUnsharded(vcursor, plan)
+	case planbuilder.SelectReference:
+		params, err = rtr.paramsSelectReference(vcursor, plan)
+	case planbuilder.SelectEqual, planbuilder.SelectEqualAggregate:
+		params, err = rtr.paramsSelectEqual(vcursor, plan)
+	case planbuilder.SelectIN, planbuilder.SelectINAggregate,
+		planbuilder.UpdateIN, planbuilder.DeleteIN:
+		params, err = rtr.paramsSelectIN(vcursor, plan)
+	case planbuilder.SelectKeyrange:
+		params, err = rtr.paramsSelectKeyrange(vcursor, plan)
+	case planbuilder.SelectScatter, planbuilder.SelectScatterAggregate:
+		params, err = rtr.paramsSelectScatter(vcursor, plan)
+	case planbuilder.SelectRange, planbuilder.SelectRangeAggregate:
+		params, err = rtr.paramsSelectRange(vcursor, plan)
+	case planbuilder.SelectEqualMerge, planbuilder.SelectINMerge,
+		planbuilder.SelectScatterMerge, planbuilder.SelectRangeMerge:
+		params, err = rtr.paramsSelectMerge(vcursor, plan)
+	case planbuilder.UpdateScatter, planbuilder.DeleteScatter:
+		params, err = rtr.paramsScatterDML(vcursor, plan)
+	case planbuilder.UpdateEqual, planbuilder.DeleteEqual:
+		err = rtr.explainEqualDML(vcursor, plan, qp)
+	case planbuilder.InsertSharded:
+		err = rtr.explainInsertSharded(vcursor, plan, qp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if params != nil {
+		qp.Rewritten = params.query
+		qp.Keyspace = params.ks
+		for shard := range params.shardVars {
+			qp.Shards = append(qp.Shards, shard)
+		}
+		sort.Strings(qp.Shards)
+	}
+	return qp, nil
+}
+
+func (rtr *Router) explainEqualDML(vcursor *requestContext, plan *planbuilder.Plan, qp *proto.QueryPlan) error {
+	keys, err := rtr.resolveKeys([]interface{}{plan.Values}, vcursor.query.BindVariables)
+	if err != nil {
+		return fmt.Errorf("explainEqualDML: %v", err)
+	}
+	ks, shard, ksid, err := rtr.resolveSingleShard(vcursor, keys[0], plan)
+	if err != nil {
+		return fmt.Errorf("explainEqualDML: %v", err)
+	}
+	if ksid == key.MinKey {
+		return nil
+	}
+	qp.Rewritten = plan.Rewritten + fmt.Sprintf(dmlPostfix, ksid)
+	qp.Keyspace = ks
+	qp.Shards = []string{shard}
+	return nil
+}
+
+// explainInsertSharded computes the shards of the rows of an insert
+// from the values of their primary vindex column. Rows whose value
+// is generated by VTGate, or not yet known to the vindex, are skipped
+// because their shard is only known after the vindex entries are created.
+func (rtr *Router) explainInsertSharded(vcursor *requestContext, plan *planbuilder.Plan, qp *proto.QueryPlan) error {
+	ks, _, allShards, err := getKeyspaceShards(vcursor.ctx, rtr.serv, rtr.cell, plan.Table.Keyspace.Name, vcursor.query.TabletType)
+	if err != nil {
+		return fmt.Errorf("explainInsertSharded: %v", err)
+	}
+	mapper := plan.Table.ColVindexes[0].Vindex.(planbuilder.Unique)
+	uniqueShards := make(map[string]bool)
+	for _, row := range plan.Values.([]interface{}) {
+		keys, err := rtr.resolveKeys(row.([]interface{})[:1], vcursor.query.BindVariables)
+		if err != nil {
+			return fmt.Errorf("explainInsertSharded: %v", err)
+		}
+		if keys[0] == nil {
+			continue
+		}
+		ksids, err := mapper.Map(vcursor, keys)
+		if err != nil {
+			return fmt.Errorf("explainInsertSharded: %v", err)
+		}
+		if ksids[0] == key.MinKey {
+			continue
+		}
+		shard, err := getShardForKeyspaceId(allShards, ksids[0])
+		if err != nil {
+			return fmt.Errorf("explainInsertSharded: %v", err)
+		}
+		uniqueShards[shard] = true
+	}
+	qp.Keyspace = ks
+	for shard := range uniqueShards {
+		qp.Shards = append(qp.Shards, shard)
+	}
+	sort.Strings(qp.Shards)
+	return nil
+}
+
+// newQueryPlan describes a plan without its shards.
+func newQueryPlan(plan *planbuilder.Plan) *proto.QueryPlan {
+	if plan == nil {
+		return nil
+	}
+	qp := &proto.QueryPlan{
+		PlanID:    plan.ID.String(),
+		Reason:    plan.Reason,
+		Rewritten: plan.Rewritten,
+		Left:      newQueryPlan(plan.Left),
+		Right:     newQueryPlan(plan.Right),
+	}
+	if plan.Table != nil {
+		qp.Table = plan.Table.Name
+		if plan.Table.Keyspace != nil {
+			qp.Keyspace = plan.Table.Keyspace.Name
+		}
+	}
+	if plan.ColVindex != nil {
+		qp.Col = strings.Join(plan.ColVindex.Columns(), ",")
+		qp.Vindex = plan.ColVindex.Name
+	}
+	return qp
+}
@@ -278,6 +278,16 @@ func (conn *FakeVTGateConn) SplitQuery(ctx context.Context, keyspace string, que
 	return reply, nil
 }
 
+// SplitQueryV2 please see vtgateconn.Impl.SplitQueryV2
+func (conn *FakeVTGateConn) SplitQueryV2(ctx context.Context, keyspace string, query tproto.BoundQuery, splitColumns []string, splitCount int, numRowsPerQueryPart int64, tabletType topo.TabletType) ([]proto.SplitQueryPart, error) {
+	panic("not implemented")
+}
+
+// ExplainQuery please see vtgateconn.Impl.ExplainQuery
+func (conn *FakeVTGateConn) ExplainQuery(ctx context.Context, query string, bindVars map[string]interface{}, tabletType topo.TabletType) (*proto.QueryPlan, error) {
+	panic("not implemented")
+}
+
 // Close please see vtgateconn.Impl.Close
 func (conn *FakeVTGateConn) Close() {
 }
@@ -334,6 +334,9 @@ func (conn *vtgateConn) Commit2(ctx context.Context, session interface{}) error
 	if err := conn.rpcConn.Call(ctx, "VTGate.Commit2", request, reply); err != nil {
 		return err
 	}
+	if reply.Session != nil {
+		*s = *reply.Session
+	}
 	return vterrors.FromRPCError(reply.Err)
 }
 
@@ -366,6 +369,41 @@ func (conn *vtgateConn) SplitQuery(ctx context.Context, keyspace string, query t
 	return result.Splits, nil
 }
 
+func (conn *vtgateConn) SplitQueryV2(ctx context.Context, keyspace string, query tproto.BoundQuery, splitColumns []string, splitCount int, numRowsPerQueryPart int64, tabletType topo.TabletType) ([]proto.SplitQueryPart, error) {
+	request := &proto.SplitQueryV2Request{
+		Keyspace:            keyspace,
+		Query:               query,
+		SplitColumns:        splitColumns,
+		SplitCount:          splitCount,
+		NumRowsPerQueryPart: numRowsPerQueryPart,
+		TabletType:          tabletType,
+	}
+	result := &proto.SplitQueryResult{}
+	if err := conn.rpcConn.Call(ctx, "VTGate.SplitQueryV2", request, result); err != nil {
+		return nil, err
+	}
+	if err := vterrors.FromRPCError(result.Err); err != nil {
+		return nil, err
+	}
+	return result.Splits, nil
+}
+
+func (conn *vtgateConn) ExplainQuery(ctx context.Context, query string, bindVars map[string]interface{}, tabletType topo.TabletType) (*proto.QueryPlan, error) {
+	request := &proto.Query{
+		Sql:           query,
+		BindVariables: bindVars,
+		TabletType:    tabletType,
+	}
+	result := &proto.ExplainQueryResult{}
+	if err := conn.rpcConn.Call(ctx, "VTGate.ExplainQuery", request, result); err != nil {
+		return nil, err
+	}
+	if err := vterrors.FromRPCError(result.Err); err != nil {
+		return nil, err
+	}
+	return result.Plan, nil
+}
+
 func (conn *vtgateConn) Close() {
 	conn.rpcConn.Close()
 }
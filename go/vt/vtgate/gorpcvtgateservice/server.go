@@ -198,6 +198,9 @@ func (vtg *VTGate) Commit2(ctx context.Context, request *proto.CommitRequest, re
 	defer cancel()
 	vtgErr := vtg.server.Commit(ctx, request.Session)
 	vtgate.AddVtGateErrorToCommitResponse(vtgErr, reply)
+	if request.Session != nil && request.Session.ReadYourWrites {
+		reply.Session = request.Session
+	}
 	if *vtgate.RPCErrorOnlyInReply {
 		return nil
 	}
@@ -230,6 +233,32 @@ func (vtg *VTGate) SplitQuery(ctx context.Context, req *proto.SplitQueryRequest,
 	return vtgErr
 }
 
+// SplitQueryV2 is the RPC version of vtgateservice.VTGateService method
+func (vtg *VTGate) SplitQueryV2(ctx context.Context, req *proto.SplitQueryV2Request, reply *proto.SplitQueryResult) (err error) {
+	defer vtg.server.HandlePanic(&err)
+	ctx, cancel := context.WithDeadline(ctx, time.Now().Add(*rpcTimeout))
+	defer cancel()
+	vtgErr := vtg.server.SplitQueryV2(ctx, req, reply)
+	vtgate.AddVtGateErrorToSplitQueryResult(vtgErr, reply)
+	if *vtgate.RPCErrorOnlyInReply {
+		return nil
+	}
+	return vtgErr
+}
+
+// ExplainQuery is the RPC version of vtgateservice.VTGateService method
+func (vtg *VTGate) ExplainQuery(ctx context.Context, query *proto.Query, reply *proto.ExplainQueryResult) (err error) {
+	defer vtg.server.HandlePanic(&err)
+	ctx, cancel := context.WithDeadline(ctx, time.Now().Add(*rpcTimeout))
+	defer cancel()
+	vtgErr := vtg.server.ExplainQuery(ctx, query, reply)
+	vtgate.AddVtGateErrorToExplainQueryResult(vtgErr, reply)
+	if *vtgate.RPCErrorOnlyInReply {
+		return nil
+	}
+	return vtgErr
+}
+
 // New returns a new VTGate service
 func New(vtGate vtgateservice.VTGateService) *VTGate {
 	return &VTGate{vtGate}
@@ -356,13 +356,17 @@ func (conn *vtgateConn) Begin(ctx context.Context) (interface{}, error) {
 }
 
 func (conn *vtgateConn) Commit(ctx context.Context, session interface{}) error {
+	s := session.(*pb.Session)
 	request := &pb.CommitRequest{
-		Session: session.(*pb.Session),
+		Session: s,
 	}
 	response, err := conn.c.Commit(ctx, request)
 	if err != nil {
 		return err
 	}
+	if response.Session != nil {
+		*s = *response.Session
+	}
 	if response.Error != nil {
 		return vterrors.FromVtRPCError(response.Error)
 	}
@@ -409,6 +413,39 @@ func (conn *vtgateConn) SplitQuery(ctx context.Context, keyspace string, query t
 	return proto.ProtoToSplitQueryParts(response), nil
 }
 
+func (conn *vtgateConn) SplitQueryV2(ctx context.Context, keyspace string, query tproto.BoundQuery, splitColumns []string, splitCount int, numRowsPerQueryPart int64, tabletType topo.TabletType) ([]proto.SplitQueryPart, error) {
+	request := &pb.SplitQueryV2Request{
+		Keyspace:            keyspace,
+		Query:               tproto.BoundQueryToProto3(query.Sql, query.BindVariables),
+		SplitColumns:        splitColumns,
+		SplitCount:          int64(splitCount),
+		NumRowsPerQueryPart: numRowsPerQueryPart,
+	}
+	if tabletType != "" {
+		request.TabletType = topo.TabletTypeToProto(tabletType)
+	}
+	response, err := conn.c.SplitQueryV2(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return proto.ProtoToSplitQueryParts(response), nil
+}
+
+func (conn *vtgateConn) ExplainQuery(ctx context.Context, query string, bindVars map[string]interface{}, tabletType topo.TabletType) (*proto.QueryPlan, error) {
+	request := &pb.ExplainQueryRequest{
+		Query:      tproto.BoundQueryToProto3(query, bindVars),
+		TabletType: topo.TabletTypeToProto(tabletType),
+	}
+	response, err := conn.c.ExplainQuery(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, vterrors.FromVtRPCError(response.Error)
+	}
+	return proto.ProtoToQueryPlan(response.Plan), nil
+}
+
 func (conn *vtgateConn) Close() {
 	conn.cc.Close()
 }
@@ -18,6 +18,7 @@ import (
 	"github.com/youtube/vitess/go/vt/vtgate/vtgateservice"
 	"golang.org/x/net/context"
 
+	pbt "github.com/youtube/vitess/go/vt/proto/topodata"
 	pb "github.com/youtube/vitess/go/vt/proto/vtgate"
 	pbs "github.com/youtube/vitess/go/vt/proto/vtgateservice"
 )
@@ -312,10 +313,14 @@ func (vtg *VTGate) Begin(ctx context.Context, request *pb.BeginRequest) (respons
 // Commit is the RPC version of vtgateservice.VTGateService method
 func (vtg *VTGate) Commit(ctx context.Context, request *pb.CommitRequest) (response *pb.CommitResponse, err error) {
 	defer vtg.server.HandlePanic(&err)
-	commitErr := vtg.server.Commit(ctx, proto.ProtoToSession(request.Session))
+	session := proto.ProtoToSession(request.Session)
+	commitErr := vtg.server.Commit(ctx, session)
 	response = &pb.CommitResponse{
 		Error: vtgate.VtGateErrorToVtRPCError(commitErr, ""),
 	}
+	if session != nil && session.ReadYourWrites {
+		response.Session = proto.SessionToProto(session)
+	}
 	if commitErr == nil {
 		return response, nil
 	}
@@ -361,6 +366,52 @@ func (vtg *VTGate) SplitQuery(ctx context.Context, request *pb.SplitQueryRequest
 	return proto.SplitQueryPartsToProto(reply.Splits), nil
 }
 
+// SplitQueryV2 is the RPC version of vtgateservice.VTGateService method
+func (vtg *VTGate) SplitQueryV2(ctx context.Context, request *pb.SplitQueryV2Request) (response *pb.SplitQueryResponse, err error) {
+	defer vtg.server.HandlePanic(&err)
+	query := &proto.SplitQueryV2Request{
+		Keyspace: request.Keyspace,
+		Query: tproto.BoundQuery{
+			Sql:           string(request.Query.Sql),
+			BindVariables: tproto.Proto3ToBindVariables(request.Query.BindVariables),
+		},
+		SplitColumns:        request.SplitColumns,
+		SplitCount:          int(request.SplitCount),
+		NumRowsPerQueryPart: request.NumRowsPerQueryPart,
+	}
+	if request.TabletType != pbt.TabletType_UNKNOWN {
+		query.TabletType = topo.ProtoToTabletType(request.TabletType)
+	}
+	reply := new(proto.SplitQueryResult)
+	if err := vtg.server.SplitQueryV2(ctx, query, reply); err != nil {
+		return nil, err
+	}
+	return proto.SplitQueryPartsToProto(reply.Splits), nil
+}
+
+// ExplainQuery is the RPC version of vtgateservice.VTGateService method
+func (vtg *VTGate) ExplainQuery(ctx context.Context, request *pb.ExplainQueryRequest) (response *pb.ExplainQueryResponse, err error) {
+	defer vtg.server.HandlePanic(&err)
+	query := &proto.Query{
+		Sql:           string(request.Query.Sql),
+		BindVariables: tproto.Proto3ToBindVariables(request.Query.BindVariables),
+		TabletType:    topo.ProtoToTabletType(request.TabletType),
+	}
+	reply := new(proto.ExplainQueryResult)
+	explainErr := vtg.server.ExplainQuery(ctx, query, reply)
+	response = &pb.ExplainQueryResponse{
+		Error: vtgate.VtGateErrorToVtRPCError(explainErr, ""),
+	}
+	if explainErr == nil {
+		response.Plan = proto.QueryPlanToProto(reply.Plan)
+		return response, nil
+	}
+	if *vtgate.RPCErrorOnlyInReply {
+		return response, nil
+	}
+	return nil, explainErr
+}
+
 func init() {
 	vtgate.RegisterVTGates = append(vtgate.RegisterVTGates, func(vtGate vtgateservice.VTGateService) {
 		if servenv.GRPCCheckServiceMap("vtgateservice") {
@@ -0,0 +1,213 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vtgate
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/golang/glog"
+	"github.com/youtube/vitess/go/stats"
+	pb "github.com/youtube/vitess/go/vt/proto/query"
+	"github.com/youtube/vitess/go/vt/tabletserver/tabletconn"
+	"github.com/youtube/vitess/go/vt/topo"
+	"golang.org/x/net/context"
+)
+
+var (
+	tabletHealthStream    = flag.Bool("tablet_health_stream", false, "if true, vtgate subscribes to the health stream of the replica and rdonly tablets, and balances their reads by replication lag and qps")
+	maxReplicationLag     = flag.Duration("max_replication_lag", 30*time.Second, "replica and rdonly tablets lagging more than this are not used for reads, unless no other tablet is available. Requires -tablet_health_stream")
+	healthStreamRetry     = flag.Duration("health_stream_retry_delay", 5*time.Second, "delay before resubscribing to the health stream of a tablet after an error")
+	healthStreamStaleness = flag.Duration("health_stream_staleness", 1*time.Minute, "the health of a tablet is unknown if its health stream was silent for longer than this")
+)
+
+var (
+	defaultHealthCacheOnce sync.Once
+	defaultHealthCache     *HealthCache
+)
+
+// getHealthCache returns the HealthCache shared by all the balancers
+// of vtgate. It's nil if -tablet_health_stream is not set.
+func getHealthCache() *HealthCache {
+	if !*tabletHealthStream {
+		return nil
+	}
+	defaultHealthCacheOnce.Do(func() {
+		defaultHealthCache = NewHealthCache(tabletconn.GetDialer(), *healthStreamRetry, *healthStreamStaleness)
+		stats.Publish("TabletReplicationLag", stats.CountersFunc(defaultHealthCache.replicationLags))
+	})
+	return defaultHealthCache
+}
+
+// HealthCache keeps the latest RealtimeStats of tablets, which it
+// receives from their StreamHealth. A tablet is watched as long as
+// at least one Balancer uses it.
+type HealthCache struct {
+	dial       tabletconn.TabletDialer
+	retryDelay time.Duration
+	staleness  time.Duration
+
+	mu      sync.Mutex
+	tablets map[string]*tabletHealth
+	// generation numbers the health streams: a stream only updates
+	// the tabletHealth it was started for, not the one of a later
+	// Watch of the same tablet.
+	generation uint64
+}
+
+// tabletHealth is the health of a tablet, and the state of its
+// health stream.
+type tabletHealth struct {
+	endPoint   topo.EndPoint
+	refs       int
+	cancel     context.CancelFunc
+	generation uint64
+
+	// stats is nil until the first response, and after errors.
+	stats      *pb.RealtimeStats
+	lastUpdate time.Time
+}
+
+// NewHealthCache creates a HealthCache. It uses dial to connect to the
+// tablets, and waits for retryDelay before resubscribing after an
+// error. The health of a tablet whose stream is silent for longer than
+// staleness is unknown.
+func NewHealthCache(dial tabletconn.TabletDialer, retryDelay, staleness time.Duration) *HealthCache {
+	return &HealthCache{
+		dial:       dial,
+		retryDelay: retryDelay,
+		staleness:  staleness,
+		tablets:    make(map[string]*tabletHealth),
+	}
+}
+
+func endPointKey(endPoint topo.EndPoint) string {
+	return fmt.Sprintf("%v/%v", endPoint.Host, endPoint.Uid)
+}
+
+// Watch starts watching the health of endPoint, unless it's
+// already watched.
+func (hc *HealthCache) Watch(endPoint topo.EndPoint) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	key := endPointKey(endPoint)
+	if th, ok := hc.tablets[key]; ok {
+		th.refs++
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	hc.generation++
+	hc.tablets[key] = &tabletHealth{
+		endPoint:   endPoint,
+		refs:       1,
+		cancel:     cancel,
+		generation: hc.generation,
+	}
+	go hc.stream(ctx, key, hc.generation, endPoint)
+}
+
+// Unwatch stops watching the health of endPoint once it's not used
+// by any caller of Watch any more.
+func (hc *HealthCache) Unwatch(endPoint topo.EndPoint) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	key := endPointKey(endPoint)
+	th, ok := hc.tablets[key]
+	if !ok {
+		return
+	}
+	th.refs--
+	if th.refs > 0 {
+		return
+	}
+	th.cancel()
+	delete(hc.tablets, key)
+}
+
+// Get returns the latest RealtimeStats of endPoint. It returns nil if
+// the health of endPoint is unknown.
+func (hc *HealthCache) Get(endPoint topo.EndPoint) *pb.RealtimeStats {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	th, ok := hc.tablets[endPointKey(endPoint)]
+	if !ok || th.stats == nil || time.Now().Sub(th.lastUpdate) > hc.staleness {
+		return nil
+	}
+	return th.stats
+}
+
+// update records the latest stats of the tablet key, received by
+// the stream of generation. A nil stats means that its health is
+// unknown.
+func (hc *HealthCache) update(key string, generation uint64, stats *pb.RealtimeStats) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	if th, ok := hc.tablets[key]; ok && th.generation == generation {
+		th.stats = stats
+		th.lastUpdate = time.Now()
+	}
+}
+
+// stream subscribes to the health stream of endPoint until ctx is
+// canceled. It resubscribes after errors.
+func (hc *HealthCache) stream(ctx context.Context, key string, generation uint64, endPoint topo.EndPoint) {
+	for {
+		if err := hc.streamOnce(ctx, key, generation, endPoint); err != nil {
+			log.Warningf("health stream of %v failed: %v", key, err)
+		}
+		hc.update(key, generation, nil)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(hc.retryDelay):
+		}
+	}
+}
+
+func (hc *HealthCache) streamOnce(ctx context.Context, key string, generation uint64, endPoint topo.EndPoint) error {
+	conn, err := hc.dial(ctx, endPoint, "", "", hc.retryDelay)
+	if err != nil {
+		return err
+	}
+	// Not all the TabletConn implementations stop streaming when ctx
+	// is canceled, but they all do when they're closed. The conn is
+	// closed either way, but only once.
+	var closeOnce sync.Once
+	closeConn := func() { closeOnce.Do(conn.Close) }
+	defer closeConn()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			closeConn()
+		case <-done:
+		}
+	}()
+	stream, errFunc, err := conn.StreamHealth(ctx)
+	if err != nil {
+		return err
+	}
+	for shr := range stream {
+		hc.update(key, generation, shr.RealtimeStats)
+	}
+	return errFunc()
+}
+
+// replicationLags returns the replication lag of the tablets
+// whose health is known, in seconds.
+func (hc *HealthCache) replicationLags() map[string]int64 {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	lags := make(map[string]int64, len(hc.tablets))
+	for key, th := range hc.tablets {
+		if th.stats != nil {
+			lags[key] = int64(th.stats.SecondsBehindMaster)
+		}
+	}
+	return lags
+}
@@ -0,0 +1,135 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vtgate
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/youtube/vitess/go/vt/proto/query"
+	"github.com/youtube/vitess/go/vt/tabletserver/tabletconn"
+	"github.com/youtube/vitess/go/vt/topo"
+	"golang.org/x/net/context"
+)
+
+// healthConn is a sandboxConn that streams its health from c.
+// It counts all its Close calls, but closes c only once.
+type healthConn struct {
+	*sandboxConn
+	c         chan *pb.StreamHealthResponse
+	closeOnce sync.Once
+}
+
+func (hconn *healthConn) StreamHealth(ctx context.Context) (<-chan *pb.StreamHealthResponse, tabletconn.ErrFunc, error) {
+	return hconn.c, func() error { return fmt.Errorf("stream closed") }, nil
+}
+
+func (hconn *healthConn) Close() {
+	hconn.sandboxConn.Close()
+	hconn.closeOnce.Do(func() {
+		close(hconn.c)
+	})
+}
+
+// updateHealth records stats as received by the current health
+// stream of the tablet key.
+func updateHealth(hc *HealthCache, key string, stats *pb.RealtimeStats) {
+	hc.mu.Lock()
+	generation := hc.tablets[key].generation
+	hc.mu.Unlock()
+	hc.update(key, generation, stats)
+}
+
+func waitForHealth(hc *HealthCache, endPoint topo.EndPoint, known bool) *pb.RealtimeStats {
+	for i := 0; i < 100; i++ {
+		if stats := hc.Get(endPoint); (stats != nil) == known {
+			return stats
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
+func TestHealthCacheStream(t *testing.T) {
+	hconn := &healthConn{
+		sandboxConn: &sandboxConn{},
+		c:           make(chan *pb.StreamHealthResponse, 1),
+	}
+	dials := 0
+	hc := NewHealthCache(func(ctx context.Context, endPoint topo.EndPoint, keyspace, shard string, timeout time.Duration) (tabletconn.TabletConn, error) {
+		dials++
+		if dials > 1 {
+			return nil, fmt.Errorf("no more connections")
+		}
+		return hconn, nil
+	}, time.Hour, time.Hour)
+	endPoint := topo.EndPoint{Uid: 1, Host: "1"}
+
+	hc.Watch(endPoint)
+	hc.Watch(endPoint)
+	hconn.c <- &pb.StreamHealthResponse{RealtimeStats: &pb.RealtimeStats{SecondsBehindMaster: 3}}
+	stats := waitForHealth(hc, endPoint, true)
+	if stats == nil || stats.SecondsBehindMaster != 3 {
+		t.Fatalf("Get: %v, want 3 seconds behind master", stats)
+	}
+
+	// The stream is only closed when the last watcher is gone.
+	hc.Unwatch(endPoint)
+	if hconn.CloseCount.Get() != 0 {
+		t.Errorf("CloseCount: %d, want 0", hconn.CloseCount.Get())
+	}
+	hc.Unwatch(endPoint)
+	for i := 0; i < 100 && hconn.CloseCount.Get() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// The stream goroutine is done once the conn is closed, but
+	// give it a chance to close it again.
+	time.Sleep(10 * time.Millisecond)
+	if hconn.CloseCount.Get() != 1 {
+		t.Errorf("CloseCount: %d, want 1", hconn.CloseCount.Get())
+	}
+	if stats := hc.Get(endPoint); stats != nil {
+		t.Errorf("Get: %v, want nil", stats)
+	}
+}
+
+func TestHealthCacheStaleness(t *testing.T) {
+	hc := NewHealthCache(blockingDial, time.Hour, 10*time.Millisecond)
+	endPoint := topo.EndPoint{Uid: 1, Host: "1"}
+	hc.Watch(endPoint)
+	defer hc.Unwatch(endPoint)
+
+	updateHealth(hc, endPointKey(endPoint), &pb.RealtimeStats{})
+	if stats := hc.Get(endPoint); stats == nil {
+		t.Errorf("Get: nil, want stats")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if stats := hc.Get(endPoint); stats != nil {
+		t.Errorf("Get: %v, want nil after staleness", stats)
+	}
+}
+
+func TestHealthCacheGeneration(t *testing.T) {
+	hc := NewHealthCache(blockingDial, time.Hour, time.Hour)
+	endPoint := topo.EndPoint{Uid: 1, Host: "1"}
+	key := endPointKey(endPoint)
+	hc.Watch(endPoint)
+	oldGeneration := hc.tablets[key].generation
+	hc.Unwatch(endPoint)
+	hc.Watch(endPoint)
+	defer hc.Unwatch(endPoint)
+
+	// The stream of the first Watch doesn't update the second one.
+	hc.update(key, oldGeneration, &pb.RealtimeStats{})
+	if stats := hc.Get(endPoint); stats != nil {
+		t.Errorf("Get: %v, want nil", stats)
+	}
+	updateHealth(hc, key, &pb.RealtimeStats{})
+	if stats := hc.Get(endPoint); stats == nil {
+		t.Errorf("Get: nil, want stats")
+	}
+}
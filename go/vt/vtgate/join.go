@@ -0,0 +1,57 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vtgate
+
+// This is a V3 file. Do not intermix with V2.
+
+import (
+	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/sqltypes"
+)
+
+// joinBindVars returns a copy of bindVars with the join vars
+// set to the values of the left row.
+func joinBindVars(bindVars map[string]interface{}, joinVars map[string]int, fields []mproto.Field, row []sqltypes.Value) (map[string]interface{}, error) {
+	newVars := make(map[string]interface{}, len(bindVars)+len(joinVars))
+	for k, v := range bindVars {
+		newVars[k] = v
+	}
+	for name, index := range joinVars {
+		val, err := mproto.Convert(fields[index], row[index])
+		if err != nil {
+			return nil, err
+		}
+		newVars[name] = val
+	}
+	return newVars, nil
+}
+
+// joinRow builds a result row of a join from a left row and a right row.
+// If right is nil, the right columns are NULL.
+func joinRow(cols []int, left, right []sqltypes.Value) []sqltypes.Value {
+	row := make([]sqltypes.Value, len(cols))
+	for i, col := range cols {
+		switch {
+		case col < 0:
+			row[i] = left[-col-1]
+		case right != nil:
+			row[i] = right[col-1]
+		}
+	}
+	return row
+}
+
+// joinFields builds the fields of the result of a join.
+func joinFields(cols []int, left, right []mproto.Field) []mproto.Field {
+	fields := make([]mproto.Field, len(cols))
+	for i, col := range cols {
+		if col < 0 {
+			fields[i] = left[-col-1]
+		} else {
+			fields[i] = right[col-1]
+		}
+	}
+	return fields
+}
@@ -0,0 +1,159 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vtgate
+
+import (
+	"flag"
+	"sync"
+	"time"
+
+	log "github.com/golang/glog"
+	"github.com/youtube/vitess/go/stats"
+	"github.com/youtube/vitess/go/vt/topo"
+	"golang.org/x/net/context"
+)
+
+var (
+	enableMasterBuffer  = flag.Bool("enable_master_buffer", false, "if true, the requests to the master of a shard are held during a failover, and replayed against the new master")
+	masterBufferSize    = flag.Int("master_buffer_size", 100, "maximum number of requests held per shard during a failover. The requests above it fail")
+	masterBufferMaxWait = flag.Duration("master_buffer_max_wait", 10*time.Second, "maximum time the requests are held during a failover. They fail if no new master was found by then")
+
+	masterBufferEvents = stats.NewMultiCounters("MasterBufferEvents", []string{"Keyspace", "Shard", "Event"})
+)
+
+// masterBuffer holds the requests to the master of a shard while it
+// fails over. A failover starts with the first request that fails
+// because the master is unavailable, and ends when the serving graph
+// has a new master, or after maxWait. The requests held meanwhile are
+// replayed against the new master, or fail if there is none. Requests
+// are only held once the shard connected to a master: without an old
+// master, there's no telling a new one.
+type masterBuffer struct {
+	keyspace   string
+	shard      string
+	size       int
+	maxWait    time.Duration
+	pollDelay  time.Duration
+	getMasters GetEndPointsFunc
+
+	mu sync.Mutex
+	// lastMaster is the last master the shard connected to.
+	lastMaster string
+	// failover is the current failover.
+	// It's nil if there is none.
+	failover *failover
+	waiters  int
+}
+
+// failover is a failover of the master of a shard.
+type failover struct {
+	// done is closed when the failover ends.
+	done chan struct{}
+	// newMaster is set before done is closed, if a new master
+	// was found.
+	newMaster bool
+}
+
+func newMasterBuffer(keyspace, shard string, getMasters GetEndPointsFunc, size int, maxWait, pollDelay time.Duration) *masterBuffer {
+	return &masterBuffer{
+		keyspace:   keyspace,
+		shard:      shard,
+		size:       size,
+		maxWait:    maxWait,
+		pollDelay:  pollDelay,
+		getMasters: getMasters,
+	}
+}
+
+// setMaster records endPoint as the current master.
+func (mb *masterBuffer) setMaster(endPoint topo.EndPoint) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	mb.lastMaster = endPointKey(endPoint)
+}
+
+// wait holds the caller until the current failover ends, and starts
+// one if needed. It returns true if there's a new master the request
+// should be replayed against. It returns false if the request should
+// fail: the old master is unknown, the buffer is full, ctx is done,
+// or the failover timed out.
+func (mb *masterBuffer) wait(ctx context.Context) bool {
+	mb.mu.Lock()
+	if mb.lastMaster == "" {
+		mb.mu.Unlock()
+		masterBufferEvents.Add([]string{mb.keyspace, mb.shard, "NoKnownMaster"}, 1)
+		return false
+	}
+	if mb.waiters >= mb.size {
+		mb.mu.Unlock()
+		masterBufferEvents.Add([]string{mb.keyspace, mb.shard, "BufferFull"}, 1)
+		return false
+	}
+	fo := mb.failover
+	if fo == nil {
+		fo = &failover{done: make(chan struct{})}
+		mb.failover = fo
+		masterBufferEvents.Add([]string{mb.keyspace, mb.shard, "FailoverStarted"}, 1)
+		go mb.watch(fo, mb.lastMaster)
+	}
+	mb.waiters++
+	mb.mu.Unlock()
+	masterBufferEvents.Add([]string{mb.keyspace, mb.shard, "Buffered"}, 1)
+
+	defer func() {
+		mb.mu.Lock()
+		mb.waiters--
+		mb.mu.Unlock()
+	}()
+	select {
+	case <-fo.done:
+		return fo.newMaster
+	case <-ctx.Done():
+		masterBufferEvents.Add([]string{mb.keyspace, mb.shard, "Canceled"}, 1)
+		return false
+	}
+}
+
+// watch polls the serving graph until it has a master other than
+// oldMaster, or until maxWait. It ends the failover afterwards.
+func (mb *masterBuffer) watch(fo *failover, oldMaster string) {
+	deadline := time.Now().Add(mb.maxWait)
+	for time.Now().Before(deadline) {
+		time.Sleep(mb.pollDelay)
+		if mb.hasNewMaster(oldMaster) {
+			fo.newMaster = true
+			break
+		}
+	}
+	if fo.newMaster {
+		log.Infof("FailoverEnded for %s/%s, replaying the held requests", mb.keyspace, mb.shard)
+		masterBufferEvents.Add([]string{mb.keyspace, mb.shard, "FailoverEnded"}, 1)
+	} else {
+		log.Warningf("FailoverTimedOut for %s/%s, failing the held requests", mb.keyspace, mb.shard)
+		masterBufferEvents.Add([]string{mb.keyspace, mb.shard, "FailoverTimedOut"}, 1)
+	}
+	mb.mu.Lock()
+	mb.failover = nil
+	mb.mu.Unlock()
+	close(fo.done)
+}
+
+// hasNewMaster returns true if the serving graph has a master
+// other than oldMaster, which must be known.
+func (mb *masterBuffer) hasNewMaster(oldMaster string) bool {
+	if oldMaster == "" {
+		return false
+	}
+	endPoints, err := mb.getMasters()
+	if err != nil || endPoints == nil {
+		return false
+	}
+	for _, endPoint := range endPoints.Entries {
+		if endPointKey(endPoint) != oldMaster {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,474 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vtgate
+
+// This is a V3 file. Do not intermix with V2.
+
+import (
+	"bytes"
+	"container/heap"
+	"errors"
+	"fmt"
+	"strings"
+
+	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/sqltypes"
+	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
+)
+
+// mergeResults merges the results of a Merge plan. Each result
+// is expected to be sorted by plan.OrderBy. The rows are merge-sorted,
+// and the LIMIT of the plan is applied.
+func mergeResults(results []*mproto.QueryResult, plan *planbuilder.Plan, bindVars map[string]interface{}) (*mproto.QueryResult, error) {
+	offset, rowcount, err := resolveLimits(plan, bindVars)
+	if err != nil {
+		return nil, err
+	}
+	maxRows := int64(-1)
+	if rowcount != -1 {
+		maxRows = offset + rowcount
+	}
+	qr, err := mergeSort(results, plan.OrderBy, maxRows)
+	if err != nil {
+		return nil, err
+	}
+	if offset >= int64(len(qr.Rows)) {
+		qr.Rows = nil
+	} else {
+		qr.Rows = qr.Rows[offset:]
+	}
+	qr.RowsAffected = uint64(len(qr.Rows))
+	return qr, nil
+}
+
+// resolveLimits returns the offset and rowcount of a Merge plan.
+// If there is no rowcount, it returns -1.
+func resolveLimits(plan *planbuilder.Plan, bindVars map[string]interface{}) (offset, rowcount int64, err error) {
+	rowcount = -1
+	if plan.Offset != nil {
+		if offset, err = resolveLimit(plan.Offset, bindVars); err != nil {
+			return 0, 0, err
+		}
+	}
+	if plan.Rowcount != nil {
+		if rowcount, err = resolveLimit(plan.Rowcount, bindVars); err != nil {
+			return 0, 0, err
+		}
+	}
+	return offset, rowcount, nil
+}
+
+// resolveLimit converts a LIMIT value, which can be an int64
+// or a bind var name, into an int64.
+func resolveLimit(val interface{}, bindVars map[string]interface{}) (int64, error) {
+	if name, ok := val.(string); ok {
+		v, ok := bindVars[name[1:]]
+		if !ok {
+			return 0, fmt.Errorf("could not find bind var %s", name)
+		}
+		val = v
+	}
+	var limit int64
+	switch v := val.(type) {
+	case int:
+		limit = int64(v)
+	case int32:
+		limit = int64(v)
+	case int64:
+		limit = v
+	case uint32:
+		limit = int64(v)
+	case uint64:
+		limit = int64(v)
+	default:
+		return 0, fmt.Errorf("unexpected type for limit: %T", val)
+	}
+	if limit < 0 {
+		return 0, fmt.Errorf("negative limit: %d", limit)
+	}
+	return limit, nil
+}
+
+// setLimitVar computes the LIMIT to be pushed down to the shards
+// if the plan couldn't compute it, and adds it to bindVars.
+func setLimitVar(plan *planbuilder.Plan, bindVars map[string]interface{}) error {
+	if plan.Offset == nil {
+		return nil
+	}
+	if _, ok := plan.Offset.(int64); ok {
+		if _, ok := plan.Rowcount.(int64); ok {
+			return nil
+		}
+	}
+	offset, rowcount, err := resolveLimits(plan, bindVars)
+	if err != nil {
+		return err
+	}
+	bindVars[planbuilder.LimitVarName] = offset + rowcount
+	return nil
+}
+
+// sortColumn is an OrderByCol resolved against the fields of a result.
+type sortColumn struct {
+	index int
+	field mproto.Field
+	desc  bool
+}
+
+// resolveOrderBy finds the positions of the order by columns in fields.
+func resolveOrderBy(orderBy []planbuilder.OrderByCol, fields []mproto.Field) ([]sortColumn, error) {
+	cols := make([]sortColumn, 0, len(orderBy))
+	for _, order := range orderBy {
+		index := order.Index
+		if index == -1 {
+			for i, field := range fields {
+				if strings.EqualFold(field.Name, order.Col) {
+					index = i
+					break
+				}
+			}
+			if index == -1 {
+				return nil, fmt.Errorf("order by column %s not found in result", order.Col)
+			}
+		}
+		if index >= len(fields) {
+			return nil, fmt.Errorf("order by position %d is out of range", index+1)
+		}
+		if !hasExactOrder(fields[index]) {
+			return nil, fmt.Errorf("cannot merge-sort order by column %s: its order depends on its collation", fields[index].Name)
+		}
+		cols = append(cols, sortColumn{
+			index: index,
+			field: fields[index],
+			desc:  order.Desc,
+		})
+	}
+	return cols, nil
+}
+
+// compareRows compares two rows based on the sort columns.
+func compareRows(cols []sortColumn, left, right []sqltypes.Value) (int, error) {
+	for _, col := range cols {
+		cmp, err := compareValues(col.field, left[col.index], right[col.index])
+		if err != nil {
+			return 0, err
+		}
+		if cmp == 0 {
+			continue
+		}
+		if col.desc {
+			return -cmp, nil
+		}
+		return cmp, nil
+	}
+	return 0, nil
+}
+
+// hasExactOrder returns true if vtgate can order the values of field
+// the way MySQL does: numbers, dates, and binary strings. The order of
+// the other strings depends on their collation, which vtgate doesn't
+// know.
+func hasExactOrder(field mproto.Field) bool {
+	switch field.Type {
+	case mproto.VT_TINY, mproto.VT_SHORT, mproto.VT_LONG, mproto.VT_INT24, mproto.VT_LONGLONG,
+		mproto.VT_FLOAT, mproto.VT_DOUBLE, mproto.VT_DECIMAL, mproto.VT_NEWDECIMAL,
+		mproto.VT_DATE, mproto.VT_NEWDATE, mproto.VT_DATETIME, mproto.VT_TIMESTAMP, mproto.VT_YEAR,
+		mproto.VT_NULL:
+		return true
+	case mproto.VT_VARCHAR, mproto.VT_VAR_STRING, mproto.VT_STRING,
+		mproto.VT_TINY_BLOB, mproto.VT_MEDIUM_BLOB, mproto.VT_LONG_BLOB, mproto.VT_BLOB:
+		return field.Flags&mproto.VT_BINARY_FLAG != 0
+	}
+	return false
+}
+
+// compareValues compares two values of the same field. Like in MySQL,
+// NULL is smaller than any other value. Only the fields that have an
+// exact order can be compared, see hasExactOrder.
+func compareValues(field mproto.Field, left, right sqltypes.Value) (int, error) {
+	switch {
+	case left.IsNull() && right.IsNull():
+		return 0, nil
+	case left.IsNull():
+		return -1, nil
+	case right.IsNull():
+		return 1, nil
+	}
+	if !hasExactOrder(field) {
+		return 0, fmt.Errorf("cannot compare values of column %s: their order depends on its collation", field.Name)
+	}
+	if isDecimal(field) {
+		l, err := parseDecimal(left)
+		if err != nil {
+			return 0, err
+		}
+		r, err := parseDecimal(right)
+		if err != nil {
+			return 0, err
+		}
+		return l.Cmp(r), nil
+	}
+	lv, err := mproto.Convert(field, left)
+	if err != nil {
+		return 0, err
+	}
+	rv, err := mproto.Convert(field, right)
+	if err != nil {
+		return 0, err
+	}
+	switch l := lv.(type) {
+	case int64:
+		r := rv.(int64)
+		switch {
+		case l < r:
+			return -1, nil
+		case l > r:
+			return 1, nil
+		}
+		return 0, nil
+	case uint64:
+		r := rv.(uint64)
+		switch {
+		case l < r:
+			return -1, nil
+		case l > r:
+			return 1, nil
+		}
+		return 0, nil
+	case float64:
+		r := rv.(float64)
+		switch {
+		case l < r:
+			return -1, nil
+		case l > r:
+			return 1, nil
+		}
+		return 0, nil
+	case []byte:
+		return bytes.Compare(l, rv.([]byte)), nil
+	}
+	return 0, fmt.Errorf("unexpected type %T for field %s", lv, field.Name)
+}
+
+// mergeSort performs a k-way merge of results that are individually
+// sorted by orderBy. If maxRows is not -1, the merge stops after
+// that many rows. A single result is already sorted: its rows are
+// not compared, so that any column can order it.
+func mergeSort(results []*mproto.QueryResult, orderBy []planbuilder.OrderByCol, maxRows int64) (*mproto.QueryResult, error) {
+	qr := &mproto.QueryResult{}
+	h := &mergeHeap{}
+	for _, result := range results {
+		if qr.Fields == nil {
+			qr.Fields = result.Fields
+		}
+		if len(result.Rows) != 0 {
+			h.streams = append(h.streams, &rowStream{rows: result.Rows})
+		}
+	}
+	if len(results) > 1 && len(h.streams) != 0 {
+		cols, err := resolveOrderBy(orderBy, qr.Fields)
+		if err != nil {
+			return nil, err
+		}
+		h.cols = cols
+	}
+	heap.Init(h)
+	for h.Len() != 0 && h.err == nil {
+		if maxRows != -1 && int64(len(qr.Rows)) >= maxRows {
+			break
+		}
+		qr.Rows = append(qr.Rows, h.next())
+	}
+	if h.err != nil {
+		return nil, h.err
+	}
+	qr.RowsAffected = uint64(len(qr.Rows))
+	return qr, nil
+}
+
+// mergePacketSize is the size of the row values above which
+// mergeStreams sends a packet. It matches the default stream
+// buffer size of vttablet.
+const mergePacketSize = 32 * 1024
+
+// mergeStreams performs a k-way merge of streams that are individually
+// sorted by orderBy, and sends the merged rows to sendReply as they
+// come. The fields are sent first, like a tablet would. Only the
+// current packet of each stream is kept in memory. Like in mergeSort,
+// a single stream is not compared.
+func mergeStreams(streams []*rowStream, orderBy []planbuilder.OrderByCol, sendReply func(*mproto.QueryResult) error) error {
+	h := &mergeHeap{}
+	var fields []mproto.Field
+	for _, rs := range streams {
+		if rs.fill() {
+			h.streams = append(h.streams, rs)
+		}
+		if fields == nil {
+			fields = rs.fields
+		}
+	}
+	if fields == nil {
+		return nil
+	}
+	if err := sendReply(&mproto.QueryResult{Fields: fields}); err != nil {
+		return err
+	}
+	if len(h.streams) == 0 {
+		return nil
+	}
+	if len(streams) > 1 {
+		cols, err := resolveOrderBy(orderBy, fields)
+		if err != nil {
+			return err
+		}
+		h.cols = cols
+	}
+	heap.Init(h)
+	var rows [][]sqltypes.Value
+	size := 0
+	for h.Len() != 0 && h.err == nil {
+		row := h.next()
+		rows = append(rows, row)
+		size += rowSize(row)
+		if size < mergePacketSize {
+			continue
+		}
+		if err := sendReply(&mproto.QueryResult{RowsAffected: uint64(len(rows)), Rows: rows}); err != nil {
+			return err
+		}
+		rows = nil
+		size = 0
+	}
+	if h.err != nil {
+		return h.err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return sendReply(&mproto.QueryResult{RowsAffected: uint64(len(rows)), Rows: rows})
+}
+
+// rowSize returns the size of the values of row.
+func rowSize(row []sqltypes.Value) int {
+	size := 0
+	for _, v := range row {
+		size += len(v.Raw())
+	}
+	return size
+}
+
+// errMergeLimitReached is returned by the sendReply functions of
+// limitRows to stop the stream once the LIMIT of a Merge plan
+// is reached.
+var errMergeLimitReached = errors.New("merge limit reached")
+
+// limitRows returns a sendReply function that skips the first
+// offset rows of a stream, and stops it with errMergeLimitReached
+// once rowcount rows are sent, unless rowcount is -1.
+func limitRows(offset, rowcount int64, sendReply func(*mproto.QueryResult) error) func(*mproto.QueryResult) error {
+	return func(qr *mproto.QueryResult) error {
+		if len(qr.Rows) == 0 {
+			return sendReply(qr)
+		}
+		rows := qr.Rows
+		if offset >= int64(len(rows)) {
+			offset -= int64(len(rows))
+			return nil
+		}
+		rows = rows[offset:]
+		offset = 0
+		if rowcount == -1 {
+			return sendReply(&mproto.QueryResult{RowsAffected: uint64(len(rows)), Rows: rows})
+		}
+		if int64(len(rows)) > rowcount {
+			rows = rows[:rowcount]
+		}
+		rowcount -= int64(len(rows))
+		if len(rows) != 0 {
+			if err := sendReply(&mproto.QueryResult{RowsAffected: uint64(len(rows)), Rows: rows}); err != nil {
+				return err
+			}
+		}
+		if rowcount == 0 {
+			return errMergeLimitReached
+		}
+		return nil
+	}
+}
+
+// rowStream is a stream of sorted rows for mergeHeap. Once rows
+// is consumed, it's refilled from results, if set.
+type rowStream struct {
+	rows    [][]sqltypes.Value
+	results <-chan *mproto.QueryResult
+	fields  []mproto.Field
+}
+
+// fill reads results up to the next packet that has rows.
+// It returns false once results is closed.
+func (rs *rowStream) fill() bool {
+	if rs.results == nil {
+		return false
+	}
+	for qr := range rs.results {
+		if rs.fields == nil && len(qr.Fields) != 0 {
+			rs.fields = qr.Fields
+		}
+		if len(qr.Rows) != 0 {
+			rs.rows = qr.Rows
+			return true
+		}
+	}
+	return false
+}
+
+// mergeHeap is a heap of row streams ordered by their first row.
+// It satisfies heap.Interface. Comparison errors are saved in err.
+type mergeHeap struct {
+	cols    []sortColumn
+	streams []*rowStream
+	err     error
+}
+
+// next removes the smallest row from the streams, and returns it.
+func (mh *mergeHeap) next() []sqltypes.Value {
+	rs := mh.streams[0]
+	row := rs.rows[0]
+	rs.rows = rs.rows[1:]
+	if len(rs.rows) == 0 && !rs.fill() {
+		heap.Pop(mh)
+	} else {
+		heap.Fix(mh, 0)
+	}
+	return row
+}
+
+func (mh *mergeHeap) Len() int {
+	return len(mh.streams)
+}
+
+func (mh *mergeHeap) Less(i, j int) bool {
+	cmp, err := compareRows(mh.cols, mh.streams[i].rows[0], mh.streams[j].rows[0])
+	if err != nil {
+		mh.err = err
+		return false
+	}
+	return cmp < 0
+}
+
+func (mh *mergeHeap) Swap(i, j int) {
+	mh.streams[i], mh.streams[j] = mh.streams[j], mh.streams[i]
+}
+
+func (mh *mergeHeap) Push(x interface{}) {
+	mh.streams = append(mh.streams, x.(*rowStream))
+}
+
+func (mh *mergeHeap) Pop() interface{} {
+	n := len(mh.streams)
+	x := mh.streams[n-1]
+	mh.streams = mh.streams[:n-1]
+	return x
+}
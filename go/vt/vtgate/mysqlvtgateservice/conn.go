@@ -0,0 +1,590 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mysqlvtgateservice
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	log "github.com/golang/glog"
+	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/vt/callerid"
+	"github.com/youtube/vitess/go/vt/sqlparser"
+	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/vtgate/proto"
+	"github.com/youtube/vitess/go/vt/vtgate/vtgateservice"
+	"golang.org/x/net/context"
+)
+
+// serverVersion is the version the server announces. Some clients
+// check it to decide which features to use.
+const serverVersion = "5.5.10-Vitess"
+
+// sqlError is an error sent to the client as an error packet.
+type sqlError struct {
+	code     uint16
+	sqlState string
+	message  string
+}
+
+func (err *sqlError) Error() string {
+	return fmt.Sprintf("%s (errno %d) (sqlstate %s)", err.message, err.code, err.sqlState)
+}
+
+func newSQLError(code uint16, sqlState, format string, args ...interface{}) *sqlError {
+	return &sqlError{code: code, sqlState: sqlState, message: fmt.Sprintf(format, args...)}
+}
+
+// mysqlConn serves one client connection. Its session is the state
+// the client changes with USE, SET and the transaction statements.
+type mysqlConn struct {
+	server       vtgateservice.VTGateService
+	pc           *packetConn
+	connectionID uint32
+	capabilities uint32
+	user         string
+
+	keyspace   string
+	tabletType topo.TabletType
+	autocommit bool
+	session    *proto.Session
+}
+
+func newMysqlConn(server vtgateservice.VTGateService, rw io.ReadWriter, connectionID uint32) *mysqlConn {
+	return &mysqlConn{
+		server:       server,
+		pc:           newPacketConn(rw),
+		connectionID: connectionID,
+		tabletType:   topo.TYPE_MASTER,
+		autocommit:   true,
+	}
+}
+
+// serve runs the handshake, and executes the commands of the
+// client until it quits or the connection fails.
+func (mc *mysqlConn) serve(auth *authConfig) {
+	defer mc.rollback()
+	if err := mc.handshake(auth); err != nil {
+		log.Infof("mysql connection %d: handshake failed: %v", mc.connectionID, err)
+		return
+	}
+	for {
+		mc.pc.sequence = 0
+		data, err := mc.pc.readPacket()
+		if err != nil {
+			if err != io.EOF {
+				log.Infof("mysql connection %d: %v", mc.connectionID, err)
+			}
+			return
+		}
+		if len(data) == 0 {
+			return
+		}
+		if quit := mc.execCommand(data[0], data[1:]); quit {
+			return
+		}
+		if err := mc.pc.flush(); err != nil {
+			log.Infof("mysql connection %d: %v", mc.connectionID, err)
+			return
+		}
+	}
+}
+
+// handshake sends the initial handshake packet, and checks the
+// credentials of the response with the mysql_native_password
+// method.
+func (mc *mysqlConn) handshake(auth *authConfig) error {
+	salt := make([]byte, 20)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	// The salt must not contain NUL bytes.
+	for i := range salt {
+		salt[i] = salt[i]&0x7f | 1
+	}
+	var pb packetBuilder
+	pb.byte(protocolVersion)
+	pb.nulString(serverVersion)
+	pb.uint32(mc.connectionID)
+	pb.bytes(salt[:8])
+	pb.byte(0)
+	pb.uint16(uint16(serverCapabilities & 0xffff))
+	pb.byte(charsetUTF8)
+	pb.uint16(mc.statusFlags())
+	pb.uint16(uint16(serverCapabilities >> 16))
+	pb.byte(byte(len(salt) + 1))
+	pb.bytes(make([]byte, 10))
+	pb.bytes(salt[8:])
+	pb.byte(0)
+	pb.nulString(nativePassword)
+	if err := mc.pc.writePacket(pb); err != nil {
+		return err
+	}
+	if err := mc.pc.flush(); err != nil {
+		return err
+	}
+
+	data, err := mc.pc.readPacket()
+	if err != nil {
+		return err
+	}
+	pr := &packetReader{data: data}
+	mc.capabilities = pr.uint32()
+	if mc.capabilities&clientProtocol41 == 0 {
+		return mc.fail(newSQLError(errUnknownComError, "08S01", "the client must support protocol 4.1"))
+	}
+	pr.next(4 + 1 + 23)
+	mc.user = pr.nulString()
+	var authResponse []byte
+	if mc.capabilities&clientSecureConnection != 0 {
+		authResponse = pr.next(int(pr.byte()))
+	} else {
+		authResponse = []byte(pr.nulString())
+	}
+	var database string
+	if mc.capabilities&clientConnectWithDB != 0 && !pr.done() {
+		database = pr.nulString()
+	}
+	if pr.err != nil {
+		return pr.err
+	}
+	if !auth.check(mc.user, salt, authResponse) {
+		return mc.fail(newSQLError(errAccessDenied, "28000", "Access denied for user '%s'", mc.user))
+	}
+	if err := mc.use(database); err != nil {
+		return mc.fail(err)
+	}
+	if err := mc.writeOK(0, 0); err != nil {
+		return err
+	}
+	return mc.pc.flush()
+}
+
+// fail sends err to the client, and returns it.
+func (mc *mysqlConn) fail(err *sqlError) error {
+	mc.writeError(err)
+	mc.pc.flush()
+	return err
+}
+
+// execCommand executes a command. It returns true if the
+// client quits.
+func (mc *mysqlConn) execCommand(command byte, data []byte) (quit bool) {
+	var err error
+	switch command {
+	case comQuit:
+		return true
+	case comPing:
+		err = mc.writeOK(0, 0)
+	case comInitDB:
+		if serr := mc.use(string(data)); serr != nil {
+			err = mc.writeError(serr)
+		} else {
+			err = mc.writeOK(0, 0)
+		}
+	case comQuery:
+		err = mc.execQuery(string(data))
+	default:
+		err = mc.writeError(newSQLError(errUnknownComError, "08S01", "command %d is not supported", command))
+	}
+	if err != nil {
+		log.Infof("mysql connection %d: %v", mc.connectionID, err)
+		return true
+	}
+	return false
+}
+
+// execQuery executes sql, and writes its result. It only returns
+// an error if the result can't be written.
+func (mc *mysqlConn) execQuery(sql string) error {
+	qr, err := mc.execute(sql)
+	if err != nil {
+		serr, ok := err.(*sqlError)
+		if !ok {
+			serr = newSQLError(errUnknown, "HY000", "%v", err)
+		}
+		return mc.writeError(serr)
+	}
+	if len(qr.Fields) == 0 {
+		return mc.writeOK(qr.RowsAffected, qr.InsertId)
+	}
+	return mc.writeResult(qr)
+}
+
+// execute executes the statements that change the session
+// itself, and sends the others to vtgate.
+func (mc *mysqlConn) execute(sql string) (*mproto.QueryResult, error) {
+	trimmed := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(sql), ";"))
+	statement := strings.ToLower(trimmed)
+	switch {
+	case statement == "begin" || statement == "start transaction":
+		if err := mc.commit(); err != nil {
+			return nil, err
+		}
+		return &mproto.QueryResult{}, mc.begin()
+	case statement == "commit":
+		return &mproto.QueryResult{}, mc.commit()
+	case statement == "rollback":
+		return &mproto.QueryResult{}, mc.rollback()
+	case strings.HasPrefix(statement, "use "):
+		if err := mc.use(strings.Trim(strings.TrimSpace(trimmed[4:]), "`")); err != nil {
+			return nil, err
+		}
+		return &mproto.QueryResult{}, nil
+	case strings.HasPrefix(statement, "set "):
+		return &mproto.QueryResult{}, mc.set(statement)
+	}
+
+	if !mc.autocommit && mc.session == nil {
+		if err := mc.begin(); err != nil {
+			return nil, err
+		}
+	}
+	query := &proto.Query{
+		Sql:        sql,
+		TabletType: mc.tabletType,
+		Session:    mc.session,
+	}
+	if mc.session != nil {
+		query.TabletType = topo.TYPE_MASTER
+	}
+	reply := new(proto.QueryResult)
+	if err := mc.server.Execute(mc.context(), query, reply); err != nil {
+		return nil, err
+	}
+	if mc.session != nil {
+		mc.session = reply.Session
+	}
+	if reply.Error != "" {
+		return nil, newSQLError(errUnknown, "HY000", "%s", reply.Error)
+	}
+	return reply.Result, nil
+}
+
+// srvKeyspaceGetter is implemented by the servers which can check
+// that a keyspace exists, like VTGate.
+type srvKeyspaceGetter interface {
+	GetSrvKeyspace(ctx context.Context, keyspace string) (*topo.SrvKeyspace, error)
+}
+
+// use selects the keyspace and the tablet type of database. The
+// tablet type is used by the queries outside of transactions.
+func (mc *mysqlConn) use(database string) *sqlError {
+	keyspace, tabletType, err := parseTarget(database)
+	if err != nil {
+		return err
+	}
+	if getter, ok := mc.server.(srvKeyspaceGetter); ok && keyspace != "" {
+		if _, err := getter.GetSrvKeyspace(mc.context(), keyspace); err != nil {
+			return newSQLError(errBadDB, "42000", "Unknown database '%s': %v", database, err)
+		}
+	}
+	mc.keyspace = keyspace
+	mc.tabletType = tabletType
+	return nil
+}
+
+// ignoredVariables are the session variables the clients commonly set
+// on connect. They are accepted, but don't change anything.
+var ignoredVariables = map[string]bool{
+	"sql_mode":              true,
+	"time_zone":             true,
+	"wait_timeout":          true,
+	"interactive_timeout":   true,
+	"net_read_timeout":      true,
+	"net_write_timeout":     true,
+	"sql_auto_is_null":      true,
+	"sql_notes":             true,
+	"sql_quote_show_create": true,
+}
+
+// set handles the SET statements. autocommit changes the session.
+// The connection is always in utf8, so the character sets can only
+// be set to it, which clients do on connect. NULL is accepted for
+// character_set_results: it disables the conversion of the results.
+// The variables of ignoredVariables are accepted and ignored, the
+// other ones are not supported.
+func (mc *mysqlConn) set(statement string) error {
+	if strings.HasPrefix(statement, "set names ") {
+		charset := strings.Fields(statement[len("set names "):])[0]
+		if !isUTF8(charset) {
+			return newSQLError(errUnknownCharacterSet, "42000", "unsupported character set: %s", charset)
+		}
+		return nil
+	}
+	parsed, err := sqlparser.Parse(statement)
+	if err != nil {
+		return newSQLError(errUnknown, "42000", "%v", err)
+	}
+	set, ok := parsed.(*sqlparser.Set)
+	if !ok {
+		return newSQLError(errUnknown, "42000", "unsupported statement: %s", statement)
+	}
+	// The statement is checked before it changes the session, so
+	// that an error leaves it unchanged.
+	autocommit := mc.autocommit
+	for _, expr := range set.Exprs {
+		var value string
+		switch v := expr.Expr.(type) {
+		case sqlparser.NumVal:
+			value = string(v)
+		case sqlparser.StrVal:
+			value = strings.ToLower(string(v))
+		case *sqlparser.ColName:
+			value = strings.ToLower(string(v.Name))
+		}
+		switch name := strings.ToLower(string(expr.Name.Name)); name {
+		case "autocommit":
+			switch value {
+			case "1", "on", "true":
+				autocommit = true
+			case "0", "off", "false":
+				autocommit = false
+			default:
+				return newSQLError(errUnknown, "42000", "invalid value for autocommit: %s", sqlparser.String(expr.Expr))
+			}
+		case "character_set_client", "character_set_connection", "character_set_results":
+			if _, ok := expr.Expr.(*sqlparser.NullVal); ok && name == "character_set_results" {
+				continue
+			}
+			if !isUTF8(value) {
+				return newSQLError(errUnknownCharacterSet, "42000", "unsupported character set: %s", sqlparser.String(expr.Expr))
+			}
+		default:
+			if ignoredVariables[name] {
+				continue
+			}
+			return newSQLError(errUnknownSystemVariable, "HY000", "unsupported variable: %s", name)
+		}
+	}
+	if autocommit && !mc.autocommit {
+		// Enabling autocommit commits the current transaction.
+		if err := mc.commit(); err != nil {
+			return err
+		}
+	}
+	mc.autocommit = autocommit
+	return nil
+}
+
+func (mc *mysqlConn) begin() error {
+	session := new(proto.Session)
+	if err := mc.server.Begin(mc.context(), session); err != nil {
+		return err
+	}
+	mc.session = session
+	return nil
+}
+
+func (mc *mysqlConn) commit() error {
+	if mc.session == nil {
+		return nil
+	}
+	session := mc.session
+	mc.session = nil
+	return mc.server.Commit(mc.context(), session)
+}
+
+func (mc *mysqlConn) rollback() error {
+	if mc.session == nil {
+		return nil
+	}
+	session := mc.session
+	mc.session = nil
+	return mc.server.Rollback(mc.context(), session)
+}
+
+// context returns the context of a request, which has the
+// user of the connection as caller id.
+func (mc *mysqlConn) context() context.Context {
+	return callerid.NewContext(
+		context.Background(),
+		callerid.NewEffectiveCallerID(mc.user, "mysql", ""),
+		callerid.NewImmediateCallerID(mc.user),
+	)
+}
+
+// isUTF8 returns true if charset, in lower case, is utf8, the
+// character set of the connection, or its superset utf8mb4.
+func isUTF8(charset string) bool {
+	charset = strings.Trim(charset, "'\"`")
+	return charset == "utf8" || charset == "utf8mb4"
+}
+
+// parseTarget parses a database name, which is keyspace@tablet_type.
+// Both parts are optional: the keyspace is empty and the tablet type
+// is master if they are omitted, so "", "user", "user@replica" and
+// "@replica" are valid.
+func parseTarget(database string) (string, topo.TabletType, *sqlError) {
+	keyspace, tabletType := database, topo.TYPE_MASTER
+	if i := strings.IndexByte(database, '@'); i != -1 {
+		keyspace = database[:i]
+		tabletType = topo.TabletType(strings.ToLower(database[i+1:]))
+		if !topo.IsInServingGraph(tabletType) {
+			return "", "", newSQLError(errBadDB, "42000", "Unknown database '%s': %s is not a serving tablet type", database, database[i+1:])
+		}
+	}
+	if !isKeyspaceName(keyspace) {
+		return "", "", newSQLError(errBadDB, "42000", "Unknown database '%s': invalid keyspace name '%s'", database, keyspace)
+	}
+	return keyspace, tabletType, nil
+}
+
+// isKeyspaceName returns true if name is empty, or only contains
+// letters, digits, '_' and '-'.
+func isKeyspaceName(name string) bool {
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (mc *mysqlConn) statusFlags() uint16 {
+	var flags uint16
+	if mc.autocommit {
+		flags |= serverStatusAutocommit
+	}
+	if mc.session != nil {
+		flags |= serverStatusInTrans
+	}
+	return flags
+}
+
+func (mc *mysqlConn) writeOK(rowsAffected, insertID uint64) error {
+	var pb packetBuilder
+	pb.byte(okPacket)
+	pb.lenEncInt(rowsAffected)
+	pb.lenEncInt(insertID)
+	pb.uint16(mc.statusFlags())
+	pb.uint16(0)
+	return mc.pc.writePacket(pb)
+}
+
+func (mc *mysqlConn) writeError(err *sqlError) error {
+	var pb packetBuilder
+	pb.byte(errPacket)
+	pb.uint16(err.code)
+	pb.byte('#')
+	pb.bytes([]byte(err.sqlState))
+	pb.bytes([]byte(err.message))
+	return mc.pc.writePacket(pb)
+}
+
+func (mc *mysqlConn) writeEOF() error {
+	var pb packetBuilder
+	pb.byte(eofPacket)
+	pb.uint16(0)
+	pb.uint16(mc.statusFlags())
+	return mc.pc.writePacket(pb)
+}
+
+// writeResult writes qr as a text result set: the column count,
+// the column definitions, and the rows.
+func (mc *mysqlConn) writeResult(qr *mproto.QueryResult) error {
+	var pb packetBuilder
+	pb.lenEncInt(uint64(len(qr.Fields)))
+	if err := mc.pc.writePacket(pb); err != nil {
+		return err
+	}
+	for _, field := range qr.Fields {
+		if err := mc.pc.writePacket(columnDefinition(field)); err != nil {
+			return err
+		}
+	}
+	if err := mc.writeEOF(); err != nil {
+		return err
+	}
+	for _, row := range qr.Rows {
+		pb = pb[:0]
+		for _, value := range row {
+			if value.IsNull() {
+				pb.byte(nullValue)
+				continue
+			}
+			pb.lenEncString(value.Raw())
+		}
+		if err := mc.pc.writePacket(pb); err != nil {
+			return err
+		}
+	}
+	return mc.writeEOF()
+}
+
+// columnDefinition returns the protocol 4.1 definition of field.
+// The vtgate fields only have a name, so it's also used for the
+// original name.
+func columnDefinition(field mproto.Field) packetBuilder {
+	charset := uint16(charsetUTF8)
+	if field.Flags&mproto.VT_BINARY_FLAG != 0 || field.Type <= mproto.VT_INT24 || field.Type == mproto.VT_YEAR || field.Type == mproto.VT_NEWDECIMAL {
+		charset = charsetBinary
+	}
+	var pb packetBuilder
+	pb.lenEncString([]byte("def"))
+	pb.lenEncString(nil)
+	pb.lenEncString(nil)
+	pb.lenEncString(nil)
+	pb.lenEncString([]byte(field.Name))
+	pb.lenEncString([]byte(field.Name))
+	pb.byte(0x0c)
+	pb.uint16(charset)
+	pb.uint32(0)
+	pb.byte(byte(field.Type))
+	pb.uint16(uint16(field.Flags))
+	pb.byte(0)
+	pb.uint16(0)
+	return pb
+}
+
+// authConfig holds the credentials the clients must use.
+// If user is empty, all the clients are accepted.
+type authConfig struct {
+	user     string
+	password string
+}
+
+// check verifies the response of the mysql_native_password method,
+// which is SHA1(password) XOR SHA1(salt + SHA1(SHA1(password))).
+func (auth *authConfig) check(user string, salt, response []byte) bool {
+	if auth.user == "" {
+		return true
+	}
+	if user != auth.user {
+		return false
+	}
+	if auth.password == "" {
+		return len(response) == 0
+	}
+	return bytes.Equal(response, scramblePassword(salt, auth.password))
+}
+
+func scramblePassword(salt []byte, password string) []byte {
+	stage1 := sha1.Sum([]byte(password))
+	stage2 := sha1.Sum(stage1[:])
+	h := sha1.New()
+	h.Write(salt)
+	h.Write(stage2[:])
+	scramble := h.Sum(nil)
+	for i := range scramble {
+		scramble[i] ^= stage1[i]
+	}
+	return scramble
+}
+
+// remoteAddr returns the address of the client of conn.
+func remoteAddr(conn net.Conn) string {
+	if addr := conn.RemoteAddr(); addr != nil {
+		return addr.String()
+	}
+	return ""
+}
@@ -0,0 +1,361 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mysqlvtgateservice
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/sqltypes"
+	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/vtgate/proto"
+	"github.com/youtube/vitess/go/vt/vtgate/vtgateservice"
+	"golang.org/x/net/context"
+)
+
+// fakeVTGate records the calls of a connection. Only the methods it
+// uses are implemented.
+type fakeVTGate struct {
+	vtgateservice.VTGateService
+	calls []string
+}
+
+func (f *fakeVTGate) Execute(ctx context.Context, query *proto.Query, reply *proto.QueryResult) error {
+	f.calls = append(f.calls, fmt.Sprintf("Execute %s %s %v", query.Sql, query.TabletType, query.Session != nil))
+	reply.Session = query.Session
+	switch query.Sql {
+	case "select id, name from user":
+		reply.Result = &mproto.QueryResult{
+			Fields: []mproto.Field{
+				{Name: "id", Type: mproto.VT_LONGLONG},
+				{Name: "name", Type: mproto.VT_VAR_STRING},
+			},
+			Rows: [][]sqltypes.Value{
+				{sqltypes.MakeNumeric([]byte("1")), sqltypes.MakeString([]byte("a"))},
+				{sqltypes.MakeNumeric([]byte("2")), sqltypes.NULL},
+			},
+		}
+	case "insert into user(name) values ('b')":
+		reply.Result = &mproto.QueryResult{RowsAffected: 1, InsertId: 3}
+	default:
+		reply.Error = "syntax error"
+	}
+	return nil
+}
+
+// GetSrvKeyspace only knows the user keyspace.
+func (f *fakeVTGate) GetSrvKeyspace(ctx context.Context, keyspace string) (*topo.SrvKeyspace, error) {
+	if keyspace != "user" {
+		return nil, fmt.Errorf("keyspace %s not found", keyspace)
+	}
+	return &topo.SrvKeyspace{}, nil
+}
+
+func (f *fakeVTGate) Begin(ctx context.Context, outSession *proto.Session) error {
+	f.calls = append(f.calls, "Begin")
+	outSession.InTransaction = true
+	return nil
+}
+
+func (f *fakeVTGate) Commit(ctx context.Context, inSession *proto.Session) error {
+	f.calls = append(f.calls, "Commit")
+	return nil
+}
+
+func (f *fakeVTGate) Rollback(ctx context.Context, inSession *proto.Session) error {
+	f.calls = append(f.calls, "Rollback")
+	return nil
+}
+
+// testClient is a minimal MySQL client.
+type testClient struct {
+	t  *testing.T
+	pc *packetConn
+}
+
+// connect runs the handshake as user, with password.
+func connect(t *testing.T, conn net.Conn, user, password, database string) (*testClient, []byte) {
+	client := &testClient{t: t, pc: newPacketConn(conn)}
+	data, err := client.pc.readPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr := &packetReader{data: data}
+	if version := pr.byte(); version != protocolVersion {
+		t.Fatalf("protocol version: %d, want %d", version, protocolVersion)
+	}
+	pr.nulString()
+	pr.uint32()
+	salt := append([]byte{}, pr.next(8)...)
+	pr.next(1 + 2 + 1 + 2 + 2 + 1 + 10)
+	salt = append(salt, pr.next(12)...)
+	if pr.err != nil {
+		t.Fatal(pr.err)
+	}
+
+	var pb packetBuilder
+	pb.uint32(clientProtocol41 | clientSecureConnection | clientConnectWithDB)
+	pb.uint32(maxPacketSize)
+	pb.byte(charsetUTF8)
+	pb.bytes(make([]byte, 23))
+	pb.nulString(user)
+	scramble := scramblePassword(salt, password)
+	pb.byte(byte(len(scramble)))
+	pb.bytes(scramble)
+	pb.nulString(database)
+	if err := client.pc.writePacket(pb); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.pc.flush(); err != nil {
+		t.Fatal(err)
+	}
+	response, err := client.pc.readPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, response
+}
+
+// query sends sql, and returns the packets of the response.
+func (client *testClient) query(sql string) [][]byte {
+	client.pc.sequence = 0
+	if err := client.pc.writePacket(append([]byte{comQuery}, sql...)); err != nil {
+		client.t.Fatal(err)
+	}
+	if err := client.pc.flush(); err != nil {
+		client.t.Fatal(err)
+	}
+	var packets [][]byte
+	eofs := 0
+	for {
+		data, err := client.pc.readPacket()
+		if err != nil {
+			client.t.Fatal(err)
+		}
+		packets = append(packets, data)
+		switch {
+		case len(packets) == 1 && (data[0] == okPacket || data[0] == errPacket):
+			return packets
+		case data[0] == eofPacket && len(data) < 9:
+			eofs++
+			if eofs == 2 {
+				return packets
+			}
+		}
+	}
+}
+
+func startConn(t *testing.T, server vtgateservice.VTGateService, auth *authConfig) net.Conn {
+	serverConn, clientConn := net.Pipe()
+	go func() {
+		defer serverConn.Close()
+		newMysqlConn(server, serverConn, 1).serve(auth)
+	}()
+	return clientConn
+}
+
+func TestHandshake(t *testing.T) {
+	auth := &authConfig{user: "vt", password: "secret"}
+	conn := startConn(t, &fakeVTGate{}, auth)
+	defer conn.Close()
+	if _, response := connect(t, conn, "vt", "secret", "@replica"); response[0] != okPacket {
+		t.Errorf("handshake response: %v, want OK", response)
+	}
+
+	conn = startConn(t, &fakeVTGate{}, auth)
+	defer conn.Close()
+	_, response := connect(t, conn, "vt", "wrong", "@replica")
+	pr := &packetReader{data: response}
+	if pr.byte() != errPacket {
+		t.Fatalf("handshake response: %v, want error", response)
+	}
+	if code := uint16(pr.byte()) | uint16(pr.byte())<<8; code != errAccessDenied {
+		t.Errorf("error code: %d, want %d", code, errAccessDenied)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	fake := &fakeVTGate{}
+	conn := startConn(t, fake, &authConfig{})
+	defer conn.Close()
+	client, _ := connect(t, conn, "vt", "", "@replica")
+
+	packets := client.query("select id, name from user")
+	// Column count, 2 columns, EOF, 2 rows, EOF.
+	if len(packets) != 7 {
+		t.Fatalf("got %d packets, want 7: %v", len(packets), packets)
+	}
+	if !reflect.DeepEqual(packets[0], []byte{2}) {
+		t.Errorf("column count: %v, want [2]", packets[0])
+	}
+	if !reflect.DeepEqual(packets[4], []byte{1, '1', 1, 'a'}) {
+		t.Errorf("row 1: %v", packets[4])
+	}
+	if !reflect.DeepEqual(packets[5], []byte{1, '2', nullValue}) {
+		t.Errorf("row 2: %v", packets[5])
+	}
+
+	packets = client.query("insert into user(name) values ('b')")
+	if want := []byte{okPacket, 1, 3, serverStatusAutocommit, 0, 0, 0}; !reflect.DeepEqual(packets[0], want) {
+		t.Errorf("insert: %v, want %v", packets[0], want)
+	}
+
+	packets = client.query("bad query")
+	if packets[0][0] != errPacket {
+		t.Errorf("bad query: %v, want error", packets[0])
+	}
+
+	wantCalls := []string{
+		"Execute select id, name from user replica false",
+		"Execute insert into user(name) values ('b') replica false",
+		"Execute bad query replica false",
+	}
+	if !reflect.DeepEqual(fake.calls, wantCalls) {
+		t.Errorf("calls:\n%v, want\n%v", fake.calls, wantCalls)
+	}
+}
+
+func TestSession(t *testing.T) {
+	fake := &fakeVTGate{}
+	conn := startConn(t, fake, &authConfig{})
+	defer conn.Close()
+	client, _ := connect(t, conn, "vt", "", "")
+
+	for _, sql := range []string{
+		"set names utf8",
+		"set names 'utf8'",
+		"set character_set_results = utf8mb4",
+		"set character_set_results = NULL",
+		"set sql_mode = '', wait_timeout = 28800",
+		"use user",
+		"use `user@replica`",
+		"use `@rdonly`",
+		"select id, name from user",
+		"begin",
+		"insert into user(name) values ('b')",
+		"commit",
+		"set autocommit = 0",
+		"insert into user(name) values ('b')",
+		"rollback",
+		"set autocommit = 1",
+	} {
+		if packets := client.query(sql); packets[0][0] == errPacket {
+			t.Errorf("%s: %s", sql, packets[0])
+		}
+	}
+	wantCalls := []string{
+		"Execute select id, name from user rdonly false",
+		"Begin",
+		"Execute insert into user(name) values ('b') master true",
+		"Commit",
+		"Begin",
+		"Execute insert into user(name) values ('b') master true",
+		"Rollback",
+	}
+	if !reflect.DeepEqual(fake.calls, wantCalls) {
+		t.Errorf("calls:\n%v, want\n%v", fake.calls, wantCalls)
+	}
+}
+
+func TestSessionErrors(t *testing.T) {
+	fake := &fakeVTGate{}
+	conn := startConn(t, fake, &authConfig{})
+	defer conn.Close()
+	client, _ := connect(t, conn, "vt", "", "@rdonly")
+
+	testcases := []struct {
+		sql  string
+		code uint16
+	}{
+		{"use other", errBadDB},
+		{"use `user@spare`", errBadDB},
+		{"use `@spare`", errBadDB},
+		{"set names latin1", errUnknownCharacterSet},
+		{"set character_set_client = latin1", errUnknownCharacterSet},
+		{"set character_set_client = NULL", errUnknownCharacterSet},
+		{"set sql_select_limit = 10", errUnknownSystemVariable},
+		{"set autocommit = 0, tx_isolation = 'SERIALIZABLE'", errUnknownSystemVariable},
+	}
+	for _, tc := range testcases {
+		packets := client.query(tc.sql)
+		if packets[0][0] != errPacket {
+			t.Errorf("%s: %v, want error", tc.sql, packets[0])
+			continue
+		}
+		if code := binary.LittleEndian.Uint16(packets[0][1:]); code != tc.code {
+			t.Errorf("%s: error code %d, want %d", tc.sql, code, tc.code)
+		}
+	}
+
+	// The session is unchanged.
+	client.query("select id, name from user")
+	wantCalls := []string{"Execute select id, name from user rdonly false"}
+	if !reflect.DeepEqual(fake.calls, wantCalls) {
+		t.Errorf("calls:\n%v, want\n%v", fake.calls, wantCalls)
+	}
+
+	// An unknown keyspace can't be selected on connect either.
+	conn = startConn(t, fake, &authConfig{})
+	defer conn.Close()
+	if _, response := connect(t, conn, "vt", "", "other@replica"); response[0] != errPacket {
+		t.Errorf("handshake response: %v, want error", response)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	testcases := []struct {
+		in         string
+		keyspace   string
+		tabletType topo.TabletType
+		err        string
+	}{
+		{"", "", topo.TYPE_MASTER, ""},
+		{"@replica", "", topo.TYPE_REPLICA, ""},
+		{"@RDONLY", "", topo.TYPE_RDONLY, ""},
+		{"user", "user", topo.TYPE_MASTER, ""},
+		{"user@replica", "user", topo.TYPE_REPLICA, ""},
+		{"test_keyspace-2", "test_keyspace-2", topo.TYPE_MASTER, ""},
+		{"@spare", "", "", "Unknown database '@spare': spare is not a serving tablet type"},
+		{"user@", "", "", "Unknown database 'user@':  is not a serving tablet type"},
+		{"user.t", "", "", "Unknown database 'user.t': invalid keyspace name 'user.t'"},
+		{"a@b@replica", "", "", "Unknown database 'a@b@replica': b@replica is not a serving tablet type"},
+	}
+	for _, tc := range testcases {
+		keyspace, tabletType, err := parseTarget(tc.in)
+		var errMessage string
+		if err != nil {
+			errMessage = err.message
+		}
+		if keyspace != tc.keyspace || tabletType != tc.tabletType || errMessage != tc.err {
+			t.Errorf("parseTarget(%s): %s, %s, %q, want %s, %s, %q", tc.in, keyspace, tabletType, errMessage, tc.keyspace, tc.tabletType, tc.err)
+		}
+	}
+}
+
+func TestListener(t *testing.T) {
+	l, err := NewListener(&fakeVTGate{}, "localhost:0", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go l.Accept()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client, response := connect(t, conn, "vt", "", "")
+	if response[0] != okPacket {
+		t.Fatalf("handshake response: %v, want OK", response)
+	}
+	if packets := client.query("select id, name from user"); len(packets) != 7 {
+		t.Errorf("got %d packets, want 7", len(packets))
+	}
+}
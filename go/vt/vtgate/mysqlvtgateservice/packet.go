@@ -0,0 +1,235 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mysqlvtgateservice
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// These are the parts of the MySQL client/server protocol
+// that the server uses.
+const (
+	protocolVersion = 10
+	maxPacketSize   = 1<<24 - 1
+
+	// Capability flags.
+	clientLongPassword     = 1
+	clientFoundRows        = 1 << 1
+	clientLongFlag         = 1 << 2
+	clientConnectWithDB    = 1 << 3
+	clientProtocol41       = 1 << 9
+	clientTransactions     = 1 << 13
+	clientSecureConnection = 1 << 15
+	clientMultiResults     = 1 << 17
+	clientPluginAuth       = 1 << 19
+
+	serverCapabilities = clientLongPassword | clientFoundRows | clientLongFlag |
+		clientConnectWithDB | clientProtocol41 | clientTransactions |
+		clientSecureConnection | clientMultiResults | clientPluginAuth
+
+	// Status flags.
+	serverStatusInTrans    = 1
+	serverStatusAutocommit = 1 << 1
+
+	// Commands.
+	comQuit   = 0x01
+	comInitDB = 0x02
+	comQuery  = 0x03
+	comPing   = 0x0e
+
+	// Packet headers.
+	okPacket  = 0x00
+	eofPacket = 0xfe
+	errPacket = 0xff
+	nullValue = 0xfb
+
+	// Character sets.
+	charsetUTF8   = 33
+	charsetBinary = 63
+
+	// Error codes.
+	errUnknownComError       = 1047
+	errAccessDenied          = 1045
+	errBadDB                 = 1049
+	errUnknown               = 1105
+	errUnknownCharacterSet   = 1115
+	errUnknownSystemVariable = 1193
+
+	nativePassword = "mysql_native_password"
+)
+
+// packetConn reads and writes the packets of a connection.
+// Every packet has a sequence number, which restarts from
+// zero with each command.
+type packetConn struct {
+	reader   *bufio.Reader
+	writer   *bufio.Writer
+	sequence uint8
+}
+
+func newPacketConn(rw io.ReadWriter) *packetConn {
+	return &packetConn{
+		reader: bufio.NewReader(rw),
+		writer: bufio.NewWriter(rw),
+	}
+}
+
+// readPacket reads the next packet. Payloads of maxPacketSize
+// and more are split across several packets.
+func (pc *packetConn) readPacket() ([]byte, error) {
+	var data []byte
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(pc.reader, header[:]); err != nil {
+			return nil, err
+		}
+		length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+		if header[3] != pc.sequence {
+			return nil, fmt.Errorf("invalid sequence number %d, want %d", header[3], pc.sequence)
+		}
+		pc.sequence++
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(pc.reader, payload); err != nil {
+			return nil, err
+		}
+		data = append(data, payload...)
+		if length < maxPacketSize {
+			return data, nil
+		}
+	}
+}
+
+// writePacket buffers data as the next packet. It's only
+// sent by flush.
+func (pc *packetConn) writePacket(data []byte) error {
+	for {
+		length := len(data)
+		if length > maxPacketSize {
+			length = maxPacketSize
+		}
+		header := []byte{byte(length), byte(length >> 8), byte(length >> 16), pc.sequence}
+		pc.sequence++
+		if _, err := pc.writer.Write(header); err != nil {
+			return err
+		}
+		if _, err := pc.writer.Write(data[:length]); err != nil {
+			return err
+		}
+		data = data[length:]
+		// A payload of exactly maxPacketSize is followed by
+		// an empty packet.
+		if length < maxPacketSize {
+			return nil
+		}
+	}
+}
+
+func (pc *packetConn) flush() error {
+	return pc.writer.Flush()
+}
+
+// packetBuilder builds the payload of a packet.
+type packetBuilder []byte
+
+func (pb *packetBuilder) byte(b byte) {
+	*pb = append(*pb, b)
+}
+
+func (pb *packetBuilder) uint16(v uint16) {
+	*pb = append(*pb, byte(v), byte(v>>8))
+}
+
+func (pb *packetBuilder) uint32(v uint32) {
+	*pb = append(*pb, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func (pb *packetBuilder) bytes(b []byte) {
+	*pb = append(*pb, b...)
+}
+
+func (pb *packetBuilder) nulString(s string) {
+	*pb = append(*pb, s...)
+	*pb = append(*pb, 0)
+}
+
+func (pb *packetBuilder) lenEncInt(v uint64) {
+	switch {
+	case v < 251:
+		pb.byte(byte(v))
+	case v < 1<<16:
+		pb.byte(0xfc)
+		pb.uint16(uint16(v))
+	case v < 1<<24:
+		*pb = append(*pb, 0xfd, byte(v), byte(v>>8), byte(v>>16))
+	default:
+		pb.byte(0xfe)
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], v)
+		pb.bytes(buf[:])
+	}
+}
+
+func (pb *packetBuilder) lenEncString(b []byte) {
+	pb.lenEncInt(uint64(len(b)))
+	pb.bytes(b)
+}
+
+// packetReader reads the fields of a payload.
+type packetReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+var errShortPacket = fmt.Errorf("packet is too short")
+
+func (pr *packetReader) next(n int) []byte {
+	if pr.err != nil {
+		return nil
+	}
+	if pr.pos+n > len(pr.data) {
+		pr.err = errShortPacket
+		return nil
+	}
+	b := pr.data[pr.pos : pr.pos+n]
+	pr.pos += n
+	return b
+}
+
+func (pr *packetReader) byte() byte {
+	if b := pr.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (pr *packetReader) uint32() uint32 {
+	if b := pr.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (pr *packetReader) nulString() string {
+	if pr.err != nil {
+		return ""
+	}
+	for i := pr.pos; i < len(pr.data); i++ {
+		if pr.data[i] == 0 {
+			s := string(pr.data[pr.pos:i])
+			pr.pos = i + 1
+			return s
+		}
+	}
+	pr.err = errShortPacket
+	return ""
+}
+
+func (pr *packetReader) done() bool {
+	return pr.err != nil || pr.pos >= len(pr.data)
+}
@@ -0,0 +1,106 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mysqlvtgateservice provides the MySQL protocol glue for
+// vtgate. It lets the MySQL clients connect to vtgate unchanged: the
+// queries are executed by the V3 API, and USE, SET autocommit and the
+// transaction statements change the session of the connection.
+// The database name of USE is keyspace@tablet_type, like "user@replica":
+// the keyspace must exist, and the tablet type is the one of the queries
+// outside of transactions. Both parts are optional. The queries are
+// still routed by their tables.
+package mysqlvtgateservice
+
+import (
+	"flag"
+	"fmt"
+	"net"
+
+	log "github.com/golang/glog"
+	"github.com/youtube/vitess/go/stats"
+	"github.com/youtube/vitess/go/sync2"
+	"github.com/youtube/vitess/go/vt/servenv"
+	"github.com/youtube/vitess/go/vt/vtgate"
+	"github.com/youtube/vitess/go/vt/vtgate/vtgateservice"
+)
+
+var (
+	mysqlServerPort     = flag.Int("mysql_server_port", 0, "port for the MySQL protocol listener of vtgate, disabled if 0")
+	mysqlServerUser     = flag.String("mysql_server_user", "", "user the MySQL clients must connect with. Any user is accepted if empty")
+	mysqlServerPassword = flag.String("mysql_server_password", "", "password of -mysql_server_user")
+
+	connCount    = stats.NewInt("MysqlServerConnCount")
+	connAccepted = stats.NewInt("MysqlServerConnAccepted")
+)
+
+// Listener accepts the MySQL connections, and serves them
+// with a VTGateService.
+type Listener struct {
+	server   vtgateservice.VTGateService
+	auth     *authConfig
+	listener net.Listener
+	connID   sync2.AtomicInt64
+}
+
+// NewListener creates a Listener on address. user and password are
+// the credentials the clients must use, unless user is empty.
+func NewListener(server vtgateservice.VTGateService, address, user, password string) (*Listener, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &Listener{
+		server:   server,
+		auth:     &authConfig{user: user, password: password},
+		listener: listener,
+	}, nil
+}
+
+// Addr returns the address of the listener.
+func (l *Listener) Addr() net.Addr {
+	return l.listener.Addr()
+}
+
+// Accept serves the connections until the listener is closed.
+func (l *Listener) Accept() {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			return
+		}
+		connAccepted.Add(1)
+		connCount.Add(1)
+		go func() {
+			defer func() {
+				conn.Close()
+				connCount.Add(-1)
+			}()
+			mc := newMysqlConn(l.server, conn, uint32(l.connID.Add(1)))
+			log.Infof("mysql connection %d from %s", mc.connectionID, remoteAddr(conn))
+			mc.serve(l.auth)
+		}()
+	}
+}
+
+// Close stops accepting connections. The open connections
+// are served until their clients quit.
+func (l *Listener) Close() {
+	l.listener.Close()
+}
+
+func init() {
+	vtgate.RegisterVTGates = append(vtgate.RegisterVTGates, func(vtGate vtgateservice.VTGateService) {
+		if *mysqlServerPort == 0 {
+			return
+		}
+		servenv.OnRun(func() {
+			l, err := NewListener(vtGate, fmt.Sprintf(":%d", *mysqlServerPort), *mysqlServerUser, *mysqlServerPassword)
+			if err != nil {
+				log.Fatalf("cannot listen for MySQL connections on port %d: %v", *mysqlServerPort, err)
+			}
+			servenv.OnTerm(l.Close)
+			go l.Accept()
+		})
+	})
+}
@@ -0,0 +1,198 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package planbuilder
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/youtube/vitess/go/vt/sqlparser"
+)
+
+// The following constants define the aggregate functions
+// that VTGate can compute from per-shard partial results.
+const (
+	AggrGroupBy = ""
+	AggrCount   = "count"
+	AggrSum     = "sum"
+	AggrMin     = "min"
+	AggrMax     = "max"
+	AggrAvg     = "avg"
+)
+
+var errPostProcessing = errors.New("multi-shard query has post-processing constructs")
+
+// AggregateCol describes how VTGate computes a column of the final
+// result of an Aggregate plan from the partial results of the shards.
+type AggregateCol struct {
+	// Func is the aggregate function, or AggrGroupBy if the
+	// column is part of the GROUP BY key.
+	Func string
+	// Index is the position of the column in the shard results.
+	// An avg is sent as a sum followed by a count, which makes
+	// Index+1 the position of the count.
+	Index int
+	// Name is the name of the result column. It's used only for avg
+	// because the shards return its sum and count instead.
+	Name string `json:",omitempty"`
+}
+
+// buildAggregatePlan converts a multi-shard select plan into its
+// Aggregate counterpart. The shards are sent partial aggregates:
+// avg is rewritten as a sum and a count. VTGate combines the
+// partial results of rows that have the same GROUP BY key. The
+// ORDER BY and LIMIT clauses are applied by VTGate after the
+// combination, and are therefore not sent to the shards.
+func buildAggregatePlan(sel *sqlparser.Select, plan *Plan) error {
+	if sel.Distinct != "" || sel.Having != nil {
+		return errPostProcessing
+	}
+	groupBy, err := getGroupByPositions(sel)
+	if err != nil {
+		return err
+	}
+	// Positions may change after rewriting avg.
+	for i, groupExpr := range sel.GroupBy {
+		if _, ok := groupExpr.(sqlparser.NumVal); ok {
+			if sel.GroupBy[i], err = groupByValue(sel.SelectExprs[groupBy[i]].(*sqlparser.NonStarExpr)); err != nil {
+				return err
+			}
+		}
+	}
+	isGroupBy := make(map[int]bool)
+	for _, pos := range groupBy {
+		isGroupBy[pos] = true
+	}
+	var selectExprs sqlparser.SelectExprs
+	var aggregates []AggregateCol
+	for i, selectExpr := range sel.SelectExprs {
+		expr, ok := selectExpr.(*sqlparser.NonStarExpr)
+		if !ok {
+			return fmt.Errorf("unsupported aggregate expression: %s", sqlparser.String(selectExpr))
+		}
+		col := AggregateCol{Index: len(selectExprs)}
+		selectExprs = append(selectExprs, expr)
+		if isGroupBy[i] {
+			aggregates = append(aggregates, col)
+			continue
+		}
+		funcExpr, ok := expr.Expr.(*sqlparser.FuncExpr)
+		if !ok || !funcExpr.IsAggregate() {
+			if exprHasAggregates(expr.Expr) {
+				return errPostProcessing
+			}
+			return fmt.Errorf("expression must be in group by: %s", sqlparser.String(expr))
+		}
+		if funcExpr.Distinct {
+			return fmt.Errorf("unsupported distinct aggregate: %s", sqlparser.String(expr))
+		}
+		col.Func = strings.ToLower(string(funcExpr.Name))
+		switch col.Func {
+		case AggrCount, AggrSum, AggrMin, AggrMax:
+		case AggrAvg:
+			col.Name = string(expr.As)
+			if col.Name == "" {
+				col.Name = sqlparser.String(funcExpr)
+			}
+			sum := *funcExpr
+			sum.Name = []byte(AggrSum)
+			count := *funcExpr
+			count.Name = []byte(AggrCount)
+			selectExprs[col.Index] = &sqlparser.NonStarExpr{Expr: &sum}
+			selectExprs = append(selectExprs, &sqlparser.NonStarExpr{Expr: &count})
+		default:
+			return fmt.Errorf("unsupported aggregate function: %s", funcExpr.Name)
+		}
+		aggregates = append(aggregates, col)
+	}
+	orderBy, err := getOrderByCols(sel.OrderBy)
+	if err != nil {
+		return err
+	}
+	offset, rowcount, err := sel.Limit.Limits()
+	if err != nil {
+		return fmt.Errorf("invalid limit: %v", err)
+	}
+	sel.SelectExprs = selectExprs
+	sel.OrderBy = nil
+	sel.Limit = nil
+	switch plan.ID {
+	case SelectEqual:
+		plan.ID = SelectEqualAggregate
+	case SelectIN:
+		plan.ID = SelectINAggregate
+	case SelectScatter:
+		plan.ID = SelectScatterAggregate
+	case SelectRange:
+		plan.ID = SelectRangeAggregate
+	default:
+		panic("unexpected")
+	}
+	plan.Aggregates = aggregates
+	plan.OrderBy = orderBy
+	plan.Offset = offset
+	plan.Rowcount = rowcount
+	return nil
+}
+
+// getGroupByPositions returns the positions of the select expressions
+// referenced by the GROUP BY clause. Every GROUP BY expression must be
+// in the select list, referenced by its value, its alias or its position.
+func getGroupByPositions(sel *sqlparser.Select) ([]int, error) {
+	var positions []int
+	for _, groupExpr := range sel.GroupBy {
+		pos := -1
+		if num, ok := groupExpr.(sqlparser.NumVal); ok {
+			n, err := strconv.ParseInt(string(num), 0, 64)
+			if err != nil || n < 1 || n > int64(len(sel.SelectExprs)) {
+				return nil, fmt.Errorf("invalid group by position: %s", sqlparser.String(num))
+			}
+			pos = int(n - 1)
+		} else {
+			pos = findSelectExpr(sel.SelectExprs, groupExpr)
+			if pos == -1 {
+				return nil, fmt.Errorf("group by expression must be in select list: %s", sqlparser.String(groupExpr))
+			}
+		}
+		if expr, ok := sel.SelectExprs[pos].(*sqlparser.NonStarExpr); !ok || exprHasAggregates(expr.Expr) {
+			return nil, fmt.Errorf("invalid group by expression: %s", sqlparser.String(groupExpr))
+		}
+		positions = append(positions, pos)
+	}
+	return positions, nil
+}
+
+// groupByValue returns the value to be sent to the shards
+// for a GROUP BY clause that references expr by position.
+func groupByValue(expr *sqlparser.NonStarExpr) (sqlparser.ValExpr, error) {
+	if expr.As != nil {
+		return &sqlparser.ColName{Name: expr.As}, nil
+	}
+	if val, ok := expr.Expr.(sqlparser.ValExpr); ok {
+		return val, nil
+	}
+	return nil, fmt.Errorf("invalid group by expression: %s", sqlparser.String(expr))
+}
+
+// findSelectExpr returns the position of the select expression
+// that matches expr, or -1 if there's none.
+func findSelectExpr(selectExprs sqlparser.SelectExprs, expr sqlparser.ValExpr) int {
+	val := sqlparser.String(expr)
+	for i, selectExpr := range selectExprs {
+		selectExpr, ok := selectExpr.(*sqlparser.NonStarExpr)
+		if !ok {
+			continue
+		}
+		if sqlparser.String(selectExpr.Expr) == val {
+			return i
+		}
+		if col, ok := expr.(*sqlparser.ColName); ok && col.Qualifier == nil && string(col.Name) == string(selectExpr.As) {
+			return i
+		}
+	}
+	return -1
+}
@@ -7,6 +7,7 @@ package planbuilder
 import (
 	"bytes"
 	"fmt"
+	"strings"
 
 	"github.com/youtube/vitess/go/vt/sqlparser"
 )
@@ -21,7 +22,8 @@ func buildUpdatePlan(upd *sqlparser.Update, schema *Schema) *Plan {
 	if plan.Reason != "" {
 		return plan
 	}
-	if !plan.Table.Keyspace.Sharded {
+	// Writes to reference tables are sent to their source keyspace.
+	if !plan.Table.Keyspace.Sharded || plan.Table.IsReference() {
 		plan.ID = UpdateUnsharded
 		return plan
 	}
@@ -30,13 +32,19 @@ func buildUpdatePlan(upd *sqlparser.Update, schema *Schema) *Plan {
 	switch plan.ID {
 	case SelectEqual:
 		plan.ID = UpdateEqual
-	case SelectIN, SelectScatter, SelectKeyrange:
+	case SelectIN:
+		plan.ID = UpdateIN
+	case SelectScatter, SelectRange:
+		plan.ID = UpdateScatter
+		plan.AllowScatter = hasScatterDirective(upd.Comments)
+	case SelectKeyrange:
 		plan.ID = NoPlan
 		plan.Reason = "update has multi-shard where clause"
 		return plan
 	default:
 		panic("unexpected")
 	}
+	plan.Rewritten = generateQuery(upd)
 	if isIndexChanging(upd.Exprs, plan.Table.ColVindexes) {
 		plan.ID = NoPlan
 		plan.Reason = "index is changing"
@@ -45,9 +53,9 @@ func buildUpdatePlan(upd *sqlparser.Update, schema *Schema) *Plan {
 }
 
 func isIndexChanging(setClauses sqlparser.UpdateExprs, colVindexes []*ColVindex) bool {
-	vindexCols := make([]string, len(colVindexes))
-	for i, index := range colVindexes {
-		vindexCols[i] = index.Col
+	var vindexCols []string
+	for _, index := range colVindexes {
+		vindexCols = append(vindexCols, index.Columns()...)
 	}
 	for _, assignment := range setClauses {
 		if sqlparser.StringIn(string(assignment.Name.Name), vindexCols...) {
@@ -67,7 +75,8 @@ func buildDeletePlan(del *sqlparser.Delete, schema *Schema) *Plan {
 	if plan.Reason != "" {
 		return plan
 	}
-	if !plan.Table.Keyspace.Sharded {
+	// Writes to reference tables are sent to their source keyspace.
+	if !plan.Table.Keyspace.Sharded || plan.Table.IsReference() {
 		plan.ID = DeleteUnsharded
 		return plan
 	}
@@ -76,17 +85,30 @@ func buildDeletePlan(del *sqlparser.Delete, schema *Schema) *Plan {
 	switch plan.ID {
 	case SelectEqual:
 		plan.ID = DeleteEqual
-		plan.Subquery = generateDeleteSubquery(del, plan.Table)
-	case SelectIN, SelectScatter, SelectKeyrange:
+		plan.Subquery = generateDeleteSubquery(del, plan.Table, false)
+	case SelectIN:
+		plan.ID = DeleteIN
+		plan.Subquery = generateDeleteSubquery(del, plan.Table, true)
+	case SelectScatter, SelectRange:
+		plan.ID = DeleteScatter
+		plan.AllowScatter = hasScatterDirective(del.Comments)
+		plan.Subquery = generateDeleteSubquery(del, plan.Table, true)
+	case SelectKeyrange:
 		plan.ID = NoPlan
 		plan.Reason = "delete has multi-shard where clause"
+		return plan
 	default:
 		panic("unexpected")
 	}
+	plan.Rewritten = generateQuery(del)
 	return plan
 }
 
-func generateDeleteSubquery(del *sqlparser.Delete, table *Table) string {
+// generateDeleteSubquery generates the query that fetches the owned
+// vindex values of the rows to be deleted. If withPrimary is set, the
+// primary vindex column is also fetched, at the end, if it's not owned.
+// MultiColumn vindexes fetch all their columns, in order.
+func generateDeleteSubquery(del *sqlparser.Delete, table *Table, withPrimary bool) string {
 	if len(table.Owned) == 0 {
 		return ""
 	}
@@ -95,11 +117,40 @@ func generateDeleteSubquery(del *sqlparser.Delete, table *Table) string {
 	prefix := ""
 	for _, cv := range table.Owned {
 		buf.WriteString(prefix)
-		buf.WriteString(cv.Col)
+		buf.WriteString(strings.Join(cv.Columns(), ", "))
 		prefix = ", "
 	}
+	if withPrimary && OwnedPosition(table, table.ColVindexes[0]) == -1 {
+		buf.WriteString(prefix)
+		buf.WriteString(strings.Join(table.ColVindexes[0].Columns(), ", "))
+	}
 	fmt.Fprintf(buf, " from %s", table.Name)
 	buf.WriteString(sqlparser.String(del.Where))
 	buf.WriteString(" for update")
 	return buf.String()
 }
+
+// OwnedPosition returns the position of colVindex
+// in the Owned list of table, or -1 if it's not owned.
+func OwnedPosition(table *Table, colVindex *ColVindex) int {
+	for i, cv := range table.Owned {
+		if cv == colVindex {
+			return i
+		}
+	}
+	return -1
+}
+
+// ScatterDirective is the query comment that allows an
+// update or delete to be sent to all the shards of a keyspace.
+const ScatterDirective = "allow_scatter"
+
+func hasScatterDirective(comments sqlparser.Comments) bool {
+	for _, comment := range comments {
+		text := strings.TrimSuffix(strings.TrimPrefix(string(comment), "/*"), "*/")
+		if strings.EqualFold(strings.TrimSpace(text), ScatterDirective) {
+			return true
+		}
+	}
+	return false
+}
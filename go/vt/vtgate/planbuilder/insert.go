@@ -20,7 +20,8 @@ func buildInsertPlan(ins *sqlparser.Insert, schema *Schema) *Plan {
 	if plan.Reason != "" {
 		return plan
 	}
-	if !plan.Table.Keyspace.Sharded {
+	// Writes to reference tables are sent to their source keyspace.
+	if !plan.Table.Keyspace.Sharded || plan.Table.IsReference() {
 		plan.ID = InsertUnsharded
 		return plan
 	}
@@ -39,53 +40,116 @@ func buildInsertPlan(ins *sqlparser.Insert, schema *Schema) *Plan {
 	default:
 		panic("unexpected")
 	}
-	if len(values) != 1 {
-		plan.Reason = "multi-row inserts not supported"
-		return plan
-	}
-	switch values[0].(type) {
-	case *sqlparser.Subquery:
-		plan.Reason = "subqueries not allowed"
-		return plan
-	}
-	row := values[0].(sqlparser.ValTuple)
-	if len(ins.Columns) != len(row) {
-		plan.Reason = "column list doesn't match values"
-		return plan
+	for _, value := range values {
+		switch value.(type) {
+		case *sqlparser.Subquery:
+			plan.Reason = "subqueries not allowed"
+			return plan
+		}
+		if len(ins.Columns) != len(value.(sqlparser.ValTuple)) {
+			plan.Reason = "column list doesn't match values"
+			return plan
+		}
 	}
 	colVindexes := schema.Tables[tablename].ColVindexes
 	plan.ID = InsertSharded
-	plan.Values = make([]interface{}, 0, len(colVindexes))
+	rows := make([]interface{}, len(values))
+	for i := range values {
+		rows[i] = make([]interface{}, 0, len(colVindexes))
+	}
 	for _, index := range colVindexes {
-		if err := buildIndexPlan(ins, tablename, index, plan); err != nil {
+		if err := buildIndexPlan(ins, index, rows); err != nil {
 			plan.ID = NoPlan
 			plan.Reason = err.Error()
 			return plan
 		}
 	}
+	// The values are replaced only after all of them are
+	// collected because vindexes can share columns.
+	for _, index := range colVindexes {
+		replaceIndexValues(ins, index)
+	}
+	plan.Values = rows
 	plan.Rewritten = generateQuery(ins)
+	buildInsertParts(ins, plan)
 	return plan
 }
 
-func buildIndexPlan(ins *sqlparser.Insert, tablename string, colVindex *ColVindex, plan *Plan) error {
-	pos := -1
+// buildIndexPlan adds the value of the vindex column of each row
+// to rows. The value of a MultiColumn vindex is a tuple that has
+// the values of all its columns. If a column is not in the list,
+// it's added with NULL values so that VTGate can supply them.
+func buildIndexPlan(ins *sqlparser.Insert, colVindex *ColVindex, rows []interface{}) error {
+	cols := colVindex.Columns()
+	positions := make([]int, len(cols))
+	for i, col := range cols {
+		positions[i] = findOrAddColumn(ins, col)
+	}
+	values := ins.Rows.(sqlparser.Values)
+	for i := range values {
+		row := values[i].(sqlparser.ValTuple)
+		vals := make([]interface{}, len(positions))
+		for j, pos := range positions {
+			val, err := asInterface(row[pos])
+			if err != nil {
+				return fmt.Errorf("could not convert val: %s, pos: %d: %v", sqlparser.String(row[pos]), pos, err)
+			}
+			vals[j] = val
+		}
+		if len(colVindex.Cols) == 0 {
+			rows[i] = append(rows[i].([]interface{}), vals[0])
+		} else {
+			rows[i] = append(rows[i].([]interface{}), vals)
+		}
+	}
+	return nil
+}
+
+// replaceIndexValues replaces the values of the vindex columns
+// with the bind vars that will hold them at execution time.
+func replaceIndexValues(ins *sqlparser.Insert, colVindex *ColVindex) {
+	values := ins.Rows.(sqlparser.Values)
+	for _, col := range colVindex.Columns() {
+		pos := findOrAddColumn(ins, col)
+		for i := range values {
+			values[i].(sqlparser.ValTuple)[pos] = sqlparser.ValArg([]byte(":" + InsertVarName(col, i)))
+		}
+	}
+}
+
+// findOrAddColumn returns the position of col in the column list
+// of the insert. If it's not there, it's added with NULL values.
+func findOrAddColumn(ins *sqlparser.Insert, col string) int {
 	for i, column := range ins.Columns {
-		if colVindex.Col == sqlparser.GetColName(column.(*sqlparser.NonStarExpr).Expr) {
-			pos = i
-			break
+		if col == sqlparser.GetColName(column.(*sqlparser.NonStarExpr).Expr) {
+			return i
 		}
 	}
-	if pos == -1 {
-		pos = len(ins.Columns)
-		ins.Columns = append(ins.Columns, &sqlparser.NonStarExpr{Expr: &sqlparser.ColName{Name: []byte(colVindex.Col)}})
-		ins.Rows.(sqlparser.Values)[0] = append(ins.Rows.(sqlparser.Values)[0].(sqlparser.ValTuple), &sqlparser.NullVal{})
+	values := ins.Rows.(sqlparser.Values)
+	ins.Columns = append(ins.Columns, &sqlparser.NonStarExpr{Expr: &sqlparser.ColName{Name: []byte(col)}})
+	for i := range values {
+		values[i] = append(values[i].(sqlparser.ValTuple), &sqlparser.NullVal{})
 	}
-	row := ins.Rows.(sqlparser.Values)[0].(sqlparser.ValTuple)
-	val, err := asInterface(row[pos])
-	if err != nil {
-		return fmt.Errorf("could not convert val: %s, pos: %d: %v", sqlparser.String(row[pos]), pos, err)
+	return len(ins.Columns) - 1
+}
+
+// buildInsertParts splits the rewritten insert into the parts
+// that VTGate reassembles into one insert per target shard.
+func buildInsertParts(ins *sqlparser.Insert, plan *Plan) {
+	buf := sqlparser.NewTrackedBuffer(nil)
+	buf.Myprintf("insert %vinto %v%v values ", ins.Comments, ins.Table, ins.Columns)
+	plan.Prefix = buf.String()
+	values := ins.Rows.(sqlparser.Values)
+	plan.Mid = make([]string, len(values))
+	for i, row := range values {
+		plan.Mid[i] = sqlparser.String(row)
 	}
-	plan.Values = append(plan.Values.([]interface{}), val)
-	row[pos] = sqlparser.ValArg([]byte(fmt.Sprintf(":_%s", colVindex.Col)))
-	return nil
+	plan.Suffix = sqlparser.String(ins.OnDup)
+}
+
+// InsertVarName returns the name of the bind var that holds
+// the value of the vindex column col for the row at rowNum
+// of an InsertSharded plan.
+func InsertVarName(col string, rowNum int) string {
+	return fmt.Sprintf("_%s_%d", col, rowNum)
 }
@@ -0,0 +1,543 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package planbuilder
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/youtube/vitess/go/vt/sqlparser"
+)
+
+// The following constants identify the tables
+// referenced by an expression of a join.
+const (
+	sideNone  = 0
+	sideLeft  = 1
+	sideRight = 2
+)
+
+var errJoinPostProcessing = errors.New("unsupported: post-processing constructs in cross-shard join")
+
+// joinTable is one of the two tables of a join.
+type joinTable struct {
+	alias string
+	expr  *sqlparser.AliasedTableExpr
+	table *Table
+}
+
+// joinBuilder builds the two selects that VTGate
+// sends for a nested-loop join.
+type joinBuilder struct {
+	left, right       *joinTable
+	leftJoin          bool
+	leftSel, rightSel *sqlparser.Select
+	cols              []int
+	joinVars          map[string]int
+}
+
+// getJoin returns the join of the FROM clause if it's a
+// join of two simple tables. Comma joins are treated like
+// inner joins.
+func getJoin(tableExprs sqlparser.TableExprs) (*sqlparser.JoinTableExpr, bool) {
+	switch len(tableExprs) {
+	case 1:
+		join, ok := tableExprs[0].(*sqlparser.JoinTableExpr)
+		if !ok {
+			return nil, false
+		}
+		_, lok := join.LeftExpr.(*sqlparser.AliasedTableExpr)
+		_, rok := join.RightExpr.(*sqlparser.AliasedTableExpr)
+		return join, lok && rok
+	case 2:
+		_, lok := tableExprs[0].(*sqlparser.AliasedTableExpr)
+		_, rok := tableExprs[1].(*sqlparser.AliasedTableExpr)
+		return &sqlparser.JoinTableExpr{
+			LeftExpr:  tableExprs[0],
+			Join:      sqlparser.AST_JOIN,
+			RightExpr: tableExprs[1],
+		}, lok && rok
+	}
+	return nil, false
+}
+
+// buildJoinPlan builds the plan for a select that joins two tables.
+// If both tables are in the same unsharded keyspace, if one of them
+// is a reference table of the keyspace of the other, or if the join
+// condition matches the same unique vindex of both tables, the join
+// is sent to the shards as is. Otherwise, VTGate performs a nested-loop
+// join: the right table is queried for every row of the left table,
+// with the values of the left row supplied as bind vars.
+func buildJoinPlan(sel *sqlparser.Select, join *sqlparser.JoinTableExpr, schema *Schema) *Plan {
+	plan := &Plan{ID: NoPlan}
+	jb := &joinBuilder{joinVars: make(map[string]int)}
+	switch join.Join {
+	case sqlparser.AST_JOIN, sqlparser.AST_STRAIGHT_JOIN, sqlparser.AST_CROSS_JOIN:
+	case sqlparser.AST_LEFT_JOIN:
+		jb.leftJoin = true
+	default:
+		plan.Reason = fmt.Sprintf("unsupported join: %s", join.Join)
+		return plan
+	}
+	if jb.left, plan.Reason = newJoinTable(join.LeftExpr, schema); plan.Reason != "" {
+		return plan
+	}
+	if jb.right, plan.Reason = newJoinTable(join.RightExpr, schema); plan.Reason != "" {
+		return plan
+	}
+	if jb.left.alias == jb.right.alias {
+		plan.Reason = fmt.Sprintf("duplicate table alias: %s", jb.left.alias)
+		return plan
+	}
+	var whereConds, onConds []sqlparser.BoolExpr
+	if sel.Where != nil {
+		whereConds = splitAnd(sel.Where.Expr, nil)
+	}
+	if join.On != nil {
+		onConds = splitAnd(join.On, nil)
+	}
+	for _, cond := range append(whereConds, onConds...) {
+		if hasSubquery(cond) {
+			plan.Reason = "has subquery"
+			return plan
+		}
+	}
+
+	// A left join matches rows based on the ON clause only.
+	matchConds := onConds
+	routingConds := whereConds
+	if !jb.leftJoin {
+		matchConds = append(matchConds, whereConds...)
+		routingConds = matchConds
+	}
+	if jb.isSameShard(matchConds) {
+		return jb.buildPushdownPlan(sel, routingConds)
+	}
+
+	if err := jb.buildSelects(sel, whereConds, onConds); err != nil {
+		plan.Reason = err.Error()
+		return plan
+	}
+	if plan.Left = buildSubPlan(jb.leftSel, schema); plan.Left.ID == NoPlan {
+		plan.Reason = plan.Left.Reason
+		return plan
+	}
+	plan.Subquery = generateQuery(&sqlparser.Select{
+		Comments:    jb.rightSel.Comments,
+		SelectExprs: jb.rightSel.SelectExprs,
+		From:        jb.rightSel.From,
+		Where:       sqlparser.NewWhere(sqlparser.AST_WHERE, &sqlparser.ComparisonExpr{Left: sqlparser.NumVal("1"), Operator: "!=", Right: sqlparser.NumVal("1")}),
+	})
+	if plan.Right = buildSubPlan(jb.rightSel, schema); plan.Right.ID == NoPlan {
+		plan.Reason = plan.Right.Reason
+		return plan
+	}
+	plan.ID = SelectJoin
+	if jb.leftJoin {
+		plan.ID = SelectLeftJoin
+	}
+	plan.Cols = jb.cols
+	plan.JoinVars = jb.joinVars
+	return plan
+}
+
+func newJoinTable(tableExpr sqlparser.TableExpr, schema *Schema) (jt *joinTable, reason string) {
+	expr := tableExpr.(*sqlparser.AliasedTableExpr)
+	tablename := sqlparser.GetTableName(expr.Expr)
+	table, reason := schema.FindTable(tablename)
+	if reason != "" {
+		return nil, reason
+	}
+	jt = &joinTable{
+		alias: tablename,
+		expr:  expr,
+		table: table,
+	}
+	if expr.As != nil {
+		jt.alias = string(expr.As)
+	}
+	return jt, ""
+}
+
+// buildSubPlan builds the plan for one side of a nested-loop join.
+// The Original query of the plan is set to the generated select.
+func buildSubPlan(sel *sqlparser.Select, schema *Schema) *Plan {
+	original := generateQuery(sel)
+	plan := buildSelectPlan(sel, schema)
+	plan.Original = original
+	return plan
+}
+
+// isSameShard returns true if the rows matched by conds are
+// guaranteed to be in the same shard. This is the case if both
+// tables are in the same unsharded keyspace, if one of the tables
+// is a reference table, or if one of the conditions equates columns
+// of both tables that have the same unique vindex.
+func (jb *joinBuilder) isSameShard(conds []sqlparser.BoolExpr) bool {
+	if jb.left.table.Keyspace != jb.right.table.Keyspace {
+		return false
+	}
+	if !jb.left.table.Keyspace.Sharded {
+		return true
+	}
+	// Every shard has all the rows of a reference table. This doesn't
+	// work if it's the left table of a left join: the rows without a
+	// match would be returned by every shard.
+	if jb.right.table.IsReference() || jb.left.table.IsReference() && !jb.leftJoin {
+		return true
+	}
+	for _, cond := range conds {
+		comparison, ok := cond.(*sqlparser.ComparisonExpr)
+		if !ok || comparison.Operator != "=" {
+			continue
+		}
+		lcol, lok := comparison.Left.(*sqlparser.ColName)
+		rcol, rok := comparison.Right.(*sqlparser.ColName)
+		if !lok || !rok {
+			continue
+		}
+		lside, lerr := jb.sideOf(lcol)
+		rside, rerr := jb.sideOf(rcol)
+		if lerr != nil || rerr != nil || lside == rside {
+			continue
+		}
+		if lside == sideRight {
+			lcol, rcol = rcol, lcol
+		}
+		lindex := findUniqueVindex(jb.left.table, string(lcol.Name))
+		rindex := findUniqueVindex(jb.right.table, string(rcol.Name))
+		if lindex != nil && rindex != nil && lindex.Name == rindex.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func findUniqueVindex(table *Table, col string) *ColVindex {
+	for _, index := range table.ColVindexes {
+		if index.Col == col && IsUnique(index.Vindex) {
+			return index
+		}
+	}
+	return nil
+}
+
+// buildPushdownPlan builds the plan for a join that can be sent to the
+// shards as is. The routing is based on the conditions of the left table
+// first, and then on those of the right table. Reference tables don't
+// affect the routing.
+func (jb *joinBuilder) buildPushdownPlan(sel *sqlparser.Select, routingConds []sqlparser.BoolExpr) *Plan {
+	plan := &Plan{ID: NoPlan, Table: jb.left.table}
+	if !plan.Table.Keyspace.Sharded {
+		plan.ID = SelectUnsharded
+		return plan
+	}
+	if jb.left.table.IsReference() && jb.right.table.IsReference() {
+		plan.ID = SelectReference
+		return plan
+	}
+	firstSide := sideLeft
+	if jb.left.table.IsReference() {
+		plan.Table = jb.right.table
+		firstSide = sideRight
+	}
+	routingTable := plan.Table
+	for _, side := range []int{sideLeft, sideRight} {
+		if side == sideLeft && jb.left.table.IsReference() || side == sideRight && jb.right.table.IsReference() {
+			continue
+		}
+		var where *sqlparser.Where
+		for _, cond := range routingConds {
+			s, err := jb.exprSides(cond)
+			if err != nil {
+				continue
+			}
+			// Conditions without columns, like keyrange, are
+			// attributed to the first table that's routed.
+			if s == side || s == sideNone && side == firstSide {
+				where = addWhere(where, cond)
+			}
+		}
+		plan.Table = jb.left.table
+		if side == sideRight {
+			plan.Table = jb.right.table
+		}
+		getWhereRouting(where, plan, false)
+		if plan.ID != SelectScatter {
+			break
+		}
+		plan.Table = routingTable
+	}
+	switch plan.ID {
+	case NoPlan:
+		return plan
+	case SelectKeyrange:
+		plan.ID = NoPlan
+		plan.Reason = "unsupported: keyrange in join"
+		return plan
+	}
+	return finishSelectPlan(sel, plan)
+}
+
+// buildSelects splits the join into the selects that VTGate
+// sends for the left and right tables of a nested-loop join.
+func (jb *joinBuilder) buildSelects(sel *sqlparser.Select, whereConds, onConds []sqlparser.BoolExpr) error {
+	if hasAggregates(sel.SelectExprs) || sel.Distinct != "" || sel.GroupBy != nil || sel.Having != nil || sel.Limit != nil {
+		return errJoinPostProcessing
+	}
+	jb.leftSel = &sqlparser.Select{
+		Comments: sel.Comments,
+		From:     sqlparser.TableExprs{jb.left.expr},
+		Lock:     sel.Lock,
+	}
+	jb.rightSel = &sqlparser.Select{
+		Comments: sel.Comments,
+		From:     sqlparser.TableExprs{jb.right.expr},
+		Lock:     sel.Lock,
+	}
+	for _, selectExpr := range sel.SelectExprs {
+		expr, ok := selectExpr.(*sqlparser.NonStarExpr)
+		if !ok {
+			return fmt.Errorf("unsupported: %s in cross-shard join", sqlparser.String(selectExpr))
+		}
+		side, err := jb.exprSides(expr.Expr)
+		if err != nil {
+			return err
+		}
+		switch side {
+		case sideNone, sideLeft:
+			jb.leftSel.SelectExprs = append(jb.leftSel.SelectExprs, expr)
+			jb.cols = append(jb.cols, -len(jb.leftSel.SelectExprs))
+		case sideRight:
+			jb.rightSel.SelectExprs = append(jb.rightSel.SelectExprs, expr)
+			jb.cols = append(jb.cols, len(jb.rightSel.SelectExprs))
+		default:
+			return fmt.Errorf("unsupported: expression references both tables in cross-shard join: %s", sqlparser.String(expr))
+		}
+	}
+	// The right select needs at least one column to tell if a left row
+	// has matches.
+	if len(jb.rightSel.SelectExprs) == 0 {
+		jb.rightSel.SelectExprs = sqlparser.SelectExprs{&sqlparser.NonStarExpr{Expr: sqlparser.NumVal("1")}}
+	}
+
+	for _, cond := range whereConds {
+		side, err := jb.exprSides(cond)
+		if err != nil {
+			return err
+		}
+		switch {
+		case side == sideNone || side == sideLeft:
+			jb.leftSel.Where = addWhere(jb.leftSel.Where, cond)
+		case jb.leftJoin:
+			return fmt.Errorf("unsupported: where clause references right table of left join: %s", sqlparser.String(cond))
+		default:
+			jb.rightSel.Where = addWhere(jb.rightSel.Where, jb.bindLeftCols(cond))
+		}
+	}
+	for _, cond := range onConds {
+		side, err := jb.exprSides(cond)
+		if err != nil {
+			return err
+		}
+		if !jb.leftJoin && (side == sideNone || side == sideLeft) {
+			jb.leftSel.Where = addWhere(jb.leftSel.Where, cond)
+			continue
+		}
+		jb.rightSel.Where = addWhere(jb.rightSel.Where, jb.bindLeftCols(cond))
+	}
+
+	// The nested-loop join preserves the order of the left rows.
+	for _, order := range sel.OrderBy {
+		col, ok := order.Expr.(*sqlparser.ColName)
+		if !ok {
+			return fmt.Errorf("unsupported: order by %s in cross-shard join", sqlparser.String(order.Expr))
+		}
+		if side, err := jb.sideOf(col); err != nil || side != sideLeft {
+			return fmt.Errorf("unsupported: order by %s in cross-shard join", sqlparser.String(order.Expr))
+		}
+		jb.leftCol(col)
+		jb.leftSel.OrderBy = append(jb.leftSel.OrderBy, order)
+	}
+	return nil
+}
+
+// sideOf returns the table that col belongs to. Columns
+// must be qualified by the table name or alias.
+func (jb *joinBuilder) sideOf(col *sqlparser.ColName) (int, error) {
+	switch string(col.Qualifier) {
+	case "":
+		return sideNone, fmt.Errorf("unsupported: unqualified column %s in join", col.Name)
+	case jb.left.alias:
+		return sideLeft, nil
+	case jb.right.alias:
+		return sideRight, nil
+	}
+	return sideNone, fmt.Errorf("table %s not found in join", col.Qualifier)
+}
+
+// exprSides returns the tables referenced by expr.
+func (jb *joinBuilder) exprSides(expr sqlparser.Expr) (int, error) {
+	sides := sideNone
+	_, err := rewriteExpr(expr, func(col *sqlparser.ColName) (sqlparser.ValExpr, error) {
+		side, err := jb.sideOf(col)
+		sides |= side
+		return col, err
+	})
+	return sides, err
+}
+
+// bindLeftCols replaces the columns of the left table in cond
+// with the join vars that VTGate supplies from the left rows.
+func (jb *joinBuilder) bindLeftCols(cond sqlparser.BoolExpr) sqlparser.BoolExpr {
+	cond, _ = rewriteBool(cond, func(col *sqlparser.ColName) (sqlparser.ValExpr, error) {
+		if side, _ := jb.sideOf(col); side != sideLeft {
+			return col, nil
+		}
+		name := jb.left.alias + "_" + string(col.Name)
+		if _, ok := jb.joinVars[name]; !ok {
+			jb.joinVars[name] = jb.leftCol(col)
+		}
+		return sqlparser.ValArg(":" + name), nil
+	})
+	// Routing only matches columns on the left of an equality.
+	if comparison, ok := cond.(*sqlparser.ComparisonExpr); ok && comparison.Operator == "=" {
+		_, lok := comparison.Left.(sqlparser.ValArg)
+		_, rok := comparison.Right.(*sqlparser.ColName)
+		if lok && rok {
+			comparison.Left, comparison.Right = comparison.Right, comparison.Left
+		}
+	}
+	return cond
+}
+
+// leftCol returns the position of col in the left select. If
+// it's not there, it's added at the end.
+func (jb *joinBuilder) leftCol(col *sqlparser.ColName) int {
+	for i, selectExpr := range jb.leftSel.SelectExprs {
+		expr, ok := selectExpr.(*sqlparser.NonStarExpr)
+		if !ok {
+			continue
+		}
+		if c, ok := expr.Expr.(*sqlparser.ColName); ok && string(c.Name) == string(col.Name) {
+			return i
+		}
+	}
+	jb.leftSel.SelectExprs = append(jb.leftSel.SelectExprs, &sqlparser.NonStarExpr{Expr: col})
+	return len(jb.leftSel.SelectExprs) - 1
+}
+
+// splitAnd appends the AND-ed conditions of node to conds.
+func splitAnd(node sqlparser.BoolExpr, conds []sqlparser.BoolExpr) []sqlparser.BoolExpr {
+	if node, ok := node.(*sqlparser.AndExpr); ok {
+		conds = splitAnd(node.Left, conds)
+		return splitAnd(node.Right, conds)
+	}
+	return append(conds, node)
+}
+
+// addWhere ANDs cond to where. It returns the new where clause.
+func addWhere(where *sqlparser.Where, cond sqlparser.BoolExpr) *sqlparser.Where {
+	if _, ok := cond.(*sqlparser.OrExpr); ok {
+		cond = &sqlparser.ParenBoolExpr{Expr: cond}
+	}
+	if where == nil {
+		return sqlparser.NewWhere(sqlparser.AST_WHERE, cond)
+	}
+	return sqlparser.NewWhere(sqlparser.AST_WHERE, &sqlparser.AndExpr{Left: where.Expr, Right: cond})
+}
+
+// colFunc is called by the rewrite functions for every column.
+// The column is replaced by the returned value.
+type colFunc func(*sqlparser.ColName) (sqlparser.ValExpr, error)
+
+func rewriteExpr(node sqlparser.Expr, fn colFunc) (sqlparser.Expr, error) {
+	switch node := node.(type) {
+	case sqlparser.BoolExpr:
+		return rewriteBool(node, fn)
+	case sqlparser.ValExpr:
+		return rewriteVal(node, fn)
+	}
+	return node, nil
+}
+
+func rewriteBool(node sqlparser.BoolExpr, fn colFunc) (sqlparser.BoolExpr, error) {
+	var err error
+	switch node := node.(type) {
+	case *sqlparser.AndExpr:
+		if node.Left, err = rewriteBool(node.Left, fn); err != nil {
+			return node, err
+		}
+		node.Right, err = rewriteBool(node.Right, fn)
+	case *sqlparser.OrExpr:
+		if node.Left, err = rewriteBool(node.Left, fn); err != nil {
+			return node, err
+		}
+		node.Right, err = rewriteBool(node.Right, fn)
+	case *sqlparser.NotExpr:
+		node.Expr, err = rewriteBool(node.Expr, fn)
+	case *sqlparser.ParenBoolExpr:
+		node.Expr, err = rewriteBool(node.Expr, fn)
+	case *sqlparser.ComparisonExpr:
+		if node.Left, err = rewriteVal(node.Left, fn); err != nil {
+			return node, err
+		}
+		node.Right, err = rewriteVal(node.Right, fn)
+	case *sqlparser.RangeCond:
+		if node.Left, err = rewriteVal(node.Left, fn); err != nil {
+			return node, err
+		}
+		if node.From, err = rewriteVal(node.From, fn); err != nil {
+			return node, err
+		}
+		node.To, err = rewriteVal(node.To, fn)
+	case *sqlparser.NullCheck:
+		node.Expr, err = rewriteVal(node.Expr, fn)
+	}
+	return node, err
+}
+
+func rewriteVal(node sqlparser.ValExpr, fn colFunc) (sqlparser.ValExpr, error) {
+	var err error
+	switch node := node.(type) {
+	case *sqlparser.ColName:
+		return fn(node)
+	case sqlparser.ValTuple:
+		for i := range node {
+			if node[i], err = rewriteVal(node[i], fn); err != nil {
+				return node, err
+			}
+		}
+	case *sqlparser.BinaryExpr:
+		if node.Left, err = rewriteExpr(node.Left, fn); err != nil {
+			return node, err
+		}
+		node.Right, err = rewriteExpr(node.Right, fn)
+	case *sqlparser.UnaryExpr:
+		node.Expr, err = rewriteExpr(node.Expr, fn)
+	case *sqlparser.FuncExpr:
+		for _, expr := range node.Exprs {
+			if expr, ok := expr.(*sqlparser.NonStarExpr); ok {
+				if expr.Expr, err = rewriteExpr(expr.Expr, fn); err != nil {
+					return node, err
+				}
+			}
+		}
+	case *sqlparser.CaseExpr:
+		if node.Expr, err = rewriteVal(node.Expr, fn); err != nil {
+			return node, err
+		}
+		for _, when := range node.Whens {
+			if when.Cond, err = rewriteBool(when.Cond, fn); err != nil {
+				return node, err
+			}
+			if when.Val, err = rewriteVal(when.Val, fn); err != nil {
+				return node, err
+			}
+		}
+		node.Else, err = rewriteVal(node.Else, fn)
+	}
+	return node, err
+}
@@ -7,6 +7,7 @@ package planbuilder
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/youtube/vitess/go/vt/sqlparser"
 )
@@ -18,14 +19,30 @@ type PlanID int
 const (
 	NoPlan = PlanID(iota)
 	SelectUnsharded
+	SelectReference
 	SelectEqual
 	SelectIN
 	SelectKeyrange
 	SelectScatter
+	SelectRange
+	SelectEqualMerge
+	SelectINMerge
+	SelectScatterMerge
+	SelectRangeMerge
+	SelectEqualAggregate
+	SelectINAggregate
+	SelectScatterAggregate
+	SelectRangeAggregate
+	SelectJoin
+	SelectLeftJoin
 	UpdateUnsharded
 	UpdateEqual
+	UpdateIN
+	UpdateScatter
 	DeleteUnsharded
 	DeleteEqual
+	DeleteIN
+	DeleteScatter
 	InsertUnsharded
 	InsertSharded
 	NumPlans
@@ -35,14 +52,30 @@ const (
 var planName = [NumPlans]string{
 	"NoPlan",
 	"SelectUnsharded",
+	"SelectReference",
 	"SelectEqual",
 	"SelectIN",
 	"SelectKeyrange",
 	"SelectScatter",
+	"SelectRange",
+	"SelectEqualMerge",
+	"SelectINMerge",
+	"SelectScatterMerge",
+	"SelectRangeMerge",
+	"SelectEqualAggregate",
+	"SelectINAggregate",
+	"SelectScatterAggregate",
+	"SelectRangeAggregate",
+	"SelectJoin",
+	"SelectLeftJoin",
 	"UpdateUnsharded",
 	"UpdateEqual",
+	"UpdateIN",
+	"UpdateScatter",
 	"DeleteUnsharded",
 	"DeleteEqual",
+	"DeleteIN",
+	"DeleteScatter",
 	"InsertUnsharded",
 	"InsertSharded",
 }
@@ -56,16 +89,66 @@ type Plan struct {
 	Table  *Table
 	// Original is the original query.
 	Original string
-	// Rewritten is the rewritten query. This is empty for
-	// all Unsharded plans since the Original query is sufficient.
+	// Rewritten is the rewritten query. This is empty for all
+	// Unsharded plans and SelectReference since the Original
+	// query is sufficient.
 	Rewritten string
-	// Subquery is used for DeleteUnsharded to fetch the column values
-	// for owned vindexes so they can be deleted.
+	// Subquery is used for the sharded Delete plans to fetch the column
+	// values for owned vindexes so they can be deleted. For DeleteIN and
+	// DeleteScatter, it also fetches the primary vindex column last,
+	// unless it's owned, so that the keyspace id of every row can be
+	// computed. For the Join plans, it fetches the fields of the right
+	// side if there are no left rows.
 	Subquery  string
 	ColVindex *ColVindex
 	// Values is a single or a list of values that are used
-	// for making routing decisions.
+	// for making routing decisions. For InsertSharded, it's a
+	// list that has one entry per row, and each entry has one
+	// value per ColVindex of the table. The value of a MultiColumn
+	// vindex is a tuple that has one value per column.
 	Values interface{}
+	// Aggregates is used by the Aggregate plans to combine
+	// the partial results returned by the shards.
+	Aggregates []AggregateCol
+	// OrderBy is used by the Merge plans to merge-sort
+	// the results returned by the shards, and by the
+	// Aggregate plans to sort the combined result.
+	OrderBy []OrderByCol
+	// Offset and Rowcount are the LIMIT values that VTGate
+	// applies after merging the results of the Merge plans,
+	// or combining the results of the Aggregate plans.
+	// Like Values, they can be int64 or bind var names.
+	Offset, Rowcount interface{}
+	// Left and Right are the plans of the two sides of a Join plan.
+	Left, Right *Plan
+	// Cols defines the result columns of a Join plan. A negative
+	// value -n refers to column n-1 of the left result, and a positive
+	// value n refers to column n-1 of the right result.
+	Cols []int
+	// JoinVars maps the bind vars of the right side of a Join plan to
+	// the columns of the left result that supply their values.
+	JoinVars map[string]int
+	// Prefix, Mid and Suffix are used by InsertSharded to build the
+	// insert sent to each shard. Mid has one entry per row. A shard
+	// receives Prefix, followed by its rows, followed by Suffix.
+	Prefix string
+	Mid    []string
+	Suffix string
+	// AllowScatter is set for UpdateScatter and DeleteScatter if the
+	// query has the ScatterDirective comment. Otherwise, the plan can
+	// only be executed if the session allows scatter DMLs.
+	AllowScatter bool
+}
+
+// OrderByCol describes a result column that VTGate uses
+// to merge-sort the rows returned by multiple shards.
+type OrderByCol struct {
+	// Col is the name of the result column. It's used only
+	// if Index is -1.
+	Col string
+	// Index is the position of the column in the result.
+	Index int
+	Desc  bool
 }
 
 // Size is defined so that Plan can be given to an LRUCache.
@@ -81,28 +164,52 @@ func (pln *Plan) MarshalJSON() ([]byte, error) {
 	}
 	if pln.ColVindex != nil {
 		vindexName = pln.ColVindex.Name
-		col = pln.ColVindex.Col
+		col = strings.Join(pln.ColVindex.Columns(), ",")
 	}
 	marshalPlan := struct {
-		ID        PlanID
-		Reason    string
-		Table     string
-		Original  string
-		Rewritten string
-		Subquery  string
-		Vindex    string
-		Col       string
-		Values    interface{}
+		ID           PlanID
+		Reason       string
+		Table        string
+		Original     string
+		Rewritten    string
+		Subquery     string
+		Vindex       string
+		Col          string
+		Values       interface{}
+		Aggregates   []AggregateCol `json:",omitempty"`
+		OrderBy      []OrderByCol   `json:",omitempty"`
+		Offset       interface{}    `json:",omitempty"`
+		Rowcount     interface{}    `json:",omitempty"`
+		Left         *Plan          `json:",omitempty"`
+		Right        *Plan          `json:",omitempty"`
+		Cols         []int          `json:",omitempty"`
+		JoinVars     map[string]int `json:",omitempty"`
+		Prefix       string         `json:",omitempty"`
+		Mid          []string       `json:",omitempty"`
+		Suffix       string         `json:",omitempty"`
+		AllowScatter bool           `json:",omitempty"`
 	}{
-		ID:        pln.ID,
-		Reason:    pln.Reason,
-		Table:     tname,
-		Original:  pln.Original,
-		Rewritten: pln.Rewritten,
-		Subquery:  pln.Subquery,
-		Vindex:    vindexName,
-		Col:       col,
-		Values:    pln.Values,
+		ID:           pln.ID,
+		Reason:       pln.Reason,
+		Table:        tname,
+		Original:     pln.Original,
+		Rewritten:    pln.Rewritten,
+		Subquery:     pln.Subquery,
+		Vindex:       vindexName,
+		Col:          col,
+		Values:       pln.Values,
+		Aggregates:   pln.Aggregates,
+		OrderBy:      pln.OrderBy,
+		Offset:       pln.Offset,
+		Rowcount:     pln.Rowcount,
+		Left:         pln.Left,
+		Right:        pln.Right,
+		Cols:         pln.Cols,
+		JoinVars:     pln.JoinVars,
+		Prefix:       pln.Prefix,
+		Mid:          pln.Mid,
+		Suffix:       pln.Suffix,
+		AllowScatter: pln.AllowScatter,
 	}
 	return json.Marshal(marshalPlan)
 }
@@ -110,7 +217,10 @@ func (pln *Plan) MarshalJSON() ([]byte, error) {
 // IsMulti returns true if the SELECT query can potentially
 // be sent to more than one shard.
 func (pln *Plan) IsMulti() bool {
-	if pln.ID == SelectIN || pln.ID == SelectScatter {
+	switch pln.ID {
+	case SelectIN, SelectScatter, SelectRange, SelectEqualMerge, SelectINMerge,
+		SelectScatterMerge, SelectRangeMerge, SelectEqualAggregate, SelectINAggregate,
+		SelectScatterAggregate, SelectRangeAggregate:
 		return true
 	}
 	if pln.ID == SelectEqual && !IsUnique(pln.ColVindex.Vindex) {
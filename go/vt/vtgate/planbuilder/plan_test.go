@@ -59,10 +59,42 @@ func (*multiIndex) Delete(VCursor, []interface{}, key.KeyspaceId) error    { ret
 
 func newMultiIndex(map[string]interface{}) (Vindex, error) { return &multiIndex{}, nil }
 
+// rangeIndex satisfies Functional, Ranged.
+type rangeIndex struct{}
+
+func (*rangeIndex) Cost() int { return 0 }
+func (*rangeIndex) Verify(VCursor, interface{}, key.KeyspaceId) (bool, error) {
+	return false, nil
+}
+func (*rangeIndex) Map(VCursor, []interface{}) ([]key.KeyspaceId, error) { return nil, nil }
+func (*rangeIndex) MapRange(VCursor, interface{}, interface{}) ([]key.KeyRange, error) {
+	return nil, nil
+}
+func (*rangeIndex) Create(VCursor, interface{}) error                   { return nil }
+func (*rangeIndex) Delete(VCursor, []interface{}, key.KeyspaceId) error { return nil }
+
+func newRangeIndex(map[string]interface{}) (Vindex, error) { return &rangeIndex{}, nil }
+
+// regionIndex satisfies Functional, Unique, MultiColumn.
+type regionIndex struct{}
+
+func (*regionIndex) Cost() int        { return 1 }
+func (*regionIndex) ColumnCount() int { return 2 }
+func (*regionIndex) Verify(VCursor, interface{}, key.KeyspaceId) (bool, error) {
+	return false, nil
+}
+func (*regionIndex) Map(VCursor, []interface{}) ([]key.KeyspaceId, error) { return nil, nil }
+func (*regionIndex) Create(VCursor, interface{}) error                    { return nil }
+func (*regionIndex) Delete(VCursor, []interface{}, key.KeyspaceId) error  { return nil }
+
+func newRegionIndex(map[string]interface{}) (Vindex, error) { return &regionIndex{}, nil }
+
 func init() {
 	Register("hash", newHashIndex)
+	Register("region", newRegionIndex)
 	Register("lookup", newLookupIndex)
 	Register("multi", newMultiIndex)
+	Register("range", newRangeIndex)
 }
 
 func TestPlanName(t *testing.T) {
@@ -16,9 +16,11 @@ import (
 
 // A VCursor is an interface that allows you to execute queries
 // in the current context and session of a VTGate request. Vindexes
-// can use this interface to execute lookup queries.
+// can use this interface to execute lookup queries, and to get the
+// next value of a sequence table when they generate ids.
 type VCursor interface {
 	Execute(query *tproto.BoundQuery) (*mproto.QueryResult, error)
+	NextSequenceValue(sequence string) (int64, error)
 }
 
 // Vindex defines the interface required to register a vindex.
@@ -57,6 +59,27 @@ func IsUnique(v Vindex) bool {
 	return ok
 }
 
+// A Ranged vindex is a Unique vindex that preserves the
+// order of its ids: if an id is smaller than another, so is
+// its keyspace id. This allows VTGate to map a range of ids
+// to the key ranges that contain their keyspace ids, and send
+// a range condition like BETWEEN to only the shards that
+// cover those key ranges. This is optional.
+type Ranged interface {
+	MapRange(cursor VCursor, from, to interface{}) ([]key.KeyRange, error)
+	Unique
+}
+
+// A MultiColumn vindex computes the keyspace id from the values
+// of more than one column. Its ids are tuples: every id is an
+// []interface{} that has one value per column, in the order of
+// the Cols of its ColVindex. A MultiColumn vindex can only be the
+// primary vindex of a table. This is optional.
+type MultiColumn interface {
+	// ColumnCount returns the number of columns of the vindex.
+	ColumnCount() int
+}
+
 // A Reversible vindex is one that can perform a
 // reverse lookup from a keyspace id to an id. This
 // is optional. If present, VTGate can use it to
@@ -19,13 +19,26 @@ type Schema struct {
 
 // Table represnts a table in Schema.
 type Table struct {
-	Name        string
-	Keyspace    *Keyspace
+	Name     string
+	Keyspace *Keyspace
+	// Source is set only for reference tables. It's the unsharded
+	// keyspace that receives the writes. The table is copied from
+	// there to every shard of Keyspace.
+	Source *Keyspace
+	// IsSequence is set for sequence tables. They're in
+	// unsharded keyspaces, and VTGate reserves blocks of
+	// values from them for the vindexes that generate ids.
+	IsSequence  bool
 	ColVindexes []*ColVindex
 	Ordered     []*ColVindex
 	Owned       []*ColVindex
 }
 
+// IsReference returns true if the table is a reference table.
+func (t *Table) IsReference() bool {
+	return t.Source != nil
+}
+
 // Keyspace contains the keyspcae info for each Table.
 type Keyspace struct {
 	Name    string
@@ -33,22 +46,37 @@ type Keyspace struct {
 }
 
 // ColVindex contains the index info for each index of a table.
+// Col is the column of the vindex. For MultiColumn vindexes, it's
+// empty, and Cols has the columns instead.
 type ColVindex struct {
 	Col    string
+	Cols   []string
 	Type   string
 	Name   string
 	Owned  bool
 	Vindex Vindex
 }
 
+// Columns returns the columns of the vindex.
+func (cv *ColVindex) Columns() []string {
+	if len(cv.Cols) != 0 {
+		return cv.Cols
+	}
+	return []string{cv.Col}
+}
+
 // BuildSchema builds a Schema from a SchemaFormal.
 func BuildSchema(source *SchemaFormal) (schema *Schema, err error) {
 	schema = &Schema{Tables: make(map[string]*Table)}
+	keyspaces := make(map[string]*Keyspace)
 	for ksname, ks := range source.Keyspaces {
-		keyspace := &Keyspace{
+		keyspaces[ksname] = &Keyspace{
 			Name:    ksname,
 			Sharded: ks.Sharded,
 		}
+	}
+	for ksname, ks := range source.Keyspaces {
+		keyspace := keyspaces[ksname]
 		vindexes := make(map[string]Vindex)
 		for vname, vindexInfo := range ks.Vindexes {
 			vindex, err := CreateVindex(vindexInfo.Type, vindexInfo.Params)
@@ -72,6 +100,16 @@ func BuildSchema(source *SchemaFormal) (schema *Schema, err error) {
 				Keyspace: keyspace,
 			}
 			if !keyspace.Sharded {
+				if cname != "" {
+					class, ok := ks.Classes[cname]
+					if !ok {
+						return nil, fmt.Errorf("class %s not found for table %s", cname, tname)
+					}
+					if class.Type != ClassSequence {
+						return nil, fmt.Errorf("class %s of unsharded table %s is not a sequence", cname, tname)
+					}
+					t.IsSequence = true
+				}
 				schema.Tables[tname] = t
 				continue
 			}
@@ -79,6 +117,24 @@ func BuildSchema(source *SchemaFormal) (schema *Schema, err error) {
 			if !ok {
 				return nil, fmt.Errorf("class %s not found for table %s", cname, tname)
 			}
+			switch class.Type {
+			case "":
+			case ClassReference:
+				if len(class.ColVindexes) != 0 {
+					return nil, fmt.Errorf("reference class %s cannot have vindexes", cname)
+				}
+				sourceKeyspace, ok := keyspaces[class.Source]
+				if !ok || sourceKeyspace.Sharded {
+					return nil, fmt.Errorf("source %s of reference class %s is not an unsharded keyspace", class.Source, cname)
+				}
+				t.Source = sourceKeyspace
+				schema.Tables[tname] = t
+				continue
+			case ClassSequence:
+				return nil, fmt.Errorf("sequence class %s is not in an unsharded keyspace", cname)
+			default:
+				return nil, fmt.Errorf("invalid type %s for class %s", class.Type, cname)
+			}
 			for i, ind := range class.ColVindexes {
 				vindexInfo, ok := ks.Vindexes[ind.Name]
 				if !ok {
@@ -86,11 +142,15 @@ func BuildSchema(source *SchemaFormal) (schema *Schema, err error) {
 				}
 				columnVindex := &ColVindex{
 					Col:    ind.Col,
+					Cols:   ind.Cols,
 					Type:   vindexInfo.Type,
 					Name:   ind.Name,
 					Owned:  vindexInfo.Owner == tname,
 					Vindex: vindexes[ind.Name],
 				}
+				if err := checkColumns(columnVindex, ind, i, cname); err != nil {
+					return nil, err
+				}
 				if i == 0 {
 					// Perform Primary vindex check.
 					if _, ok := columnVindex.Vindex.(Unique); !ok {
@@ -121,6 +181,25 @@ func BuildSchema(source *SchemaFormal) (schema *Schema, err error) {
 	return schema, nil
 }
 
+// checkColumns verifies that the columns of the vindex at
+// position pos of a class match what the vindex expects.
+func checkColumns(columnVindex *ColVindex, ind ColVindexFormal, pos int, cname string) error {
+	if ind.Col != "" && len(ind.Cols) != 0 {
+		return fmt.Errorf("index %s has both Col and Cols for class %s", ind.Name, cname)
+	}
+	want := 1
+	if multi, ok := columnVindex.Vindex.(MultiColumn); ok {
+		want = multi.ColumnCount()
+	}
+	if len(columnVindex.Columns()) != want {
+		return fmt.Errorf("index %s needs %d columns for class %s", ind.Name, want, cname)
+	}
+	if want > 1 && pos != 0 {
+		return fmt.Errorf("multi-column index %s is not primary for class %s", ind.Name, cname)
+	}
+	return nil
+}
+
 // FindTable returns a pointer to the Table if found.
 // Otherwise, it returns a reason, which is equivalent to an error.
 func (schema *Schema) FindTable(tablename string) (table *Table, reason string) {
@@ -173,16 +252,36 @@ type VindexFormal struct {
 	Owner  string
 }
 
+// ClassReference is the Type of the classes of reference tables.
+// A reference table is fully copied to every shard of its keyspace
+// from its Source keyspace. Reads are sent to any one shard, and
+// writes to the Source keyspace.
+const ClassReference = "reference"
+
+// ClassSequence is the Type of the classes of sequence tables.
+// Unlike other classes, it's used in unsharded keyspaces. A
+// sequence table has a single row, with an id of 0, that holds
+// the next value of the sequence in next_id, and the number of
+// values that VTGate reserves at a time in cache.
+const ClassSequence = "sequence"
+
 // ClassFormal is the info for each table class as loaded from
-// the source.
+// the source. Type is empty for regular sharded tables. For
+// reference tables, it's ClassReference, and Source is the
+// unsharded keyspace that receives the writes. For sequence
+// tables, it's ClassSequence.
 type ClassFormal struct {
+	Type        string
+	Source      string
 	ColVindexes []ColVindexFormal
 }
 
 // ColVindexFormal is the info for each indexed column
-// of a table as loaded from the source.
+// of a table as loaded from the source. Cols is used
+// instead of Col for MultiColumn vindexes.
 type ColVindexFormal struct {
 	Col  string
+	Cols []string
 	Name string
 }
 
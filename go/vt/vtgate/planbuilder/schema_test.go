@@ -69,11 +69,28 @@ func NewSTLU(params map[string]interface{}) (Vindex, error) {
 	return &stLU{Params: params}, nil
 }
 
+// stFM satisfies Functional, Unique, MultiColumn.
+type stFM struct {
+	Params map[string]interface{}
+}
+
+func (*stFM) Cost() int                                                 { return 1 }
+func (*stFM) ColumnCount() int                                          { return 2 }
+func (*stFM) Verify(VCursor, interface{}, key.KeyspaceId) (bool, error) { return false, nil }
+func (*stFM) Map(VCursor, []interface{}) ([]key.KeyspaceId, error)      { return nil, nil }
+func (*stFM) Create(VCursor, interface{}) error                         { return nil }
+func (*stFM) Delete(VCursor, []interface{}, key.KeyspaceId) error       { return nil }
+
+func NewSTFM(params map[string]interface{}) (Vindex, error) {
+	return &stFM{Params: params}, nil
+}
+
 func init() {
 	Register("stfu", NewSTFU)
 	Register("stf", NewSTF)
 	Register("stln", NewSTLN)
 	Register("stlu", NewSTLU)
+	Register("stfm", NewSTFM)
 }
 
 func TestUnshardedSchema(t *testing.T) {
@@ -262,6 +279,217 @@ func TestShardedSchemaNotOwned(t *testing.T) {
 	}
 }
 
+func TestReferenceSchema(t *testing.T) {
+	good := SchemaFormal{
+		Keyspaces: map[string]KeyspaceFormal{
+			"sharded": {
+				Sharded: true,
+				Classes: map[string]ClassFormal{
+					"ref": {
+						Type:   ClassReference,
+						Source: "unsharded",
+					},
+				},
+				Tables: map[string]string{
+					"t1": "ref",
+				},
+			},
+			"unsharded": {},
+		},
+	}
+	got, err := BuildSchema(&good)
+	if err != nil {
+		t.Error(err)
+	}
+	want := &Schema{
+		Tables: map[string]*Table{
+			"t1": &Table{
+				Name: "t1",
+				Keyspace: &Keyspace{
+					Name:    "sharded",
+					Sharded: true,
+				},
+				Source: &Keyspace{
+					Name: "unsharded",
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildSchema:s\n%v, want\n%v", got, want)
+	}
+	if !got.Tables["t1"].IsReference() {
+		t.Errorf("IsReference: false, want true")
+	}
+}
+
+func TestMultiColumnSchema(t *testing.T) {
+	good := SchemaFormal{
+		Keyspaces: map[string]KeyspaceFormal{
+			"sharded": {
+				Sharded: true,
+				Vindexes: map[string]VindexFormal{
+					"stfm": {
+						Type:  "stfm",
+						Owner: "t1",
+					},
+				},
+				Classes: map[string]ClassFormal{
+					"t1": {
+						ColVindexes: []ColVindexFormal{{
+							Cols: []string{"c1", "c2"},
+							Name: "stfm",
+						}},
+					},
+				},
+				Tables: map[string]string{
+					"t1": "t1",
+				},
+			},
+		},
+	}
+	got, err := BuildSchema(&good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cv := got.Tables["t1"].ColVindexes[0]
+	if cv.Col != "" || !reflect.DeepEqual(cv.Columns(), []string{"c1", "c2"}) {
+		t.Errorf("ColVindex: %+v, want columns c1, c2", cv)
+	}
+	if !cv.Owned {
+		t.Errorf("Owned: false, want true")
+	}
+}
+
+func TestBuildSchemaMultiColumnFail(t *testing.T) {
+	testcases := []struct {
+		colVindexes []ColVindexFormal
+		err         string
+	}{{
+		colVindexes: []ColVindexFormal{{Col: "c1", Name: "stfm"}},
+		err:         "index stfm needs 2 columns for class t1",
+	}, {
+		colVindexes: []ColVindexFormal{{Cols: []string{"c1", "c2", "c3"}, Name: "stfm"}},
+		err:         "index stfm needs 2 columns for class t1",
+	}, {
+		colVindexes: []ColVindexFormal{{Cols: []string{"c1", "c2"}, Name: "stfu"}},
+		err:         "index stfu needs 1 columns for class t1",
+	}, {
+		colVindexes: []ColVindexFormal{{Col: "c1", Cols: []string{"c1", "c2"}, Name: "stfm"}},
+		err:         "index stfm has both Col and Cols for class t1",
+	}, {
+		colVindexes: []ColVindexFormal{{Col: "c1", Name: "stfu"}, {Cols: []string{"c1", "c2"}, Name: "stfm"}},
+		err:         "multi-column index stfm is not primary for class t1",
+	}}
+	for _, tcase := range testcases {
+		bad := SchemaFormal{
+			Keyspaces: map[string]KeyspaceFormal{
+				"sharded": {
+					Sharded: true,
+					Vindexes: map[string]VindexFormal{
+						"stfu": {
+							Type: "stfu",
+						},
+						"stfm": {
+							Type: "stfm",
+						},
+					},
+					Classes: map[string]ClassFormal{
+						"t1": {
+							ColVindexes: tcase.colVindexes,
+						},
+					},
+					Tables: map[string]string{
+						"t1": "t1",
+					},
+				},
+			},
+		}
+		_, err := BuildSchema(&bad)
+		if err == nil || err.Error() != tcase.err {
+			t.Errorf("BuildSchema: %v, want %v", err, tcase.err)
+		}
+	}
+}
+
+func TestSequenceSchema(t *testing.T) {
+	good := SchemaFormal{
+		Keyspaces: map[string]KeyspaceFormal{
+			"unsharded": {
+				Classes: map[string]ClassFormal{
+					"seq": {
+						Type: ClassSequence,
+					},
+				},
+				Tables: map[string]string{
+					"t1":  "",
+					"seq": "seq",
+				},
+			},
+		},
+	}
+	got, err := BuildSchema(&good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Tables["t1"].IsSequence {
+		t.Errorf("t1.IsSequence: true, want false")
+	}
+	if !got.Tables["seq"].IsSequence {
+		t.Errorf("seq.IsSequence: false, want true")
+	}
+}
+
+func TestBuildSchemaSequenceFail(t *testing.T) {
+	testcases := []struct {
+		keyspace KeyspaceFormal
+		err      string
+	}{{
+		keyspace: KeyspaceFormal{
+			Tables: map[string]string{
+				"t1": "noexist",
+			},
+		},
+		err: "class noexist not found for table t1",
+	}, {
+		keyspace: KeyspaceFormal{
+			Classes: map[string]ClassFormal{
+				"ref": {
+					Type: ClassReference,
+				},
+			},
+			Tables: map[string]string{
+				"t1": "ref",
+			},
+		},
+		err: "class ref of unsharded table t1 is not a sequence",
+	}, {
+		keyspace: KeyspaceFormal{
+			Sharded: true,
+			Classes: map[string]ClassFormal{
+				"seq": {
+					Type: ClassSequence,
+				},
+			},
+			Tables: map[string]string{
+				"t1": "seq",
+			},
+		},
+		err: "sequence class seq is not in an unsharded keyspace",
+	}}
+	for _, tcase := range testcases {
+		bad := SchemaFormal{
+			Keyspaces: map[string]KeyspaceFormal{
+				"ks": tcase.keyspace,
+			},
+		}
+		_, err := BuildSchema(&bad)
+		if err == nil || err.Error() != tcase.err {
+			t.Errorf("BuildSchema: %v, want %v", err, tcase.err)
+		}
+	}
+}
+
 func TestLoadSchemaFail(t *testing.T) {
 	_, err := LoadFile("bogus file name")
 	want := "ReadFile failed"
@@ -568,3 +796,51 @@ func TestBuildSchemaNonPrimaryLookupFail(t *testing.T) {
 		t.Errorf("BuildSchema: %v, want %v", err, want)
 	}
 }
+
+func TestBuildSchemaReferenceFail(t *testing.T) {
+	testcases := []struct {
+		class ClassFormal
+		err   string
+	}{{
+		class: ClassFormal{Type: "noexist"},
+		err:   "invalid type noexist for class ref",
+	}, {
+		class: ClassFormal{Type: ClassReference, Source: "noexist"},
+		err:   "source noexist of reference class ref is not an unsharded keyspace",
+	}, {
+		class: ClassFormal{Type: ClassReference, Source: "sharded"},
+		err:   "source sharded of reference class ref is not an unsharded keyspace",
+	}, {
+		class: ClassFormal{
+			Type:        ClassReference,
+			Source:      "unsharded",
+			ColVindexes: []ColVindexFormal{{Col: "c1", Name: "stfu"}},
+		},
+		err: "reference class ref cannot have vindexes",
+	}}
+	for _, tcase := range testcases {
+		bad := SchemaFormal{
+			Keyspaces: map[string]KeyspaceFormal{
+				"sharded": {
+					Sharded: true,
+					Vindexes: map[string]VindexFormal{
+						"stfu": {
+							Type: "stfu",
+						},
+					},
+					Classes: map[string]ClassFormal{
+						"ref": tcase.class,
+					},
+					Tables: map[string]string{
+						"t1": "ref",
+					},
+				},
+				"unsharded": {},
+			},
+		}
+		_, err := BuildSchema(&bad)
+		if err == nil || err.Error() != tcase.err {
+			t.Errorf("BuildSchema: %v, want %v", err, tcase.err)
+		}
+	}
+}
@@ -4,9 +4,22 @@
 
 package planbuilder
 
-import "github.com/youtube/vitess/go/vt/sqlparser"
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/youtube/vitess/go/vt/sqlparser"
+)
+
+// LimitVarName is the bind var name used for the
+// pushed down LIMIT of a Merge plan if it cannot
+// be computed at plan time.
+const LimitVarName = "_limit"
 
 func buildSelectPlan(sel *sqlparser.Select, schema *Schema) *Plan {
+	if join, ok := getJoin(sel.From); ok {
+		return buildJoinPlan(sel, join, schema)
+	}
 	plan := &Plan{ID: NoPlan}
 	tablename, _ := analyzeFrom(sel.From)
 	plan.Table, plan.Reason = schema.FindTable(tablename)
@@ -17,13 +30,35 @@ func buildSelectPlan(sel *sqlparser.Select, schema *Schema) *Plan {
 		plan.ID = SelectUnsharded
 		return plan
 	}
+	if plan.Table.IsReference() {
+		plan.ID = SelectReference
+		return plan
+	}
 
 	getWhereRouting(sel.Where, plan, false)
+	return finishSelectPlan(sel, plan)
+}
+
+// finishSelectPlan converts a multi-shard select plan into its
+// Aggregate or Merge counterpart if needed, and sets the rewritten query.
+func finishSelectPlan(sel *sqlparser.Select, plan *Plan) *Plan {
 	if plan.IsMulti() {
-		if hasPostProcessing(sel) {
+		if hasAggregates(sel.SelectExprs) || sel.GroupBy != nil {
+			if err := buildAggregatePlan(sel, plan); err != nil {
+				plan.ID = NoPlan
+				plan.Reason = err.Error()
+				return plan
+			}
+		} else if hasPostProcessing(sel) {
 			plan.ID = NoPlan
-			plan.Reason = "multi-shard query has post-processing constructs"
+			plan.Reason = errPostProcessing.Error()
 			return plan
+		} else if sel.OrderBy != nil || sel.Limit != nil {
+			if err := buildMergePlan(sel, plan); err != nil {
+				plan.ID = NoPlan
+				plan.Reason = err.Error()
+				return plan
+			}
 		}
 	}
 	// The where clause might have changed.
@@ -31,6 +66,76 @@ func buildSelectPlan(sel *sqlparser.Select, schema *Schema) *Plan {
 	return plan
 }
 
+// buildMergePlan converts a multi-shard select plan into its Merge
+// counterpart. The ORDER BY clause is sent to the shards as is. The LIMIT
+// clause is rewritten to fetch offset+rowcount rows from every shard.
+// VTGate merge-sorts the results and applies the original LIMIT.
+func buildMergePlan(sel *sqlparser.Select, plan *Plan) error {
+	orderBy, err := getOrderByCols(sel.OrderBy)
+	if err != nil {
+		return err
+	}
+	offset, rowcount, err := sel.Limit.Limits()
+	if err != nil {
+		return fmt.Errorf("invalid limit: %v", err)
+	}
+	if offset != nil {
+		sel.Limit = &sqlparser.Limit{Rowcount: pushdownLimit(offset, rowcount)}
+	}
+	switch plan.ID {
+	case SelectEqual:
+		plan.ID = SelectEqualMerge
+	case SelectIN:
+		plan.ID = SelectINMerge
+	case SelectScatter:
+		plan.ID = SelectScatterMerge
+	case SelectRange:
+		plan.ID = SelectRangeMerge
+	default:
+		panic("unexpected")
+	}
+	plan.OrderBy = orderBy
+	plan.Offset = offset
+	plan.Rowcount = rowcount
+	return nil
+}
+
+func getOrderByCols(orderBy sqlparser.OrderBy) ([]OrderByCol, error) {
+	var cols []OrderByCol
+	for _, order := range orderBy {
+		col := OrderByCol{
+			Index: -1,
+			Desc:  order.Direction == sqlparser.AST_DESC,
+		}
+		switch expr := order.Expr.(type) {
+		case *sqlparser.ColName:
+			col.Col = string(expr.Name)
+		case sqlparser.NumVal:
+			pos, err := strconv.ParseInt(string(expr), 0, 64)
+			if err != nil || pos < 1 {
+				return nil, fmt.Errorf("invalid order by position: %s", sqlparser.String(expr))
+			}
+			col.Index = int(pos - 1)
+		default:
+			return nil, fmt.Errorf("complex order by expression: %s", sqlparser.String(expr))
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+// pushdownLimit returns the LIMIT expression to be sent to every shard.
+// If offset or rowcount are bind vars, VTGate computes the value at
+// execution time and supplies it as LimitVarName.
+func pushdownLimit(offset, rowcount interface{}) sqlparser.ValExpr {
+	o, ok1 := offset.(int64)
+	r, ok2 := rowcount.(int64)
+	if ok1 && ok2 {
+		return sqlparser.NumVal(strconv.FormatInt(o+r, 10))
+	}
+	return sqlparser.ValArg(":" + LimitVarName)
+}
+
 // TODO(sougou): Copied from tabletserver. Reuse.
 func analyzeFrom(tableExprs sqlparser.TableExprs) (tablename string, hasHints bool) {
 	if len(tableExprs) > 1 {
@@ -111,6 +216,8 @@ func exprHasAggregates(node sqlparser.Expr) bool {
 	}
 }
 
+// hasPostProcessing returns true if a select without aggregates has
+// constructs that VTGate cannot compute from the results of multiple shards.
 func hasPostProcessing(sel *sqlparser.Select) bool {
-	return hasAggregates(sel.SelectExprs) || sel.Distinct != "" || sel.GroupBy != nil || sel.Having != nil || sel.OrderBy != nil || sel.Limit != nil
+	return sel.Distinct != "" || sel.Having != nil
 }
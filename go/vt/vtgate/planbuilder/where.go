@@ -45,6 +45,15 @@ func getWhereRouting(where *sqlparser.Where, plan *Plan, onlyUnique bool) {
 		if onlyUnique && !IsUnique(index.Vindex) {
 			continue
 		}
+		if len(index.Cols) != 0 {
+			if values := getTupleMatch(where.Expr, index.Cols); values != nil {
+				plan.ID = SelectEqual
+				plan.ColVindex = index
+				plan.Values = values
+				return
+			}
+			continue
+		}
 		if planID, values := getMatch(where.Expr, index.Col); planID != SelectScatter {
 			plan.ID = planID
 			plan.ColVindex = index
@@ -52,6 +61,17 @@ func getWhereRouting(where *sqlparser.Where, plan *Plan, onlyUnique bool) {
 			return
 		}
 	}
+	for _, index := range plan.Table.Ordered {
+		if _, ok := index.Vindex.(Ranged); !ok {
+			continue
+		}
+		if values := getRangeMatch(where.Expr, index.Col); values != nil {
+			plan.ID = SelectRange
+			plan.ColVindex = index
+			plan.Values = values
+			return
+		}
+	}
 	plan.ID = SelectScatter
 }
 
@@ -211,6 +231,73 @@ func getMatch(node sqlparser.BoolExpr, col string) (planID PlanID, values interf
 	return SelectScatter, nil
 }
 
+// getTupleMatch returns the values of the equality conditions on
+// all the cols of a MultiColumn vindex, or nil if one is missing.
+func getTupleMatch(node sqlparser.BoolExpr, cols []string) []interface{} {
+	values := make([]interface{}, len(cols))
+	for i, col := range cols {
+		val, ok := getEqualMatch(node, col)
+		if !ok {
+			return nil
+		}
+		values[i] = val
+	}
+	return values
+}
+
+func getEqualMatch(node sqlparser.BoolExpr, col string) (value interface{}, ok bool) {
+	switch node := node.(type) {
+	case *sqlparser.AndExpr:
+		if value, ok = getEqualMatch(node.Left, col); ok {
+			return value, true
+		}
+		return getEqualMatch(node.Right, col)
+	case *sqlparser.ParenBoolExpr:
+		return getEqualMatch(node.Expr, col)
+	case *sqlparser.ComparisonExpr:
+		if node.Operator != "=" || !nameMatch(node.Left, col) || !sqlparser.IsValue(node.Right) {
+			return nil, false
+		}
+		val, err := asInterface(node.Right)
+		if err != nil {
+			return nil, false
+		}
+		return val, true
+	}
+	return nil, false
+}
+
+// getRangeMatch returns the bounds of a BETWEEN
+// condition on col, or nil if there's none.
+func getRangeMatch(node sqlparser.BoolExpr, col string) []interface{} {
+	switch node := node.(type) {
+	case *sqlparser.AndExpr:
+		if values := getRangeMatch(node.Left, col); values != nil {
+			return values
+		}
+		return getRangeMatch(node.Right, col)
+	case *sqlparser.ParenBoolExpr:
+		return getRangeMatch(node.Expr, col)
+	case *sqlparser.RangeCond:
+		if node.Operator != sqlparser.AST_BETWEEN || !nameMatch(node.Left, col) {
+			return nil
+		}
+		if !sqlparser.IsValue(node.From) || !sqlparser.IsValue(node.To) {
+			return nil
+		}
+		from, err := asInterface(node.From)
+		if err != nil {
+			return nil
+		}
+		to, err := asInterface(node.To)
+		if err != nil {
+			return nil
+		}
+		return []interface{}{from, to}
+	}
+	return nil
+}
+
 func nameMatch(node sqlparser.ValExpr, col string) bool {
 	colname, ok := node.(*sqlparser.ColName)
 	if !ok {
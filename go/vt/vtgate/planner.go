@@ -32,9 +32,6 @@ func NewPlanner(schema *planbuilder.Schema, cacheSize int) *Planner {
 		schema: schema,
 		plans:  cache.NewLRUCache(int64(cacheSize)),
 	}
-	// TODO(sougou): Uncomment after making Planner testable.
-	//http.Handle("/debug/query_plans", plr)
-	//http.Handle("/debug/schema", plr)
 	return plr
 }
 
@@ -12,6 +12,7 @@ import (
 	"github.com/youtube/vitess/go/vt/topo"
 
 	pbq "github.com/youtube/vitess/go/vt/proto/query"
+	pbt "github.com/youtube/vitess/go/vt/proto/topodata"
 	pb "github.com/youtube/vitess/go/vt/proto/vtgate"
 )
 
@@ -21,7 +22,10 @@ func SessionToProto(s *Session) *pb.Session {
 		return nil
 	}
 	result := &pb.Session{
-		InTransaction: s.InTransaction,
+		InTransaction:       s.InTransaction,
+		AllowScatterDml:     s.AllowScatterDml,
+		ReadYourWrites:      s.ReadYourWrites,
+		MaxStalenessSeconds: s.MaxStalenessSeconds,
 	}
 	result.ShardSessions = make([]*pb.Session_ShardSession, len(s.ShardSessions))
 	for i, ss := range s.ShardSessions {
@@ -34,6 +38,14 @@ func SessionToProto(s *Session) *pb.Session {
 			TransactionId: ss.TransactionId,
 		}
 	}
+	result.ShardPositions = make([]*pb.Session_ShardPosition, len(s.ShardPositions))
+	for i, sp := range s.ShardPositions {
+		result.ShardPositions[i] = &pb.Session_ShardPosition{
+			Keyspace: sp.Keyspace,
+			Shard:    sp.Shard,
+			Position: sp.Position,
+		}
+	}
 	return result
 }
 
@@ -43,7 +55,10 @@ func ProtoToSession(s *pb.Session) *Session {
 		return nil
 	}
 	result := &Session{
-		InTransaction: s.InTransaction,
+		InTransaction:       s.InTransaction,
+		AllowScatterDml:     s.AllowScatterDml,
+		ReadYourWrites:      s.ReadYourWrites,
+		MaxStalenessSeconds: s.MaxStalenessSeconds,
 	}
 	result.ShardSessions = make([]*ShardSession, len(s.ShardSessions))
 	for i, ss := range s.ShardSessions {
@@ -54,6 +69,14 @@ func ProtoToSession(s *pb.Session) *Session {
 			TransactionId: ss.TransactionId,
 		}
 	}
+	result.ShardPositions = make([]*ShardPosition, len(s.ShardPositions))
+	for i, sp := range s.ShardPositions {
+		result.ShardPositions[i] = &ShardPosition{
+			Keyspace: sp.Keyspace,
+			Shard:    sp.Shard,
+			Position: sp.Position,
+		}
+	}
 	return result
 }
 
@@ -221,6 +244,9 @@ func SplitQueryPartsToProto(sqp []SplitQueryPart) *pb.SplitQueryResponse {
 				Keyspace: split.QueryShard.Keyspace,
 				Shards:   split.QueryShard.Shards,
 			}
+			if split.QueryShard.TabletType != "" {
+				result.Splits[i].ShardPart.TabletType = topo.TabletTypeToProto(split.QueryShard.TabletType)
+			}
 		}
 	}
 	return result
@@ -249,8 +275,49 @@ func ProtoToSplitQueryParts(sqr *pb.SplitQueryResponse) []SplitQueryPart {
 				Keyspace:      split.ShardPart.Keyspace,
 				Shards:        split.ShardPart.Shards,
 			}
+			if split.ShardPart.TabletType != pbt.TabletType_UNKNOWN {
+				result[i].QueryShard.TabletType = topo.ProtoToTabletType(split.ShardPart.TabletType)
+			}
 		}
 		result[i].Size = split.Size
 	}
 	return result
 }
+
+// QueryPlanToProto transforms a QueryPlan into proto3
+func QueryPlanToProto(qp *QueryPlan) *pb.QueryPlan {
+	if qp == nil {
+		return nil
+	}
+	return &pb.QueryPlan{
+		PlanId:    qp.PlanID,
+		Reason:    qp.Reason,
+		Table:     qp.Table,
+		Col:       qp.Col,
+		Vindex:    qp.Vindex,
+		Rewritten: qp.Rewritten,
+		Keyspace:  qp.Keyspace,
+		Shards:    qp.Shards,
+		Left:      QueryPlanToProto(qp.Left),
+		Right:     QueryPlanToProto(qp.Right),
+	}
+}
+
+// ProtoToQueryPlan transforms a proto3 QueryPlan into native types
+func ProtoToQueryPlan(qp *pb.QueryPlan) *QueryPlan {
+	if qp == nil {
+		return nil
+	}
+	return &QueryPlan{
+		PlanID:    qp.PlanId,
+		Reason:    qp.Reason,
+		Table:     qp.Table,
+		Col:       qp.Col,
+		Vindex:    qp.Vindex,
+		Rewritten: qp.Rewritten,
+		Keyspace:  qp.Keyspace,
+		Shards:    qp.Shards,
+		Left:      ProtoToQueryPlan(qp.Left),
+		Right:     ProtoToQueryPlan(qp.Right),
+	}
+}
@@ -16,15 +16,27 @@ import (
 // Session represents the session state. It keeps track of
 // the shards on which transactions are in progress, along
 // with the corresponding transaction ids.
+// AllowScatterDml allows the V3 API to send updates and
+// deletes to all the shards of a keyspace.
+// ReadYourWrites makes the replica and rdonly reads see the
+// transactions committed by the session, and its autocommitted
+// writes: ShardPositions keeps the replication positions of their
+// shards. MaxStalenessSeconds, if not
+// zero, is the maximum replication lag of the replica and rdonly reads.
+// The reads that can't honor them are sent to the master.
 type Session struct {
-	InTransaction bool
-	ShardSessions []*ShardSession
+	InTransaction       bool
+	ShardSessions       []*ShardSession
+	AllowScatterDml     bool
+	ReadYourWrites      bool
+	ShardPositions      []*ShardPosition
+	MaxStalenessSeconds int64
 }
 
 //go:generate bsongen -file $GOFILE -type Session -o session_bson.go
 
 func (session *Session) String() string {
-	return fmt.Sprintf("InTransaction: %v, ShardSession: %+v", session.InTransaction, session.ShardSessions)
+	return fmt.Sprintf("InTransaction: %v, ShardSession: %+v, AllowScatterDml: %v, ReadYourWrites: %v, ShardPositions: %+v, MaxStalenessSeconds: %v", session.InTransaction, session.ShardSessions, session.AllowScatterDml, session.ReadYourWrites, session.ShardPositions, session.MaxStalenessSeconds)
 }
 
 // ShardSession represents the session state for a shard.
@@ -41,6 +53,20 @@ func (shardSession *ShardSession) String() string {
 	return fmt.Sprintf("Keyspace: %v, Shard: %v, TabletType: %v, TransactionId: %v", shardSession.Keyspace, shardSession.Shard, shardSession.TabletType, shardSession.TransactionId)
 }
 
+// ShardPosition is the replication position of a shard
+// after the last commit of a session.
+type ShardPosition struct {
+	Keyspace string
+	Shard    string
+	Position string
+}
+
+//go:generate bsongen -file $GOFILE -type ShardPosition -o shard_position_bson.go
+
+func (shardPosition *ShardPosition) String() string {
+	return fmt.Sprintf("Keyspace: %v, Shard: %v, Position: %v", shardPosition.Keyspace, shardPosition.Shard, shardPosition.Position)
+}
+
 // Query represents a keyspace agnostic query request.
 type Query struct {
 	Sql              string
@@ -186,6 +212,21 @@ type SplitQueryRequest struct {
 	SplitCount  int
 }
 
+// SplitQueryV2Request is a request to split a query into multiple
+// parts with SplitQueryV2. The parts scan ranges of SplitColumns,
+// which must be a prefix of the primary key of the table of Query, or
+// empty to use the whole primary key. Each part has about
+// NumRowsPerQueryPart rows. If it's 0, there are about SplitCount
+// parts. The parts are sent to TabletType tablets, rdonly if empty.
+type SplitQueryV2Request struct {
+	Keyspace            string
+	Query               tproto.BoundQuery
+	SplitColumns        []string
+	SplitCount          int
+	NumRowsPerQueryPart int64
+	TabletType          topo.TabletType
+}
+
 // SplitQueryPart is a sub query of SplitQueryRequest.Query
 // Only one of Query or QueryShard will be set.
 type SplitQueryPart struct {
@@ -200,6 +241,33 @@ type SplitQueryResult struct {
 	Err    *mproto.RPCError
 }
 
+// QueryPlan describes how VTGate routes a query.
+// It's returned by ExplainQuery.
+type QueryPlan struct {
+	// PlanID is the name of the plan, like SelectEqual.
+	PlanID string
+	// Reason is set if VTGate cannot route the query.
+	Reason    string
+	Table     string
+	Col       string
+	Vindex    string
+	Rewritten string
+	Keyspace  string
+	// Shards are the shards the query is sent to
+	// for the bind variables of the request.
+	Shards []string
+	// Left and Right are the plans of the two sides of a join.
+	// The shards of Right depend on the rows returned by Left,
+	// and are not set.
+	Left, Right *QueryPlan
+}
+
+// ExplainQueryResult is the result for ExplainQuery
+type ExplainQueryResult struct {
+	Plan *QueryPlan
+	Err  *mproto.RPCError
+}
+
 // BeginRequest is the BSON implementation of the proto3 query.BeginkRequest
 type BeginRequest struct {
 	CallerID *tproto.CallerID
@@ -224,6 +292,9 @@ type CommitResponse struct {
 	// Err is named 'Err' instead of 'Error' (as the proto3 version is) to remain
 	// consistent with other BSON structs.
 	Err *mproto.RPCError
+	// Session is only returned for the sessions with ReadYourWrites:
+	// it carries the replication positions of the commit.
+	Session *Session
 }
 
 // RollbackRequest is the BSON implementation of the proto3 vtgate.RollbackRequest
@@ -28,17 +28,32 @@ var commonSession = Session{
 		TabletType:    topo.TabletType("master"),
 		TransactionId: 2,
 	}},
+	ReadYourWrites: true,
+	ShardPositions: []*ShardPosition{{
+		Keyspace: "b",
+		Shard:    "1",
+		Position: "MariaDB/0-1-5",
+	}},
+	MaxStalenessSeconds: 10,
 }
 
 type reflectSession struct {
-	InTransaction bool
-	ShardSessions []*ShardSession
+	InTransaction       bool
+	ShardSessions       []*ShardSession
+	AllowScatterDml     bool
+	ReadYourWrites      bool
+	ShardPositions      []*ShardPosition
+	MaxStalenessSeconds int64
 }
 
 type extraSession struct {
-	Extra         int
-	InTransaction bool
-	ShardSessions []*ShardSession
+	Extra               int
+	InTransaction       bool
+	ShardSessions       []*ShardSession
+	AllowScatterDml     bool
+	ReadYourWrites      bool
+	ShardPositions      []*ShardPosition
+	MaxStalenessSeconds int64
 }
 
 func TestSession(t *testing.T) {
@@ -55,6 +70,13 @@ func TestSession(t *testing.T) {
 			TabletType:    topo.TabletType("master"),
 			TransactionId: 2,
 		}},
+		ReadYourWrites: true,
+		ShardPositions: []*ShardPosition{{
+			Keyspace: "b",
+			Shard:    "1",
+			Position: "MariaDB/0-1-5",
+		}},
+		MaxStalenessSeconds: 10,
 	})
 	if err != nil {
 		t.Error(err)
@@ -164,7 +186,7 @@ func TestQueryShard(t *testing.T) {
 func TestQueryResult(t *testing.T) {
 	// We can't do the reflection test because bson
 	// doesn't do it correctly for embedded fields.
-	want := "\xc7\x01\x00\x00\x03Result\x00\x99\x00\x00\x00\x04Fields\x009\x00\x00\x00\x030\x001\x00\x00\x00\x05Name\x00\x04\x00\x00\x00\x00name\x12Type\x00\x01\x00\x00\x00\x00\x00\x00\x00\x12Flags\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00?RowsAffected\x00\x02\x00\x00\x00\x00\x00\x00\x00?InsertId\x00\x03\x00\x00\x00\x00\x00\x00\x00\x04Rows\x00 \x00\x00\x00\x040\x00\x18\x00\x00\x00\x050\x00\x01\x00\x00\x00\x001\x051\x00\x02\x00\x00\x00\x00aa\x00\x00\nErr\x00\x00\x03Session\x00\xd0\x00\x00\x00\bInTransaction\x00\x01\x04ShardSessions\x00\xac\x00\x00\x00\x030\x00Q\x00\x00\x00\x05Keyspace\x00\x01\x00\x00\x00\x00a\x05Shard\x00\x01\x00\x00\x00\x000\x05TabletType\x00\a\x00\x00\x00\x00replica\x12TransactionId\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x031\x00P\x00\x00\x00\x05Keyspace\x00\x01\x00\x00\x00\x00b\x05Shard\x00\x01\x00\x00\x00\x001\x05TabletType\x00\x06\x00\x00\x00\x00master\x12TransactionId\x00\x02\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x05Error\x00\x05\x00\x00\x00\x00error\x03Err\x002\x00\x00\x00\x12Code\x00\xd0\a\x00\x00\x00\x00\x00\x00\x05Message\x00\x11\x00\x00\x00\x00failed due to err\x00\x00"
+	want := "]\x02\x00\x00\x03Result\x00\x99\x00\x00\x00\x04Fields\x009\x00\x00\x00\x030\x001\x00\x00\x00\x05Name\x00\x04\x00\x00\x00\x00name\x12Type\x00\x01\x00\x00\x00\x00\x00\x00\x00\x12Flags\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00?RowsAffected\x00\x02\x00\x00\x00\x00\x00\x00\x00?InsertId\x00\x03\x00\x00\x00\x00\x00\x00\x00\x04Rows\x00 \x00\x00\x00\x040\x00\x18\x00\x00\x00\x050\x00\x01\x00\x00\x00\x001\x051\x00\x02\x00\x00\x00\x00aa\x00\x00\nErr\x00\x00\x03Session\x00f\x01\x00\x00\bInTransaction\x00\x01\x04ShardSessions\x00\xac\x00\x00\x00\x030\x00Q\x00\x00\x00\x05Keyspace\x00\x01\x00\x00\x00\x00a\x05Shard\x00\x01\x00\x00\x00\x000\x05TabletType\x00\a\x00\x00\x00\x00replica\x12TransactionId\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x031\x00P\x00\x00\x00\x05Keyspace\x00\x01\x00\x00\x00\x00b\x05Shard\x00\x01\x00\x00\x00\x001\x05TabletType\x00\x06\x00\x00\x00\x00master\x12TransactionId\x00\x02\x00\x00\x00\x00\x00\x00\x00\x00\x00\bAllowScatterDml\x00\x00\bReadYourWrites\x00\x01\x04ShardPositions\x00F\x00\x00\x00\x030\x00>\x00\x00\x00\x05Keyspace\x00\x01\x00\x00\x00\x00b\x05Shard\x00\x01\x00\x00\x00\x001\x05Position\x00\r\x00\x00\x00\x00MariaDB/0-1-5\x00\x00\x12MaxStalenessSeconds\x00\n\x00\x00\x00\x00\x00\x00\x00\x00\x05Error\x00\x05\x00\x00\x00\x00error\x03Err\x002\x00\x00\x00\x12Code\x00\xd0\a\x00\x00\x00\x00\x00\x00\x05Message\x00\x11\x00\x00\x00\x00failed due to err\x00\x00"
 
 	custom := QueryResult{
 		Result: &mproto.QueryResult{
@@ -244,6 +266,13 @@ func TestBatchQueryShard(t *testing.T) {
 				TabletType:    topo.TabletType("master"),
 				TransactionId: 2,
 			}},
+			ReadYourWrites: true,
+			ShardPositions: []*ShardPosition{{
+				Keyspace: "b",
+				Shard:    "1",
+				Position: "MariaDB/0-1-5",
+			}},
+			MaxStalenessSeconds: 10,
 		},
 	})
 	if err != nil {
@@ -579,6 +608,13 @@ func TestKeyspaceIdBatchQuery(t *testing.T) {
 				TabletType:    topo.TabletType("master"),
 				TransactionId: 2,
 			}},
+			ReadYourWrites: true,
+			ShardPositions: []*ShardPosition{{
+				Keyspace: "b",
+				Shard:    "1",
+				Position: "MariaDB/0-1-5",
+			}},
+			MaxStalenessSeconds: 10,
 		},
 	})
 	if err != nil {
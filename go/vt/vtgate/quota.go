@@ -0,0 +1,116 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vtgate
+
+import (
+	"flag"
+	"time"
+
+	"github.com/youtube/vitess/go/vt/key"
+	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
+	"github.com/youtube/vitess/go/vt/vtgate/proto"
+	"golang.org/x/net/context"
+)
+
+var quotasRefreshInterval = flag.Duration("vtgate_quotas_refresh_interval", 1*time.Minute, "how often the per-caller quotas are reloaded from the topo server")
+
+// InitQuotas loads the per-caller quotas of VTGate from store, and
+// reloads them every -vtgate_quotas_refresh_interval. Without it,
+// the callers have no quotas.
+func InitQuotas(store topo.VTGateQuotaStore) {
+	rpcVTGate.quotas.Load(store, *quotasRefreshInterval)
+}
+
+// admit checks that a new query can be served: VTGate must be under
+// its maximum number of requests in flight, and the caller of ctx
+// under its quotas. isScatter returns true if the query targets more
+// than one shard, it's only called if the quotas need it. The
+// returned function must be called once the query is done.
+func (vtg *VTGate) admit(ctx context.Context, isScatter func() bool) (done func(), err error) {
+	x := vtg.inFlight.Add(1)
+	if 0 < vtg.maxInFlight && vtg.maxInFlight < x {
+		vtg.inFlight.Add(-1)
+		return nil, errTooManyInFlight
+	}
+	release, err := vtg.quotas.Acquire(ctx, isScatter)
+	if err != nil {
+		vtg.inFlight.Add(-1)
+		return nil, err
+	}
+	return func() {
+		release()
+		vtg.inFlight.Add(-1)
+	}, nil
+}
+
+// scatterPlans are the plans that send their query to all the shards
+// of a keyspace.
+var scatterPlans = map[planbuilder.PlanID]bool{
+	planbuilder.SelectScatter:          true,
+	planbuilder.SelectScatterMerge:     true,
+	planbuilder.SelectScatterAggregate: true,
+	planbuilder.UpdateScatter:          true,
+	planbuilder.DeleteScatter:          true,
+}
+
+// isScatter returns true if the plan of sql, or of one of the sides
+// of its joins, is a scatter plan. The plans that need the bind
+// variables to pick their shards aren't counted as scatter queries.
+func (rtr *Router) isScatter(sql string) bool {
+	return isScatterPlan(rtr.planner.GetPlan(sql))
+}
+
+func isScatterPlan(plan *planbuilder.Plan) bool {
+	if plan == nil {
+		return false
+	}
+	return scatterPlans[plan.ID] || isScatterPlan(plan.Left) || isScatterPlan(plan.Right)
+}
+
+// The following functions return true if a query of the Resolver
+// targets more than one shard. Queries that can't be mapped to
+// their shards aren't scatter queries: they fail later on anyway.
+
+func (res *Resolver) isScatterKeyspaceIds(ctx context.Context, keyspace string, tabletType topo.TabletType, keyspaceIds []key.KeyspaceId) bool {
+	if len(keyspaceIds) < 2 {
+		return false
+	}
+	_, shards, err := mapKeyspaceIdsToShards(ctx, res.scatterConn.toposerv, res.scatterConn.cell, keyspace, tabletType, keyspaceIds)
+	return err == nil && len(shards) > 1
+}
+
+func (res *Resolver) isScatterKeyRanges(ctx context.Context, keyspace string, tabletType topo.TabletType, keyRanges []key.KeyRange) bool {
+	_, shards, err := mapKeyRangesToShards(ctx, res.scatterConn.toposerv, res.scatterConn.cell, keyspace, tabletType, keyRanges)
+	return err == nil && len(shards) > 1
+}
+
+func (res *Resolver) isScatterEntityIds(ctx context.Context, query *proto.EntityIdsQuery) bool {
+	if len(query.EntityKeyspaceIDs) < 2 {
+		return false
+	}
+	_, shardIDMap, err := mapEntityIdsToShards(ctx, res.scatterConn.toposerv, res.scatterConn.cell, query.Keyspace, query.EntityKeyspaceIDs, query.TabletType)
+	return err == nil && len(shardIDMap) > 1
+}
+
+func isScatterBatchShard(queries []proto.BoundShardQuery) bool {
+	shards := make(map[string]bool)
+	for _, query := range queries {
+		for _, shard := range query.Shards {
+			shards[query.Keyspace+"/"+shard] = true
+		}
+	}
+	return len(shards) > 1
+}
+
+func isScatterBatchKeyspaceIds(queries []proto.BoundKeyspaceIdQuery) bool {
+	keyspaceIds := make(map[string]bool)
+	for _, query := range queries {
+		for _, keyspaceID := range query.KeyspaceIds {
+			keyspaceIds[query.Keyspace+"/"+string(keyspaceID)] = true
+		}
+	}
+	return len(keyspaceIds) > 1
+}
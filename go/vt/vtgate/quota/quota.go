@@ -0,0 +1,283 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package quota enforces per-caller query limits in vtgate.
+//
+// The limits are a JSON document stored in the topo server, for
+// instance:
+//
+//	{
+//	  "Default": {"QPS": 100, "MaxConcurrent": 10, "MaxScatterConcurrent": 2},
+//	  "Principals": {
+//	    "batch": {"QPS": 10, "MaxConcurrent": 2, "MaxScatterConcurrent": 1}
+//	  }
+//	}
+//
+// Callers are identified by the principal of their effective caller
+// id. The callers without a quota of their own, including the ones
+// without a caller id, use the Default quota. A zero limit means no
+// limit.
+package quota
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/golang/glog"
+	"github.com/youtube/vitess/go/ratelimiter"
+	"github.com/youtube/vitess/go/stats"
+	"github.com/youtube/vitess/go/sync2"
+	"github.com/youtube/vitess/go/vt/callerid"
+	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/vterrors"
+	"golang.org/x/net/context"
+)
+
+// ErrQuotaExceeded starts the message of the errors of the rejected
+// queries.
+const ErrQuotaExceeded = "quota_exceeded"
+
+// Quota is the set of limits of a caller.
+type Quota struct {
+	// QPS is the maximum number of queries per second.
+	QPS int
+	// MaxConcurrent is the maximum number of queries in flight.
+	MaxConcurrent int
+	// MaxScatterConcurrent is the maximum number of queries in
+	// flight that target more than one shard.
+	MaxScatterConcurrent int
+}
+
+// Config is the quota configuration of vtgate.
+type Config struct {
+	Default    Quota
+	Principals map[string]Quota
+}
+
+// NewConfig parses a JSON quota configuration.
+func NewConfig(data []byte) (*Config, error) {
+	config := &Config{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("Unmarshal failed: %v, %s", err, data)
+	}
+	if err := config.Default.validate(); err != nil {
+		return nil, fmt.Errorf("invalid Default quota: %v", err)
+	}
+	for principal, quota := range config.Principals {
+		if err := quota.validate(); err != nil {
+			return nil, fmt.Errorf("invalid quota for %v: %v", principal, err)
+		}
+	}
+	return config, nil
+}
+
+func (quota Quota) validate() error {
+	if quota.QPS < 0 || quota.MaxConcurrent < 0 || quota.MaxScatterConcurrent < 0 {
+		return fmt.Errorf("negative limit in %+v", quota)
+	}
+	return nil
+}
+
+// hasLimits returns true if quota has a limit.
+func (quota Quota) hasLimits() bool {
+	return quota.QPS != 0 || quota.MaxConcurrent != 0 || quota.MaxScatterConcurrent != 0
+}
+
+// hasLimits returns true if one of the quotas of config has a limit.
+func (config *Config) hasLimits() bool {
+	if config.Default.hasLimits() {
+		return true
+	}
+	for _, quota := range config.Principals {
+		if quota.hasLimits() {
+			return true
+		}
+	}
+	return false
+}
+
+// quota returns the quota of principal.
+func (config *Config) quota(principal string) Quota {
+	if quota, ok := config.Principals[principal]; ok {
+		return quota
+	}
+	return config.Default
+}
+
+// idleTimeout is how long a caller without queries in flight is
+// kept. It's longer than the interval of the rate limiters, so that
+// forgetting a caller doesn't reset its rate.
+const idleTimeout = time.Minute
+
+// Manager enforces a Config. The zero Config, which the Manager
+// starts with, has no limits.
+type Manager struct {
+	queries    *stats.MultiCounters
+	rejections *stats.MultiCounters
+
+	// enabled is 1 if the config has a limit. Otherwise, the
+	// queries aren't checked.
+	enabled sync2.AtomicInt32
+
+	// mu protects all the fields below, and the callers.
+	mu        sync.Mutex
+	config    *Config
+	callers   map[string]*caller
+	lastEvict time.Time
+}
+
+// caller is the state of the quota of a principal.
+type caller struct {
+	quota             Quota
+	limiter           *ratelimiter.RateLimiter
+	concurrent        int
+	scatterConcurrent int
+	lastUsed          time.Time
+}
+
+// newLimiter returns the rate limiter of qps, nil if there is no limit.
+func newLimiter(qps int) *ratelimiter.RateLimiter {
+	if qps == 0 {
+		return nil
+	}
+	return ratelimiter.NewRateLimiter(qps, time.Second)
+}
+
+// NewManager creates a Manager, which exports its stats with
+// counterPrefix.
+func NewManager(counterPrefix string) *Manager {
+	return &Manager{
+		queries:    stats.NewMultiCounters(counterPrefix+"Queries", []string{"Principal"}),
+		rejections: stats.NewMultiCounters(counterPrefix+"Rejections", []string{"Principal", "Reason"}),
+		config:     &Config{},
+		callers:    make(map[string]*caller),
+	}
+}
+
+// SetConfig replaces the configuration of the Manager. The queries
+// in flight count against the new limits.
+func (m *Manager) SetConfig(config *Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config = config
+	if config.hasLimits() {
+		m.enabled.Set(1)
+	} else {
+		m.enabled.Set(0)
+	}
+	for principal, c := range m.callers {
+		quota := config.quota(principal)
+		if quota.QPS != c.quota.QPS {
+			c.limiter = newLimiter(quota.QPS)
+		}
+		c.quota = quota
+	}
+}
+
+// Acquire checks the quota of the caller of ctx for a new query, and
+// reserves a slot for it. The scatter queries, for which isScatter
+// returns true, also use the scatter concurrency limit. isScatter is
+// only called if the quotas are enabled. It returns a QuotaExceeded
+// vterrors error if the caller is over one of its limits. Otherwise,
+// the returned function must be called once the query is done.
+func (m *Manager) Acquire(ctx context.Context, isScatter func() bool) (release func(), err error) {
+	if m.enabled.Get() == 0 {
+		return func() {}, nil
+	}
+	principal := callerid.GetPrincipal(callerid.EffectiveCallerIDFromContext(ctx))
+	m.queries.Add([]string{principal}, 1)
+	// isScatter may have to resolve the shards of the query, so
+	// it's called before m.mu is locked.
+	scatter := isScatter()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if now.Sub(m.lastEvict) > idleTimeout {
+		m.evictIdle(now)
+	}
+	c, ok := m.callers[principal]
+	if !ok {
+		quota := m.config.quota(principal)
+		c = &caller{
+			quota:   quota,
+			limiter: newLimiter(quota.QPS),
+		}
+		m.callers[principal] = c
+	}
+	c.lastUsed = now
+
+	// The rate is checked last, so the queries rejected for their
+	// concurrency don't use it.
+	switch {
+	case c.quota.MaxConcurrent != 0 && c.concurrent >= c.quota.MaxConcurrent:
+		return nil, m.reject(principal, "Concurrent", fmt.Sprintf("%d concurrent queries", c.quota.MaxConcurrent))
+	case scatter && c.quota.MaxScatterConcurrent != 0 && c.scatterConcurrent >= c.quota.MaxScatterConcurrent:
+		return nil, m.reject(principal, "ScatterConcurrent", fmt.Sprintf("%d concurrent scatter queries", c.quota.MaxScatterConcurrent))
+	case c.limiter != nil && !c.limiter.Allow():
+		return nil, m.reject(principal, "QPS", fmt.Sprintf("%d queries per second", c.quota.QPS))
+	}
+
+	c.concurrent++
+	if scatter {
+		c.scatterConcurrent++
+	}
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		c.concurrent--
+		if scatter {
+			c.scatterConcurrent--
+		}
+		c.lastUsed = time.Now()
+	}, nil
+}
+
+// evictIdle forgets the callers that have no query in flight, and
+// had none for idleTimeout. It must be called with m.mu held.
+func (m *Manager) evictIdle(now time.Time) {
+	for principal, c := range m.callers {
+		if c.concurrent == 0 && now.Sub(c.lastUsed) > idleTimeout {
+			delete(m.callers, principal)
+		}
+	}
+	m.lastEvict = now
+}
+
+func (m *Manager) reject(principal, reason, limit string) error {
+	m.rejections.Add([]string{principal, reason}, 1)
+	return vterrors.FromError(vterrors.QuotaExceeded, fmt.Errorf("%s: caller %q is over its limit of %s", ErrQuotaExceeded, principal, limit))
+}
+
+// Load reads the configuration of the Manager from store. If refresh
+// isn't zero, it then reloads it in the background every refresh. A
+// configuration that can't be read or parsed is ignored, the Manager
+// keeps the previous one.
+func (m *Manager) Load(store topo.VTGateQuotaStore, refresh time.Duration) {
+	m.load(store)
+	if refresh == 0 {
+		return
+	}
+	go func() {
+		for range time.Tick(refresh) {
+			m.load(store)
+		}
+	}()
+}
+
+func (m *Manager) load(store topo.VTGateQuotaStore) {
+	data, err := store.GetVTGateQuotas(context.Background())
+	if err != nil {
+		log.Warningf("cannot read the vtgate quotas, keeping the current ones: %v", err)
+		return
+	}
+	config, err := NewConfig([]byte(data))
+	if err != nil {
+		log.Warningf("invalid vtgate quotas, keeping the current ones: %v", err)
+		return
+	}
+	m.SetConfig(config)
+}
@@ -0,0 +1,208 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package quota
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/youtube/vitess/go/vt/callerid"
+	"github.com/youtube/vitess/go/vt/vterrors"
+	"golang.org/x/net/context"
+)
+
+func callerContext(principal string) context.Context {
+	return callerid.NewContext(context.Background(), callerid.NewEffectiveCallerID(principal, "", ""), nil)
+}
+
+func scatter() bool    { return true }
+func notScatter() bool { return false }
+
+func TestNewConfig(t *testing.T) {
+	config, err := NewConfig([]byte(`{"Default": {"QPS": 10}, "Principals": {"batch": {"MaxConcurrent": 2}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config.quota("batch"); got != (Quota{MaxConcurrent: 2}) {
+		t.Errorf("quota(batch): %+v, want MaxConcurrent 2", got)
+	}
+	if got := config.quota("other"); got != (Quota{QPS: 10}) {
+		t.Errorf("quota(other): %+v, want QPS 10", got)
+	}
+
+	testcases := []struct {
+		in  string
+		err string
+	}{
+		{"invalid", "Unmarshal failed:"},
+		{`{"Default": {"QPS": -1}}`, "invalid Default quota:"},
+		{`{"Principals": {"batch": {"MaxScatterConcurrent": -1}}}`, "invalid quota for batch:"},
+	}
+	for _, tc := range testcases {
+		if _, err := NewConfig([]byte(tc.in)); err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+			t.Errorf("NewConfig(%s): %v, must start with %s", tc.in, err, tc.err)
+		}
+	}
+}
+
+// checkRejected makes sure err is a quota error of reason.
+func checkRejected(t *testing.T, err error, reason string) {
+	vtErr, ok := err.(*vterrors.VitessError)
+	if !ok || vtErr.Code != vterrors.QuotaExceeded {
+		t.Fatalf("got %v, want a QuotaExceeded error", err)
+	}
+	if want := ErrQuotaExceeded + ": "; !strings.HasPrefix(err.Error(), want) || !strings.Contains(err.Error(), reason) {
+		t.Errorf("got %v, want %s... %s", err, want, reason)
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	m := NewManager("TestConcurrency")
+	m.SetConfig(&Config{
+		Default: Quota{MaxConcurrent: 2, MaxScatterConcurrent: 1},
+	})
+	ctx := callerContext("user")
+
+	release1, err := m.Acquire(ctx, scatter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Acquire(ctx, scatter)
+	checkRejected(t, err, "1 concurrent scatter queries")
+	release2, err := m.Acquire(ctx, notScatter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Acquire(ctx, notScatter)
+	checkRejected(t, err, "2 concurrent queries")
+
+	// other callers have their own slots
+	if _, err := m.Acquire(callerContext("other"), scatter); err != nil {
+		t.Errorf("other caller: %v", err)
+	}
+
+	release1()
+	release2()
+	if _, err := m.Acquire(ctx, scatter); err != nil {
+		t.Errorf("after release: %v", err)
+	}
+
+	want := map[string]int64{"user.ScatterConcurrent": 1, "user.Concurrent": 1}
+	for k, v := range want {
+		if got := m.rejections.Counts()[k]; got != v {
+			t.Errorf("rejections[%v]: %v, want %v", k, got, v)
+		}
+	}
+	if got := m.queries.Counts()["user"]; got != 5 {
+		t.Errorf("queries[user]: %v, want 5", got)
+	}
+}
+
+func TestQPS(t *testing.T) {
+	m := NewManager("TestQPS")
+	m.SetConfig(&Config{
+		Principals: map[string]Quota{"batch": {QPS: 2}},
+	})
+	ctx := callerContext("batch")
+	for i := 0; i < 2; i++ {
+		release, err := m.Acquire(ctx, notScatter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	_, err := m.Acquire(ctx, notScatter)
+	checkRejected(t, err, "2 queries per second")
+
+	// callers without a caller id use the unlimited Default
+	for i := 0; i < 3; i++ {
+		if _, err := m.Acquire(context.Background(), notScatter); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a new configuration applies to the known callers
+	m.SetConfig(&Config{})
+	if _, err := m.Acquire(ctx, notScatter); err != nil {
+		t.Errorf("after SetConfig: %v", err)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	m := NewManager("TestDisabled")
+	isScatter := func() bool {
+		t.Errorf("isScatter was called without quotas")
+		return false
+	}
+	release, err := m.Acquire(callerContext("user"), isScatter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	m.SetConfig(&Config{Principals: map[string]Quota{"batch": {}}})
+	if _, err := m.Acquire(callerContext("user"), isScatter); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.callers) != 0 || len(m.queries.Counts()) != 0 {
+		t.Errorf("the queries were checked without quotas: %v %v", m.callers, m.queries.Counts())
+	}
+}
+
+func TestEvictIdle(t *testing.T) {
+	m := NewManager("TestEvictIdle")
+	m.SetConfig(&Config{Default: Quota{MaxConcurrent: 1}})
+	release, err := m.Acquire(callerContext("busy"), notScatter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	idleRelease, err := m.Acquire(callerContext("idle"), notScatter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idleRelease()
+
+	// The next Acquire after idleTimeout evicts the idle callers,
+	// but keeps the ones with queries in flight.
+	m.lastEvict = time.Now().Add(-2 * idleTimeout)
+	m.callers["idle"].lastUsed = time.Now().Add(-2 * idleTimeout)
+	if _, err := m.Acquire(callerContext("other"), notScatter); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.callers["idle"]; ok {
+		t.Errorf("the idle caller wasn't evicted")
+	}
+	_, err = m.Acquire(callerContext("busy"), notScatter)
+	checkRejected(t, err, "1 concurrent queries")
+}
+
+type fakeStore struct {
+	quotas string
+}
+
+func (fs *fakeStore) SaveVTGateQuotas(ctx context.Context, quotas string) error {
+	fs.quotas = quotas
+	return nil
+}
+
+func (fs *fakeStore) GetVTGateQuotas(ctx context.Context) (string, error) {
+	return fs.quotas, nil
+}
+
+func TestLoad(t *testing.T) {
+	m := NewManager("TestLoad")
+	m.Load(&fakeStore{quotas: `{"Default": {"MaxConcurrent": 1}}`}, 0)
+	if _, err := m.Acquire(context.Background(), notScatter); err != nil {
+		t.Fatal(err)
+	}
+	_, err := m.Acquire(context.Background(), notScatter)
+	checkRejected(t, err, "1 concurrent queries")
+
+	// an invalid configuration is ignored
+	m.Load(&fakeStore{quotas: "invalid"}, 0)
+	_, err = m.Acquire(context.Background(), notScatter)
+	checkRejected(t, err, "1 concurrent queries")
+}
@@ -0,0 +1,91 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vtgate
+
+import (
+	"flag"
+	"time"
+
+	log "github.com/golang/glog"
+	"github.com/youtube/vitess/go/stats"
+	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/vtgate/proto"
+	"golang.org/x/net/context"
+)
+
+var (
+	positionWaitTimeout = flag.Duration("read_your_writes_wait_timeout", 1*time.Second, "maximum time a replica or rdonly tablet is given to replicate the writes of a session with ReadYourWrites. The reads are sent to the master afterwards")
+
+	masterFallbacks = stats.NewCounters("ReplicaReadMasterFallbacks")
+)
+
+// recordPositions reads the replication positions of the masters of
+// shardSessions into session, once their transactions are committed.
+// A position that can't be read is recorded as empty: the next reads
+// of its shard are sent to the master.
+func (stc *ScatterConn) recordPositions(ctx context.Context, session *SafeSession, shardSessions []*proto.ShardSession) {
+	for _, shardSession := range shardSessions {
+		if shardSession.TabletType != topo.TYPE_MASTER {
+			continue
+		}
+		stc.recordPosition(ctx, stc.getConnection(ctx, shardSession.Keyspace, shardSession.Shard, shardSession.TabletType), session)
+	}
+}
+
+// recordAutocommitPosition records the replication position of the
+// master of sdc into session after a query outside of a transaction,
+// which the tablet autocommits if it's a write. The position is only
+// recorded for the masters, and if session reads its writes.
+func (stc *ScatterConn) recordAutocommitPosition(ctx context.Context, sdc *ShardConn, session *SafeSession) {
+	if sdc.tabletType != topo.TYPE_MASTER || !session.ReadsYourWrites() {
+		return
+	}
+	stc.recordPosition(ctx, sdc, session)
+}
+
+// recordPosition reads the replication position of the master of sdc
+// into session. A position that can't be read is recorded as empty.
+func (stc *ScatterConn) recordPosition(ctx context.Context, sdc *ShardConn, session *SafeSession) {
+	position, err := sdc.MasterPosition(ctx)
+	if err != nil {
+		log.Warningf("cannot read the replication position of %s/%s, its next reads will use the master: %v", sdc.keyspace, sdc.shard, err)
+	}
+	session.SetPosition(sdc.keyspace, sdc.shard, position)
+}
+
+// readConnection returns the connection a read outside of a
+// transaction must use to honor the read options of session.
+// It's sdc, pinned to the tablet that replicated the last commit of
+// the session if it must be waited for. If the tablet lags more than
+// the maximum staleness, or can't replicate that commit in time, the
+// read is sent to the master of the shard.
+func (stc *ScatterConn) readConnection(ctx context.Context, sdc *ShardConn, session *SafeSession) *ShardConn {
+	if sdc.tabletType == topo.TYPE_MASTER {
+		return sdc
+	}
+	position, mustWait, maxStaleness := session.ReadOptions(sdc.keyspace, sdc.shard)
+	if maxStaleness != 0 {
+		if lag, ok := sdc.ReplicationLag(ctx); !ok || lag > maxStaleness {
+			masterFallbacks.Add("Staleness", 1)
+			return stc.getConnection(ctx, sdc.keyspace, sdc.shard, topo.TYPE_MASTER)
+		}
+	}
+	if mustWait {
+		if position == "" {
+			masterFallbacks.Add("UnknownPosition", 1)
+			return stc.getConnection(ctx, sdc.keyspace, sdc.shard, topo.TYPE_MASTER)
+		}
+		waitCtx, cancel := context.WithTimeout(ctx, *positionWaitTimeout)
+		pinned, err := sdc.WaitMasterPos(waitCtx, position, *positionWaitTimeout)
+		cancel()
+		if err != nil {
+			masterFallbacks.Add("Position", 1)
+			return stc.getConnection(ctx, sdc.keyspace, sdc.shard, topo.TYPE_MASTER)
+		}
+		// The read must go to the tablet that replicated position.
+		return pinned
+	}
+	return sdc
+}
@@ -34,3 +34,7 @@ func (vc *requestContext) Execute(boundQuery *tproto.BoundQuery) (*mproto.QueryR
 	}
 	return vc.router.Execute(vc.ctx, q)
 }
+
+func (vc *requestContext) NextSequenceValue(sequence string) (int64, error) {
+	return vc.router.nextSequenceValue(vc.ctx, sequence)
+}
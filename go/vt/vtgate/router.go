@@ -8,10 +8,15 @@ package vtgate
 
 import (
 	"fmt"
+	"math/rand"
+	"strings"
 
 	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/sqltypes"
+	"github.com/youtube/vitess/go/sync2"
 	"github.com/youtube/vitess/go/vt/key"
-	"github.com/youtube/vitess/go/vt/topo"
+	tproto "github.com/youtube/vitess/go/vt/tabletserver/proto"
+	"github.com/youtube/vitess/go/vt/vterrors"
 	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
 	"github.com/youtube/vitess/go/vt/vtgate/proto"
 	"golang.org/x/net/context"
@@ -29,6 +34,11 @@ type Router struct {
 	cell        string
 	planner     *Planner
 	scatterConn *ScatterConn
+	sequences   *sequenceCache
+
+	// consolidate is set by -vtgate_consolidate_reads.
+	consolidate  bool
+	consolidator *sync2.Consolidator
 }
 
 type scatterParams struct {
@@ -55,6 +65,10 @@ func NewRouter(serv SrvTopoServer, cell string, schema *planbuilder.Schema, stat
 		cell:        cell,
 		planner:     NewPlanner(schema, 5000),
 		scatterConn: scatterConn,
+		sequences:   newSequenceCache(),
+
+		consolidate:  *consolidateReads,
+		consolidator: sync2.NewConsolidator(),
 	}
 }
 
@@ -65,12 +79,33 @@ func (rtr *Router) Execute(ctx context.Context, query *proto.Query) (*mproto.Que
 	}
 	vcursor := newRequestContext(ctx, query, rtr)
 	plan := rtr.planner.GetPlan(string(query.Sql))
+	if rtr.consolidate && isRead(plan) && canConsolidate(query) {
+		return rtr.executeConsolidated(vcursor, plan)
+	}
+	return rtr.execute(vcursor, plan)
+}
 
+// execute executes a plan. It's also used for the
+// sub-plans of a join.
+func (rtr *Router) execute(vcursor *requestContext, plan *planbuilder.Plan) (*mproto.QueryResult, error) {
 	switch plan.ID {
+	case planbuilder.SelectEqualMerge, planbuilder.SelectINMerge, planbuilder.SelectScatterMerge, planbuilder.SelectRangeMerge:
+		return rtr.execSelectMerge(vcursor, plan)
+	case planbuilder.SelectEqualAggregate, planbuilder.SelectINAggregate, planbuilder.SelectScatterAggregate,
+		planbuilder.SelectRangeAggregate:
+		return rtr.execSelectAggregate(vcursor, plan)
+	case planbuilder.SelectJoin, planbuilder.SelectLeftJoin:
+		return rtr.execSelectJoin(vcursor, plan)
 	case planbuilder.UpdateEqual:
 		return rtr.execUpdateEqual(vcursor, plan)
 	case planbuilder.DeleteEqual:
 		return rtr.execDeleteEqual(vcursor, plan)
+	case planbuilder.UpdateIN:
+		return rtr.execUpdateIN(vcursor, plan)
+	case planbuilder.DeleteIN:
+		return rtr.execDeleteIN(vcursor, plan)
+	case planbuilder.DeleteScatter:
+		return rtr.execDeleteScatter(vcursor, plan)
 	case planbuilder.InsertSharded:
 		return rtr.execInsertSharded(vcursor, plan)
 	}
@@ -81,6 +116,8 @@ func (rtr *Router) Execute(ctx context.Context, query *proto.Query) (*mproto.Que
 	case planbuilder.SelectUnsharded, planbuilder.UpdateUnsharded,
 		planbuilder.DeleteUnsharded, planbuilder.InsertUnsharded:
 		params, err = rtr.paramsUnsharded(vcursor, plan)
+	case planbuilder.SelectReference:
+		params, err = rtr.paramsSelectReference(vcursor, plan)
 	case planbuilder.SelectEqual:
 		params, err = rtr.paramsSelectEqual(vcursor, plan)
 	case planbuilder.SelectIN:
@@ -89,36 +126,54 @@ func (rtr *Router) Execute(ctx context.Context, query *proto.Query) (*mproto.Que
 		params, err = rtr.paramsSelectKeyrange(vcursor, plan)
 	case planbuilder.SelectScatter:
 		params, err = rtr.paramsSelectScatter(vcursor, plan)
+	case planbuilder.SelectRange:
+		params, err = rtr.paramsSelectRange(vcursor, plan)
+	case planbuilder.UpdateScatter:
+		params, err = rtr.paramsScatterDML(vcursor, plan)
 	default:
-		return nil, fmt.Errorf("cannot route query: %s: %s", query.Sql, plan.Reason)
+		return nil, fmt.Errorf("cannot route query: %s: %s", vcursor.query.Sql, plan.Reason)
 	}
 	if err != nil {
 		return nil, err
 	}
 	return rtr.scatterConn.ExecuteMulti(
-		ctx,
+		vcursor.ctx,
 		params.query,
 		params.ks,
 		params.shardVars,
-		query.TabletType,
+		vcursor.query.TabletType,
 		NewSafeSession(vcursor.query.Session),
-		query.NotInTransaction,
+		vcursor.query.NotInTransaction,
 	)
 }
 
-// StreamExecute executes a streaming query.
+// StreamExecute executes a streaming query. Only selects can be
+// streamed, and aggregates and joins are not supported: combining
+// aggregates requires all the rows of the shards, and a join sends
+// a query for every row of its left side. Such queries fail, and
+// must be sent through Execute instead.
 func (rtr *Router) StreamExecute(ctx context.Context, query *proto.Query, sendReply func(*mproto.QueryResult) error) error {
 	if query.BindVariables == nil {
 		query.BindVariables = make(map[string]interface{})
 	}
 	vcursor := newRequestContext(ctx, query, rtr)
 	plan := rtr.planner.GetPlan(string(query.Sql))
+	// The stream limits only count the rows sent to the client,
+	// after the OFFSET of the merge plans.
+	sendReply = limitStream(sendReply)
+
+	switch plan.ID {
+	case planbuilder.SelectEqualMerge, planbuilder.SelectINMerge, planbuilder.SelectScatterMerge, planbuilder.SelectRangeMerge:
+		return rtr.streamSelectMerge(vcursor, plan, sendReply)
+	}
 
 	var err error
 	var params *scatterParams
 	switch plan.ID {
 	case planbuilder.SelectUnsharded:
 		params, err = rtr.paramsUnsharded(vcursor, plan)
+	case planbuilder.SelectReference:
+		params, err = rtr.paramsSelectReference(vcursor, plan)
 	case planbuilder.SelectEqual:
 		params, err = rtr.paramsSelectEqual(vcursor, plan)
 	case planbuilder.SelectIN:
@@ -127,6 +182,8 @@ func (rtr *Router) StreamExecute(ctx context.Context, query *proto.Query, sendRe
 		params, err = rtr.paramsSelectKeyrange(vcursor, plan)
 	case planbuilder.SelectScatter:
 		params, err = rtr.paramsSelectScatter(vcursor, plan)
+	case planbuilder.SelectRange:
+		params, err = rtr.paramsSelectRange(vcursor, plan)
 	default:
 		return fmt.Errorf("query %q cannot be used for streaming", query.Sql)
 	}
@@ -140,13 +197,19 @@ func (rtr *Router) StreamExecute(ctx context.Context, query *proto.Query, sendRe
 		params.shardVars,
 		query.TabletType,
 		NewSafeSession(vcursor.query.Session),
+		nil,
 		sendReply,
 		query.NotInTransaction,
 	)
 }
 
 func (rtr *Router) paramsUnsharded(vcursor *requestContext, plan *planbuilder.Plan) (*scatterParams, error) {
-	ks, _, allShards, err := getKeyspaceShards(vcursor.ctx, rtr.serv, rtr.cell, plan.Table.Keyspace.Name, vcursor.query.TabletType)
+	keyspace := plan.Table.Keyspace.Name
+	if plan.Table.IsReference() {
+		// Writes to reference tables go to their source keyspace.
+		keyspace = plan.Table.Source.Name
+	}
+	ks, _, allShards, err := getKeyspaceShards(vcursor.ctx, rtr.serv, rtr.cell, keyspace, vcursor.query.TabletType)
 	if err != nil {
 		return nil, fmt.Errorf("paramsUnsharded: %v", err)
 	}
@@ -156,6 +219,20 @@ func (rtr *Router) paramsUnsharded(vcursor *requestContext, plan *planbuilder.Pl
 	return newScatterParams(vcursor.query.Sql, ks, vcursor.query.BindVariables, []string{allShards[0].Name}), nil
 }
 
+// paramsSelectReference sends the query to one of the shards, chosen
+// at random, since every shard has a copy of the reference tables.
+func (rtr *Router) paramsSelectReference(vcursor *requestContext, plan *planbuilder.Plan) (*scatterParams, error) {
+	ks, _, allShards, err := getKeyspaceShards(vcursor.ctx, rtr.serv, rtr.cell, plan.Table.Keyspace.Name, vcursor.query.TabletType)
+	if err != nil {
+		return nil, fmt.Errorf("paramsSelectReference: %v", err)
+	}
+	if len(allShards) == 0 {
+		return nil, fmt.Errorf("paramsSelectReference: keyspace %s has no shards", ks)
+	}
+	shard := allShards[rand.Intn(len(allShards))].Name
+	return newScatterParams(vcursor.query.Sql, ks, vcursor.query.BindVariables, []string{shard}), nil
+}
+
 func (rtr *Router) paramsSelectEqual(vcursor *requestContext, plan *planbuilder.Plan) (*scatterParams, error) {
 	keys, err := rtr.resolveKeys([]interface{}{plan.Values}, vcursor.query.BindVariables)
 	if err != nil {
@@ -231,6 +308,227 @@ func (rtr *Router) paramsSelectScatter(vcursor *requestContext, plan *planbuilde
 	return newScatterParams(plan.Rewritten, ks, vcursor.query.BindVariables, shards), nil
 }
 
+// paramsSelectRange returns the params of a plan that uses a Ranged
+// vindex. The query is sent to the shards that cover the key ranges
+// of the values between the bounds of the plan.
+func (rtr *Router) paramsSelectRange(vcursor *requestContext, plan *planbuilder.Plan) (*scatterParams, error) {
+	keys, err := rtr.resolveKeys(plan.Values.([]interface{}), vcursor.query.BindVariables)
+	if err != nil {
+		return nil, fmt.Errorf("paramsSelectRange: %v", err)
+	}
+	krs, err := plan.ColVindex.Vindex.(planbuilder.Ranged).MapRange(vcursor, keys[0], keys[1])
+	if err != nil {
+		return nil, fmt.Errorf("paramsSelectRange: %v", err)
+	}
+	ks, shards, err := mapKeyRangesToShards(vcursor.ctx, rtr.serv, rtr.cell, plan.Table.Keyspace.Name, vcursor.query.TabletType, krs)
+	if err != nil {
+		return nil, fmt.Errorf("paramsSelectRange: %v", err)
+	}
+	return newScatterParams(plan.Rewritten, ks, vcursor.query.BindVariables, shards), nil
+}
+
+// paramsScatterDML returns the params of an UpdateScatter or DeleteScatter
+// plan. Such plans are executed only if they're explicitly allowed by the
+// ScatterDirective comment, or by the session. The keyspace id of the rows
+// they change is unknown, so they're also refused on the keyspaces that
+// have a sharding column: those are resharded with filtered replication,
+// which routes a statement by the keyspace id of its comment.
+func (rtr *Router) paramsScatterDML(vcursor *requestContext, plan *planbuilder.Plan) (*scatterParams, error) {
+	session := vcursor.query.Session
+	if !plan.AllowScatter && (session == nil || !session.AllowScatterDml) {
+		return nil, fmt.Errorf("paramsScatterDML: multi-shard dml not allowed without the /* %s */ comment: %s", planbuilder.ScatterDirective, vcursor.query.Sql)
+	}
+	ks, srvKeyspace, allShards, err := getKeyspaceShards(vcursor.ctx, rtr.serv, rtr.cell, plan.Table.Keyspace.Name, vcursor.query.TabletType)
+	if err != nil {
+		return nil, fmt.Errorf("paramsScatterDML: %v", err)
+	}
+	if srvKeyspace.ShardingColumnName != "" {
+		return nil, fmt.Errorf("paramsScatterDML: multi-shard dml not allowed on keyspace %s: resharding needs the keyspace id of every statement: %s", ks, vcursor.query.Sql)
+	}
+	var shards []string
+	for _, shard := range allShards {
+		shards = append(shards, shard.Name)
+	}
+	return newScatterParams(plan.Rewritten, ks, vcursor.query.BindVariables, shards), nil
+}
+
+func (rtr *Router) paramsSelectMerge(vcursor *requestContext, plan *planbuilder.Plan) (*scatterParams, error) {
+	if err := setLimitVar(plan, vcursor.query.BindVariables); err != nil {
+		return nil, fmt.Errorf("paramsSelectMerge: %v", err)
+	}
+	switch plan.ID {
+	case planbuilder.SelectEqualMerge:
+		return rtr.paramsSelectEqual(vcursor, plan)
+	case planbuilder.SelectINMerge:
+		return rtr.paramsSelectIN(vcursor, plan)
+	case planbuilder.SelectScatterMerge:
+		return rtr.paramsSelectScatter(vcursor, plan)
+	case planbuilder.SelectRangeMerge:
+		return rtr.paramsSelectRange(vcursor, plan)
+	}
+	panic("unexpected")
+}
+
+func (rtr *Router) execSelectMerge(vcursor *requestContext, plan *planbuilder.Plan) (*mproto.QueryResult, error) {
+	params, err := rtr.paramsSelectMerge(vcursor, plan)
+	if err != nil {
+		return nil, err
+	}
+	results, err := rtr.scatterConn.ExecuteMultiPerShard(
+		vcursor.ctx,
+		params.query,
+		params.ks,
+		params.shardVars,
+		vcursor.query.TabletType,
+		NewSafeSession(vcursor.query.Session),
+		vcursor.query.NotInTransaction,
+	)
+	if err != nil {
+		return nil, err
+	}
+	result, err := mergeResults(results, plan, vcursor.query.BindVariables)
+	if err != nil {
+		return nil, fmt.Errorf("execSelectMerge: %v", err)
+	}
+	return result, nil
+}
+
+func (rtr *Router) streamSelectMerge(vcursor *requestContext, plan *planbuilder.Plan, sendReply func(*mproto.QueryResult) error) error {
+	params, err := rtr.paramsSelectMerge(vcursor, plan)
+	if err != nil {
+		return err
+	}
+	offset, rowcount, err := resolveLimits(plan, vcursor.query.BindVariables)
+	if err != nil {
+		return vterrors.WithPrefix("streamSelectMerge", err)
+	}
+	err = rtr.scatterConn.StreamExecuteMulti(
+		vcursor.ctx,
+		params.query,
+		params.ks,
+		params.shardVars,
+		vcursor.query.TabletType,
+		NewSafeSession(vcursor.query.Session),
+		plan.OrderBy,
+		limitRows(offset, rowcount, sendReply),
+		vcursor.query.NotInTransaction,
+	)
+	switch err {
+	case nil, errMergeLimitReached:
+		return nil
+	}
+	return vterrors.WithPrefix("streamSelectMerge", err)
+}
+
+func (rtr *Router) execSelectAggregate(vcursor *requestContext, plan *planbuilder.Plan) (*mproto.QueryResult, error) {
+	var err error
+	var params *scatterParams
+	switch plan.ID {
+	case planbuilder.SelectEqualAggregate:
+		params, err = rtr.paramsSelectEqual(vcursor, plan)
+	case planbuilder.SelectINAggregate:
+		params, err = rtr.paramsSelectIN(vcursor, plan)
+	case planbuilder.SelectScatterAggregate:
+		params, err = rtr.paramsSelectScatter(vcursor, plan)
+	case planbuilder.SelectRangeAggregate:
+		params, err = rtr.paramsSelectRange(vcursor, plan)
+	}
+	if err != nil {
+		return nil, err
+	}
+	qr, err := rtr.scatterConn.ExecuteMulti(
+		vcursor.ctx,
+		params.query,
+		params.ks,
+		params.shardVars,
+		vcursor.query.TabletType,
+		NewSafeSession(vcursor.query.Session),
+		vcursor.query.NotInTransaction,
+	)
+	if err != nil {
+		return nil, err
+	}
+	result, err := combineAggregates(qr, plan, vcursor.query.BindVariables)
+	if err != nil {
+		return nil, fmt.Errorf("execSelectAggregate: %v", err)
+	}
+	return result, nil
+}
+
+// execSelectJoin performs a nested-loop join. The right plan is
+// executed for every row returned by the left plan, with the join
+// vars set to the values of the row.
+func (rtr *Router) execSelectJoin(vcursor *requestContext, plan *planbuilder.Plan) (*mproto.QueryResult, error) {
+	lresult, err := rtr.execSubPlan(vcursor, plan.Left, vcursor.query.BindVariables)
+	if err != nil {
+		return nil, err
+	}
+	result := &mproto.QueryResult{}
+	var rfields []mproto.Field
+	for _, lrow := range lresult.Rows {
+		bindVars, err := joinBindVars(vcursor.query.BindVariables, plan.JoinVars, lresult.Fields, lrow)
+		if err != nil {
+			return nil, fmt.Errorf("execSelectJoin: %v", err)
+		}
+		rresult, err := rtr.execSubPlan(vcursor, plan.Right, bindVars)
+		if err != nil {
+			return nil, err
+		}
+		if rfields == nil {
+			rfields = rresult.Fields
+		}
+		for _, rrow := range rresult.Rows {
+			result.Rows = append(result.Rows, joinRow(plan.Cols, lrow, rrow))
+		}
+		if len(rresult.Rows) == 0 && plan.ID == planbuilder.SelectLeftJoin {
+			result.Rows = append(result.Rows, joinRow(plan.Cols, lrow, nil))
+		}
+	}
+	if rfields == nil {
+		if rfields, err = rtr.getJoinFields(vcursor, plan); err != nil {
+			return nil, err
+		}
+	}
+	result.Fields = joinFields(plan.Cols, lresult.Fields, rfields)
+	result.RowsAffected = uint64(len(result.Rows))
+	return result, nil
+}
+
+// execSubPlan executes one side of a join with the specified bind vars.
+func (rtr *Router) execSubPlan(vcursor *requestContext, plan *planbuilder.Plan, bindVars map[string]interface{}) (*mproto.QueryResult, error) {
+	query := &proto.Query{
+		Sql:              plan.Original,
+		BindVariables:    bindVars,
+		TabletType:       vcursor.query.TabletType,
+		Session:          vcursor.query.Session,
+		NotInTransaction: vcursor.query.NotInTransaction,
+	}
+	return rtr.execute(newRequestContext(vcursor.ctx, query, rtr), plan)
+}
+
+// getJoinFields fetches the fields of the right side of a join
+// from one of the shards of its keyspace.
+func (rtr *Router) getJoinFields(vcursor *requestContext, plan *planbuilder.Plan) ([]mproto.Field, error) {
+	ks, _, allShards, err := getKeyspaceShards(vcursor.ctx, rtr.serv, rtr.cell, plan.Right.Table.Keyspace.Name, vcursor.query.TabletType)
+	if err != nil {
+		return nil, fmt.Errorf("getJoinFields: %v", err)
+	}
+	result, err := rtr.scatterConn.Execute(
+		vcursor.ctx,
+		plan.Subquery,
+		vcursor.query.BindVariables,
+		ks,
+		[]string{allShards[0].Name},
+		vcursor.query.TabletType,
+		NewSafeSession(vcursor.query.Session),
+		vcursor.query.NotInTransaction,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return result.Fields, nil
+}
+
 func (rtr *Router) execUpdateEqual(vcursor *requestContext, plan *planbuilder.Plan) (*mproto.QueryResult, error) {
 	keys, err := rtr.resolveKeys([]interface{}{plan.Values}, vcursor.query.BindVariables)
 	if err != nil {
@@ -269,7 +567,8 @@ func (rtr *Router) execDeleteEqual(vcursor *requestContext, plan *planbuilder.Pl
 		return &mproto.QueryResult{}, nil
 	}
 	if plan.Subquery != "" {
-		err = rtr.deleteVindexEntries(vcursor, plan, ks, shard, ksid)
+		shardVars := map[string]map[string]interface{}{shard: vcursor.query.BindVariables}
+		err = rtr.deleteVindexEntries(vcursor, plan, ks, shardVars, ksid)
 		if err != nil {
 			return nil, fmt.Errorf("execDeleteEqual: %v", err)
 		}
@@ -287,40 +586,260 @@ func (rtr *Router) execDeleteEqual(vcursor *requestContext, plan *planbuilder.Pl
 		vcursor.query.NotInTransaction)
 }
 
-func (rtr *Router) execInsertSharded(vcursor *requestContext, plan *planbuilder.Plan) (*mproto.QueryResult, error) {
-	input := plan.Values.([]interface{})
-	keys, err := rtr.resolveKeys(input, vcursor.query.BindVariables)
+// ksidValues are the values of the IN clause of a DML
+// plan that map to the same keyspace id.
+type ksidValues struct {
+	ksid  key.KeyspaceId
+	shard string
+	vals  []interface{}
+}
+
+// resolveKeyspaceIds groups the values of the IN clause of an UpdateIN
+// or DeleteIN plan by the keyspace id of their unique vindex. Values
+// that don't map to a keyspace id are skipped.
+func (rtr *Router) resolveKeyspaceIds(vcursor *requestContext, plan *planbuilder.Plan) (ks string, groups []*ksidValues, err error) {
+	keys, err := rtr.resolveKeys(plan.Values.([]interface{}), vcursor.query.BindVariables)
 	if err != nil {
-		return nil, fmt.Errorf("execInsertSharded: %v", err)
+		return "", nil, err
 	}
-	ksid, generated, err := rtr.handlePrimary(vcursor, keys[0], plan.Table.ColVindexes[0], vcursor.query.BindVariables)
+	ks, _, allShards, err := getKeyspaceShards(vcursor.ctx, rtr.serv, rtr.cell, plan.Table.Keyspace.Name, vcursor.query.TabletType)
 	if err != nil {
-		return nil, fmt.Errorf("execInsertSharded: %v", err)
+		return "", nil, err
+	}
+	ksids, err := plan.ColVindex.Vindex.(planbuilder.Unique).Map(vcursor, keys)
+	if err != nil {
+		return "", nil, err
+	}
+	index := make(map[key.KeyspaceId]*ksidValues)
+	for i, ksid := range ksids {
+		if ksid == key.MinKey {
+			continue
+		}
+		group, ok := index[ksid]
+		if !ok {
+			shard, err := getShardForKeyspaceId(allShards, ksid)
+			if err != nil {
+				return "", nil, err
+			}
+			group = &ksidValues{ksid: ksid, shard: shard}
+			index[ksid] = group
+			groups = append(groups, group)
+		}
+		group.vals = appendUnique(group.vals, keys[i])
+	}
+	return ks, groups, nil
+}
+
+// appendUnique appends val to vals if it's not there yet.
+func appendUnique(vals []interface{}, val interface{}) []interface{} {
+	for _, v := range vals {
+		if v == val {
+			return vals
+		}
+	}
+	return append(vals, val)
+}
+
+// ksidQueries returns one query per keyspace id of groups, and the
+// shards they target. Every statement carries a single keyspace id,
+// because filtered replication routes a statement by the keyspace
+// id of its comment.
+func ksidQueries(query string, groups []*ksidValues, bindVars map[string]interface{}) (shards []string, shardQueries map[string][]tproto.BoundQuery) {
+	shardQueries = make(map[string][]tproto.BoundQuery)
+	for _, group := range groups {
+		bv := make(map[string]interface{}, len(bindVars)+2)
+		for k, v := range bindVars {
+			bv[k] = v
+		}
+		bv[planbuilder.ListVarName] = group.vals
+		bv[ksidName] = string(group.ksid)
+		if _, ok := shardQueries[group.shard]; !ok {
+			shards = append(shards, group.shard)
+		}
+		shardQueries[group.shard] = append(shardQueries[group.shard], tproto.BoundQuery{
+			Sql:           query + fmt.Sprintf(dmlPostfix, group.ksid),
+			BindVariables: bv,
+		})
+	}
+	return shards, shardQueries
+}
+
+// execUpdateIN sends one update per keyspace id of the values of the
+// IN clause. The updates of a shard are applied in one transaction.
+func (rtr *Router) execUpdateIN(vcursor *requestContext, plan *planbuilder.Plan) (*mproto.QueryResult, error) {
+	ks, groups, err := rtr.resolveKeyspaceIds(vcursor, plan)
+	if err != nil {
+		return nil, fmt.Errorf("execUpdateIN: %v", err)
+	}
+	if len(groups) == 0 {
+		return &mproto.QueryResult{}, nil
+	}
+	shards, shardQueries := ksidQueries(plan.Rewritten, groups, vcursor.query.BindVariables)
+	return rtr.scatterConn.ExecuteBatchPerShard(
+		vcursor.ctx,
+		ks,
+		shards,
+		shardQueries,
+		vcursor.query.TabletType,
+		NewSafeSession(vcursor.query.Session),
+		vcursor.query.NotInTransaction)
+}
+
+// execDeleteIN deletes the owned vindex entries of the rows, and then
+// sends one delete per keyspace id of the values of the IN clause, like
+// execUpdateIN.
+func (rtr *Router) execDeleteIN(vcursor *requestContext, plan *planbuilder.Plan) (*mproto.QueryResult, error) {
+	ks, groups, err := rtr.resolveKeyspaceIds(vcursor, plan)
+	if err != nil {
+		return nil, fmt.Errorf("execDeleteIN: %v", err)
+	}
+	if len(groups) == 0 {
+		return &mproto.QueryResult{}, nil
+	}
+	if plan.Subquery != "" {
+		routing := make(routingMap)
+		for _, group := range groups {
+			for _, val := range group.vals {
+				routing.Add(group.shard, val)
+			}
+		}
+		err = rtr.deleteVindexEntries(vcursor, plan, ks, routing.ShardVars(vcursor.query.BindVariables), key.MinKey)
+		if err != nil {
+			return nil, fmt.Errorf("execDeleteIN: %v", err)
+		}
 	}
-	ks, shard, err := rtr.getRouting(vcursor.ctx, plan.Table.Keyspace.Name, vcursor.query.TabletType, ksid)
+	shards, shardQueries := ksidQueries(plan.Rewritten, groups, vcursor.query.BindVariables)
+	return rtr.scatterConn.ExecuteBatchPerShard(
+		vcursor.ctx,
+		ks,
+		shards,
+		shardQueries,
+		vcursor.query.TabletType,
+		NewSafeSession(vcursor.query.Session),
+		vcursor.query.NotInTransaction)
+}
+
+// execDeleteScatter executes DeleteScatter plans. The owned vindex
+// entries of the rows are deleted before the rows themselves.
+func (rtr *Router) execDeleteScatter(vcursor *requestContext, plan *planbuilder.Plan) (*mproto.QueryResult, error) {
+	params, err := rtr.paramsScatterDML(vcursor, plan)
+	if err != nil {
+		return nil, err
+	}
+	if len(params.shardVars) == 0 {
+		return &mproto.QueryResult{}, nil
+	}
+	if plan.Subquery != "" {
+		err = rtr.deleteVindexEntries(vcursor, plan, params.ks, params.shardVars, key.MinKey)
+		if err != nil {
+			return nil, fmt.Errorf("execDeleteScatter: %v", err)
+		}
+	}
+	return rtr.scatterConn.ExecuteMulti(
+		vcursor.ctx,
+		params.query,
+		params.ks,
+		params.shardVars,
+		vcursor.query.TabletType,
+		NewSafeSession(vcursor.query.Session),
+		vcursor.query.NotInTransaction,
+	)
+}
+
+// execInsertSharded computes the keyspace id of every row of the insert,
+// creates the owned vindex entries, and sends each target shard an insert
+// of its rows. The shard inserts are executed in parallel.
+func (rtr *Router) execInsertSharded(vcursor *requestContext, plan *planbuilder.Plan) (*mproto.QueryResult, error) {
+	ks, _, allShards, err := getKeyspaceShards(vcursor.ctx, rtr.serv, rtr.cell, plan.Table.Keyspace.Name, vcursor.query.TabletType)
 	if err != nil {
 		return nil, fmt.Errorf("execInsertSharded: %v", err)
 	}
-	for i := 1; i < len(keys); i++ {
-		newgen, err := rtr.handleNonPrimary(vcursor, keys[i], plan.Table.ColVindexes[i], vcursor.query.BindVariables, ksid)
+	rows := plan.Values.([]interface{})
+	ksids := make([]key.KeyspaceId, len(rows))
+	rowVars := make([]map[string]interface{}, len(rows))
+	var generated int64
+	var shards []string
+	shardRows := make(map[string][]int)
+	for rowNum, row := range rows {
+		keys, err := rtr.resolveKeys(row.([]interface{}), vcursor.query.BindVariables)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("execInsertSharded: %v", err)
 		}
-		if newgen != 0 {
-			if generated != 0 {
-				return nil, fmt.Errorf("insert generated more than one value")
+		rowVars[rowNum] = make(map[string]interface{}, len(keys))
+		ksid, rowGenerated, err := rtr.handlePrimary(vcursor, keys[0], plan.Table.ColVindexes[0], rowVars[rowNum], rowNum)
+		if err != nil {
+			return nil, fmt.Errorf("execInsertSharded: %v", err)
+		}
+		shard, err := getShardForKeyspaceId(allShards, ksid)
+		if err != nil {
+			return nil, fmt.Errorf("execInsertSharded: %v", err)
+		}
+		for i := 1; i < len(keys); i++ {
+			newgen, err := rtr.handleNonPrimary(vcursor, keys[i], plan.Table.ColVindexes[i], rowVars[rowNum], rowNum, ksid)
+			if err != nil {
+				return nil, err
 			}
-			generated = newgen
+			if newgen != 0 {
+				if rowGenerated != 0 {
+					return nil, fmt.Errorf("insert generated more than one value")
+				}
+				rowGenerated = newgen
+			}
+		}
+		// Like MySQL, the insert id of a multi-row
+		// insert is the first generated value.
+		if generated == 0 {
+			generated = rowGenerated
 		}
+		ksids[rowNum] = ksid
+		if _, ok := shardRows[shard]; !ok {
+			shards = append(shards, shard)
+		}
+		shardRows[shard] = append(shardRows[shard], rowNum)
 	}
-	vcursor.query.BindVariables[ksidName] = string(ksid)
-	rewritten := plan.Rewritten + fmt.Sprintf(dmlPostfix, ksid)
-	result, err := rtr.scatterConn.Execute(
+
+	// Every statement carries a single keyspace id, because filtered
+	// replication routes a statement by the keyspace id of its comment.
+	// The rows of a shard are therefore split by keyspace id.
+	shardQueries := make(map[string][]tproto.BoundQuery, len(shards))
+	for _, shard := range shards {
+		var ksidRows [][]int
+		ksidIndex := make(map[string]int)
+		for _, rowNum := range shardRows[shard] {
+			ksid := string(ksids[rowNum])
+			i, ok := ksidIndex[ksid]
+			if !ok {
+				i = len(ksidRows)
+				ksidIndex[ksid] = i
+				ksidRows = append(ksidRows, nil)
+			}
+			ksidRows[i] = append(ksidRows[i], rowNum)
+		}
+		for _, rowNums := range ksidRows {
+			ksid := ksids[rowNums[0]]
+			bv := make(map[string]interface{}, len(vcursor.query.BindVariables)+1)
+			for k, v := range vcursor.query.BindVariables {
+				bv[k] = v
+			}
+			mids := make([]string, 0, len(rowNums))
+			for _, rowNum := range rowNums {
+				mids = append(mids, plan.Mid[rowNum])
+				for k, v := range rowVars[rowNum] {
+					bv[k] = v
+				}
+			}
+			bv[ksidName] = string(ksid)
+			shardQueries[shard] = append(shardQueries[shard], tproto.BoundQuery{
+				Sql:           plan.Prefix + strings.Join(mids, ", ") + plan.Suffix + fmt.Sprintf(dmlPostfix, ksid),
+				BindVariables: bv,
+			})
+		}
+	}
+	result, err := rtr.scatterConn.ExecuteBatchPerShard(
 		vcursor.ctx,
-		rewritten,
-		vcursor.query.BindVariables,
 		ks,
-		[]string{shard},
+		shards,
+		shardQueries,
 		vcursor.query.TabletType,
 		NewSafeSession(vcursor.query.Session),
 		vcursor.query.NotInTransaction)
@@ -348,6 +867,13 @@ func (rtr *Router) resolveKeys(vals []interface{}, bindVars map[string]interface
 			keys = append(keys, v)
 		case []byte:
 			keys = append(keys, string(val))
+		case []interface{}:
+			// The tuple of a MultiColumn vindex.
+			tuple, err := rtr.resolveKeys(val, bindVars)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, tuple)
 		default:
 			keys = append(keys, val)
 		}
@@ -418,13 +944,15 @@ func (rtr *Router) resolveSingleShard(vcursor *requestContext, vindexKey interfa
 	return newKeyspace, shard, ksid, nil
 }
 
-func (rtr *Router) deleteVindexEntries(vcursor *requestContext, plan *planbuilder.Plan, ks, shard string, ksid key.KeyspaceId) error {
-	result, err := rtr.scatterConn.Execute(
+// deleteVindexEntries deletes the owned vindex entries of the rows returned
+// by plan.Subquery on the shards of shardVars. If ksid is key.MinKey, the
+// keyspace id of every row is computed from its primary vindex value.
+func (rtr *Router) deleteVindexEntries(vcursor *requestContext, plan *planbuilder.Plan, ks string, shardVars map[string]map[string]interface{}, ksid key.KeyspaceId) error {
+	result, err := rtr.scatterConn.ExecuteMulti(
 		vcursor.ctx,
 		plan.Subquery,
-		vcursor.query.BindVariables,
 		ks,
-		[]string{shard},
+		shardVars,
 		vcursor.query.TabletType,
 		NewSafeSession(vcursor.query.Session),
 		vcursor.query.NotInTransaction)
@@ -434,41 +962,108 @@ func (rtr *Router) deleteVindexEntries(vcursor *requestContext, plan *planbuilde
 	if len(result.Rows) == 0 {
 		return nil
 	}
-	for i, colVindex := range plan.Table.Owned {
-		keys := make(map[interface{}]bool)
-		for _, row := range result.Rows {
-			k, err := mproto.Convert(result.Fields[i], row[i])
+	ksids, err := rtr.rowKeyspaceIds(vcursor, plan, result, ksid)
+	if err != nil {
+		return err
+	}
+	pos := 0
+	for _, colVindex := range plan.Table.Owned {
+		// The ids are grouped by keyspace id because
+		// a vindex deletes the entries of one keyspace id.
+		var order []key.KeyspaceId
+		ids := make(map[key.KeyspaceId][]interface{})
+		seen := make(map[key.KeyspaceId]map[interface{}]bool)
+		for r, row := range result.Rows {
+			k, err := subqueryValue(result, row, pos, colVindex)
 			if err != nil {
 				return err
 			}
-			switch k := k.(type) {
+			if seen[ksids[r]] == nil {
+				seen[ksids[r]] = make(map[interface{}]bool)
+				order = append(order, ksids[r])
+			}
+			switch tk := k.(type) {
 			case []byte:
-				keys[string(k)] = true
-			default:
-				keys[k] = true
+				k = string(tk)
+			case []interface{}:
+				// Tuples can't be map keys, so they're not deduplicated.
+				ids[ksids[r]] = append(ids[ksids[r]], tk)
+				continue
 			}
-		}
-		var ids []interface{}
-		for k := range keys {
-			ids = append(ids, k)
-		}
-		switch vindex := colVindex.Vindex.(type) {
-		case planbuilder.Functional:
-			if err = vindex.Delete(vcursor, ids, ksid); err != nil {
-				return err
+			if !seen[ksids[r]][k] {
+				seen[ksids[r]][k] = true
+				ids[ksids[r]] = append(ids[ksids[r]], k)
 			}
-		case planbuilder.Lookup:
-			if err = vindex.Delete(vcursor, ids, ksid); err != nil {
-				return err
+		}
+		pos += len(colVindex.Columns())
+		for _, rowKsid := range order {
+			switch vindex := colVindex.Vindex.(type) {
+			case planbuilder.Functional:
+				if err = vindex.Delete(vcursor, ids[rowKsid], rowKsid); err != nil {
+					return err
+				}
+			case planbuilder.Lookup:
+				if err = vindex.Delete(vcursor, ids[rowKsid], rowKsid); err != nil {
+					return err
+				}
+			default:
+				panic("unexpceted")
 			}
-		default:
-			panic("unexpceted")
 		}
 	}
 	return nil
 }
 
-func (rtr *Router) handlePrimary(vcursor *requestContext, vindexKey interface{}, colVindex *planbuilder.ColVindex, bv map[string]interface{}) (ksid key.KeyspaceId, generated int64, err error) {
+// rowKeyspaceIds returns the keyspace id of every row of the result of
+// plan.Subquery. If ksid is not key.MinKey, it's the keyspace id of all the
+// rows. Otherwise, the primary vindex values of the rows are mapped.
+func (rtr *Router) rowKeyspaceIds(vcursor *requestContext, plan *planbuilder.Plan, result *mproto.QueryResult, ksid key.KeyspaceId) ([]key.KeyspaceId, error) {
+	if ksid != key.MinKey {
+		ksids := make([]key.KeyspaceId, len(result.Rows))
+		for i := range ksids {
+			ksids[i] = ksid
+		}
+		return ksids, nil
+	}
+	primary := plan.Table.ColVindexes[0]
+	// The primary vindex columns follow the columns of the owned
+	// vindexes that precede it, or all of them if it's not owned.
+	pos := 0
+	for _, colVindex := range plan.Table.Owned {
+		if colVindex == primary {
+			break
+		}
+		pos += len(colVindex.Columns())
+	}
+	vals := make([]interface{}, len(result.Rows))
+	for i, row := range result.Rows {
+		val, err := subqueryValue(result, row, pos, primary)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = val
+	}
+	ksids, err := primary.Vindex.(planbuilder.Unique).Map(vcursor, vals)
+	if err != nil {
+		return nil, err
+	}
+	for i, rowKsid := range ksids {
+		if rowKsid == key.MinKey {
+			return nil, fmt.Errorf("could not map %v to a keyspace id", vals[i])
+		}
+	}
+	return ksids, nil
+}
+
+func (rtr *Router) handlePrimary(vcursor *requestContext, vindexKey interface{}, colVindex *planbuilder.ColVindex, bv map[string]interface{}, rowNum int) (ksid key.KeyspaceId, generated int64, err error) {
+	if tuple, ok := vindexKey.([]interface{}); ok {
+		// The values of MultiColumn vindexes can't be generated.
+		for i, val := range tuple {
+			if val == nil {
+				return "", 0, fmt.Errorf("value must be supplied for column %s", colVindex.Cols[i])
+			}
+		}
+	}
 	if colVindex.Owned {
 		if vindexKey == nil {
 			generator, ok := colVindex.Vindex.(planbuilder.FunctionalGenerator)
@@ -499,11 +1094,11 @@ func (rtr *Router) handlePrimary(vcursor *requestContext, vindexKey interface{},
 	if ksid == key.MinKey {
 		return "", 0, fmt.Errorf("could not map %v to a keyspace id", vindexKey)
 	}
-	bv["_"+colVindex.Col] = vindexKey
+	setInsertVars(bv, colVindex, vindexKey, rowNum)
 	return ksid, generated, nil
 }
 
-func (rtr *Router) handleNonPrimary(vcursor *requestContext, vindexKey interface{}, colVindex *planbuilder.ColVindex, bv map[string]interface{}, ksid key.KeyspaceId) (generated int64, err error) {
+func (rtr *Router) handleNonPrimary(vcursor *requestContext, vindexKey interface{}, colVindex *planbuilder.ColVindex, bv map[string]interface{}, rowNum int, ksid key.KeyspaceId) (generated int64, err error) {
 	if colVindex.Owned {
 		if vindexKey == nil {
 			generator, ok := colVindex.Vindex.(planbuilder.LookupGenerator)
@@ -544,18 +1139,36 @@ func (rtr *Router) handleNonPrimary(vcursor *requestContext, vindexKey interface
 			}
 		}
 	}
-	bv["_"+colVindex.Col] = vindexKey
+	setInsertVars(bv, colVindex, vindexKey, rowNum)
 	return generated, nil
 }
 
-func (rtr *Router) getRouting(ctx context.Context, keyspace string, tabletType topo.TabletType, ksid key.KeyspaceId) (newKeyspace, shard string, err error) {
-	newKeyspace, _, allShards, err := getKeyspaceShards(ctx, rtr.serv, rtr.cell, keyspace, tabletType)
-	if err != nil {
-		return "", "", err
+// setInsertVars sets the bind vars that hold the
+// value of colVindex for the row at rowNum of an insert.
+func setInsertVars(bv map[string]interface{}, colVindex *planbuilder.ColVindex, vindexKey interface{}, rowNum int) {
+	if len(colVindex.Cols) == 0 {
+		bv[planbuilder.InsertVarName(colVindex.Col, rowNum)] = vindexKey
+		return
 	}
-	shard, err = getShardForKeyspaceId(allShards, ksid)
-	if err != nil {
-		return "", "", err
+	for i, val := range vindexKey.([]interface{}) {
+		bv[planbuilder.InsertVarName(colVindex.Cols[i], rowNum)] = val
+	}
+}
+
+// subqueryValue returns the value of colVindex in a row of the
+// result of plan.Subquery, where its columns start at pos. The
+// value of a MultiColumn vindex is the tuple of its columns.
+func subqueryValue(result *mproto.QueryResult, row []sqltypes.Value, pos int, colVindex *planbuilder.ColVindex) (interface{}, error) {
+	if len(colVindex.Cols) == 0 {
+		return mproto.Convert(result.Fields[pos], row[pos])
+	}
+	tuple := make([]interface{}, len(colVindex.Cols))
+	for i := range tuple {
+		val, err := mproto.Convert(result.Fields[pos+i], row[pos+i])
+		if err != nil {
+			return nil, err
+		}
+		tuple[i] = val
 	}
-	return newKeyspace, shard, nil
+	return tuple, nil
 }
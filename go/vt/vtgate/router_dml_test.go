@@ -8,11 +8,15 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	mproto "github.com/youtube/vitess/go/mysql/proto"
 	"github.com/youtube/vitess/go/sqltypes"
 	tproto "github.com/youtube/vitess/go/vt/tabletserver/proto"
+	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/vtgate/proto"
 	_ "github.com/youtube/vitess/go/vt/vtgate/vindexes"
+	"golang.org/x/net/context"
 )
 
 func TestUpdateEqual(t *testing.T) {
@@ -288,6 +292,257 @@ func TestDeleteVindexFail(t *testing.T) {
 	}
 }
 
+func TestUpdateIN(t *testing.T) {
+	router, sbc1, sbc2, _ := createRouterEnv()
+
+	_, err := routerExec(router, "update user set a=2 where id in (1, 3)", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql: "update user set a = 2 where id in ::_vals /* _routing keyspace_id:166b40b44aba4bd6 */",
+		BindVariables: map[string]interface{}{
+			"_vals":       []interface{}{int64(1)},
+			"keyspace_id": "\x16k@\xb4J\xbaK\xd6",
+		},
+	}}
+	if !reflect.DeepEqual(sbc1.Queries, wantQueries) {
+		t.Errorf("sbc1.Queries: %+v, want %+v\n", sbc1.Queries, wantQueries)
+	}
+	wantQueries = []tproto.BoundQuery{{
+		Sql: "update user set a = 2 where id in ::_vals /* _routing keyspace_id:4eb190c9a2fa169c */",
+		BindVariables: map[string]interface{}{
+			"_vals":       []interface{}{int64(3)},
+			"keyspace_id": "N\xb1\x90ɢ\xfa\x16\x9c",
+		},
+	}}
+	if !reflect.DeepEqual(sbc2.Queries, wantQueries) {
+		t.Errorf("sbc2.Queries: %+v, want %+v\n", sbc2.Queries, wantQueries)
+	}
+}
+
+func TestDeleteIN(t *testing.T) {
+	router, sbc, _, sbclookup := createRouterEnv()
+
+	sbc.setResults([]*mproto.QueryResult{&mproto.QueryResult{
+		Fields: []mproto.Field{
+			{"id", 3, mproto.VT_ZEROVALUE_FLAG},
+			{"name", 253, mproto.VT_ZEROVALUE_FLAG},
+		},
+		RowsAffected: 2,
+		InsertId:     0,
+		Rows: [][]sqltypes.Value{{
+			{sqltypes.Numeric("1")},
+			{sqltypes.String("myname")},
+		}, {
+			{sqltypes.Numeric("2")},
+			{sqltypes.String("myname2")},
+		}},
+	}})
+	_, err := routerExec(router, "delete from user where id in (1, 2)", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql: "select id, name from user where id in ::_vals for update",
+		BindVariables: map[string]interface{}{
+			"_vals": []interface{}{int64(1), int64(2)},
+		},
+	}, {
+		Sql: "delete from user where id in ::_vals /* _routing keyspace_id:166b40b44aba4bd6 */",
+		BindVariables: map[string]interface{}{
+			"_vals":       []interface{}{int64(1)},
+			"keyspace_id": "\x16k@\xb4J\xbaK\xd6",
+		},
+	}, {
+		Sql: "delete from user where id in ::_vals /* _routing keyspace_id:06e7ea22ce92708f */",
+		BindVariables: map[string]interface{}{
+			"_vals":       []interface{}{int64(2)},
+			"keyspace_id": "\x06\xe7\xea\"Βp\x8f",
+		},
+	}}
+	if !reflect.DeepEqual(sbc.Queries, wantQueries) {
+		t.Errorf("sbc.Queries: %+v, want %+v\n", sbc.Queries, wantQueries)
+	}
+
+	wantQueries = []tproto.BoundQuery{{
+		Sql: "delete from user_idx where id in ::id",
+		BindVariables: map[string]interface{}{
+			"id": []interface{}{int64(1)},
+		},
+	}, {
+		Sql: "delete from user_idx where id in ::id",
+		BindVariables: map[string]interface{}{
+			"id": []interface{}{int64(2)},
+		},
+	}, {
+		Sql: "delete from name_user_map where name in ::name and user_id = :user_id",
+		BindVariables: map[string]interface{}{
+			"user_id": int64(1),
+			"name":    []interface{}{"myname"},
+		},
+	}, {
+		Sql: "delete from name_user_map where name in ::name and user_id = :user_id",
+		BindVariables: map[string]interface{}{
+			"user_id": int64(2),
+			"name":    []interface{}{"myname2"},
+		},
+	}}
+	if !reflect.DeepEqual(sbclookup.Queries, wantQueries) {
+		t.Errorf("sbclookup.Queries: %+v, want %+v\n", sbclookup.Queries, wantQueries)
+	}
+}
+
+func createScatterRouterEnv() (router *Router, conns []*sandboxConn, sbclookup *sandboxConn) {
+	s := createSandbox("TestRouter")
+	// Scatter DML is allowed only on custom sharded keyspaces.
+	s.ShardingColumnName = ""
+	shards := []string{"-20", "20-40", "40-60", "60-80", "80-a0", "a0-c0", "c0-e0", "e0-"}
+	for _, shard := range shards {
+		sbc := &sandboxConn{}
+		conns = append(conns, sbc)
+		s.MapTestConn(shard, sbc)
+	}
+	l := createSandbox(KsTestUnsharded)
+	sbclookup = &sandboxConn{}
+	l.MapTestConn("0", sbclookup)
+
+	serv := new(sandboxTopo)
+	scatterConn := NewScatterConn(serv, "", "aa", 1*time.Second, 10, 2*time.Millisecond, 1*time.Millisecond, 24*time.Hour)
+	router = NewRouter(serv, "aa", routerSchema, "", scatterConn)
+	return router, conns, sbclookup
+}
+
+func TestUpdateScatter(t *testing.T) {
+	router, conns, _ := createScatterRouterEnv()
+
+	_, err := routerExec(router, "update user set a=2 where name = 'foo'", nil)
+	want := "paramsScatterDML: multi-shard dml not allowed without the /* allow_scatter */ comment: update user set a=2 where name = 'foo'"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+	for _, conn := range conns {
+		if conn.Queries != nil {
+			t.Errorf("conn.Queries = %#v, want nil", conn.Queries)
+		}
+	}
+
+	_, err = routerExec(router, "update /* allow_scatter */ user set a=2 where name = 'foo'", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql:           "update /* allow_scatter */ user set a = 2 where name = 'foo'",
+		BindVariables: map[string]interface{}{},
+	}}
+	for _, conn := range conns {
+		if !reflect.DeepEqual(conn.Queries, wantQueries) {
+			t.Errorf("conn.Queries = %#v, want %#v", conn.Queries, wantQueries)
+		}
+		conn.Queries = nil
+	}
+
+	_, err = router.Execute(context.Background(), &proto.Query{
+		Sql:        "update user set a=2 where name = 'foo'",
+		TabletType: topo.TYPE_MASTER,
+		Session:    &proto.Session{AllowScatterDml: true},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	wantQueries = []tproto.BoundQuery{{
+		Sql:           "update user set a = 2 where name = 'foo'",
+		BindVariables: map[string]interface{}{},
+	}}
+	for _, conn := range conns {
+		if !reflect.DeepEqual(conn.Queries, wantQueries) {
+			t.Errorf("conn.Queries = %#v, want %#v", conn.Queries, wantQueries)
+		}
+	}
+}
+
+func TestScatterDMLFail(t *testing.T) {
+	router, conns, _ := createScatterRouterEnv()
+	getSandbox("TestRouter").ShardingColumnName = "user_id"
+	defer func() { getSandbox("TestRouter").ShardingColumnName = "" }()
+
+	_, err := routerExec(router, "update /* allow_scatter */ user set a=2 where name = 'foo'", nil)
+	want := "paramsScatterDML: multi-shard dml not allowed on keyspace TestRouter: resharding needs the keyspace id of every statement: update /* allow_scatter */ user set a=2 where name = 'foo'"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+
+	_, err = routerExec(router, "delete /* allow_scatter */ from music where a = 2", nil)
+	want = "paramsScatterDML: multi-shard dml not allowed on keyspace TestRouter: resharding needs the keyspace id of every statement: delete /* allow_scatter */ from music where a = 2"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+	for _, conn := range conns {
+		if conn.Queries != nil {
+			t.Errorf("conn.Queries = %#v, want nil", conn.Queries)
+		}
+	}
+}
+
+func TestDeleteScatter(t *testing.T) {
+	router, conns, sbclookup := createScatterRouterEnv()
+
+	for _, conn := range conns[1:] {
+		conn.setResults([]*mproto.QueryResult{&mproto.QueryResult{}})
+	}
+	conns[0].setResults([]*mproto.QueryResult{&mproto.QueryResult{
+		Fields: []mproto.Field{
+			{"id", 3, mproto.VT_ZEROVALUE_FLAG},
+			{"user_id", 3, mproto.VT_ZEROVALUE_FLAG},
+		},
+		RowsAffected: 1,
+		InsertId:     0,
+		Rows: [][]sqltypes.Value{{
+			{sqltypes.Numeric("3")},
+			{sqltypes.Numeric("1")},
+		}},
+	}})
+	_, err := routerExec(router, "delete /* allow_scatter */ from music where a = 2", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql:           "select id, user_id from music where a = 2 for update",
+		BindVariables: map[string]interface{}{},
+	}, {
+		Sql:           "delete /* allow_scatter */ from music where a = 2",
+		BindVariables: map[string]interface{}{},
+	}}
+	for _, conn := range conns {
+		if !reflect.DeepEqual(conn.Queries, wantQueries) {
+			t.Errorf("conn.Queries = %#v, want %#v", conn.Queries, wantQueries)
+		}
+	}
+	wantQueries = []tproto.BoundQuery{{
+		Sql: "delete from music_user_map where music_id in ::music_id and user_id = :user_id",
+		BindVariables: map[string]interface{}{
+			"music_id": []interface{}{int64(3)},
+			"user_id":  int64(1),
+		},
+	}}
+	if !reflect.DeepEqual(sbclookup.Queries, wantQueries) {
+		t.Errorf("sbclookup.Queries: %+v, want %+v\n", sbclookup.Queries, wantQueries)
+	}
+
+	_, err = routerExec(router, "delete from music where a = 2", nil)
+	want := "paramsScatterDML: multi-shard dml not allowed without the /* allow_scatter */ comment: delete from music where a = 2"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+
+	conns[0].mustFailServer = 1
+	_, err = routerExec(router, "delete /* allow_scatter */ from music where a = 2", nil)
+	want = "execDeleteScatter: shard, host: TestRouter.-20.master"
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("routerExec: %v, want prefix %v", err, want)
+	}
+}
+
 func TestInsertSharded(t *testing.T) {
 	router, sbc1, sbc2, sbclookup := createRouterEnv()
 
@@ -296,11 +551,11 @@ func TestInsertSharded(t *testing.T) {
 		t.Error(err)
 	}
 	wantQueries := []tproto.BoundQuery{{
-		Sql: "insert into user(id, v, name) values (:_id, 2, :_name) /* _routing keyspace_id:166b40b44aba4bd6 */",
+		Sql: "insert into user(id, v, name) values (:_id_0, 2, :_name_0) /* _routing keyspace_id:166b40b44aba4bd6 */",
 		BindVariables: map[string]interface{}{
 			"keyspace_id": "\x16k@\xb4J\xbaK\xd6",
-			"_id":         int64(1),
-			"_name":       "myname",
+			"_id_0":       int64(1),
+			"_name_0":     "myname",
 		},
 	}}
 	if !reflect.DeepEqual(sbc1.Queries, wantQueries) {
@@ -332,11 +587,11 @@ func TestInsertSharded(t *testing.T) {
 		t.Error(err)
 	}
 	wantQueries = []tproto.BoundQuery{{
-		Sql: "insert into user(id, v, name) values (:_id, 2, :_name) /* _routing keyspace_id:4eb190c9a2fa169c */",
+		Sql: "insert into user(id, v, name) values (:_id_0, 2, :_name_0) /* _routing keyspace_id:4eb190c9a2fa169c */",
 		BindVariables: map[string]interface{}{
 			"keyspace_id": "N\xb1\x90ɢ\xfa\x16\x9c",
-			"_id":         int64(3),
-			"_name":       "myname2",
+			"_id_0":       int64(3),
+			"_name_0":     "myname2",
 		},
 	}}
 	if !reflect.DeepEqual(sbc2.Queries, wantQueries) {
@@ -362,6 +617,126 @@ func TestInsertSharded(t *testing.T) {
 	}
 }
 
+func TestInsertShardedMulti(t *testing.T) {
+	router, sbc1, sbc2, sbclookup := createRouterEnv()
+
+	_, err := routerExec(router, "insert into user(id, v, name) values (1, 2, 'myname'), (3, 4, 'myname2'), (2, 6, 'myname3')", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	// Rows of different keyspace ids are inserted by different
+	// statements, in a single transaction.
+	wantQueries := []tproto.BoundQuery{{
+		Sql: "insert into user(id, v, name) values (:_id_0, 2, :_name_0) /* _routing keyspace_id:166b40b44aba4bd6 */",
+		BindVariables: map[string]interface{}{
+			"keyspace_id": "\x16k@\xb4J\xbaK\xd6",
+			"_id_0":       int64(1),
+			"_name_0":     "myname",
+		},
+	}, {
+		Sql: "insert into user(id, v, name) values (:_id_2, 6, :_name_2) /* _routing keyspace_id:06e7ea22ce92708f */",
+		BindVariables: map[string]interface{}{
+			"keyspace_id": "\x06\xe7\xea\"Βp\x8f",
+			"_id_2":       int64(2),
+			"_name_2":     "myname3",
+		},
+	}}
+	if !reflect.DeepEqual(sbc1.Queries, wantQueries) {
+		t.Errorf("sbc1.Queries: %+v, want %+v\n", sbc1.Queries, wantQueries)
+	}
+	if count := sbc1.AsTransactionCount.Get(); count != 1 {
+		t.Errorf("sbc1.AsTransactionCount: %d, want 1", count)
+	}
+	wantQueries = []tproto.BoundQuery{{
+		Sql: "insert into user(id, v, name) values (:_id_1, 4, :_name_1) /* _routing keyspace_id:4eb190c9a2fa169c */",
+		BindVariables: map[string]interface{}{
+			"keyspace_id": "N\xb1\x90ɢ\xfa\x16\x9c",
+			"_id_1":       int64(3),
+			"_name_1":     "myname2",
+		},
+	}}
+	if !reflect.DeepEqual(sbc2.Queries, wantQueries) {
+		t.Errorf("sbc2.Queries: %+v, want %+v\n", sbc2.Queries, wantQueries)
+	}
+	wantQueries = []tproto.BoundQuery{{
+		Sql: "insert into user_idx(id) values(:id)",
+		BindVariables: map[string]interface{}{
+			"id": int64(1),
+		},
+	}, {
+		Sql: "insert into name_user_map(name, user_id) values(:name, :user_id)",
+		BindVariables: map[string]interface{}{
+			"name":    "myname",
+			"user_id": int64(1),
+		},
+	}, {
+		Sql: "insert into user_idx(id) values(:id)",
+		BindVariables: map[string]interface{}{
+			"id": int64(3),
+		},
+	}, {
+		Sql: "insert into name_user_map(name, user_id) values(:name, :user_id)",
+		BindVariables: map[string]interface{}{
+			"name":    "myname2",
+			"user_id": int64(3),
+		},
+	}, {
+		Sql: "insert into user_idx(id) values(:id)",
+		BindVariables: map[string]interface{}{
+			"id": int64(2),
+		},
+	}, {
+		Sql: "insert into name_user_map(name, user_id) values(:name, :user_id)",
+		BindVariables: map[string]interface{}{
+			"name":    "myname3",
+			"user_id": int64(2),
+		},
+	}}
+	if !reflect.DeepEqual(sbclookup.Queries, wantQueries) {
+		t.Errorf("sbclookup.Queries: %+v, want %+v\n", sbclookup.Queries, wantQueries)
+	}
+}
+
+func TestInsertShardedMultiGenerator(t *testing.T) {
+	router, sbc, _, sbclookup := createRouterEnv()
+
+	sbclookup.setResults([]*mproto.QueryResult{
+		&mproto.QueryResult{RowsAffected: 1, InsertId: 1},
+		&mproto.QueryResult{},
+		&mproto.QueryResult{RowsAffected: 1, InsertId: 2},
+	})
+	sbc.setResults([]*mproto.QueryResult{
+		&mproto.QueryResult{RowsAffected: 1},
+		&mproto.QueryResult{RowsAffected: 1},
+	})
+	result, err := routerExec(router, "insert into user(v, name) values (2, 'myname'), (4, 'myname2')", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql: "insert into user(v, name, id) values (2, :_name_0, :_id_0) /* _routing keyspace_id:166b40b44aba4bd6 */",
+		BindVariables: map[string]interface{}{
+			"keyspace_id": "\x16k@\xb4J\xbaK\xd6",
+			"_id_0":       int64(1),
+			"_name_0":     "myname",
+		},
+	}, {
+		Sql: "insert into user(v, name, id) values (4, :_name_1, :_id_1) /* _routing keyspace_id:06e7ea22ce92708f */",
+		BindVariables: map[string]interface{}{
+			"keyspace_id": "\x06\xe7\xea\"Βp\x8f",
+			"_id_1":       int64(2),
+			"_name_1":     "myname2",
+		},
+	}}
+	if !reflect.DeepEqual(sbc.Queries, wantQueries) {
+		t.Errorf("sbc.Queries: %+v, want %+v\n", sbc.Queries, wantQueries)
+	}
+	wantResult := &mproto.QueryResult{RowsAffected: 2, InsertId: 1}
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("result: %+v, want %+v", result, wantResult)
+	}
+}
+
 func TestInsertGenerator(t *testing.T) {
 	router, sbc, _, sbclookup := createRouterEnv()
 
@@ -371,11 +746,11 @@ func TestInsertGenerator(t *testing.T) {
 		t.Error(err)
 	}
 	wantQueries := []tproto.BoundQuery{{
-		Sql: "insert into user(v, name, id) values (2, :_name, :_id) /* _routing keyspace_id:166b40b44aba4bd6 */",
+		Sql: "insert into user(v, name, id) values (2, :_name_0, :_id_0) /* _routing keyspace_id:166b40b44aba4bd6 */",
 		BindVariables: map[string]interface{}{
 			"keyspace_id": "\x16k@\xb4J\xbaK\xd6",
-			"_id":         int64(1),
-			"_name":       "myname",
+			"_id_0":       int64(1),
+			"_name_0":     "myname",
 		},
 	}}
 	if !reflect.DeepEqual(sbc.Queries, wantQueries) {
@@ -411,11 +786,11 @@ func TestInsertLookupOwned(t *testing.T) {
 		t.Error(err)
 	}
 	wantQueries := []tproto.BoundQuery{{
-		Sql: "insert into music(user_id, id) values (:_user_id, :_id) /* _routing keyspace_id:06e7ea22ce92708f */",
+		Sql: "insert into music(user_id, id) values (:_user_id_0, :_id_0) /* _routing keyspace_id:06e7ea22ce92708f */",
 		BindVariables: map[string]interface{}{
 			"keyspace_id": "\x06\xe7\xea\"Βp\x8f",
-			"_user_id":    int64(2),
-			"_id":         int64(3),
+			"_user_id_0":  int64(2),
+			"_id_0":       int64(3),
 		},
 	}}
 	if !reflect.DeepEqual(sbc.Queries, wantQueries) {
@@ -442,11 +817,11 @@ func TestInsertLookupOwnedGenerator(t *testing.T) {
 		t.Error(err)
 	}
 	wantQueries := []tproto.BoundQuery{{
-		Sql: "insert into music(user_id, id) values (:_user_id, :_id) /* _routing keyspace_id:06e7ea22ce92708f */",
+		Sql: "insert into music(user_id, id) values (:_user_id_0, :_id_0) /* _routing keyspace_id:06e7ea22ce92708f */",
 		BindVariables: map[string]interface{}{
 			"keyspace_id": "\x06\xe7\xea\"Βp\x8f",
-			"_user_id":    int64(2),
-			"_id":         int64(1),
+			"_user_id_0":  int64(2),
+			"_id_0":       int64(1),
 		},
 	}}
 	if !reflect.DeepEqual(sbc.Queries, wantQueries) {
@@ -477,11 +852,11 @@ func TestInsertLookupUnowned(t *testing.T) {
 		t.Error(err)
 	}
 	wantQueries := []tproto.BoundQuery{{
-		Sql: "insert into music_extra(user_id, music_id) values (:_user_id, :_music_id) /* _routing keyspace_id:06e7ea22ce92708f */",
+		Sql: "insert into music_extra(user_id, music_id) values (:_user_id_0, :_music_id_0) /* _routing keyspace_id:06e7ea22ce92708f */",
 		BindVariables: map[string]interface{}{
 			"keyspace_id": "\x06\xe7\xea\"Βp\x8f",
-			"_user_id":    int64(2),
-			"_music_id":   int64(3),
+			"_user_id_0":  int64(2),
+			"_music_id_0": int64(3),
 		},
 	}}
 	if !reflect.DeepEqual(sbc.Queries, wantQueries) {
@@ -507,11 +882,11 @@ func TestInsertLookupUnownedUnsupplied(t *testing.T) {
 		t.Error(err)
 	}
 	wantQueries := []tproto.BoundQuery{{
-		Sql: "insert into music_extra_reversed(music_id, user_id) values (:_music_id, :_user_id) /* _routing keyspace_id:166b40b44aba4bd6 */",
+		Sql: "insert into music_extra_reversed(music_id, user_id) values (:_music_id_0, :_user_id_0) /* _routing keyspace_id:166b40b44aba4bd6 */",
 		BindVariables: map[string]interface{}{
 			"keyspace_id": "\x16k@\xb4J\xbaK\xd6",
-			"_user_id":    int64(1),
-			"_music_id":   int64(3),
+			"_user_id_0":  int64(1),
+			"_music_id_0": int64(3),
 		},
 	}}
 	if !reflect.DeepEqual(sbc.Queries, wantQueries) {
@@ -528,6 +903,83 @@ func TestInsertLookupUnownedUnsupplied(t *testing.T) {
 	}
 }
 
+func TestInsertMultiColumn(t *testing.T) {
+	router, sbc1, sbc2, sbclookup := createRouterEnv()
+
+	_, err := routerExec(router, "insert into customer(region, id, name) values (1, 1, 'a'), (69, 1, 'b')", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql: "insert into customer(region, id, name) values (:_region_0, :_id_0, 'a') /* _routing keyspace_id:01166b40b44aba4bd6 */",
+		BindVariables: map[string]interface{}{
+			"keyspace_id": "\x01\x16k@\xb4J\xbaK\xd6",
+			"_region_0":   int64(1),
+			"_id_0":       int64(1),
+		},
+	}}
+	if !reflect.DeepEqual(sbc1.Queries, wantQueries) {
+		t.Errorf("sbc1.Queries: %+v, want %+v\n", sbc1.Queries, wantQueries)
+	}
+	wantQueries = []tproto.BoundQuery{{
+		Sql: "insert into customer(region, id, name) values (:_region_1, :_id_1, 'b') /* _routing keyspace_id:45166b40b44aba4bd6 */",
+		BindVariables: map[string]interface{}{
+			"keyspace_id": "E\x16k@\xb4J\xbaK\xd6",
+			"_region_1":   int64(69),
+			"_id_1":       int64(1),
+		},
+	}}
+	if !reflect.DeepEqual(sbc2.Queries, wantQueries) {
+		t.Errorf("sbc2.Queries: %+v, want %+v\n", sbc2.Queries, wantQueries)
+	}
+	if sbclookup.Queries != nil {
+		t.Errorf("sbclookup.Queries: %+v, want nil\n", sbclookup.Queries)
+	}
+
+	_, err = routerExec(router, "insert into customer(id, name) values (1, 'a')", nil)
+	want := "execInsertSharded: value must be supplied for column region"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+}
+
+func TestDeleteMultiColumn(t *testing.T) {
+	router, sbc, _, _ := createRouterEnv()
+
+	sbc.setResults([]*mproto.QueryResult{&mproto.QueryResult{
+		Fields: []mproto.Field{
+			{"region", 3, mproto.VT_ZEROVALUE_FLAG},
+			{"id", 3, mproto.VT_ZEROVALUE_FLAG},
+		},
+		RowsAffected: 1,
+		Rows: [][]sqltypes.Value{{
+			{sqltypes.Numeric("1")},
+			{sqltypes.Numeric("1")},
+		}},
+	}})
+	_, err := routerExec(router, "delete from customer where region = :region and id = 1", map[string]interface{}{
+		"region": 1,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql: "select region, id from customer where region = :region and id = 1 for update",
+		BindVariables: map[string]interface{}{
+			"region": 1,
+		},
+	}, {
+		Sql: "delete from customer where region = :region and id = 1 /* _routing keyspace_id:01166b40b44aba4bd6 */",
+		BindVariables: map[string]interface{}{
+			"region":      1,
+			"keyspace_id": "\x01\x16k@\xb4J\xbaK\xd6",
+		},
+	}}
+	if !reflect.DeepEqual(sbc.Queries, wantQueries) {
+		t.Errorf("sbc.Queries: %+v, want %+v\n", sbc.Queries, wantQueries)
+	}
+}
+
 func TestInsertFail(t *testing.T) {
 	router, sbc, _, sbclookup := createRouterEnv()
 
@@ -586,6 +1038,12 @@ func TestInsertFail(t *testing.T) {
 	}
 	getSandbox("TestRouter").ShardSpec = DefaultShardSpec
 
+	_, err = routerExec(router, "insert into music_extra(user_id, music_id) values (1, 2), (:aa, 3)", nil)
+	want = "execInsertSharded: could not find bind var :aa"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+
 	sbclookup.mustFailServer = 1
 	_, err = routerExec(router, "insert into music(user_id, id) values (1, null)", nil)
 	want = "lookup.Generate: shard, host: TestUnsharded.0.master"
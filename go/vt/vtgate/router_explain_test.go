@@ -0,0 +1,165 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vtgate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/vtgate/proto"
+	"golang.org/x/net/context"
+)
+
+func routerExplain(router *Router, sql string, bv map[string]interface{}) (*proto.QueryPlan, error) {
+	return router.Explain(context.Background(), &proto.Query{
+		Sql:           sql,
+		BindVariables: bv,
+		TabletType:    topo.TYPE_MASTER,
+	})
+}
+
+func TestExplainSelect(t *testing.T) {
+	router, sbc1, sbc2, sbclookup := createRouterEnv()
+
+	plan, err := routerExplain(router, "select * from user where id = :id", map[string]interface{}{
+		"id": 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPlan := &proto.QueryPlan{
+		PlanID:    "SelectEqual",
+		Table:     "user",
+		Col:       "id",
+		Vindex:    "user_index",
+		Rewritten: "select * from user where id = :id",
+		Keyspace:  "TestRouter",
+		Shards:    []string{"40-60"},
+	}
+	if !reflect.DeepEqual(plan, wantPlan) {
+		t.Errorf("plan: %+v, want %+v", plan, wantPlan)
+	}
+
+	plan, err = routerExplain(router, "select * from user where name = 'foo'", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPlan = &proto.QueryPlan{
+		PlanID:    "SelectEqual",
+		Table:     "user",
+		Col:       "name",
+		Vindex:    "name_user_map",
+		Rewritten: "select * from user where name = 'foo'",
+		Keyspace:  "TestRouter",
+		Shards:    []string{"-20"},
+	}
+	if !reflect.DeepEqual(plan, wantPlan) {
+		t.Errorf("plan: %+v, want %+v", plan, wantPlan)
+	}
+
+	// Only the lookup vindex was queried.
+	if sbc1.Queries != nil || sbc2.Queries != nil {
+		t.Errorf("sbc1.Queries: %+v, sbc2.Queries: %+v, want nil", sbc1.Queries, sbc2.Queries)
+	}
+	if len(sbclookup.Queries) != 1 {
+		t.Errorf("sbclookup.Queries: %+v, want 1 query", sbclookup.Queries)
+	}
+
+	plan, err = routerExplain(router, "select * from nonexistent", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPlan = &proto.QueryPlan{
+		PlanID: "NoPlan",
+		Reason: "table nonexistent not found",
+	}
+	if !reflect.DeepEqual(plan, wantPlan) {
+		t.Errorf("plan: %+v, want %+v", plan, wantPlan)
+	}
+
+	_, err = routerExplain(router, "select * from user where id = :id", nil)
+	want := "paramsSelectEqual: could not find bind var :id"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExplain: %v, want %v", err, want)
+	}
+}
+
+func TestExplainJoin(t *testing.T) {
+	router, sbc1, sbc2, _ := createRouterEnv()
+
+	plan, err := routerExplain(router, "select e.extra, u.id from user u join user_extra e on e.user_id = u.col where u.id = 1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPlan := &proto.QueryPlan{
+		PlanID: "SelectJoin",
+		Left: &proto.QueryPlan{
+			PlanID:    "SelectEqual",
+			Table:     "user",
+			Col:       "id",
+			Vindex:    "user_index",
+			Rewritten: "select u.id, u.col from user as u where u.id = 1",
+			Keyspace:  "TestRouter",
+			Shards:    []string{"-20"},
+		},
+		Right: &proto.QueryPlan{
+			PlanID:    "SelectEqual",
+			Table:     "user_extra",
+			Col:       "user_id",
+			Vindex:    "user_index",
+			Rewritten: "select e.extra from user_extra as e where e.user_id = :u_col",
+			Keyspace:  "TestRouter",
+		},
+	}
+	if !reflect.DeepEqual(plan, wantPlan) {
+		t.Errorf("plan: %+v, want %+v", plan, wantPlan)
+		t.Errorf("plan.Left: %+v, want %+v", plan.Left, wantPlan.Left)
+		t.Errorf("plan.Right: %+v, want %+v", plan.Right, wantPlan.Right)
+	}
+	if sbc1.Queries != nil || sbc2.Queries != nil {
+		t.Errorf("sbc1.Queries: %+v, sbc2.Queries: %+v, want nil", sbc1.Queries, sbc2.Queries)
+	}
+}
+
+func TestExplainDML(t *testing.T) {
+	router, sbc1, sbc2, sbclookup := createRouterEnv()
+
+	plan, err := routerExplain(router, "update user set a=2 where id = 1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPlan := &proto.QueryPlan{
+		PlanID:    "UpdateEqual",
+		Table:     "user",
+		Col:       "id",
+		Vindex:    "user_index",
+		Rewritten: "update user set a = 2 where id = 1 /* _routing keyspace_id:166b40b44aba4bd6 */",
+		Keyspace:  "TestRouter",
+		Shards:    []string{"-20"},
+	}
+	if !reflect.DeepEqual(plan, wantPlan) {
+		t.Errorf("plan: %+v, want %+v", plan, wantPlan)
+	}
+
+	plan, err = routerExplain(router, "insert into user(id, v, name) values (1, 2, 'myname'), (3, 4, 'othername'), (null, 5, 'gen')", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.PlanID != "InsertSharded" || !reflect.DeepEqual(plan.Shards, []string{"-20", "40-60"}) {
+		t.Errorf("plan: %+v, want InsertSharded on -20 and 40-60", plan)
+	}
+
+	// Explain doesn't create vindex entries.
+	if sbc1.Queries != nil || sbc2.Queries != nil || sbclookup.Queries != nil {
+		t.Errorf("sbc1.Queries: %+v, sbc2.Queries: %+v, sbclookup.Queries: %+v, want nil", sbc1.Queries, sbc2.Queries, sbclookup.Queries)
+	}
+
+	_, err = routerExplain(router, "delete from user where name = 'foo'", nil)
+	want := "paramsScatterDML: multi-shard dml not allowed without the /* allow_scatter */ comment: delete from user where name = 'foo'"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExplain: %v, want %v", err, want)
+	}
+}
@@ -72,6 +72,19 @@ var routerSchema = createTestSchema(`
         },
         "keyspace_id": {
           "Type": "numeric"
+        },
+        "region_index": {
+          "Type": "region",
+          "Owner": "customer"
+        },
+        "seq_index": {
+          "Type": "hash_autoinc",
+          "Owner": "seq_table",
+          "Params": {
+            "Table": "seq_idx",
+            "Column": "id",
+            "Sequence": "id_seq"
+          }
         }
       },
       "Classes": {
@@ -158,6 +171,26 @@ var routerSchema = createTestSchema(`
               "Name": "keyspace_id"
             }
           ]
+        },
+        "seq_table": {
+          "ColVindexes": [
+            {
+              "Col": "id",
+              "Name": "seq_index"
+            }
+          ]
+        },
+        "customer": {
+          "ColVindexes": [
+            {
+              "Cols": ["region", "id"],
+              "Name": "region_index"
+            }
+          ]
+        },
+        "country": {
+          "Type": "reference",
+          "Source": "TestUnsharded"
         }
       },
       "Tables": {
@@ -168,7 +201,10 @@ var routerSchema = createTestSchema(`
         "music_extra_reversed": "music_extra_reversed",
         "multi_autoinc_table": "multi_autoinc_table",
         "noauto_table": "noauto_table",
-        "ksid_table": "ksid_table"
+        "ksid_table": "ksid_table",
+        "customer": "customer",
+        "seq_table": "seq_table",
+        "country": "country"
       }
     },
     "TestBadSharding": {
@@ -179,12 +215,19 @@ var routerSchema = createTestSchema(`
     },
     "TestUnsharded": {
       "Sharded": false,
+      "Classes": {
+        "seq": {
+          "Type": "sequence"
+        }
+      },
       "Tables": {
         "user_idx": "",
         "music_user_map": "",
         "name_user_map": "",
         "idx1": "",
-        "idx2": ""
+        "idx2": "",
+        "seq_idx": "",
+        "id_seq": "seq"
       }
     }
   }
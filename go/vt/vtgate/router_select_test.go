@@ -12,10 +12,13 @@ import (
 
 	mproto "github.com/youtube/vitess/go/mysql/proto"
 	"github.com/youtube/vitess/go/sqltypes"
+	"github.com/youtube/vitess/go/vt/callerid"
 	tproto "github.com/youtube/vitess/go/vt/tabletserver/proto"
 	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/vterrors"
 	"github.com/youtube/vitess/go/vt/vtgate/proto"
 	_ "github.com/youtube/vitess/go/vt/vtgate/vindexes"
+	"golang.org/x/net/context"
 )
 
 func TestUnsharded(t *testing.T) {
@@ -134,6 +137,109 @@ func TestStreamUnshardedFail(t *testing.T) {
 	}
 }
 
+func TestSelectReference(t *testing.T) {
+	router, conns, sbclookup := createScatterRouterEnv()
+
+	for i := 0; i < 10; i++ {
+		_, err := routerExec(router, "select * from country where id = 1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	wantQuery := tproto.BoundQuery{
+		Sql:           "select * from country where id = 1",
+		BindVariables: map[string]interface{}{},
+	}
+	count := 0
+	for _, conn := range conns {
+		for _, query := range conn.Queries {
+			if !reflect.DeepEqual(query, wantQuery) {
+				t.Errorf("conn.Queries: %+v, want %+v", query, wantQuery)
+			}
+		}
+		count += len(conn.Queries)
+	}
+	if count != 10 {
+		t.Errorf("query count: %d, want 10", count)
+	}
+	if sbclookup.Queries != nil {
+		t.Errorf("sbclookup.Queries: %+v, want nil", sbclookup.Queries)
+	}
+
+	q := proto.Query{
+		Sql:        "select * from country where id = 1",
+		TabletType: topo.TYPE_MASTER,
+	}
+	result, err := routerStream(router, &q)
+	if err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(result, singleRowResult) {
+		t.Errorf("result: %+v, want %+v", result, singleRowResult)
+	}
+
+	getSandbox("TestRouter").SrvKeyspaceMustFail = 1
+	_, err = routerExec(router, "select * from country where id = 1", nil)
+	want := "paramsSelectReference: keyspace TestRouter fetch error: topo error GetSrvKeyspace"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+}
+
+func TestSelectReferenceJoin(t *testing.T) {
+	router, sbc1, sbc2, sbclookup := createRouterEnv()
+
+	_, err := routerExec(router, "select u.id, c.name from user u join country c on u.country_id = c.id where u.id = 1", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql:           "select u.id, c.name from user as u join country as c on u.country_id = c.id where u.id = 1",
+		BindVariables: map[string]interface{}{},
+	}}
+	if !reflect.DeepEqual(sbc1.Queries, wantQueries) {
+		t.Errorf("sbc1.Queries: %+v, want %+v\n", sbc1.Queries, wantQueries)
+	}
+	if sbc2.Queries != nil || sbclookup.Queries != nil {
+		t.Errorf("sbc2.Queries: %+v, sbclookup.Queries: %+v, want nil\n", sbc2.Queries, sbclookup.Queries)
+	}
+}
+
+func TestReferenceDML(t *testing.T) {
+	router, conns, sbclookup := createScatterRouterEnv()
+
+	_, err := routerExec(router, "update country set name = 'foo' where id = 1", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = routerExec(router, "delete from country where id = 1", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = routerExec(router, "insert into country(id, name) values (1, 'foo')", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql:           "update country set name = 'foo' where id = 1",
+		BindVariables: map[string]interface{}{},
+	}, {
+		Sql:           "delete from country where id = 1",
+		BindVariables: map[string]interface{}{},
+	}, {
+		Sql:           "insert into country(id, name) values (1, 'foo')",
+		BindVariables: map[string]interface{}{},
+	}}
+	if !reflect.DeepEqual(sbclookup.Queries, wantQueries) {
+		t.Errorf("sbclookup.Queries: %+v, want %+v\n", sbclookup.Queries, wantQueries)
+	}
+	for _, conn := range conns {
+		if conn.Queries != nil {
+			t.Errorf("conn.Queries: %+v, want nil", conn.Queries)
+		}
+	}
+}
+
 func TestSelectEqual(t *testing.T) {
 	router, sbc1, sbc2, sbclookup := createRouterEnv()
 
@@ -194,6 +300,35 @@ func TestSelectEqual(t *testing.T) {
 	}
 }
 
+func TestSelectEqualMultiColumn(t *testing.T) {
+	router, sbc1, sbc2, _ := createRouterEnv()
+
+	_, err := routerExec(router, "select * from customer where region = 69 and id = :id", map[string]interface{}{
+		"id": 1,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql: "select * from customer where region = 69 and id = :id",
+		BindVariables: map[string]interface{}{
+			"id": 1,
+		},
+	}}
+	if !reflect.DeepEqual(sbc2.Queries, wantQueries) {
+		t.Errorf("sbc2.Queries: %+v, want %+v\n", sbc2.Queries, wantQueries)
+	}
+	if sbc1.Queries != nil {
+		t.Errorf("sbc1.Queries: %+v, want nil\n", sbc1.Queries)
+	}
+
+	_, err = routerExec(router, "select * from customer where region = 256 and id = 1", nil)
+	want := "paramsSelectEqual: Region.Map: region 256 does not fit in 1 bytes"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+}
+
 func TestSelectEqualNotFound(t *testing.T) {
 	router, _, _, sbclookup := createRouterEnv()
 
@@ -615,3 +750,719 @@ func TestSelectScatterFail(t *testing.T) {
 		t.Errorf("routerExec: %v, want %v", err, want)
 	}
 }
+
+func TestSelectRange(t *testing.T) {
+	router, conns, _ := createScatterRouterEnv()
+
+	_, err := routerExec(router, "select * from ksid_table where keyspace_id between 1 and 4611686018427387904", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql:           "select * from ksid_table where keyspace_id between 1 and 4611686018427387904",
+		BindVariables: map[string]interface{}{},
+	}}
+	for i, conn := range conns {
+		if i < 3 {
+			if !reflect.DeepEqual(conn.Queries, wantQueries) {
+				t.Errorf("conns[%d].Queries = %#v, want %#v", i, conn.Queries, wantQueries)
+			}
+			continue
+		}
+		if conn.Queries != nil {
+			t.Errorf("conns[%d].Queries = %#v, want nil", i, conn.Queries)
+		}
+	}
+
+	// An empty range is not sent anywhere.
+	router, conns, _ = createScatterRouterEnv()
+	_, err = routerExec(router, "select * from ksid_table where keyspace_id between :a and :b", map[string]interface{}{
+		"a": 10,
+		"b": 1,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	for i, conn := range conns {
+		if conn.Queries != nil {
+			t.Errorf("conns[%d].Queries = %#v, want nil", i, conn.Queries)
+		}
+	}
+
+	router, conns, _ = createScatterRouterEnv()
+	_, err = routerExec(router, "select * from ksid_table where keyspace_id between :a and 1", nil)
+	want := "paramsSelectRange: could not find bind var :a"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+}
+
+func TestSelectRangeMerge(t *testing.T) {
+	router, conns, _ := createScatterRouterEnv()
+
+	conns[0].setResults([]*mproto.QueryResult{idResult("1", "3")})
+	conns[1].setResults([]*mproto.QueryResult{idResult("2")})
+	result, err := routerExec(router, "select id from ksid_table where keyspace_id between 1 and 2305843009213693952 order by id", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	wantResult := idResult("1", "2", "3")
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("result: %+v, want %+v", result, wantResult)
+	}
+	for i, conn := range conns[2:] {
+		if conn.Queries != nil {
+			t.Errorf("conns[%d].Queries = %#v, want nil", i+2, conn.Queries)
+		}
+	}
+}
+
+func idResult(ids ...string) *mproto.QueryResult {
+	qr := &mproto.QueryResult{
+		Fields:       []mproto.Field{{"id", mproto.VT_LONG, mproto.VT_ZEROVALUE_FLAG}},
+		RowsAffected: uint64(len(ids)),
+	}
+	for _, id := range ids {
+		qr.Rows = append(qr.Rows, []sqltypes.Value{{sqltypes.Numeric(id)}})
+	}
+	return qr
+}
+
+func TestSelectMerge(t *testing.T) {
+	router, sbc1, sbc2, _ := createRouterEnv()
+
+	sbc1.setResults([]*mproto.QueryResult{idResult("1", "3", "5")})
+	sbc2.setResults([]*mproto.QueryResult{idResult("2", "4")})
+	result, err := routerExec(router, "select id from user where id in (1, 3) order by id limit 1, 3", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	wantResult := idResult("2", "3", "4")
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("result: %+v, want %+v", result, wantResult)
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql: "select id from user where id in ::_vals order by id asc limit 4",
+		BindVariables: map[string]interface{}{
+			"_vals": []interface{}{int64(1)},
+		},
+	}}
+	if !reflect.DeepEqual(sbc1.Queries, wantQueries) {
+		t.Errorf("sbc1.Queries: %+v, want %+v\n", sbc1.Queries, wantQueries)
+	}
+
+	sbc1.setResults([]*mproto.QueryResult{idResult("5", "3", "1")})
+	sbc2.setResults([]*mproto.QueryResult{idResult("4", "2")})
+	result, err = routerExec(router, "select id from user where id in (1, 3) order by 1 desc limit :off, :cnt", map[string]interface{}{
+		"off": 0,
+		"cnt": int64(2),
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	wantResult = idResult("5", "4")
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("result: %+v, want %+v", result, wantResult)
+	}
+	wantQueries = []tproto.BoundQuery{{
+		Sql: "select id from user where id in ::_vals order by 1 desc limit :_limit",
+		BindVariables: map[string]interface{}{
+			"_vals":  []interface{}{int64(3)},
+			"_limit": int64(2),
+			"off":    0,
+			"cnt":    int64(2),
+		},
+	}}
+	if !reflect.DeepEqual(sbc2.Queries[1:], wantQueries) {
+		t.Errorf("sbc2.Queries: %+v, want %+v\n", sbc2.Queries[1:], wantQueries)
+	}
+
+	sbc1.setResults([]*mproto.QueryResult{idResult("1", "3")})
+	sbc2.setResults([]*mproto.QueryResult{idResult("2")})
+	result, err = routerExec(router, "select id from user where id in (1, 3) limit 10, 1", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	wantResult = &mproto.QueryResult{Fields: idResult().Fields}
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("result: %+v, want %+v", result, wantResult)
+	}
+}
+
+func TestStreamSelectMerge(t *testing.T) {
+	router, sbc1, sbc2, _ := createRouterEnv()
+
+	sbc1.setResults([]*mproto.QueryResult{idResult("1", "4")})
+	sbc2.setResults([]*mproto.QueryResult{idResult("2", "3")})
+	q := proto.Query{
+		Sql:        "select id from user where id in (1, 3) order by id",
+		TabletType: topo.TYPE_MASTER,
+	}
+	result, err := routerStream(router, &q)
+	if err != nil {
+		t.Error(err)
+	}
+	wantResult := idResult("1", "2", "3", "4")
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("result: %+v, want %+v", result, wantResult)
+	}
+
+	sbc1.setResults([]*mproto.QueryResult{idResult("1", "3", "5")})
+	sbc2.setResults([]*mproto.QueryResult{idResult("2", "4")})
+	q.Sql = "select id from user where id in (1, 3) order by id limit 1, 3"
+	result, err = routerStream(router, &q)
+	if err != nil {
+		t.Error(err)
+	}
+	wantResult = idResult("2", "3", "4")
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("result: %+v, want %+v", result, wantResult)
+	}
+
+	sbc1.setResults([]*mproto.QueryResult{idResult("1", "3", "5")})
+	sbc2.setResults([]*mproto.QueryResult{idResult("2", "4")})
+	q.Sql = "select id from user where id in (1, 3) order by id limit 10, 3"
+	result, err = routerStream(router, &q)
+	if err != nil {
+		t.Error(err)
+	}
+	wantResult = &mproto.QueryResult{Fields: idResult().Fields}
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("result: %+v, want %+v", result, wantResult)
+	}
+}
+
+func TestSelectMergeStreamLimits(t *testing.T) {
+	defer func(maxRows int64) {
+		*streamMaxRows = maxRows
+	}(*streamMaxRows)
+	*streamMaxRows = 2
+	router, sbc1, sbc2, _ := createRouterEnv()
+
+	// The rows skipped by the OFFSET are not counted.
+	sbc1.setResults([]*mproto.QueryResult{idResult("1", "3", "5")})
+	sbc2.setResults([]*mproto.QueryResult{idResult("2", "4")})
+	q := proto.Query{
+		Sql:        "select id from user where id in (1, 3) order by id limit 3, 10",
+		TabletType: topo.TYPE_MASTER,
+	}
+	result, err := routerStream(router, &q)
+	if err != nil {
+		t.Error(err)
+	}
+	wantResult := idResult("4", "5")
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("result: %+v, want %+v", result, wantResult)
+	}
+
+	sbc1.setResults([]*mproto.QueryResult{idResult("1", "3", "5")})
+	sbc2.setResults([]*mproto.QueryResult{idResult("2", "4")})
+	q.Sql = "select id from user where id in (1, 3) order by id limit 2, 10"
+	_, err = routerStream(router, &q)
+	want := "stream_limit_exceeded: more than 2 rows"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerStream: %v, want %s", err, want)
+	}
+	if vtErr, ok := err.(*vterrors.VitessError); !ok || vtErr.Code != vterrors.ResourceExhausted {
+		t.Errorf("routerStream: %#v, want code %v", err, vterrors.ResourceExhausted)
+	}
+}
+
+func TestSelectMergeFail(t *testing.T) {
+	router, sbc1, sbc2, _ := createRouterEnv()
+
+	_, err := routerExec(router, "select id from user where id in (1, 3) limit :a, 1", nil)
+	want := "paramsSelectMerge: could not find bind var :a"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+
+	_, err = routerExec(router, "select id from user where id in (1, 3) limit :a, 1", map[string]interface{}{
+		"a": "abcd",
+	})
+	want = "paramsSelectMerge: unexpected type for limit: string"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+
+	_, err = routerExec(router, "select id from user where id in (1, 3) order by nocol", nil)
+	want = "execSelectMerge: order by column nocol not found in result"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+
+	q := proto.Query{
+		Sql:        "select id from user where id in (1, 3) order by 5",
+		TabletType: topo.TYPE_MASTER,
+	}
+	_, err = routerStream(router, &q)
+	want = "streamSelectMerge: order by position 5 is out of range"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerStream: %v, want %v", err, want)
+	}
+
+	// Non-binary strings can't be merge-sorted: vtgate doesn't
+	// know their collation.
+	nameResult := &mproto.QueryResult{
+		Fields:       []mproto.Field{{"name", mproto.VT_VARCHAR, mproto.VT_ZEROVALUE_FLAG}},
+		RowsAffected: 1,
+		Rows:         [][]sqltypes.Value{{sqltypes.MakeString([]byte("a"))}},
+	}
+	sbc1.setResults([]*mproto.QueryResult{nameResult})
+	sbc2.setResults([]*mproto.QueryResult{nameResult})
+	_, err = routerExec(router, "select name from user where id in (1, 3) order by name", nil)
+	want = "execSelectMerge: cannot merge-sort order by column name: its order depends on its collation"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+
+	// The rows of a single shard are sorted by MySQL.
+	sbc1.setResults([]*mproto.QueryResult{nameResult})
+	qr, err := routerExec(router, "select name from user where id in (1) order by name limit 1", nil)
+	if err != nil {
+		t.Fatalf("routerExec: %v", err)
+	}
+	if !reflect.DeepEqual(qr.Rows, nameResult.Rows) {
+		t.Errorf("routerExec: %v, want %v", qr.Rows, nameResult.Rows)
+	}
+	sbc1.setResults([]*mproto.QueryResult{nameResult})
+	qr, err = routerStream(router, &proto.Query{
+		Sql:        "select name from user where id in (1) order by name limit 1",
+		TabletType: topo.TYPE_MASTER,
+	})
+	if err != nil {
+		t.Fatalf("routerStream: %v", err)
+	}
+	if !reflect.DeepEqual(qr.Rows, nameResult.Rows) {
+		t.Errorf("routerStream: %v, want %v", qr.Rows, nameResult.Rows)
+	}
+}
+
+func aggregateResult(rows ...[]sqltypes.Value) *mproto.QueryResult {
+	return &mproto.QueryResult{
+		Fields: []mproto.Field{
+			{"a", mproto.VT_VARCHAR, mproto.VT_BINARY_FLAG},
+			{"count(*)", mproto.VT_LONGLONG, mproto.VT_ZEROVALUE_FLAG},
+			{"sum(b)", mproto.VT_NEWDECIMAL, mproto.VT_ZEROVALUE_FLAG},
+			{"min(c)", mproto.VT_LONG, mproto.VT_ZEROVALUE_FLAG},
+			{"sum(d)", mproto.VT_NEWDECIMAL, mproto.VT_ZEROVALUE_FLAG},
+			{"count(d)", mproto.VT_LONGLONG, mproto.VT_ZEROVALUE_FLAG},
+		},
+		RowsAffected: uint64(len(rows)),
+		Rows:         rows,
+	}
+}
+
+func aggregateRow(vals ...string) []sqltypes.Value {
+	row := make([]sqltypes.Value, 0, len(vals))
+	for _, val := range vals {
+		if val == "null" {
+			row = append(row, sqltypes.NULL)
+			continue
+		}
+		row = append(row, sqltypes.MakeString([]byte(val)))
+	}
+	return row
+}
+
+func TestSelectAggregate(t *testing.T) {
+	router, sbc1, sbc2, _ := createRouterEnv()
+
+	sbc1.setResults([]*mproto.QueryResult{aggregateResult(
+		aggregateRow("x", "2", "10.5", "3", "7", "2"),
+		aggregateRow("z", "1", "1", "9", "null", "0"),
+	)})
+	sbc2.setResults([]*mproto.QueryResult{aggregateResult(
+		aggregateRow("y", "1", "2", "1", "5", "1"),
+		aggregateRow("x", "3", "9.25", "4", "1", "1"),
+	)})
+	result, err := routerExec(router, "select a, count(*), sum(b), min(c), avg(d) from user where id in (1, 3) group by a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantResult := &mproto.QueryResult{
+		Fields: []mproto.Field{
+			{"a", mproto.VT_VARCHAR, mproto.VT_BINARY_FLAG},
+			{"count(*)", mproto.VT_LONGLONG, mproto.VT_ZEROVALUE_FLAG},
+			{"sum(b)", mproto.VT_NEWDECIMAL, mproto.VT_ZEROVALUE_FLAG},
+			{"min(c)", mproto.VT_LONG, mproto.VT_ZEROVALUE_FLAG},
+			{"avg(d)", mproto.VT_NEWDECIMAL, mproto.VT_ZEROVALUE_FLAG},
+		},
+		RowsAffected: 3,
+		Rows: [][]sqltypes.Value{
+			{sqltypes.MakeString([]byte("x")), sqltypes.MakeNumeric([]byte("5")), sqltypes.MakeFractional([]byte("19.75")), sqltypes.MakeString([]byte("3")), sqltypes.MakeFractional([]byte("2.6667"))},
+			{sqltypes.MakeString([]byte("y")), sqltypes.MakeString([]byte("1")), sqltypes.MakeString([]byte("2")), sqltypes.MakeString([]byte("1")), sqltypes.MakeFractional([]byte("5.0000"))},
+			{sqltypes.MakeString([]byte("z")), sqltypes.MakeString([]byte("1")), sqltypes.MakeString([]byte("1")), sqltypes.MakeString([]byte("9")), sqltypes.NULL},
+		},
+	}
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("result: %+v, want %+v", result, wantResult)
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql: "select a, count(*), sum(b), min(c), sum(d), count(d) from user where id in ::_vals group by a",
+		BindVariables: map[string]interface{}{
+			"_vals": []interface{}{int64(1)},
+		},
+	}}
+	if !reflect.DeepEqual(sbc1.Queries, wantQueries) {
+		t.Errorf("sbc1.Queries: %+v, want %+v\n", sbc1.Queries, wantQueries)
+	}
+
+	sbc1.setResults([]*mproto.QueryResult{aggregateResult(
+		aggregateRow("x", "2", "1", "1", "1", "1"),
+		aggregateRow("y", "1", "1", "1", "1", "1"),
+	)})
+	sbc2.setResults([]*mproto.QueryResult{aggregateResult(
+		aggregateRow("y", "3", "1", "1", "1", "1"),
+		aggregateRow("z", "3", "1", "1", "1", "1"),
+	)})
+	result, err = routerExec(router, "select a, count(*) from user where id in (1, 3) group by a order by 2 desc, a limit 1, 2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, row := range result.Rows {
+		got = append(got, row[0].String()+":"+row[1].String())
+	}
+	want := []string{"z:3", "x:2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rows: %v, want %v", got, want)
+	}
+}
+
+func TestSelectAggregateFail(t *testing.T) {
+	router, sbc1, sbc2, _ := createRouterEnv()
+
+	sbc1.setResults([]*mproto.QueryResult{aggregateResult()})
+	sbc2.setResults([]*mproto.QueryResult{aggregateResult()})
+	_, err := routerExec(router, "select a, count(*) from user where id in (1, 3) group by a order by nocol", nil)
+	want := "execSelectAggregate: order by column nocol not found in result"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+
+	sbc1.setResults([]*mproto.QueryResult{aggregateResult(
+		aggregateRow("x", "2", "1", "1", "1", "1"),
+		aggregateRow("x", "abcd", "1", "1", "1", "1"),
+	)})
+	sbc2.setResults([]*mproto.QueryResult{aggregateResult()})
+	_, err = routerExec(router, "select a, count(*) from user where id in (1, 3) group by a", nil)
+	want = "execSelectAggregate: cannot combine count values of column count(*): strconv.ParseInt: parsing \"abcd\": invalid syntax"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+
+	// Non-binary strings can't be grouped, even if they don't drive
+	// the order: 'A' and 'a' can be the same group.
+	collated := aggregateResult(aggregateRow("A", "1", "1", "1", "1", "1"))
+	collated.Fields[0].Flags = mproto.VT_ZEROVALUE_FLAG
+	sbc1.setResults([]*mproto.QueryResult{collated})
+	sbc2.setResults([]*mproto.QueryResult{collated})
+	_, err = routerExec(router, "select a, count(*) from user where id in (1, 3) group by a order by 2", nil)
+	want = "execSelectAggregate: cannot group by column a: its values depend on its collation"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+
+	q := proto.Query{
+		Sql:        "select count(*) from user",
+		TabletType: topo.TYPE_MASTER,
+	}
+	_, err = routerStream(router, &q)
+	want = "query \"select count(*) from user\" cannot be used for streaming"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerStream: %v, want %v", err, want)
+	}
+}
+
+func TestSelectJoin(t *testing.T) {
+	router, sbc1, sbc2, _ := createRouterEnv()
+
+	leftResult := &mproto.QueryResult{
+		Fields: []mproto.Field{
+			{"id", mproto.VT_LONG, mproto.VT_ZEROVALUE_FLAG},
+			{"col", mproto.VT_LONG, mproto.VT_ZEROVALUE_FLAG},
+		},
+		RowsAffected: 2,
+		Rows: [][]sqltypes.Value{
+			{sqltypes.MakeNumeric([]byte("1")), sqltypes.MakeNumeric([]byte("1"))},
+			{sqltypes.MakeNumeric([]byte("2")), sqltypes.MakeNumeric([]byte("3"))},
+		},
+	}
+	rightResult := &mproto.QueryResult{
+		Fields: []mproto.Field{
+			{"extra", mproto.VT_VARCHAR, mproto.VT_ZEROVALUE_FLAG},
+		},
+		RowsAffected: 2,
+		Rows: [][]sqltypes.Value{
+			{sqltypes.MakeString([]byte("a"))},
+			{sqltypes.MakeString([]byte("b"))},
+		},
+	}
+	emptyResult := &mproto.QueryResult{
+		Fields: []mproto.Field{
+			{"extra", mproto.VT_VARCHAR, mproto.VT_ZEROVALUE_FLAG},
+		},
+	}
+	sbc1.setResults([]*mproto.QueryResult{leftResult, rightResult})
+	sbc2.setResults([]*mproto.QueryResult{emptyResult})
+	result, err := routerExec(router, "select e.extra, u.id from user u join user_extra e on e.user_id = u.col where u.id = 1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantResult := &mproto.QueryResult{
+		Fields: []mproto.Field{
+			{"extra", mproto.VT_VARCHAR, mproto.VT_ZEROVALUE_FLAG},
+			{"id", mproto.VT_LONG, mproto.VT_ZEROVALUE_FLAG},
+		},
+		RowsAffected: 2,
+		Rows: [][]sqltypes.Value{
+			{sqltypes.MakeString([]byte("a")), sqltypes.MakeNumeric([]byte("1"))},
+			{sqltypes.MakeString([]byte("b")), sqltypes.MakeNumeric([]byte("1"))},
+		},
+	}
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("result: %+v, want %+v", result, wantResult)
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql:           "select u.id, u.col from user as u where u.id = 1",
+		BindVariables: map[string]interface{}{},
+	}, {
+		Sql: "select e.extra from user_extra as e where e.user_id = :u_col",
+		BindVariables: map[string]interface{}{
+			"u_col": int64(1),
+		},
+	}}
+	if !reflect.DeepEqual(sbc1.Queries, wantQueries) {
+		t.Errorf("sbc1.Queries: %+v, want %+v\n", sbc1.Queries, wantQueries)
+	}
+	wantQueries = []tproto.BoundQuery{{
+		Sql: "select e.extra from user_extra as e where e.user_id = :u_col",
+		BindVariables: map[string]interface{}{
+			"u_col": int64(3),
+		},
+	}}
+	if !reflect.DeepEqual(sbc2.Queries, wantQueries) {
+		t.Errorf("sbc2.Queries: %+v, want %+v\n", sbc2.Queries, wantQueries)
+	}
+
+	// Left join returns NULLs for left rows that have no match.
+	sbc1.setResults([]*mproto.QueryResult{leftResult, rightResult})
+	sbc2.setResults([]*mproto.QueryResult{emptyResult})
+	result, err = routerExec(router, "select e.extra, u.id from user u left join user_extra e on e.user_id = u.col where u.id = 1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantResult.Rows = append(wantResult.Rows, []sqltypes.Value{sqltypes.NULL, sqltypes.MakeNumeric([]byte("2"))})
+	wantResult.RowsAffected = 3
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("result: %+v, want %+v", result, wantResult)
+	}
+}
+
+func TestSelectJoinNoRows(t *testing.T) {
+	router, sbc1, _, _ := createRouterEnv()
+
+	sbc1.setResults([]*mproto.QueryResult{{
+		Fields: []mproto.Field{
+			{"id", mproto.VT_LONG, mproto.VT_ZEROVALUE_FLAG},
+			{"col", mproto.VT_LONG, mproto.VT_ZEROVALUE_FLAG},
+		},
+	}, {
+		Fields: []mproto.Field{
+			{"extra", mproto.VT_VARCHAR, mproto.VT_ZEROVALUE_FLAG},
+		},
+	}})
+	result, err := routerExec(router, "select e.extra, u.id from user u join user_extra e on e.user_id = u.col where u.id = 1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantResult := &mproto.QueryResult{
+		Fields: []mproto.Field{
+			{"extra", mproto.VT_VARCHAR, mproto.VT_ZEROVALUE_FLAG},
+			{"id", mproto.VT_LONG, mproto.VT_ZEROVALUE_FLAG},
+		},
+	}
+	if !reflect.DeepEqual(result, wantResult) {
+		t.Errorf("result: %+v, want %+v", result, wantResult)
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql:           "select u.id, u.col from user as u where u.id = 1",
+		BindVariables: map[string]interface{}{},
+	}, {
+		Sql:           "select e.extra from user_extra as e where 1 != 1",
+		BindVariables: map[string]interface{}{},
+	}}
+	if !reflect.DeepEqual(sbc1.Queries, wantQueries) {
+		t.Errorf("sbc1.Queries: %+v, want %+v\n", sbc1.Queries, wantQueries)
+	}
+}
+
+func TestSelectJoinPushdown(t *testing.T) {
+	router, sbc1, sbc2, _ := createRouterEnv()
+
+	_, err := routerExec(router, "select u.id, e.extra from user u join user_extra e on u.id = e.user_id where u.id = 1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql:           "select u.id, e.extra from user as u join user_extra as e on u.id = e.user_id where u.id = 1",
+		BindVariables: map[string]interface{}{},
+	}}
+	if !reflect.DeepEqual(sbc1.Queries, wantQueries) {
+		t.Errorf("sbc1.Queries: %+v, want %+v\n", sbc1.Queries, wantQueries)
+	}
+	if sbc2.Queries != nil {
+		t.Errorf("sbc2.Queries: %+v, want nil\n", sbc2.Queries)
+	}
+}
+
+func TestSelectJoinFail(t *testing.T) {
+	router, sbc1, _, _ := createRouterEnv()
+
+	sbc1.mustFailServer = 1
+	_, err := routerExec(router, "select e.extra, u.id from user u join user_extra e on e.user_id = u.col where u.id = 1", nil)
+	want := "error: err"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+
+	sbc1.setResults([]*mproto.QueryResult{{
+		Fields: []mproto.Field{
+			{"id", mproto.VT_LONG, mproto.VT_ZEROVALUE_FLAG},
+			{"col", mproto.VT_LONG, mproto.VT_ZEROVALUE_FLAG},
+		},
+		RowsAffected: 1,
+		Rows: [][]sqltypes.Value{
+			{sqltypes.MakeNumeric([]byte("1")), sqltypes.MakeNumeric([]byte("abcd"))},
+		},
+	}})
+	_, err = routerExec(router, "select e.extra, u.id from user u join user_extra e on e.user_id = u.col where u.id = 1", nil)
+	want = "execSelectJoin: strconv.ParseInt: parsing \"abcd\": invalid syntax"
+	if err == nil || err.Error() != want {
+		t.Errorf("routerExec: %v, want %v", err, want)
+	}
+}
+
+func TestSelectConsolidated(t *testing.T) {
+	router, sbc1, _, _ := createRouterEnv()
+	router.consolidate = true
+
+	sbc1.mustDelay = 100 * time.Millisecond
+	results := make(chan *mproto.QueryResult, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			qr, err := routerExec(router, "select * from user where id = :id", map[string]interface{}{"id": 1})
+			if err != nil {
+				t.Error(err)
+			}
+			results <- qr
+		}()
+	}
+	var first *mproto.QueryResult
+	for i := 0; i < 3; i++ {
+		qr := <-results
+		if first == nil {
+			first = qr
+		}
+		if qr != first {
+			t.Errorf("routerExec: got %p, want the shared result %p", qr, first)
+		}
+	}
+	if got := sbc1.ExecCount.Get(); got != 1 {
+		t.Errorf("sbc1.ExecCount: %v, want 1", got)
+	}
+
+	// Different bind variables don't share their results.
+	sbc1.mustDelay = 0
+	if _, err := routerExec(router, "select * from user where id = :id", map[string]interface{}{"id": 2}); err != nil {
+		t.Error(err)
+	}
+	if got := sbc1.ExecCount.Get(); got != 2 {
+		t.Errorf("sbc1.ExecCount: %v, want 2", got)
+	}
+}
+
+func TestSelectConsolidatedSession(t *testing.T) {
+	router, _, _, _ := createRouterEnv()
+	plan := router.planner.GetPlan("select * from user where id = 1")
+	key := func(ctx context.Context, session *proto.Session) string {
+		return consolidationKey(newRequestContext(ctx, &proto.Query{
+			Sql:           "select * from user where id = 1",
+			BindVariables: map[string]interface{}{},
+			TabletType:    topo.TYPE_MASTER,
+			Session:       session,
+		}, router), plan)
+	}
+
+	// Different callers don't share their results.
+	ctx1 := callerid.NewContext(context.Background(), callerid.NewEffectiveCallerID("user1", "", ""), nil)
+	ctx2 := callerid.NewContext(context.Background(), callerid.NewEffectiveCallerID("user2", "", ""), nil)
+	if key(ctx1, nil) == key(ctx2, nil) {
+		t.Errorf("consolidationKey: different callers share key %q", key(ctx1, nil))
+	}
+	if key(ctx1, nil) != key(ctx1, &proto.Session{}) {
+		t.Errorf("consolidationKey: %q, want %q", key(ctx1, &proto.Session{}), key(ctx1, nil))
+	}
+
+	// Different staleness bounds don't share their results.
+	key1 := key(context.Background(), &proto.Session{MaxStalenessSeconds: 1})
+	key2 := key(context.Background(), &proto.Session{MaxStalenessSeconds: 2})
+	if key1 == key2 {
+		t.Errorf("consolidationKey: different staleness share key %q", key1)
+	}
+
+	// Reads in a transaction or a read-your-writes session are
+	// never consolidated.
+	testcases := []struct {
+		session *proto.Session
+		want    bool
+	}{
+		{nil, true},
+		{&proto.Session{MaxStalenessSeconds: 1}, true},
+		{&proto.Session{InTransaction: true}, false},
+		{&proto.Session{ReadYourWrites: true}, false},
+	}
+	for _, tcase := range testcases {
+		if got := canConsolidate(&proto.Query{Session: tcase.session}); got != tcase.want {
+			t.Errorf("canConsolidate(%+v): %v, want %v", tcase.session, got, tcase.want)
+		}
+	}
+}
+
+func TestSelectConsolidatedCancel(t *testing.T) {
+	router, sbc1, _, _ := createRouterEnv()
+	router.consolidate = true
+
+	sbc1.mustDelay = 200 * time.Millisecond
+	first := make(chan error, 1)
+	go func() {
+		_, err := routerExec(router, "select * from user where id = 1", nil)
+		first <- err
+	}()
+	// Let the first query start executing.
+	time.Sleep(50 * time.Millisecond)
+
+	// The waiter gives up when its context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	startTime := time.Now()
+	_, err := router.Execute(ctx, &proto.Query{
+		Sql:           "select * from user where id = 1",
+		BindVariables: map[string]interface{}{},
+		TabletType:    topo.TYPE_MASTER,
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("Execute: %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Now().Sub(startTime); elapsed > 100*time.Millisecond {
+		t.Errorf("Execute waited %v, want less than 100ms", elapsed)
+	}
+
+	if err := <-first; err != nil {
+		t.Error(err)
+	}
+	if got := sbc1.ExecCount.Get(); got != 1 {
+		t.Errorf("sbc1.ExecCount: %v, want 1", got)
+	}
+}
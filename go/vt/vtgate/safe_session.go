@@ -6,6 +6,7 @@ package vtgate
 
 import (
 	"sync"
+	"time"
 
 	"github.com/youtube/vitess/go/vt/topo"
 	"github.com/youtube/vitess/go/vt/vtgate/proto"
@@ -55,3 +56,54 @@ func (session *SafeSession) Reset() {
 	session.Session.InTransaction = false
 	session.ShardSessions = nil
 }
+
+// ReadsYourWrites returns true if the reads of session outside of
+// transactions must see its writes.
+func (session *SafeSession) ReadsYourWrites() bool {
+	if session == nil || session.Session == nil {
+		return false
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.ReadYourWrites
+}
+
+// SetPosition records the replication position of keyspace/shard
+// after a commit. An empty position means it's unknown.
+func (session *SafeSession) SetPosition(keyspace, shard, position string) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for _, shardPosition := range session.ShardPositions {
+		if keyspace == shardPosition.Keyspace && shard == shardPosition.Shard {
+			shardPosition.Position = position
+			return
+		}
+	}
+	session.ShardPositions = append(session.ShardPositions, &proto.ShardPosition{
+		Keyspace: keyspace,
+		Shard:    shard,
+		Position: position,
+	})
+}
+
+// ReadOptions returns how the reads of keyspace/shard outside of
+// transactions are done. If mustWait is true, they must wait until
+// position is replicated. They can't lag more than maxStaleness,
+// unless it's zero.
+func (session *SafeSession) ReadOptions(keyspace, shard string) (position string, mustWait bool, maxStaleness time.Duration) {
+	if session == nil || session.Session == nil {
+		return "", false, 0
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	maxStaleness = time.Duration(session.MaxStalenessSeconds) * time.Second
+	if !session.ReadYourWrites {
+		return "", false, maxStaleness
+	}
+	for _, shardPosition := range session.ShardPositions {
+		if keyspace == shardPosition.Keyspace && shard == shardPosition.Shard {
+			return shardPosition.Position, true, maxStaleness
+		}
+	}
+	return "", false, maxStaleness
+}
@@ -94,6 +94,10 @@ type sandbox struct {
 	// ShardSpec specifies the sharded keyranges
 	ShardSpec string
 
+	// ShardingColumnName specifies the sharding column of the keyspace,
+	// empty for custom sharding
+	ShardingColumnName string
+
 	// SrvKeyspaceCallback specifies the callback function in GetSrvKeyspace
 	SrvKeyspaceCallback func()
 
@@ -113,6 +117,7 @@ func (s *sandbox) Reset() {
 	s.DialMustTimeout = 0
 	s.KeyspaceServedFrom = ""
 	s.ShardSpec = DefaultShardSpec
+	s.ShardingColumnName = DefaultShardingColumnName
 	s.SrvKeyspaceCallback = nil
 }
 
@@ -155,6 +160,9 @@ func (s *sandbox) DeleteTestConn(shard string, conn tabletconn.TabletConn) {
 
 var DefaultShardSpec = "-20-40-60-80-a0-c0-e0-"
 
+// DefaultShardingColumnName is the sharding column of the sandboxes.
+var DefaultShardingColumnName = "user_id"
+
 func getAllShards(shardSpec string) (key.KeyRangeArray, error) {
 	shardedKrArray, err := key.ParseShardingSpec(shardSpec)
 	if err != nil {
@@ -167,7 +175,7 @@ func getKeyRangeName(kr key.KeyRange) string {
 	return fmt.Sprintf("%v-%v", string(kr.Start.Hex()), string(kr.End.Hex()))
 }
 
-func createShardedSrvKeyspace(shardSpec, servedFromKeyspace string) (*topo.SrvKeyspace, error) {
+func createShardedSrvKeyspace(shardSpec, shardingColumnName, servedFromKeyspace string) (*topo.SrvKeyspace, error) {
 	shardKrArray, err := getAllShards(shardSpec)
 	if err != nil {
 		return nil, err
@@ -181,7 +189,7 @@ func createShardedSrvKeyspace(shardSpec, servedFromKeyspace string) (*topo.SrvKe
 		shards = append(shards, shard)
 	}
 	shardedSrvKeyspace := &topo.SrvKeyspace{
-		ShardingColumnName: "user_id", // exact value is ignored
+		ShardingColumnName: shardingColumnName,
 		Partitions: map[topo.TabletType]*topo.KeyspacePartition{
 			topo.TYPE_MASTER: &topo.KeyspacePartition{
 				ShardReferences: shards,
@@ -264,7 +272,7 @@ func (sct *sandboxTopo) GetSrvKeyspace(ctx context.Context, cell, keyspace strin
 		return createUnshardedKeyspace()
 	}
 
-	return createShardedSrvKeyspace(sand.ShardSpec, sand.KeyspaceServedFrom)
+	return createShardedSrvKeyspace(sand.ShardSpec, sand.ShardingColumnName, sand.KeyspaceServedFrom)
 }
 
 func (sct *sandboxTopo) GetSrvShard(ctx context.Context, cell, keyspace, shard string) (*topo.SrvShard, error) {
@@ -273,10 +281,14 @@ func (sct *sandboxTopo) GetSrvShard(ctx context.Context, cell, keyspace, shard s
 
 func (sct *sandboxTopo) GetEndPoints(ctx context.Context, cell, keyspace, shard string, tabletType topo.TabletType) (*topo.EndPoints, int64, error) {
 	sand := getSandbox(keyspace)
+	sand.sandmu.Lock()
 	sand.EndPointCounter++
+	sand.sandmu.Unlock()
 	if sct.callbackGetEndPoints != nil {
 		sct.callbackGetEndPoints(sct)
 	}
+	sand.sandmu.Lock()
+	defer sand.sandmu.Unlock()
 	if sand.EndPointMustFail > 0 {
 		sand.EndPointMustFail--
 		return nil, -1, fmt.Errorf("topo error")
@@ -333,6 +345,19 @@ type sandboxConn struct {
 	RollbackCount sync2.AtomicInt64
 	CloseCount    sync2.AtomicInt64
 
+	// AsTransactionCount reports how many batches were
+	// executed as a transaction.
+	AsTransactionCount sync2.AtomicInt64
+
+	// TwoPCCalls stores the two-phase commit calls received,
+	// as the name of the call followed by its dtid.
+	TwoPCCalls []string
+
+	// Position is returned by MasterPosition. WaitMasterPos
+	// fails unless it waits for Position.
+	Position           string
+	WaitMasterPosCount sync2.AtomicInt64
+
 	// Queries stores the requests received.
 	Queries []tproto.BoundQuery
 
@@ -405,6 +430,19 @@ func (sbc *sandboxConn) Execute2(ctx context.Context, query string, bindVars map
 
 func (sbc *sandboxConn) ExecuteBatch(ctx context.Context, queries []tproto.BoundQuery, asTransaction bool, transactionID int64) (*tproto.QueryResultList, error) {
 	sbc.ExecCount.Add(1)
+	if asTransaction {
+		sbc.AsTransactionCount.Add(1)
+	}
+	for _, query := range queries {
+		bv := make(map[string]interface{})
+		for k, v := range query.BindVariables {
+			bv[k] = v
+		}
+		sbc.Queries = append(sbc.Queries, tproto.BoundQuery{
+			Sql:           query.Sql,
+			BindVariables: bv,
+		})
+	}
 	if sbc.mustDelay != 0 {
 		time.Sleep(sbc.mustDelay)
 	}
@@ -490,6 +528,59 @@ func (sbc *sandboxConn) Rollback2(ctx context.Context, transactionID int64) erro
 	return sbc.Rollback(ctx, transactionID)
 }
 
+func (sbc *sandboxConn) twoPC(call, dtid string) error {
+	sbc.ExecCount.Add(1)
+	sbc.TwoPCCalls = append(sbc.TwoPCCalls, call+" "+dtid)
+	if sbc.mustDelay != 0 {
+		time.Sleep(sbc.mustDelay)
+	}
+	return sbc.getError()
+}
+
+func (sbc *sandboxConn) Prepare(ctx context.Context, transactionID int64, dtid string) error {
+	return sbc.twoPC("Prepare", dtid)
+}
+
+func (sbc *sandboxConn) CommitPrepared(ctx context.Context, dtid string) error {
+	return sbc.twoPC("CommitPrepared", dtid)
+}
+
+func (sbc *sandboxConn) RollbackPrepared(ctx context.Context, dtid string) error {
+	return sbc.twoPC("RollbackPrepared", dtid)
+}
+
+func (sbc *sandboxConn) CreateTransaction(ctx context.Context, dtid string, participants []tproto.DTParticipant) error {
+	return sbc.twoPC("CreateTransaction", dtid)
+}
+
+func (sbc *sandboxConn) StartCommit(ctx context.Context, transactionID int64, dtid string) error {
+	return sbc.twoPC("StartCommit", dtid)
+}
+
+func (sbc *sandboxConn) ConcludeTransaction(ctx context.Context, dtid string) error {
+	return sbc.twoPC("ConcludeTransaction", dtid)
+}
+
+func (sbc *sandboxConn) MasterPosition(ctx context.Context) (string, error) {
+	sbc.ExecCount.Add(1)
+	if err := sbc.getError(); err != nil {
+		return "", err
+	}
+	return sbc.Position, nil
+}
+
+func (sbc *sandboxConn) WaitMasterPos(ctx context.Context, position string, waitTimeout time.Duration) error {
+	sbc.ExecCount.Add(1)
+	sbc.WaitMasterPosCount.Add(1)
+	if err := sbc.getError(); err != nil {
+		return err
+	}
+	if position != sbc.Position {
+		return fmt.Errorf("timed out waiting for position %v", position)
+	}
+	return nil
+}
+
 var sandboxSQRowCount = int64(10)
 
 // Fake SplitQuery creates splits from the original query by appending the
@@ -509,6 +600,23 @@ func (sbc *sandboxConn) SplitQuery(ctx context.Context, query tproto.BoundQuery,
 	return splits, nil
 }
 
+// Fake SplitQueryV2 creates splits like SplitQuery, with the split
+// columns in a comment.
+func (sbc *sandboxConn) SplitQueryV2(ctx context.Context, query tproto.BoundQuery, splitColumns []string, splitCount int, numRowsPerQueryPart int64) ([]tproto.QuerySplit, error) {
+	splits := []tproto.QuerySplit{}
+	for i := 0; i < splitCount; i++ {
+		split := tproto.QuerySplit{
+			Query: tproto.BoundQuery{
+				Sql:           fmt.Sprintf("%s /*split %v %v */", query.Sql, splitColumns, i),
+				BindVariables: query.BindVariables,
+			},
+			RowCount: sandboxSQRowCount,
+		}
+		splits = append(splits, split)
+	}
+	return splits, nil
+}
+
 // StreamHealth does nothing
 func (sbc *sandboxConn) StreamHealth(ctx context.Context) (<-chan *pb.StreamHealthResponse, tabletconn.ErrFunc, error) {
 	return nil, nil, fmt.Errorf("Not implemented in test")
@@ -5,11 +5,13 @@
 package vtgate
 
 import (
+	"flag"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
 
+	log "github.com/golang/glog"
 	mproto "github.com/youtube/vitess/go/mysql/proto"
 	"github.com/youtube/vitess/go/stats"
 	"github.com/youtube/vitess/go/sync2"
@@ -18,12 +20,18 @@ import (
 	tproto "github.com/youtube/vitess/go/vt/tabletserver/proto"
 	"github.com/youtube/vitess/go/vt/tabletserver/tabletconn"
 	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
 	"github.com/youtube/vitess/go/vt/vtgate/proto"
 	"golang.org/x/net/context"
 )
 
 var idGen sync2.AtomicInt64
 
+var (
+	enableTwoPC  = flag.Bool("enable_twopc", false, "if true, transactions that span multiple shards are committed atomically with a two-phase commit. The masters of the shards must run vttablet with its -enable-twopc flag, which keeps the redo log of the prepared transactions.")
+	twoPCCommits = stats.NewCounters("TwoPCCommits")
+)
+
 // ScatterConn is used for executing queries across
 // multiple ShardConn connections.
 type ScatterConn struct {
@@ -197,6 +205,45 @@ func (stc *ScatterConn) ExecuteMulti(
 	return qr, nil
 }
 
+// ExecuteMultiPerShard is like ExecuteMulti, but it returns the
+// result of each shard separately instead of concatenating them.
+// It's used for results that need to be merged by VTGate.
+func (stc *ScatterConn) ExecuteMultiPerShard(
+	ctx context.Context,
+	query string,
+	keyspace string,
+	shardVars map[string]map[string]interface{},
+	tabletType topo.TabletType,
+	session *SafeSession,
+	notInTransaction bool,
+) ([]*mproto.QueryResult, error) {
+	results, allErrors := stc.multiGo(
+		ctx,
+		"Execute",
+		keyspace,
+		getShards(shardVars),
+		tabletType,
+		session,
+		notInTransaction,
+		func(sdc *ShardConn, transactionId int64, sResults chan<- interface{}) error {
+			innerqr, err := sdc.Execute(ctx, query, shardVars[sdc.shard], transactionId)
+			if err != nil {
+				return err
+			}
+			sResults <- innerqr
+			return nil
+		})
+
+	qrs := make([]*mproto.QueryResult, 0, len(shardVars))
+	for innerqr := range results {
+		qrs = append(qrs, innerqr.(*mproto.QueryResult))
+	}
+	if allErrors.HasErrors() {
+		return nil, allErrors.AggrError(stc.aggregateErrors)
+	}
+	return qrs, nil
+}
+
 // ExecuteEntityIds executes queries that are shard specific.
 func (stc *ScatterConn) ExecuteEntityIds(
 	ctx context.Context,
@@ -239,6 +286,60 @@ func (stc *ScatterConn) ExecuteEntityIds(
 	return qr, nil
 }
 
+// ExecuteBatchPerShard executes a list of queries on each of the
+// shards. Outside of a transaction, the queries of a shard are executed
+// in a transaction of their own, so they are applied atomically, like a
+// single statement would be.
+func (stc *ScatterConn) ExecuteBatchPerShard(
+	ctx context.Context,
+	keyspace string,
+	shards []string,
+	shardQueries map[string][]tproto.BoundQuery,
+	tabletType topo.TabletType,
+	session *SafeSession,
+	notInTransaction bool,
+) (*mproto.QueryResult, error) {
+	results, allErrors := stc.multiGo(
+		ctx,
+		"ExecuteBatchPerShard",
+		keyspace,
+		shards,
+		tabletType,
+		session,
+		notInTransaction,
+		func(sdc *ShardConn, transactionId int64, sResults chan<- interface{}) error {
+			queries := shardQueries[sdc.shard]
+			if len(queries) == 1 {
+				innerqr, err := sdc.Execute(ctx, queries[0].Sql, queries[0].BindVariables, transactionId)
+				if err != nil {
+					return err
+				}
+				sResults <- innerqr
+				return nil
+			}
+			innerqrs, err := sdc.ExecuteBatch(ctx, queries, transactionId == 0, transactionId)
+			if err != nil {
+				return err
+			}
+			innerqr := new(mproto.QueryResult)
+			for i := range innerqrs.List {
+				appendResult(innerqr, &innerqrs.List[i])
+			}
+			sResults <- innerqr
+			return nil
+		})
+
+	qr := new(mproto.QueryResult)
+	for innerqr := range results {
+		innerqr := innerqr.(*mproto.QueryResult)
+		appendResult(qr, innerqr)
+	}
+	if allErrors.HasErrors() {
+		return nil, allErrors.AggrError(stc.aggregateErrors)
+	}
+	return qr, nil
+}
+
 // scatterBatchRequest needs to be built to perform a scatter batch query.
 // A VTGate batch request will get translated into a differnt set of batches
 // for each keyspace:shard, and those results will map to different positions in the
@@ -285,6 +386,9 @@ func (stc *ScatterConn) ExecuteBatch(
 				stc.tabletCallErrorCount.Add(statsKey, 1)
 				return
 			}
+			if transactionID == 0 {
+				sdc = stc.readConnection(ctx, sdc, session)
+			}
 
 			innerqrs, err := sdc.ExecuteBatch(ctx, req.Queries, asTransaction, transactionID)
 			if err != nil {
@@ -296,6 +400,9 @@ func (stc *ScatterConn) ExecuteBatch(
 				}
 				return
 			}
+			if transactionID == 0 {
+				stc.recordAutocommitPosition(ctx, sdc, session)
+			}
 			// Encapsulate in a function for safe mutex operation.
 			func() {
 				resMutex.Lock()
@@ -336,6 +443,9 @@ func (stc *ScatterConn) StreamExecute(
 	sendReply func(reply *mproto.QueryResult) error,
 	notInTransaction bool,
 ) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sendReply = limitStream(sendReply)
 	results, allErrors := stc.multiGo(
 		ctx,
 		"StreamExecute",
@@ -368,10 +478,14 @@ func (stc *ScatterConn) StreamExecute(
 			}
 			fieldSent = true
 		}
-		replyErr = sendReply(mqr)
+		if replyErr = sendReply(mqr); replyErr != nil {
+			// Stop the shards: their errors are then only
+			// caused by the cancelation.
+			cancel()
+		}
 	}
 	if replyErr != nil {
-		allErrors.RecordError(replyErr)
+		return replyErr
 	}
 	return allErrors.AggrError(stc.aggregateErrors)
 }
@@ -379,6 +493,10 @@ func (stc *ScatterConn) StreamExecute(
 // StreamExecuteMulti is like StreamExecute,
 // but each shard gets its own bindVars. If len(shards) is not equal to
 // len(bindVars), the function panics.
+// If orderBy is set, the stream of every shard must be sorted by it,
+// and the rows are merge-sorted. Otherwise, they're sent in the order
+// they arrive. Unlike StreamExecute, it doesn't enforce the stream
+// limits: the router does, on the rows it sends after the OFFSET.
 func (stc *ScatterConn) StreamExecuteMulti(
 	ctx context.Context,
 	query string,
@@ -386,9 +504,15 @@ func (stc *ScatterConn) StreamExecuteMulti(
 	shardVars map[string]map[string]interface{},
 	tabletType topo.TabletType,
 	session *SafeSession,
+	orderBy []planbuilder.OrderByCol,
 	sendReply func(reply *mproto.QueryResult) error,
 	notInTransaction bool,
 ) error {
+	if len(orderBy) != 0 {
+		return stc.streamExecuteOrdered(ctx, query, keyspace, shardVars, tabletType, session, orderBy, sendReply, notInTransaction)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	results, allErrors := stc.multiGo(
 		ctx,
 		"StreamExecute",
@@ -421,10 +545,14 @@ func (stc *ScatterConn) StreamExecuteMulti(
 			}
 			fieldSent = true
 		}
-		replyErr = sendReply(mqr)
+		if replyErr = sendReply(mqr); replyErr != nil {
+			// Stop the shards: their errors are then only
+			// caused by the cancelation.
+			cancel()
+		}
 	}
 	if replyErr != nil {
-		allErrors.RecordError(replyErr)
+		return replyErr
 	}
 	return allErrors.AggrError(stc.aggregateErrors)
 }
@@ -437,8 +565,19 @@ func (stc *ScatterConn) Commit(ctx context.Context, session *SafeSession) (err e
 	if !session.InTransaction() {
 		return fmt.Errorf("cannot commit: not in transaction")
 	}
+	shardSessions := session.ShardSessions
+	if session.ReadYourWrites {
+		// The positions are read even if the commit fails, as some of
+		// the shards may have committed. It's harmless for the others.
+		defer stc.recordPositions(ctx, session, shardSessions)
+	}
+	if *enableTwoPC && len(shardSessions) > 1 {
+		err = stc.commit2PC(ctx, session)
+		session.Reset()
+		return err
+	}
 	committing := true
-	for _, shardSession := range session.ShardSessions {
+	for _, shardSession := range shardSessions {
 		sdc := stc.getConnection(ctx, shardSession.Keyspace, shardSession.Shard, shardSession.TabletType)
 		if !committing {
 			sdc.Rollback(ctx, shardSession.TransactionId)
@@ -452,6 +591,97 @@ func (stc *ScatterConn) Commit(ctx context.Context, session *SafeSession) (err e
 	return err
 }
 
+// commit2PC commits the transaction of session atomically across all
+// its shards. The first shard is the coordinator: it records the
+// distributed transaction and its commit decision. The other shards
+// are the participants: they're prepared before the decision, and
+// committed after it. If vtgate fails after the decision, the
+// coordinator resolves the transaction once it's abandoned.
+func (stc *ScatterConn) commit2PC(ctx context.Context, session *SafeSession) error {
+	coordinator := session.ShardSessions[0]
+	participants := session.ShardSessions[1:]
+	csdc := stc.getConnection(ctx, coordinator.Keyspace, coordinator.Shard, coordinator.TabletType)
+	dtid := fmt.Sprintf("%s:%s:%d", coordinator.Keyspace, coordinator.Shard, coordinator.TransactionId)
+	dtParticipants := make([]tproto.DTParticipant, 0, len(participants))
+	for _, shardSession := range participants {
+		dtParticipants = append(dtParticipants, tproto.DTParticipant{Keyspace: shardSession.Keyspace, Shard: shardSession.Shard})
+	}
+
+	if err := csdc.CreateTransaction(ctx, dtid, dtParticipants); err != nil {
+		for _, shardSession := range session.ShardSessions {
+			sdc := stc.getConnection(ctx, shardSession.Keyspace, shardSession.Shard, shardSession.TabletType)
+			sdc.Rollback(ctx, shardSession.TransactionId)
+		}
+		twoPCCommits.Add("Aborted", 1)
+		return err
+	}
+	for i, shardSession := range participants {
+		sdc := stc.getConnection(ctx, shardSession.Keyspace, shardSession.Shard, shardSession.TabletType)
+		if err := sdc.Prepare(ctx, shardSession.TransactionId, dtid); err != nil {
+			stc.abort2PC(ctx, dtid, csdc, coordinator, participants[:i], participants[i:])
+			twoPCCommits.Add("Aborted", 1)
+			return err
+		}
+	}
+
+	// The coordinator rolls back its transaction if StartCommit fails.
+	// The decision may still have been committed if the error came
+	// afterwards, so the participants are left to the coordinator.
+	if err := csdc.StartCommit(ctx, coordinator.TransactionId, dtid); err != nil {
+		twoPCCommits.Add("Unresolved", 1)
+		return fmt.Errorf("commit of %s is unknown, it will be resolved by its coordinator: %v", dtid, err)
+	}
+
+	// The transaction is committed from here on: participants that
+	// fail are committed later by the coordinator.
+	resolved := true
+	for _, shardSession := range participants {
+		sdc := stc.getConnection(ctx, shardSession.Keyspace, shardSession.Shard, shardSession.TabletType)
+		if err := sdc.CommitPrepared(ctx, dtid); err != nil {
+			log.Warningf("CommitPrepared of %s failed on %s/%s, it will be resolved by its coordinator: %v", dtid, shardSession.Keyspace, shardSession.Shard, err)
+			resolved = false
+		}
+	}
+	if !resolved {
+		twoPCCommits.Add("Unresolved", 1)
+		return nil
+	}
+	if err := csdc.ConcludeTransaction(ctx, dtid); err != nil {
+		log.Warningf("ConcludeTransaction of %s failed, it will be resolved by its coordinator: %v", dtid, err)
+	}
+	twoPCCommits.Add("Committed", 1)
+	return nil
+}
+
+// abort2PC rolls back a distributed transaction whose commit decision
+// was not made. The first unprepared participant is also rolled back as
+// prepared, in case its Prepare succeeded but returned an error. The
+// transaction is only concluded if all participants were rolled back.
+func (stc *ScatterConn) abort2PC(ctx context.Context, dtid string, csdc *ShardConn, coordinator *proto.ShardSession, prepared, unprepared []*proto.ShardSession) {
+	csdc.Rollback(ctx, coordinator.TransactionId)
+	resolved := true
+	if len(unprepared) > 0 {
+		prepared = append(prepared[:len(prepared):len(prepared)], unprepared[0])
+	}
+	for _, shardSession := range prepared {
+		sdc := stc.getConnection(ctx, shardSession.Keyspace, shardSession.Shard, shardSession.TabletType)
+		if err := sdc.RollbackPrepared(ctx, dtid); err != nil {
+			log.Warningf("RollbackPrepared of %s failed on %s/%s, it will be resolved by its coordinator: %v", dtid, shardSession.Keyspace, shardSession.Shard, err)
+			resolved = false
+		}
+	}
+	for _, shardSession := range unprepared {
+		sdc := stc.getConnection(ctx, shardSession.Keyspace, shardSession.Shard, shardSession.TabletType)
+		sdc.Rollback(ctx, shardSession.TransactionId)
+	}
+	if !resolved {
+		return
+	}
+	if err := csdc.ConcludeTransaction(ctx, dtid); err != nil {
+		log.Warningf("ConcludeTransaction of %s failed, it will be resolved by its coordinator: %v", dtid, err)
+	}
+}
+
 // Rollback rolls back the current transaction. There are no retries on this operation.
 func (stc *ScatterConn) Rollback(ctx context.Context, session *SafeSession) (err error) {
 	if session == nil {
@@ -559,6 +789,45 @@ func (stc *ScatterConn) SplitQueryCustomSharding(ctx context.Context, query tpro
 	return splits, nil
 }
 
+// SplitQueryV2 scatters a SplitQueryV2 request to the shards. Each
+// split received from a shard is returned as a query on that shard
+// only, for tabletType. Aggregates all splits across all shards in
+// no specific order and returns.
+func (stc *ScatterConn) SplitQueryV2(ctx context.Context, query tproto.BoundQuery, splitColumns []string, splitCount int, numRowsPerQueryPart int64, keyspace string, shards []string, tabletType topo.TabletType) ([]proto.SplitQueryPart, error) {
+	actionFunc := func(sdc *ShardConn, transactionID int64, results chan<- interface{}) error {
+		queries, err := sdc.SplitQueryV2(ctx, query, splitColumns, splitCount, numRowsPerQueryPart)
+		if err != nil {
+			return err
+		}
+		splits := make([]proto.SplitQueryPart, 0, len(queries))
+		for _, query := range queries {
+			splits = append(splits, proto.SplitQueryPart{
+				QueryShard: &proto.QueryShard{
+					Sql:           query.Query.Sql,
+					BindVariables: query.Query.BindVariables,
+					Keyspace:      keyspace,
+					Shards:        []string{sdc.shard},
+					TabletType:    tabletType,
+				},
+				Size: query.RowCount,
+			})
+		}
+		results <- splits
+		return nil
+	}
+
+	allSplits, allErrors := stc.multiGo(ctx, "SplitQueryV2", keyspace, shards, tabletType, NewSafeSession(&proto.Session{}), false, actionFunc)
+	splits := []proto.SplitQueryPart{}
+	for s := range allSplits {
+		splits = append(splits, s.([]proto.SplitQueryPart)...)
+	}
+	if allErrors.HasErrors() {
+		err := allErrors.AggrError(stc.aggregateErrors)
+		return nil, err
+	}
+	return splits, nil
+}
+
 // Close closes the underlying ShardConn connections.
 func (stc *ScatterConn) Close() error {
 	stc.mu.Lock()
@@ -648,6 +917,9 @@ func (stc *ScatterConn) multiGo(
 				stc.tabletCallErrorCount.Add(statsKey, 1)
 				return
 			}
+			if transactionID == 0 {
+				sdc = stc.readConnection(ctx, sdc, session)
+			}
 			err = action(sdc, transactionID, results)
 			if err != nil {
 				allErrors.RecordError(err)
@@ -658,6 +930,9 @@ func (stc *ScatterConn) multiGo(
 				}
 				return
 			}
+			if transactionID == 0 {
+				stc.recordAutocommitPosition(ctx, sdc, session)
+			}
 		}(shard)
 	}
 	go func() {
@@ -13,6 +13,9 @@ import (
 	mproto "github.com/youtube/vitess/go/mysql/proto"
 	"github.com/youtube/vitess/go/sqltypes"
 	"github.com/youtube/vitess/go/vt/tabletserver/tabletconn"
+	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/vterrors"
+	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
 	"github.com/youtube/vitess/go/vt/vtgate/proto"
 	"golang.org/x/net/context"
 )
@@ -78,7 +81,7 @@ func TestScatterConnStreamExecuteMulti(t *testing.T) {
 		for _, shard := range shards {
 			shardVars[shard] = nil
 		}
-		err := stc.StreamExecuteMulti(context.Background(), "query", "TestScatterConnStreamExecute", shardVars, "", nil, func(r *mproto.QueryResult) error {
+		err := stc.StreamExecuteMulti(context.Background(), "query", "TestScatterConnStreamExecute", shardVars, "", nil, nil, func(r *mproto.QueryResult) error {
 			appendResult(qr, r)
 			return nil
 		}, false)
@@ -191,7 +194,7 @@ func TestMultiExecs(t *testing.T) {
 	}
 	sbc0.Queries = nil
 	sbc1.Queries = nil
-	_ = stc.StreamExecuteMulti(context.Background(), "query", "TestMultiExecs", shardVars, "", nil, func(*mproto.QueryResult) error {
+	_ = stc.StreamExecuteMulti(context.Background(), "query", "TestMultiExecs", shardVars, "", nil, nil, func(*mproto.QueryResult) error {
 		return nil
 	}, false)
 	if !reflect.DeepEqual(sbc0.Queries[0].BindVariables, shardVars["0"]) {
@@ -217,6 +220,142 @@ func TestScatterConnStreamExecuteSendError(t *testing.T) {
 	}
 }
 
+func TestScatterConnStreamExecuteMultiOrdered(t *testing.T) {
+	s := createSandbox("TestScatterConnStreamExecuteMultiOrdered")
+	sbc0 := &sandboxConn{}
+	s.MapTestConn("0", sbc0)
+	sbc1 := &sandboxConn{}
+	s.MapTestConn("1", sbc1)
+	sbc0.setResults([]*mproto.QueryResult{idResult("1", "4", "5")})
+	sbc1.setResults([]*mproto.QueryResult{idResult("2", "3", "6")})
+	stc := NewScatterConn(new(sandboxTopo), "", "aa", 1*time.Millisecond, 3, 2*time.Millisecond, 1*time.Millisecond, 24*time.Hour)
+	shardVars := map[string]map[string]interface{}{
+		"0": nil,
+		"1": nil,
+	}
+	orderBy := []planbuilder.OrderByCol{{Index: 0}}
+	var results []*mproto.QueryResult
+	err := stc.StreamExecuteMulti(context.Background(), "query", "TestScatterConnStreamExecuteMultiOrdered", shardVars, "", nil, orderBy, func(qr *mproto.QueryResult) error {
+		results = append(results, qr)
+		return nil
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fields are sent first, then the merged rows.
+	want := []*mproto.QueryResult{
+		{Fields: idResult().Fields},
+		{RowsAffected: 6, Rows: idResult("1", "2", "3", "4", "5", "6").Rows},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("results: %+v, want %+v", results, want)
+	}
+
+	sbc0.setResults([]*mproto.QueryResult{idResult("1")})
+	sbc1.mustFailServer = 1
+	err = stc.StreamExecuteMulti(context.Background(), "query", "TestScatterConnStreamExecuteMultiOrdered", shardVars, "", nil, orderBy, func(qr *mproto.QueryResult) error {
+		return nil
+	}, false)
+	wantErr := "shard, host: TestScatterConnStreamExecuteMultiOrdered.1., {Uid:0 Host:1 NamedPortMap:map[vt:1] Health:map[]}, error: err"
+	if err == nil || err.Error() != wantErr {
+		t.Errorf("StreamExecuteMulti: %v, want %s", err, wantErr)
+	}
+}
+
+func TestMergeStreams(t *testing.T) {
+	newStream := func(packets ...*mproto.QueryResult) *rowStream {
+		results := make(chan *mproto.QueryResult, len(packets))
+		for _, qr := range packets {
+			results <- qr
+		}
+		close(results)
+		return &rowStream{results: results}
+	}
+	streams := []*rowStream{
+		newStream(&mproto.QueryResult{Fields: idResult().Fields}, idResult("1"), idResult("5", "6")),
+		newStream(&mproto.QueryResult{Fields: idResult().Fields}),
+		newStream(&mproto.QueryResult{Fields: idResult().Fields}, idResult("2", "3"), idResult("4"), idResult("7")),
+	}
+	qr := new(mproto.QueryResult)
+	err := mergeStreams(streams, []planbuilder.OrderByCol{{Index: 0}}, func(r *mproto.QueryResult) error {
+		appendResult(qr, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := idResult("1", "2", "3", "4", "5", "6", "7"); !reflect.DeepEqual(qr, want) {
+		t.Errorf("mergeStreams: %+v, want %+v", qr, want)
+	}
+
+	// A failed comparison aborts the merge.
+	streams = []*rowStream{
+		newStream(idResult("1")),
+		newStream(&mproto.QueryResult{Rows: [][]sqltypes.Value{{sqltypes.MakeString([]byte("a"))}}}),
+	}
+	err = mergeStreams(streams, []planbuilder.OrderByCol{{Index: 0}}, func(r *mproto.QueryResult) error {
+		return nil
+	})
+	if err == nil {
+		t.Errorf("mergeStreams: nil, want error")
+	}
+}
+
+func TestScatterConnStreamLimits(t *testing.T) {
+	defer func(maxRows, maxBytes int64) {
+		*streamMaxRows = maxRows
+		*streamMaxBytes = maxBytes
+	}(*streamMaxRows, *streamMaxBytes)
+
+	s := createSandbox("TestScatterConnStreamLimits")
+	sbc0 := &sandboxConn{}
+	s.MapTestConn("0", sbc0)
+	sbc1 := &sandboxConn{}
+	s.MapTestConn("1", sbc1)
+	stc := NewScatterConn(new(sandboxTopo), "", "aa", 1*time.Millisecond, 3, 2*time.Millisecond, 1*time.Millisecond, 24*time.Hour)
+	shardVars := map[string]map[string]interface{}{
+		"0": nil,
+		"1": nil,
+	}
+	testcases := []struct {
+		maxRows, maxBytes int64
+		orderBy           []planbuilder.OrderByCol
+		rows              int
+		err               string
+	}{
+		{maxRows: 4, rows: 4},
+		{maxRows: 3, err: "stream_limit_exceeded: more than 3 rows"},
+		{maxBytes: 2, err: "stream_limit_exceeded: more than 2 bytes"},
+		{maxRows: 3, orderBy: []planbuilder.OrderByCol{{Index: 0}}, err: "stream_limit_exceeded: more than 3 rows"},
+		{maxBytes: 4, orderBy: []planbuilder.OrderByCol{{Index: 0}}, rows: 4},
+	}
+	for _, tc := range testcases {
+		*streamMaxRows = tc.maxRows
+		*streamMaxBytes = tc.maxBytes
+		sbc0.setResults([]*mproto.QueryResult{idResult("1", "3")})
+		sbc1.setResults([]*mproto.QueryResult{idResult("2", "4")})
+		rows := 0
+		// The router enforces the limits of StreamExecuteMulti.
+		err := stc.StreamExecuteMulti(context.Background(), "query", "TestScatterConnStreamLimits", shardVars, "", nil, tc.orderBy, limitStream(func(qr *mproto.QueryResult) error {
+			rows += len(qr.Rows)
+			return nil
+		}), false)
+		if tc.err == "" {
+			if err != nil || rows != tc.rows {
+				t.Errorf("%+v: %d rows, %v, want %d rows", tc, rows, err, tc.rows)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("%+v: %v, want %s", tc, err, tc.err)
+			continue
+		}
+		if vtErr, ok := err.(*vterrors.VitessError); !ok || vtErr.Code != vterrors.ResourceExhausted {
+			t.Errorf("%+v: %#v, want code %v", tc, err, vterrors.ResourceExhausted)
+		}
+	}
+}
+
 func TestScatterCommitRollbackIncorrectSession(t *testing.T) {
 	s := createSandbox("TestScatterCommitRollbackIncorrectSession")
 	sbc0 := &sandboxConn{}
@@ -298,6 +437,171 @@ func TestScatterConnCommitSuccess(t *testing.T) {
 	}
 }
 
+func TestScatterConnCommit2PC(t *testing.T) {
+	*enableTwoPC = true
+	defer func() { *enableTwoPC = false }()
+	s := createSandbox("TestScatterConnCommit2PC")
+	sbc0 := &sandboxConn{}
+	s.MapTestConn("0", sbc0)
+	sbc1 := &sandboxConn{}
+	s.MapTestConn("1", sbc1)
+	sbc2 := &sandboxConn{}
+	s.MapTestConn("2", sbc2)
+	stc := NewScatterConn(new(sandboxTopo), "", "aa", 1*time.Millisecond, 3, 2*time.Millisecond, 1*time.Millisecond, 24*time.Hour)
+
+	// Sequence the executes to make shard 0 the coordinator.
+	session := NewSafeSession(&proto.Session{InTransaction: true})
+	stc.Execute(context.Background(), "query1", nil, "TestScatterConnCommit2PC", []string{"0"}, "", session, false)
+	stc.Execute(context.Background(), "query1", nil, "TestScatterConnCommit2PC", []string{"1", "2"}, "", session, false)
+	if err := stc.Commit(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+	if want := (proto.Session{}); !reflect.DeepEqual(want, *session.Session) {
+		t.Errorf("want\n%#v, got\n%#v", want, *session.Session)
+	}
+	wantCalls := []string{
+		"CreateTransaction TestScatterConnCommit2PC:0:1",
+		"StartCommit TestScatterConnCommit2PC:0:1",
+		"ConcludeTransaction TestScatterConnCommit2PC:0:1",
+	}
+	if !reflect.DeepEqual(sbc0.TwoPCCalls, wantCalls) {
+		t.Errorf("sbc0.TwoPCCalls: %v, want %v", sbc0.TwoPCCalls, wantCalls)
+	}
+	wantCalls = []string{
+		"Prepare TestScatterConnCommit2PC:0:1",
+		"CommitPrepared TestScatterConnCommit2PC:0:1",
+	}
+	for _, sbc := range []*sandboxConn{sbc1, sbc2} {
+		if !reflect.DeepEqual(sbc.TwoPCCalls, wantCalls) {
+			t.Errorf("TwoPCCalls: %v, want %v", sbc.TwoPCCalls, wantCalls)
+		}
+		if sbc.CommitCount.Get() != 0 {
+			t.Errorf("CommitCount: %d, want 0", sbc.CommitCount.Get())
+		}
+	}
+
+	// A failed Prepare rolls back every shard.
+	for _, sbc := range []*sandboxConn{sbc0, sbc1, sbc2} {
+		sbc.TwoPCCalls = nil
+	}
+	session = NewSafeSession(&proto.Session{InTransaction: true})
+	stc.Execute(context.Background(), "query1", nil, "TestScatterConnCommit2PC", []string{"0"}, "", session, false)
+	stc.Execute(context.Background(), "query1", nil, "TestScatterConnCommit2PC", []string{"1"}, "", session, false)
+	stc.Execute(context.Background(), "query1", nil, "TestScatterConnCommit2PC", []string{"2"}, "", session, false)
+	sbc2.mustFailServer = 1
+	if err := stc.Commit(context.Background(), session); err == nil {
+		t.Errorf("want error, got nil")
+	}
+	wantCalls = []string{
+		"CreateTransaction TestScatterConnCommit2PC:0:2",
+		"ConcludeTransaction TestScatterConnCommit2PC:0:2",
+	}
+	if !reflect.DeepEqual(sbc0.TwoPCCalls, wantCalls) {
+		t.Errorf("sbc0.TwoPCCalls: %v, want %v", sbc0.TwoPCCalls, wantCalls)
+	}
+	wantCalls = []string{
+		"Prepare TestScatterConnCommit2PC:0:2",
+		"RollbackPrepared TestScatterConnCommit2PC:0:2",
+	}
+	for _, sbc := range []*sandboxConn{sbc1, sbc2} {
+		if !reflect.DeepEqual(sbc.TwoPCCalls, wantCalls) {
+			t.Errorf("TwoPCCalls: %v, want %v", sbc.TwoPCCalls, wantCalls)
+		}
+	}
+	if sbc0.RollbackCount.Get() != 1 || sbc1.RollbackCount.Get() != 0 || sbc2.RollbackCount.Get() != 1 {
+		t.Errorf("RollbackCount: %d, %d, %d, want 1, 0, 1", sbc0.RollbackCount.Get(), sbc1.RollbackCount.Get(), sbc2.RollbackCount.Get())
+	}
+}
+
+func TestScatterConnReadYourWrites(t *testing.T) {
+	keyspace := "TestScatterConnReadYourWrites"
+	s := createSandbox(keyspace)
+	sbc := &sandboxConn{Position: "pos1"}
+	s.MapTestConn("0", sbc)
+	stc := NewScatterConn(new(sandboxTopo), "", "aa", 1*time.Millisecond, 3, 2*time.Millisecond, 1*time.Millisecond, 24*time.Hour)
+	ctx := context.Background()
+
+	session := NewSafeSession(&proto.Session{InTransaction: true, ReadYourWrites: true})
+	stc.Execute(ctx, "query1", nil, keyspace, []string{"0"}, topo.TYPE_MASTER, session, false)
+	if err := stc.Commit(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	wantPositions := []*proto.ShardPosition{{Keyspace: keyspace, Shard: "0", Position: "pos1"}}
+	if !reflect.DeepEqual(session.ShardPositions, wantPositions) {
+		t.Errorf("ShardPositions: %v, want %v", session.ShardPositions, wantPositions)
+	}
+
+	testcases := []struct {
+		desc       string
+		position   string
+		replicated string
+		staleness  int64
+		waits      int64
+		fallbackTo string
+	}{
+		{"position replicated", "pos1", "pos1", 0, 1, ""},
+		{"position not replicated", "pos1", "pos0", 0, 1, "Position"},
+		{"position unknown", "", "pos1", 0, 0, "UnknownPosition"},
+		{"lag unknown", "pos1", "pos1", 10, 0, "Staleness"},
+	}
+	for _, tc := range testcases {
+		sbc.Position = tc.replicated
+		session.SetPosition(keyspace, "0", tc.position)
+		session.MaxStalenessSeconds = tc.staleness
+		sbc.WaitMasterPosCount.Set(0)
+		before := masterFallbacks.Counts()
+		if _, err := stc.Execute(ctx, "query1", nil, keyspace, []string{"0"}, topo.TYPE_REPLICA, session, false); err != nil {
+			t.Errorf("%s: %v", tc.desc, err)
+		}
+		if got := sbc.WaitMasterPosCount.Get(); got != tc.waits {
+			t.Errorf("%s: WaitMasterPosCount: %d, want %d", tc.desc, got, tc.waits)
+		}
+		after := masterFallbacks.Counts()
+		for _, reason := range []string{"Position", "UnknownPosition", "Staleness"} {
+			want := before[reason]
+			if reason == tc.fallbackTo {
+				want++
+			}
+			if after[reason] != want {
+				t.Errorf("%s: %s fallbacks: %d, want %d", tc.desc, reason, after[reason], want)
+			}
+		}
+	}
+
+	// The ordered streams wait for the position too.
+	sbc.Position = "pos1"
+	session.SetPosition(keyspace, "0", "pos1")
+	session.MaxStalenessSeconds = 0
+	sbc.WaitMasterPosCount.Set(0)
+	shardVars := map[string]map[string]interface{}{"0": nil}
+	orderBy := []planbuilder.OrderByCol{{Index: 0}}
+	if err := stc.StreamExecuteMulti(ctx, "query1", keyspace, shardVars, topo.TYPE_REPLICA, session, orderBy, func(*mproto.QueryResult) error { return nil }, false); err != nil {
+		t.Error(err)
+	}
+	if got := sbc.WaitMasterPosCount.Get(); got != 1 {
+		t.Errorf("ordered stream: WaitMasterPosCount: %d, want 1", got)
+	}
+
+	// The writes outside of transactions are autocommitted: their
+	// positions are recorded too, but not the ones of the replicas.
+	autocommit := NewSafeSession(&proto.Session{ReadYourWrites: true})
+	sbc.Position = "pos2"
+	stc.Execute(ctx, "query1", nil, keyspace, []string{"0"}, topo.TYPE_REPLICA, autocommit, false)
+	if autocommit.ShardPositions != nil {
+		t.Errorf("ShardPositions: %v, want none", autocommit.ShardPositions)
+	}
+	stc.Execute(ctx, "query1", nil, keyspace, []string{"0"}, topo.TYPE_MASTER, autocommit, false)
+	wantPositions = []*proto.ShardPosition{{Keyspace: keyspace, Shard: "0", Position: "pos2"}}
+	if !reflect.DeepEqual(autocommit.ShardPositions, wantPositions) {
+		t.Errorf("ShardPositions: %v, want %v", autocommit.ShardPositions, wantPositions)
+	}
+	autocommit = NewSafeSession(&proto.Session{})
+	stc.Execute(ctx, "query1", nil, keyspace, []string{"0"}, topo.TYPE_MASTER, autocommit, false)
+	if autocommit.ShardPositions != nil {
+		t.Errorf("ShardPositions: %v, want none without ReadYourWrites", autocommit.ShardPositions)
+	}
+}
+
 func TestScatterConnRollback(t *testing.T) {
 	s := createSandbox("TestScatterConnRollback")
 	sbc0 := &sandboxConn{}
@@ -0,0 +1,169 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vtgate
+
+// This is a V3 file. Do not intermix with V2.
+
+import (
+	"fmt"
+	"sync"
+
+	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/stats"
+	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
+	"github.com/youtube/vitess/go/vt/vtgate/proto"
+	"golang.org/x/net/context"
+)
+
+var sequenceReserves = stats.NewCounters("SequenceReserves")
+
+// sequenceCache serves the values of the sequence tables from
+// memory. The values are reserved from the tables in blocks, whose
+// size is the cache column of each table. The unused values of a
+// block are lost if VTGate restarts, so a sequence can have gaps.
+type sequenceCache struct {
+	mu     sync.Mutex
+	blocks map[string]*sequenceBlock
+}
+
+// sequenceBlock holds the reserved values of a sequence
+// that are not used yet. They're from next to limit-1.
+type sequenceBlock struct {
+	mu          sync.Mutex
+	next, limit int64
+}
+
+func newSequenceCache() *sequenceCache {
+	return &sequenceCache{blocks: make(map[string]*sequenceBlock)}
+}
+
+func (sc *sequenceCache) block(name string) *sequenceBlock {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	block, ok := sc.blocks[name]
+	if !ok {
+		block = &sequenceBlock{}
+		sc.blocks[name] = block
+	}
+	return block
+}
+
+// Next returns the next value of the sequence name. If its block
+// is used up, reserve is called to reserve the next one. Other
+// callers of the same sequence wait until it returns.
+func (sc *sequenceCache) Next(name string, reserve func() (next, limit int64, err error)) (int64, error) {
+	block := sc.block(name)
+	block.mu.Lock()
+	defer block.mu.Unlock()
+	if block.next >= block.limit {
+		next, limit, err := reserve()
+		if err != nil {
+			return 0, err
+		}
+		block.next, block.limit = next, limit
+		sequenceReserves.Add(name, 1)
+	}
+	value := block.next
+	block.next++
+	return value, nil
+}
+
+// nextSequenceValue returns the next value of the sequence table name.
+func (rtr *Router) nextSequenceValue(ctx context.Context, name string) (int64, error) {
+	if rtr.planner.schema == nil {
+		return 0, fmt.Errorf("nextSequenceValue: no schema")
+	}
+	table, reason := rtr.planner.schema.FindTable(name)
+	if reason != "" {
+		return 0, fmt.Errorf("nextSequenceValue: %s", reason)
+	}
+	if !table.IsSequence {
+		return 0, fmt.Errorf("nextSequenceValue: %s is not a sequence table", name)
+	}
+	value, err := rtr.sequences.Next(name, func() (int64, int64, error) {
+		return rtr.reserveSequenceBlock(ctx, table)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("nextSequenceValue: %v", err)
+	}
+	return value, nil
+}
+
+// reserveSequenceBlock reserves the next block of values of a sequence
+// table by advancing its next_id by its cache. It uses a transaction of
+// its own on the master, so that the block remains reserved even if
+// the transaction of the caller is rolled back.
+func (rtr *Router) reserveSequenceBlock(ctx context.Context, table *planbuilder.Table) (next, limit int64, err error) {
+	ks, _, allShards, err := getKeyspaceShards(ctx, rtr.serv, rtr.cell, table.Keyspace.Name, topo.TYPE_MASTER)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(allShards) != 1 {
+		return 0, 0, fmt.Errorf("unsharded keyspace %s has multiple shards", ks)
+	}
+	shards := []string{allShards[0].Name}
+	session := NewSafeSession(&proto.Session{InTransaction: true})
+	defer func() {
+		if err != nil {
+			rtr.scatterConn.Rollback(ctx, session)
+		}
+	}()
+	qr, err := rtr.scatterConn.Execute(
+		ctx,
+		fmt.Sprintf("select next_id, cache from %s where id = 0 for update", table.Name),
+		map[string]interface{}{},
+		ks,
+		shards,
+		topo.TYPE_MASTER,
+		session,
+		false)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(qr.Rows) != 1 || len(qr.Fields) != 2 {
+		return 0, 0, fmt.Errorf("sequence table %s has no row with id 0", table.Name)
+	}
+	if next, err = sequenceColumn(qr, 0); err != nil {
+		return 0, 0, err
+	}
+	cache, err := sequenceColumn(qr, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	if cache < 1 {
+		return 0, 0, fmt.Errorf("cache of sequence table %s is %d, must be positive", table.Name, cache)
+	}
+	_, err = rtr.scatterConn.Execute(
+		ctx,
+		fmt.Sprintf("update %s set next_id = :next_id where id = 0", table.Name),
+		map[string]interface{}{"next_id": next + cache},
+		ks,
+		shards,
+		topo.TYPE_MASTER,
+		session,
+		false)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err = rtr.scatterConn.Commit(ctx, session); err != nil {
+		return 0, 0, err
+	}
+	return next, next + cache, nil
+}
+
+func sequenceColumn(qr *mproto.QueryResult, col int) (int64, error) {
+	val, err := mproto.Convert(qr.Fields[col], qr.Rows[0][col])
+	if err != nil {
+		return 0, err
+	}
+	switch val := val.(type) {
+	case int64:
+		return val, nil
+	case uint64:
+		return int64(val), nil
+	}
+	return 0, fmt.Errorf("unexpected type for %s: %T", qr.Fields[col].Name, val)
+}
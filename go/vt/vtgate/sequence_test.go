@@ -0,0 +1,127 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vtgate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/sqltypes"
+	tproto "github.com/youtube/vitess/go/vt/tabletserver/proto"
+	"golang.org/x/net/context"
+)
+
+func sequenceResult(next, cache string) *mproto.QueryResult {
+	return &mproto.QueryResult{
+		Fields: []mproto.Field{
+			{"next_id", mproto.VT_LONGLONG, 0},
+			{"cache", mproto.VT_LONGLONG, 0},
+		},
+		RowsAffected: 1,
+		Rows: [][]sqltypes.Value{{
+			{sqltypes.Numeric(next)},
+			{sqltypes.Numeric(cache)},
+		}},
+	}
+}
+
+func TestSequence(t *testing.T) {
+	router, sbc1, sbc2, sbclookup := createRouterEnv()
+
+	sbclookup.setResults([]*mproto.QueryResult{sequenceResult("1", "2")})
+	for i := 1; i <= 2; i++ {
+		result, err := routerExec(router, "insert into seq_table(id, v) values (null, 1)", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.InsertId != uint64(i) {
+			t.Errorf("InsertId: %d, want %d", result.InsertId, i)
+		}
+	}
+	wantQueries := []tproto.BoundQuery{{
+		Sql:           "select next_id, cache from id_seq where id = 0 for update",
+		BindVariables: map[string]interface{}{},
+	}, {
+		Sql: "update id_seq set next_id = :next_id where id = 0",
+		BindVariables: map[string]interface{}{
+			"next_id": int64(3),
+		},
+	}}
+	if !reflect.DeepEqual(sbclookup.Queries, wantQueries) {
+		t.Errorf("sbclookup.Queries: %+v, want %+v\n", sbclookup.Queries, wantQueries)
+	}
+	if sbclookup.BeginCount.Get() != 1 || sbclookup.CommitCount.Get() != 1 {
+		t.Errorf("begin, commit: %d, %d, want 1, 1", sbclookup.BeginCount.Get(), sbclookup.CommitCount.Get())
+	}
+	if len(sbc1.Queries) != 2 {
+		t.Errorf("sbc1.Queries: %+v, want 2 queries", sbc1.Queries)
+	}
+
+	// The block is used up: the next value reserves another one.
+	sbclookup.Queries = nil
+	sbclookup.setResults([]*mproto.QueryResult{sequenceResult("3", "10")})
+	result, err := routerExec(router, "insert into seq_table(id, v) values (null, 1)", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.InsertId != 3 {
+		t.Errorf("InsertId: %d, want 3", result.InsertId)
+	}
+	if len(sbclookup.Queries) != 2 || sbclookup.CommitCount.Get() != 2 {
+		t.Errorf("sbclookup.Queries: %+v, commits: %d, want 2 queries and 2 commits", sbclookup.Queries, sbclookup.CommitCount.Get())
+	}
+	wantQueries = []tproto.BoundQuery{{
+		Sql: "insert into seq_table(id, v) values (:_id_0, 1) /* _routing keyspace_id:4eb190c9a2fa169c */",
+		BindVariables: map[string]interface{}{
+			"keyspace_id": "N\xb1\x90ɢ\xfa\x16\x9c",
+			"_id_0":       int64(3),
+		},
+	}}
+	if !reflect.DeepEqual(sbc2.Queries, wantQueries) {
+		t.Errorf("sbc2.Queries: %+v, want %+v\n", sbc2.Queries, wantQueries)
+	}
+}
+
+func TestSequenceFail(t *testing.T) {
+	router, _, _, sbclookup := createRouterEnv()
+
+	_, err := router.nextSequenceValue(context.Background(), "user_idx")
+	want := "nextSequenceValue: user_idx is not a sequence table"
+	if err == nil || err.Error() != want {
+		t.Errorf("nextSequenceValue: %v, want %v", err, want)
+	}
+
+	_, err = router.nextSequenceValue(context.Background(), "noexist")
+	want = "nextSequenceValue: table noexist not found"
+	if err == nil || err.Error() != want {
+		t.Errorf("nextSequenceValue: %v, want %v", err, want)
+	}
+
+	sbclookup.setResults([]*mproto.QueryResult{&mproto.QueryResult{}})
+	_, err = router.nextSequenceValue(context.Background(), "id_seq")
+	want = "nextSequenceValue: sequence table id_seq has no row with id 0"
+	if err == nil || err.Error() != want {
+		t.Errorf("nextSequenceValue: %v, want %v", err, want)
+	}
+	if sbclookup.RollbackCount.Get() != 1 {
+		t.Errorf("RollbackCount: %d, want 1", sbclookup.RollbackCount.Get())
+	}
+
+	sbclookup.setResults([]*mproto.QueryResult{sequenceResult("1", "0")})
+	_, err = router.nextSequenceValue(context.Background(), "id_seq")
+	want = "nextSequenceValue: cache of sequence table id_seq is 0, must be positive"
+	if err == nil || err.Error() != want {
+		t.Errorf("nextSequenceValue: %v, want %v", err, want)
+	}
+
+	sbclookup.mustFailServer = 1
+	_, err = routerExec(router, "insert into seq_table(id, v) values (null, 1)", nil)
+	want = "execInsertSharded: hash.Generate: nextSequenceValue: "
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("routerExec: %v, want prefix %v", err, want)
+	}
+}
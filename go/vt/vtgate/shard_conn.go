@@ -36,11 +36,15 @@ type ShardConn struct {
 	connTimeoutPerConn time.Duration
 	connLife           time.Duration
 	balancer           *Balancer
+	buffer             *masterBuffer
 	consolidator       *sync2.Consolidator
 	ticker             *timer.RandTicker
 
 	connectTimings *stats.MultiTimings
 
+	// parent is the ShardConn a pinned ShardConn got its conn from.
+	parent *ShardConn
+
 	// conn needs a mutex because it can change during the lifetime of ShardConn.
 	mu   sync.Mutex
 	conn tabletconn.TabletConn
@@ -57,7 +61,12 @@ func NewShardConn(ctx context.Context, serv SrvTopoServer, cell, keyspace, shard
 		}
 		return endpoints, nil
 	}
-	blc := NewBalancer(getAddresses, retryDelay)
+	var blc *Balancer
+	if hc := getHealthCache(); hc != nil && tabletType != topo.TYPE_MASTER {
+		blc = NewHealthBalancer(getAddresses, retryDelay, hc, *maxReplicationLag)
+	} else {
+		blc = NewBalancer(getAddresses, retryDelay)
+	}
 	var ticker *timer.RandTicker
 	if tabletType != topo.TYPE_MASTER {
 		ticker = timer.NewRandTicker(connLife, connLife/2)
@@ -76,6 +85,9 @@ func NewShardConn(ctx context.Context, serv SrvTopoServer, cell, keyspace, shard
 		consolidator:       sync2.NewConsolidator(),
 		connectTimings:     tabletConnectTimings,
 	}
+	if tabletType == topo.TYPE_MASTER && *enableMasterBuffer {
+		sdc.buffer = newMasterBuffer(keyspace, shard, getAddresses, *masterBufferSize, *masterBufferMaxWait, retryDelay)
+	}
 	if ticker != nil {
 		go func() {
 			for range ticker.C {
@@ -175,6 +187,120 @@ func (sdc *ShardConn) Rollback(ctx context.Context, transactionID int64) (err er
 	}, transactionID, false)
 }
 
+// Prepare prepares the transaction for a two-phase commit as dtid.
+// There are no retries, like for Commit.
+func (sdc *ShardConn) Prepare(ctx context.Context, transactionID int64, dtid string) (err error) {
+	return sdc.withRetry(ctx, func(conn tabletconn.TabletConn) error {
+		return conn.Prepare(ctx, transactionID, dtid)
+	}, transactionID, false)
+}
+
+// CommitPrepared commits the prepared transaction dtid. It's
+// idempotent, so the retry rules are the same as Execute.
+func (sdc *ShardConn) CommitPrepared(ctx context.Context, dtid string) (err error) {
+	return sdc.withRetry(ctx, func(conn tabletconn.TabletConn) error {
+		return conn.CommitPrepared(ctx, dtid)
+	}, 0, false)
+}
+
+// RollbackPrepared rolls back the prepared transaction dtid. It's
+// idempotent, so the retry rules are the same as Execute.
+func (sdc *ShardConn) RollbackPrepared(ctx context.Context, dtid string) (err error) {
+	return sdc.withRetry(ctx, func(conn tabletconn.TabletConn) error {
+		return conn.RollbackPrepared(ctx, dtid)
+	}, 0, false)
+}
+
+// CreateTransaction records the distributed transaction dtid in the
+// coordinator. The retry rules are the same as Execute.
+func (sdc *ShardConn) CreateTransaction(ctx context.Context, dtid string, participants []tproto.DTParticipant) (err error) {
+	return sdc.withRetry(ctx, func(conn tabletconn.TabletConn) error {
+		return conn.CreateTransaction(ctx, dtid, participants)
+	}, 0, false)
+}
+
+// StartCommit records the commit decision of dtid in the coordinator,
+// and commits the transaction. There are no retries, like for Commit.
+func (sdc *ShardConn) StartCommit(ctx context.Context, transactionID int64, dtid string) (err error) {
+	return sdc.withRetry(ctx, func(conn tabletconn.TabletConn) error {
+		return conn.StartCommit(ctx, transactionID, dtid)
+	}, transactionID, false)
+}
+
+// ConcludeTransaction forgets dtid in the coordinator. It's
+// idempotent, so the retry rules are the same as Execute.
+func (sdc *ShardConn) ConcludeTransaction(ctx context.Context, dtid string) (err error) {
+	return sdc.withRetry(ctx, func(conn tabletconn.TabletConn) error {
+		return conn.ConcludeTransaction(ctx, dtid)
+	}, 0, false)
+}
+
+// MasterPosition returns the replication position of the tablet.
+// The retry rules are the same as Execute.
+func (sdc *ShardConn) MasterPosition(ctx context.Context) (position string, err error) {
+	err = sdc.withRetry(ctx, func(conn tabletconn.TabletConn) error {
+		var innerErr error
+		position, innerErr = conn.MasterPosition(ctx)
+		return innerErr
+	}, 0, false)
+	return position, err
+}
+
+// WaitMasterPos waits until a tablet has replicated position, or
+// until waitTimeout. The retry rules are the same as Execute. It
+// returns a ShardConn that sends its requests to that tablet.
+func (sdc *ShardConn) WaitMasterPos(ctx context.Context, position string, waitTimeout time.Duration) (*ShardConn, error) {
+	var replicated tabletconn.TabletConn
+	err := sdc.withRetry(ctx, func(conn tabletconn.TabletConn) error {
+		replicated = conn
+		return conn.WaitMasterPos(ctx, position, waitTimeout)
+	}, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	return sdc.pin(replicated), nil
+}
+
+// pin returns a ShardConn that sends its requests to conn only,
+// which is a connection of sdc. They are not retried, as another
+// tablet may not have replicated what conn's tablet did. Its errors
+// mark conn down in sdc.
+func (sdc *ShardConn) pin(conn tabletconn.TabletConn) *ShardConn {
+	return &ShardConn{
+		keyspace:       sdc.keyspace,
+		shard:          sdc.shard,
+		tabletType:     sdc.tabletType,
+		retryDelay:     sdc.retryDelay,
+		consolidator:   sync2.NewConsolidator(),
+		connectTimings: sdc.connectTimings,
+		parent:         sdc,
+		conn:           conn,
+	}
+}
+
+// ReplicationLag returns the replication lag of the tablet the
+// requests are sent to, as reported by its health stream. ok is false
+// if it's unknown, for instance because -tablet_health_stream is off.
+func (sdc *ShardConn) ReplicationLag(ctx context.Context) (lag time.Duration, ok bool) {
+	hc := getHealthCache()
+	if hc == nil {
+		return 0, false
+	}
+	var endPoint topo.EndPoint
+	err := sdc.withRetry(ctx, func(conn tabletconn.TabletConn) error {
+		endPoint = conn.EndPoint()
+		return nil
+	}, 0, false)
+	if err != nil {
+		return 0, false
+	}
+	stats := hc.Get(endPoint)
+	if stats == nil {
+		return 0, false
+	}
+	return time.Duration(stats.SecondsBehindMaster) * time.Second, true
+}
+
 // SplitQuery splits a query into sub queries. The retry rules are the same as Execute.
 func (sdc *ShardConn) SplitQuery(ctx context.Context, query tproto.BoundQuery, splitColumn string, splitCount int) (queries []tproto.QuerySplit, err error) {
 	err = sdc.withRetry(ctx, func(conn tabletconn.TabletConn) error {
@@ -185,11 +311,23 @@ func (sdc *ShardConn) SplitQuery(ctx context.Context, query tproto.BoundQuery, s
 	return
 }
 
+// SplitQueryV2 splits a query into sub queries on ranges of its
+// primary key. The retry rules are the same as Execute.
+func (sdc *ShardConn) SplitQueryV2(ctx context.Context, query tproto.BoundQuery, splitColumns []string, splitCount int, numRowsPerQueryPart int64) (queries []tproto.QuerySplit, err error) {
+	err = sdc.withRetry(ctx, func(conn tabletconn.TabletConn) error {
+		var innerErr error
+		queries, innerErr = conn.SplitQueryV2(ctx, query, splitColumns, splitCount, numRowsPerQueryPart)
+		return innerErr
+	}, 0, false)
+	return
+}
+
 // Close closes the underlying TabletConn.
 func (sdc *ShardConn) Close() {
 	if sdc.ticker != nil {
 		sdc.ticker.Stop()
 	}
+	sdc.balancer.Close()
 	sdc.closeCurrent()
 }
 
@@ -211,17 +349,29 @@ func (sdc *ShardConn) closeCurrent() {
 // it retries retryCount times before failing. It does not retry if the connection is in
 // the middle of a transaction. While returning the error check if it maybe a result of
 // a resharding event, and set the re-resolve bit and let the upper layers
-// re-resolve and retry.
+// re-resolve and retry. If the master buffer is enabled, an action on a
+// master that still fails after its retries is held during the failover
+// of the master, and replayed once against the new master.
 func (sdc *ShardConn) withRetry(ctx context.Context, action func(conn tabletconn.TabletConn) error, transactionID int64, isStreaming bool) error {
+	endPoint, retryable, err := sdc.retry(ctx, action, transactionID, isStreaming)
+	if err != nil && retryable && sdc.buffer != nil && sdc.buffer.wait(ctx) {
+		// The failover is over: replay the action
+		// against the new master.
+		endPoint, _, err = sdc.retry(ctx, action, transactionID, isStreaming)
+	}
+	return sdc.WrapError(err, endPoint, transactionID != 0)
+}
+
+// retry executes action up to retryCount+1 times. retryable is true
+// if action could have been retried more.
+func (sdc *ShardConn) retry(ctx context.Context, action func(conn tabletconn.TabletConn) error, transactionID int64, isStreaming bool) (endPoint topo.EndPoint, retryable bool, err error) {
 	var conn tabletconn.TabletConn
-	var endPoint topo.EndPoint
-	var err error
 	var isTimeout bool
-	inTransaction := (transactionID != 0)
 	// execute the action at least once even without retrying
 	for i := 0; i < sdc.retryCount+1; i++ {
 		conn, endPoint, isTimeout, err = sdc.getConn(ctx)
 		if err != nil {
+			retryable = transactionID == 0
 			if isTimeout || i == sdc.retryCount {
 				break
 			}
@@ -229,12 +379,13 @@ func (sdc *ShardConn) withRetry(ctx context.Context, action func(conn tabletconn
 			continue
 		}
 		err = action(conn)
-		if sdc.canRetry(ctx, err, transactionID, conn, isStreaming) {
+		retryable = sdc.canRetry(ctx, err, transactionID, conn, isStreaming)
+		if retryable {
 			continue
 		}
 		break
 	}
-	return sdc.WrapError(err, endPoint, inTransaction)
+	return endPoint, retryable, err
 }
 
 type connectResult struct {
@@ -298,6 +449,9 @@ func (sdc *ShardConn) getNewConn(ctx context.Context) (conn tabletconn.TabletCon
 		conn, err = tabletconn.GetDialer()(ctx, endPoint, sdc.keyspace, sdc.shard, perConnTimeout)
 		if err == nil {
 			sdc.connectTimings.Record([]string{sdc.keyspace, sdc.shard, string(sdc.tabletType)}, perConnStartTime)
+			if sdc.buffer != nil {
+				sdc.buffer.setMaster(endPoint)
+			}
 			sdc.mu.Lock()
 			defer sdc.mu.Unlock()
 			sdc.conn = conn
@@ -372,6 +526,10 @@ func (sdc *ShardConn) canRetry(ctx context.Context, err error, transactionID int
 // markDown closes conn and temporarily marks the associated
 // end point as unusable.
 func (sdc *ShardConn) markDown(conn tabletconn.TabletConn, reason string) {
+	if sdc.parent != nil {
+		sdc.parent.markDown(conn, reason)
+		return
+	}
 	sdc.mu.Lock()
 	defer sdc.mu.Unlock()
 	if conn != sdc.conn {
@@ -789,3 +789,115 @@ func TestMasterShardConnLife(t *testing.T) {
 	}
 	sdc.Close()
 }
+
+func TestShardConnWaitMasterPos(t *testing.T) {
+	s := createSandbox("TestShardConnWaitMasterPos")
+	sbc := &sandboxConn{Position: "pos1"}
+	s.MapTestConn("0", sbc)
+	sdc := NewShardConn(context.Background(), new(sandboxTopo), "aa", "TestShardConnWaitMasterPos", "0", topo.TYPE_REPLICA, 1*time.Millisecond, 3, connTimeoutTotal, connTimeoutPerConn, 24*time.Hour, connectTimings)
+	defer sdc.Close()
+
+	if _, err := sdc.WaitMasterPos(context.Background(), "pos2", time.Second); err == nil {
+		t.Errorf("WaitMasterPos for an unreplicated position: nil, want error")
+	}
+	pinned, err := sdc.WaitMasterPos(context.Background(), "pos1", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The pinned ShardConn keeps its connection when sdc moves to
+	// another one.
+	dials := s.DialCounter
+	sdc.closeCurrent()
+	sbc.ExecCount.Set(0)
+	if _, err := pinned.Execute(context.Background(), "query", nil, 0); err != nil {
+		t.Error(err)
+	}
+	if sbc.ExecCount.Get() != 1 {
+		t.Errorf("ExecCount: %d, want 1", sbc.ExecCount.Get())
+	}
+	if s.DialCounter != dials {
+		t.Errorf("DialCounter: %d, want %d", s.DialCounter, dials)
+	}
+
+	// Its errors aren't retried, and mark the connection down in sdc.
+	pinned, err = sdc.WaitMasterPos(context.Background(), "pos1", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sbc.mustFailRetry = 1
+	sbc.ExecCount.Set(0)
+	if _, err := pinned.Execute(context.Background(), "query", nil, 0); err == nil {
+		t.Errorf("Execute: nil, want error")
+	}
+	if sbc.ExecCount.Get() != 1 {
+		t.Errorf("ExecCount: %d, want 1", sbc.ExecCount.Get())
+	}
+	sdc.mu.Lock()
+	conn := sdc.conn
+	sdc.mu.Unlock()
+	if conn != nil {
+		t.Errorf("sdc.conn: %v, want nil", conn)
+	}
+}
+
+func TestShardConnMasterBuffer(t *testing.T) {
+	*enableMasterBuffer = true
+	defer func() { *enableMasterBuffer = false }()
+	retryDelay := 5 * time.Millisecond
+	s := createSandbox("TestShardConnMasterBuffer")
+	sbc0 := &sandboxConn{}
+	s.MapTestConn("0", sbc0)
+	sdc := NewShardConn(context.Background(), new(sandboxTopo), "aa", "TestShardConnMasterBuffer", "0", topo.TYPE_MASTER, retryDelay, 2, connTimeoutTotal, connTimeoutPerConn, 24*time.Hour, connectTimings)
+	if _, err := sdc.Execute(context.Background(), "query", nil, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The master stops serving, and a new one takes over later.
+	sbc0.mustFailRetry = 1000
+	sbc1 := &sandboxConn{}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		s.MapTestConn("0", sbc1)
+		s.DeleteTestConn("0", sbc0)
+	}()
+	if _, err := sdc.Execute(context.Background(), "query", nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	if sbc1.ExecCount.Get() != 1 {
+		t.Errorf("want 1, got %v", sbc1.ExecCount.Get())
+	}
+
+	// Transactions are not held.
+	sbc1.mustFailRetry = 1
+	if _, err := sdc.Execute(context.Background(), "query", nil, 1); err == nil {
+		t.Errorf("want error, got nil")
+	}
+
+	// Without a new master, the held requests fail after maxWait.
+	sdc.buffer.maxWait = 20 * time.Millisecond
+	sbc1.mustFailRetry = 1000
+	timedOut := "TestShardConnMasterBuffer.0.FailoverTimedOut"
+	timeouts := masterBufferEvents.Counts()[timedOut]
+	if _, err := sdc.Execute(context.Background(), "query", nil, 0); err == nil {
+		t.Errorf("want error, got nil")
+	}
+	if got := masterBufferEvents.Counts()[timedOut] - timeouts; got != 1 {
+		t.Errorf("FailoverTimedOut: %v, want 1", got)
+	}
+
+	// Requests above the size of the buffer fail.
+	sdc.buffer.size = 0
+	if _, err := sdc.Execute(context.Background(), "query", nil, 0); err == nil {
+		t.Errorf("want error, got nil")
+	}
+
+	// Requests are not held if the old master is unknown.
+	sdc = NewShardConn(context.Background(), new(sandboxTopo), "aa", "TestShardConnMasterBuffer", "0", topo.TYPE_MASTER, retryDelay, 2, connTimeoutTotal, connTimeoutPerConn, 24*time.Hour, connectTimings)
+	if sdc.buffer.wait(context.Background()) {
+		t.Errorf("wait: true, want false")
+	}
+	if sdc.buffer.failover != nil {
+		t.Errorf("a failover was started")
+	}
+}
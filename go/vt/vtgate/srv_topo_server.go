@@ -24,6 +24,7 @@ var (
 	srvTopoCacheTTL    = flag.Duration("srv_topo_cache_ttl", 1*time.Second, "how long to use cached entries for topology")
 	enableRemoteMaster = flag.Bool("enable_remote_master", false, "enable remote master access")
 	srvTopoTimeout     = flag.Duration("srv_topo_timeout", 2*time.Second, "topo server timeout")
+	srvTopoWatch       = flag.Bool("srv_topo_watch", true, "keep the cached SrvKeyspace, SrvShard and EndPoints up to date with topology watches. The entries that can't be watched are refreshed after srv_topo_cache_ttl")
 )
 
 const (
@@ -32,6 +33,8 @@ const (
 	errorCategory       = "error"
 	remoteQueryCategory = "remote-query"
 	remoteErrorCategory = "remote-error"
+	watchCategory       = "watch"
+	watchErrorCategory  = "watch-error"
 )
 
 // SrvTopoServer is a subset of topo.Server that only contains the serving
@@ -50,12 +53,20 @@ type SrvTopoServer interface {
 // on a topo.Server that uses a cache for two purposes:
 // - limit the QPS to the underlying topo.Server
 // - return the last known value of the data if there is an error
+// The SrvKeyspace, SrvShard and EndPoints entries are watched after
+// their first query, and are updated by the watch notifications. They
+// expire after cacheTTL only when the watch can't provide their value.
 type ResilientSrvTopoServer struct {
 	topoServer         topo.Server
 	cacheTTL           time.Duration
 	enableRemoteMaster bool
+	watch              bool
 	counts             *stats.Counters
 
+	// ctx is the context of the watches. It's canceled by Close.
+	ctx    context.Context
+	cancel context.CancelFunc
+
 	// mutex protects the cache map itself, not the individual
 	// values in the cache.
 	mutex                 sync.Mutex
@@ -124,6 +135,8 @@ type srvKeyspaceEntry struct {
 	value         *topo.SrvKeyspace
 	lastError     error
 	lastErrorCtx  context.Context
+
+	entryWatch
 }
 
 type srvShardEntry struct {
@@ -139,6 +152,8 @@ type srvShardEntry struct {
 	value         *topo.SrvShard
 	lastError     error
 	lastErrorCtx  context.Context
+
+	entryWatch
 }
 
 type endPointsEntry struct {
@@ -161,6 +176,20 @@ type endPointsEntry struct {
 	originalValue *topo.EndPoints
 	lastError     error
 	lastErrorCtx  context.Context
+
+	entryWatch
+}
+
+// entryWatch is the watch state of a cache entry. It's protected by
+// the mutex of the entry.
+type entryWatch struct {
+	// started is set while the entry is watched, or its watch is
+	// being started.
+	started bool
+	// watching is set while the watch keeps the value up to date.
+	watching bool
+	// stop stops the watch.
+	stop chan<- struct{}
 }
 
 func endPointIsHealthy(ep topo.EndPoint) bool {
@@ -199,11 +228,15 @@ func filterUnhealthyServers(endPoints *topo.EndPoints) *topo.EndPoints {
 // NewResilientSrvTopoServer creates a new ResilientSrvTopoServer
 // based on the provided SrvTopoServer.
 func NewResilientSrvTopoServer(base topo.Server, counterPrefix string) *ResilientSrvTopoServer {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &ResilientSrvTopoServer{
 		topoServer:         base,
 		cacheTTL:           *srvTopoCacheTTL,
 		enableRemoteMaster: *enableRemoteMaster,
+		watch:              *srvTopoWatch,
 		counts:             stats.NewCounters(counterPrefix + "Counts"),
+		ctx:                ctx,
+		cancel:             cancel,
 
 		srvKeyspaceNamesCache: make(map[string]*srvKeyspaceNamesEntry),
 		srvKeyspaceCache:      make(map[string]*srvKeyspaceEntry),
@@ -288,14 +321,15 @@ func (server *ResilientSrvTopoServer) GetSrvKeyspace(ctx context.Context, cell,
 	entry.mutex.Lock()
 	defer entry.mutex.Unlock()
 
-	// If the entry is fresh enough, return it
-	if time.Now().Sub(entry.insertionTime) < server.cacheTTL {
+	// If the entry is watched or fresh enough, return it
+	if entry.watching || time.Now().Sub(entry.insertionTime) < server.cacheTTL {
 		return entry.value, entry.lastError
 	}
 
 	// not in cache or too old, get the real value
 	newCtx, cancel := context.WithTimeout(context.Background(), *srvTopoTimeout)
 	defer cancel()
+	defer server.watchSrvKeyspace(entry)
 
 	result, err := server.topoServer.GetSrvKeyspace(newCtx, cell, keyspace)
 	if err != nil {
@@ -341,14 +375,15 @@ func (server *ResilientSrvTopoServer) GetSrvShard(ctx context.Context, cell, key
 	entry.mutex.Lock()
 	defer entry.mutex.Unlock()
 
-	// If the entry is fresh enough, return it
-	if time.Now().Sub(entry.insertionTime) < server.cacheTTL {
+	// If the entry is watched or fresh enough, return it
+	if entry.watching || time.Now().Sub(entry.insertionTime) < server.cacheTTL {
 		return entry.value, entry.lastError
 	}
 
 	// not in cache or too old, get the real value
 	newCtx, cancel := context.WithTimeout(context.Background(), *srvTopoTimeout)
 	defer cancel()
+	defer server.watchSrvShard(entry)
 
 	result, err := server.topoServer.GetSrvShard(newCtx, cell, keyspace, shard)
 	if err != nil {
@@ -423,8 +458,8 @@ func (server *ResilientSrvTopoServer) GetEndPoints(ctx context.Context, cell, ke
 		}
 	}()
 
-	// If the entry is fresh enough, return it
-	if time.Now().Sub(entry.insertionTime) < server.cacheTTL {
+	// If the entry is watched or fresh enough, return it
+	if entry.watching || time.Now().Sub(entry.insertionTime) < server.cacheTTL {
 		server.endPointCounters.cacheHits.Add(key, 1)
 		remote = entry.remote
 		return entry.value, -1, entry.lastError
@@ -433,6 +468,7 @@ func (server *ResilientSrvTopoServer) GetEndPoints(ctx context.Context, cell, ke
 	// not in cache or too old, get the real value
 	newCtx, cancel := context.WithTimeout(context.Background(), *srvTopoTimeout)
 	defer cancel()
+	defer server.watchEndPoints(entry)
 
 	result, _, err = server.topoServer.GetEndPoints(newCtx, cell, keyspace, shard, tabletType)
 	// get remote endpoints for master if enabled
@@ -476,6 +512,158 @@ func (server *ResilientSrvTopoServer) GetEndPoints(ctx context.Context, cell, ke
 	return entry.value, -1, err
 }
 
+// Close stops the watches of the cache entries. They're refreshed
+// after cacheTTL afterwards.
+func (server *ResilientSrvTopoServer) Close() {
+	server.cancel()
+	var watches []*entryWatch
+	var mutexes []*sync.Mutex
+	server.mutex.Lock()
+	for _, entry := range server.srvKeyspaceCache {
+		watches = append(watches, &entry.entryWatch)
+		mutexes = append(mutexes, &entry.mutex)
+	}
+	for _, entry := range server.srvShardCache {
+		watches = append(watches, &entry.entryWatch)
+		mutexes = append(mutexes, &entry.mutex)
+	}
+	for _, entry := range server.endPointsCache {
+		watches = append(watches, &entry.entryWatch)
+		mutexes = append(mutexes, &entry.mutex)
+	}
+	server.mutex.Unlock()
+	for i, w := range watches {
+		mutexes[i].Lock()
+		if w.stop != nil {
+			close(w.stop)
+			w.stop = nil
+		}
+		mutexes[i].Unlock()
+	}
+}
+
+// watchEntry starts the watch of a cache entry, unless it's already
+// started. It must be called with the mutex of the entry, mu, held.
+// name describes the watch in the logs. start issues the Watch RPC,
+// without mu held, and returns next, which receives the notifications
+// as nil for a node that doesn't exist. update stores a notified value
+// in the entry, with mu held. The entry is then kept up to date until
+// the node is deleted or the watch stops. It's refreshed after cacheTTL
+// afterwards, which starts the watch again.
+func (server *ResilientSrvTopoServer) watchEntry(mu *sync.Mutex, w *entryWatch, name string, start func(ctx context.Context) (next func() (interface{}, bool), stop chan<- struct{}, err error), update func(value interface{})) {
+	if !server.watch || w.started || server.ctx.Err() != nil {
+		return
+	}
+	w.started = true
+	go func() {
+		next, stop, err := start(server.ctx)
+		mu.Lock()
+		if err != nil {
+			w.started = false
+			mu.Unlock()
+			server.counts.Add(watchErrorCategory, 1)
+			log.Warningf("%v failed: %v (the entry is refreshed after %v)", name, err, server.cacheTTL)
+			return
+		}
+		if server.ctx.Err() != nil {
+			// Close was called during start.
+			close(stop)
+		} else {
+			w.stop = stop
+		}
+		mu.Unlock()
+
+		for {
+			value, ok := next()
+			if !ok {
+				break
+			}
+			server.counts.Add(watchCategory, 1)
+			mu.Lock()
+			if value == nil {
+				// The node doesn't exist: let the TTL path
+				// cache the error.
+				w.watching = false
+			} else {
+				update(value)
+				w.watching = true
+			}
+			mu.Unlock()
+		}
+		log.Warningf("%v stopped (the entry is refreshed after %v)", name, server.cacheTTL)
+		mu.Lock()
+		w.started = false
+		w.watching = false
+		w.stop = nil
+		mu.Unlock()
+	}()
+}
+
+// watchSrvKeyspace starts the watch of entry with watchEntry.
+func (server *ResilientSrvTopoServer) watchSrvKeyspace(entry *srvKeyspaceEntry) {
+	name := fmt.Sprintf("WatchSrvKeyspace(%v, %v)", entry.cell, entry.keyspace)
+	server.watchEntry(&entry.mutex, &entry.entryWatch, name, func(ctx context.Context) (func() (interface{}, bool), chan<- struct{}, error) {
+		notifications, stop, err := server.topoServer.WatchSrvKeyspace(ctx, entry.cell, entry.keyspace)
+		return func() (interface{}, bool) {
+			value, ok := <-notifications
+			if value == nil {
+				return nil, ok
+			}
+			return value, ok
+		}, stop, err
+	}, func(value interface{}) {
+		entry.insertionTime = time.Now()
+		entry.value = value.(*topo.SrvKeyspace)
+		entry.lastError = nil
+		entry.lastErrorCtx = nil
+	})
+}
+
+// watchSrvShard starts the watch of entry with watchEntry.
+func (server *ResilientSrvTopoServer) watchSrvShard(entry *srvShardEntry) {
+	name := fmt.Sprintf("WatchSrvShard(%v, %v, %v)", entry.cell, entry.keyspace, entry.shard)
+	server.watchEntry(&entry.mutex, &entry.entryWatch, name, func(ctx context.Context) (func() (interface{}, bool), chan<- struct{}, error) {
+		notifications, stop, err := server.topoServer.WatchSrvShard(ctx, entry.cell, entry.keyspace, entry.shard)
+		return func() (interface{}, bool) {
+			value, ok := <-notifications
+			if value == nil {
+				return nil, ok
+			}
+			return value, ok
+		}, stop, err
+	}, func(value interface{}) {
+		entry.insertionTime = time.Now()
+		entry.value = value.(*topo.SrvShard)
+		entry.lastError = nil
+		entry.lastErrorCtx = nil
+	})
+}
+
+// watchEndPoints starts the watch of entry with watchEntry. The
+// watch is on the cell of the entry: while it has no end points, a
+// remote master is still found by the TTL path.
+func (server *ResilientSrvTopoServer) watchEndPoints(entry *endPointsEntry) {
+	name := fmt.Sprintf("WatchEndPoints(%v, %v, %v, %v)", entry.cell, entry.keyspace, entry.shard, entry.tabletType)
+	server.watchEntry(&entry.mutex, &entry.entryWatch, name, func(ctx context.Context) (func() (interface{}, bool), chan<- struct{}, error) {
+		notifications, stop, err := server.topoServer.WatchEndPoints(ctx, entry.cell, entry.keyspace, entry.shard, entry.tabletType)
+		return func() (interface{}, bool) {
+			value, ok := <-notifications
+			if value == nil {
+				return nil, ok
+			}
+			return value, ok
+		}, stop, err
+	}, func(notified interface{}) {
+		value := notified.(*topo.EndPoints)
+		entry.insertionTime = time.Now()
+		entry.originalValue = value
+		entry.value = filterUnhealthyServers(value)
+		entry.lastError = nil
+		entry.lastErrorCtx = nil
+		entry.remote = false
+	})
+}
+
 // The next few structures and methods are used to get a displayable
 // version of the cache in a status page
 
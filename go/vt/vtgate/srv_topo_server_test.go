@@ -7,7 +7,9 @@ package vtgate
 import (
 	"fmt"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/youtube/vitess/go/vt/topo"
 	"github.com/youtube/vitess/go/vt/topo/test/faketopo"
@@ -350,3 +352,119 @@ func TestCachedErrors(t *testing.T) {
 		t.Fatalf("GetSrvKeyspace was not called again: %v times", ft.callCount)
 	}
 }
+
+// fakeTopoWatch is a fakeTopo that lets the test send the
+// notifications of the SrvKeyspace watches.
+type fakeTopoWatch struct {
+	fakeTopo
+
+	mu            sync.Mutex
+	watches       int
+	notifications chan *topo.SrvKeyspace
+	stop          chan struct{}
+}
+
+func (ft *fakeTopoWatch) WatchSrvKeyspace(ctx context.Context, cell, keyspace string) (<-chan *topo.SrvKeyspace, chan<- struct{}, error) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	ft.watches++
+	ft.notifications = make(chan *topo.SrvKeyspace, 1)
+	ft.stop = make(chan struct{})
+	return ft.notifications, ft.stop, nil
+}
+
+// waitForWatch waits for the nth watch, and returns its channels.
+func (ft *fakeTopoWatch) waitForWatch(t *testing.T, n int) (chan *topo.SrvKeyspace, chan struct{}) {
+	for i := 0; i < 100; i++ {
+		ft.mu.Lock()
+		watches, notifications, stop := ft.watches, ft.notifications, ft.stop
+		ft.mu.Unlock()
+		if watches == n {
+			return notifications, stop
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("WatchSrvKeyspace was never called %v times", n)
+	return nil, nil
+}
+
+// waitForSrvKeyspace waits until GetSrvKeyspace returns the
+// SrvKeyspace of shardingColumnName.
+func waitForSrvKeyspace(t *testing.T, rsts *ResilientSrvTopoServer, shardingColumnName string) {
+	for i := 0; i < 100; i++ {
+		sk, err := rsts.GetSrvKeyspace(context.Background(), "", "test_ks")
+		if err == nil && sk.ShardingColumnName == shardingColumnName {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("GetSrvKeyspace never returned the %v SrvKeyspace", shardingColumnName)
+}
+
+// TestWatchSrvKeyspace will test the cache is updated by the watch
+// notifications, and falls back to the TTL when the watch can't be used.
+func TestWatchSrvKeyspace(t *testing.T) {
+	ft := &fakeTopoWatch{
+		fakeTopo: fakeTopo{keyspace: "test_ks"},
+	}
+	rsts := NewResilientSrvTopoServer(ft, "TestWatchSrvKeyspace")
+	rsts.watch = true
+	rsts.cacheTTL = 0
+
+	// the first query reads the value, and starts the watch
+	if _, err := rsts.GetSrvKeyspace(context.Background(), "", "test_ks"); err != nil {
+		t.Fatalf("GetSrvKeyspace got unexpected error: %v", err)
+	}
+	if ft.callCount != 1 {
+		t.Fatalf("GetSrvKeyspace didn't get called 1 but %v times", ft.callCount)
+	}
+	notifications, _ := ft.waitForWatch(t, 1)
+
+	// the notifications update the value. Once it's watched, the
+	// topo server isn't queried any more, even if the TTL expired.
+	notifications <- &topo.SrvKeyspace{ShardingColumnName: "user_id"}
+	waitForSrvKeyspace(t, rsts, "user_id")
+	callCount := ft.callCount
+	notifications <- &topo.SrvKeyspace{ShardingColumnName: "video_id"}
+	waitForSrvKeyspace(t, rsts, "video_id")
+	if ft.callCount != callCount {
+		t.Fatalf("GetSrvKeyspace was called again: %v times", ft.callCount)
+	}
+
+	// a nil notification makes the cache use the TTL again
+	notifications <- nil
+	waitForSrvKeyspace(t, rsts, "")
+	if ft.callCount == callCount {
+		t.Fatalf("GetSrvKeyspace was not called again: %v times", ft.callCount)
+	}
+
+	// and so does the end of the watch, after which the next
+	// query starts a new watch
+	notifications <- &topo.SrvKeyspace{ShardingColumnName: "user_id"}
+	waitForSrvKeyspace(t, rsts, "user_id")
+	close(notifications)
+	waitForSrvKeyspace(t, rsts, "")
+	notifications, stop := ft.waitForWatch(t, 2)
+	notifications <- &topo.SrvKeyspace{ShardingColumnName: "video_id"}
+	waitForSrvKeyspace(t, rsts, "video_id")
+
+	// Close stops the watch, and no new watch is started
+	rsts.Close()
+	select {
+	case <-stop:
+	case <-time.After(time.Second):
+		t.Fatalf("Close didn't stop the watch")
+	}
+	close(notifications)
+	waitForSrvKeyspace(t, rsts, "")
+	if _, err := rsts.GetSrvKeyspace(context.Background(), "", "test_ks"); err != nil {
+		t.Fatalf("GetSrvKeyspace got unexpected error: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	ft.mu.Lock()
+	watches := ft.watches
+	ft.mu.Unlock()
+	if watches != 2 {
+		t.Fatalf("WatchSrvKeyspace was called %v times after Close, want 2", watches)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vtgate
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+
+	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/stats"
+	"github.com/youtube/vitess/go/sync2"
+	"github.com/youtube/vitess/go/vt/concurrency"
+	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/vterrors"
+	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
+	"golang.org/x/net/context"
+)
+
+// errStreamLimitExceeded starts the message of the errors of the
+// streams aborted by limitStream. Their code is ResourceExhausted.
+const errStreamLimitExceeded = "stream_limit_exceeded"
+
+var (
+	streamMaxRows  = flag.Int64("stream_execute_max_rows", 0, "maximum number of rows a streaming query can return. The stream is aborted with a stream_limit_exceeded error beyond it. Unlimited if 0")
+	streamMaxBytes = flag.Int64("stream_execute_max_bytes", 0, "maximum size in bytes of the values a streaming query can return. The stream is aborted with a stream_limit_exceeded error beyond it. Unlimited if 0")
+
+	streamLimitAborts = stats.NewCounters("StreamLimitAborts")
+)
+
+// limitStream returns a sendReply function that enforces
+// -stream_execute_max_rows and -stream_execute_max_bytes for
+// one request. The packet that exceeds a limit is not sent.
+func limitStream(sendReply func(*mproto.QueryResult) error) func(*mproto.QueryResult) error {
+	maxRows, maxBytes := *streamMaxRows, *streamMaxBytes
+	if maxRows == 0 && maxBytes == 0 {
+		return sendReply
+	}
+	var rows, size int64
+	return func(qr *mproto.QueryResult) error {
+		rows += int64(len(qr.Rows))
+		for _, row := range qr.Rows {
+			size += int64(rowSize(row))
+		}
+		if maxRows != 0 && rows > maxRows {
+			streamLimitAborts.Add("Rows", 1)
+			return vterrors.FromError(vterrors.ResourceExhausted, fmt.Errorf("%s: more than %d rows", errStreamLimitExceeded, maxRows))
+		}
+		if maxBytes != 0 && size > maxBytes {
+			streamLimitAborts.Add("Bytes", 1)
+			return vterrors.FromError(vterrors.ResourceExhausted, fmt.Errorf("%s: more than %d bytes", errStreamLimitExceeded, maxBytes))
+		}
+		return sendReply(qr)
+	}
+}
+
+// streamExecuteOrdered is the ordered mode of StreamExecuteMulti.
+// The stream of every shard must be sorted by orderBy. They are
+// merged with mergeStreams, which keeps one packet per shard in
+// memory, and blocks the shards that are ahead of the others.
+// The first error of a shard or of sendReply aborts all the streams.
+func (stc *ScatterConn) streamExecuteOrdered(
+	ctx context.Context,
+	query string,
+	keyspace string,
+	shardVars map[string]map[string]interface{},
+	tabletType topo.TabletType,
+	session *SafeSession,
+	orderBy []planbuilder.OrderByCol,
+	sendReply func(reply *mproto.QueryResult) error,
+	notInTransaction bool,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Every shard is streamed by its own multiGo, whose results are
+	// closed once the shard is done, even if it fails before it
+	// streams: the merge needs the end of every stream. Once aborted,
+	// the errors of the shards are caused by the cancelation, and are
+	// not recorded.
+	var aborted sync2.AtomicInt32
+	var wg sync.WaitGroup
+	var streams []*rowStream
+	var shardErrors []*concurrency.AllErrorRecorder
+	for shard := range shardVars {
+		sResults, allErrors := stc.multiGo(
+			ctx,
+			"StreamExecute",
+			keyspace,
+			[]string{shard},
+			tabletType,
+			session,
+			notInTransaction,
+			func(sdc *ShardConn, transactionId int64, sResults chan<- interface{}) error {
+				sr, errFunc := sdc.StreamExecute(ctx, query, shardVars[sdc.shard], transactionId)
+				if sr != nil {
+					for qr := range sr {
+						sResults <- qr
+					}
+				}
+				err := errFunc()
+				if err == nil || !aborted.CompareAndSwap(0, 1) {
+					return nil
+				}
+				cancel()
+				return err
+			})
+		shardErrors = append(shardErrors, allErrors)
+		results := make(chan *mproto.QueryResult, 1)
+		streams = append(streams, &rowStream{results: results})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(results)
+			for qr := range sResults {
+				select {
+				case results <- qr.(*mproto.QueryResult):
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	err := mergeStreams(streams, orderBy, sendReply)
+	if err != nil {
+		aborted.Set(1)
+		cancel()
+	}
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	allErrors := new(concurrency.AllErrorRecorder)
+	for _, shardErrors := range shardErrors {
+		for _, err := range shardErrors.Errors {
+			allErrors.RecordError(err)
+		}
+	}
+	return allErrors.AggrError(stc.aggregateErrors)
+}
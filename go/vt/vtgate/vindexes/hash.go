@@ -63,10 +63,12 @@ func (vind *Hash) Delete(vcursor planbuilder.VCursor, ids []interface{}, _ key.K
 // by using null-key 3DES hash. It's Unique, Reversible and
 // Functional. Additionally, it's also a FunctionalGenerator
 // because it's capable of generating new values from a vindex table
-// with a single unique autoinc column.
+// with a single unique autoinc column. If the Sequence param is set,
+// the values are generated from that sequence table instead, and the
+// vindex table only reserves the values supplied by the app.
 type HashAuto struct {
-	Table, Column string
-	ins, del      string
+	Table, Column, Sequence string
+	ins, del                string
 }
 
 // NewHashAuto creates a new HashAuto.
@@ -86,6 +88,7 @@ func (vind *HashAuto) Init(m map[string]interface{}) {
 	c := get("Column")
 	vind.Table = t
 	vind.Column = c
+	vind.Sequence = get("Sequence")
 	vind.ins = fmt.Sprintf("insert into %s(%s) values(:%s)", t, c, c)
 	vind.del = fmt.Sprintf("delete from %s where %s in ::%s", t, c, c)
 }
@@ -136,8 +139,17 @@ func (vind *HashAuto) Create(vcursor planbuilder.VCursor, id interface{}) error
 	return nil
 }
 
-// Generate generates a new id by using the autoinc of the vindex table.
+// Generate generates a new id by using the autoinc of the vindex table,
+// or the sequence. The ids of the sequence are unique, so they're not
+// inserted into the vindex table.
 func (vind *HashAuto) Generate(vcursor planbuilder.VCursor) (id int64, err error) {
+	if vind.Sequence != "" {
+		id, err = vcursor.NextSequenceValue(vind.Sequence)
+		if err != nil {
+			return 0, fmt.Errorf("hash.Generate: %v", err)
+		}
+		return id, nil
+	}
 	bq := &tproto.BoundQuery{
 		Sql: vind.ins,
 		BindVariables: map[string]interface{}{
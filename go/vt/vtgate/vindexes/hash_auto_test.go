@@ -136,6 +136,7 @@ type vcursor struct {
 	numRows  int
 	result   *mproto.QueryResult
 	query    *tproto.BoundQuery
+	sequence string
 }
 
 func (vc *vcursor) Execute(query *tproto.BoundQuery) (*mproto.QueryResult, error) {
@@ -168,6 +169,66 @@ func (vc *vcursor) Execute(query *tproto.BoundQuery) (*mproto.QueryResult, error
 	panic("unexpected")
 }
 
+func (vc *vcursor) NextSequenceValue(sequence string) (int64, error) {
+	vc.sequence = sequence
+	if vc.mustFail {
+		return 0, errors.New("sequence failed")
+	}
+	return 100, nil
+}
+
+func TestHashAutoSequence(t *testing.T) {
+	hv, err := planbuilder.CreateVindex("hash_autoinc", map[string]interface{}{"Table": "t", "Column": "c", "Sequence": "seq"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vc := &vcursor{}
+	got, err := hv.(planbuilder.FunctionalGenerator).Generate(vc)
+	if err != nil {
+		t.Error(err)
+	}
+	if got != 100 || vc.sequence != "seq" {
+		t.Errorf("Generate(): %d from %s, want 100 from seq", got, vc.sequence)
+	}
+	// The generated values are not inserted into the vindex table.
+	if vc.query != nil {
+		t.Errorf("vc.query = %#v, want nil", vc.query)
+	}
+
+	// The values supplied by the app are still reserved.
+	if err := hv.(planbuilder.Functional).Create(vc, 1); err != nil {
+		t.Error(err)
+	}
+	wantQuery := &tproto.BoundQuery{
+		Sql: "insert into t(c) values(:c)",
+		BindVariables: map[string]interface{}{
+			"c": 1,
+		},
+	}
+	if !reflect.DeepEqual(vc.query, wantQuery) {
+		t.Errorf("vc.query = %#v, want %#v", vc.query, wantQuery)
+	}
+	if err := hv.(planbuilder.Functional).Delete(vc, []interface{}{1}, ""); err != nil {
+		t.Error(err)
+	}
+	wantQuery = &tproto.BoundQuery{
+		Sql: "delete from t where c in ::c",
+		BindVariables: map[string]interface{}{
+			"c": []interface{}{1},
+		},
+	}
+	if !reflect.DeepEqual(vc.query, wantQuery) {
+		t.Errorf("vc.query = %#v, want %#v", vc.query, wantQuery)
+	}
+
+	vc = &vcursor{mustFail: true}
+	_, err = hv.(planbuilder.FunctionalGenerator).Generate(vc)
+	want := "hash.Generate: sequence failed"
+	if err == nil || err.Error() != want {
+		t.Errorf("Generate(): %v, want %v", err, want)
+	}
+}
+
 func TestHashAutoCreate(t *testing.T) {
 	vc := &vcursor{}
 	err := hashAuto.(planbuilder.Functional).Create(vc, 1)
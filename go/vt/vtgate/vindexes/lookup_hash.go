@@ -199,9 +199,12 @@ func (vind *LookupHashUniqueAuto) Delete(vcursor planbuilder.VCursor, ids []inte
 //====================================================================
 
 // lookup implements the functions for the Lookup vindexes.
+// If Sequence is set, the Generate functions get the new
+// ids from that sequence table instead of the autoinc of
+// the vindex table.
 type lookup struct {
-	Table, From, To    string
-	sel, ver, ins, del string
+	Table, From, To, Sequence string
+	sel, ver, ins, del        string
 }
 
 func (lkp *lookup) Init(m map[string]interface{}) {
@@ -216,6 +219,7 @@ func (lkp *lookup) Init(m map[string]interface{}) {
 	lkp.Table = t
 	lkp.From = from
 	lkp.To = to
+	lkp.Sequence = get("Sequence")
 	lkp.sel = fmt.Sprintf("select %s from %s where %s = :%s", to, t, from, from)
 	lkp.ver = fmt.Sprintf("select %s from %s where %s = :%s and %s = :%s", from, t, from, from, to, to)
 	lkp.ins = fmt.Sprintf("insert into %s(%s, %s) values(:%s, :%s)", t, from, to, from, to)
@@ -331,6 +335,16 @@ func (lkp *lookup) Create(vcursor planbuilder.VCursor, id interface{}, ksid key.
 
 // Generate generates an id and associates the ksid to the new id.
 func (lkp *lookup) Generate(vcursor planbuilder.VCursor, ksid key.KeyspaceId) (id int64, err error) {
+	if lkp.Sequence != "" {
+		id, err = vcursor.NextSequenceValue(lkp.Sequence)
+		if err != nil {
+			return 0, fmt.Errorf("lookup.Generate: %v", err)
+		}
+		if err = lkp.Create(vcursor, id, ksid); err != nil {
+			return 0, err
+		}
+		return id, nil
+	}
 	val, err := vunhash(ksid)
 	if err != nil {
 		return 0, fmt.Errorf("lookup.Generate: %v", err)
@@ -149,6 +149,31 @@ func TestLookupHashAutoGenerate(t *testing.T) {
 	}
 }
 
+func TestLookupHashAutoSequence(t *testing.T) {
+	h, err := planbuilder.CreateVindex("lookup_hash_autoinc", map[string]interface{}{"Table": "t", "From": "fromc", "To": "toc", "Sequence": "seq"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vc := &vcursor{}
+	got, err := h.(planbuilder.LookupGenerator).Generate(vc, "\x16k@\xb4J\xbaK\xd6")
+	if err != nil {
+		t.Error(err)
+	}
+	if got != 100 || vc.sequence != "seq" {
+		t.Errorf("Generate(): %d from %s, want 100 from seq", got, vc.sequence)
+	}
+	wantQuery := &tproto.BoundQuery{
+		Sql: "insert into t(fromc, toc) values(:fromc, :toc)",
+		BindVariables: map[string]interface{}{
+			"fromc": int64(100),
+			"toc":   int64(1),
+		},
+	}
+	if !reflect.DeepEqual(vc.query, wantQuery) {
+		t.Errorf("vc.query = %#v, want %#v", vc.query, wantQuery)
+	}
+}
+
 func TestLookupHashAutoReverse(t *testing.T) {
 	_, ok := lha.(planbuilder.Reversible)
 	if ok {
@@ -7,13 +7,14 @@ package vindexes
 import (
 	"encoding/binary"
 	"fmt"
+	"math"
 
 	"github.com/youtube/vitess/go/vt/key"
 	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
 )
 
 // Numeric defines a bit-pattern mapping of a uint64 to the KeyspaceId.
-// It's Unique and Reversible.
+// It's Unique, Reversible and Ranged.
 type Numeric struct{}
 
 // NewNumeric creates a Numeric vindex.
@@ -52,6 +53,66 @@ func (Numeric) Map(_ planbuilder.VCursor, ids []interface{}) ([]key.KeyspaceId,
 	return out, nil
 }
 
+// MapRange returns the key ranges that contain the keyspace ids
+// of the ids between from and to, inclusive. The ids are compared
+// by their value, whether they're signed or not. Negative ids map
+// to the top of the keyspace id range, so a range that goes from a
+// negative to a positive id wraps around, and is split in two key
+// ranges. If from is greater than to, there's no such key range.
+func (Numeric) MapRange(_ planbuilder.VCursor, from, to interface{}) ([]key.KeyRange, error) {
+	start, err := getNumber(from)
+	if err != nil {
+		return nil, fmt.Errorf("Numeric.MapRange: %v", err)
+	}
+	end, err := getNumber(to)
+	if err != nil {
+		return nil, fmt.Errorf("Numeric.MapRange: %v", err)
+	}
+	startNeg := start < 0 && !isUnsigned(from)
+	endNeg := end < 0 && !isUnsigned(to)
+	switch {
+	case startNeg && endNeg:
+		if start > end {
+			return nil, nil
+		}
+	case endNeg:
+		return nil, nil
+	case startNeg:
+		return []key.KeyRange{
+			numericKeyRange(0, uint64(end)),
+			numericKeyRange(uint64(start), math.MaxUint64),
+		}, nil
+	default:
+		if uint64(start) > uint64(end) {
+			return nil, nil
+		}
+	}
+	return []key.KeyRange{numericKeyRange(uint64(start), uint64(end))}, nil
+}
+
+// isUnsigned returns true if v is of an unsigned type.
+func isUnsigned(v interface{}) bool {
+	switch v.(type) {
+	case uint, uint32, uint64:
+		return true
+	}
+	return false
+}
+
+// numericKeyRange returns the key range of the keyspace ids
+// between start and end, inclusive.
+func numericKeyRange(start, end uint64) key.KeyRange {
+	var keybytes [8]byte
+	binary.BigEndian.PutUint64(keybytes[:], start)
+	kr := key.KeyRange{Start: key.KeyspaceId(keybytes[:])}
+	// The end of a key range is exclusive.
+	if end != math.MaxUint64 {
+		binary.BigEndian.PutUint64(keybytes[:], end+1)
+		kr.End = key.KeyspaceId(keybytes[:])
+	}
+	return kr
+}
+
 // ReverseMap returns the associated id for the ksid.
 func (Numeric) ReverseMap(_ planbuilder.VCursor, ksid key.KeyspaceId) (interface{}, error) {
 	if len(ksid) != 8 {
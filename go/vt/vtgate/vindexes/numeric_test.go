@@ -107,3 +107,83 @@ func TestNumericReverseMapBadData(t *testing.T) {
 		t.Errorf("numeric.Map: %v, want %v", err, want)
 	}
 }
+
+func TestNumericMapRange(t *testing.T) {
+	ranged := numeric.(planbuilder.Ranged)
+	got, err := ranged.MapRange(nil, 1, uint64(0x2000000000000000))
+	if err != nil {
+		t.Error(err)
+	}
+	want := []key.KeyRange{{
+		Start: "\x00\x00\x00\x00\x00\x00\x00\x01",
+		End:   "\x20\x00\x00\x00\x00\x00\x00\x01",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MapRange(): %#v, want %+v", got, want)
+	}
+
+	got, err = ranged.MapRange(nil, 5, uint64(0xffffffffffffffff))
+	if err != nil {
+		t.Error(err)
+	}
+	want = []key.KeyRange{{
+		Start: "\x00\x00\x00\x00\x00\x00\x00\x05",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MapRange(): %#v, want %+v", got, want)
+	}
+
+	got, err = ranged.MapRange(nil, 5, 4)
+	if err != nil {
+		t.Error(err)
+	}
+	if got != nil {
+		t.Errorf("MapRange(): %#v, want nil", got)
+	}
+
+	// Negative ids are at the top of the keyspace id range.
+	got, err = ranged.MapRange(nil, -3, -2)
+	if err != nil {
+		t.Error(err)
+	}
+	want = []key.KeyRange{{
+		Start: "\xff\xff\xff\xff\xff\xff\xff\xfd",
+		End:   "\xff\xff\xff\xff\xff\xff\xff\xff",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MapRange(): %#v, want %+v", got, want)
+	}
+
+	// A range from a negative to a positive id wraps around.
+	got, err = ranged.MapRange(nil, int64(-2), 3)
+	if err != nil {
+		t.Error(err)
+	}
+	want = []key.KeyRange{{
+		Start: "\x00\x00\x00\x00\x00\x00\x00\x00",
+		End:   "\x00\x00\x00\x00\x00\x00\x00\x04",
+	}, {
+		Start: "\xff\xff\xff\xff\xff\xff\xff\xfe",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MapRange(): %#v, want %+v", got, want)
+	}
+
+	for _, bounds := range [][2]interface{}{{-1, -2}, {1, -1}, {uint64(0xffffffffffffffff), 1}} {
+		got, err = ranged.MapRange(nil, bounds[0], bounds[1])
+		if err != nil {
+			t.Error(err)
+		}
+		if got != nil {
+			t.Errorf("MapRange(%v, %v): %#v, want nil", bounds[0], bounds[1], got)
+		}
+	}
+}
+
+func TestNumericMapRangeBadData(t *testing.T) {
+	_, err := numeric.(planbuilder.Ranged).MapRange(nil, 1, 1.1)
+	want := `Numeric.MapRange: unexpected type for 1.1: float64`
+	if err == nil || err.Error() != want {
+		t.Errorf("numeric.MapRange: %v, want %v", err, want)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vindexes
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/youtube/vitess/go/vt/key"
+	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
+)
+
+// Region defines a vindex that computes the KeyspaceId from two
+// columns: a region and an id. The region is the prefix of the
+// KeyspaceId, and is followed by the hash of the id. So, the rows
+// of a region are contiguous, and shards can be aligned to regions.
+// The RegionBytes param is the size of the prefix. It can be 1 or 2,
+// and defaults to 1. It's Unique, Functional and MultiColumn.
+type Region struct {
+	regionBytes int
+}
+
+// NewRegion creates a new Region.
+func NewRegion(m map[string]interface{}) (planbuilder.Vindex, error) {
+	vind := &Region{regionBytes: 1}
+	switch v := m["RegionBytes"].(type) {
+	case nil:
+	case float64:
+		// JSON numbers are float64.
+		vind.regionBytes = int(v)
+	case int:
+		vind.regionBytes = v
+	default:
+		return nil, fmt.Errorf("region: invalid RegionBytes: %v", v)
+	}
+	if vind.regionBytes != 1 && vind.regionBytes != 2 {
+		return nil, fmt.Errorf("region: RegionBytes must be 1 or 2, got %d", vind.regionBytes)
+	}
+	return vind, nil
+}
+
+// Cost returns the cost of this index as 1.
+func (vind *Region) Cost() int {
+	return 1
+}
+
+// ColumnCount returns 2: the region and the id.
+func (vind *Region) ColumnCount() int {
+	return 2
+}
+
+// Map returns the corresponding KeyspaceId values for the given
+// ids. Every id is a tuple of a region and an id.
+func (vind *Region) Map(_ planbuilder.VCursor, ids []interface{}) ([]key.KeyspaceId, error) {
+	out := make([]key.KeyspaceId, 0, len(ids))
+	for _, id := range ids {
+		ksid, err := vind.keyspaceID(id)
+		if err != nil {
+			return nil, fmt.Errorf("Region.Map: %v", err)
+		}
+		out = append(out, ksid)
+	}
+	return out, nil
+}
+
+// Verify returns true if id maps to ksid.
+func (vind *Region) Verify(_ planbuilder.VCursor, id interface{}, ksid key.KeyspaceId) (bool, error) {
+	computed, err := vind.keyspaceID(id)
+	if err != nil {
+		return false, fmt.Errorf("Region.Verify: %v", err)
+	}
+	return computed == ksid, nil
+}
+
+// Create does nothing: the KeyspaceId is computed from
+// the id, and there's no vindex table to insert into.
+func (vind *Region) Create(_ planbuilder.VCursor, _ interface{}) error {
+	return nil
+}
+
+// Delete does nothing, like Create.
+func (vind *Region) Delete(_ planbuilder.VCursor, _ []interface{}, _ key.KeyspaceId) error {
+	return nil
+}
+
+func (vind *Region) keyspaceID(id interface{}) (key.KeyspaceId, error) {
+	tuple, ok := id.([]interface{})
+	if !ok || len(tuple) != 2 {
+		return "", fmt.Errorf("id must be a tuple of a region and an id: %v", id)
+	}
+	region, err := getNumber(tuple[0])
+	if err != nil {
+		return "", err
+	}
+	if region < 0 || region >= 1<<(8*uint(vind.regionBytes)) {
+		return "", fmt.Errorf("region %d does not fit in %d bytes", region, vind.regionBytes)
+	}
+	num, err := getNumber(tuple[1])
+	if err != nil {
+		return "", err
+	}
+	var prefix [2]byte
+	binary.BigEndian.PutUint16(prefix[:], uint16(region))
+	return key.KeyspaceId(prefix[2-vind.regionBytes:]) + vhash(num), nil
+}
+
+func init() {
+	planbuilder.Register("region", NewRegion)
+}
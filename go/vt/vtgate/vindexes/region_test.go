@@ -0,0 +1,146 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vindexes
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/youtube/vitess/go/vt/key"
+	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
+)
+
+var region planbuilder.Vindex
+
+func init() {
+	rv, err := planbuilder.CreateVindex("region", nil)
+	if err != nil {
+		panic(err)
+	}
+	region = rv
+}
+
+func TestRegionCost(t *testing.T) {
+	if region.Cost() != 1 {
+		t.Errorf("Cost(): %d, want 1", region.Cost())
+	}
+}
+
+func TestRegionColumnCount(t *testing.T) {
+	if got := region.(planbuilder.MultiColumn).ColumnCount(); got != 2 {
+		t.Errorf("ColumnCount(): %d, want 2", got)
+	}
+}
+
+func TestRegionMap(t *testing.T) {
+	got, err := region.(planbuilder.Unique).Map(nil, []interface{}{
+		[]interface{}{1, 1},
+		[]interface{}{int64(2), uint64(2)},
+		[]interface{}{255, int32(3)},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	want := []key.KeyspaceId{
+		"\x01\x16k@\xb4J\xbaK\xd6",
+		"\x02\x06\xe7\xea\"Βp\x8f",
+		"\xffN\xb1\x90ɢ\xfa\x16\x9c",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Map(): %#v, want %#v", got, want)
+	}
+}
+
+func TestRegionMapTwoBytes(t *testing.T) {
+	rv, err := planbuilder.CreateVindex("region", map[string]interface{}{"RegionBytes": float64(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := rv.(planbuilder.Unique).Map(nil, []interface{}{[]interface{}{258, 1}})
+	if err != nil {
+		t.Error(err)
+	}
+	want := []key.KeyspaceId{"\x01\x02\x16k@\xb4J\xbaK\xd6"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Map(): %#v, want %#v", got, want)
+	}
+}
+
+func TestRegionMapBadData(t *testing.T) {
+	tcases := []struct {
+		id   interface{}
+		want string
+	}{{
+		id:   1,
+		want: "Region.Map: id must be a tuple of a region and an id: 1",
+	}, {
+		id:   []interface{}{1},
+		want: "Region.Map: id must be a tuple of a region and an id: [1]",
+	}, {
+		id:   []interface{}{256, 1},
+		want: "Region.Map: region 256 does not fit in 1 bytes",
+	}, {
+		id:   []interface{}{"a", 1},
+		want: "Region.Map: unexpected type for a: string",
+	}, {
+		id:   []interface{}{1, 1.1},
+		want: "Region.Map: unexpected type for 1.1: float64",
+	}}
+	for _, tcase := range tcases {
+		_, err := region.(planbuilder.Unique).Map(nil, []interface{}{tcase.id})
+		if err == nil || err.Error() != tcase.want {
+			t.Errorf("Map(%v): %v, want %s", tcase.id, err, tcase.want)
+		}
+	}
+}
+
+func TestRegionVerify(t *testing.T) {
+	success, err := region.Verify(nil, []interface{}{1, 1}, "\x01\x16k@\xb4J\xbaK\xd6")
+	if err != nil {
+		t.Error(err)
+	}
+	if !success {
+		t.Errorf("Verify(): %+v, want true", success)
+	}
+	success, err = region.Verify(nil, []interface{}{2, 1}, "\x01\x16k@\xb4J\xbaK\xd6")
+	if err != nil {
+		t.Error(err)
+	}
+	if success {
+		t.Errorf("Verify(): %+v, want false", success)
+	}
+}
+
+func TestRegionFunctional(t *testing.T) {
+	vc := &vcursor{}
+	if err := region.(planbuilder.Functional).Create(vc, []interface{}{1, 1}); err != nil {
+		t.Error(err)
+	}
+	if err := region.(planbuilder.Functional).Delete(vc, []interface{}{[]interface{}{1, 1}}, ""); err != nil {
+		t.Error(err)
+	}
+	if vc.query != nil {
+		t.Errorf("vc.query: %v, want nil", vc.query)
+	}
+}
+
+func TestNewRegionFail(t *testing.T) {
+	tcases := []struct {
+		params map[string]interface{}
+		want   string
+	}{{
+		params: map[string]interface{}{"RegionBytes": float64(3)},
+		want:   "region: RegionBytes must be 1 or 2, got 3",
+	}, {
+		params: map[string]interface{}{"RegionBytes": "1"},
+		want:   "region: invalid RegionBytes: 1",
+	}}
+	for _, tcase := range tcases {
+		_, err := planbuilder.CreateVindex("region", tcase.params)
+		if err == nil || err.Error() != tcase.want {
+			t.Errorf("CreateVindex(%v): %v, want %s", tcase.params, err, tcase.want)
+		}
+	}
+}
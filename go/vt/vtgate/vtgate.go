@@ -11,7 +11,9 @@ import (
 	"flag"
 	"fmt"
 	"math"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/golang/glog"
@@ -24,8 +26,10 @@ import (
 	"github.com/youtube/vitess/go/vt/servenv"
 	"github.com/youtube/vitess/go/vt/tabletserver/tabletconn"
 	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/vterrors"
 	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
 	"github.com/youtube/vitess/go/vt/vtgate/proto"
+	"github.com/youtube/vitess/go/vt/vtgate/quota"
 	// import vindexes implementations
 	_ "github.com/youtube/vitess/go/vt/vtgate/vindexes"
 	"github.com/youtube/vitess/go/vt/vtgate/vtgateservice"
@@ -65,6 +69,9 @@ type VTGate struct {
 	maxInFlight int64
 	inFlight    sync2.AtomicInt64
 
+	// quotas enforces the per-caller quotas.
+	quotas *quota.Manager
+
 	// the throttled loggers for all errors, one per API entry
 	logExecute                  *logutil.ThrottledLogger
 	logExecuteShard             *logutil.ThrottledLogger
@@ -102,6 +109,7 @@ func Init(serv SrvTopoServer, schema *planbuilder.Schema, cell string, retryDela
 
 		maxInFlight: int64(maxInFlight),
 		inFlight:    0,
+		quotas:      quota.NewManager("VtgateQuota"),
 
 		logExecute:                  logutil.NewThrottledLogger("Execute", 5*time.Second),
 		logExecuteShard:             logutil.NewThrottledLogger("ExecuteShard", 5*time.Second),
@@ -129,11 +137,30 @@ func Init(serv SrvTopoServer, schema *planbuilder.Schema, cell string, retryDela
 	errorsByKeyspace = stats.NewRates("ErrorsByKeyspace", stats.CounterForDimension(normalErrors, "Keyspace"), 15, 1*time.Minute)
 	errorsByDbType = stats.NewRates("ErrorsByDbType", stats.CounterForDimension(normalErrors, "DbType"), 15, 1*time.Minute)
 
+	debugHandlersOnce.Do(registerDebugHandlers)
+
 	for _, f := range RegisterVTGates {
 		f(rpcVTGate)
 	}
 }
 
+// debugHandlersOnce registers the debug handlers for the first
+// Init only: http.Handle panics if a path is registered twice.
+var debugHandlersOnce sync.Once
+
+// registerDebugHandlers registers the debug pages of the router.
+// They serve the router of the current VTGate.
+func registerDebugHandlers() {
+	planner := func(w http.ResponseWriter, r *http.Request) {
+		rpcVTGate.router.planner.ServeHTTP(w, r)
+	}
+	http.HandleFunc("/debug/query_plans", planner)
+	http.HandleFunc("/debug/schema", planner)
+	http.HandleFunc("/debug/consolidations", func(w http.ResponseWriter, r *http.Request) {
+		rpcVTGate.router.consolidator.ServeHTTP(w, r)
+	})
+}
+
 // InitializeConnections pre-initializes VTGate by connecting to vttablets of all keyspace/shard/type.
 // It is not necessary to call this function before serving queries,
 // but it would reduce connection overhead when serving.
@@ -156,11 +183,11 @@ func (vtg *VTGate) Execute(ctx context.Context, query *proto.Query, reply *proto
 	statsKey := []string{"Execute", "Any", string(query.TabletType)}
 	defer vtg.timings.Record(statsKey, startTime)
 
-	x := vtg.inFlight.Add(1)
-	defer vtg.inFlight.Add(-1)
-	if 0 < vtg.maxInFlight && vtg.maxInFlight < x {
-		return errTooManyInFlight
+	done, err := vtg.admit(ctx, func() bool { return vtg.router.isScatter(query.Sql) })
+	if err != nil {
+		return err
 	}
+	defer done()
 
 	qr, err := vtg.router.Execute(ctx, query)
 	if err == nil {
@@ -179,11 +206,11 @@ func (vtg *VTGate) ExecuteShard(ctx context.Context, query *proto.QueryShard, re
 	statsKey := []string{"ExecuteShard", query.Keyspace, string(query.TabletType)}
 	defer vtg.timings.Record(statsKey, startTime)
 
-	x := vtg.inFlight.Add(1)
-	defer vtg.inFlight.Add(-1)
-	if 0 < vtg.maxInFlight && vtg.maxInFlight < x {
-		return errTooManyInFlight
+	done, err := vtg.admit(ctx, func() bool { return len(query.Shards) > 1 })
+	if err != nil {
+		return err
 	}
+	defer done()
 
 	qr, err := vtg.resolver.Execute(
 		ctx,
@@ -213,11 +240,13 @@ func (vtg *VTGate) ExecuteKeyspaceIds(ctx context.Context, query *proto.Keyspace
 	statsKey := []string{"ExecuteKeyspaceIds", query.Keyspace, string(query.TabletType)}
 	defer vtg.timings.Record(statsKey, startTime)
 
-	x := vtg.inFlight.Add(1)
-	defer vtg.inFlight.Add(-1)
-	if 0 < vtg.maxInFlight && vtg.maxInFlight < x {
-		return errTooManyInFlight
+	done, err := vtg.admit(ctx, func() bool {
+		return vtg.resolver.isScatterKeyspaceIds(ctx, query.Keyspace, query.TabletType, query.KeyspaceIds)
+	})
+	if err != nil {
+		return err
 	}
+	defer done()
 
 	qr, err := vtg.resolver.ExecuteKeyspaceIds(ctx, query)
 	if err == nil {
@@ -236,11 +265,13 @@ func (vtg *VTGate) ExecuteKeyRanges(ctx context.Context, query *proto.KeyRangeQu
 	statsKey := []string{"ExecuteKeyRanges", query.Keyspace, string(query.TabletType)}
 	defer vtg.timings.Record(statsKey, startTime)
 
-	x := vtg.inFlight.Add(1)
-	defer vtg.inFlight.Add(-1)
-	if 0 < vtg.maxInFlight && vtg.maxInFlight < x {
-		return errTooManyInFlight
+	done, err := vtg.admit(ctx, func() bool {
+		return vtg.resolver.isScatterKeyRanges(ctx, query.Keyspace, query.TabletType, query.KeyRanges)
+	})
+	if err != nil {
+		return err
 	}
+	defer done()
 
 	qr, err := vtg.resolver.ExecuteKeyRanges(ctx, query)
 	if err == nil {
@@ -259,11 +290,11 @@ func (vtg *VTGate) ExecuteEntityIds(ctx context.Context, query *proto.EntityIdsQ
 	statsKey := []string{"ExecuteEntityIds", query.Keyspace, string(query.TabletType)}
 	defer vtg.timings.Record(statsKey, startTime)
 
-	x := vtg.inFlight.Add(1)
-	defer vtg.inFlight.Add(-1)
-	if 0 < vtg.maxInFlight && vtg.maxInFlight < x {
-		return errTooManyInFlight
+	done, err := vtg.admit(ctx, func() bool { return vtg.resolver.isScatterEntityIds(ctx, query) })
+	if err != nil {
+		return err
 	}
+	defer done()
 
 	qr, err := vtg.resolver.ExecuteEntityIds(ctx, query)
 	if err == nil {
@@ -282,11 +313,11 @@ func (vtg *VTGate) ExecuteBatchShard(ctx context.Context, batchQuery *proto.Batc
 	statsKey := []string{"ExecuteBatchShard", "", ""}
 	defer vtg.timings.Record(statsKey, startTime)
 
-	x := vtg.inFlight.Add(1)
-	defer vtg.inFlight.Add(-1)
-	if 0 < vtg.maxInFlight && vtg.maxInFlight < x {
-		return errTooManyInFlight
+	done, err := vtg.admit(ctx, func() bool { return isScatterBatchShard(batchQuery.Queries) })
+	if err != nil {
+		return err
 	}
+	defer done()
 
 	// TODO(sougou): implement functionality
 	qrs, err := vtg.resolver.ExecuteBatch(
@@ -317,11 +348,11 @@ func (vtg *VTGate) ExecuteBatchKeyspaceIds(ctx context.Context, query *proto.Key
 	statsKey := []string{"ExecuteBatchKeyspaceIds", "", ""}
 	defer vtg.timings.Record(statsKey, startTime)
 
-	x := vtg.inFlight.Add(1)
-	defer vtg.inFlight.Add(-1)
-	if 0 < vtg.maxInFlight && vtg.maxInFlight < x {
-		return errTooManyInFlight
+	done, err := vtg.admit(ctx, func() bool { return isScatterBatchKeyspaceIds(query.Queries) })
+	if err != nil {
+		return err
 	}
+	defer done()
 
 	qrs, err := vtg.resolver.ExecuteBatchKeyspaceIds(
 		ctx,
@@ -346,14 +377,14 @@ func (vtg *VTGate) StreamExecute(ctx context.Context, query *proto.Query, sendRe
 	statsKey := []string{"StreamExecute", "Any", string(query.TabletType)}
 	defer vtg.timings.Record(statsKey, startTime)
 
-	x := vtg.inFlight.Add(1)
-	defer vtg.inFlight.Add(-1)
-	if 0 < vtg.maxInFlight && vtg.maxInFlight < x {
-		return errTooManyInFlight
+	done, err := vtg.admit(ctx, func() bool { return vtg.router.isScatter(query.Sql) })
+	if err != nil {
+		return err
 	}
+	defer done()
 
 	var rowCount int64
-	err := vtg.router.StreamExecute(
+	err = vtg.router.StreamExecute(
 		ctx,
 		query,
 		func(mreply *mproto.QueryResult) error {
@@ -388,14 +419,16 @@ func (vtg *VTGate) StreamExecuteKeyspaceIds(ctx context.Context, query *proto.Ke
 	statsKey := []string{"StreamExecuteKeyspaceIds", query.Keyspace, string(query.TabletType)}
 	defer vtg.timings.Record(statsKey, startTime)
 
-	x := vtg.inFlight.Add(1)
-	defer vtg.inFlight.Add(-1)
-	if 0 < vtg.maxInFlight && vtg.maxInFlight < x {
-		return errTooManyInFlight
+	done, err := vtg.admit(ctx, func() bool {
+		return vtg.resolver.isScatterKeyspaceIds(ctx, query.Keyspace, query.TabletType, query.KeyspaceIds)
+	})
+	if err != nil {
+		return err
 	}
+	defer done()
 
 	var rowCount int64
-	err := vtg.resolver.StreamExecuteKeyspaceIds(
+	err = vtg.resolver.StreamExecuteKeyspaceIds(
 		ctx,
 		query,
 		func(mreply *mproto.QueryResult) error {
@@ -430,14 +463,16 @@ func (vtg *VTGate) StreamExecuteKeyRanges(ctx context.Context, query *proto.KeyR
 	statsKey := []string{"StreamExecuteKeyRanges", query.Keyspace, string(query.TabletType)}
 	defer vtg.timings.Record(statsKey, startTime)
 
-	x := vtg.inFlight.Add(1)
-	defer vtg.inFlight.Add(-1)
-	if 0 < vtg.maxInFlight && vtg.maxInFlight < x {
-		return errTooManyInFlight
+	done, err := vtg.admit(ctx, func() bool {
+		return vtg.resolver.isScatterKeyRanges(ctx, query.Keyspace, query.TabletType, query.KeyRanges)
+	})
+	if err != nil {
+		return err
 	}
+	defer done()
 
 	var rowCount int64
-	err := vtg.resolver.StreamExecuteKeyRanges(
+	err = vtg.resolver.StreamExecuteKeyRanges(
 		ctx,
 		query,
 		func(mreply *mproto.QueryResult) error {
@@ -467,14 +502,14 @@ func (vtg *VTGate) StreamExecuteShard(ctx context.Context, query *proto.QuerySha
 	statsKey := []string{"StreamExecuteShard", query.Keyspace, string(query.TabletType)}
 	defer vtg.timings.Record(statsKey, startTime)
 
-	x := vtg.inFlight.Add(1)
-	defer vtg.inFlight.Add(-1)
-	if 0 < vtg.maxInFlight && vtg.maxInFlight < x {
-		return errTooManyInFlight
+	done, err := vtg.admit(ctx, func() bool { return len(query.Shards) > 1 })
+	if err != nil {
+		return err
 	}
+	defer done()
 
 	var rowCount int64
-	err := vtg.resolver.StreamExecute(
+	err = vtg.resolver.StreamExecute(
 		ctx,
 		query.Sql,
 		query.BindVariables,
@@ -564,6 +599,56 @@ func (vtg *VTGate) SplitQuery(ctx context.Context, req *proto.SplitQueryRequest,
 	return nil
 }
 
+// SplitQueryV2 splits a query into sub queries on ranges of the
+// primary key of its table. Unlike SplitQuery, the primary key can
+// be of any type, or composite. Each part is a query on a single
+// shard of the keyspace, whatever its sharding scheme, for the
+// requested tablet type (rdonly by default).
+func (vtg *VTGate) SplitQueryV2(ctx context.Context, req *proto.SplitQueryV2Request, reply *proto.SplitQueryResult) error {
+	tabletType := req.TabletType
+	if tabletType == "" {
+		tabletType = topo.TYPE_RDONLY
+	}
+	sc := vtg.resolver.scatterConn
+	keyspace, _, shards, err := getKeyspaceShards(ctx, sc.toposerv, sc.cell, req.Keyspace, tabletType)
+	if err != nil {
+		return err
+	}
+	if len(shards) == 0 {
+		return fmt.Errorf("no shards to split for keyspace %v, tablet type %v", req.Keyspace, tabletType)
+	}
+	perShardSplitCount := int(math.Ceil(float64(req.SplitCount) / float64(len(shards))))
+	shardNames := make([]string, len(shards))
+	for i, shard := range shards {
+		shardNames[i] = shard.Name
+	}
+	splits, err := sc.SplitQueryV2(ctx, req.Query, req.SplitColumns, perShardSplitCount, req.NumRowsPerQueryPart, keyspace, shardNames, tabletType)
+	if err != nil {
+		return err
+	}
+	reply.Splits = splits
+	return nil
+}
+
+// GetSrvKeyspace returns the SrvKeyspace of keyspace in the cell
+// of vtgate. It fails if the keyspace is not served.
+func (vtg *VTGate) GetSrvKeyspace(ctx context.Context, keyspace string) (*topo.SrvKeyspace, error) {
+	sc := vtg.resolver.scatterConn
+	return sc.toposerv.GetSrvKeyspace(ctx, sc.cell, keyspace)
+}
+
+// ExplainQuery returns how a query is routed for its bind variables,
+// without executing it. If VTGate cannot route the query, the reason
+// is returned as part of the plan.
+func (vtg *VTGate) ExplainQuery(ctx context.Context, query *proto.Query, reply *proto.ExplainQueryResult) error {
+	plan, err := vtg.router.Explain(ctx, query)
+	if err != nil {
+		return err
+	}
+	reply.Plan = plan
+	return nil
+}
+
 // Any errors that are caused by VTGate dependencies (e.g, VtTablet) should be logged
 // as errors in those components, but logged to Info in VTGate itself.
 func logError(err error, query interface{}, logger *logutil.ThrottledLogger) {
@@ -626,7 +711,12 @@ func formatError(err error) error {
 	if err == nil {
 		return nil
 	}
-	return fmt.Errorf("%v, vtgate: %v", err, servenv.ListeningURL.String())
+	formatted := fmt.Errorf("%v, vtgate: %v", err, servenv.ListeningURL.String())
+	// Keep the code of the Vitess errors, for the clients.
+	if vtErr, ok := err.(*vterrors.VitessError); ok {
+		return vterrors.FromError(vtErr.Code, formatted)
+	}
+	return formatted
 }
 
 // HandlePanic recovers from panics, and logs / increment counters
@@ -16,11 +16,16 @@ func rpcErrFromVtGateError(err error) *mproto.RPCError {
 	if err == nil {
 		return nil
 	}
-	// TODO(aaijazi): for now, we don't have any differentiation of VtGate errors.
-	// However, we should have them soon, so that clients don't have to parse the
+	// TODO(aaijazi): for now, only the VitessErrors, like the quota
+	// errors, are differentiated. We should have codes for all the
+	// VtGate errors soon, so that clients don't have to parse the
 	// returned error string.
+	code := int64(vterrors.UnknownVtgateError)
+	if vtErr, ok := err.(*vterrors.VitessError); ok {
+		code = vtErr.Code
+	}
 	return &mproto.RPCError{
-		Code:    vterrors.UnknownVtgateError,
+		Code:    code,
 		Message: err.Error(),
 	}
 }
@@ -52,6 +57,15 @@ func AddVtGateErrorToSplitQueryResult(err error, reply *proto.SplitQueryResult)
 	reply.Err = rpcErrFromVtGateError(err)
 }
 
+// AddVtGateErrorToExplainQueryResult will mutate an ExplainQueryResult struct to fill in the Err
+// field with details from the VTGate error.
+func AddVtGateErrorToExplainQueryResult(err error, reply *proto.ExplainQueryResult) {
+	if err == nil {
+		return
+	}
+	reply.Err = rpcErrFromVtGateError(err)
+}
+
 // AddVtGateErrorToBeginResponse will mutate a BeginResponse struct to fill in the Err
 // field with details from the VTGate error.
 func AddVtGateErrorToBeginResponse(err error, reply *proto.BeginResponse) {
@@ -85,13 +99,17 @@ func VtGateErrorToVtRPCError(err error, errString string) *vtrpc.RPCError {
 		return nil
 	}
 	message := ""
+	code := vtrpc.ErrorCode_UnknownVtgateError
 	if err != nil {
 		message = err.Error()
+		if vtErr, ok := err.(*vterrors.VitessError); ok {
+			code = vtrpc.ErrorCode(vtErr.Code)
+		}
 	} else {
 		message = errString
 	}
 	return &vtrpc.RPCError{
-		Code:    vtrpc.ErrorCode_UnknownVtgateError,
+		Code:    code,
 		Message: message,
 	}
 }
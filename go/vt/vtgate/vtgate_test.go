@@ -6,16 +6,24 @@ package vtgate
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/youtube/vitess/go/vt/callerid"
 	"github.com/youtube/vitess/go/vt/key"
 	kproto "github.com/youtube/vitess/go/vt/key"
+	"github.com/youtube/vitess/go/vt/proto/vtrpc"
 	tproto "github.com/youtube/vitess/go/vt/tabletserver/proto"
 	"github.com/youtube/vitess/go/vt/tabletserver/tabletconn"
 	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/vterrors"
+	"github.com/youtube/vitess/go/vt/vtgate/planbuilder"
 	"github.com/youtube/vitess/go/vt/vtgate/proto"
+	"github.com/youtube/vitess/go/vt/vtgate/quota"
 	"golang.org/x/net/context"
 )
 
@@ -37,6 +45,21 @@ func init() {
 	Init(new(sandboxTopo), schema, "aa", 1*time.Second, 10, 2*time.Millisecond, 1*time.Millisecond, 24*time.Hour, 0)
 }
 
+func TestVTGateDebugHandlers(t *testing.T) {
+	// A second registration must not panic.
+	debugHandlersOnce.Do(registerDebugHandlers)
+
+	response := httptest.NewRecorder()
+	request, err := http.NewRequest("GET", "/debug/schema", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	http.DefaultServeMux.ServeHTTP(response, request)
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), "TestUnsharded") {
+		t.Errorf("/debug/schema: %d %s, want the schema", response.Code, response.Body.String())
+	}
+}
+
 func TestVTGateExecute(t *testing.T) {
 	sandbox := createSandbox(KsTestUnsharded)
 	sbc := &sandboxConn{}
@@ -472,6 +495,31 @@ func TestVTGateStreamExecute(t *testing.T) {
 	}
 }
 
+func TestVTGateStreamExecuteLimits(t *testing.T) {
+	defer func(maxBytes int64) {
+		*streamMaxBytes = maxBytes
+	}(*streamMaxBytes)
+	*streamMaxBytes = 1
+	sandbox := createSandbox(KsTestUnsharded)
+	sbc := &sandboxConn{}
+	sandbox.MapTestConn("0", sbc)
+	q := proto.Query{
+		Sql:        "select * from t1",
+		TabletType: topo.TYPE_MASTER,
+	}
+	err := rpcVTGate.StreamExecute(context.Background(), &q, func(r *proto.QueryResult) error {
+		return nil
+	})
+	// The code of the error reaches the clients.
+	want := "stream_limit_exceeded: more than 1 bytes"
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("StreamExecute: %v, want %s", err, want)
+	}
+	if vtErr, ok := err.(*vterrors.VitessError); !ok || vtErr.Code != vterrors.ResourceExhausted {
+		t.Errorf("StreamExecute: %#v, want code %v", err, vterrors.ResourceExhausted)
+	}
+}
+
 func TestVTGateStreamExecuteKeyspaceIds(t *testing.T) {
 	s := createSandbox("TestVTGateStreamExecuteKeyspaceIds")
 	sbc := &sandboxConn{}
@@ -743,6 +791,123 @@ func TestVTGateSplitQuery(t *testing.T) {
 	}
 }
 
+func TestVTGateSplitQueryV2(t *testing.T) {
+	keyspace := "TestVTGateSplitQueryV2"
+	keyranges, _ := key.ParseShardingSpec(DefaultShardSpec)
+	s := createSandbox(keyspace)
+	for _, kr := range keyranges {
+		s.MapTestConn(fmt.Sprintf("%s-%s", kr.Start, kr.End), &sandboxConn{})
+	}
+	req := proto.SplitQueryV2Request{
+		Keyspace: keyspace,
+		Query: tproto.BoundQuery{
+			Sql: "select col1, col2 from table",
+		},
+		SplitColumns: []string{"col1", "col2"},
+		SplitCount:   20,
+	}
+	result := new(proto.SplitQueryResult)
+	if err := rpcVTGate.SplitQueryV2(context.Background(), &req, result); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	// 20 parts over 8 shards are 3 parts per shard.
+	if want := 3 * len(keyranges); len(result.Splits) != want {
+		t.Errorf("wrong number of splits, want %v, got %v", want, len(result.Splits))
+	}
+	actualSqlsByShard := map[string][]string{}
+	for _, split := range result.Splits {
+		if split.Query != nil {
+			t.Errorf("want a QueryShard split, got %+v", split.Query)
+			continue
+		}
+		qs := split.QueryShard
+		if split.Size != sandboxSQRowCount {
+			t.Errorf("wrong split size, want %v, got %v", sandboxSQRowCount, split.Size)
+		}
+		if qs.Keyspace != keyspace || qs.TabletType != topo.TYPE_RDONLY || len(qs.Shards) != 1 {
+			t.Errorf("wrong split target, want %v/<shard> rdonly, got %+v", keyspace, qs)
+			continue
+		}
+		actualSqlsByShard[qs.Shards[0]] = append(actualSqlsByShard[qs.Shards[0]], qs.Sql)
+	}
+	expectedSqlsByShard := map[string][]string{}
+	for _, kr := range keyranges {
+		expectedSqlsByShard[fmt.Sprintf("%s-%s", kr.Start, kr.End)] = []string{
+			"select col1, col2 from table /*split [col1 col2] 0 */",
+			"select col1, col2 from table /*split [col1 col2] 1 */",
+			"select col1, col2 from table /*split [col1 col2] 2 */",
+		}
+	}
+	if !reflect.DeepEqual(actualSqlsByShard, expectedSqlsByShard) {
+		t.Errorf("splits contain the wrong sqls and/or shards, got: %v, want: %v", actualSqlsByShard, expectedSqlsByShard)
+	}
+
+	// The parts can target another tablet type.
+	req.TabletType = topo.TYPE_REPLICA
+	result = new(proto.SplitQueryResult)
+	if err := rpcVTGate.SplitQueryV2(context.Background(), &req, result); err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	for _, split := range result.Splits {
+		if split.QueryShard.TabletType != topo.TYPE_REPLICA {
+			t.Errorf("wrong tablet type, want %v, got %v", topo.TYPE_REPLICA, split.QueryShard.TabletType)
+		}
+	}
+}
+
+func TestVTGateQuotas(t *testing.T) {
+	sandbox := createSandbox("TestVTGateQuotas")
+	sandbox.MapTestConn("0", &sandboxConn{})
+	sandbox.MapTestConn("1", &sandboxConn{})
+	rpcVTGate.quotas.SetConfig(&quota.Config{
+		Principals: map[string]quota.Quota{"TestVTGateQuotas": {QPS: 1}},
+	})
+	defer rpcVTGate.quotas.SetConfig(&quota.Config{})
+	ctx := callerid.NewContext(context.Background(), callerid.NewEffectiveCallerID("TestVTGateQuotas", "", ""), nil)
+	q := proto.QueryShard{
+		Sql:      "query",
+		Keyspace: "TestVTGateQuotas",
+		Shards:   []string{"0", "1"},
+	}
+
+	qr := new(proto.QueryResult)
+	if err := rpcVTGate.ExecuteShard(ctx, &q, qr); err != nil || qr.Error != "" {
+		t.Fatalf("first query: %v %v", err, qr.Error)
+	}
+	err := rpcVTGate.ExecuteShard(ctx, &q, qr)
+	want := quota.ErrQuotaExceeded + `: caller "TestVTGateQuotas" is over its limit of 1 queries per second`
+	if err == nil || err.Error() != want {
+		t.Fatalf("second query: %v, want %v", err, want)
+	}
+	if rpcErr := rpcErrFromVtGateError(err); rpcErr.Code != vterrors.QuotaExceeded {
+		t.Errorf("rpcErrFromVtGateError: %v, want code %v", rpcErr, vterrors.QuotaExceeded)
+	}
+	if rpcErr := VtGateErrorToVtRPCError(err, ""); rpcErr.Code != vtrpc.ErrorCode_QuotaExceeded {
+		t.Errorf("VtGateErrorToVtRPCError: %v, want code %v", rpcErr, vtrpc.ErrorCode_QuotaExceeded)
+	}
+
+	// the other callers have no quota
+	if err := rpcVTGate.ExecuteShard(context.Background(), &q, qr); err != nil {
+		t.Errorf("query without caller id: %v", err)
+	}
+}
+
+func TestIsScatter(t *testing.T) {
+	if !isScatterBatchShard([]proto.BoundShardQuery{{Keyspace: "ks", Shards: []string{"0"}}, {Keyspace: "ks", Shards: []string{"1"}}}) {
+		t.Errorf("isScatterBatchShard(0, 1): false, want true")
+	}
+	if isScatterBatchShard([]proto.BoundShardQuery{{Keyspace: "ks", Shards: []string{"0"}}, {Keyspace: "ks", Shards: []string{"0"}}}) {
+		t.Errorf("isScatterBatchShard(0, 0): true, want false")
+	}
+	scatter := &planbuilder.Plan{ID: planbuilder.SelectScatter}
+	if !isScatterPlan(&planbuilder.Plan{ID: planbuilder.SelectJoin, Left: &planbuilder.Plan{ID: planbuilder.SelectEqual}, Right: scatter}) {
+		t.Errorf("isScatterPlan(join with scatter): false, want true")
+	}
+	if isScatterPlan(&planbuilder.Plan{ID: planbuilder.SelectEqual}) {
+		t.Errorf("isScatterPlan(SelectEqual): true, want false")
+	}
+}
+
 func TestIsErrorCausedByVTGate(t *testing.T) {
 	unknownError := fmt.Errorf("unknown error")
 	serverError := &tabletconn.ServerError{
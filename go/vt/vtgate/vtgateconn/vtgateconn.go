@@ -166,6 +166,21 @@ func (conn *VTGateConn) SplitQuery(ctx context.Context, keyspace string, query t
 	return conn.impl.SplitQuery(ctx, keyspace, query, splitColumn, splitCount)
 }
 
+// SplitQueryV2 splits a query into smaller queries that scan ranges
+// of the primary key of its table, each with the shard to send it to.
+// Unlike SplitQuery, it supports primary keys of any type, and
+// composite primary keys. See proto.SplitQueryV2Request for the
+// parameters.
+func (conn *VTGateConn) SplitQueryV2(ctx context.Context, keyspace string, query tproto.BoundQuery, splitColumns []string, splitCount int, numRowsPerQueryPart int64, tabletType topo.TabletType) ([]proto.SplitQueryPart, error) {
+	return conn.impl.SplitQueryV2(ctx, keyspace, query, splitColumns, splitCount, numRowsPerQueryPart, tabletType)
+}
+
+// ExplainQuery returns how vtgate would route a query
+// for the given bind variables, without executing it.
+func (conn *VTGateConn) ExplainQuery(ctx context.Context, query string, bindVars map[string]interface{}, tabletType topo.TabletType) (*proto.QueryPlan, error) {
+	return conn.impl.ExplainQuery(ctx, query, bindVars, tabletType)
+}
+
 // VTGateTx defines an ongoing transaction.
 // It should not be concurrently used across goroutines.
 type VTGateTx struct {
@@ -348,6 +363,14 @@ type Impl interface {
 	// appending primary key range clauses to the original query.
 	SplitQuery(ctx context.Context, keyspace string, query tproto.BoundQuery, splitColumn string, splitCount int) ([]proto.SplitQueryPart, error)
 
+	// SplitQueryV2 splits a query into smaller queries that scan
+	// ranges of the primary key of its table, each with its shard.
+	SplitQueryV2(ctx context.Context, keyspace string, query tproto.BoundQuery, splitColumns []string, splitCount int, numRowsPerQueryPart int64, tabletType topo.TabletType) ([]proto.SplitQueryPart, error)
+
+	// ExplainQuery returns how vtgate would route a query
+	// for the given bind variables, without executing it.
+	ExplainQuery(ctx context.Context, query string, bindVars map[string]interface{}, tabletType topo.TabletType) (*proto.QueryPlan, error)
+
 	// Close must be called for releasing resources.
 	Close()
 }
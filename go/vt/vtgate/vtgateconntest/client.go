@@ -375,6 +375,36 @@ func (f *fakeVTGateService) SplitQuery(ctx context.Context, req *proto.SplitQuer
 	return nil
 }
 
+// SplitQueryV2 is part of the VTGateService interface
+func (f *fakeVTGateService) SplitQueryV2(ctx context.Context, req *proto.SplitQueryV2Request, reply *proto.SplitQueryResult) error {
+	if f.hasError {
+		return testVtGateError
+	}
+	if f.panics {
+		panic(fmt.Errorf("test forced panic"))
+	}
+	if !reflect.DeepEqual(req, splitQueryV2Request) {
+		f.t.Errorf("SplitQueryV2 has wrong input: got %#v wanted %#v", req, splitQueryV2Request)
+	}
+	*reply = *splitQueryV2Result
+	return nil
+}
+
+// ExplainQuery is part of the VTGateService interface
+func (f *fakeVTGateService) ExplainQuery(ctx context.Context, query *proto.Query, reply *proto.ExplainQueryResult) error {
+	if f.hasError {
+		return testVtGateError
+	}
+	if f.panics {
+		panic(fmt.Errorf("test forced panic"))
+	}
+	if !reflect.DeepEqual(query, explainQuery) {
+		f.t.Errorf("ExplainQuery has wrong input: got %#v wanted %#v", query, explainQuery)
+	}
+	*reply = *explainQueryResult
+	return nil
+}
+
 // CreateFakeServer returns the fake server for the tests
 func CreateFakeServer(t *testing.T) vtgateservice.VTGateService {
 	return &fakeVTGateService{
@@ -418,6 +448,8 @@ func TestSuite(t *testing.T, impl vtgateconn.Impl, fakeServer vtgateservice.VTGa
 	testTx2PassNotInTransaction(t, conn)
 	testTx2Fail(t, conn)
 	testSplitQuery(t, conn)
+	testSplitQueryV2(t, conn)
+	testExplainQuery(t, conn)
 
 	// return an error for every call, make sure they're handled properly
 	fakeServer.(*fakeVTGateService).hasError = true
@@ -445,6 +477,8 @@ func TestSuite(t *testing.T, impl vtgateconn.Impl, fakeServer vtgateservice.VTGa
 	testCommit2Error(t, conn)
 	testRollback2Error(t, conn)
 	testSplitQueryError(t, conn)
+	testSplitQueryV2Error(t, conn)
+	testExplainQueryError(t, conn)
 	fakeServer.(*fakeVTGateService).hasError = false
 
 	// force a panic at every call, then test that works
@@ -473,6 +507,8 @@ func TestSuite(t *testing.T, impl vtgateconn.Impl, fakeServer vtgateservice.VTGa
 	testCommit2Panic(t, conn)
 	testRollback2Panic(t, conn)
 	testSplitQueryPanic(t, conn)
+	testSplitQueryV2Panic(t, conn)
+	testExplainQueryPanic(t, conn)
 	fakeServer.(*fakeVTGateService).panics = false
 }
 
@@ -1607,6 +1643,52 @@ func testSplitQueryPanic(t *testing.T, conn *vtgateconn.VTGateConn) {
 	expectPanic(t, err)
 }
 
+func testSplitQueryV2(t *testing.T, conn *vtgateconn.VTGateConn) {
+	ctx := context.Background()
+	qsl, err := conn.SplitQueryV2(ctx, splitQueryV2Request.Keyspace, splitQueryV2Request.Query, splitQueryV2Request.SplitColumns, splitQueryV2Request.SplitCount, splitQueryV2Request.NumRowsPerQueryPart, splitQueryV2Request.TabletType)
+	if err != nil {
+		t.Fatalf("SplitQueryV2 failed: %v", err)
+	}
+	if !reflect.DeepEqual(qsl, splitQueryV2Result.Splits) {
+		t.Errorf("SplitQueryV2 returned wrong result: got %+v wanted %+v", qsl, splitQueryV2Result.Splits)
+	}
+}
+
+func testSplitQueryV2Error(t *testing.T, conn *vtgateconn.VTGateConn) {
+	ctx := context.Background()
+	_, err := conn.SplitQueryV2(ctx, splitQueryV2Request.Keyspace, splitQueryV2Request.Query, splitQueryV2Request.SplitColumns, splitQueryV2Request.SplitCount, splitQueryV2Request.NumRowsPerQueryPart, splitQueryV2Request.TabletType)
+	verifyError(t, err, "SplitQueryV2")
+}
+
+func testSplitQueryV2Panic(t *testing.T, conn *vtgateconn.VTGateConn) {
+	ctx := context.Background()
+	_, err := conn.SplitQueryV2(ctx, splitQueryV2Request.Keyspace, splitQueryV2Request.Query, splitQueryV2Request.SplitColumns, splitQueryV2Request.SplitCount, splitQueryV2Request.NumRowsPerQueryPart, splitQueryV2Request.TabletType)
+	expectPanic(t, err)
+}
+
+func testExplainQuery(t *testing.T, conn *vtgateconn.VTGateConn) {
+	ctx := context.Background()
+	plan, err := conn.ExplainQuery(ctx, explainQuery.Sql, explainQuery.BindVariables, explainQuery.TabletType)
+	if err != nil {
+		t.Fatalf("ExplainQuery failed: %v", err)
+	}
+	if !reflect.DeepEqual(plan, explainQueryResult.Plan) {
+		t.Errorf("ExplainQuery returned wrong result: got %+v wanted %+v", plan, explainQueryResult.Plan)
+	}
+}
+
+func testExplainQueryError(t *testing.T, conn *vtgateconn.VTGateConn) {
+	ctx := context.Background()
+	_, err := conn.ExplainQuery(ctx, explainQuery.Sql, explainQuery.BindVariables, explainQuery.TabletType)
+	verifyError(t, err, "ExplainQuery")
+}
+
+func testExplainQueryPanic(t *testing.T, conn *vtgateconn.VTGateConn) {
+	ctx := context.Background()
+	_, err := conn.ExplainQuery(ctx, explainQuery.Sql, explainQuery.BindVariables, explainQuery.TabletType)
+	expectPanic(t, err)
+}
+
 var execMap = map[string]struct {
 	execQuery            *proto.Query
 	shardQuery           *proto.QueryShard
@@ -2051,8 +2133,9 @@ var result1 = mproto.QueryResult{
 }
 
 var session1 = &proto.Session{
-	InTransaction: true,
-	ShardSessions: []*proto.ShardSession{},
+	InTransaction:  true,
+	ShardSessions:  []*proto.ShardSession{},
+	ShardPositions: []*proto.ShardPosition{},
 }
 
 var session2 = &proto.Session{
@@ -2065,6 +2148,7 @@ var session2 = &proto.Session{
 			TransactionId: 1,
 		},
 	},
+	ShardPositions: []*proto.ShardPosition{},
 }
 
 var splitQueryRequest = &proto.SplitQueryRequest{
@@ -2099,3 +2183,54 @@ var splitQueryResult = &proto.SplitQueryResult{
 		},
 	},
 }
+
+var splitQueryV2Request = &proto.SplitQueryV2Request{
+	Keyspace: "ks",
+	Query: tproto.BoundQuery{
+		Sql: "in for SplitQueryV2",
+		BindVariables: map[string]interface{}{
+			"bind1": int64(43),
+		},
+	},
+	SplitColumns:        []string{"split_column1", "split_column2"},
+	SplitCount:          13,
+	NumRowsPerQueryPart: 1000,
+	TabletType:          topo.TYPE_RDONLY,
+}
+
+var splitQueryV2Result = &proto.SplitQueryResult{
+	Splits: []proto.SplitQueryPart{
+		proto.SplitQueryPart{
+			QueryShard: &proto.QueryShard{
+				Sql: "out for SplitQueryV2",
+				BindVariables: map[string]interface{}{
+					"bind1": int64(1114444),
+				},
+				Keyspace:   "ksout",
+				Shards:     []string{"-80"},
+				TabletType: topo.TYPE_RDONLY,
+			},
+			Size: 12344,
+		},
+	},
+}
+
+var explainQuery = &proto.Query{
+	Sql: "in for ExplainQuery",
+	BindVariables: map[string]interface{}{
+		"bind1": int64(43),
+	},
+	TabletType: topo.TYPE_RDONLY,
+}
+
+var explainQueryResult = &proto.ExplainQueryResult{
+	Plan: &proto.QueryPlan{
+		PlanID:    "SelectIN",
+		Table:     "user",
+		Col:       "id",
+		Vindex:    "user_index",
+		Rewritten: "select * from user where id in ::_vals",
+		Keyspace:  "ks",
+		Shards:    []string{"-80", "80-"},
+	},
+}
@@ -36,6 +36,10 @@ type VTGateService interface {
 
 	// Map Reduce support
 	SplitQuery(ctx context.Context, req *proto.SplitQueryRequest, reply *proto.SplitQueryResult) error
+	SplitQueryV2(ctx context.Context, req *proto.SplitQueryV2Request, reply *proto.SplitQueryResult) error
+
+	// Plan inspection
+	ExplainQuery(ctx context.Context, query *proto.Query, reply *proto.ExplainQueryResult) error
 
 	// HandlePanic should be called with defer at the beginning of each
 	// RPC implementation method, before calling any of the previous methods
@@ -25,3 +25,13 @@ type VerticalSplitClone struct {
 	Tables                []string
 	Strategy              string
 }
+
+// ReferenceSync is an event that describes a single step in the copy
+// and sync of reference tables.
+type ReferenceSync struct {
+	base.StatusUpdater
+
+	SourceKeyspace, SourceShard string
+	Keyspace, Cell              string
+	Tables                      []string
+}
@@ -23,5 +23,12 @@ func (ev *VerticalSplitClone) Syslog() (syslog.Priority, string) {
 		ev.Keyspace, ev.Shard, ev.Cell, ev.Status)
 }
 
+// Syslog writes a ReferenceSync event to syslog.
+func (ev *ReferenceSync) Syslog() (syslog.Priority, string) {
+	return syslog.LOG_INFO, fmt.Sprintf("%s/%s [reference sync from %s/%s] %s",
+		ev.Keyspace, ev.Cell, ev.SourceKeyspace, ev.SourceShard, ev.Status)
+}
+
 var _ syslogger.Syslogger = (*SplitClone)(nil)         // compile-time interface check
 var _ syslogger.Syslogger = (*VerticalSplitClone)(nil) // compile-time interface check
+var _ syslogger.Syslogger = (*ReferenceSync)(nil)      // compile-time interface check
@@ -46,3 +46,22 @@ func TestVerticalSplitCloneSyslog(t *testing.T) {
 		t.Errorf("wrong message: got %v, want %v", gotMsg, wantMsg)
 	}
 }
+
+func TestReferenceSyncSyslog(t *testing.T) {
+	wantSev, wantMsg := syslog.LOG_INFO, "keyspace-123/cell-1 [reference sync from source-123/0] status"
+	ev := &ReferenceSync{
+		Cell:           "cell-1",
+		Keyspace:       "keyspace-123",
+		SourceKeyspace: "source-123",
+		SourceShard:    "0",
+		StatusUpdater:  base.StatusUpdater{Status: "status"},
+	}
+	gotSev, gotMsg := ev.Syslog()
+
+	if gotSev != wantSev {
+		t.Errorf("wrong severity: got %v, want %v", gotSev, wantSev)
+	}
+	if gotMsg != wantMsg {
+		t.Errorf("wrong message: got %v, want %v", gotMsg, wantMsg)
+	}
+}
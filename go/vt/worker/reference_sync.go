@@ -0,0 +1,727 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"fmt"
+	"html/template"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/youtube/vitess/go/event"
+	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/sqltypes"
+	"github.com/youtube/vitess/go/sync2"
+	"github.com/youtube/vitess/go/vt/binlog/binlogplayer"
+	myproto "github.com/youtube/vitess/go/vt/mysqlctl/proto"
+	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/worker/events"
+	"github.com/youtube/vitess/go/vt/wrangler"
+)
+
+// referenceSyncBlpUid is the uid of the _vt.blp_checkpoint row that
+// stores the replication position of the reference tables on the
+// destination shards. It's far above the uids of the SourceShards
+// used by filtered replication, which are numbered from 0.
+const referenceSyncBlpUid = 1000
+
+// ReferenceSyncWorker copies reference tables from an unsharded source
+// keyspace to every shard of a destination keyspace. It then keeps the
+// copies in sync by replaying the binlog stream of the source shard on
+// the destination masters, until it's canceled.
+type ReferenceSyncWorker struct {
+	StatusWorker
+
+	wr                     *wrangler.Wrangler
+	cell                   string
+	sourceKeyspace         string
+	sourceShard            string
+	destinationKeyspace    string
+	skipCopy               bool
+	sourceReaderCount      int
+	destinationPackCount   int
+	minTableSizeForSplit   uint64
+	destinationWriterCount int
+	cleaner                *wrangler.Cleaner
+
+	// all subsequent fields are protected by the StatusWorker mutex
+
+	// populated during WorkerStateInit, read-only after that
+	destinationShards []string
+
+	// populated during WorkerStateFindTargets, read-only after that
+	sourceAlias  topo.TabletAlias
+	sourceTablet *topo.TabletInfo
+
+	// populated during WorkerStateCopy. tables is then resolved to
+	// the copied tables, and is read-only after that.
+	tables      []string
+	tableStatus []*tableStatus
+	startTime   time.Time
+
+	// populated during WorkerStateReplay, one entry per destination shard
+	playerStats map[string]*binlogplayer.BinlogPlayerStats
+
+	ev *events.ReferenceSync
+
+	// Mutex to protect fields that might change when (re)resolving topology.
+	resolveMu                  sync.Mutex
+	destinationShardsToTablets map[string]*topo.TabletInfo
+	resolveTime                time.Time
+}
+
+// NewReferenceSyncWorker returns a new ReferenceSyncWorker object.
+func NewReferenceSyncWorker(wr *wrangler.Wrangler, cell, sourceKeyspace, sourceShard, destinationKeyspace string, tables []string, skipCopy bool, sourceReaderCount, destinationPackCount int, minTableSizeForSplit uint64, destinationWriterCount int) (Worker, error) {
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("no tables to sync")
+	}
+	return &ReferenceSyncWorker{
+		StatusWorker:           NewStatusWorker(),
+		wr:                     wr,
+		cell:                   cell,
+		sourceKeyspace:         sourceKeyspace,
+		sourceShard:            sourceShard,
+		destinationKeyspace:    destinationKeyspace,
+		tables:                 tables,
+		skipCopy:               skipCopy,
+		sourceReaderCount:      sourceReaderCount,
+		destinationPackCount:   destinationPackCount,
+		minTableSizeForSplit:   minTableSizeForSplit,
+		destinationWriterCount: destinationWriterCount,
+		cleaner:                &wrangler.Cleaner{},
+		playerStats:            make(map[string]*binlogplayer.BinlogPlayerStats),
+
+		ev: &events.ReferenceSync{
+			SourceKeyspace: sourceKeyspace,
+			SourceShard:    sourceShard,
+			Keyspace:       destinationKeyspace,
+			Cell:           cell,
+			Tables:         tables,
+		},
+	}, nil
+}
+
+func (rsw *ReferenceSyncWorker) setState(state StatusWorkerState) {
+	rsw.SetState(state)
+	event.DispatchUpdate(rsw.ev, state.String())
+}
+
+func (rsw *ReferenceSyncWorker) setErrorState(err error) {
+	rsw.SetState(WorkerStateError)
+	event.DispatchUpdate(rsw.ev, "error: "+err.Error())
+}
+
+// playerStatuses returns the replication status of every destination
+// shard. It assumes the StatusWorker mutex is held.
+func (rsw *ReferenceSyncWorker) playerStatuses() []string {
+	var result []string
+	for _, shard := range rsw.destinationShards {
+		stats, ok := rsw.playerStats[shard]
+		if !ok {
+			continue
+		}
+		result = append(result, fmt.Sprintf("%v: position %v, %v seconds behind master", shard, stats.GetLastPosition(), stats.SecondsBehindMaster.Get()))
+	}
+	return result
+}
+
+// StatusAsHTML implements the Worker interface
+func (rsw *ReferenceSyncWorker) StatusAsHTML() template.HTML {
+	rsw.Mu.Lock()
+	defer rsw.Mu.Unlock()
+	result := "<b>Working on:</b> " + rsw.destinationKeyspace + " from " + rsw.sourceKeyspace + "/" + rsw.sourceShard + "</br>\n"
+	result += "<b>State:</b> " + rsw.State.String() + "</br>\n"
+	switch rsw.State {
+	case WorkerStateCopy:
+		result += "<b>Running</b>:</br>\n"
+		result += "<b>Copying from</b>: " + rsw.sourceAlias.String() + "</br>\n"
+		statuses, eta := formatTableStatuses(rsw.tableStatus, rsw.startTime)
+		result += "<b>ETA</b>: " + eta.String() + "</br>\n"
+		result += strings.Join(statuses, "</br>\n")
+	case WorkerStateReplay:
+		result += "<b>Replaying</b>: " + strings.Join(rsw.tables, ", ") + "</br>\n"
+		result += strings.Join(rsw.playerStatuses(), "</br>\n")
+	}
+
+	return template.HTML(result)
+}
+
+// StatusAsText implements the Worker interface
+func (rsw *ReferenceSyncWorker) StatusAsText() string {
+	rsw.Mu.Lock()
+	defer rsw.Mu.Unlock()
+	result := "Working on: " + rsw.destinationKeyspace + " from " + rsw.sourceKeyspace + "/" + rsw.sourceShard + "\n"
+	result += "State: " + rsw.State.String() + "\n"
+	switch rsw.State {
+	case WorkerStateCopy:
+		result += "Running:\n"
+		result += "Copying from: " + rsw.sourceAlias.String() + "\n"
+		statuses, eta := formatTableStatuses(rsw.tableStatus, rsw.startTime)
+		result += "ETA: " + eta.String() + "\n"
+		result += strings.Join(statuses, "\n")
+	case WorkerStateReplay:
+		result += "Replaying: " + strings.Join(rsw.tables, ", ") + "\n"
+		result += strings.Join(rsw.playerStatuses(), "\n")
+	}
+	return result
+}
+
+// Run implements the Worker interface. The source tablet used for
+// the copy is cleaned up before the replay starts.
+func (rsw *ReferenceSyncWorker) Run(ctx context.Context) error {
+	resetVars()
+	err := rsw.run(ctx)
+
+	rsw.setState(WorkerStateCleanUp)
+	cerr := rsw.cleaner.CleanUp(rsw.wr)
+	if cerr != nil {
+		if err != nil {
+			rsw.wr.Logger().Errorf("CleanUp failed in addition to job error: %v", cerr)
+		} else {
+			err = cerr
+		}
+	}
+	if err == nil {
+		err = rsw.replay(ctx)
+	}
+	if err != nil {
+		rsw.setErrorState(err)
+		return err
+	}
+	rsw.setState(WorkerStateDone)
+	return nil
+}
+
+func (rsw *ReferenceSyncWorker) run(ctx context.Context) error {
+	// first state: read what we need to do
+	if err := rsw.init(ctx); err != nil {
+		return fmt.Errorf("init() failed: %v", err)
+	}
+	if err := checkDone(ctx); err != nil {
+		return err
+	}
+
+	if rsw.skipCopy {
+		if err := rsw.ResolveDestinationMasters(ctx); err != nil {
+			return fmt.Errorf("ResolveDestinationMasters() failed: %v", err)
+		}
+		return nil
+	}
+
+	// second state: find targets
+	if err := rsw.findTargets(ctx); err != nil {
+		return fmt.Errorf("findTargets() failed: %v", err)
+	}
+	if err := checkDone(ctx); err != nil {
+		return err
+	}
+
+	// third state: copy data
+	if err := rsw.copy(ctx); err != nil {
+		return fmt.Errorf("copy() failed: %v", err)
+	}
+	return checkDone(ctx)
+}
+
+// init phase:
+// - make sure the source keyspace is unsharded
+// - read the shards of the destination keyspace
+func (rsw *ReferenceSyncWorker) init(ctx context.Context) error {
+	rsw.setState(WorkerStateInit)
+
+	shortCtx, cancel := context.WithTimeout(ctx, *remoteActionsTimeout)
+	sourceShards, err := rsw.wr.TopoServer().GetShardNames(shortCtx, rsw.sourceKeyspace)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("cannot read shards of source keyspace %v: %v", rsw.sourceKeyspace, err)
+	}
+	if len(sourceShards) != 1 || sourceShards[0] != rsw.sourceShard {
+		return fmt.Errorf("source keyspace %v must have the single shard %v, has %v", rsw.sourceKeyspace, rsw.sourceShard, sourceShards)
+	}
+
+	shortCtx, cancel = context.WithTimeout(ctx, *remoteActionsTimeout)
+	destinationShards, err := rsw.wr.TopoServer().GetShardNames(shortCtx, rsw.destinationKeyspace)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("cannot read shards of destination keyspace %v: %v", rsw.destinationKeyspace, err)
+	}
+	if len(destinationShards) == 0 {
+		return fmt.Errorf("destination keyspace %v has no shards", rsw.destinationKeyspace)
+	}
+	sort.Strings(destinationShards)
+
+	rsw.Mu.Lock()
+	rsw.destinationShards = destinationShards
+	rsw.Mu.Unlock()
+	return nil
+}
+
+// findTargets phase:
+// - find one rdonly in the source shard
+// - mark it as 'worker' pointing back to us
+// - get the aliases of all the targets
+func (rsw *ReferenceSyncWorker) findTargets(ctx context.Context) error {
+	rsw.setState(WorkerStateFindTargets)
+
+	// find an appropriate endpoint in the source shard
+	var err error
+	rsw.sourceAlias, err = FindWorkerTablet(ctx, rsw.wr, rsw.cleaner, rsw.cell, rsw.sourceKeyspace, rsw.sourceShard)
+	if err != nil {
+		return fmt.Errorf("FindWorkerTablet() failed for %v/%v/%v: %v", rsw.cell, rsw.sourceKeyspace, rsw.sourceShard, err)
+	}
+	rsw.wr.Logger().Infof("Using tablet %v as the source", rsw.sourceAlias)
+
+	// get the tablet info for it
+	rsw.sourceTablet, err = rsw.wr.TopoServer().GetTablet(ctx, rsw.sourceAlias)
+	if err != nil {
+		return fmt.Errorf("cannot read tablet %v: %v", rsw.sourceAlias, err)
+	}
+
+	// stop replication on it
+	shortCtx, cancel := context.WithTimeout(ctx, *remoteActionsTimeout)
+	err = rsw.wr.TabletManagerClient().StopSlave(shortCtx, rsw.sourceTablet)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("cannot stop replication on tablet %v", rsw.sourceAlias)
+	}
+
+	wrangler.RecordStartSlaveAction(rsw.cleaner, rsw.sourceTablet)
+	action, err := wrangler.FindChangeSlaveTypeActionByTarget(rsw.cleaner, rsw.sourceAlias)
+	if err != nil {
+		return fmt.Errorf("cannot find ChangeSlaveType action for %v: %v", rsw.sourceAlias, err)
+	}
+	action.TabletType = topo.TYPE_SPARE
+
+	return rsw.ResolveDestinationMasters(ctx)
+}
+
+// ResolveDestinationMasters implements the Resolver interface.
+// It will attempt to resolve all shards and update rsw.destinationShardsToTablets;
+// if it is unable to do so, it will not modify rsw.destinationShardsToTablets at all.
+func (rsw *ReferenceSyncWorker) ResolveDestinationMasters(ctx context.Context) error {
+	statsDestinationAttemptedResolves.Add(1)
+	// Allow at most one resolution request at a time; if there are concurrent requests, only
+	// one of them will actualy hit the topo server.
+	rsw.resolveMu.Lock()
+	defer rsw.resolveMu.Unlock()
+
+	// If the last resolution was fresh enough, return it.
+	if time.Now().Sub(rsw.resolveTime) < *resolveTTL {
+		return nil
+	}
+
+	destinationShardsToTablets := make(map[string]*topo.TabletInfo)
+	for _, shard := range rsw.destinationShards {
+		ti, err := resolveDestinationShardMaster(ctx, rsw.destinationKeyspace, shard, rsw.wr)
+		if err != nil {
+			return err
+		}
+		destinationShardsToTablets[shard] = ti
+	}
+	rsw.destinationShardsToTablets = destinationShardsToTablets
+	// save the time of the last successful resolution
+	rsw.resolveTime = time.Now()
+	statsDestinationActualResolves.Add(1)
+	return nil
+}
+
+// GetDestinationMaster implements the Resolver interface
+func (rsw *ReferenceSyncWorker) GetDestinationMaster(shardName string) (*topo.TabletInfo, error) {
+	rsw.resolveMu.Lock()
+	defer rsw.resolveMu.Unlock()
+	ti, ok := rsw.destinationShardsToTablets[shardName]
+	if !ok {
+		return nil, fmt.Errorf("no tablet found for destination shard %v", shardName)
+	}
+	return ti, nil
+}
+
+// copy phase:
+//   - copy the tables from the source tablet to every destination master
+//   - create and populate the blp_checkpoint table of every destination
+//
+// Assumes that the schema has already been created on each destination tablet
+// (probably from vtctl's CopySchemaShard)
+func (rsw *ReferenceSyncWorker) copy(ctx context.Context) error {
+	rsw.setState(WorkerStateCopy)
+
+	// get source schema
+	shortCtx, cancel := context.WithTimeout(ctx, *remoteActionsTimeout)
+	sourceSchemaDefinition, err := rsw.wr.GetSchema(shortCtx, rsw.sourceAlias, rsw.tables, nil, false)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("cannot get schema from source %v: %v", rsw.sourceAlias, err)
+	}
+	if len(sourceSchemaDefinition.TableDefinitions) == 0 {
+		return fmt.Errorf("no tables matching the table filter")
+	}
+	rsw.wr.Logger().Infof("Source tablet has %v tables to copy", len(sourceSchemaDefinition.TableDefinitions))
+	rsw.Mu.Lock()
+	rsw.tables = nil
+	rsw.tableStatus = make([]*tableStatus, len(sourceSchemaDefinition.TableDefinitions))
+	for i, td := range sourceSchemaDefinition.TableDefinitions {
+		rsw.tables = append(rsw.tables, td.Name)
+		rsw.tableStatus[i] = &tableStatus{
+			name:     td.Name,
+			rowCount: td.RowCount,
+		}
+	}
+	rsw.startTime = time.Now()
+	rsw.Mu.Unlock()
+
+	// In parallel, setup the channels to send SQL data chunks to
+	// for each destination tablet.
+	//
+	// mu protects firstError
+	mu := sync.Mutex{}
+	var firstError error
+
+	ctx, cancelCopy := context.WithCancel(ctx)
+	defer cancelCopy()
+	processError := func(format string, args ...interface{}) {
+		rsw.wr.Logger().Errorf(format, args...)
+		mu.Lock()
+		if firstError == nil {
+			firstError = fmt.Errorf(format, args...)
+			cancelCopy()
+		}
+		mu.Unlock()
+	}
+
+	// we create one channel per destination shard. Every row
+	// read from the source is sent to all of them.
+	destinationWaitGroup := sync.WaitGroup{}
+	insertChannels := make([]chan string, len(rsw.destinationShards))
+	for i, shard := range rsw.destinationShards {
+		insertChannels[i] = make(chan string, rsw.destinationWriterCount*2)
+		for j := 0; j < rsw.destinationWriterCount; j++ {
+			destinationWaitGroup.Add(1)
+			go func(shard string, insertChannel chan string) {
+				defer destinationWaitGroup.Done()
+
+				if err := executeFetchLoop(ctx, rsw.wr, rsw, shard, insertChannel); err != nil {
+					processError("executeFetchLoop failed: %v", err)
+				}
+			}(shard, insertChannels[i])
+		}
+	}
+
+	// Now for each table, read data chunks and send them to all insertChannels
+	sourceWaitGroup := sync.WaitGroup{}
+	sema := sync2.NewSemaphore(rsw.sourceReaderCount, 0)
+	for tableIndex, td := range sourceSchemaDefinition.TableDefinitions {
+		chunks, err := FindChunks(ctx, rsw.wr, rsw.sourceTablet, td, rsw.minTableSizeForSplit, rsw.sourceReaderCount)
+		if err != nil {
+			return err
+		}
+		rsw.tableStatus[tableIndex].setThreadCount(len(chunks) - 1)
+
+		for chunkIndex := 0; chunkIndex < len(chunks)-1; chunkIndex++ {
+			sourceWaitGroup.Add(1)
+			go func(td *myproto.TableDefinition, tableIndex, chunkIndex int) {
+				defer sourceWaitGroup.Done()
+
+				sema.Acquire()
+				defer sema.Release()
+
+				rsw.tableStatus[tableIndex].threadStarted()
+
+				// build the query, and start the streaming
+				selectSQL := buildSQLFromChunks(rsw.wr, td, chunks, chunkIndex, rsw.sourceAlias.String())
+				qrr, err := NewQueryResultReaderForTablet(ctx, rsw.wr.TopoServer(), rsw.sourceAlias, selectSQL)
+				if err != nil {
+					processError("NewQueryResultReaderForTablet failed: %v", err)
+					return
+				}
+				defer qrr.Close()
+
+				// process the data
+				if err := rsw.processData(td, tableIndex, qrr, insertChannels, ctx.Done()); err != nil {
+					processError("QueryResultReader failed: %v", err)
+				}
+				rsw.tableStatus[tableIndex].threadDone()
+			}(td, tableIndex, chunkIndex)
+		}
+	}
+	sourceWaitGroup.Wait()
+
+	for _, insertChannel := range insertChannels {
+		close(insertChannel)
+	}
+	destinationWaitGroup.Wait()
+	if firstError != nil {
+		return firstError
+	}
+
+	// then create and populate the blp_checkpoint table with the
+	// position of the copy
+	shortCtx, cancel = context.WithTimeout(ctx, *remoteActionsTimeout)
+	status, err := rsw.wr.TabletManagerClient().SlaveStatus(shortCtx, rsw.sourceTablet)
+	cancel()
+	if err != nil {
+		return err
+	}
+	queries := binlogplayer.CreateBlpCheckpoint()
+	queries = append(queries, binlogplayer.PopulateBlpCheckpoint(referenceSyncBlpUid, status.Position, time.Now().Unix(), ""))
+	for _, shard := range rsw.destinationShards {
+		destinationWaitGroup.Add(1)
+		go func(shard string) {
+			defer destinationWaitGroup.Done()
+			rsw.wr.Logger().Infof("Making and populating blp_checkpoint table on shard %v", shard)
+			if err := runSqlCommands(ctx, rsw.wr, rsw, shard, queries); err != nil {
+				processError("blp_checkpoint queries failed: %v", err)
+			}
+		}(shard)
+	}
+	destinationWaitGroup.Wait()
+	return firstError
+}
+
+// processData pumps the data out of the provided QueryResultReader,
+// and sends it to all the insert channels.
+// It returns any error the source encounters.
+func (rsw *ReferenceSyncWorker) processData(td *myproto.TableDefinition, tableIndex int, qrr *QueryResultReader, insertChannels []chan string, abort <-chan struct{}) error {
+	baseCmd := td.Name + "(" + strings.Join(td.Columns, ", ") + ") VALUES "
+	var rows [][]sqltypes.Value
+	packCount := 0
+
+	sendRows := func() bool {
+		cmd := baseCmd + makeValueString(qrr.Fields, rows)
+		for _, insertChannel := range insertChannels {
+			select {
+			case insertChannel <- cmd:
+			case <-abort:
+				return false
+			}
+		}
+		return true
+	}
+
+	for {
+		select {
+		case r, ok := <-qrr.Output:
+			if !ok {
+				// we are done, see if there was an error
+				if err := qrr.Error(); err != nil {
+					return err
+				}
+
+				// send the remainder if any
+				if packCount > 0 {
+					sendRows()
+				}
+				return nil
+			}
+
+			// add the rows to our current result
+			rows = append(rows, r.Rows...)
+			rsw.tableStatus[tableIndex].addCopiedRows(len(r.Rows))
+
+			// see if we reach the destination pack count
+			packCount++
+			if packCount < rsw.destinationPackCount {
+				continue
+			}
+
+			// send the rows to be inserted
+			if !sendRows() {
+				return nil
+			}
+
+			// and reset our row buffer
+			rows = nil
+			packCount = 0
+
+		case <-abort:
+			return nil
+		}
+	}
+}
+
+// replay phase:
+//   - replay the binlog stream of the source shard on every destination
+//     master, from the position of its blp_checkpoint row, until canceled.
+func (rsw *ReferenceSyncWorker) replay(ctx context.Context) error {
+	rsw.setState(WorkerStateReplay)
+
+	if rsw.skipCopy {
+		if err := rsw.resolveTables(ctx); err != nil {
+			return err
+		}
+	}
+
+	wg := sync.WaitGroup{}
+	for _, shard := range rsw.destinationShards {
+		stats := binlogplayer.NewBinlogPlayerStats()
+		rsw.Mu.Lock()
+		rsw.playerStats[shard] = stats
+		rsw.Mu.Unlock()
+
+		wg.Add(1)
+		go func(shard string) {
+			defer wg.Done()
+			for {
+				err := rsw.replayShard(ctx, shard, stats)
+				if err == nil {
+					// this happens when we get interrupted
+					return
+				}
+				rsw.wr.Logger().Warningf("Replaying binlogs on shard %v failed, will retry: %v", shard, err)
+
+				// sleep for a bit before retrying to connect
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(5 * time.Second):
+				}
+			}
+		}(shard)
+	}
+	wg.Wait()
+	return nil
+}
+
+// resolveTables resolves the table filter from the schema of the first
+// destination master, when the copy phase that does it was skipped.
+func (rsw *ReferenceSyncWorker) resolveTables(ctx context.Context) error {
+	ti, err := rsw.GetDestinationMaster(rsw.destinationShards[0])
+	if err != nil {
+		return err
+	}
+	shortCtx, cancel := context.WithTimeout(ctx, *remoteActionsTimeout)
+	sd, err := rsw.wr.GetSchema(shortCtx, ti.Alias, rsw.tables, nil, false)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("cannot get schema from destination %v: %v", ti.Alias, err)
+	}
+	if len(sd.TableDefinitions) == 0 {
+		return fmt.Errorf("no tables matching the table filter")
+	}
+	var tables []string
+	for _, td := range sd.TableDefinitions {
+		tables = append(tables, td.Name)
+	}
+	rsw.Mu.Lock()
+	rsw.tables = tables
+	rsw.Mu.Unlock()
+	return nil
+}
+
+// replayShard plays the binlog stream of the source shard on the
+// master of a destination shard, until interrupted, or until an
+// error occurs.
+func (rsw *ReferenceSyncWorker) replayShard(ctx context.Context, shard string, stats *binlogplayer.BinlogPlayerStats) error {
+	vtClient := &tabletVtClient{
+		ctx:   ctx,
+		wr:    rsw.wr,
+		r:     rsw,
+		shard: shard,
+	}
+	if err := vtClient.Connect(); err != nil {
+		return err
+	}
+	defer vtClient.Close()
+
+	startPosition, flags, err := binlogplayer.ReadStartPosition(vtClient, referenceSyncBlpUid)
+	if err != nil {
+		return fmt.Errorf("can't read startPosition: %v", err)
+	}
+	if strings.Index(flags, binlogplayer.BlpFlagDontStart) != -1 {
+		return fmt.Errorf("not starting because flag '%v' is set", binlogplayer.BlpFlagDontStart)
+	}
+
+	addrs, _, err := rsw.wr.TopoServer().GetEndPoints(ctx, rsw.cell, rsw.sourceKeyspace, rsw.sourceShard, topo.TYPE_REPLICA)
+	if err != nil {
+		return fmt.Errorf("can't find any source tablet for %v %v/%v %v: %v", rsw.cell, rsw.sourceKeyspace, rsw.sourceShard, topo.TYPE_REPLICA, err)
+	}
+	if len(addrs.Entries) == 0 {
+		return fmt.Errorf("empty source tablet list for %v %v/%v %v", rsw.cell, rsw.sourceKeyspace, rsw.sourceShard, topo.TYPE_REPLICA)
+	}
+	endPoint := addrs.Entries[rand.Intn(len(addrs.Entries))]
+
+	player := binlogplayer.NewBinlogPlayerTables(vtClient, endPoint, rsw.tables, startPosition, myproto.ReplicationPosition{}, stats)
+	return player.ApplyBinlogEvents(ctx)
+}
+
+// tabletVtClient is a binlogplayer.VtClient that runs the queries on
+// the master of a destination shard, through the tablet manager.
+// The statements of a transaction are buffered until Commit, which
+// runs them one by one in a transaction on a dba connection of the
+// tablet. The first statement of a transaction is the update of the
+// blp_checkpoint row, so the row is updated if and only if the
+// transaction is committed.
+type tabletVtClient struct {
+	ctx   context.Context
+	wr    *wrangler.Wrangler
+	r     Resolver
+	shard string
+
+	ti      *topo.TabletInfo
+	inTx    bool
+	queries []string
+}
+
+// Connect is part of the binlogplayer.VtClient interface.
+func (tc *tabletVtClient) Connect() error {
+	ti, err := tc.r.GetDestinationMaster(tc.shard)
+	if err != nil {
+		return err
+	}
+	tc.ti = ti
+	return nil
+}
+
+// Begin is part of the binlogplayer.VtClient interface.
+func (tc *tabletVtClient) Begin() error {
+	tc.inTx = true
+	tc.queries = nil
+	return nil
+}
+
+// Commit is part of the binlogplayer.VtClient interface.
+// If a statement fails, the tablet rolls the transaction back.
+func (tc *tabletVtClient) Commit() error {
+	queries := tc.queries
+	tc.inTx = false
+	tc.queries = nil
+	if len(queries) == 0 {
+		return nil
+	}
+	shortCtx, cancel := context.WithTimeout(tc.ctx, *remoteActionsTimeout)
+	defer cancel()
+	if err := tc.wr.TabletManagerClient().ExecuteTransactionAsDba(shortCtx, tc.ti, queries); err != nil {
+		return fmt.Errorf("transaction failed on %v: %v", tc.ti.Alias, err)
+	}
+	return nil
+}
+
+// Rollback is part of the binlogplayer.VtClient interface.
+func (tc *tabletVtClient) Rollback() error {
+	tc.inTx = false
+	tc.queries = nil
+	return nil
+}
+
+// Close is part of the binlogplayer.VtClient interface.
+func (tc *tabletVtClient) Close() {
+}
+
+// ExecuteFetch is part of the binlogplayer.VtClient interface.
+// Queries sent in a transaction are buffered, and reported as
+// affecting one row.
+func (tc *tabletVtClient) ExecuteFetch(query string, maxrows int, wantfields bool) (*mproto.QueryResult, error) {
+	if tc.inTx {
+		tc.queries = append(tc.queries, query)
+		return &mproto.QueryResult{RowsAffected: 1}, nil
+	}
+	shortCtx, cancel := context.WithTimeout(tc.ctx, *remoteActionsTimeout)
+	defer cancel()
+	return tc.wr.TabletManagerClient().ExecuteFetchAsApp(shortCtx, tc.ti, query, maxrows, wantfields)
+}
@@ -0,0 +1,149 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/wrangler"
+	"golang.org/x/net/context"
+)
+
+const referenceSyncHTML = `
+<!DOCTYPE html>
+<head>
+  <title>Reference Sync Action</title>
+</head>
+<body>
+  <h1>Reference Sync Action</h1>
+
+    {{if .Error}}
+      <b>Error:</b> {{.Error}}</br>
+    {{else}}
+      <form action="/Clones/ReferenceSync" method="post">
+        <LABEL for="source">Source keyspace/shard: </LABEL>
+          <INPUT type="text" id="source" name="source" value=""></BR>
+        <LABEL for="keyspace">Destination keyspace: </LABEL>
+          <INPUT type="text" id="keyspace" name="keyspace" value=""></BR>
+        <LABEL for="tables">Tables: </LABEL>
+          <INPUT type="text" id="tables" name="tables" value=""></BR>
+        <LABEL for="skipCopy">Skip Copy: </LABEL>
+          <INPUT type="checkbox" id="skipCopy" name="skipCopy" value="true"></BR>
+        <LABEL for="sourceReaderCount">Source Reader Count: </LABEL>
+          <INPUT type="text" id="sourceReaderCount" name="sourceReaderCount" value="{{.DefaultSourceReaderCount}}"></BR>
+        <LABEL for="destinationPackCount">Destination Pack Count: </LABEL>
+          <INPUT type="text" id="destinationPackCount" name="destinationPackCount" value="{{.DefaultDestinationPackCount}}"></BR>
+        <LABEL for="minTableSizeForSplit">Minimun Table Size For Split: </LABEL>
+          <INPUT type="text" id="minTableSizeForSplit" name="minTableSizeForSplit" value="{{.DefaultMinTableSizeForSplit}}"></BR>
+        <LABEL for="destinationWriterCount">Destination Writer Count: </LABEL>
+          <INPUT type="text" id="destinationWriterCount" name="destinationWriterCount" value="{{.DefaultDestinationWriterCount}}"></BR>
+        <INPUT type="submit" value="Sync"/>
+      </form>
+    {{end}}
+
+  <h1>Help</h1>
+    <p>Copies the reference tables from the unsharded source keyspace to every shard of the destination keyspace, and then replays the binlog stream of the source on every destination master until the worker is canceled. Each transaction is replayed as one multi-statement query, so the destination tablets need CLIENT_MULTI_STATEMENTS (65536) in their -db-config-dba-flags.</p>
+    <p>Skip Copy restarts the replay from the positions saved by a previous run.</p>
+  </body>
+`
+
+var referenceSyncTemplate = mustParseTemplate("referenceSync", referenceSyncHTML)
+
+func commandReferenceSync(wi *Instance, wr *wrangler.Wrangler, subFlags *flag.FlagSet, args []string) (Worker, error) {
+	tables := subFlags.String("tables", "", "comma separated list of reference tables to copy and sync")
+	skipCopy := subFlags.Bool("skip_copy", false, "don't copy the tables, restart the replay from the positions saved by a previous run")
+	sourceReaderCount := subFlags.Int("source_reader_count", defaultSourceReaderCount, "number of concurrent streaming queries to use on the source")
+	destinationPackCount := subFlags.Int("destination_pack_count", defaultDestinationPackCount, "number of packets to pack in one destination insert")
+	minTableSizeForSplit := subFlags.Int("min_table_size_for_split", defaultMinTableSizeForSplit, "tables bigger than this size on disk in bytes will be split into source_reader_count chunks if possible")
+	destinationWriterCount := subFlags.Int("destination_writer_count", defaultDestinationWriterCount, "number of concurrent RPCs to execute on each destination shard")
+	if err := subFlags.Parse(args); err != nil {
+		return nil, err
+	}
+	if subFlags.NArg() != 2 {
+		subFlags.Usage()
+		return nil, fmt.Errorf("command ReferenceSync requires <source keyspace/shard> <destination keyspace>")
+	}
+
+	sourceKeyspace, sourceShard, err := topo.ParseKeyspaceShardString(subFlags.Arg(0))
+	if err != nil {
+		return nil, err
+	}
+	var tableArray []string
+	if *tables != "" {
+		tableArray = strings.Split(*tables, ",")
+	}
+	worker, err := NewReferenceSyncWorker(wr, wi.cell, sourceKeyspace, sourceShard, subFlags.Arg(1), tableArray, *skipCopy, *sourceReaderCount, *destinationPackCount, uint64(*minTableSizeForSplit), *destinationWriterCount)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create worker: %v", err)
+	}
+	return worker, nil
+}
+
+func interactiveReferenceSync(ctx context.Context, wi *Instance, wr *wrangler.Wrangler, w http.ResponseWriter, r *http.Request) (Worker, *template.Template, map[string]interface{}, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot parse form: %s", err)
+	}
+
+	source := r.FormValue("source")
+	keyspace := r.FormValue("keyspace")
+	tables := r.FormValue("tables")
+	if source == "" || keyspace == "" || tables == "" {
+		// display the input form
+		result := make(map[string]interface{})
+		result["DefaultSourceReaderCount"] = fmt.Sprintf("%v", defaultSourceReaderCount)
+		result["DefaultDestinationPackCount"] = fmt.Sprintf("%v", defaultDestinationPackCount)
+		result["DefaultMinTableSizeForSplit"] = fmt.Sprintf("%v", defaultMinTableSizeForSplit)
+		result["DefaultDestinationWriterCount"] = fmt.Sprintf("%v", defaultDestinationWriterCount)
+		return nil, referenceSyncTemplate, result, nil
+	}
+	sourceKeyspace, sourceShard, err := topo.ParseKeyspaceShardString(source)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot parse source: %s", err)
+	}
+	tableArray := strings.Split(tables, ",")
+
+	// get other parameters
+	skipCopy := r.FormValue("skipCopy") == "true"
+	sourceReaderCountStr := r.FormValue("sourceReaderCount")
+	sourceReaderCount, err := strconv.ParseInt(sourceReaderCountStr, 0, 64)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot parse sourceReaderCount: %s", err)
+	}
+	destinationPackCountStr := r.FormValue("destinationPackCount")
+	destinationPackCount, err := strconv.ParseInt(destinationPackCountStr, 0, 64)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot parse destinationPackCount: %s", err)
+	}
+	minTableSizeForSplitStr := r.FormValue("minTableSizeForSplit")
+	minTableSizeForSplit, err := strconv.ParseInt(minTableSizeForSplitStr, 0, 64)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot parse minTableSizeForSplit: %s", err)
+	}
+	destinationWriterCountStr := r.FormValue("destinationWriterCount")
+	destinationWriterCount, err := strconv.ParseInt(destinationWriterCountStr, 0, 64)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot parse destinationWriterCount: %s", err)
+	}
+
+	// start the sync job
+	wrk, err := NewReferenceSyncWorker(wr, wi.cell, sourceKeyspace, sourceShard, keyspace, tableArray, skipCopy, int(sourceReaderCount), int(destinationPackCount), uint64(minTableSizeForSplit), int(destinationWriterCount))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot create worker: %v", err)
+	}
+	return wrk, nil, nil, nil
+}
+
+func init() {
+	AddCommand("Clones", Command{"ReferenceSync",
+		commandReferenceSync, interactiveReferenceSync,
+		"[--tables=''] [--skip_copy] <source keyspace/shard> <destination keyspace>",
+		"Copies reference tables to every shard of a keyspace, and keeps them in sync from the binlog stream of the source."})
+}
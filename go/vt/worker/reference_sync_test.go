@@ -0,0 +1,175 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	mproto "github.com/youtube/vitess/go/mysql/proto"
+	"github.com/youtube/vitess/go/sqltypes"
+	"github.com/youtube/vitess/go/vt/binlog/binlogplayer"
+	"github.com/youtube/vitess/go/vt/logutil"
+	myproto "github.com/youtube/vitess/go/vt/mysqlctl/proto"
+	"github.com/youtube/vitess/go/vt/tabletmanager/tmclient"
+	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/wrangler"
+	"golang.org/x/net/context"
+)
+
+// checkpointPosition extracts the position from a blp_checkpoint update.
+var checkpointPosition = regexp.MustCompile(`SET pos='([^']*)'`)
+
+// fakeCheckpointTMC is a tmclient.TabletManagerClient that records the
+// transactions it's sent, and emulates a blp_checkpoint row: a
+// transaction that contains a checkpoint update and no failing
+// statement updates the row.
+type fakeCheckpointTMC struct {
+	tmclient.TabletManagerClient
+
+	transactions [][]string
+	appQueries   []string
+	pos          string
+	// fail makes the transactions that contain it fail.
+	fail string
+}
+
+func (tmc *fakeCheckpointTMC) ExecuteTransactionAsDba(ctx context.Context, tablet *topo.TabletInfo, queries []string) error {
+	tmc.transactions = append(tmc.transactions, queries)
+	pos := tmc.pos
+	for _, query := range queries {
+		if tmc.fail != "" && strings.Contains(query, tmc.fail) {
+			return fmt.Errorf("mysql error")
+		}
+		if match := checkpointPosition.FindStringSubmatch(query); match != nil {
+			pos = match[1]
+		}
+	}
+	tmc.pos = pos
+	return nil
+}
+
+func (tmc *fakeCheckpointTMC) ExecuteFetchAsApp(ctx context.Context, tablet *topo.TabletInfo, query string, maxRows int, wantFields bool) (*mproto.QueryResult, error) {
+	tmc.appQueries = append(tmc.appQueries, query)
+	return &mproto.QueryResult{
+		RowsAffected: 1,
+		Rows: [][]sqltypes.Value{{
+			sqltypes.MakeString([]byte(tmc.pos)),
+			sqltypes.MakeString(nil),
+		}},
+	}, nil
+}
+
+// fakeResolver is a Resolver that always resolves the same tablet.
+type fakeResolver struct {
+	ti *topo.TabletInfo
+}
+
+func (r *fakeResolver) ResolveDestinationMasters(ctx context.Context) error {
+	return nil
+}
+
+func (r *fakeResolver) GetDestinationMaster(shardName string) (*topo.TabletInfo, error) {
+	return r.ti, nil
+}
+
+func newTestTabletVtClient(tmc *fakeCheckpointTMC) *tabletVtClient {
+	ti := topo.NewTabletInfo(&topo.Tablet{
+		Alias:    topo.TabletAlias{Cell: "cell1", Uid: 1},
+		Keyspace: "ks",
+		Shard:    "-80",
+	}, 0)
+	return &tabletVtClient{
+		ctx:   context.Background(),
+		wr:    wrangler.New(logutil.NewConsoleLogger(), nil, tmc, time.Minute),
+		r:     &fakeResolver{ti: ti},
+		shard: "-80",
+	}
+}
+
+func testCheckpoint(t *testing.T, gtid string) (string, string) {
+	pos, err := myproto.DecodeReplicationPosition("MariaDB/" + gtid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return binlogplayer.UpdateBlpCheckpoint(referenceSyncBlpUid, pos, 1, 0), myproto.EncodeReplicationPosition(pos)
+}
+
+func TestTabletVtClientCommit(t *testing.T) {
+	tmc := &fakeCheckpointTMC{}
+	tc := newTestTabletVtClient(tmc)
+	if err := tc.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint, pos := testCheckpoint(t, "0-1-2")
+
+	tc.Begin()
+	for _, query := range []string{checkpoint, "insert into a values(1)", "update b set c=1"} {
+		qr, err := tc.ExecuteFetch(query, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if qr.RowsAffected != 1 {
+			t.Errorf("ExecuteFetch in transaction: got %v rows affected, want 1", qr.RowsAffected)
+		}
+	}
+	if len(tmc.transactions) != 0 || len(tmc.appQueries) != 0 {
+		t.Errorf("queries were sent before Commit: %v %v", tmc.transactions, tmc.appQueries)
+	}
+	if err := tc.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{checkpoint, "insert into a values(1)", "update b set c=1"}}
+	if !reflect.DeepEqual(tmc.transactions, want) {
+		t.Errorf("transactions: %#v, want %#v", tmc.transactions, want)
+	}
+	if tmc.pos != pos {
+		t.Errorf("checkpoint position: %v, want %v", tmc.pos, pos)
+	}
+
+	// An empty transaction sends nothing.
+	tc.Begin()
+	if err := tc.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if len(tmc.transactions) != 1 {
+		t.Errorf("empty transaction was sent: %v", tmc.transactions[1:])
+	}
+
+	// A rolled back transaction sends nothing.
+	tc.Begin()
+	tc.ExecuteFetch(checkpoint, 0, false)
+	tc.Rollback()
+	if len(tmc.transactions) != 1 {
+		t.Errorf("rolled back transaction was sent: %v", tmc.transactions[1:])
+	}
+}
+
+func TestTabletVtClientCommitFail(t *testing.T) {
+	tmc := &fakeCheckpointTMC{}
+	tc := newTestTabletVtClient(tmc)
+	if err := tc.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint, _ := testCheckpoint(t, "0-1-2")
+
+	// A failing statement fails the transaction, and leaves
+	// the checkpoint alone.
+	tmc.fail = "update b"
+	tc.Begin()
+	tc.ExecuteFetch(checkpoint, 0, false)
+	tc.ExecuteFetch("update b set c=1", 0, false)
+	want := "transaction failed on cell1-0000000001: mysql error"
+	if err := tc.Commit(); err == nil || err.Error() != want {
+		t.Errorf("Commit: %v, want %v", err, want)
+	}
+	if tmc.pos != "" {
+		t.Errorf("checkpoint position: %v, want none", tmc.pos)
+	}
+}
@@ -25,6 +25,7 @@ const (
 	WorkerStateCopy            StatusWorkerState = "copying the data"
 	WorkerStateDiff            StatusWorkerState = "running the diff"
 	WorkerStateCleanUp         StatusWorkerState = "cleaning up"
+	WorkerStateReplay          StatusWorkerState = "replaying the binlog stream"
 )
 
 func (state StatusWorkerState) String() string {
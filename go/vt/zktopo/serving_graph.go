@@ -300,76 +300,131 @@ func (zkts *Server) WatchEndPoints(ctx context.Context, cell, keyspace, shard st
 
 	notifications := make(chan *topo.EndPoints, 10)
 	stopWatching := make(chan struct{})
+	go func() {
+		zkts.watchNode(filePath, stopWatching, func(data string, stat zk.Stat) {
+			// send the value, or nil if no data
+			var ep *topo.EndPoints
+			if len(data) > 0 {
+				ep = &topo.EndPoints{}
+				if err := json.Unmarshal([]byte(data), ep); err != nil {
+					log.Errorf("EndPoints unmarshal failed: %v %v", data, err)
+					return
+				}
+			}
+			notifications <- ep
+		})
+		close(notifications)
+	}()
+	return notifications, stopWatching, nil
+}
 
+// WatchSrvKeyspace is part of the topo.Server interface
+func (zkts *Server) WatchSrvKeyspace(ctx context.Context, cell, keyspace string) (<-chan *topo.SrvKeyspace, chan<- struct{}, error) {
+	filePath := zkPathForVtKeyspace(cell, keyspace)
+
+	notifications := make(chan *topo.SrvKeyspace, 10)
+	stopWatching := make(chan struct{})
+	go func() {
+		zkts.watchNode(filePath, stopWatching, func(data string, stat zk.Stat) {
+			// send the value, or nil if the node doesn't exist
+			var srvKeyspace *topo.SrvKeyspace
+			if stat != nil {
+				srvKeyspace = topo.NewSrvKeyspace(int64(stat.Version()))
+				if len(data) > 0 {
+					if err := json.Unmarshal([]byte(data), srvKeyspace); err != nil {
+						log.Errorf("SrvKeyspace unmarshal failed: %v %v", data, err)
+						return
+					}
+				}
+			}
+			notifications <- srvKeyspace
+		})
+		close(notifications)
+	}()
+	return notifications, stopWatching, nil
+}
+
+// WatchSrvShard is part of the topo.Server interface
+func (zkts *Server) WatchSrvShard(ctx context.Context, cell, keyspace, shard string) (<-chan *topo.SrvShard, chan<- struct{}, error) {
+	filePath := zkPathForVtShard(cell, keyspace, shard)
+
+	notifications := make(chan *topo.SrvShard, 10)
+	stopWatching := make(chan struct{})
+	go func() {
+		zkts.watchNode(filePath, stopWatching, func(data string, stat zk.Stat) {
+			// send the value, or nil if the node doesn't exist
+			var srvShard *topo.SrvShard
+			if stat != nil {
+				srvShard = topo.NewSrvShard(int64(stat.Version()))
+				if len(data) > 0 {
+					if err := json.Unmarshal([]byte(data), srvShard); err != nil {
+						log.Errorf("SrvShard unmarshal failed: %v %v", data, err)
+						return
+					}
+				}
+			}
+			notifications <- srvShard
+		})
+		close(notifications)
+	}()
+	return notifications, stopWatching, nil
+}
+
+// watchNode sets a watch on filePath, and calls notify with the
+// contents and stat of the node every time the watch fires, until
+// stopWatching is closed. If the node doesn't exist, notify is called
+// with a nil stat, and the watch is retried every WatchSleepDuration.
+func (zkts *Server) watchNode(filePath string, stopWatching <-chan struct{}, notify func(data string, stat zk.Stat)) {
 	// waitOrInterrupted will return true if stopWatching is triggered
 	waitOrInterrupted := func() bool {
 		timer := time.After(WatchSleepDuration)
 		select {
 		case <-stopWatching:
-			close(notifications)
 			return true
 		case <-timer:
 		}
 		return false
 	}
 
-	go func() {
-		for {
-			// set the watch
-			data, _, watch, err := zkts.zconn.GetW(filePath)
-			if err != nil {
-				if zookeeper.IsError(err, zookeeper.ZNONODE) {
-					// the parent directory doesn't exist
-					notifications <- nil
-				}
+	for {
+		// set the watch
+		data, stat, watch, err := zkts.zconn.GetW(filePath)
+		if err != nil {
+			if zookeeper.IsError(err, zookeeper.ZNONODE) {
+				// the node or its parent directory doesn't exist
+				notify("", nil)
+			}
+
+			log.Errorf("Cannot set watch on %v, waiting for %v to retry: %v", filePath, WatchSleepDuration, err)
+			if waitOrInterrupted() {
+				return
+			}
+			continue
+		}
+
+		// send the initial value
+		notify(data, stat)
 
-				log.Errorf("Cannot set watch on %v, waiting for %v to retry: %v", filePath, WatchSleepDuration, err)
+		// now act on the watch
+		select {
+		case event, ok := <-watch:
+			if !ok {
+				log.Warningf("watch on %v was closed, waiting for %v to retry", filePath, WatchSleepDuration)
 				if waitOrInterrupted() {
 					return
 				}
 				continue
 			}
 
-			// get the initial value, send it, or send nil if no
-			// data
-			var ep *topo.EndPoints
-			sendIt := true
-			if len(data) > 0 {
-				ep = &topo.EndPoints{}
-				if err := json.Unmarshal([]byte(data), ep); err != nil {
-					log.Errorf("EndPoints unmarshal failed: %v %v", data, err)
-					sendIt = false
-				}
-			}
-			if sendIt {
-				notifications <- ep
-			}
-
-			// now act on the watch
-			select {
-			case event, ok := <-watch:
-				if !ok {
-					log.Warningf("watch on %v was closed, waiting for %v to retry", filePath, WatchSleepDuration)
-					if waitOrInterrupted() {
-						return
-					}
-					continue
-				}
-
-				if !event.Ok() {
-					log.Warningf("received a non-OK event for %v, waiting for %v to retry", filePath, WatchSleepDuration)
-					if waitOrInterrupted() {
-						return
-					}
+			if !event.Ok() {
+				log.Warningf("received a non-OK event for %v, waiting for %v to retry", filePath, WatchSleepDuration)
+				if waitOrInterrupted() {
+					return
 				}
-			case <-stopWatching:
-				// user is not interested any more
-				close(notifications)
-				return
 			}
+		case <-stopWatching:
+			// user is not interested any more
+			return
 		}
-	}()
-
-	return notifications, stopWatching, nil
-
+	}
 }
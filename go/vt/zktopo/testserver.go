@@ -63,3 +63,15 @@ func (s *TestServer) SaveVSchema(ctx context.Context, vschema string) error {
 func (s *TestServer) GetVSchema(ctx context.Context) (string, error) {
 	return s.Server.(topo.Schemafier).GetVSchema(ctx)
 }
+
+// SaveVTGateQuotas has to be redefined here.
+// Otherwise the test type assertion fails.
+func (s *TestServer) SaveVTGateQuotas(ctx context.Context, quotas string) error {
+	return s.Server.(topo.VTGateQuotaStore).SaveVTGateQuotas(ctx, quotas)
+}
+
+// GetVTGateQuotas has to be redefined here.
+// Otherwise the test type assertion fails.
+func (s *TestServer) GetVTGateQuotas(ctx context.Context) (string, error) {
+	return s.Server.(topo.VTGateQuotaStore).GetVTGateQuotas(ctx)
+}
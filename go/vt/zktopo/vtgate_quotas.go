@@ -0,0 +1,44 @@
+// Copyright 2015, Google Inc. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zktopo
+
+import (
+	"github.com/youtube/vitess/go/vt/topo"
+	"github.com/youtube/vitess/go/vt/vtgate/quota"
+	"github.com/youtube/vitess/go/zk"
+	"golang.org/x/net/context"
+	"launchpad.net/gozk/zookeeper"
+)
+
+/*
+This file contains the vtgate quotas management code for zktopo.Server
+*/
+
+const (
+	globalVTGateQuotasPath = "/zk/global/vt/vtgate_quotas"
+)
+
+var _ topo.VTGateQuotaStore = (*Server)(nil) // compile-time interface check
+
+// SaveVTGateQuotas saves the JSON vtgate quotas into the topo.
+func (zkts *Server) SaveVTGateQuotas(ctx context.Context, quotas string) error {
+	if _, err := quota.NewConfig([]byte(quotas)); err != nil {
+		return err
+	}
+	_, err := zk.CreateOrUpdate(zkts.zconn, globalVTGateQuotasPath, quotas, 0, zookeeper.WorldACL(zookeeper.PERM_ALL), true)
+	return err
+}
+
+// GetVTGateQuotas fetches the JSON vtgate quotas from the topo.
+func (zkts *Server) GetVTGateQuotas(ctx context.Context) (string, error) {
+	data, _, err := zkts.zconn.Get(globalVTGateQuotasPath)
+	if err != nil {
+		if zookeeper.IsError(err, zookeeper.ZNONODE) {
+			return "{}", nil
+		}
+		return "", err
+	}
+	return data, nil
+}
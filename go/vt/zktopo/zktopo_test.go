@@ -54,6 +54,20 @@ func TestWatchEndPoints(t *testing.T) {
 	test.CheckWatchEndPoints(context.Background(), t, ts)
 }
 
+func TestWatchSrvKeyspace(t *testing.T) {
+	WatchSleepDuration = 2 * time.Millisecond
+	ts := NewTestServer(t, []string{"test"})
+	defer ts.Close()
+	test.CheckWatchSrvKeyspace(context.Background(), t, ts)
+}
+
+func TestWatchSrvShard(t *testing.T) {
+	WatchSleepDuration = 2 * time.Millisecond
+	ts := NewTestServer(t, []string{"test"})
+	defer ts.Close()
+	test.CheckWatchSrvShard(context.Background(), t, ts)
+}
+
 func TestKeyspaceLock(t *testing.T) {
 	ctx := context.Background()
 	ts := NewTestServer(t, []string{"test"})
@@ -90,6 +104,13 @@ func TestVSchema(t *testing.T) {
 	test.CheckVSchema(ctx, t, ts)
 }
 
+func TestVTGateQuotas(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTestServer(t, []string{"test"})
+	defer ts.Close()
+	test.CheckVTGateQuotas(ctx, t, ts)
+}
+
 // TestPurgeActions is a ZK specific unit test
 func TestPurgeActions(t *testing.T) {
 	ctx := context.Background()
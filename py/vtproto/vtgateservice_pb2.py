@@ -20,7 +20,7 @@ DESCRIPTOR = _descriptor.FileDescriptor(
   name='vtgateservice.proto',
   package='vtgateservice',
   syntax='proto3',
-  serialized_pb=_b('\n\x13vtgateservice.proto\x12\rvtgateservice\x1a\x0cvtgate.proto2\x85\n\n\x06Vitess\x12<\n\x07\x45xecute\x12\x16.vtgate.ExecuteRequest\x1a\x17.vtgate.ExecuteResponse\"\x00\x12N\n\rExecuteShards\x12\x1c.vtgate.ExecuteShardsRequest\x1a\x1d.vtgate.ExecuteShardsResponse\"\x00\x12]\n\x12\x45xecuteKeyspaceIds\x12!.vtgate.ExecuteKeyspaceIdsRequest\x1a\".vtgate.ExecuteKeyspaceIdsResponse\"\x00\x12W\n\x10\x45xecuteKeyRanges\x12\x1f.vtgate.ExecuteKeyRangesRequest\x1a .vtgate.ExecuteKeyRangesResponse\"\x00\x12W\n\x10\x45xecuteEntityIds\x12\x1f.vtgate.ExecuteEntityIdsRequest\x1a .vtgate.ExecuteEntityIdsResponse\"\x00\x12]\n\x12\x45xecuteBatchShards\x12!.vtgate.ExecuteBatchShardsRequest\x1a\".vtgate.ExecuteBatchShardsResponse\"\x00\x12l\n\x17\x45xecuteBatchKeyspaceIds\x12&.vtgate.ExecuteBatchKeyspaceIdsRequest\x1a\'.vtgate.ExecuteBatchKeyspaceIdsResponse\"\x00\x12P\n\rStreamExecute\x12\x1c.vtgate.StreamExecuteRequest\x1a\x1d.vtgate.StreamExecuteResponse\"\x00\x30\x01\x12\x62\n\x13StreamExecuteShards\x12\".vtgate.StreamExecuteShardsRequest\x1a#.vtgate.StreamExecuteShardsResponse\"\x00\x30\x01\x12q\n\x18StreamExecuteKeyspaceIds\x12\'.vtgate.StreamExecuteKeyspaceIdsRequest\x1a(.vtgate.StreamExecuteKeyspaceIdsResponse\"\x00\x30\x01\x12k\n\x16StreamExecuteKeyRanges\x12%.vtgate.StreamExecuteKeyRangesRequest\x1a&.vtgate.StreamExecuteKeyRangesResponse\"\x00\x30\x01\x12\x36\n\x05\x42\x65gin\x12\x14.vtgate.BeginRequest\x1a\x15.vtgate.BeginResponse\"\x00\x12\x39\n\x06\x43ommit\x12\x15.vtgate.CommitRequest\x1a\x16.vtgate.CommitResponse\"\x00\x12?\n\x08Rollback\x12\x17.vtgate.RollbackRequest\x1a\x18.vtgate.RollbackResponse\"\x00\x12\x45\n\nSplitQuery\x12\x19.vtgate.SplitQueryRequest\x1a\x1a.vtgate.SplitQueryResponse\"\x00\x62\x06proto3')
+  serialized_pb=_b('\n\x13vtgateservice.proto\x12\rvtgateservice\x1a\x0cvtgate.proto2\x9d\x0b\n\x06Vitess\x12<\n\x07\x45xecute\x12\x16.vtgate.ExecuteRequest\x1a\x17.vtgate.ExecuteResponse\"\x00\x12N\n\rExecuteShards\x12\x1c.vtgate.ExecuteShardsRequest\x1a\x1d.vtgate.ExecuteShardsResponse\"\x00\x12]\n\x12\x45xecuteKeyspaceIds\x12!.vtgate.ExecuteKeyspaceIdsRequest\x1a\".vtgate.ExecuteKeyspaceIdsResponse\"\x00\x12W\n\x10\x45xecuteKeyRanges\x12\x1f.vtgate.ExecuteKeyRangesRequest\x1a .vtgate.ExecuteKeyRangesResponse\"\x00\x12W\n\x10\x45xecuteEntityIds\x12\x1f.vtgate.ExecuteEntityIdsRequest\x1a .vtgate.ExecuteEntityIdsResponse\"\x00\x12]\n\x12\x45xecuteBatchShards\x12!.vtgate.ExecuteBatchShardsRequest\x1a\".vtgate.ExecuteBatchShardsResponse\"\x00\x12l\n\x17\x45xecuteBatchKeyspaceIds\x12&.vtgate.ExecuteBatchKeyspaceIdsRequest\x1a\'.vtgate.ExecuteBatchKeyspaceIdsResponse\"\x00\x12P\n\rStreamExecute\x12\x1c.vtgate.StreamExecuteRequest\x1a\x1d.vtgate.StreamExecuteResponse\"\x00\x30\x01\x12\x62\n\x13StreamExecuteShards\x12\".vtgate.StreamExecuteShardsRequest\x1a#.vtgate.StreamExecuteShardsResponse\"\x00\x30\x01\x12q\n\x18StreamExecuteKeyspaceIds\x12\'.vtgate.StreamExecuteKeyspaceIdsRequest\x1a(.vtgate.StreamExecuteKeyspaceIdsResponse\"\x00\x30\x01\x12k\n\x16StreamExecuteKeyRanges\x12%.vtgate.StreamExecuteKeyRangesRequest\x1a&.vtgate.StreamExecuteKeyRangesResponse\"\x00\x30\x01\x12\x36\n\x05\x42\x65gin\x12\x14.vtgate.BeginRequest\x1a\x15.vtgate.BeginResponse\"\x00\x12\x39\n\x06\x43ommit\x12\x15.vtgate.CommitRequest\x1a\x16.vtgate.CommitResponse\"\x00\x12?\n\x08Rollback\x12\x17.vtgate.RollbackRequest\x1a\x18.vtgate.RollbackResponse\"\x00\x12\x45\n\nSplitQuery\x12\x19.vtgate.SplitQueryRequest\x1a\x1a.vtgate.SplitQueryResponse\"\x00\x12I\n\x0cSplitQueryV2\x12\x1b.vtgate.SplitQueryV2Request\x1a\x1a.vtgate.SplitQueryResponse\"\x00\x12K\n\x0c\x45xplainQuery\x12\x1b.vtgate.ExplainQueryRequest\x1a\x1c.vtgate.ExplainQueryResponse\"\x00\x62\x06proto3')
   ,
   dependencies=[vtgate__pb2.DESCRIPTOR,])
 _sym_db.RegisterFileDescriptor(DESCRIPTOR)
@@ -80,6 +80,12 @@ class EarlyAdopterVitessServicer(object):
   @abc.abstractmethod
   def SplitQuery(self, request, context):
     raise NotImplementedError()
+  @abc.abstractmethod
+  def SplitQueryV2(self, request, context):
+    raise NotImplementedError()
+  @abc.abstractmethod
+  def ExplainQuery(self, request, context):
+    raise NotImplementedError()
 class EarlyAdopterVitessServer(object):
   """<fill me in later!>"""
   __metaclass__ = abc.ABCMeta
@@ -152,6 +158,14 @@ class EarlyAdopterVitessStub(object):
   def SplitQuery(self, request):
     raise NotImplementedError()
   SplitQuery.async = None
+  @abc.abstractmethod
+  def SplitQueryV2(self, request):
+    raise NotImplementedError()
+  SplitQueryV2.async = None
+  @abc.abstractmethod
+  def ExplainQuery(self, request):
+    raise NotImplementedError()
+  ExplainQuery.async = None
 def early_adopter_create_Vitess_server(servicer, port, private_key=None, certificate_chain=None):
   import vtgate_pb2
   import vtgate_pb2
@@ -183,6 +197,10 @@ def early_adopter_create_Vitess_server(servicer, port, private_key=None, certifi
   import vtgate_pb2
   import vtgate_pb2
   import vtgate_pb2
+  import vtgate_pb2
+  import vtgate_pb2
+  import vtgate_pb2
+  import vtgate_pb2
   method_service_descriptions = {
     "Begin": utilities.unary_unary_service_description(
       servicer.Begin,
@@ -229,6 +247,11 @@ def early_adopter_create_Vitess_server(servicer, port, private_key=None, certifi
       vtgate_pb2.ExecuteShardsRequest.FromString,
       vtgate_pb2.ExecuteShardsResponse.SerializeToString,
     ),
+    "ExplainQuery": utilities.unary_unary_service_description(
+      servicer.ExplainQuery,
+      vtgate_pb2.ExplainQueryRequest.FromString,
+      vtgate_pb2.ExplainQueryResponse.SerializeToString,
+    ),
     "Rollback": utilities.unary_unary_service_description(
       servicer.Rollback,
       vtgate_pb2.RollbackRequest.FromString,
@@ -239,6 +262,11 @@ def early_adopter_create_Vitess_server(servicer, port, private_key=None, certifi
       vtgate_pb2.SplitQueryRequest.FromString,
       vtgate_pb2.SplitQueryResponse.SerializeToString,
     ),
+    "SplitQueryV2": utilities.unary_unary_service_description(
+      servicer.SplitQueryV2,
+      vtgate_pb2.SplitQueryV2Request.FromString,
+      vtgate_pb2.SplitQueryResponse.SerializeToString,
+    ),
     "StreamExecute": utilities.unary_stream_service_description(
       servicer.StreamExecute,
       vtgate_pb2.StreamExecuteRequest.FromString,
@@ -292,6 +320,10 @@ def early_adopter_create_Vitess_stub(host, port, metadata_transformer=None, secu
   import vtgate_pb2
   import vtgate_pb2
   import vtgate_pb2
+  import vtgate_pb2
+  import vtgate_pb2
+  import vtgate_pb2
+  import vtgate_pb2
   method_invocation_descriptions = {
     "Begin": utilities.unary_unary_invocation_description(
       vtgate_pb2.BeginRequest.SerializeToString,
@@ -329,6 +361,10 @@ def early_adopter_create_Vitess_stub(host, port, metadata_transformer=None, secu
       vtgate_pb2.ExecuteShardsRequest.SerializeToString,
       vtgate_pb2.ExecuteShardsResponse.FromString,
     ),
+    "ExplainQuery": utilities.unary_unary_invocation_description(
+      vtgate_pb2.ExplainQueryRequest.SerializeToString,
+      vtgate_pb2.ExplainQueryResponse.FromString,
+    ),
     "Rollback": utilities.unary_unary_invocation_description(
       vtgate_pb2.RollbackRequest.SerializeToString,
       vtgate_pb2.RollbackResponse.FromString,
@@ -337,6 +373,10 @@ def early_adopter_create_Vitess_stub(host, port, metadata_transformer=None, secu
       vtgate_pb2.SplitQueryRequest.SerializeToString,
       vtgate_pb2.SplitQueryResponse.FromString,
     ),
+    "SplitQueryV2": utilities.unary_unary_invocation_description(
+      vtgate_pb2.SplitQueryV2Request.SerializeToString,
+      vtgate_pb2.SplitQueryResponse.FromString,
+    ),
     "StreamExecute": utilities.unary_stream_invocation_description(
       vtgate_pb2.StreamExecuteRequest.SerializeToString,
       vtgate_pb2.StreamExecuteResponse.FromString,
@@ -20,7 +20,7 @@ DESCRIPTOR = _descriptor.FileDescriptor(
   name='vtrpc.proto',
   package='vtrpc',
   syntax='proto3',
-  serialized_pb=_b('\n\x0bvtrpc.proto\x12\x05vtrpc\"F\n\x08\x43\x61llerID\x12\x11\n\tprincipal\x18\x01 \x01(\t\x12\x11\n\tcomponent\x18\x02 \x01(\t\x12\x14\n\x0csubcomponent\x18\x03 \x01(\t\";\n\x08RPCError\x12\x1e\n\x04\x63ode\x18\x01 \x01(\x0e\x32\x10.vtrpc.ErrorCode\x12\x0f\n\x07message\x18\x02 \x01(\t*n\n\tErrorCode\x12\x0b\n\x07NoError\x10\x00\x12\x10\n\x0bTabletError\x10\xe8\x07\x12\x17\n\x12UnknownTabletError\x10\xcf\x0f\x12\x10\n\x0bVtgateError\x10\xd0\x0f\x12\x17\n\x12UnknownVtgateError\x10\xb7\x17\x62\x06proto3')
+  serialized_pb=_b('\n\x0bvtrpc.proto\x12\x05vtrpc\"F\n\x08\x43\x61llerID\x12\x11\n\tprincipal\x18\x01 \x01(\t\x12\x11\n\tcomponent\x18\x02 \x01(\t\x12\x14\n\x0csubcomponent\x18\x03 \x01(\t\";\n\x08RPCError\x12\x1e\n\x04\x63ode\x18\x01 \x01(\x0e\x32\x10.vtrpc.ErrorCode\x12\x0f\n\x07message\x18\x02 \x01(\t*\x9a\x01\n\tErrorCode\x12\x0b\n\x07NoError\x10\x00\x12\x10\n\x0bTabletError\x10\xe8\x07\x12\x17\n\x12UnknownTabletError\x10\xcf\x0f\x12\x10\n\x0bVtgateError\x10\xd0\x0f\x12\x12\n\rQuotaExceeded\x10\xd1\x0f\x12\x16\n\x11ResourceExhausted\x10\xd2\x0f\x12\x17\n\x12UnknownVtgateError\x10\xb7\x17\x62\x06proto3')
 )
 _sym_db.RegisterFileDescriptor(DESCRIPTOR)
 
@@ -47,14 +47,22 @@ _ERRORCODE = _descriptor.EnumDescriptor(
       options=None,
       type=None),
     _descriptor.EnumValueDescriptor(
-      name='UnknownVtgateError', index=4, number=2999,
+      name='QuotaExceeded', index=4, number=2001,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='ResourceExhausted', index=5, number=2002,
+      options=None,
+      type=None),
+    _descriptor.EnumValueDescriptor(
+      name='UnknownVtgateError', index=6, number=2999,
       options=None,
       type=None),
   ],
   containing_type=None,
   options=None,
-  serialized_start=155,
-  serialized_end=265,
+  serialized_start=156,
+  serialized_end=310,
 )
 _sym_db.RegisterEnumDescriptor(_ERRORCODE)
 
@@ -63,6 +71,8 @@ NoError = 0
 TabletError = 1000
 UnknownTabletError = 1999
 VtgateError = 2000
+QuotaExceeded = 2001
+ResourceExhausted = 2002
 UnknownVtgateError = 2999
 
 